@@ -4,10 +4,11 @@ go 1.15
 
 require (
 	github.com/akamensky/argparse v1.2.2
-	github.com/google/uuid v1.1.2
+	github.com/coreos/go-systemd v0.0.0-20190321100706-95778dfbb74e // indirect
+	github.com/google/uuid v1.3.0
 	github.com/refractionPOINT/go-limacharlie/limacharlie v0.0.0
-	github.com/rs/zerolog v1.20.0
-	golang.org/x/crypto v0.0.0-20190308221718-c2843e01d9a2
+	github.com/rs/zerolog v1.28.0
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
 )
 
 replace github.com/refractionPOINT/go-limacharlie/limacharlie => ../limacharlie
@@ -10,12 +10,35 @@ type ArtifactRule struct {
 	DaysRetentions uint               `json:"days_retention"`
 	Patterns       []string           `json:"patterns"`
 	Filters        ArtifactRuleFilter `json:"filters"`
+	// MaxFileSize in bytes above which files are not collected,
+	// no limit if 0.
+	MaxFileSize uint64 `json:"max_file_size,omitempty"`
+	// Mode is how files are collected, ArtifactRuleModes.Batch if empty.
+	Mode ArtifactRuleMode `json:"mode,omitempty"`
 }
 
 type ArtifactRuleFilter struct {
-	Tags      []string `json:"tags"`
-	Platforms []string `json:"platforms"`
+	Tags      []string   `json:"tags"`
+	Platforms []Platform `json:"platforms"`
+	// ExcludeTags are the tags of the sensors not collected from,
+	// even if they have one of the Tags.
+	ExcludeTags []string `json:"exclude_tags,omitempty"`
 }
+
+type ArtifactRuleMode = string
+
+// ArtifactRuleModes are how the files of an artifact rule are collected.
+var ArtifactRuleModes = struct {
+	// Batch collects files once complete, like rotated logs.
+	Batch ArtifactRuleMode
+	// Streaming collects files as they are written, like
+	// active logs, which cannot be deleted after collection.
+	Streaming ArtifactRuleMode
+}{
+	Batch:     "batch",
+	Streaming: "streaming",
+}
+
 type ArtifactRulesByName = map[ArtifactRuleName]ArtifactRule
 
 func (org Organization) artifact(responseData interface{}, action string, req Dict) error {
@@ -34,7 +57,7 @@ func (org Organization) ArtifactsRules() (ArtifactRulesByName, error) {
 
 func (org Organization) ArtifactRuleAdd(ruleName ArtifactRuleName, rule ArtifactRule) error {
 	resp := Dict{}
-	if err := org.artifact(&resp, "add_rule", Dict{
+	req := Dict{
 		"name":            ruleName,
 		"patterns":        rule.Patterns,
 		"is_delete_after": rule.IsDeleteAfter,
@@ -42,7 +65,18 @@ func (org Organization) ArtifactRuleAdd(ruleName ArtifactRuleName, rule Artifact
 		"days_retention":  rule.DaysRetentions,
 		"tags":            rule.Filters.Tags,
 		"platforms":       rule.Filters.Platforms,
-	}); err != nil {
+	}
+	// The newer attributes are only sent when set.
+	if len(rule.Filters.ExcludeTags) != 0 {
+		req["exclude_tags"] = rule.Filters.ExcludeTags
+	}
+	if rule.MaxFileSize != 0 {
+		req["max_file_size"] = rule.MaxFileSize
+	}
+	if rule.Mode != "" {
+		req["mode"] = rule.Mode
+	}
+	if err := org.artifact(&resp, "add_rule", req); err != nil {
 		return err
 	}
 	return nil
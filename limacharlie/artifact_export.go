@@ -0,0 +1,322 @@
+package limacharlie
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultArtifactExportConcurrency = 4
+	artifactPartialSuffix            = ".part"
+)
+
+// ArtifactInfo describes an artifact collected in an Org.
+type ArtifactInfo struct {
+	ID        string `json:"payload_id"`
+	SensorID  string `json:"sid,omitempty"`
+	Type      string `json:"type"`
+	Source    string `json:"source"`
+	Size      uint64 `json:"size"`
+	Timestamp int64  `json:"ts"`
+	// SHA256 of the content of the artifact, if known.
+	Hash string `json:"hash,omitempty"`
+	// OriginalPath of the file collected on the sensor, if any.
+	OriginalPath string `json:"original_path,omitempty"`
+}
+
+// ArtifactFilter selects artifacts, empty fields match all.
+type ArtifactFilter struct {
+	// Start and End of the time range, in seconds since epoch.
+	Start  int64
+	End    int64
+	Type   string
+	Source string
+}
+
+type artifactList struct {
+	Artifacts  []ArtifactInfo `json:"artifacts"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+type artifactExportPointer struct {
+	URL string `json:"export"`
+}
+
+// ListArtifacts lists the artifacts matching the filter.
+func (org Organization) ListArtifacts(filter ArtifactFilter) ([]ArtifactInfo, error) {
+	artifacts := []ArtifactInfo{}
+	cursor := ""
+	for {
+		q := Dict{}
+		if filter.Start != 0 {
+			q["start"] = filter.Start
+		}
+		if filter.End != 0 {
+			q["end"] = filter.End
+		}
+		if filter.Type != "" {
+			q["type"] = filter.Type
+		}
+		if filter.Source != "" {
+			q["source"] = filter.Source
+		}
+		if cursor != "" {
+			q["cursor"] = cursor
+		}
+		resp := artifactList{}
+		request := makeDefaultRequest(&resp).withQueryData(q)
+		if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("insight/%s/artifacts", org.client.options.OID), request); err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, resp.Artifacts...)
+		if resp.NextCursor == "" || resp.NextCursor == cursor {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	return artifacts, nil
+}
+
+// ArtifactDownloadURL returns a signed URL to download an artifact.
+func (org Organization) ArtifactDownloadURL(id string) (string, error) {
+	resp := artifactExportPointer{}
+	request := makeDefaultRequest(&resp)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("insight/%s/artifacts/originals/%s", org.client.options.OID, id), request); err != nil {
+		return "", err
+	}
+	if resp.URL == "" {
+		return "", ErrorResourceNotFound
+	}
+	return resp.URL, nil
+}
+
+// ArtifactExportOptions configures ExportArtifacts.
+type ArtifactExportOptions struct {
+	Filter ArtifactFilter
+
+	// Directory the artifacts are written to.
+	Directory string
+
+	// Layout returns the path of an artifact relative to the
+	// Directory, defaults to "<source>/<type>/<id>".
+	Layout func(artifact ArtifactInfo) string
+
+	// Concurrency is the number of parallel downloads, defaults to 4.
+	Concurrency int
+
+	// OnArtifact, if set, is called as each artifact is processed,
+	// with the error if it failed. It may be called concurrently.
+	OnArtifact func(artifact ArtifactInfo, err error)
+}
+
+// ArtifactExportResult reports what ExportArtifacts did.
+type ArtifactExportResult struct {
+	Downloaded []ArtifactInfo
+	// Skipped artifacts were already fully downloaded.
+	Skipped []ArtifactInfo
+	// Failed artifacts by ID.
+	Failed map[string]error
+}
+
+// ArtifactExportError is returned when some artifacts failed to export.
+type ArtifactExportError struct {
+	Failed map[string]error
+}
+
+func (e ArtifactExportError) Error() string {
+	ids := []string{}
+	for id := range e.Failed {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	msgs := []string{}
+	for _, id := range ids {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", id, e.Failed[id]))
+	}
+	return fmt.Sprintf("failed to export %d artifacts: %s", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+func defaultArtifactLayout(artifact ArtifactInfo) string {
+	source := artifact.Source
+	if source == "" {
+		source = artifact.SensorID
+	}
+	return filepath.Join(sanitizeArtifactPath(source), sanitizeArtifactPath(artifact.Type), sanitizeArtifactPath(artifact.ID))
+}
+
+func sanitizeArtifactPath(s string) string {
+	if s == "" {
+		return "_"
+	}
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(s)
+}
+
+// ExportArtifacts downloads the artifacts matching the filter to a
+// directory, for evidence preservation. Downloads are written to a
+// ".part" file first so an interrupted export resumes where it stopped
+// when run again, and artifacts already downloaded are skipped. The
+// content of artifacts with a known Hash is verified.
+func (org Organization) ExportArtifacts(opts ArtifactExportOptions) (ArtifactExportResult, error) {
+	result := ArtifactExportResult{
+		Downloaded: []ArtifactInfo{},
+		Skipped:    []ArtifactInfo{},
+		Failed:     map[string]error{},
+	}
+	if opts.Directory == "" {
+		return result, fmt.Errorf("export directory is required")
+	}
+	layout := opts.Layout
+	if layout == nil {
+		layout = defaultArtifactLayout
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultArtifactExportConcurrency
+	}
+
+	artifacts, err := org.ListArtifacts(opts.Filter)
+	if err != nil {
+		return result, err
+	}
+
+	mResult := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	work := make(chan ArtifactInfo)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for artifact := range work {
+				dst := filepath.Join(opts.Directory, layout(artifact))
+				isSkipped, err := org.exportArtifact(artifact, dst)
+				mResult.Lock()
+				if err != nil {
+					result.Failed[artifact.ID] = err
+				} else if isSkipped {
+					result.Skipped = append(result.Skipped, artifact)
+				} else {
+					result.Downloaded = append(result.Downloaded, artifact)
+				}
+				mResult.Unlock()
+				if opts.OnArtifact != nil {
+					opts.OnArtifact(artifact, err)
+				}
+			}
+		}()
+	}
+	for _, artifact := range artifacts {
+		work <- artifact
+	}
+	close(work)
+	wg.Wait()
+
+	if len(result.Failed) != 0 {
+		return result, ArtifactExportError{Failed: result.Failed}
+	}
+	return result, nil
+}
+
+// exportArtifact downloads an artifact to dst unless already there.
+func (org Organization) exportArtifact(artifact ArtifactInfo, dst string) (bool, error) {
+	if _, err := os.Stat(dst); err == nil {
+		if err := verifyArtifactFile(artifact, dst); err == nil {
+			return true, nil
+		}
+		// Download it again if corrupted.
+		if err := os.Remove(dst); err != nil {
+			return false, err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0700); err != nil {
+		return false, err
+	}
+
+	url, err := org.ArtifactDownloadURL(artifact.ID)
+	if err != nil {
+		return false, err
+	}
+	partial := dst + artifactPartialSuffix
+	if err := org.downloadArtifact(url, partial); err != nil {
+		return false, err
+	}
+	if err := verifyArtifactFile(artifact, partial); err != nil {
+		os.Remove(partial)
+		return false, err
+	}
+	return false, os.Rename(partial, dst)
+}
+
+// downloadArtifact downloads to a file, resuming from its current
+// size if the server supports ranges.
+func (org Organization) downloadArtifact(url string, path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset != 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	// No timeout, artifacts can be large.
+	resp, err := org.client.httpClient(0).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// Already complete.
+		return nil
+	case http.StatusOK:
+		// Ranges not supported, start over.
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("failed to GET artifact, http status: %d", resp.StatusCode)
+	}
+	_, err = io.Copy(f, resp.Body)
+	return err
+}
+
+func verifyArtifactFile(artifact ArtifactInfo, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return err
+	}
+	if artifact.Size != 0 && uint64(n) != artifact.Size {
+		return fmt.Errorf("size mismatch: expected %d bytes, got %d", artifact.Size, n)
+	}
+	if artifact.Hash != "" && !strings.EqualFold(hex.EncodeToString(h.Sum(nil)), artifact.Hash) {
+		return fmt.Errorf("checksum mismatch: expected %s", artifact.Hash)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package limacharlie
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportArtifacts(t *testing.T) {
+	a := assert.New(t)
+	contents := map[string]string{
+		"a1": "first artifact content",
+		"a2": "second artifact content",
+		"a3": "corrupted",
+	}
+	sum := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+
+	mRanges := sync.Mutex{}
+	ranges := map[string]string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "storage.example.com" {
+			id := strings.TrimPrefix(r.URL.Path, "/")
+			content := contents[id]
+			rng := r.Header.Get("Range")
+			mRanges.Lock()
+			ranges[id] = rng
+			mRanges.Unlock()
+			if rng == "" {
+				return jsonResponse(http.StatusOK, content), nil
+			}
+			offset := 0
+			fmt.Sscanf(rng, "bytes=%d-", &offset)
+			return jsonResponse(http.StatusPartialContent, content[offset:]), nil
+		}
+		switch {
+		case r.URL.Path == fmt.Sprintf("/v1/insight/%s/artifacts", vcrTestOID):
+			a.Equal("pcap", r.URL.Query().Get("type"))
+			if r.URL.Query().Get("cursor") == "" {
+				return jsonResponse(http.StatusOK, fmt.Sprintf(`{"artifacts":[{"payload_id":"a1","type":"pcap","source":"s1","hash":"%s"},{"payload_id":"a2","type":"pcap","source":"s1","hash":"%s"}],"next_cursor":"c1"}`, sum(contents["a1"]), sum(contents["a2"]))), nil
+			}
+			return jsonResponse(http.StatusOK, `{"artifacts":[{"payload_id":"a3","type":"pcap","source":"s2","hash":"0000"}]}`), nil
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/v1/insight/%s/artifacts/originals/", vcrTestOID)):
+			id := filepath.Base(r.URL.Path)
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{"export":"https://storage.example.com/%s"}`, id)), nil
+		}
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	dir, err := ioutil.TempDir("", "artifacts")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+
+	// An interrupted download of a2.
+	a.NoError(os.MkdirAll(filepath.Join(dir, "s1", "pcap"), 0700))
+	a.NoError(ioutil.WriteFile(filepath.Join(dir, "s1", "pcap", "a2.part"), []byte("second "), 0600))
+
+	opts := ArtifactExportOptions{
+		Filter:      ArtifactFilter{Type: "pcap"},
+		Directory:   dir,
+		Concurrency: 2,
+	}
+	result, err := org.ExportArtifacts(opts)
+	a.Error(err)
+	a.Len(result.Downloaded, 2)
+	a.Empty(result.Skipped)
+	a.Contains(result.Failed, "a3")
+	a.Contains(result.Failed["a3"].Error(), "checksum mismatch")
+	a.Equal("bytes=7-", ranges["a2"])
+
+	for _, id := range []string{"a1", "a2"} {
+		data, err := ioutil.ReadFile(filepath.Join(dir, "s1", "pcap", id))
+		a.NoError(err)
+		a.Equal(contents[id], string(data))
+	}
+	_, err = os.Stat(filepath.Join(dir, "s2", "pcap", "a3"))
+	a.True(os.IsNotExist(err))
+
+	// Running again skips what was already downloaded.
+	result, err = org.ExportArtifacts(opts)
+	a.Error(err)
+	a.Len(result.Skipped, 2)
+	a.Empty(result.Downloaded)
+}
@@ -0,0 +1,191 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ArtifactPatternScheme is the kind of source an artifact
+// rule pattern collects from, files when empty.
+type ArtifactPatternScheme = string
+
+var ArtifactPatternSchemes = struct {
+	File            ArtifactPatternScheme
+	WindowsEventLog ArtifactPatternScheme
+	MacUnifiedLog   ArtifactPatternScheme
+}{
+	File:            "",
+	WindowsEventLog: "wel",
+	MacUnifiedLog:   "mul",
+}
+
+// ArtifactPattern is a parsed artifact rule pattern.
+type ArtifactPattern struct {
+	Scheme ArtifactPatternScheme
+	// Value is the pattern without its scheme: a file path, a
+	// "<channel>:<filter>" event log or a unified log predicate.
+	Value string
+}
+
+func (p ArtifactPattern) String() string {
+	if p.Scheme == ArtifactPatternSchemes.File {
+		return p.Value
+	}
+	return p.Scheme + "://" + p.Value
+}
+
+// Platforms returns the platforms the pattern can collect from,
+// sorted, or nil if it is not specific to any.
+func (p ArtifactPattern) Platforms() []Platform {
+	switch p.Scheme {
+	case ArtifactPatternSchemes.WindowsEventLog:
+		return []Platform{"windows"}
+	case ArtifactPatternSchemes.MacUnifiedLog:
+		return []Platform{"macos"}
+	}
+	if isWindowsPath(p.Value) {
+		return []Platform{"windows"}
+	}
+	if strings.HasPrefix(p.Value, "/") {
+		return []Platform{"linux", "macos"}
+	}
+	return nil
+}
+
+var windowsDrivePath = regexp.MustCompile(`^[a-zA-Z]:[\\/]`)
+
+func isWindowsPath(path string) bool {
+	return windowsDrivePath.MatchString(path) || strings.HasPrefix(path, "%") || strings.HasPrefix(path, `\\`)
+}
+
+// Registry keys are not files, their hives are.
+var registryKeyPrefixes = []string{"hklm", "hkcu", "hku", "hkcr", "hkcc", "hkey_"}
+
+// ParseArtifactPattern parses and validates an artifact rule pattern.
+func ParseArtifactPattern(pattern string) (ArtifactPattern, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return ArtifactPattern{}, fmt.Errorf("empty pattern")
+	}
+	i := strings.Index(pattern, "://")
+	if i == -1 {
+		lower := strings.ToLower(pattern)
+		for _, prefix := range registryKeyPrefixes {
+			if strings.HasPrefix(lower, prefix) {
+				return ArtifactPattern{}, fmt.Errorf("registry keys cannot be collected, collect the file of the hive instead, see RegistryHivePattern: %q", pattern)
+			}
+		}
+		return ArtifactPattern{Scheme: ArtifactPatternSchemes.File, Value: pattern}, nil
+	}
+	p := ArtifactPattern{Scheme: strings.ToLower(pattern[:i]), Value: pattern[i+len("://"):]}
+	switch p.Scheme {
+	case ArtifactPatternSchemes.WindowsEventLog:
+		channel, filter := p.Value, ""
+		if j := strings.Index(p.Value, ":"); j != -1 {
+			channel, filter = p.Value[:j], p.Value[j+1:]
+		}
+		if channel == "" || filter == "" {
+			return ArtifactPattern{}, fmt.Errorf("expected wel://<channel>:<filter>, like wel://Security:*: %q", pattern)
+		}
+	case ArtifactPatternSchemes.MacUnifiedLog:
+		if strings.TrimSpace(p.Value) == "" {
+			return ArtifactPattern{}, fmt.Errorf("expected mul://<predicate>: %q", pattern)
+		}
+	default:
+		return ArtifactPattern{}, fmt.Errorf("unknown pattern scheme %q: %q", p.Scheme, pattern)
+	}
+	return p, nil
+}
+
+// WindowsEventLogPattern returns the pattern collecting the events
+// of a Windows Event Log channel matching the filter, all if empty.
+func WindowsEventLogPattern(channel string, filter string) string {
+	if filter == "" {
+		filter = "*"
+	}
+	return ArtifactPattern{Scheme: ArtifactPatternSchemes.WindowsEventLog, Value: channel + ":" + filter}.String()
+}
+
+// MacUnifiedLogPattern returns the pattern collecting the
+// entries of the macOS unified log matching the predicate.
+func MacUnifiedLogPattern(predicate string) string {
+	return ArtifactPattern{Scheme: ArtifactPatternSchemes.MacUnifiedLog, Value: predicate}.String()
+}
+
+// RegistryHivePattern returns the pattern collecting the file of
+// a machine registry hive, like "SYSTEM" or "SOFTWARE".
+func RegistryHivePattern(hive string) string {
+	return `%windir%\system32\config\` + strings.ToUpper(hive)
+}
+
+// ValidateArtifactRule checks the patterns of an artifact rule and
+// that they can collect from the platforms the rule targets, since
+// invalid patterns otherwise only fail at collection time, as well
+// as its collection mode and tag filters.
+func ValidateArtifactRule(rule OrgSyncArtifactRule) []error {
+	errs := []error{}
+	switch strings.ToLower(rule.Mode) {
+	case "", ArtifactRuleModes.Batch:
+	case ArtifactRuleModes.Streaming:
+		if rule.IsDeleteAfter {
+			errs = append(errs, errors.New("is_delete_after cannot be set on a streaming rule, whose files are still written to"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("unknown mode %q, expected %s or %s", rule.Mode, ArtifactRuleModes.Batch, ArtifactRuleModes.Streaming))
+	}
+	for _, tag := range rule.ExcludeTags {
+		if arrayExistsInString(tag, rule.Tags) {
+			errs = append(errs, fmt.Errorf("tag %q is both included and excluded", tag))
+		}
+	}
+	targeted := map[Platform]bool{}
+	for _, p := range rule.Platforms {
+		targeted[Platform(strings.ToLower(string(p)))] = true
+	}
+	for _, pattern := range rule.Patterns {
+		p, err := ParseArtifactPattern(pattern)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		supported := p.Platforms()
+		if len(targeted) == 0 || len(supported) == 0 {
+			continue
+		}
+		isCompatible := false
+		for _, platform := range supported {
+			if targeted[platform] {
+				isCompatible = true
+				break
+			}
+		}
+		if !isCompatible {
+			errs = append(errs, fmt.Errorf("pattern %q only applies to %s, not to the platforms of the rule: %s", pattern, joinPlatforms(supported), joinPlatforms(rule.Platforms)))
+		}
+	}
+	return errs
+}
+
+func joinPlatforms(platforms []Platform) string {
+	names := []string{}
+	for _, p := range platforms {
+		names = append(names, string(p))
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func lintArtifactInvalidPattern(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	for name, rule := range conf.Artifacts {
+		for _, err := range ValidateArtifactRule(rule) {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Error,
+				Message:  err.Error(),
+			}.at("artifact", name))
+		}
+	}
+	return findings
+}
@@ -0,0 +1,80 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseArtifactPattern(t *testing.T) {
+	a := assert.New(t)
+
+	p, err := ParseArtifactPattern("wel://Security:*")
+	a.NoError(err)
+	a.Equal(ArtifactPattern{Scheme: ArtifactPatternSchemes.WindowsEventLog, Value: "Security:*"}, p)
+	a.Equal([]Platform{"windows"}, p.Platforms())
+
+	p, err = ParseArtifactPattern("/var/log/auth.log")
+	a.NoError(err)
+	a.Equal([]Platform{"linux", "macos"}, p.Platforms())
+
+	p, err = ParseArtifactPattern(`C:\Windows\Temp\*.log`)
+	a.NoError(err)
+	a.Equal([]Platform{"windows"}, p.Platforms())
+
+	p, err = ParseArtifactPattern("*.log")
+	a.NoError(err)
+	a.Nil(p.Platforms())
+
+	for _, invalid := range []string{"", "wel://Security", "wel://:*", "mul://", "ftp://host/file", `HKLM\SYSTEM\CurrentControlSet`} {
+		_, err := ParseArtifactPattern(invalid)
+		a.Error(err, invalid)
+	}
+
+	a.Equal("wel://System:*", WindowsEventLogPattern("System", ""))
+	a.Equal(`mul://process == "sshd"`, MacUnifiedLogPattern(`process == "sshd"`))
+	a.Equal(`%windir%\system32\config\SAM`, RegistryHivePattern("sam"))
+	for _, pattern := range []string{WindowsEventLogPattern("System", ""), MacUnifiedLogPattern("x"), RegistryHivePattern("SAM")} {
+		_, err := ParseArtifactPattern(pattern)
+		a.NoError(err, pattern)
+	}
+}
+
+func TestLintArtifactInvalidPattern(t *testing.T) {
+	a := assert.New(t)
+	conf := OrgConfig{Artifacts: orgSyncArtifacts{
+		"linux-logs": {
+			Patterns:  []string{"/var/log/syslog", "wel://System:*"},
+			Platforms: []Platform{"linux"},
+		},
+		"any": {Patterns: []string{"wel://System:*", "/var/log/syslog"}},
+		"bad": {Patterns: []string{"wel://System"}},
+	}}
+	findings := lintArtifactInvalidPattern(conf)
+	a.Len(findings, 2)
+	locations := []string{findings[0].Location, findings[1].Location}
+	a.ElementsMatch([]string{"artifact.linux-logs", "artifact.bad"}, locations)
+	for _, f := range findings {
+		if f.Location == "artifact.linux-logs" {
+			a.Equal(`pattern "wel://System:*" only applies to windows, not to the platforms of the rule: linux`, f.Message)
+		}
+	}
+}
+
+func TestValidateArtifactRuleAttributes(t *testing.T) {
+	a := assert.New(t)
+	a.Empty(ValidateArtifactRule(OrgSyncArtifactRule{
+		Patterns:    []string{"/var/log/syslog"},
+		Mode:        ArtifactRuleModes.Streaming,
+		Tags:        []string{"prod"},
+		ExcludeTags: []string{"noisy"},
+		MaxFileSize: 1 << 20,
+	}))
+	a.Len(ValidateArtifactRule(OrgSyncArtifactRule{Mode: ArtifactRuleModes.Streaming, IsDeleteAfter: true}), 1)
+	a.Len(ValidateArtifactRule(OrgSyncArtifactRule{Mode: "continuous"}), 1)
+	a.Len(ValidateArtifactRule(OrgSyncArtifactRule{Tags: []string{"prod"}, ExcludeTags: []string{"prod"}}), 1)
+
+	// The newer attributes round-trip through the API representation.
+	rule := OrgSyncArtifactRule{ExcludeTags: []string{"noisy"}, MaxFileSize: 10, Mode: ArtifactRuleModes.Streaming}
+	a.Equal(rule, OrgSyncArtifactRule{}.FromArtifactRule(rule.ToArtifactRule()))
+}
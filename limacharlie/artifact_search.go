@@ -0,0 +1,119 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// ArtifactSearchFilter selects artifacts by their metadata,
+// empty fields match all.
+type ArtifactSearchFilter struct {
+	SensorID string
+	// OriginalPath of the files collected, case insensitive. Paths
+	// with wildcards, like "c:\\windows\\temp\\*.exe", are matched
+	// by the SDK on the artifacts of the other criteria.
+	OriginalPath string
+	Type         string
+	// Hash is the SHA256 of the content of the artifacts.
+	Hash string
+	// Start and End of the time range, in seconds since epoch.
+	Start int64
+	End   int64
+
+	// Limit is the maximum number of artifacts per page,
+	// the default of the API if 0.
+	Limit int
+	// Cursor of the page, from the NextCursor of the previous one.
+	Cursor string
+}
+
+// ArtifactSearchPage is a page of the artifacts found.
+type ArtifactSearchPage struct {
+	Artifacts []ArtifactInfo `json:"artifacts"`
+	// NextCursor is empty on the last page.
+	NextCursor string `json:"next_cursor"`
+}
+
+func (f ArtifactSearchFilter) hasPathPattern() bool {
+	return strings.ContainsAny(f.OriginalPath, "*?[")
+}
+
+// matches checks the criteria the API may not, only the
+// original path when it has wildcards.
+func (f ArtifactSearchFilter) matches(artifact ArtifactInfo) bool {
+	if !f.hasPathPattern() {
+		return true
+	}
+	// Separators are normalized so patterns of Windows paths
+	// do not escape their wildcards with backslashes.
+	normalize := func(p string) string {
+		return strings.ToLower(strings.ReplaceAll(p, `\`, "/"))
+	}
+	isMatched, err := path.Match(normalize(f.OriginalPath), normalize(artifact.OriginalPath))
+	return err == nil && isMatched
+}
+
+// ArtifactsSearch returns a page of the artifacts matching the filter,
+// the following ones being returned for the NextCursor. Pages of an
+// OriginalPath with wildcards may have fewer than Limit artifacts.
+func (org Organization) ArtifactsSearch(filter ArtifactSearchFilter) (ArtifactSearchPage, error) {
+	q := Dict{}
+	if filter.SensorID != "" {
+		q["sid"] = filter.SensorID
+	}
+	if filter.OriginalPath != "" && !filter.hasPathPattern() {
+		q["original_path"] = filter.OriginalPath
+	}
+	if filter.Type != "" {
+		q["type"] = filter.Type
+	}
+	if filter.Hash != "" {
+		q["hash"] = strings.ToLower(filter.Hash)
+	}
+	if filter.Start != 0 {
+		q["start"] = filter.Start
+	}
+	if filter.End != 0 {
+		q["end"] = filter.End
+	}
+	if filter.Limit > 0 {
+		q["limit"] = filter.Limit
+	}
+	if filter.Cursor != "" {
+		q["cursor"] = filter.Cursor
+	}
+	resp := artifactList{}
+	request := makeDefaultRequest(&resp).withQueryData(q)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("insight/%s/artifacts", org.client.options.OID), request); err != nil {
+		return ArtifactSearchPage{}, err
+	}
+	page := ArtifactSearchPage{Artifacts: []ArtifactInfo{}}
+	for _, artifact := range resp.Artifacts {
+		if filter.matches(artifact) {
+			page.Artifacts = append(page.Artifacts, artifact)
+		}
+	}
+	if resp.NextCursor != filter.Cursor {
+		page.NextCursor = resp.NextCursor
+	}
+	return page, nil
+}
+
+// ArtifactsSearchAll returns the artifacts of all
+// the pages of ArtifactsSearch from the filter's.
+func (org Organization) ArtifactsSearchAll(filter ArtifactSearchFilter) ([]ArtifactInfo, error) {
+	artifacts := []ArtifactInfo{}
+	for {
+		page, err := org.ArtifactsSearch(filter)
+		if err != nil {
+			return nil, err
+		}
+		artifacts = append(artifacts, page.Artifacts...)
+		if page.NextCursor == "" {
+			return artifacts, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
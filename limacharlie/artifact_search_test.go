@@ -0,0 +1,64 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArtifactsSearch(t *testing.T) {
+	a := assert.New(t)
+	queries := []map[string]string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal(fmt.Sprintf("/v1/insight/%s/artifacts", vcrTestOID), r.URL.Path)
+		q := map[string]string{}
+		for k := range r.URL.Query() {
+			q[k] = r.URL.Query().Get(k)
+		}
+		queries = append(queries, q)
+		if q["cursor"] == "" {
+			return jsonResponse(http.StatusOK, `{"artifacts": [
+				{"payload_id": "a1", "sid": "s1", "type": "file", "original_path": "C:\\Windows\\Temp\\evil.exe"},
+				{"payload_id": "a2", "sid": "s1", "type": "file", "original_path": "C:\\Windows\\Temp\\notes.txt"}
+			], "next_cursor": "c1"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"artifacts": [
+			{"payload_id": "a3", "sid": "s1", "type": "file", "original_path": "c:\\windows\\temp\\other.EXE"}
+		], "next_cursor": "c1"}`), nil
+	}))
+
+	page, err := org.ArtifactsSearch(ArtifactSearchFilter{
+		SensorID: "s1",
+		Hash:     "ABCD",
+		Start:    100,
+		End:      200,
+		Limit:    2,
+	})
+	a.NoError(err)
+	a.Len(page.Artifacts, 2)
+	a.Equal("c1", page.NextCursor)
+	a.Equal(map[string]string{"sid": "s1", "hash": "abcd", "start": "100", "end": "200", "limit": "2"}, queries[0])
+
+	// Wildcards are matched by the SDK, across all the pages.
+	queries = nil
+	artifacts, err := org.ArtifactsSearchAll(ArtifactSearchFilter{
+		Type:         "file",
+		OriginalPath: `c:\windows\temp\*.exe`,
+	})
+	a.NoError(err)
+	ids := []string{}
+	for _, artifact := range artifacts {
+		ids = append(ids, artifact.ID)
+	}
+	a.Equal([]string{"a1", "a3"}, ids)
+	a.Len(queries, 2)
+	a.NotContains(queries[0], "original_path")
+	a.Equal("c1", queries[1]["cursor"])
+
+	queries = nil
+	_, err = org.ArtifactsSearch(ArtifactSearchFilter{OriginalPath: `/etc/passwd`})
+	a.NoError(err)
+	a.Equal("/etc/passwd", queries[0]["original_path"])
+}
@@ -27,7 +27,7 @@ func TestLoggingAddDelete(t *testing.T) {
 		Patterns:       []string{"/var/log.log", "/home/user"},
 		Filters: ArtifactRuleFilter{
 			Tags:      []string{"test-tag0"},
-			Platforms: []string{"windows", "chrome"},
+			Platforms: []Platform{"windows", "chrome"},
 		},
 	}))
 
@@ -0,0 +1,193 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type AttackTechniqueID = string
+
+// AttackCoverage describes which MITRE ATT&CK techniques
+// are covered by the D&R rules of an OrgConfig.
+type AttackCoverage struct {
+	// D&R rules referencing each technique.
+	Techniques map[AttackTechniqueID][]DRRuleName `json:"techniques"`
+	// D&R rules not referencing any technique.
+	UnmappedRules []DRRuleName `json:"unmapped_rules"`
+}
+
+var attackTechniqueRegexp = regexp.MustCompile(`(?i)\bT(\d{4})(\.\d{3})?\b`)
+
+// Metadata fields of the report actions holding ATT&CK technique IDs.
+var attackMetadataFields = []string{"mitre", "attack"}
+
+// Hives containing D&R rules.
+var drRuleHives = []HiveName{"dr-general", "dr-managed", "dr-service"}
+
+// AttackCoverage looks for ATT&CK technique IDs like "T1059" or
+// "attack.t1059.001" in the "mitre" and "attack" metadata of the
+// report actions of the D&R rules in the config and in the tags
+// of the D&R hive records. A rule defined both in "rules" and in
+// a D&R hive is counted once.
+func (o OrgConfig) AttackCoverage() AttackCoverage {
+	coverage := AttackCoverage{
+		Techniques:    map[AttackTechniqueID][]DRRuleName{},
+		UnmappedRules: []DRRuleName{},
+	}
+	rules := map[DRRuleName]map[AttackTechniqueID]struct{}{}
+	techniquesOf := func(ruleName DRRuleName) map[AttackTechniqueID]struct{} {
+		techniques, ok := rules[ruleName]
+		if !ok {
+			techniques = map[AttackTechniqueID]struct{}{}
+			rules[ruleName] = techniques
+		}
+		return techniques
+	}
+	for ruleName, rule := range o.DRRules {
+		findReportAttackTechniques(rule.Response, techniquesOf(ruleName))
+	}
+	for _, hiveName := range drRuleHives {
+		for ruleName, data := range o.Hives[hiveName] {
+			techniques := techniquesOf(ruleName)
+			findReportAttackTechniques(data.Data["respond"], techniques)
+			findAttackTechniques(data.UsrMtd.Tags, techniques)
+		}
+	}
+
+	for ruleName, techniques := range rules {
+		if len(techniques) == 0 {
+			coverage.UnmappedRules = append(coverage.UnmappedRules, ruleName)
+			continue
+		}
+		for t := range techniques {
+			coverage.Techniques[t] = append(coverage.Techniques[t], ruleName)
+		}
+	}
+	for t := range coverage.Techniques {
+		sort.Strings(coverage.Techniques[t])
+	}
+	sort.Strings(coverage.UnmappedRules)
+	return coverage
+}
+
+// findReportAttackTechniques looks for techniques in the
+// ATT&CK metadata fields of the report actions of a respond.
+func findReportAttackTechniques(respond interface{}, out map[AttackTechniqueID]struct{}) {
+	var actions []interface{}
+	switch r := respond.(type) {
+	case []interface{}:
+		actions = r
+	case List:
+		actions = r
+	}
+	for _, a := range actions {
+		action, ok := toRespondDict(a)
+		if !ok || action["action"] != "report" {
+			continue
+		}
+		if metadata, ok := toRespondDict(action["metadata"]); ok {
+			findMetadataAttackTechniques(metadata, out)
+		}
+	}
+}
+
+// findMetadataAttackTechniques looks for techniques in the
+// ATT&CK fields of the metadata of a report or a detection.
+func findMetadataAttackTechniques(metadata Dict, out map[AttackTechniqueID]struct{}) {
+	for _, field := range attackMetadataFields {
+		findAttackTechniques(metadata[field], out)
+	}
+}
+
+func findAttackTechniques(v interface{}, out map[AttackTechniqueID]struct{}) {
+	switch val := v.(type) {
+	case string:
+		for _, m := range attackTechniqueRegexp.FindAllStringSubmatch(val, -1) {
+			out[fmt.Sprintf("T%s%s", m[1], m[2])] = struct{}{}
+		}
+	case []interface{}:
+		for _, e := range val {
+			findAttackTechniques(e, out)
+		}
+	case List:
+		for _, e := range val {
+			findAttackTechniques(e, out)
+		}
+	case []string:
+		for _, e := range val {
+			findAttackTechniques(e, out)
+		}
+	}
+}
+
+// TechniqueIDs returns the sorted list of covered techniques.
+func (c AttackCoverage) TechniqueIDs() []AttackTechniqueID {
+	ids := []AttackTechniqueID{}
+	for t := range c.Techniques {
+		ids = append(ids, t)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (c AttackCoverage) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+type attackNavigatorLayer struct {
+	Name        string                     `json:"name"`
+	Versions    map[string]string          `json:"versions"`
+	Domain      string                     `json:"domain"`
+	Description string                     `json:"description"`
+	Techniques  []attackNavigatorTechnique `json:"techniques"`
+	Gradient    attackNavigatorGradient    `json:"gradient"`
+}
+
+type attackNavigatorTechnique struct {
+	TechniqueID string `json:"techniqueID"`
+	Score       int    `json:"score"`
+	Comment     string `json:"comment"`
+	Enabled     bool   `json:"enabled"`
+}
+
+type attackNavigatorGradient struct {
+	Colors   []string `json:"colors"`
+	MinValue int      `json:"minValue"`
+	MaxValue int      `json:"maxValue"`
+}
+
+// ToNavigatorLayer exports the coverage as an ATT&CK Navigator
+// layer where the score of a technique is the number of rules
+// covering it.
+func (c AttackCoverage) ToNavigatorLayer(name string) ([]byte, error) {
+	layer := attackNavigatorLayer{
+		Name: name,
+		Versions: map[string]string{
+			"layer": "4.5",
+		},
+		Domain:      "enterprise-attack",
+		Description: "D&R rules coverage",
+		Techniques:  []attackNavigatorTechnique{},
+		Gradient: attackNavigatorGradient{
+			Colors:   []string{"#ffffff", "#66b1ff"},
+			MinValue: 0,
+			MaxValue: 1,
+		},
+	}
+	for _, t := range c.TechniqueIDs() {
+		rules := c.Techniques[t]
+		layer.Techniques = append(layer.Techniques, attackNavigatorTechnique{
+			TechniqueID: t,
+			Score:       len(rules),
+			Comment:     strings.Join(rules, ", "),
+			Enabled:     true,
+		})
+		if len(rules) > layer.Gradient.MaxValue {
+			layer.Gradient.MaxValue = len(rules)
+		}
+	}
+	return json.MarshalIndent(layer, "", "  ")
+}
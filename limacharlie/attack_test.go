@@ -0,0 +1,85 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestAttackCoverage(t *testing.T) {
+	a := assert.New(t)
+
+	conf := `
+rules:
+  encoded-powershell:
+    detect:
+      op: contains
+      path: event/COMMAND_LINE
+      value: t1140
+    respond:
+      - action: report
+        name: encoded-powershell
+        metadata:
+          mitre: attack.t1059.001
+          attack:
+            - attack.T1027
+          description: not T1105
+  no-mapping:
+    detect:
+      op: exists
+      path: event/FILE_PATH
+    respond:
+      - action: report
+        name: no-mapping T1105
+        metadata:
+          description: see attack.t1105
+hives:
+  dr-general:
+    obfuscation:
+      data:
+        detect:
+          op: exists
+          path: event/COMMAND_LINE
+        respond:
+          - action: report
+            name: obfuscation
+            metadata:
+              attack: T1027
+      usr_mtd:
+        enabled: true
+        tags:
+          - attack.t1140
+  dr-managed:
+    encoded-powershell:
+      data:
+        detect:
+          op: exists
+          path: event/COMMAND_LINE
+        respond:
+          - action: report
+            name: encoded-powershell
+            metadata:
+              mitre: attack.T1027
+`
+	orgConfig := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(conf), &orgConfig))
+
+	coverage := orgConfig.AttackCoverage()
+	a.Equal([]AttackTechniqueID{"T1027", "T1059.001", "T1140"}, coverage.TechniqueIDs())
+	a.Equal([]DRRuleName{"encoded-powershell", "obfuscation"}, coverage.Techniques["T1027"])
+	a.Equal([]DRRuleName{"encoded-powershell"}, coverage.Techniques["T1059.001"])
+	a.Equal([]DRRuleName{"obfuscation"}, coverage.Techniques["T1140"])
+	a.Equal([]DRRuleName{"no-mapping"}, coverage.UnmappedRules)
+
+	b, err := coverage.ToNavigatorLayer("my rules")
+	a.NoError(err)
+	layer := attackNavigatorLayer{}
+	a.NoError(json.Unmarshal(b, &layer))
+	a.Equal("my rules", layer.Name)
+	a.Len(layer.Techniques, 3)
+	a.Equal("T1027", layer.Techniques[0].TechniqueID)
+	a.Equal(2, layer.Techniques[0].Score)
+	a.Equal(2, layer.Gradient.MaxValue)
+}
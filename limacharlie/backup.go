@@ -0,0 +1,335 @@
+package limacharlie
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	backupFormatVersion  = 1
+	backupManifestFile   = "manifest.json"
+	backupConfigFile     = "config.yaml"
+	backupPayloadsFile   = "payloads.json"
+	backupYaraSourcesDir = "yara-sources"
+	backupPayloadsDir    = "payloads"
+)
+
+// BackupOptions selects what a backup includes.
+type BackupOptions struct {
+	// Sync selects the configuration categories, all of
+	// them along with DefaultBackupHives if nil.
+	Sync *SyncOptions
+
+	// IncludePayloadContent adds the content of the payloads
+	// to the backup, otherwise only their manifest is.
+	IncludePayloadContent bool
+}
+
+// DefaultBackupHives are the hives backed up by default. The
+// secret hive is excluded but backups are still sensitive, they
+// hold the org values and the credentials of the outputs.
+var DefaultBackupHives = []HiveName{
+	"cloud_sensor",
+	"lookup",
+	"extension_config",
+}
+
+// RestoreOptions configures the sync replaying a backup.
+type RestoreOptions struct {
+	// Sync options like IsForce or IsDryRun. The categories
+	// synced are the ones recorded in the backup.
+	Sync SyncOptions
+
+	// SkipPayloads does not upload the payloads of the backup.
+	SkipPayloads bool
+}
+
+// BackupManifest describes the content of a backup.
+type BackupManifest struct {
+	Version    int         `json:"version"`
+	OID        string      `json:"oid"`
+	CreatedAt  int64       `json:"created_at"`
+	Categories SyncOptions `json:"categories"`
+}
+
+// orgBackup is the content of a backup archive.
+type orgBackup struct {
+	Manifest    BackupManifest
+	Config      OrgConfig
+	YaraSources map[YaraSourceName]string
+	Payloads    map[PayloadName]Payload
+	// Content of the payloads, if included.
+	PayloadContent map[PayloadName][]byte
+}
+
+func allSyncOptions() SyncOptions {
+	hives := map[string]bool{}
+	for _, h := range DefaultBackupHives {
+		hives[h] = true
+	}
+	return SyncOptions{
+		SyncDRRules:          true,
+		SyncOutputs:          true,
+		SyncResources:        true,
+		SyncIntegrity:        true,
+		SyncFPRules:          true,
+		SyncExfil:            true,
+		SyncArtifacts:        true,
+		SyncOrgValues:        true,
+		SyncHives:            hives,
+		SyncInstallationKeys: true,
+		SyncYara:             true,
+		SyncExtensions:       true,
+		SyncNetPolicies:      true,
+		SyncRetention:        true,
+		SyncDetectionRouting: true,
+		SyncThreatFeeds:      true,
+	}
+}
+
+// Backup writes a gzipped tar archive of the configuration of the Org
+// along with assets not part of it, like the bodies of the yara sources
+// and the payloads, for Restore to recreate the Org.
+func (org Organization) Backup(w io.Writer, opts ...BackupOptions) error {
+	opt := BackupOptions{}
+	for _, o := range opts {
+		opt = o
+	}
+	categories := allSyncOptions()
+	if opt.Sync != nil {
+		categories = *opt.Sync
+	}
+
+	backup := orgBackup{
+		Manifest: BackupManifest{
+			Version:    backupFormatVersion,
+			OID:        org.client.options.OID,
+			CreatedAt:  time.Now().Unix(),
+			Categories: categories,
+		},
+		YaraSources:    map[YaraSourceName]string{},
+		PayloadContent: map[PayloadName][]byte{},
+	}
+	var err error
+	if backup.Config, err = org.SyncFetch(categories); err != nil {
+		return fmt.Errorf("config: %v", err)
+	}
+
+	if categories.SyncYara && backup.Config.Yara != nil {
+		for name := range backup.Config.Yara.Sources {
+			content, err := org.YaraGetSource(name)
+			if err != nil {
+				return fmt.Errorf("yara source %s: %v", name, err)
+			}
+			backup.YaraSources[name] = content
+		}
+	}
+
+	if backup.Payloads, err = org.Payloads(); err != nil {
+		return fmt.Errorf("payloads: %v", err)
+	}
+	if opt.IncludePayloadContent {
+		for name := range backup.Payloads {
+			content, err := org.Payload(name)
+			if err != nil {
+				return fmt.Errorf("payload %s: %v", name, err)
+			}
+			backup.PayloadContent[name] = content
+		}
+	}
+
+	return writeBackupArchive(w, backup)
+}
+
+// Restore replays a backup made by Backup through SyncPush, then
+// uploads the payloads it contains.
+func (org Organization) Restore(r io.Reader, opts RestoreOptions) ([]OrgSyncOperation, error) {
+	backup, err := readBackupArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	options := opts.Sync
+	categories := backup.Manifest.Categories
+	options.SyncDRRules = categories.SyncDRRules
+	options.SyncOutputs = categories.SyncOutputs
+	options.SyncResources = categories.SyncResources
+	options.SyncIntegrity = categories.SyncIntegrity
+	options.SyncFPRules = categories.SyncFPRules
+	options.SyncExfil = categories.SyncExfil
+	options.SyncArtifacts = categories.SyncArtifacts
+	options.SyncOrgValues = categories.SyncOrgValues
+	options.SyncHives = categories.SyncHives
+	options.SyncInstallationKeys = categories.SyncInstallationKeys
+	options.SyncYara = categories.SyncYara
+	options.SyncExtensions = categories.SyncExtensions
+	options.SyncNetPolicies = categories.SyncNetPolicies
+	options.SyncRetention = categories.SyncRetention
+	options.SyncDetectionRouting = categories.SyncDetectionRouting
+	options.SyncThreatFeeds = categories.SyncThreatFeeds
+
+	// The bodies of the sources are not part of the config
+	// fetched, only of the sources not fetched from a URL.
+	if backup.Config.Yara != nil {
+		for name, content := range backup.YaraSources {
+			source, ok := backup.Config.Yara.Sources[name]
+			if !ok || source.Source != "" {
+				continue
+			}
+			source.Content = content
+			backup.Config.Yara.Sources[name] = source
+		}
+	}
+
+	ops, err := org.SyncPush(backup.Config, options)
+	if err != nil || opts.SkipPayloads {
+		return ops, err
+	}
+
+	names := []PayloadName{}
+	for name := range backup.PayloadContent {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.Payload,
+			ElementName: name,
+			IsAdded:     true,
+		}
+		if options.IsDryRun {
+			ops = options.appendOp(ops, op)
+			continue
+		}
+		if err := org.CreatePayloadFromBytes(name, backup.PayloadContent[name]); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
+	if options.ContinueOnError {
+		return ops, syncOpsError(ops)
+	}
+	return ops, nil
+}
+
+func writeBackupArchive(w io.Writer, backup orgBackup) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	modTime := time.Unix(backup.Manifest.CreatedAt, 0)
+	addFile := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: modTime,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(backup.Manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addFile(backupManifestFile, manifest); err != nil {
+		return err
+	}
+	config, err := yaml.Marshal(backup.Config)
+	if err != nil {
+		return err
+	}
+	if err := addFile(backupConfigFile, config); err != nil {
+		return err
+	}
+	payloads, err := json.MarshalIndent(backup.Payloads, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addFile(backupPayloadsFile, payloads); err != nil {
+		return err
+	}
+	for name, content := range backup.YaraSources {
+		if err := addFile(path.Join(backupYaraSourcesDir, name), []byte(content)); err != nil {
+			return err
+		}
+	}
+	for name, content := range backup.PayloadContent {
+		if err := addFile(path.Join(backupPayloadsDir, name), content); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func readBackupArchive(r io.Reader) (orgBackup, error) {
+	backup := orgBackup{
+		YaraSources:    map[YaraSourceName]string{},
+		Payloads:       map[PayloadName]Payload{},
+		PayloadContent: map[PayloadName][]byte{},
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return backup, fmt.Errorf("invalid backup: %v", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+	hasManifest := false
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return backup, fmt.Errorf("invalid backup: %v", err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return backup, err
+		}
+		dir, name := path.Split(h.Name)
+		switch {
+		case h.Name == backupManifestFile:
+			if err := json.Unmarshal(data, &backup.Manifest); err != nil {
+				return backup, fmt.Errorf("invalid manifest: %v", err)
+			}
+			hasManifest = true
+		case h.Name == backupConfigFile:
+			if err := yaml.Unmarshal(data, &backup.Config); err != nil {
+				return backup, fmt.Errorf("invalid config: %v", err)
+			}
+		case h.Name == backupPayloadsFile:
+			if err := json.Unmarshal(data, &backup.Payloads); err != nil {
+				return backup, fmt.Errorf("invalid payloads: %v", err)
+			}
+		case dir == backupYaraSourcesDir+"/":
+			backup.YaraSources[name] = string(data)
+		case dir == backupPayloadsDir+"/":
+			backup.PayloadContent[name] = data
+		}
+	}
+	if !hasManifest {
+		return backup, fmt.Errorf("invalid backup: missing %s", backupManifestFile)
+	}
+	if backup.Manifest.Version > backupFormatVersion {
+		return backup, fmt.Errorf("unsupported backup version: %d", backup.Manifest.Version)
+	}
+	return backup, nil
+}
@@ -0,0 +1,97 @@
+package limacharlie
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupArchive(t *testing.T) {
+	a := assert.New(t)
+	isEnabled := true
+	backup := orgBackup{
+		Manifest: BackupManifest{
+			Version:    backupFormatVersion,
+			OID:        "oid",
+			CreatedAt:  1700000000,
+			Categories: SyncOptions{SyncDRRules: true, SyncHives: map[string]bool{"lookup": true}},
+		},
+		Config: OrgConfig{
+			DRRules: orgSyncDRRules{
+				"r1": {Detect: Dict{"op": "is"}, Response: List{Dict{"action": "report"}}, IsEnabled: &isEnabled},
+			},
+		},
+		YaraSources:    map[YaraSourceName]string{"src": "rule x {}"},
+		Payloads:       map[PayloadName]Payload{"p.exe": {Name: "p.exe", Size: 3}},
+		PayloadContent: map[PayloadName][]byte{"p.exe": []byte("abc")},
+	}
+
+	b := bytes.Buffer{}
+	a.NoError(writeBackupArchive(&b, backup))
+	restored, err := readBackupArchive(&b)
+	a.NoError(err)
+	a.Equal(backup.Manifest, restored.Manifest)
+	a.Equal(backup.YaraSources, restored.YaraSources)
+	a.Equal(backup.Payloads, restored.Payloads)
+	a.Equal(backup.PayloadContent, restored.PayloadContent)
+	a.True(backup.Config.DRRules["r1"].Equal(restored.Config.DRRules["r1"]))
+
+	_, err = readBackupArchive(bytes.NewReader([]byte("not a backup")))
+	a.Error(err)
+
+	backup.Manifest.Version = backupFormatVersion + 1
+	b.Reset()
+	a.NoError(writeBackupArchive(&b, backup))
+	_, err = readBackupArchive(&b)
+	a.Error(err)
+}
+
+func TestRestoreYaraSources(t *testing.T) {
+	a := assert.New(t)
+	backup := orgBackup{
+		Manifest: BackupManifest{
+			Version:    backupFormatVersion,
+			OID:        vcrTestOID,
+			Categories: SyncOptions{SyncYara: true},
+		},
+		Config: OrgConfig{
+			Yara: &orgSyncYara{
+				Sources: map[YaraSourceName]YaraSource{
+					"local":  {},
+					"remote": {Source: "https://example.com/rules.yar"},
+				},
+			},
+		},
+		YaraSources: map[YaraSourceName]string{
+			"local":  "rule local {}",
+			"remote": "rule remote {}",
+		},
+	}
+	b := bytes.Buffer{}
+	a.NoError(writeBackupArchive(&b, backup))
+
+	added := map[YaraSourceName]Dict{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["yara.get","yara.set"]}`), nil
+		}
+		a.NoError(r.ParseForm())
+		data, err := base64.StdEncoding.DecodeString(r.PostForm.Get("request_data"))
+		a.NoError(err)
+		req := Dict{}
+		a.NoError(json.Unmarshal(data, &req))
+		if req["action"] == "add_source" {
+			added[req["name"].(string)] = req
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+	_, err := org.Restore(&b, RestoreOptions{SkipPayloads: true})
+	a.NoError(err)
+	a.Equal("rule local {}", added["local"]["content"])
+	a.Equal("", added["remote"]["content"])
+	a.Equal("https://example.com/rules.yar", added["remote"]["source"])
+}
@@ -0,0 +1,64 @@
+package limacharlie
+
+import (
+	"sync"
+	"time"
+)
+
+// ResponseCache stores the raw responses of read-only API calls.
+// Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	Get(key string) ([]byte, bool)
+	Set(key string, value []byte, ttl time.Duration)
+	// Clear removes all the entries, it is called
+	// after any request modifying an Org.
+	Clear()
+}
+
+type memoryCacheEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryResponseCache is an in-memory ResponseCache.
+type MemoryResponseCache struct {
+	mutex   sync.Mutex
+	entries map[string]memoryCacheEntry
+	now     func() time.Time
+}
+
+func NewMemoryResponseCache() *MemoryResponseCache {
+	return &MemoryResponseCache{
+		entries: map[string]memoryCacheEntry{},
+		now:     time.Now,
+	}
+}
+
+func (c *MemoryResponseCache) Get(key string) ([]byte, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().After(e.expiresAt) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (c *MemoryResponseCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[key] = memoryCacheEntry{
+		value:     value,
+		expiresAt: c.now().Add(ttl),
+	}
+}
+
+func (c *MemoryResponseCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = map[string]memoryCacheEntry{}
+}
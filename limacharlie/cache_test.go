@@ -0,0 +1,44 @@
+package limacharlie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryResponseCache(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	c := NewMemoryResponseCache()
+	c.now = func() time.Time { return now }
+
+	c.Set("k1", []byte(`{"a":1}`), time.Minute)
+	v, ok := c.Get("k1")
+	a.True(ok)
+	a.Equal([]byte(`{"a":1}`), v)
+
+	now = now.Add(2 * time.Minute)
+	_, ok = c.Get("k1")
+	a.False(ok)
+
+	c.Set("k2", []byte(`{}`), time.Minute)
+	c.Clear()
+	_, ok = c.Get("k2")
+	a.False(ok)
+}
+
+func TestClientCacheKey(t *testing.T) {
+	a := assert.New(t)
+
+	c1 := &Client{options: ClientOptions{OID: "o1", APIKey: "k1"}}
+	c2 := &Client{options: ClientOptions{OID: "o1", APIKey: "k2"}}
+	a.Equal(c1.cacheKey("/v1/", "who", ""), c1.cacheKey("/v1/", "who", ""))
+	a.NotEqual(c1.cacheKey("/v1/", "who", ""), c2.cacheKey("/v1/", "who", ""))
+	a.NotEqual(c1.cacheKey("/v1/", "sensors/o1", "continuation_token=a"), c1.cacheKey("/v1/", "sensors/o1", ""))
+
+	resp := Dict{}
+	a.NoError(unmarshalResponse([]byte(`{"n":12345678901}`), &resp))
+	a.Equal(int64(12345678901), resp["n"])
+}
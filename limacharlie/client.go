@@ -2,7 +2,10 @@ package limacharlie
 
 import (
 	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -18,9 +21,7 @@ import (
 )
 
 const (
-	rootURL           = "https://api.limacharlie.io"
 	currentAPIVersion = "v1"
-	getJWTURL         = "https://jwt.limacharlie.io"
 
 	defaultConfigFileLocation = "~/.limacharlie"
 	environmentNameEnvVar     = "LC_CURRENT_ENV"
@@ -29,6 +30,8 @@ const (
 	keyEnvVar                 = "LC_API_KEY"
 	credsEnvVar               = "LC_CREDS_FILE"
 
+	idempotencyKeyHeader = "Idempotency-Key"
+
 	restRetries          = 3
 	restTimeout          = 5 * time.Second
 	restCreateOrgTimeout = 35 * time.Second
@@ -38,6 +41,17 @@ const (
 type Client struct {
 	options ClientOptions
 	logger  LCLogger
+
+	// onRequestError, if set, is called for every failed
+	// attempt of a request, see withRequestErrorHook.
+	onRequestError func(statusCode int, isRetried bool)
+
+	// transport of the requests honoring the proxy
+	// and TLS options, nil without any.
+	transport http.RoundTripper
+
+	// limiter of the requests, nil without ClientOptions.RateLimit.
+	limiter *rateLimiter
 }
 
 // ClientOptions holds all options for Client
@@ -49,6 +63,62 @@ type ClientOptions struct {
 	Environment   string
 	Permissions   []string
 	JWTExpiryTime time.Duration
+
+	// OAuth, if set, provides the identity token of a user exchanged
+	// for JWTs instead of an API key, like with an OIDCTokenSource,
+	// for tools acting on behalf of the user in all their Orgs.
+	OAuth OAuthTokenSource
+
+	// CompressionThreshold is the size in bytes above which form
+	// request bodies, like large lookups and yara sources, are gzip
+	// compressed as they are sent, 0 disables compression. The form
+	// itself is still encoded in memory, payloads are streamed with
+	// CreatePayloadFromReader and PayloadToWriter instead.
+	CompressionThreshold int
+
+	// Cache, if set, stores the responses of read-heavy endpoints
+	// like Resources(), sensor lists and who-am-i for CacheTTL.
+	Cache    ResponseCache
+	CacheTTL time.Duration
+
+	// Transport, if set, performs the HTTP requests of the client,
+	// like a VCRTransport replaying recorded fixtures in tests.
+	Transport http.RoundTripper
+
+	// DisableIdempotencyKeys stops sending an Idempotency-Key header
+	// with mutating requests. The key is the same across the retries
+	// of a request so the API does not apply a change twice when a
+	// response is lost, like creating an output twice.
+	DisableIdempotencyKeys bool
+
+	// Endpoints the client talks to, the LimaCharlie cloud by
+	// default. EndpointPreset names EndpointPresets to use instead
+	// when Endpoints are not set.
+	Endpoints      Endpoints
+	EndpointPreset string
+
+	// ProxyURL, if set, is the proxy the requests go through, like
+	// "http://proxy.corp:3128", instead of the one of the HTTPS_PROXY
+	// and NO_PROXY environment variables.
+	ProxyURL string
+	// CABundlePath is a PEM file of certificates trusted in addition
+	// to the system ones, like the one of an inspecting proxy.
+	CABundlePath string
+	// ClientCertPath and ClientKeyPath are PEM files of the
+	// certificate the client authenticates with, if required.
+	ClientCertPath string
+	ClientKeyPath  string
+	// TLSMinVersion is the minimum TLS version
+	// accepted, like tls.VersionTLS12.
+	TLSMinVersion uint16
+
+	// RateLimit, if set, is the maximum number of API requests per
+	// second, retries included, shared by all the goroutines using
+	// the client and the clients of other Orgs derived from it, like
+	// by an OrgSet. Up to RateLimitBurst requests, RateLimit by
+	// default, are sent at once.
+	RateLimit      float64
+	RateLimitBurst int
 }
 
 type jwtResponse struct {
@@ -62,6 +132,18 @@ type restRequest struct {
 	formData  interface{}
 	response  interface{}
 	urlRoot   string
+
+	// If set, the response body is streamed to it
+	// instead of being unmarshaled into response.
+	responseWriter io.Writer
+
+	// If set, the response may be served from
+	// and stored in the client's cache.
+	isCacheable bool
+
+	// Sent as the Idempotency-Key header, generated
+	// for mutating requests if not set.
+	idempotencyKey string
 }
 
 func makeDefaultRequest(response interface{}) restRequest {
@@ -88,6 +170,21 @@ func (r restRequest) withQueryData(queryData interface{}) restRequest {
 	return r
 }
 
+func (r restRequest) withResponseWriter(w io.Writer) restRequest {
+	r.responseWriter = w
+	return r
+}
+
+func (r restRequest) withCache() restRequest {
+	r.isCacheable = true
+	return r
+}
+
+func (r restRequest) withIdempotencyKey(key string) restRequest {
+	r.idempotencyKey = key
+	return r
+}
+
 func (r restRequest) withURLRoot(root string) restRequest {
 	r.urlRoot = root
 	return r
@@ -101,7 +198,7 @@ func isEmpty(s string) bool {
 // Will return a valid client as soon as one loader returns valid requirements
 func NewClientFromLoader(inOpt ClientOptions, logger LCLogger, optsLoaders ...ClientOptionLoader) (*Client, error) {
 	if inOpt.validateMinimumRequirements() == nil && inOpt.validate() == nil {
-		return &Client{options: inOpt, logger: logger}, nil
+		return newClientWithOptions(inOpt, logger)
 	}
 
 	loaderCount := len(optsLoaders)
@@ -127,20 +224,22 @@ func NewClientFromLoader(inOpt ClientOptions, logger LCLogger, optsLoaders ...Cl
 		return nil, err
 	}
 
-	return &Client{
-		options: opt,
-		logger:  logger,
-	}, nil
+	return newClientWithOptions(opt, logger)
 }
 
 // NewClient loads client options from
 // first, environment varibles;
 // then from a file specified by the environment variable LC_CREDS_FILE;
-// then from .limacharlie in home directory
+// then from .limacharlie in home directory.
+// The environment of the files, if not set, is the one of LC_CURRENT_ENV,
+// like for the Python SDK/CLI.
 func NewClient(opt ClientOptions, logger LCLogger) (*Client, error) {
 	if logger == nil {
 		logger = &LCLoggerEmpty{}
 	}
+	if isEmpty(opt.Environment) {
+		opt.Environment = os.Getenv(environmentNameEnvVar)
+	}
 	return NewClientFromLoader(opt,
 		logger,
 		&EnvironmentClientOptionLoader{},
@@ -160,11 +259,18 @@ func validateUUID(s string) error {
 }
 
 func (c *Client) RefreshJWT(expiry time.Duration) (string, error) {
-	if c.options.APIKey == "" {
+	authData := url.Values{}
+	if c.options.APIKey != "" {
+		authData.Set("secret", c.options.APIKey)
+	} else if c.options.OAuth != nil {
+		token, err := c.options.OAuth.IDToken()
+		if err != nil {
+			return "", fmt.Errorf("oauth: %v", err)
+		}
+		authData.Set("fb_auth", token)
+	} else {
 		return "", ErrorNoAPIKeyConfigured
 	}
-	authData := url.Values{}
-	authData.Set("secret", c.options.APIKey)
 	if c.options.UID != "" {
 		authData.Set("uid", c.options.UID)
 	}
@@ -178,7 +284,7 @@ func (c *Client) RefreshJWT(expiry time.Duration) (string, error) {
 		authData.Set("perms", strings.Join(c.options.Permissions, ","))
 	}
 
-	r, err := http.NewRequest(http.MethodPost, getJWTURL, strings.NewReader(authData.Encode()))
+	r, err := http.NewRequest(http.MethodPost, c.endpoints().JWT, strings.NewReader(authData.Encode()))
 	if err != nil {
 		return "", err
 	}
@@ -186,7 +292,7 @@ func (c *Client) RefreshJWT(expiry time.Duration) (string, error) {
 	r.Header.Set("User-Agent", "limacharlie-sdk")
 	r.Header.Add("Content-Type", "application/x-www-form-urlencoded")
 
-	resp, err := getHTTPClient(10 * time.Second).Do(r)
+	resp, err := c.httpClient(10 * time.Second).Do(r)
 	if err != nil {
 		return "", err
 	}
@@ -219,43 +325,103 @@ func getHTTPClient(timeout time.Duration) *http.Client {
 				Timeout: 10 * time.Second,
 			}).Dial,
 			TLSHandshakeTimeout: 10 * time.Second,
+			Proxy:               http.ProxyFromEnvironment,
 		},
 	}
 }
 
+func (c *Client) httpClient(timeout time.Duration) *http.Client {
+	hc := getHTTPClient(timeout)
+	if c.transport != nil {
+		hc.Transport = c.transport
+	}
+	if c.options.Transport != nil {
+		hc.Transport = c.options.Transport
+	}
+	return hc
+}
+
 func (c *Client) reliableRequest(verb string, path string, request restRequest) (err error) {
+	if request.idempotencyKey == "" && !c.options.DisableIdempotencyKeys && isMutatingVerb(verb) {
+		// Generated once so that all the retries share it.
+		request.idempotencyKey = uuid.New().String()
+	}
 	request.nRetries++
-	for request.nRetries > 0 {
+	for attempt := 1; request.nRetries > 0; attempt++ {
 		var statusCode int
+		c.limiter.wait()
+		start := time.Now()
 		statusCode, err = c.request(verb, path, request)
+		fields := map[string]interface{}{
+			"verb":     verb,
+			"path":     path,
+			"status":   statusCode,
+			"duration": time.Since(start).String(),
+			"attempt":  attempt,
+		}
+		logWithFields(c.logger, LogLevels.Debug, "api request", fields)
 		if err == nil && statusCode == http.StatusOK {
 			break
 		}
+		if request.responseWriter != nil && statusCode == http.StatusOK {
+			// Part of the response may already have been
+			// streamed, retrying would duplicate it.
+			break
+		}
 		request.nRetries--
+		isRetrying := request.nRetries > 0
+		if err != nil {
+			fields["error"] = err.Error()
+		}
 
 		if statusCode == http.StatusUnauthorized {
 			// Unauthorized, the JWT may have expired, refresh
 			// it and retry.
+			logWithFields(c.logger, LogLevels.Debug, "refreshing jwt", fields)
 			if _, err = c.RefreshJWT(c.options.JWTExpiryTime); err != nil {
 				// If we cannot get a new JWT there is no point in
 				// retrying with bad creds.
+				c.observeRequestError(statusCode, false)
 				return err
 			}
 		} else if statusCode == http.StatusTooManyRequests {
 			// Out of quota, wait a bit and retry.
+			fields["wait"] = (10 * time.Second).String()
+			logWithFields(c.logger, LogLevels.Warn, "rate limited", fields)
 			time.Sleep(10 * time.Second)
 		} else if statusCode == http.StatusGatewayTimeout {
 			// Looks like the API might be under load.
+			fields["wait"] = (5 * time.Second).String()
+			logWithFields(c.logger, LogLevels.Warn, "api gateway timeout", fields)
 			time.Sleep(5 * time.Second)
 		} else if err == nil {
 			// If no errors, any other status code other than those
 			// above will not be retried.
+			c.observeRequestError(statusCode, false)
 			break
 		}
+		c.observeRequestError(statusCode, isRetrying)
+		if isRetrying {
+			logWithFields(c.logger, LogLevels.Warn, "retrying api request", fields)
+		}
 	}
 	return err
 }
 
+// withRequestErrorHook returns a copy of the client calling cb for
+// every failed attempt of its requests, leaving the client untouched.
+func (c *Client) withRequestErrorHook(cb func(statusCode int, isRetried bool)) *Client {
+	cp := *c
+	cp.onRequestError = cb
+	return &cp
+}
+
+func (c *Client) observeRequestError(statusCode int, isRetried bool) {
+	if c.onRequestError != nil {
+		c.onRequestError(statusCode, isRetried)
+	}
+}
+
 func (c *Client) serviceRequest(responseData interface{}, serviceName string, serviceData Dict, isAsync bool) error {
 	bytes, err := json.Marshal(serviceData)
 	if err != nil {
@@ -263,13 +429,29 @@ func (c *Client) serviceRequest(responseData interface{}, serviceName string, se
 	}
 	encodedData := base64.StdEncoding.EncodeToString(bytes)
 
-	req := makeDefaultRequest(responseData).withFormData(Dict{
+	form := Dict{
 		"request_data": encodedData,
 		"is_async":     isAsync,
-	})
+	}
+	req := makeDefaultRequest(responseData)
+	if !c.options.DisableIdempotencyKeys {
+		// Services deduplicate requests with the key from the form.
+		key := uuid.New().String()
+		form["idempotency_key"] = key
+		req = req.withIdempotencyKey(key)
+	}
+	req = req.withFormData(form)
 	return c.reliableRequest(http.MethodPost, fmt.Sprintf("service/%s/%s", c.options.OID, serviceName), req)
 }
 
+func isMutatingVerb(verb string) bool {
+	switch verb {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
 func getStringKV(d interface{}) (*url.Values, error) {
 	b, err := json.Marshal(d)
 	if err != nil {
@@ -323,25 +505,39 @@ func (c *Client) request(verb string, path string, request restRequest) (int, er
 	var body io.Reader
 	rawQuery := ""
 
-	fData, err := getStringKV(request.formData)
+	qData, err := getStringKV(request.queryData)
 	if err != nil {
 		return 0, err
 	}
+	if qData != nil {
+		rawQuery = qData.Encode()
+	}
 
-	if fData != nil {
-		body = strings.NewReader(fData.Encode())
-		headers["Content-Type"] = "application/x-www-form-urlencoded"
+	cacheKey := ""
+	if c.options.Cache != nil && c.options.CacheTTL > 0 && request.isCacheable && verb == http.MethodGet && request.responseWriter == nil {
+		cacheKey = c.cacheKey(request.urlRoot, path, rawQuery)
+		if data, ok := c.options.Cache.Get(cacheKey); ok {
+			return http.StatusOK, unmarshalResponse(data, request.response)
+		}
 	}
 
-	qData, err := getStringKV(request.queryData)
+	fData, err := getStringKV(request.formData)
 	if err != nil {
 		return 0, err
 	}
-	if qData != nil {
-		rawQuery = qData.Encode()
+
+	if fData != nil {
+		encoded := fData.Encode()
+		headers["Content-Type"] = "application/x-www-form-urlencoded"
+		if c.options.CompressionThreshold > 0 && len(encoded) >= c.options.CompressionThreshold {
+			body = gzipReader(strings.NewReader(encoded))
+			headers["Content-Encoding"] = "gzip"
+		} else {
+			body = strings.NewReader(encoded)
+		}
 	}
 
-	r, err := http.NewRequest(verb, fmt.Sprintf("%s%s%s", rootURL, request.urlRoot, path), body)
+	r, err := http.NewRequest(verb, fmt.Sprintf("%s%s%s", c.endpoints().APIRoot, request.urlRoot, path), body)
 	if err != nil {
 		return 0, err
 	}
@@ -351,12 +547,15 @@ func (c *Client) request(verb string, path string, request restRequest) (int, er
 	for k, v := range headers {
 		r.Header.Set(k, v)
 	}
+	if request.idempotencyKey != "" && !c.options.DisableIdempotencyKeys {
+		r.Header.Set(idempotencyKeyHeader, request.idempotencyKey)
+	}
 
 	if rawQuery != "" {
 		r.URL.RawQuery = rawQuery
 	}
 
-	resp, err := getHTTPClient(request.timeout).Do(r)
+	resp, err := c.httpClient(request.timeout).Do(r)
 	if err != nil {
 		return 0, err
 	}
@@ -371,36 +570,107 @@ func (c *Client) request(verb string, path string, request restRequest) (int, er
 		return resp.StatusCode, NewRESTError(fmt.Sprintf("%s: %s", resp.Status, errorStr))
 	}
 
+	if request.responseWriter != nil {
+		_, err := io.Copy(request.responseWriter, resp.Body)
+		return resp.StatusCode, err
+	}
+
+	if c.options.Cache == nil {
+		// Decode the response as it is read rather than buffering
+		// it first, large listings like the rules of an Org making
+		// for most of the memory used otherwise.
+		if err := decodeResponse(resp.Body, request.response); err != nil {
+			return resp.StatusCode, err
+		}
+		return resp.StatusCode, nil
+	}
+
 	respData := bytes.Buffer{}
 	if _, err := io.Copy(&respData, resp.Body); err != nil {
 		return resp.StatusCode, err
 	}
 
+	if err := unmarshalResponse(respData.Bytes(), request.response); err != nil {
+		return resp.StatusCode, err
+	}
+	if c.options.Cache != nil {
+		if cacheKey != "" {
+			c.options.Cache.Set(cacheKey, respData.Bytes(), c.options.CacheTTL)
+		} else if verb != http.MethodGet {
+			// The Org may have changed.
+			c.options.Cache.Clear()
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func unmarshalResponse(data []byte, response interface{}) error {
 	// If the response is not a well structured
 	// datatype (and is a map[]interface{} instead)
 	// we will perform a CleanUnmarshal to better
 	// interpret large integers to int64 whenever
 	// possible instead of the json's default float64.
-	if originalResponse, ok := request.response.(*map[string]interface{}); ok {
-		tmpResp, err := UnmarshalCleanJSON(respData.String())
+	if originalResponse, ok := response.(*map[string]interface{}); ok {
+		tmpResp, err := UnmarshalCleanJSON(string(data))
 		if err != nil {
-			return resp.StatusCode, fmt.Errorf("error parsing response: %v", err)
+			return fmt.Errorf("error parsing response: %v", err)
 		}
 		for k, v := range tmpResp {
 			(*originalResponse)[k] = v
 		}
-		return resp.StatusCode, nil
+		return nil
 	}
 
 	// Looks like it is not a map[string]interface{}, let json do its thing.
-	if err := json.Unmarshal(respData.Bytes(), request.response); err != nil {
-		return resp.StatusCode, fmt.Errorf("error parsing response: %v", err)
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
 	}
-	return resp.StatusCode, nil
+	return nil
+}
+
+// decodeResponse is unmarshalResponse streaming the response
+// from r, unless it is a map[string]interface{} needing the
+// whole of it to be cleaned up.
+func decodeResponse(r io.Reader, response interface{}) error {
+	if _, ok := response.(*map[string]interface{}); ok {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return unmarshalResponse(data, response)
+	}
+	if err := json.NewDecoder(r).Decode(response); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+	return nil
+}
+
+// cacheKey identifies a request along with the
+// credentials used since responses depend on them.
+func (c *Client) cacheKey(urlRoot string, path string, rawQuery string) string {
+	creds := sha256.Sum256([]byte(fmt.Sprintf("%s/%s/%s", c.options.OID, c.options.UID, c.options.APIKey)))
+	return fmt.Sprintf("%s %s%s?%s", hex.EncodeToString(creds[:8]), urlRoot, path, rawQuery)
+}
+
+// gzipReader compresses r as it is read, the compressed body
+// is sent as it is produced instead of being held in memory.
+// The request closes the returned reader once sent or failed,
+// which stops the compression.
+func gzipReader(r io.Reader) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		w := gzip.NewWriter(pw)
+		_, err := io.Copy(w, r)
+		if err == nil {
+			err = w.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
 }
 
 type whoAmIJsonResponse struct {
-	UserPermissions *map[string][]string `json:"user_perms:omitempty"`
+	UserPermissions *map[string][]string `json:"user_perms,omitempty"`
 	Organizations   *[]string            `json:"orgs"`
 	Permissions     *[]string            `json:"perms"`
 	Identity        *string              `json:"ident"`
@@ -411,12 +681,86 @@ type GenericJSON = map[string]interface{}
 
 func (c *Client) whoAmI() (whoAmIJsonResponse, error) {
 	who := whoAmIJsonResponse{}
-	if err := c.reliableRequest(http.MethodGet, "who", makeDefaultRequest(&who)); err != nil {
+	if err := c.reliableRequest(http.MethodGet, "who", makeDefaultRequest(&who).withCache()); err != nil {
 		return whoAmIJsonResponse{}, err
 	}
 	return who, nil
 }
 
+// WhoAmI describes the credentials used by a Client.
+type WhoAmI struct {
+	Identity string `json:"ident"`
+	// Organizations accessible along with Permissions,
+	// for credentials with the same permissions in all of them.
+	Organizations []string `json:"orgs,omitempty"`
+	Permissions   []string `json:"perms,omitempty"`
+	// UserPermissions are the permissions per OID,
+	// for credentials like user's with different ones.
+	UserPermissions map[string][]string `json:"user_perms,omitempty"`
+}
+
+// WhoAmI returns the identity and permissions of the credentials in use.
+func (c *Client) WhoAmI() (WhoAmI, error) {
+	who, err := c.whoAmI()
+	if err != nil {
+		return WhoAmI{}, err
+	}
+	return who.toWhoAmI(), nil
+}
+
+func (w whoAmIJsonResponse) toWhoAmI() WhoAmI {
+	who := WhoAmI{}
+	if w.Identity != nil {
+		who.Identity = *w.Identity
+	}
+	if w.Organizations != nil {
+		who.Organizations = *w.Organizations
+	}
+	if w.Permissions != nil {
+		who.Permissions = *w.Permissions
+	}
+	if w.UserPermissions != nil {
+		who.UserPermissions = *w.UserPermissions
+	}
+	return who
+}
+
+// HasAccessToOrg returns true if the credentials can access the Org.
+func (w WhoAmI) HasAccessToOrg(oid string) bool {
+	if _, ok := w.UserPermissions[oid]; ok {
+		return true
+	}
+	return arrayExistsInString(oid, w.Organizations)
+}
+
+// EffectivePermissions returns the permissions granted in the Org.
+func (w WhoAmI) EffectivePermissions(oid string) []string {
+	if perms, ok := w.UserPermissions[oid]; ok {
+		return perms
+	}
+	if arrayExistsInString(oid, w.Organizations) {
+		return w.Permissions
+	}
+	return []string{}
+}
+
+// HasPermission returns true if the permission is granted in the Org.
+func (w WhoAmI) HasPermission(oid string, permName string) bool {
+	return arrayExistsInString(permName, w.EffectivePermissions(oid))
+}
+
+// MissingPermissions returns the permissions not granted in the Org.
+func (w WhoAmI) MissingPermissions(oid string, permNames []string) []string {
+	effective := w.EffectivePermissions(oid)
+	missing := []string{}
+	for _, p := range permNames {
+		if !arrayExistsInString(p, effective) {
+			missing = append(missing, p)
+		}
+	}
+	return missing
+}
+
 // GetCurrentJWT returns the JWT from the client options
 func (c *Client) GetCurrentJWT() string {
 	return c.options.JWT
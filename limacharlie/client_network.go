@@ -0,0 +1,102 @@
+package limacharlie
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// hasNetworkOptions returns whether the options
+// change how the client connects to the API.
+func (o ClientOptions) hasNetworkOptions() bool {
+	return o.ProxyURL != "" || o.CABundlePath != "" || o.ClientCertPath != "" || o.ClientKeyPath != "" || o.TLSMinVersion != 0
+}
+
+// tlsConfig returns the TLS configuration of the connections
+// to the API, nil if the options do not change it.
+func (o ClientOptions) tlsConfig() (*tls.Config, error) {
+	if o.CABundlePath == "" && o.ClientCertPath == "" && o.ClientKeyPath == "" && o.TLSMinVersion == 0 {
+		return nil, nil
+	}
+	conf := &tls.Config{MinVersion: o.TLSMinVersion}
+	if o.CABundlePath != "" {
+		pem, err := ioutil.ReadFile(o.CABundlePath)
+		if err != nil {
+			return nil, NewInvalidClientOptionsError(fmt.Sprintf("invalid CABundlePath: %v", err))
+		}
+		// The bundle is trusted in addition to the system roots, so
+		// the API stays reachable with or without an inspecting proxy.
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, NewInvalidClientOptionsError(fmt.Sprintf("no certificates found in CABundlePath %s", o.CABundlePath))
+		}
+		conf.RootCAs = pool
+	}
+	if o.ClientCertPath != "" || o.ClientKeyPath != "" {
+		if o.ClientCertPath == "" || o.ClientKeyPath == "" {
+			return nil, NewInvalidClientOptionsError("ClientCertPath and ClientKeyPath must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(o.ClientCertPath, o.ClientKeyPath)
+		if err != nil {
+			return nil, NewInvalidClientOptionsError(fmt.Sprintf("invalid client certificate: %v", err))
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// networkTransport returns the transport of the requests to
+// the API honoring the proxy and TLS options, nil without any.
+func (o ClientOptions) networkTransport() (*http.Transport, error) {
+	if !o.hasNetworkOptions() {
+		return nil, nil
+	}
+	tlsConf, err := o.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	t := &http.Transport{
+		Dial: (&net.Dialer{
+			Timeout: 10 * time.Second,
+		}).Dial,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig:     tlsConf,
+		Proxy:               http.ProxyFromEnvironment,
+	}
+	if o.ProxyURL != "" {
+		proxy, err := url.Parse(o.ProxyURL)
+		if err != nil || proxy.Host == "" {
+			return nil, NewInvalidClientOptionsError(fmt.Sprintf("invalid ProxyURL: %s", o.ProxyURL))
+		}
+		t.Proxy = http.ProxyURL(proxy)
+	}
+	return t, nil
+}
+
+// newClientWithOptions creates a client, with the
+// transport of its network options if any.
+func newClientWithOptions(opt ClientOptions, logger LCLogger) (*Client, error) {
+	t, err := opt.networkTransport()
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		options: opt,
+		logger:  logger,
+	}
+	if t != nil {
+		c.transport = t
+	}
+	if opt.RateLimit > 0 {
+		c.limiter = newRateLimiter(opt.RateLimit, opt.RateLimitBurst)
+	}
+	return c, nil
+}
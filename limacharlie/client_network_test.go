@@ -0,0 +1,73 @@
+package limacharlie
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientNetworkOptions(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+
+	api := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"url": {"ingestion": "ingest.lc.example.com"}}`))
+	}))
+	defer api.Close()
+	caPath := filepath.Join(dir, "ca.pem")
+	a.NoError(ioutil.WriteFile(caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: api.Certificate().Raw}), 0600))
+
+	opts := ClientOptions{OID: vcrTestOID, JWT: "jwt", Endpoints: Endpoints{APIRoot: api.URL}}
+	c, err := NewClientFromLoader(opts, &LCLoggerEmpty{})
+	a.NoError(err)
+	org, _ := NewOrganization(c)
+	_, err = org.GetURLs()
+	a.Error(err, "the certificate of the server is not trusted")
+
+	opts.CABundlePath = caPath
+	opts.TLSMinVersion = tls.VersionTLS12
+	c, err = NewClientFromLoader(opts, &LCLoggerEmpty{})
+	a.NoError(err)
+	a.Equal(uint16(tls.VersionTLS12), c.transport.(*http.Transport).TLSClientConfig.MinVersion)
+	org, _ = NewOrganization(c)
+	urls, err := org.GetURLs()
+	a.NoError(err)
+	a.Equal("ingest.lc.example.com", urls["ingestion"])
+	a.Equal(c.transport, c.forOrg("other").transport)
+
+	proxied := []string{}
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"url": {}}`))
+	}))
+	defer proxy.Close()
+	c, err = NewClientFromLoader(ClientOptions{
+		OID:       vcrTestOID,
+		JWT:       "jwt",
+		Endpoints: Endpoints{APIRoot: "http://api.lc.example.com"},
+		ProxyURL:  proxy.URL,
+	}, &LCLoggerEmpty{})
+	a.NoError(err)
+	org, _ = NewOrganization(c)
+	_, err = org.GetURLs()
+	a.NoError(err)
+	a.Equal([]string{"http://api.lc.example.com/v1/orgs/" + vcrTestOID + "/url"}, proxied)
+
+	for _, invalid := range []ClientOptions{
+		{OID: vcrTestOID, ProxyURL: "not a url"},
+		{OID: vcrTestOID, CABundlePath: filepath.Join(dir, "missing.pem")},
+		{OID: vcrTestOID, CABundlePath: filepath.Join(dir, "ca.pem"), ClientCertPath: caPath},
+		{OID: vcrTestOID, ClientCertPath: caPath, ClientKeyPath: caPath},
+	} {
+		_, err := NewClientFromLoader(invalid, &LCLoggerEmpty{})
+		a.Error(err)
+	}
+}
@@ -35,6 +35,9 @@ func (l *EnvironmentClientOptionLoader) Load(inOpt ClientOptions) (ClientOptions
 	if isEmpty(opt.APIKey) {
 		opt.APIKey = os.Getenv("LC_API_KEY")
 	}
+	if isEmpty(opt.EndpointPreset) {
+		opt.EndpointPreset = os.Getenv("LC_ENDPOINT_PRESET")
+	}
 	return opt, nil
 }
 
@@ -50,11 +53,33 @@ func NewFileClientOptionLoader(configFile string) *FileClientOptionLoader {
 	}
 }
 
-// Load retrieve options from a config file
+// Load retrieve options from a config file. Loaders without
+// a path, like of an unset LC_CREDS_FILE, load nothing.
 func (l *FileClientOptionLoader) Load(inOpt ClientOptions) (ClientOptions, error) {
+	if isEmpty(l.path) {
+		return inOpt, nil
+	}
 	opts := ClientOptions{}
 	if err := opts.FromConfigFile(l.path, inOpt.Environment); err != nil {
 		return opts, err
 	}
+	opts.OAuth = inOpt.OAuth
+	opts.CompressionThreshold = inOpt.CompressionThreshold
+	opts.Cache = inOpt.Cache
+	opts.CacheTTL = inOpt.CacheTTL
+	opts.Transport = inOpt.Transport
+	opts.DisableIdempotencyKeys = inOpt.DisableIdempotencyKeys
+	opts.ProxyURL = inOpt.ProxyURL
+	opts.CABundlePath = inOpt.CABundlePath
+	opts.ClientCertPath = inOpt.ClientCertPath
+	opts.ClientKeyPath = inOpt.ClientKeyPath
+	opts.TLSMinVersion = inOpt.TLSMinVersion
+	opts.RateLimit = inOpt.RateLimit
+	opts.RateLimitBurst = inOpt.RateLimitBurst
+	// Endpoints set by the caller take precedence over the file's.
+	if inOpt.EndpointPreset != "" || !isZeroEndpoints(inOpt.Endpoints) {
+		opts.Endpoints = inOpt.Endpoints
+		opts.EndpointPreset = inOpt.EndpointPreset
+	}
 	return opts, nil
 }
@@ -1,9 +1,16 @@
 package limacharlie
 
 import (
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -55,3 +62,99 @@ func (s *ClientTestSuite) TestFileLoader() {
 		})
 	}
 }
+
+func TestCompressedRequests(t *testing.T) {
+	a := assert.New(t)
+
+	bodies := []string{}
+	isFailing := true
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if isFailing {
+			// The body is closed unread, stopping its compression.
+			isFailing = false
+			return nil, errors.New("connection reset")
+		}
+		body := r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			gz, err := gzip.NewReader(r.Body)
+			if err != nil {
+				return nil, err
+			}
+			body = gz
+		}
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		bodies = append(bodies, r.Header.Get("Content-Encoding")+" "+string(data))
+		return jsonResponse(http.StatusOK, `{"iid":"i1"}`), nil
+	}))
+	org.client.options.CompressionThreshold = 100
+
+	// Large bodies are compressed again on each attempt.
+	description := strings.Repeat("a", 100)
+	_, err := org.AddInstallationKey(InstallationKey{Description: description})
+	a.NoError(err)
+	_, err = org.AddInstallationKey(InstallationKey{Description: "k"})
+	a.NoError(err)
+	if a.Len(bodies, 2) {
+		a.True(strings.HasPrefix(bodies[0], "gzip "))
+		a.Contains(bodies[0], description)
+		a.True(strings.HasPrefix(bodies[1], " "))
+	}
+}
+
+func TestIdempotencyKeys(t *testing.T) {
+	a := assert.New(t)
+
+	keys := []string{}
+	isFailing := true
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if isFailing {
+			isFailing = false
+			return nil, errors.New("connection reset")
+		}
+		return jsonResponse(http.StatusOK, `{"iid":"i1"}`), nil
+	}))
+
+	// A lost response is retried with the same key.
+	iid, err := org.AddInstallationKey(InstallationKey{Description: "k"})
+	a.NoError(err)
+	a.Equal("i1", iid)
+	a.Len(keys, 2)
+	a.NotEmpty(keys[0])
+	a.Equal(keys[0], keys[1])
+
+	// Each request has its own key and reads have none.
+	keys = keys[:0]
+	_, err = org.AddInstallationKey(InstallationKey{Description: "k"})
+	a.NoError(err)
+	_, err = org.AddInstallationKey(InstallationKey{Description: "k"})
+	a.NoError(err)
+	_, err = org.GetURLs()
+	a.NoError(err)
+	a.Len(keys, 3)
+	a.NotEqual(keys[0], keys[1])
+	a.Empty(keys[2])
+
+	// Services also receive the key in the request.
+	var form url.Values
+	org = newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.NoError(r.ParseForm())
+		form = r.PostForm
+		a.Equal(form.Get("idempotency_key"), r.Header.Get("Idempotency-Key"))
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+	a.NoError(org.ArtifactRuleDelete("r"))
+	a.NotEmpty(form.Get("idempotency_key"))
+
+	org.client.options.DisableIdempotencyKeys = true
+	keys = keys[:0]
+	org.client.options.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		return jsonResponse(http.StatusOK, `{}`), nil
+	})
+	a.NoError(org.DelInstallationKey("i1"))
+	a.Equal([]string{""}, keys)
+}
@@ -0,0 +1,416 @@
+// Command limacharlie exposes the SDK on the command line.
+//
+// Credentials are loaded like limacharlie.NewClient does, from the
+// LC_OID and LC_API_KEY environment variables or from the
+// ~/.limacharlie file, for the environment selected by --env.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+
+	lc "github.com/refractionPOINT/go-limacharlie/limacharlie"
+	"gopkg.in/yaml.v3"
+)
+
+const usage = `usage: limacharlie [--oid OID] [--env ENV] [--rate-limit RPS] <command> [arguments]
+
+commands:
+  fetch [--categories all] [--out FILE] [--include-secrets]
+                                             fetch the config of the org as YAML, secrets masked
+  push [--categories all] [--dry-run] [--force] [--max-destructive N] [--transaction-log FILE] [--notify URL] CONFIG
+                                             push a config to the org
+  drift [--categories all] [--force] [--json] [--estimate-impact] [--stale-after DURATION] [--notify URL] CONFIG
+                                             show how the org differs from a config
+  sensors list [--selector SELECTOR]         list the sensors of the org
+  sensors cleanup [--selector SELECTOR] [--offline-for DURATION] [--max N] [--dry-run]
+                                             delete offline sensors
+  task SID COMMAND                           send a task to a sensor
+  detections tail --listen IP:PORT --connect-to HOST
+                                             stream the detections of the org
+`
+
+type globalOptions struct {
+	oid       string
+	env       string
+	rateLimit float64
+}
+
+// exitError carries the exit code of a command.
+type exitError struct {
+	code int
+}
+
+func (e exitError) Error() string {
+	return fmt.Sprintf("exit code %d", e.code)
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout)
+	var exitErr exitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.code)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, out io.Writer) error {
+	global := globalOptions{}
+	fs := flag.NewFlagSet("limacharlie", flag.ContinueOnError)
+	fs.StringVar(&global.oid, "oid", "", "the OID of the organization")
+	fs.StringVar(&global.env, "env", "", "the environment of the credentials file")
+	fs.Float64Var(&global.rateLimit, "rate-limit", 0, "maximum API requests per second, unlimited if 0")
+	fs.SetOutput(out)
+	fs.Usage = func() { fmt.Fprint(fs.Output(), usage) }
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+	if len(args) == 0 {
+		fs.Usage()
+		return exitError{code: 2}
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "fetch":
+		return cmdFetch(global, args, out)
+	case "push":
+		return cmdPush(global, args, out)
+	case "drift":
+		return cmdDrift(global, args, out)
+	case "sensors":
+		if len(args) != 0 && args[0] == "list" {
+			return cmdSensorsList(global, args[1:], out)
+		}
+		if len(args) != 0 && args[0] == "cleanup" {
+			return cmdSensorsCleanup(global, args[1:], out)
+		}
+		return errors.New("usage: sensors list|cleanup [arguments]")
+	case "task":
+		return cmdTask(global, args, out)
+	case "detections":
+		if len(args) == 0 || args[0] != "tail" {
+			return errors.New("usage: detections tail --listen IP:PORT --connect-to HOST")
+		}
+		return cmdDetectionsTail(global, args[1:], out)
+	}
+	fs.Usage()
+	return fmt.Errorf("unknown command: %s", cmd)
+}
+
+// newOrg connects to the org of the command, tests replace it
+// to talk to a fake API.
+var newOrg = func(global globalOptions) (*lc.Organization, error) {
+	client, err := lc.NewClient(lc.ClientOptions{
+		OID:         global.oid,
+		Environment: global.env,
+		RateLimit:   global.rateLimit,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return lc.NewOrganization(client)
+}
+
+func categoriesFlag(fs *flag.FlagSet) *string {
+	return fs.String("categories", "all", fmt.Sprintf("comma separated categories to sync, \"all\", \"hive:<name>\" or: %s", strings.Join(lc.SyncCategories, ", ")))
+}
+
+func notifyFlag(fs *flag.FlagSet) *string {
+	return fs.String("notify", "", "Slack or Microsoft Teams incoming webhook the changes are posted to")
+}
+
+func syncOptions(categories string) (lc.SyncOptions, error) {
+	return lc.NewSyncOptionsForCategories(strings.Split(categories, ",")...)
+}
+
+func cmdFetch(global globalOptions, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("fetch", flag.ContinueOnError)
+	categories := categoriesFlag(fs)
+	outFile := fs.String("out", "", "file to write the config to instead of stdout")
+	isIncludeSecrets := fs.Bool("include-secrets", false, "do not mask the secret org values, output credentials and secret hive records")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	options, err := syncOptions(*categories)
+	if err != nil {
+		return err
+	}
+	org, err := newOrg(global)
+	if err != nil {
+		return err
+	}
+	conf, err := org.SyncFetch(options)
+	if err != nil {
+		return err
+	}
+	if !*isIncludeSecrets {
+		conf = org.MaskConfigSecrets(conf)
+	}
+	data, err := yaml.Marshal(conf)
+	if err != nil {
+		return err
+	}
+	if *outFile != "" {
+		return ioutil.WriteFile(*outFile, data, 0600)
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+func cmdPush(global globalOptions, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("push", flag.ContinueOnError)
+	categories := categoriesFlag(fs)
+	isDryRun := fs.Bool("dry-run", false, "only show the changes")
+	isForce := fs.Bool("force", false, "remove elements absent from the config")
+	maxDestructive := fs.Int("max-destructive", -1, "abort a forced push removing more elements than this, no limit if negative")
+	transactionLog := fs.String("transaction-log", "", "file the changes applied are appended to, as JSON lines")
+	notify := notifyFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: push [--categories all] [--dry-run] [--force] [--max-destructive N] [--transaction-log FILE] [--notify URL] CONFIG")
+	}
+	options, err := syncOptions(*categories)
+	if err != nil {
+		return err
+	}
+	options.IsDryRun = *isDryRun
+	options.IsForce = *isForce
+	if *maxDestructive >= 0 {
+		options.MaxDestructiveOps = maxDestructive
+	}
+	if *transactionLog != "" {
+		f, err := os.OpenFile(*transactionLog, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		options.TransactionLog = f
+	}
+	org, err := newOrg(global)
+	if err != nil {
+		return err
+	}
+	ops, err := org.SyncPushFromFiles(fs.Arg(0), options)
+	for _, op := range ops {
+		fmt.Fprintln(out, op.String())
+	}
+	if *notify != "" {
+		if notifyErr := org.NotifySync(lc.SyncNotification{
+			WebhookURL: *notify,
+			Title:      fmt.Sprintf("push of %s to %s", fs.Arg(0), org.GetOID()),
+		}, lc.NewSyncPlanResult(ops), err); notifyErr != nil && err == nil {
+			err = notifyErr
+		}
+	}
+	return err
+}
+
+func cmdDrift(global globalOptions, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("drift", flag.ContinueOnError)
+	categories := categoriesFlag(fs)
+	isForce := fs.Bool("force", false, "include elements absent from the config")
+	isJSON := fs.Bool("json", false, "output the plan as JSON")
+	isEstimateImpact := fs.Bool("estimate-impact", false, "count the sensors the collection rules changed apply to")
+	staleAfter := fs.Duration("stale-after", 0, "report the D&R rules which did not fire for this long")
+	notify := notifyFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: drift [--categories all] [--force] [--json] [--estimate-impact] [--stale-after DURATION] [--notify URL] CONFIG")
+	}
+	options, err := syncOptions(*categories)
+	if err != nil {
+		return err
+	}
+	options.IsForce = *isForce
+	options.EstimateImpact = *isEstimateImpact
+	options.StaleAfter = *staleAfter
+	org, err := newOrg(global)
+	if err != nil {
+		return err
+	}
+	plan, err := org.SyncPlanFromFiles(fs.Arg(0), options)
+	if *notify != "" {
+		if notifyErr := org.NotifySync(lc.SyncNotification{
+			WebhookURL:    *notify,
+			Title:         fmt.Sprintf("drift of %s from %s", org.GetOID(), fs.Arg(0)),
+			IsOnlyChanges: true,
+		}, plan, err); notifyErr != nil && err == nil {
+			err = notifyErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if *isJSON {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(out, string(data))
+	} else {
+		fmt.Fprint(out, plan.String())
+	}
+	if code := plan.ExitCode(); code != 0 {
+		return exitError{code: code}
+	}
+	return nil
+}
+
+func cmdSensorsList(global globalOptions, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("sensors list", flag.ContinueOnError)
+	selector := fs.String("selector", "", "only list the sensors matching this selector")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	org, err := newOrg(global)
+	if err != nil {
+		return err
+	}
+	var sensors map[string]*lc.Sensor
+	if *selector != "" {
+		sensors, err = org.ListSensorsFromSelector(*selector)
+	} else {
+		sensors, err = org.ListSensors()
+	}
+	if err != nil {
+		return err
+	}
+	sids := []string{}
+	for sid := range sensors {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+	for _, sid := range sids {
+		s := sensors[sid]
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", s.SID, s.Hostname, lc.PlatformStrings[s.Platform], s.AliveTS)
+	}
+	return nil
+}
+
+func cmdSensorsCleanup(global globalOptions, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("sensors cleanup", flag.ContinueOnError)
+	policy := lc.SensorCleanupPolicy{}
+	fs.StringVar(&policy.Selector, "selector", "", "only delete the sensors matching this selector")
+	fs.DurationVar(&policy.OfflineFor, "offline-for", 0, "only delete the sensors offline for longer than this")
+	fs.IntVar(&policy.MaxDeletions, "max", 0, "abort if more sensors would be deleted")
+	fs.BoolVar(&policy.IsDryRun, "dry-run", false, "only list the sensors that would be deleted")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	org, err := newOrg(global)
+	if err != nil {
+		return err
+	}
+	result, err := org.CleanupSensors(policy)
+	for _, p := range result.Sensors {
+		status := "deleted"
+		if policy.IsDryRun {
+			status = "would delete"
+		} else if e, ok := result.Errors[p.Sensor.SID]; ok {
+			status = fmt.Sprintf("failed: %v", e)
+		}
+		fmt.Fprintf(out, "%s\t%s\t%s\t%s\n", p.Sensor.SID, p.Sensor.Hostname, p.Sensor.AliveTS, status)
+	}
+	return err
+}
+
+func cmdTask(global globalOptions, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("task", flag.ContinueOnError)
+	investigationID := fs.String("investigation-id", "", "investigation the responses are tagged with")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return errors.New("usage: task SID COMMAND")
+	}
+	org, err := newOrg(global)
+	if err != nil {
+		return err
+	}
+	command := strings.Join(fs.Args()[1:], " ")
+	if err := org.GetSensor(fs.Arg(0)).Task(command, lc.TaskingOptions{
+		InvestigationID: *investigationID,
+	}); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "tasked %s: %s\n", fs.Arg(0), command)
+	return nil
+}
+
+func cmdDetectionsTail(global globalOptions, args []string, out io.Writer) error {
+	fs := flag.NewFlagSet("detections tail", flag.ContinueOnError)
+	listen := fs.String("listen", "", "local IP:PORT to receive detections on")
+	connectTo := fs.String("connect-to", "", "public host LimaCharlie connects to, defaults to the listen IP")
+	category := fs.String("category", "", "only receive detections of this category")
+	tag := fs.String("tag", "", "only receive detections from sensors with this tag")
+	sid := fs.String("sid", "", "only receive detections from this sensor")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	host, port, err := net.SplitHostPort(*listen)
+	if err != nil {
+		return fmt.Errorf("invalid --listen: %v", err)
+	}
+	nPort, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return fmt.Errorf("invalid --listen port: %v", err)
+	}
+	if *connectTo == "" {
+		*connectTo = host
+	}
+	org, err := newOrg(global)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		<-interrupt
+		cancel()
+	}()
+
+	detections, err := org.StreamDetections(ctx, lc.StreamDetectionsOptions{
+		Firehose: lc.FirehoseOptions{
+			ListenOnIP:    net.ParseIP(host),
+			ListenOnPort:  uint16(nPort),
+			ConnectTo:     *connectTo,
+			ConnectToPort: uint16(nPort),
+		},
+		Category: *category,
+		Tag:      *tag,
+		SensorID: *sid,
+	})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	for d := range detections {
+		if err := enc.Encode(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
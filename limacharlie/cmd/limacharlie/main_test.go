@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lc "github.com/refractionPOINT/go-limacharlie/limacharlie"
+	"github.com/stretchr/testify/assert"
+)
+
+const testOID = "00000000-0000-0000-0000-000000000001"
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// useTestOrg makes the commands talk to an API with a
+// single output where they can list the outputs.
+func useTestOrg(t *testing.T) {
+	previous := newOrg
+	t.Cleanup(func() { newOrg = previous })
+	newOrg = func(global globalOptions) (*lc.Organization, error) {
+		return lc.NewOrganizationFromClientOptions(lc.ClientOptions{
+			OID:    testOID,
+			APIKey: "00000000-0000-0000-0000-000000000002",
+			JWT:    "jwt",
+			Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				body := `{"` + testOID + `":{}}`
+				if strings.HasPrefix(r.URL.Path, "/v1/outputs/") {
+					body = `{"` + testOID + `":{"remote":{"module":"s3","for":"detect","bucket":"b","secret_key":"s3cr3t"}}}`
+				}
+				if strings.HasSuffix(r.URL.Path, "/who") {
+					body = `{"ident":"me","orgs":["` + testOID + `"],"perms":["output.list"]}`
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Status:     "200 OK",
+					Header:     http.Header{"Content-Type": []string{"application/json"}},
+					Body:       ioutil.NopCloser(strings.NewReader(body)),
+				}, nil
+			}),
+		}, nil)
+	}
+}
+
+func writeTestConfig(t *testing.T, conf string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := ioutil.WriteFile(path, []byte(conf), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	useTestOrg(t)
+	emptyConfig := writeTestConfig(t, "version: 3\n")
+	outputConfig := writeTestConfig(t, `version: 3
+outputs:
+  o1:
+    module: syslog
+    type: detect
+    dest_host: example.com:514
+`)
+
+	for _, test := range []struct {
+		name     string
+		args     []string
+		exitCode int
+		err      string
+		out      string
+	}{
+		{
+			name:     "no command",
+			args:     []string{},
+			exitCode: 2,
+			out:      usage,
+		},
+		{
+			name: "unknown command",
+			args: []string{"nope"},
+			err:  "unknown command: nope",
+			out:  usage,
+		},
+		{
+			name: "unknown flag",
+			args: []string{"--nope", "fetch"},
+			err:  "flag provided but not defined: -nope",
+			out:  usage,
+		},
+		{
+			name: "missing sensors subcommand",
+			args: []string{"sensors"},
+			err:  "usage: sensors list|cleanup [arguments]",
+		},
+		{
+			name: "missing detections subcommand",
+			args: []string{"detections"},
+			err:  "usage: detections tail --listen IP:PORT --connect-to HOST",
+		},
+		{
+			name: "push without config",
+			args: []string{"push"},
+			err:  "usage: push [--categories all] [--dry-run]",
+		},
+		{
+			name: "drift without config",
+			args: []string{"drift"},
+			err:  "usage: drift [--categories all] [--force] [--json]",
+		},
+		{
+			name: "task without command",
+			args: []string{"task", "sid"},
+			err:  "usage: task SID COMMAND",
+		},
+		{
+			name: "fetch masks secrets",
+			args: []string{"fetch", "--categories", "outputs"},
+			out:  "secret_key: '********'",
+		},
+		{
+			name: "fetch with secrets",
+			args: []string{"fetch", "--categories", "outputs", "--include-secrets"},
+			out:  "secret_key: s3cr3t",
+		},
+		{
+			name: "push without removals allowed",
+			args: []string{"push", "--categories", "outputs", "--force", "--max-destructive", "0", emptyConfig},
+			err:  "too many destructive operations",
+		},
+		{
+			name: "drift without changes",
+			args: []string{"drift", "--categories", "outputs", emptyConfig},
+		},
+		{
+			name:     "drift with changes",
+			args:     []string{"drift", "--categories", "outputs", outputConfig},
+			exitCode: lc.SyncPlanExitChanges,
+			out:      "o1",
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			a := assert.New(t)
+			out := &bytes.Buffer{}
+			err := run(test.args, out)
+
+			var exitErr exitError
+			switch {
+			case test.exitCode != 0:
+				a.True(errors.As(err, &exitErr), "%v", err)
+				a.Equal(test.exitCode, exitErr.code)
+			case test.err != "":
+				a.Error(err)
+				a.False(errors.As(err, &exitErr))
+				if err != nil {
+					a.Contains(err.Error(), test.err)
+				}
+			default:
+				a.NoError(err)
+			}
+			a.Contains(out.String(), test.out)
+		})
+	}
+}
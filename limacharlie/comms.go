@@ -0,0 +1,266 @@
+package limacharlie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+type CaseID = string
+type CaseStatus = string
+type CasePriority = int
+
+// CaseStatuses is all the supported states of a case.
+var CaseStatuses = struct {
+	New        CaseStatus
+	InProgress CaseStatus
+	Resolved   CaseStatus
+	Closed     CaseStatus
+}{
+	New:        "new",
+	InProgress: "in_progress",
+	Resolved:   "resolved",
+	Closed:     "closed",
+}
+
+// Case is a triage case created from one or more detections.
+type Case struct {
+	ID         CaseID       `json:"cid"`
+	OID        string       `json:"oid"`
+	Title      string       `json:"title"`
+	Status     CaseStatus   `json:"status"`
+	Priority   CasePriority `json:"priority"`
+	Assignee   string       `json:"assignee,omitempty"`
+	Detections []string     `json:"detections"`
+	CreatedBy  string       `json:"by"`
+	CreatedAt  int64        `json:"created"`
+	UpdatedAt  int64        `json:"updated"`
+}
+
+type CaseComment struct {
+	ID        string `json:"comment_id"`
+	Author    string `json:"by"`
+	Message   string `json:"message"`
+	CreatedAt int64  `json:"created"`
+}
+
+type CaseAttachment struct {
+	ID        string `json:"attachment_id"`
+	Name      string `json:"name"`
+	Size      uint64 `json:"size"`
+	Author    string `json:"by"`
+	CreatedAt int64  `json:"created"`
+}
+
+type NewCaseOptions struct {
+	// Title of the case, defaults to the detection name.
+	Title string
+	// Priority of the case, higher is more urgent.
+	Priority CasePriority
+	// User the case is assigned to.
+	Assignee string
+}
+
+type CaseUpdate struct {
+	Title    *string
+	Status   *CaseStatus
+	Priority *CasePriority
+	Assignee *string
+}
+
+type CaseFilter func(map[string]string)
+
+type casesList struct {
+	Cases []Case `json:"cases"`
+}
+
+type caseCommentsList struct {
+	Comments []CaseComment `json:"comments"`
+}
+
+type caseAttachmentsList struct {
+	Attachments []CaseAttachment `json:"attachments"`
+}
+
+type caseAttachmentPointer struct {
+	Attachment CaseAttachment `json:"attachment"`
+	URL        string         `json:"put_url"`
+}
+
+func (org Organization) casesPath(caseID CaseID, sub string) string {
+	path := fmt.Sprintf("comms/%s/cases", org.client.options.OID)
+	if caseID != "" {
+		path = fmt.Sprintf("%s/%s", path, url.PathEscape(caseID))
+	}
+	if sub != "" {
+		path = fmt.Sprintf("%s/%s", path, sub)
+	}
+	return path
+}
+
+// CaseCreateFromDetection creates a new case seeded with a detection.
+func (org Organization) CaseCreateFromDetection(detectionID string, opts ...NewCaseOptions) (Case, error) {
+	if detectionID == "" {
+		return Case{}, errors.New("detection id required")
+	}
+	req := Dict{
+		"detect_id": detectionID,
+	}
+	for _, o := range opts {
+		if o.Title != "" {
+			req["title"] = o.Title
+		}
+		if o.Priority != 0 {
+			req["priority"] = o.Priority
+		}
+		if o.Assignee != "" {
+			req["assignee"] = o.Assignee
+		}
+	}
+	resp := Case{}
+	request := makeDefaultRequest(&resp).withFormData(req)
+	if err := org.client.reliableRequest(http.MethodPost, org.casesPath("", ""), request); err != nil {
+		return Case{}, err
+	}
+	return resp, nil
+}
+
+// Cases lists the cases of an organization.
+func (org Organization) Cases(filters ...CaseFilter) ([]Case, error) {
+	req := map[string]string{}
+	for _, f := range filters {
+		f(req)
+	}
+	resp := casesList{}
+	request := makeDefaultRequest(&resp).withQueryData(req)
+	if err := org.client.reliableRequest(http.MethodGet, org.casesPath("", ""), request); err != nil {
+		return nil, err
+	}
+	return resp.Cases, nil
+}
+
+// Case gets a single case.
+func (org Organization) Case(caseID CaseID) (Case, error) {
+	resp := Case{}
+	if err := org.client.reliableRequest(http.MethodGet, org.casesPath(caseID, ""), makeDefaultRequest(&resp)); err != nil {
+		return Case{}, err
+	}
+	return resp, nil
+}
+
+// CaseUpdate changes the fields of a case that are set in the update.
+func (org Organization) CaseUpdate(caseID CaseID, update CaseUpdate) (Case, error) {
+	req := Dict{}
+	if update.Title != nil {
+		req["title"] = *update.Title
+	}
+	if update.Status != nil {
+		req["status"] = *update.Status
+	}
+	if update.Priority != nil {
+		req["priority"] = *update.Priority
+	}
+	if update.Assignee != nil {
+		req["assignee"] = *update.Assignee
+	}
+	if len(req) == 0 {
+		return Case{}, errors.New("nothing to update")
+	}
+	resp := Case{}
+	request := makeDefaultRequest(&resp).withFormData(req)
+	if err := org.client.reliableRequest(http.MethodPost, org.casesPath(caseID, ""), request); err != nil {
+		return Case{}, err
+	}
+	return resp, nil
+}
+
+// CaseSetStatus is a shorthand to only change the status of a case.
+func (org Organization) CaseSetStatus(caseID CaseID, status CaseStatus) (Case, error) {
+	return org.CaseUpdate(caseID, CaseUpdate{Status: &status})
+}
+
+// CaseAddDetection links an additional detection to an existing case.
+func (org Organization) CaseAddDetection(caseID CaseID, detectionID string) error {
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withFormData(Dict{
+		"detect_id": detectionID,
+	})
+	return org.client.reliableRequest(http.MethodPost, org.casesPath(caseID, "detections"), request)
+}
+
+// CaseComments lists the comments of a case.
+func (org Organization) CaseComments(caseID CaseID) ([]CaseComment, error) {
+	resp := caseCommentsList{}
+	if err := org.client.reliableRequest(http.MethodGet, org.casesPath(caseID, "comments"), makeDefaultRequest(&resp)); err != nil {
+		return nil, err
+	}
+	return resp.Comments, nil
+}
+
+// CaseAddComment posts a new comment on a case.
+func (org Organization) CaseAddComment(caseID CaseID, message string) (CaseComment, error) {
+	resp := CaseComment{}
+	request := makeDefaultRequest(&resp).withFormData(Dict{
+		"message": message,
+	})
+	if err := org.client.reliableRequest(http.MethodPost, org.casesPath(caseID, "comments"), request); err != nil {
+		return CaseComment{}, err
+	}
+	return resp, nil
+}
+
+// CaseAttachments lists the attachments of a case.
+func (org Organization) CaseAttachments(caseID CaseID) ([]CaseAttachment, error) {
+	resp := caseAttachmentsList{}
+	if err := org.client.reliableRequest(http.MethodGet, org.casesPath(caseID, "attachments"), makeDefaultRequest(&resp)); err != nil {
+		return nil, err
+	}
+	return resp.Attachments, nil
+}
+
+// CaseAddAttachmentFromBytes attaches a file to a case.
+func (org Organization) CaseAddAttachmentFromBytes(caseID CaseID, name string, data []byte) (CaseAttachment, error) {
+	return org.CaseAddAttachmentFromReader(caseID, name, bytes.NewBuffer(data))
+}
+
+// CaseAddAttachmentFromReader attaches a file to a case, the content
+// is uploaded directly to the storage location returned by the API.
+func (org Organization) CaseAddAttachmentFromReader(caseID CaseID, name string, data io.Reader) (CaseAttachment, error) {
+	resp := caseAttachmentPointer{}
+	request := makeDefaultRequest(&resp).withFormData(Dict{
+		"name": name,
+	})
+	if err := org.client.reliableRequest(http.MethodPost, org.casesPath(caseID, "attachments"), request); err != nil {
+		return CaseAttachment{}, err
+	}
+	c := org.client.httpClient(0)
+	req, err := http.NewRequest(http.MethodPut, resp.URL, data)
+	if err != nil {
+		return CaseAttachment{}, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	httpResp, err := c.Do(req)
+	if err != nil {
+		return CaseAttachment{}, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != 200 {
+		return CaseAttachment{}, fmt.Errorf("failed to PUT attachment, http status: %d", httpResp.StatusCode)
+	}
+	return resp.Attachment, nil
+}
+
+func WithCaseStatus(status CaseStatus) CaseFilter {
+	return func(m map[string]string) {
+		m["status"] = status
+	}
+}
+
+func WithCaseAssignee(assignee string) CaseFilter {
+	return func(m map[string]string) {
+		m["assignee"] = assignee
+	}
+}
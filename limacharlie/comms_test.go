@@ -0,0 +1,14 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCasesList(t *testing.T) {
+	a := assert.New(t)
+	org := getTestOrgFromEnv(a)
+	_, err := org.Cases(WithCaseStatus(CaseStatuses.New))
+	a.NoError(err)
+}
@@ -0,0 +1,121 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ConfigDuplicate is an element defined in more than one of the files
+// of a config, in which case the one from the last file is used.
+type ConfigDuplicate struct {
+	// Path of the element, like "rules/my-rule".
+	Path string `json:"path"`
+	// Files defining the element, in the order they are merged.
+	Files []string `json:"files"`
+}
+
+func (d ConfigDuplicate) String() string {
+	return fmt.Sprintf("%s defined in %s", d.Path, strings.Join(d.Files, ", "))
+}
+
+// ConfigDuplicatesError is returned when loading a config with
+// duplicate elements while SyncOptions.ErrorOnDuplicates is set.
+type ConfigDuplicatesError struct {
+	Duplicates []ConfigDuplicate
+}
+
+func (e *ConfigDuplicatesError) Error() string {
+	dups := []string{}
+	for _, d := range e.Duplicates {
+		dups = append(dups, d.String())
+	}
+	return fmt.Sprintf("duplicate elements: %s", strings.Join(dups, "; "))
+}
+
+// Number of levels of nesting before reaching the elements which
+// replace each other when merging includes, hives are replaced as
+// a whole.
+var orgConfigDuplicateDepth = map[string]int{
+	"exfil": 2,
+	"yara":  2,
+}
+
+// Sections merged without replacing elements.
+var orgConfigUnionSections = map[string]struct{}{
+	"version":    {},
+	"resources":  {},
+	"extensions": {},
+}
+
+// configElementSources records the files defining each element.
+type configElementSources map[string][]string
+
+func (s configElementSources) add(file string, conf OrgConfig) error {
+	g, err := orgConfigToGeneric(conf)
+	if err != nil {
+		return err
+	}
+	for section, v := range g {
+		if _, ok := orgConfigUnionSections[section]; ok {
+			continue
+		}
+		depth := orgConfigDuplicateDepth[section]
+		if depth == 0 {
+			depth = 1
+		}
+		s.addPaths(file, section, depth, v)
+	}
+	return nil
+}
+
+func (s configElementSources) addPaths(file string, path string, depth int, v interface{}) {
+	m, ok := v.(map[string]interface{})
+	if depth == 0 || !ok {
+		for _, f := range s[path] {
+			if f == file {
+				// Included more than once.
+				return
+			}
+		}
+		s[path] = append(s[path], file)
+		return
+	}
+	for k, e := range m {
+		s.addPaths(file, path+"/"+k, depth-1, e)
+	}
+}
+
+func (s configElementSources) duplicates() []ConfigDuplicate {
+	dups := []ConfigDuplicate{}
+	for path, files := range s {
+		if len(files) > 1 {
+			dups = append(dups, ConfigDuplicate{Path: path, Files: files})
+		}
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		return dups[i].Path < dups[j].Path
+	})
+	return dups
+}
+
+// reportDuplicates warns of the duplicates, returning
+// an error if they are not allowed by the options.
+func (options SyncOptions) reportDuplicates(dups []ConfigDuplicate) error {
+	if len(dups) == 0 {
+		return nil
+	}
+	for _, d := range dups {
+		if options.OnDuplicate != nil {
+			options.OnDuplicate(d)
+		}
+		logWithFields(options.Logger, LogLevels.Warn, "duplicate config element", map[string]interface{}{
+			"path":  d.Path,
+			"files": strings.Join(d.Files, ", "),
+		})
+	}
+	if options.ErrorOnDuplicates {
+		return &ConfigDuplicatesError{Duplicates: dups}
+	}
+	return nil
+}
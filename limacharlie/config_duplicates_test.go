@@ -0,0 +1,84 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEffectiveConfigDuplicates(t *testing.T) {
+	a := assert.New(t)
+
+	files := map[string][]byte{
+		"root.yaml": []byte(`version: 3
+include:
+  - a.yaml
+  - b.yaml
+rules:
+  r1:
+    detect:
+      op: is
+    respond: []
+resources:
+  replicant:
+    - a1
+`),
+		"a.yaml": []byte(`version: 3
+rules:
+  r1:
+    detect:
+      op: exists
+    respond: []
+resources:
+  replicant:
+    - a1
+exfil:
+  watch:
+    w1:
+      event: NEW_PROCESS
+`),
+		"b.yaml": []byte(`version: 3
+include: a.yaml
+exfil:
+  watch:
+    w1:
+      event: CODE_IDENTITY
+    w2:
+      event: CODE_IDENTITY
+`),
+	}
+	ldr := func(parent string, configFile string) ([]byte, error) {
+		full := filepath.Join(filepath.Dir(parent), configFile)
+		d, ok := files[full]
+		if !ok {
+			return nil, fmt.Errorf("file not found: %s", full)
+		}
+		return d, nil
+	}
+
+	dups := []ConfigDuplicate{}
+	conf, err := loadEffectiveConfig("", "root.yaml", SyncOptions{
+		IncludeLoader: ldr,
+		OnDuplicate: func(dup ConfigDuplicate) {
+			dups = append(dups, dup)
+		},
+	})
+	a.NoError(err)
+	a.Contains(conf.DRRules, "r1")
+	a.Equal([]ConfigDuplicate{
+		{Path: "exfil/watch/w1", Files: []string{"a.yaml", "b.yaml"}},
+		{Path: "rules/r1", Files: []string{"root.yaml", "a.yaml"}},
+	}, dups)
+
+	_, err = loadEffectiveConfig("", "root.yaml", SyncOptions{
+		IncludeLoader:     ldr,
+		ErrorOnDuplicates: true,
+	})
+	dupErr := &ConfigDuplicatesError{}
+	a.True(errors.As(err, &dupErr))
+	a.Len(dupErr.Duplicates, 2)
+	a.EqualError(err, "duplicate elements: exfil/watch/w1 defined in a.yaml, b.yaml; rules/r1 defined in root.yaml, a.yaml")
+}
@@ -0,0 +1,85 @@
+package limacharlie
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Encrypted values in config files are strings like "ENC[age,<ciphertext>]"
+// where the scheme, "age" here, tells the Decrypter how to decrypt them.
+var encryptedValueRegexp = regexp.MustCompile(`^ENC\[([A-Za-z0-9_-]+),(.+)\]$`)
+
+// Decrypter decrypts the encrypted values of config files, so that
+// secrets, like the credentials of outputs, can be committed encrypted
+// and only be decrypted when loading the config to push it.
+type Decrypter interface {
+	Decrypt(scheme string, ciphertext string) (string, error)
+}
+
+// DecrypterFunc adapts a function to a Decrypter.
+type DecrypterFunc func(scheme string, ciphertext string) (string, error)
+
+func (f DecrypterFunc) Decrypt(scheme string, ciphertext string) (string, error) {
+	return f(scheme, ciphertext)
+}
+
+// ErrorNoDecrypter is returned when loading a config with
+// encrypted values without a Decrypter.
+var ErrorNoDecrypter = errors.New("encrypted value found but no decrypter configured")
+
+// IsEncryptedValue returns true if the value is an encrypted "ENC[...]" string.
+func IsEncryptedValue(value string) bool {
+	return encryptedValueRegexp.MatchString(value)
+}
+
+// DecryptConfig returns the YAML config with its encrypted values
+// decrypted. The config is returned as is if it has none.
+func DecryptConfig(data []byte, decrypter Decrypter) ([]byte, error) {
+	if !bytes.Contains(data, []byte("ENC[")) {
+		return data, nil
+	}
+	root := yaml.Node{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	isDecrypted, err := decryptNode(&root, decrypter)
+	if err != nil {
+		return nil, err
+	}
+	if !isDecrypted {
+		return data, nil
+	}
+	return yaml.Marshal(&root)
+}
+
+func decryptNode(n *yaml.Node, decrypter Decrypter) (bool, error) {
+	if n.Kind == yaml.ScalarNode {
+		m := encryptedValueRegexp.FindStringSubmatch(n.Value)
+		if m == nil || n.Tag != "!!str" {
+			return false, nil
+		}
+		if decrypter == nil {
+			return false, fmt.Errorf("line %d: %w", n.Line, ErrorNoDecrypter)
+		}
+		value, err := decrypter.Decrypt(m[1], m[2])
+		if err != nil {
+			return false, fmt.Errorf("line %d: decrypting %s value: %v", n.Line, m[1], err)
+		}
+		n.Value = value
+		n.Style = yaml.DoubleQuotedStyle
+		return true, nil
+	}
+	isDecrypted := false
+	for _, c := range n.Content {
+		isChildDecrypted, err := decryptNode(c, decrypter)
+		if err != nil {
+			return false, err
+		}
+		isDecrypted = isDecrypted || isChildDecrypted
+	}
+	return isDecrypted, nil
+}
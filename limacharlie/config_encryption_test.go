@@ -0,0 +1,57 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecryptConfig(t *testing.T) {
+	a := assert.New(t)
+
+	data := []byte(`version: 3
+outputs:
+  siem:
+    module: syslog
+    type: event
+    dest_host: siem.example.com
+    secret_key: ENC[rot,fhcre-frperg]
+`)
+	rot := DecrypterFunc(func(scheme string, ciphertext string) (string, error) {
+		if scheme != "rot" {
+			return "", fmt.Errorf("unknown scheme: %s", scheme)
+		}
+		return strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' {
+				return 'a' + (r-'a'+13)%26
+			}
+			return r
+		}, ciphertext), nil
+	})
+
+	files := map[string][]byte{"root.yaml": data}
+	ldr := func(parent string, configFile string) ([]byte, error) {
+		return files[configFile], nil
+	}
+	conf, err := loadEffectiveConfig("", "root.yaml", SyncOptions{
+		IncludeLoader: ldr,
+		Decrypter:     rot,
+	})
+	a.NoError(err)
+	a.Equal("super-secret", conf.Outputs["siem"].SecretKey)
+	a.Equal("siem.example.com", conf.Outputs["siem"].DestinationHost)
+
+	_, err = loadEffectiveConfig("", "root.yaml", SyncOptions{IncludeLoader: ldr})
+	a.True(errors.Is(err, ErrorNoDecrypter))
+
+	plain := []byte("version: 3\n")
+	out, err := DecryptConfig(plain, nil)
+	a.NoError(err)
+	a.Equal(plain, out)
+
+	a.True(IsEncryptedValue("ENC[age,abc]"))
+	a.False(IsEncryptedValue("ENC[]"))
+}
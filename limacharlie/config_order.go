@@ -0,0 +1,123 @@
+package limacharlie
+
+import (
+	"sort"
+)
+
+// sortedLists returns a copy of the config with the lists whose order
+// is not meaningful, like the tags and platforms of filters, sorted.
+// The API does not preserve their order, or returns them as sets, so
+// SyncFetch sorts them for the YAML of fetched configs, whose maps are
+// already written with sorted keys, to be deterministic and committing
+// them to yield minimal diffs. The config is left untouched.
+func (o OrgConfig) sortedLists() OrgConfig {
+	if o.Resources != nil {
+		resources := orgSyncResources{}
+		for category, names := range o.Resources {
+			resources[category] = sortedStrings(names)
+		}
+		o.Resources = resources
+	}
+	if o.DRRules != nil {
+		rules := orgSyncDRRules{}
+		for name, r := range o.DRRules {
+			if r.Filters != nil {
+				filters := *r.Filters
+				filters.Tags = sortedStrings(filters.Tags)
+				filters.Platforms = sortedPlatforms(filters.Platforms)
+				r.Filters = &filters
+			}
+			rules[name] = r
+		}
+		o.DRRules = rules
+	}
+	if o.Integrity != nil {
+		integrity := orgSyncIntegrityRules{}
+		for name, r := range o.Integrity {
+			r.Patterns = sortedStrings(r.Patterns)
+			r.Tags = sortedStrings(r.Tags)
+			r.Platforms = sortedPlatforms(r.Platforms)
+			integrity[name] = r
+		}
+		o.Integrity = integrity
+	}
+	if o.Exfil != nil {
+		exfil := *o.Exfil
+		if exfil.Events != nil {
+			exfil.Events = map[ExfilRuleName]ExfilRuleEvent{}
+			for name, r := range o.Exfil.Events {
+				r.Events = sortedStrings(r.Events)
+				r.Filters = sortedExfilFilters(r.Filters)
+				exfil.Events[name] = r
+			}
+		}
+		if exfil.Watches != nil {
+			exfil.Watches = map[ExfilRuleName]ExfilRuleWatch{}
+			for name, r := range o.Exfil.Watches {
+				r.Filters = sortedExfilFilters(r.Filters)
+				exfil.Watches[name] = r
+			}
+		}
+		o.Exfil = &exfil
+	}
+	if o.Artifacts != nil {
+		artifacts := orgSyncArtifacts{}
+		for name, r := range o.Artifacts {
+			r.Patterns = sortedStrings(r.Patterns)
+			r.Tags = sortedStrings(r.Tags)
+			r.Platforms = sortedPlatforms(r.Platforms)
+			artifacts[name] = r
+		}
+		o.Artifacts = artifacts
+	}
+	if o.InstallationKeys != nil {
+		keys := orgSyncInstallationKeys{}
+		for name, k := range o.InstallationKeys {
+			k.Tags = sortedStrings(k.Tags)
+			keys[name] = k
+		}
+		o.InstallationKeys = keys
+	}
+	if o.Yara != nil {
+		yara := *o.Yara
+		if yara.Rules != nil {
+			yara.Rules = map[YaraRuleName]YaraRule{}
+			for name, r := range o.Yara.Rules {
+				r.Sources = sortedStrings(r.Sources)
+				r.Filters.Tags = sortedStrings(r.Filters.Tags)
+				r.Filters.Platforms = sortedPlatforms(r.Filters.Platforms)
+				yara.Rules[name] = r
+			}
+		}
+		o.Yara = &yara
+	}
+	o.Extensions = sortedStrings(o.Extensions)
+	return o
+}
+
+func sortedExfilFilters(f ExfilEventFilters) ExfilEventFilters {
+	f.Tags = sortedStrings(f.Tags)
+	f.Platforms = sortedPlatforms(f.Platforms)
+	return f
+}
+
+// sortedStrings returns a sorted copy of l, nil if l is.
+func sortedStrings(l []string) []string {
+	if l == nil {
+		return nil
+	}
+	sorted := append([]string{}, l...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func sortedPlatforms(l []Platform) []Platform {
+	if l == nil {
+		return nil
+	}
+	sorted := append([]Platform{}, l...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	return sorted
+}
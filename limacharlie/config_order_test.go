@@ -0,0 +1,66 @@
+package limacharlie
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestSyncFetchIsDeterministic(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.Contains(r.URL.Path, "resources") {
+			return jsonResponse(http.StatusOK, `{"resources":{"api":["vt","insight","ip-geo","hybrid-analysis"]}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	var previous string
+	for i := 0; i < 10; i++ {
+		conf, err := org.SyncFetch(SyncOptions{SyncResources: true})
+		a.NoError(err)
+		a.Equal([]string{"hybrid-analysis", "insight", "ip-geo", "vt"}, conf.Resources["api"])
+		data, err := yaml.Marshal(conf)
+		a.NoError(err)
+		if previous != "" {
+			a.Equal(previous, string(data))
+		}
+		previous = string(data)
+	}
+}
+
+func TestOrgConfigSortedLists(t *testing.T) {
+	a := assert.New(t)
+	tags := []string{"b", "c", "a"}
+	platforms := []Platform{"windows", "linux"}
+	conf := OrgConfig{
+		Extensions: []string{"ext-b", "ext-a"},
+		DRRules: orgSyncDRRules{
+			"r1": {Filters: &DRRuleTargets{Tags: tags, Platforms: platforms}},
+		},
+		Integrity: orgSyncIntegrityRules{
+			"i1": {Patterns: tags, Tags: tags, Platforms: platforms},
+		},
+		Exfil: &orgSyncExfilRules{
+			Events: map[ExfilRuleName]ExfilRuleEvent{
+				"e1": {Events: tags, Filters: ExfilEventFilters{Tags: tags, Platforms: platforms}},
+			},
+		},
+		InstallationKeys: orgSyncInstallationKeys{
+			"k1": {Description: "k1", Tags: tags},
+		},
+	}
+	sorted := conf.sortedLists()
+	a.Equal([]string{"ext-a", "ext-b"}, sorted.Extensions)
+	a.Equal([]string{"a", "b", "c"}, sorted.DRRules["r1"].Filters.Tags)
+	a.Equal([]Platform{"linux", "windows"}, sorted.Integrity["i1"].Platforms)
+	a.Equal([]string{"a", "b", "c"}, sorted.Exfil.Events["e1"].Events)
+	a.Equal([]string{"a", "b", "c"}, sorted.InstallationKeys["k1"].Tags)
+
+	// The config is left untouched.
+	a.Equal([]string{"b", "c", "a"}, tags)
+	a.Equal([]string{"ext-b", "ext-a"}, conf.Extensions)
+}
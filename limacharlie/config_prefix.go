@@ -0,0 +1,201 @@
+package limacharlie
+
+import (
+	"fmt"
+)
+
+// ConfigInclude is an included config file along with
+// the options it is included with, like:
+//
+//	include:
+//	  - path: soc/rules.yaml
+//	    prefix: soc-
+type ConfigInclude struct {
+	Path string `json:"path" yaml:"path"`
+	// Prefix is prepended to the names of the elements of the
+	// file, see OrgConfig.WithNamePrefix.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+}
+
+// parseConfigIncludes parses an "include" value, a path, a ConfigInclude
+// or a list of either.
+func parseConfigIncludes(inc interface{}) ([]ConfigInclude, error) {
+	list, ok := inc.([]interface{})
+	if !ok {
+		list = []interface{}{inc}
+	}
+	entries := []ConfigInclude{}
+	for _, e := range list {
+		entry, err := parseConfigInclude(e)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func parseConfigInclude(inc interface{}) (ConfigInclude, error) {
+	var m map[string]interface{}
+	switch v := inc.(type) {
+	case string:
+		return ConfigInclude{Path: v}, nil
+	case map[string]interface{}:
+		m = v
+	case map[interface{}]interface{}:
+		var err error
+		if m, err = yamlMapToJsonMap(v); err != nil {
+			return ConfigInclude{}, err
+		}
+	default:
+		return ConfigInclude{}, fmt.Errorf("unknown include format: %T", inc)
+	}
+	entry := ConfigInclude{}
+	for k, v := range m {
+		s, ok := v.(string)
+		if !ok {
+			return entry, fmt.Errorf("include %s must be a string: %T", k, v)
+		}
+		switch k {
+		case "path":
+			entry.Path = s
+		case "prefix":
+			entry.Prefix = s
+		default:
+			return entry, fmt.Errorf("unknown include option: %s", k)
+		}
+	}
+	if entry.Path == "" {
+		return entry, fmt.Errorf("include path is required")
+	}
+	return entry, nil
+}
+
+// includeEntries returns the includes of the config with their options.
+func (c OrgConfig) includeEntries() []ConfigInclude {
+	if len(c.IncludeEntries) == len(c.Includes) {
+		return c.IncludeEntries
+	}
+	entries := []ConfigInclude{}
+	for _, path := range c.Includes {
+		entries = append(entries, ConfigInclude{Path: path})
+	}
+	return entries
+}
+
+// WithNamePrefix returns the config with the prefix prepended to the
+// names of its elements, so that a shared library of elements can be
+// included more than once without collisions. References between
+// elements of the config, like the rules of rule tests and the sources
+// of yara rules, are renamed along. Elements whose names are fixed by
+// the platform, like org values and resources, are left alone, as are
+// hive records since D&R rules reference them by name.
+func (c OrgConfig) WithNamePrefix(prefix string) OrgConfig {
+	if prefix == "" {
+		return c
+	}
+	n := c
+	if c.DRRules != nil {
+		n.DRRules = orgSyncDRRules{}
+		for name, rule := range c.DRRules {
+			if rule.Name != "" {
+				rule.Name = prefix + rule.Name
+			}
+			n.DRRules[prefix+name] = rule
+		}
+	}
+	if c.FPRules != nil {
+		n.FPRules = orgSyncFPRules{}
+		for name, rule := range c.FPRules {
+			n.FPRules[prefix+name] = rule
+		}
+	}
+	if c.Outputs != nil {
+		n.Outputs = orgSyncOutputs{}
+		for name, output := range c.Outputs {
+			if output.Name != "" {
+				output.Name = prefix + output.Name
+			}
+			n.Outputs[prefix+name] = output
+		}
+	}
+	if c.Integrity != nil {
+		n.Integrity = orgSyncIntegrityRules{}
+		for name, rule := range c.Integrity {
+			n.Integrity[prefix+name] = rule
+		}
+	}
+	if c.Exfil != nil {
+		exfil := *c.Exfil
+		if c.Exfil.Events != nil {
+			exfil.Events = map[ExfilRuleName]ExfilRuleEvent{}
+			for name, rule := range c.Exfil.Events {
+				exfil.Events[prefix+name] = rule
+			}
+		}
+		if c.Exfil.Watches != nil {
+			exfil.Watches = map[ExfilRuleName]ExfilRuleWatch{}
+			for name, rule := range c.Exfil.Watches {
+				exfil.Watches[prefix+name] = rule
+			}
+		}
+		n.Exfil = &exfil
+	}
+	if c.Artifacts != nil {
+		n.Artifacts = orgSyncArtifacts{}
+		for name, rule := range c.Artifacts {
+			n.Artifacts[prefix+name] = rule
+		}
+	}
+	if c.InstallationKeys != nil {
+		n.InstallationKeys = orgSyncInstallationKeys{}
+		for name, key := range c.InstallationKeys {
+			n.InstallationKeys[prefix+name] = key
+		}
+	}
+	if c.Yara != nil {
+		yara := &orgSyncYara{}
+		if c.Yara.Sources != nil {
+			yara.Sources = map[YaraSourceName]YaraSource{}
+			for name, source := range c.Yara.Sources {
+				yara.Sources[prefix+name] = source
+			}
+		}
+		if c.Yara.Rules != nil {
+			yara.Rules = map[YaraRuleName]YaraRule{}
+			for name, rule := range c.Yara.Rules {
+				sources := []string{}
+				for _, s := range rule.Sources {
+					if _, ok := c.Yara.Sources[s]; ok {
+						s = prefix + s
+					}
+					sources = append(sources, s)
+				}
+				if rule.Sources != nil {
+					rule.Sources = sources
+				}
+				yara.Rules[prefix+name] = rule
+			}
+		}
+		n.Yara = yara
+	}
+	if c.RuleTests != nil {
+		n.RuleTests = orgSyncRuleTests{}
+		for name, test := range c.RuleTests {
+			if _, ok := c.DRRules[test.Rule]; ok {
+				test.Rule = prefix + test.Rule
+			}
+			n.RuleTests[prefix+name] = test
+		}
+	}
+	if c.NetPolicies != nil {
+		n.NetPolicies = orgSyncNetPolicies{}
+		for name, policy := range c.NetPolicies {
+			if policy.Name != "" {
+				policy.Name = prefix + policy.Name
+			}
+			n.NetPolicies[prefix+name] = policy
+		}
+	}
+	return n
+}
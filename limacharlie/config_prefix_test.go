@@ -0,0 +1,103 @@
+package limacharlie
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigIncludePrefix(t *testing.T) {
+	a := assert.New(t)
+
+	files := map[string][]byte{
+		"root.yaml": []byte(`version: 3
+include:
+  - path: lib/rules.yaml
+    prefix: soc-
+  - path: lib/rules.yaml
+    prefix: it-
+  - other.yaml
+`),
+		"lib/rules.yaml": []byte(`version: 3
+rules:
+  r1:
+    detect:
+      op: is
+    respond: []
+rules_tests:
+  t1:
+    rule: r1
+    event: {}
+    match: true
+yara:
+  sources:
+    s1:
+      source: https://example.com/s1.yar
+  rules:
+    y1:
+      sources:
+        - s1
+        - shared
+org-value:
+  otx: key
+`),
+		"other.yaml": []byte(`version: 3
+fps:
+  f1:
+    data:
+      op: is
+`),
+	}
+	ldr := func(parent string, configFile string) ([]byte, error) {
+		full := filepath.Join(filepath.Dir(parent), configFile)
+		d, ok := files[full]
+		if !ok {
+			return nil, fmt.Errorf("file not found: %s", full)
+		}
+		return d, nil
+	}
+
+	dups := []ConfigDuplicate{}
+	conf, err := loadEffectiveConfig("", "root.yaml", SyncOptions{
+		IncludeLoader: ldr,
+		OnDuplicate: func(dup ConfigDuplicate) {
+			dups = append(dups, dup)
+		},
+	})
+	a.NoError(err)
+	a.Contains(conf.DRRules, "soc-r1")
+	a.Contains(conf.DRRules, "it-r1")
+	a.NotContains(conf.DRRules, "r1")
+	a.Equal("soc-r1", conf.RuleTests["soc-t1"].Rule)
+	a.Contains(conf.FPRules, "f1")
+	a.Contains(conf.OrgValues, "otx")
+	// The unprefixed org value comes from the same file both times.
+	a.Empty(dups)
+
+	lib, err := LoadOrgConfig(files["lib/rules.yaml"])
+	a.NoError(err)
+	prefixed := lib.WithNamePrefix("it-")
+	a.Contains(prefixed.Yara.Sources, "it-s1")
+	a.Equal([]string{"it-s1", "shared"}, prefixed.Yara.Rules["it-y1"].Sources)
+	a.Equal([]string{"s1", "shared"}, lib.Yara.Rules["y1"].Sources)
+}
+
+func TestConfigIncludeFormats(t *testing.T) {
+	a := assert.New(t)
+
+	conf := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte("include:\n  path: a.yaml\n  prefix: p-\n"), &conf))
+	a.Equal([]string{"a.yaml"}, conf.Includes)
+	a.Equal([]ConfigInclude{{Path: "a.yaml", Prefix: "p-"}}, conf.includeEntries())
+
+	conf = OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte("include:\n  - a.yaml\n  - b.yaml\n"), &conf))
+	a.Equal([]ConfigInclude{{Path: "a.yaml"}, {Path: "b.yaml"}}, conf.includeEntries())
+
+	conf = OrgConfig{}
+	a.Error(yaml.Unmarshal([]byte("include:\n  - prefix: p-\n"), &conf))
+	a.Error(yaml.Unmarshal([]byte("include:\n  - path: a.yaml\n    other: x\n"), &conf))
+}
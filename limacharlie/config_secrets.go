@@ -0,0 +1,62 @@
+package limacharlie
+
+// secretHiveName is the hive holding the secrets
+// referenced by other elements, like "hive://secret/name".
+const secretHiveName HiveName = "secret"
+
+// MaskConfigSecrets returns the config with its secrets masked, for it
+// to be displayed: the Org Values which are secrets or are unknown to
+// the catalog of the Org, the credentials of the outputs and the data
+// of the records of the secret hive. The config is not modified.
+func (org Organization) MaskConfigSecrets(conf OrgConfig) OrgConfig {
+	return maskConfigSecrets(conf, org.orgValueCatalog())
+}
+
+func maskConfigSecrets(conf OrgConfig, catalog map[OrgValueName]OrgValueMetadata) OrgConfig {
+	if conf.OrgValues != nil {
+		values := orgSyncOrgValues{}
+		for name, value := range conf.OrgValues {
+			md, ok := catalog[name]
+			if !ok {
+				md = OrgValueMetadata{Name: name, IsSecret: true}
+			}
+			values[name] = md.MaskOrgValue(value)
+		}
+		conf.OrgValues = values
+	}
+
+	if conf.Outputs != nil {
+		outputs := orgSyncOutputs{}
+		for name, output := range conf.Outputs {
+			for _, f := range OutputSecretFields[output.Module] {
+				if p := outputStringField(&output, f); p != nil && *p != "" {
+					*p = orgValueMask
+				}
+			}
+			outputs[name] = output
+		}
+		conf.Outputs = outputs
+	}
+
+	if records, ok := conf.Hives[secretHiveName]; ok {
+		hives := orgSyncHives{}
+		for hiveName, h := range conf.Hives {
+			hives[hiveName] = h
+		}
+		masked := map[HiveKey]SyncHiveData{}
+		for key, record := range records {
+			data := map[string]interface{}{}
+			for k, v := range record.Data {
+				if s, ok := v.(string); ok && s != "" {
+					v = orgValueMask
+				}
+				data[k] = v
+			}
+			record.Data = data
+			masked[key] = record
+		}
+		hives[secretHiveName] = masked
+		conf.Hives = hives
+	}
+	return conf
+}
@@ -0,0 +1,48 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskConfigSecrets(t *testing.T) {
+	a := assert.New(t)
+	conf := OrgConfig{
+		OrgValues: orgSyncOrgValues{
+			OrgValueNames.VirusTotal: "vt-key",
+			OrgValueNames.Domain:     "example.com",
+			"unknown":                "value",
+		},
+		Outputs: orgSyncOutputs{
+			"s3":  {Module: OutputTypes.S3, SecretKey: "s3-secret", Bucket: "bucket"},
+			"web": {Module: OutputTypes.Webhook, DestinationHost: "https://example.com", AuthHeaderValue: "token"},
+		},
+		Hives: orgSyncHives{
+			"secret": {"api": {Data: map[string]interface{}{"secret": "p4ss"}}},
+			"lookup": {"l": {Data: map[string]interface{}{"secret": "not one"}}},
+		},
+	}
+	catalog := map[OrgValueName]OrgValueMetadata{}
+	for _, md := range defaultOrgValueCatalog {
+		catalog[md.Name] = md
+	}
+
+	masked := maskConfigSecrets(conf, catalog)
+	a.Equal(orgSyncOrgValues{
+		OrgValueNames.VirusTotal: orgValueMask,
+		OrgValueNames.Domain:     "example.com",
+		"unknown":                orgValueMask,
+	}, masked.OrgValues)
+	a.Equal(orgValueMask, masked.Outputs["s3"].SecretKey)
+	a.Equal("bucket", masked.Outputs["s3"].Bucket)
+	a.Equal(orgValueMask, masked.Outputs["web"].AuthHeaderValue)
+	a.Equal("", masked.Outputs["web"].SecretKey)
+	a.Equal(orgValueMask, masked.Hives["secret"]["api"].Data["secret"])
+	a.Equal("not one", masked.Hives["lookup"]["l"].Data["secret"])
+
+	// The config itself is left untouched.
+	a.Equal("vt-key", conf.OrgValues[OrgValueNames.VirusTotal])
+	a.Equal("s3-secret", conf.Outputs["s3"].SecretKey)
+	a.Equal("p4ss", conf.Hives["secret"]["api"].Data["secret"])
+}
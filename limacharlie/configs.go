@@ -3,7 +3,9 @@ package limacharlie
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"os/user"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -22,26 +24,97 @@ type ConfigEnvironment struct {
 	OID    string `yaml:"oid"`
 	UID    string `yaml:"uid"`
 	APIKey string `yaml:"api_key"`
+
+	// Endpoints of the deployment of the environment,
+	// or the name of one of the EndpointPresets.
+	Endpoints      Endpoints `yaml:"endpoints,omitempty"`
+	EndpointPreset string    `yaml:"endpoint_preset,omitempty"`
 }
 
-// FromConfigFile updates self from the file path
-func (o *ClientOptions) FromConfigFile(configFilePath string, environmentName string) error {
+// readConfigFile reads a config file, expanding a leading "~/".
+func readConfigFile(configFilePath string) ([]byte, error) {
 	cleanPath := configFilePath
 	if strings.HasPrefix(cleanPath, "~/") {
 		usr, err := user.Current()
 		if err != nil {
-			return err
+			return nil, err
 		}
 		dir := usr.HomeDir
 		cleanPath = fmt.Sprintf("%s/%s", dir, cleanPath[2:])
 	}
-	data, err := ioutil.ReadFile(cleanPath)
+	return ioutil.ReadFile(cleanPath)
+}
+
+// FromConfigFile updates self from the file path
+func (o *ClientOptions) FromConfigFile(configFilePath string, environmentName string) error {
+	data, err := readConfigFile(configFilePath)
 	if err != nil {
 		return err
 	}
 	return o.FromConfigString(data, environmentName)
 }
 
+// credentialsFilePath returns the config file shared with the Python
+// SDK/CLI, the one of LC_CREDS_FILE or ~/.limacharlie by default.
+func credentialsFilePath() string {
+	if path := os.Getenv(credsEnvVar); path != "" {
+		return path
+	}
+	return defaultConfigFileLocation
+}
+
+// ConfigEnvironmentNames returns the sorted names of the environments
+// of a config file, "default" included when its top level is set.
+func ConfigEnvironmentNames(configFilePath string) ([]string, error) {
+	data, err := readConfigFile(configFilePath)
+	if err != nil {
+		return nil, err
+	}
+	cfg := ConfigFile{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &cfg.ConfigEnvironment); err != nil {
+		return nil, err
+	}
+	unique := map[string]struct{}{}
+	if cfg.OID != "" || cfg.UID != "" {
+		unique["default"] = struct{}{}
+	}
+	for name := range cfg.Environments {
+		unique[name] = struct{}{}
+	}
+	names := []string{}
+	for name := range unique {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// NewClientFromEnvironment creates a client with the credentials of a
+// named environment of the config file used by the Python SDK/CLI, like
+// "prod-msp", ignoring the credentials of the environment variables.
+// The file is the one of LC_CREDS_FILE, or ~/.limacharlie by default.
+func NewClientFromEnvironment(environmentName string, logger LCLogger) (*Client, error) {
+	if logger == nil {
+		logger = &LCLoggerEmpty{}
+	}
+	return NewClientFromLoader(ClientOptions{Environment: environmentName},
+		logger,
+		NewFileClientOptionLoader(credentialsFilePath()),
+	)
+}
+
+// NewOrganizationFromEnvironment is NewClientFromEnvironment for an Org.
+func NewOrganizationFromEnvironment(environmentName string, logger LCLogger) (*Organization, error) {
+	c, err := NewClientFromEnvironment(environmentName, logger)
+	if err != nil {
+		return nil, err
+	}
+	return NewOrganization(c)
+}
+
 // FromConfigString updates self from strings
 func (o *ClientOptions) FromConfigString(configFileString []byte, environmentName string) error {
 	cfg := ConfigFile{}
@@ -66,6 +139,10 @@ func (o *ClientOptions) FromConfig(cfg ConfigFile, environmentName string) error
 	var ok bool
 	if environmentName == "default" {
 		env = cfg.ConfigEnvironment
+		// The Python CLI can also store it with the named ones.
+		if named, ok := cfg.Environments[environmentName]; ok && env.OID == "" && env.UID == "" {
+			env = named
+		}
 	} else if env, ok = cfg.Environments[environmentName]; !ok {
 		return NewInvalidClientOptionsError(fmt.Sprintf("environment %s not found", environmentName))
 	}
@@ -74,12 +151,14 @@ func (o *ClientOptions) FromConfig(cfg ConfigFile, environmentName string) error
 	o.OID = env.OID
 	o.UID = env.UID
 	o.APIKey = env.APIKey
+	o.Endpoints = env.Endpoints
+	o.EndpointPreset = env.EndpointPreset
 
 	return nil
 }
 
 func (o *ClientOptions) validateMinimumRequirements() error {
-	if o.OID == "" && o.UID == "" {
+	if o.OID == "" && o.UID == "" && o.OAuth == nil {
 		return newLCError(lcErrClientMissingRequirements)
 	}
 	return nil
@@ -93,5 +172,10 @@ func (o *ClientOptions) validate() error {
 	if err := validateUUID(o.APIKey); err != nil {
 		return NewInvalidClientOptionsError(fmt.Sprintf("invalid APIKey: %v", err))
 	}
+	if o.EndpointPreset != "" {
+		if _, err := EndpointsFromPreset(o.EndpointPreset); err != nil {
+			return err
+		}
+	}
 	return nil
 }
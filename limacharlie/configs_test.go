@@ -1,6 +1,9 @@
 package limacharlie
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/suite"
@@ -50,3 +53,53 @@ func (s *ConfigsTestSuite) TestFromConfigStringFromEnvironment() {
 	s.Equal(o.UID, "81111111-2222-3333-4444-555555555555")
 	s.Equal(o.APIKey, "91111111-2222-3333-4444-555555555555")
 }
+
+func (s *ConfigsTestSuite) TestFromConfigStringNamedDefault() {
+	o := ClientOptions{}
+	s.NoError(o.FromConfigString([]byte(`
+env:
+  default:
+    oid: 11111111-2222-3333-4444-555555555555
+    api_key: 31111111-2222-3333-4444-555555555555`), ""))
+	s.Equal("11111111-2222-3333-4444-555555555555", o.OID)
+	s.Equal("31111111-2222-3333-4444-555555555555", o.APIKey)
+}
+
+func (s *ConfigsTestSuite) TestNewClientFromEnvironment() {
+	path := filepath.Join(s.T().TempDir(), "creds")
+	s.NoError(ioutil.WriteFile(path, []byte(testConfig), 0600))
+	defer os.Setenv(credsEnvVar, os.Getenv(credsEnvVar))
+	os.Setenv(credsEnvVar, path)
+
+	names, err := ConfigEnvironmentNames(path)
+	s.NoError(err)
+	s.Equal([]string{"default", "ttt", "vvv"}, names)
+
+	c, err := NewClientFromEnvironment("ttt", nil)
+	s.NoError(err)
+	s.Equal("41111111-2222-3333-4444-555555555555", c.options.OID)
+	s.Equal("61111111-2222-3333-4444-555555555555", c.options.APIKey)
+
+	_, err = NewClientFromEnvironment("missing", nil)
+	s.Error(err)
+}
+
+func (s *ConfigsTestSuite) TestNewClientCurrentEnvironment() {
+	path := filepath.Join(s.T().TempDir(), "creds")
+	s.NoError(ioutil.WriteFile(path, []byte(testConfig), 0600))
+	for _, name := range []string{credsEnvVar, environmentNameEnvVar, oidEnvVar, uidEnvVar, keyEnvVar} {
+		defer os.Setenv(name, os.Getenv(name))
+		os.Unsetenv(name)
+	}
+	os.Setenv(credsEnvVar, path)
+	os.Setenv(environmentNameEnvVar, "vvv")
+
+	c, err := NewClient(ClientOptions{}, nil)
+	s.NoError(err)
+	s.Equal("71111111-2222-3333-4444-555555555555", c.options.OID)
+
+	// Loaders without a path, like of an unset LC_CREDS_FILE, are skipped.
+	opts, err := NewFileClientOptionLoader("").Load(ClientOptions{OID: "x"})
+	s.NoError(err)
+	s.Equal("x", opts.OID)
+}
@@ -0,0 +1,16 @@
+package limacharlie
+
+// Detection is a detection as generated by a D&R rule report action.
+type Detection struct {
+	DetectID   string  `json:"detect_id"`
+	Category   string  `json:"cat"`
+	Source     string  `json:"source"`
+	SourceRule string  `json:"source_rule"`
+	Namespace  string  `json:"namespace,omitempty"`
+	Author     string  `json:"author,omitempty"`
+	Link       string  `json:"link,omitempty"`
+	Routing    Routing `json:"routing"`
+	Detect     Dict    `json:"detect"`
+	Metadata   Dict    `json:"detect_mtd,omitempty"`
+	TimeStamp  int64   `json:"ts"`
+}
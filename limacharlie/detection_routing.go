@@ -0,0 +1,156 @@
+package limacharlie
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DetectionCategory is the name of detections, the "cat"
+// they are reported with by D&R rules.
+type DetectionCategory = string
+
+// detectionRoutingFPPrefix prefixes the names of the FP rules
+// implementing the detection-routing section, which the fps
+// section leaves alone.
+const detectionRoutingFPPrefix = "detection-routing-"
+
+// DetectionRoute is the routing of a category of detections across
+// the Org, independently of the D&R rules reporting them.
+type DetectionRoute struct {
+	// IsMuted drops the detections of the category
+	// before they reach the outputs and the detection feed.
+	IsMuted bool `json:"muted,omitempty" yaml:"muted,omitempty"`
+}
+
+type orgSyncDetectionRouting = map[DetectionCategory]DetectionRoute
+
+func isDetectionRoutingFPRule(name FPRuleName) bool {
+	return strings.HasPrefix(name, detectionRoutingFPPrefix)
+}
+
+func detectionRoutingFPRuleName(category DetectionCategory) FPRuleName {
+	return detectionRoutingFPPrefix + category
+}
+
+// detectionRoutingFPRule is the detection of the FP rule muting a category.
+func detectionRoutingFPRule(category DetectionCategory) OrgSyncFPRule {
+	return OrgSyncFPRule{
+		Detection: Dict{
+			"op":    "is",
+			"path":  "cat",
+			"value": category,
+		},
+	}
+}
+
+func syncFetchDetectionRouting(store FPRuleStore) (orgSyncDetectionRouting, error) {
+	orgRules, err := store.FPRules()
+	if err != nil {
+		return nil, err
+	}
+	routing := orgSyncDetectionRouting{}
+	for name := range orgRules {
+		if !isDetectionRoutingFPRule(name) {
+			continue
+		}
+		routing[strings.TrimPrefix(name, detectionRoutingFPPrefix)] = DetectionRoute{IsMuted: true}
+	}
+	return routing, nil
+}
+
+// syncDetectionRouting mutes the categories with an FP rule each.
+// Categories in the config which are not muted are unmuted, the
+// ones absent from the config only with IsForce.
+func syncDetectionRouting(store FPRuleStore, routing orgSyncDetectionRouting, options SyncOptions) ([]OrgSyncOperation, error) {
+	if !options.IsForce && len(routing) == 0 {
+		return nil, nil
+	}
+
+	ops := []OrgSyncOperation{}
+	orgRules, err := store.FPRules()
+	if err != nil {
+		return ops, err
+	}
+
+	for category, route := range routing {
+		if category == "" {
+			return ops, fmt.Errorf("empty detection category")
+		}
+		name := detectionRoutingFPRuleName(category)
+		orgRule, found := orgRules[name]
+		if !route.IsMuted {
+			if !found {
+				ops = options.appendOp(ops, OrgSyncOperation{
+					ElementType: OrgSyncOperationElementType.DetectionRoute,
+					ElementName: category,
+				})
+				continue
+			}
+			var err error
+			if ops, err = syncDetectionRoutingRemove(store, category, ops, options); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		rule := detectionRoutingFPRule(category)
+		if found && rule.DetectionEquals(orgRule) {
+			ops = options.appendOp(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.DetectionRoute,
+				ElementName: category,
+			})
+			continue
+		}
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.DetectionRoute,
+			ElementName: category,
+			IsAdded:     true,
+			IsUpdated:   found,
+		}
+		if options.IsDryRun {
+			ops = options.appendOp(ops, op)
+			continue
+		}
+		if err := store.FPRuleAdd(name, rule.Detection, FPRuleOptions{IsReplace: true}); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
+
+	if !options.IsForce {
+		return ops, nil
+	}
+
+	// Unmute the categories not in the config.
+	for name := range orgRules {
+		if !isDetectionRoutingFPRule(name) {
+			continue
+		}
+		category := strings.TrimPrefix(name, detectionRoutingFPPrefix)
+		if _, found := routing[category]; found {
+			continue
+		}
+		var err error
+		if ops, err = syncDetectionRoutingRemove(store, category, ops, options); err != nil {
+			return ops, err
+		}
+	}
+	return ops, nil
+}
+
+func syncDetectionRoutingRemove(store FPRuleStore, category DetectionCategory, ops []OrgSyncOperation, options SyncOptions) ([]OrgSyncOperation, error) {
+	op := OrgSyncOperation{
+		ElementType: OrgSyncOperationElementType.DetectionRoute,
+		ElementName: category,
+		IsRemoved:   true,
+	}
+	if options.IsDryRun {
+		return options.appendOp(ops, op), nil
+	}
+	if err := store.FPRuleDelete(detectionRoutingFPRuleName(category)); err != nil {
+		return options.failOp(ops, op, err)
+	}
+	return options.appendOp(ops, op), nil
+}
@@ -0,0 +1,82 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type fakeFPRuleStore struct {
+	rules map[FPRuleName]FPRule
+}
+
+func (s *fakeFPRuleStore) FPRules() (map[FPRuleName]FPRule, error) {
+	rules := map[FPRuleName]FPRule{}
+	for k, v := range s.rules {
+		rules[k] = v
+	}
+	return rules, nil
+}
+
+func (s *fakeFPRuleStore) FPRuleAdd(name FPRuleName, detection interface{}, opts ...FPRuleOptions) error {
+	rule := FPRule{Name: name, Detection: detection.(Dict)}
+	for _, o := range opts {
+		rule.Description = o.Description
+	}
+	s.rules[name] = rule
+	return nil
+}
+
+func (s *fakeFPRuleStore) FPRuleDelete(name FPRuleName) error {
+	delete(s.rules, name)
+	return nil
+}
+
+func TestSyncDetectionRouting(t *testing.T) {
+	a := assert.New(t)
+
+	conf := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(`
+detection-routing:
+  noisy-scanner:
+    muted: true
+  was-muted: {}
+`), &conf))
+	a.Equal(orgSyncDetectionRouting{
+		"noisy-scanner": {IsMuted: true},
+		"was-muted":     {},
+	}, conf.DetectionRouting)
+
+	store := &fakeFPRuleStore{rules: map[FPRuleName]FPRule{
+		"detection-routing-was-muted": {Detection: detectionRoutingFPRule("was-muted").Detection},
+		"detection-routing-stale":     {Detection: detectionRoutingFPRule("stale").Detection},
+		"user-fp":                     {Detection: Dict{"op": "is", "path": "cat", "value": "x"}},
+	}}
+
+	ops, err := syncDetectionRouting(store, conf.DetectionRouting, SyncOptions{})
+	a.NoError(err)
+	a.Len(ops, 2)
+	a.Contains(store.rules, "detection-routing-noisy-scanner")
+	a.NotContains(store.rules, "detection-routing-was-muted")
+	a.Contains(store.rules, "detection-routing-stale")
+
+	ops, err = syncDetectionRouting(store, conf.DetectionRouting, SyncOptions{IsForce: true})
+	a.NoError(err)
+	a.NotContains(store.rules, "detection-routing-stale")
+	a.Contains(store.rules, "user-fp")
+	a.Len(ops, 3)
+
+	// The fps section ignores the rules of the routing.
+	_, err = syncFPRules(store, orgSyncFPRules{}, SyncOptions{IsForce: true})
+	a.NoError(err)
+	a.Contains(store.rules, "detection-routing-noisy-scanner")
+	a.NotContains(store.rules, "user-fp")
+
+	fetched, err := syncFetchDetectionRouting(store)
+	a.NoError(err)
+	a.Equal(orgSyncDetectionRouting{"noisy-scanner": {IsMuted: true}}, fetched)
+	fps, err := syncFetchFPRules(store)
+	a.NoError(err)
+	a.Empty(fps)
+}
@@ -0,0 +1,87 @@
+package limacharlie
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// defaultDetectionQueueSize is the number of detections buffered
+// when no FirehoseOptions.MaxMessageCount is set, an unbuffered
+// queue would drop those received while one is being yielded.
+const defaultDetectionQueueSize = 1000
+
+// StreamDetectionsOptions configures the temporary output
+// used to stream detections.
+type StreamDetectionsOptions struct {
+	// Listener receiving the detections, which must be
+	// reachable by LimaCharlie at ConnectTo:ConnectToPort.
+	Firehose FirehoseOptions
+
+	// Only receive detections of this category.
+	Category string
+	// Only receive detections from sensors with this tag.
+	Tag string
+	// Only receive detections from this sensor.
+	SensorID string
+}
+
+// StreamDetections creates a temporary output sending the detections
+// of the Org to a Firehose and yields them on the returned channel.
+// The output is removed and the channel closed when ctx is done.
+func (org *Organization) StreamDetections(ctx context.Context, opts StreamDetectionsOptions) (<-chan Detection, error) {
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	fhOpts := opts.Firehose
+	fhOpts.ParseMessage = false
+	if fhOpts.MaxMessageCount == 0 {
+		fhOpts.MaxMessageCount = defaultDetectionQueueSize
+	}
+	fh, err := NewFirehose(org, fhOpts, &FirehoseOutputOptions{
+		UniqueName:        fmt.Sprintf("detections_%s", hex.EncodeToString(suffix)),
+		Type:              OutputType.Detect,
+		Category:          opts.Category,
+		Tag:               opts.Tag,
+		SensorID:          opts.SensorID,
+		IsDeleteOnFailure: true,
+		// Self-signed certificates are used when none is provided.
+		IsNotStrictSSL: fhOpts.SSLCertPath == "",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := fh.Start(); err != nil {
+		return nil, err
+	}
+
+	detections := make(chan Detection)
+	go func() {
+		defer close(detections)
+		defer fh.Shutdown()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-fh.Messages:
+				if !ok {
+					return
+				}
+				d := Detection{}
+				if err := json.Unmarshal([]byte(msg.RawContent), &d); err != nil {
+					org.logger.Warn(fmt.Sprintf("invalid detection received: %v", err))
+					continue
+				}
+				select {
+				case detections <- d:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return detections, nil
+}
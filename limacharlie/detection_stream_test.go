@@ -0,0 +1,109 @@
+package limacharlie
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamDetections(t *testing.T) {
+	a := assert.New(t)
+
+	mu := sync.Mutex{}
+	outputs := map[string]url.Values{}
+	deleted := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		// Forms are only parsed for POST, PUT and PATCH.
+		body, _ := ioutil.ReadAll(r.Body)
+		form, err := url.ParseQuery(string(body))
+		a.NoError(err)
+		switch r.Method {
+		case http.MethodPost:
+			outputs[form.Get("name")] = form
+		case http.MethodDelete:
+			deleted = append(deleted, form.Get("name"))
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	detections, err := org.StreamDetections(ctx, StreamDetectionsOptions{
+		Firehose: FirehoseOptions{
+			ListenOnPort:  3001,
+			ListenOnIP:    net.ParseIP("127.0.0.1"),
+			ConnectTo:     "example.com",
+			ConnectToPort: 443,
+		},
+		Category: "suspicious-exec",
+		Tag:      "vip",
+		SensorID: "s1",
+	})
+	if !a.NoError(err) {
+		return
+	}
+
+	// The filters are applied by the temporary output.
+	mu.Lock()
+	if a.Len(outputs, 1) {
+		for name, form := range outputs {
+			a.Regexp(`^tmp_live_detections_[0-9a-f]{16}$`, name)
+			a.Equal("detect", form.Get("type"))
+			a.Equal("suspicious-exec", form.Get("cat"))
+			a.Equal("vip", form.Get("tag"))
+			a.Equal("s1", form.Get("sid"))
+			a.Equal("example.com:443", form.Get("dest_host"))
+		}
+	}
+	mu.Unlock()
+
+	conn, err := getTestFeeder(3001)
+	if !a.NoError(err) {
+		return
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	for _, l := range []string{
+		`{"detect_id":"d1","cat":"suspicious-exec","routing":{"sid":"s1"}}`,
+		`not a detection`,
+		`{"detect_id":"d2","cat":"suspicious-exec","routing":{"sid":"s1"}}`,
+	} {
+		_, err := conn.Write([]byte(fmt.Sprintf("%s\n", l)))
+		a.NoError(err)
+	}
+
+	// Invalid detections are skipped.
+	for _, expected := range []string{"d1", "d2"} {
+		select {
+		case d := <-detections:
+			a.Equal(expected, d.DetectID)
+			a.Equal("s1", d.Routing.SID)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("detection %s not received", expected)
+		}
+	}
+
+	// Cancelling removes the output before closing the channel.
+	cancel()
+	select {
+	case _, ok := <-detections:
+		a.False(ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("detections not closed")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for name := range outputs {
+		a.Equal([]string{name}, deleted)
+	}
+}
@@ -0,0 +1,173 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// FindByPath returns the value at a "/" separated path, like
+// "event/FILE_PATH", as used in D&R rules. Elements of lists
+// are selected by their index, like "event/MODULES/0/FILE_PATH".
+func (d Dict) FindByPath(path string) (interface{}, bool) {
+	var v interface{} = map[string]interface{}(d)
+	for _, k := range strings.Split(strings.Trim(path, "/"), "/") {
+		if k == "" {
+			continue
+		}
+		var ok bool
+		if v, ok = pathElement(v, k); !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func pathElement(v interface{}, k string) (interface{}, bool) {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		e, ok := c[k]
+		return e, ok
+	case Dict:
+		e, ok := c[k]
+		return e, ok
+	case []interface{}:
+		return listElement(c, k)
+	case List:
+		return listElement(c, k)
+	}
+	return nil, false
+}
+
+func listElement(l []interface{}, k string) (interface{}, bool) {
+	i, err := strconv.Atoi(k)
+	if err != nil || i < 0 || i >= len(l) {
+		return nil, false
+	}
+	return l[i], true
+}
+
+// GetString returns the string at the path, false
+// if absent or of another type.
+func (d Dict) GetString(path string) (string, bool) {
+	v, _ := d.FindByPath(path)
+	s, ok := v.(string)
+	return s, ok
+}
+
+// GetInt returns the integer at the path, false if absent, of another
+// type or a float with a fractional part. Numbers are unmarshaled as
+// int64 or float64 depending on how they were encoded, both are
+// supported here.
+func (d Dict) GetInt(path string) (int64, bool) {
+	v, _ := d.FindByPath(path)
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case int32:
+		return int64(n), true
+	case uint32:
+		return int64(n), true
+	case uint64:
+		if n > math.MaxInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	case float64:
+		if n != math.Trunc(n) || n > math.MaxInt64 || n < math.MinInt64 {
+			return 0, false
+		}
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// GetBool returns the boolean at the path, false
+// if absent or of another type.
+func (d Dict) GetBool(path string) (bool, bool) {
+	v, _ := d.FindByPath(path)
+	b, ok := v.(bool)
+	return b, ok
+}
+
+// GetDict returns the Dict at the path, false
+// if absent or of another type.
+func (d Dict) GetDict(path string) (Dict, bool) {
+	v, _ := d.FindByPath(path)
+	switch m := v.(type) {
+	case Dict:
+		return m, true
+	case map[string]interface{}:
+		return Dict(m), true
+	}
+	return nil, false
+}
+
+// GetList returns the List at the path, false
+// if absent or of another type.
+func (d Dict) GetList(path string) (List, bool) {
+	v, _ := d.FindByPath(path)
+	switch l := v.(type) {
+	case List:
+		return l, true
+	case []interface{}:
+		return List(l), true
+	}
+	return nil, false
+}
+
+// DeepCopy returns a copy of the Dict sharing
+// no maps or lists with the original.
+func (d Dict) DeepCopy() Dict {
+	if d == nil {
+		return nil
+	}
+	return deepCopyMap(d)
+}
+
+// DeepCopy returns a copy of the List sharing
+// no maps or lists with the original.
+func (l List) DeepCopy() List {
+	if l == nil {
+		return nil
+	}
+	return deepCopyList(l)
+}
+
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	n := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		n[k] = deepCopyValue(v)
+	}
+	return n
+}
+
+func deepCopyList(l []interface{}) []interface{} {
+	n := make([]interface{}, len(l))
+	for i, v := range l {
+		n[i] = deepCopyValue(v)
+	}
+	return n
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch c := v.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(c)
+	case Dict:
+		return Dict(deepCopyMap(c))
+	case []interface{}:
+		return deepCopyList(c)
+	case List:
+		return List(deepCopyList(c))
+	case []string:
+		return append([]string{}, c...)
+	}
+	return v
+}
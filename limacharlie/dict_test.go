@@ -0,0 +1,80 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDictAccessors(t *testing.T) {
+	a := assert.New(t)
+
+	d := Dict{}
+	a.NoError(json.Unmarshal([]byte(`{"event":{
+		"FILE_PATH":"c:\\a.exe",
+		"PROCESS_ID":42,
+		"RATIO":0.5,
+		"IS_SIGNED":true,
+		"MODULES":[{"FILE_PATH":"m.dll"}]
+	}}`), &d))
+
+	s, ok := d.GetString("event/FILE_PATH")
+	a.True(ok)
+	a.Equal(`c:\a.exe`, s)
+	s, ok = d.GetString("/event/MODULES/0/FILE_PATH")
+	a.True(ok)
+	a.Equal("m.dll", s)
+	_, ok = d.GetString("event/PROCESS_ID")
+	a.False(ok)
+	_, ok = d.GetString("event/MODULES/1/FILE_PATH")
+	a.False(ok)
+
+	i, ok := d.GetInt("event/PROCESS_ID")
+	a.True(ok)
+	a.Equal(int64(42), i)
+	_, ok = d.GetInt("event/RATIO")
+	a.False(ok)
+
+	b, ok := d.GetBool("event/IS_SIGNED")
+	a.True(ok)
+	a.True(b)
+
+	l, ok := d.GetList("event/MODULES")
+	a.True(ok)
+	a.Len(l, 1)
+	e, ok := d.GetDict("event")
+	a.True(ok)
+	a.Contains(e, "FILE_PATH")
+
+	// YAML numbers are ints.
+	y := Dict{}
+	a.NoError(yaml.Unmarshal([]byte("event:\n  PROCESS_ID: 42\n"), &y))
+	i, ok = y.GetInt("event/PROCESS_ID")
+	a.True(ok)
+	a.Equal(int64(42), i)
+
+	_, ok = Dict(nil).FindByPath("event")
+	a.False(ok)
+}
+
+func TestDictDeepCopy(t *testing.T) {
+	a := assert.New(t)
+
+	d := Dict{
+		"op":    "and",
+		"rules": List{Dict{"op": "is", "path": "event/FILE_PATH"}},
+		"tags":  []interface{}{"a"},
+	}
+	c := d.DeepCopy()
+	a.Equal(d, c)
+
+	c["rules"].(List)[0].(Dict)["op"] = "contains"
+	c["tags"].([]interface{})[0] = "b"
+	a.Equal("is", d["rules"].(List)[0].(Dict)["op"])
+	a.Equal("a", d["tags"].([]interface{})[0])
+
+	a.Nil(Dict(nil).DeepCopy())
+	a.Nil(List(nil).DeepCopy())
+}
@@ -0,0 +1,260 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RespondAction is a typed action of the respond
+// component of a D&R rule.
+type RespondAction interface {
+	// ActionName is the "action" of the action in the respond list.
+	ActionName() string
+	Validate() error
+}
+
+// ActionReport reports a detection.
+type ActionReport struct {
+	Name        string             `json:"name" yaml:"name"`
+	Priority    int                `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Metadata    Dict               `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+	Publish     *bool              `json:"publish,omitempty" yaml:"publish,omitempty"`
+	Suppression *DRRuleSuppression `json:"suppression,omitempty" yaml:"suppression,omitempty"`
+}
+
+func (a ActionReport) ActionName() string {
+	return "report"
+}
+
+func (a ActionReport) Validate() error {
+	if a.Name == "" {
+		return errors.New("name is required")
+	}
+	if a.Priority < 0 || a.Priority > 10 {
+		return fmt.Errorf("priority must be between 0 and 10: %d", a.Priority)
+	}
+	return validateActionSuppression(a.Suppression)
+}
+
+// ActionTask sends a command to the sensor.
+type ActionTask struct {
+	Command         string             `json:"command" yaml:"command"`
+	InvestigationID string             `json:"investigation,omitempty" yaml:"investigation,omitempty"`
+	Suppression     *DRRuleSuppression `json:"suppression,omitempty" yaml:"suppression,omitempty"`
+}
+
+func (a ActionTask) ActionName() string {
+	return "task"
+}
+
+func (a ActionTask) Validate() error {
+	if strings.TrimSpace(a.Command) == "" {
+		return errors.New("command is required")
+	}
+	return validateActionSuppression(a.Suppression)
+}
+
+// ActionAddTag tags the sensor, for TTL seconds if set.
+type ActionAddTag struct {
+	Tag string `json:"tag" yaml:"tag"`
+	TTL int64  `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+}
+
+func (a ActionAddTag) ActionName() string {
+	return "add tag"
+}
+
+func (a ActionAddTag) Validate() error {
+	if a.Tag == "" {
+		return errors.New("tag is required")
+	}
+	if a.TTL < 0 {
+		return fmt.Errorf("ttl must be positive: %d", a.TTL)
+	}
+	return nil
+}
+
+// ActionIsolate isolates the sensor from the network.
+type ActionIsolate struct{}
+
+func (a ActionIsolate) ActionName() string {
+	return "isolate network"
+}
+
+func (a ActionIsolate) Validate() error {
+	return nil
+}
+
+// ActionServiceRequest sends a request to a service.
+type ActionServiceRequest struct {
+	Name        string             `json:"name" yaml:"name"`
+	Request     Dict               `json:"request" yaml:"request"`
+	Suppression *DRRuleSuppression `json:"suppression,omitempty" yaml:"suppression,omitempty"`
+}
+
+func (a ActionServiceRequest) ActionName() string {
+	return "service request"
+}
+
+func (a ActionServiceRequest) Validate() error {
+	if a.Name == "" {
+		return errors.New("name is required")
+	}
+	if a.Request == nil {
+		return errors.New("request is required")
+	}
+	if err := validateRequestSelector(a.Request); err != nil {
+		return err
+	}
+	return validateActionSuppression(a.Suppression)
+}
+
+// ActionExtensionRequest sends a request to an extension.
+type ActionExtensionRequest struct {
+	Extension   string             `json:"extension name" yaml:"extension name"`
+	Action      string             `json:"extension action" yaml:"extension action"`
+	Request     Dict               `json:"extension request" yaml:"extension request"`
+	Suppression *DRRuleSuppression `json:"suppression,omitempty" yaml:"suppression,omitempty"`
+}
+
+func (a ActionExtensionRequest) ActionName() string {
+	return "extension request"
+}
+
+func (a ActionExtensionRequest) Validate() error {
+	if a.Extension == "" {
+		return errors.New("extension name is required")
+	}
+	if a.Action == "" {
+		return errors.New("extension action is required")
+	}
+	if err := validateRequestSelector(a.Request); err != nil {
+		return err
+	}
+	return validateActionSuppression(a.Suppression)
+}
+
+func validateActionSuppression(s *DRRuleSuppression) error {
+	if s == nil {
+		return nil
+	}
+	return s.Validate()
+}
+
+// respondActionTypes creates the typed actions by name.
+var respondActionTypes = map[string]func() RespondAction{
+	"report":            func() RespondAction { return &ActionReport{} },
+	"task":              func() RespondAction { return &ActionTask{} },
+	"add tag":           func() RespondAction { return &ActionAddTag{} },
+	"isolate network":   func() RespondAction { return &ActionIsolate{} },
+	"service request":   func() RespondAction { return &ActionServiceRequest{} },
+	"extension request": func() RespondAction { return &ActionExtensionRequest{} },
+}
+
+// RespondActionToDict validates an action and returns
+// it in the format of the respond list.
+func RespondActionToDict(action RespondAction) (Dict, error) {
+	if err := action.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", action.ActionName(), err)
+	}
+	b, err := json.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+	d := Dict{}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	d["action"] = action.ActionName()
+	return d, nil
+}
+
+// NewRespond validates the actions and returns the
+// respond component of a D&R rule.
+func NewRespond(actions ...RespondAction) (List, error) {
+	respond := List{}
+	for i, action := range actions {
+		d, err := RespondActionToDict(action)
+		if err != nil {
+			return nil, fmt.Errorf("action %d: %v", i, err)
+		}
+		respond = append(respond, d)
+	}
+	return respond, nil
+}
+
+// ParseRespondAction returns the typed version of an action of a respond
+// list. Actions without a typed version are returned as nil without error.
+func ParseRespondAction(action Dict) (RespondAction, error) {
+	name, _ := action["action"].(string)
+	if name == "" {
+		return nil, errors.New("action is required")
+	}
+	newAction, ok := respondActionTypes[name]
+	if !ok {
+		return nil, nil
+	}
+	b, err := json.Marshal(action)
+	if err != nil {
+		return nil, err
+	}
+	typed := newAction()
+	if err := json.Unmarshal(b, typed); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if err := typed.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return typed, nil
+}
+
+// ValidateRespond checks the actions of a respond list
+// which have a typed version.
+func ValidateRespond(respond List) error {
+	for i, e := range respond {
+		action, ok := toRespondDict(e)
+		if !ok {
+			return fmt.Errorf("action %d: not a dictionary", i)
+		}
+		if _, err := ParseRespondAction(action); err != nil {
+			return fmt.Errorf("action %d: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func toRespondDict(e interface{}) (Dict, bool) {
+	switch d := e.(type) {
+	case Dict:
+		return d, true
+	case map[string]interface{}:
+		return Dict(d), true
+	}
+	return nil, false
+}
+
+func lintDRRuleInvalidRespond(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	for name, rule := range conf.DRRules {
+		if err := ValidateRespond(rule.Response); err != nil {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Error,
+				Message:  fmt.Sprintf("invalid respond: %v", err),
+			}.at("rules", name))
+		}
+	}
+	for _, hiveName := range drRuleHives {
+		for name, data := range conf.Hives[hiveName] {
+			respond, _ := data.Data["respond"].([]interface{})
+			if err := ValidateRespond(List(respond)); err != nil {
+				findings = append(findings, LintFinding{
+					Severity: LintSeverities.Error,
+					Message:  fmt.Sprintf("invalid respond: %v", err),
+				}.at("hives", hiveName, name))
+			}
+		}
+	}
+	return findings
+}
@@ -0,0 +1,71 @@
+package limacharlie
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondActions(t *testing.T) {
+	a := assert.New(t)
+
+	respond, err := NewRespond(
+		ActionReport{Name: "evil", Priority: 3, Metadata: Dict{"author": "me"}},
+		ActionTask{Command: "history_dump", InvestigationID: "inv"},
+		ActionAddTag{Tag: "suspicious", TTL: 3600},
+		ActionIsolate{},
+		ActionServiceRequest{Name: "dumper", Request: Dict{"sid": "<<routing/sid>>"}},
+		ActionExtensionRequest{Extension: "ext-zeek", Action: "run", Request: Dict{"file": "a.pcap"}},
+	)
+	a.NoError(err)
+	a.Equal(List{
+		Dict{"action": "report", "name": "evil", "priority": int64(3), "metadata": map[string]interface{}{"author": "me"}},
+		Dict{"action": "task", "command": "history_dump", "investigation": "inv"},
+		Dict{"action": "add tag", "tag": "suspicious", "ttl": int64(3600)},
+		Dict{"action": "isolate network"},
+		Dict{"action": "service request", "name": "dumper", "request": map[string]interface{}{"sid": "<<routing/sid>>"}},
+		Dict{"action": "extension request", "extension name": "ext-zeek", "extension action": "run", "extension request": map[string]interface{}{"file": "a.pcap"}},
+	}, respond)
+	a.NoError(ValidateRespond(respond))
+
+	_, err = NewRespond(ActionReport{Name: "evil"}, ActionAddTag{})
+	a.EqualError(err, "action 1: add tag: tag is required")
+
+	action, err := ParseRespondAction(Dict{"action": "add tag", "tag": "t", "ttl": 60})
+	a.NoError(err)
+	a.Equal(&ActionAddTag{Tag: "t", TTL: 60}, action)
+
+	// Actions without a typed version are not validated.
+	action, err = ParseRespondAction(Dict{"action": "undelete sensor"})
+	a.NoError(err)
+	a.Nil(action)
+
+	a.EqualError(ValidateRespond(List{Dict{"action": "task"}}), "action 0: task: command is required")
+	a.EqualError(ValidateRespond(List{map[string]interface{}{"action": "report", "priority": "high", "name": "x"}}),
+		"action 0: report: json: cannot unmarshal string into Go struct field ActionReport.priority of type int")
+
+	conf := OrgConfig{
+		DRRules: map[string]CoreDRRule{
+			"bad": {Detect: Dict{"op": "exists"}, Response: List{Dict{"action": "service request"}}},
+		},
+		Hives: orgSyncHives{
+			"dr-service": {
+				"bad-service": SyncHiveData{Data: map[string]interface{}{
+					"detect":  map[string]interface{}{"op": "exists"},
+					"respond": []interface{}{map[string]interface{}{"action": "task"}},
+				}},
+			},
+		},
+	}
+	findings := conf.Lint()
+	found := []string{}
+	for _, f := range findings {
+		if f.Rule == "dr-rule-invalid-respond" {
+			found = append(found, f.Location)
+			a.Equal(LintSeverities.Error, f.Severity)
+		}
+	}
+	sort.Strings(found)
+	a.Equal([]string{"hives.dr-service.bad-service", "rules.bad"}, found)
+}
@@ -0,0 +1,256 @@
+package limacharlie
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DRLookups maps a lookup resource, like "hive://lookup/bad-domains",
+// to the values it contains. It is used to resolve the "lookup"
+// operator when evaluating rules locally.
+type DRLookups = map[string][]string
+
+// EvaluateDetection evaluates the detection component of a D&R rule
+// against an event locally, without using the API. The event is
+// the full event including its "routing" and "event" components.
+//
+// Only the stateless subset of the detection grammar is supported:
+// and, or, is, exists, contains, starts with, ends with, matches,
+// is greater than, is lower than, is platform, is tagged and lookup.
+func EvaluateDetection(detect Dict, event Dict, lookups DRLookups) (bool, error) {
+	return evalDetectionNode(map[string]interface{}(detect), map[string]interface{}(event), lookups)
+}
+
+func evalDetectionNode(node map[string]interface{}, event map[string]interface{}, lookups DRLookups) (bool, error) {
+	if !detectionMatchesEventType(node, event) {
+		return false, nil
+	}
+
+	op, _ := node["op"].(string)
+	isMatch, err := evalDetectionOp(op, node, event, lookups)
+	if err != nil {
+		return false, err
+	}
+	if isNot, _ := node["not"].(bool); isNot {
+		return !isMatch, nil
+	}
+	return isMatch, nil
+}
+
+func detectionMatchesEventType(node map[string]interface{}, event map[string]interface{}) bool {
+	types := []string{}
+	if t, ok := node["event"].(string); ok {
+		types = append(types, t)
+	}
+	if ts, ok := node["events"].([]interface{}); ok {
+		for _, t := range ts {
+			types = append(types, fmt.Sprintf("%v", t))
+		}
+	}
+	if len(types) == 0 {
+		return true
+	}
+	for _, eventType := range detectionPathValues(event, "routing/event_type") {
+		for _, t := range types {
+			if t == eventType {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func evalDetectionOp(op string, node map[string]interface{}, event map[string]interface{}, lookups DRLookups) (bool, error) {
+	path, _ := node["path"].(string)
+	values := detectionPathValues(event, path)
+	isCaseSensitive := true
+	if cs, ok := node["case sensitive"].(bool); ok {
+		isCaseSensitive = cs
+	}
+	expected := ""
+	if v, ok := node["value"]; ok {
+		expected = fmt.Sprintf("%v", v)
+	}
+	if !isCaseSensitive {
+		expected = strings.ToLower(expected)
+	}
+
+	switch op {
+	case "and", "or":
+		rules, ok := node["rules"].([]interface{})
+		if !ok {
+			return false, fmt.Errorf("'%s' operator requires a list of rules", op)
+		}
+		for _, r := range rules {
+			sub, ok := r.(map[string]interface{})
+			if !ok {
+				if d, isDict := r.(Dict); isDict {
+					sub = d
+				} else {
+					return false, fmt.Errorf("invalid rule in '%s': %v", op, r)
+				}
+			}
+			isMatch, err := evalDetectionNode(sub, event, lookups)
+			if err != nil {
+				return false, err
+			}
+			if op == "and" && !isMatch {
+				return false, nil
+			}
+			if op == "or" && isMatch {
+				return true, nil
+			}
+		}
+		return op == "and", nil
+	case "exists":
+		return len(values) != 0, nil
+	case "is", "contains", "starts with", "ends with":
+		for _, v := range values {
+			if !isCaseSensitive {
+				v = strings.ToLower(v)
+			}
+			if (op == "is" && v == expected) ||
+				(op == "contains" && strings.Contains(v, expected)) ||
+				(op == "starts with" && strings.HasPrefix(v, expected)) ||
+				(op == "ends with" && strings.HasSuffix(v, expected)) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "matches":
+		pattern, _ := node["re"].(string)
+		if !isCaseSensitive {
+			pattern = "(?i)" + pattern
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return false, fmt.Errorf("invalid regular expression %q: %v", pattern, err)
+		}
+		for _, v := range values {
+			if re.MatchString(v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "is greater than", "is lower than":
+		threshold, err := strconv.ParseFloat(expected, 64)
+		if err != nil {
+			return false, fmt.Errorf("'%s' requires a numeric value: %v", op, err)
+		}
+		for _, v := range values {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			if (op == "is greater than" && n > threshold) || (op == "is lower than" && n < threshold) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "is platform":
+		name, _ := node["name"].(string)
+		for _, v := range detectionPathValues(event, "routing/plat") {
+			if n, err := strconv.ParseUint(v, 10, 32); err == nil {
+				v = PlatformStrings[uint32(n)]
+			}
+			if strings.EqualFold(v, name) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "is tagged":
+		tag, _ := node["tag"].(string)
+		for _, v := range detectionPathValues(event, "routing/tags/*") {
+			if v == tag {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "lookup":
+		resource, _ := node["resource"].(string)
+		lookup, ok := lookups[resource]
+		if !ok {
+			return false, fmt.Errorf("lookup resource not provided: %s", resource)
+		}
+		for _, v := range values {
+			for _, l := range lookup {
+				if v == l || (!isCaseSensitive && strings.EqualFold(v, l)) {
+					return true, nil
+				}
+			}
+		}
+		return false, nil
+	case "":
+		return false, fmt.Errorf("missing operator in rule: %v", node)
+	}
+	return false, fmt.Errorf("unsupported operator for local evaluation: %s", op)
+}
+
+// detectionPathValues returns the string representation of all the
+// values found at the path, where a "*" component matches any element.
+func detectionPathValues(event map[string]interface{}, path string) []string {
+	current := []interface{}{event}
+	for _, component := range strings.Split(strings.Trim(path, "/"), "/") {
+		if component == "" {
+			continue
+		}
+		next := []interface{}{}
+		for _, c := range current {
+			switch v := c.(type) {
+			case map[string]interface{}:
+				next = append(next, detectionPathChildren(v, component)...)
+			case Dict:
+				next = append(next, detectionPathChildren(v, component)...)
+			case []interface{}:
+				if component == "*" {
+					next = append(next, v...)
+				} else if i, err := strconv.Atoi(component); err == nil && i >= 0 && i < len(v) {
+					next = append(next, v[i])
+				}
+			case []string:
+				for _, s := range v {
+					next = append(next, s)
+				}
+			}
+		}
+		current = next
+	}
+
+	out := []string{}
+	for _, c := range current {
+		switch v := c.(type) {
+		case nil:
+		case []interface{}:
+			for _, e := range v {
+				out = append(out, fmt.Sprintf("%v", e))
+			}
+		case []string:
+			out = append(out, v...)
+		default:
+			out = append(out, fmt.Sprintf("%v", v))
+		}
+	}
+	return out
+}
+
+func detectionPathChildren(m map[string]interface{}, component string) []interface{} {
+	if component != "*" {
+		if v, ok := m[component]; ok {
+			return []interface{}{v}
+		}
+		return nil
+	}
+	keys := []string{}
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := []interface{}{}
+	for _, k := range keys {
+		out = append(out, m[k])
+	}
+	return out
+}
@@ -1,9 +1,13 @@
 package limacharlie
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"sort"
 	"time"
 )
 
@@ -16,6 +20,17 @@ type NewDRRuleOptions struct {
 	IsEnabled bool
 	// Number of seconds before rule auto-deletes.
 	TTL int64
+	// Author recorded in the history of the rule, like
+	// the user and commit the rule originates from.
+	Author string
+	// Sensors the rule applies to, all of them if empty.
+	Filters *DRRuleTargets
+	// Priority of the rule, rules with a higher one are evaluated first.
+	Priority int
+	// Suppression limits how often the rule reports.
+	Suppression *DRRuleSuppression
+	// Description documents the rule, it is stored with it.
+	Description string
 }
 
 type DRRuleFilter func(map[string]string)
@@ -28,6 +43,12 @@ type drAddRuleRequest struct {
 	IsEnabled bool   `json:"is_enabled"`
 	ExpireOn  int64  `json:"expire_on,omitempty"`
 	Namespace string `json:"namespace,omitempty"`
+	Author    string `json:"author,omitempty"`
+
+	Filters     string `json:"filters,omitempty"`
+	Priority    int    `json:"priority,omitempty"`
+	Suppression string `json:"suppression,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 type CoreDRRule struct {
@@ -36,6 +57,106 @@ type CoreDRRule struct {
 	Detect    Dict   `json:"detect" yaml:"detect"`
 	Response  List   `json:"respond" yaml:"respond"`
 	IsEnabled *bool  `json:"is_enabled,omitempty" yaml:"is_enabled,omitempty"`
+	// Description documents the rule, stored with it so that
+	// it survives a fetch.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	Filters     *DRRuleTargets     `json:"filters,omitempty" yaml:"filters,omitempty"`
+	Priority    int                `json:"priority,omitempty" yaml:"priority,omitempty"`
+	Suppression *DRRuleSuppression `json:"suppression,omitempty" yaml:"suppression,omitempty"`
+	// TTL in seconds after which a pushed rule is deleted.
+	TTL int64 `json:"ttl,omitempty" yaml:"ttl,omitempty"`
+	// ExpireOn is the time at which the rule is deleted, as reported by the API.
+	ExpireOn int64 `json:"expire_on,omitempty" yaml:"-"`
+	// ReadOnly rules are owned by a service or an extension, like the
+	// ones of the "replicant" namespace, and are only fetched, sync
+	// never adding, modifying or removing them.
+	ReadOnly bool `json:"read_only,omitempty" yaml:"read_only,omitempty"`
+}
+
+// DRRuleTargets restricts the sensors a D&R rule applies to.
+type DRRuleTargets struct {
+	Tags      []string   `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Platforms []Platform `json:"platforms,omitempty" yaml:"platforms,omitempty"`
+}
+
+// DRRuleSuppression limits how often a D&R rule reports,
+// like at most MaxCount times per Period for the same Keys.
+type DRRuleSuppression struct {
+	MaxCount int      `json:"max_count,omitempty" yaml:"max_count,omitempty"`
+	MinCount int      `json:"min_count,omitempty" yaml:"min_count,omitempty"`
+	Period   string   `json:"period,omitempty" yaml:"period,omitempty"`
+	IsGlobal bool     `json:"is_global,omitempty" yaml:"is_global,omitempty"`
+	Keys     []string `json:"keys,omitempty" yaml:"keys,omitempty"`
+}
+
+// Validate checks the counts and that the period is a duration, like "1h".
+func (s DRRuleSuppression) Validate() error {
+	if s.MaxCount < 0 || s.MinCount < 0 {
+		return errors.New("suppression counts must be positive")
+	}
+	if s.MaxCount != 0 && s.MinCount > s.MaxCount {
+		return fmt.Errorf("suppression min_count %d is greater than max_count %d", s.MinCount, s.MaxCount)
+	}
+	if s.MaxCount == 0 && s.MinCount == 0 {
+		return errors.New("suppression requires max_count or min_count")
+	}
+	if s.Period == "" {
+		return errors.New("suppression period is required")
+	}
+	if _, err := time.ParseDuration(s.Period); err != nil {
+		return fmt.Errorf("invalid suppression period %q: %v", s.Period, err)
+	}
+	for _, k := range s.Keys {
+		if k == "" {
+			return errors.New("suppression keys cannot be empty")
+		}
+	}
+	return nil
+}
+
+// normalized returns the suppression with its period in the canonical
+// form of a duration, like "1h0m0s" for "60m", and its keys sorted.
+func (s *DRRuleSuppression) normalized() *DRRuleSuppression {
+	if s == nil {
+		return nil
+	}
+	n := *s
+	if p, err := time.ParseDuration(s.Period); err == nil {
+		n.Period = p.String()
+	}
+	n.Keys = append([]string{}, s.Keys...)
+	sort.Strings(n.Keys)
+	return &n
+}
+
+// normalizeRespond returns a copy of the respond list
+// with the suppression of its actions normalized.
+func normalizeRespond(respond List) List {
+	normalized := make(List, 0, len(respond))
+	for _, e := range respond {
+		action, ok := toRespondDict(e)
+		if !ok || action["suppression"] == nil {
+			normalized = append(normalized, e)
+			continue
+		}
+		suppression := &DRRuleSuppression{}
+		b, err := json.Marshal(action["suppression"])
+		if err == nil {
+			err = json.Unmarshal(b, suppression)
+		}
+		if err != nil {
+			normalized = append(normalized, e)
+			continue
+		}
+		c := Dict{}
+		for k, v := range action {
+			c[k] = v
+		}
+		c["suppression"] = suppression.normalized()
+		normalized = append(normalized, c)
+	}
+	return normalized
 }
 
 // DRRuleAdd add a D&R Rule to an LC organization
@@ -46,9 +167,11 @@ func (org Organization) DRRuleAdd(name string, detection interface{}, response i
 	}
 	for _, o := range opt {
 		reqOpt = o
-		if reqOpt.TTL != 0 {
-			reqOpt.TTL = time.Now().Unix() + reqOpt.TTL
-		}
+	}
+	// The API expects the absolute time at which the rule expires.
+	expireOn := int64(0)
+	if reqOpt.TTL != 0 {
+		expireOn = time.Now().Unix() + reqOpt.TTL
 	}
 
 	serialDet, err := json.Marshal(detection)
@@ -60,15 +183,38 @@ func (org Organization) DRRuleAdd(name string, detection interface{}, response i
 		return err
 	}
 
-	request := makeDefaultRequest(&resp).withFormData(drAddRuleRequest{
+	req := drAddRuleRequest{
 		Name:      name,
 		IsReplace: reqOpt.IsReplace,
 		Detection: string(serialDet),
 		Response:  string(serialResp),
 		IsEnabled: reqOpt.IsEnabled,
-		ExpireOn:  reqOpt.TTL,
+		ExpireOn:  expireOn,
 		Namespace: reqOpt.Namespace,
-	})
+		Author:    reqOpt.Author,
+		Priority:  reqOpt.Priority,
+
+		Description: reqOpt.Description,
+	}
+	if reqOpt.Filters != nil {
+		serialFilters, err := json.Marshal(reqOpt.Filters)
+		if err != nil {
+			return err
+		}
+		req.Filters = string(serialFilters)
+	}
+	if reqOpt.Suppression != nil {
+		if err := reqOpt.Suppression.Validate(); err != nil {
+			return err
+		}
+		serialSuppression, err := json.Marshal(reqOpt.Suppression)
+		if err != nil {
+			return err
+		}
+		req.Suppression = string(serialSuppression)
+	}
+
+	request := makeDefaultRequest(&resp).withFormData(req)
 	if err := org.client.reliableRequest(http.MethodPost, fmt.Sprintf("rules/%s", org.client.options.OID), request); err != nil {
 		return err
 	}
@@ -77,19 +223,50 @@ func (org Organization) DRRuleAdd(name string, detection interface{}, response i
 
 // DRRules get all D&R rules for an LC organization
 func (org Organization) DRRules(filters ...DRRuleFilter) (map[string]Dict, error) {
-	req := map[string]string{}
+	rules := map[string]Dict{}
+	var err error
+	org.DRRulesIter(context.Background(), filters...)(func(name string, rule Dict, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		rules[name] = rule
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
 
+// DRRuleGet returns a single D&R rule as stored, including the
+// defaults set by the API, in the "general" namespace unless
+// filtered with WithNamespace().
+func (org Organization) DRRuleGet(name string, filters ...DRRuleFilter) (CoreDRRule, error) {
+	req := map[string]string{}
 	for _, f := range filters {
 		f(req)
 	}
 
-	resp := map[string]Dict{}
-
+	resp := Dict{}
 	request := makeDefaultRequest(&resp).withQueryData(req)
-	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("rules/%s", org.client.options.OID), request); err != nil {
-		return nil, err
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("rules/%s/%s", org.client.options.OID, url.PathEscape(name)), request); err != nil {
+		if isRESTNotFound(err) {
+			return CoreDRRule{}, ErrorResourceNotFound
+		}
+		return CoreDRRule{}, err
 	}
-	return resp, nil
+	if len(resp) == 0 {
+		return CoreDRRule{}, ErrorResourceNotFound
+	}
+	rule := CoreDRRule{}
+	if err := resp.UnMarshalToStruct(&rule); err != nil {
+		return CoreDRRule{}, err
+	}
+	if rule.Name == "" {
+		rule.Name = name
+	}
+	return rule, nil
 }
 
 // DRRuleDelete delete a D&R rule from an LC organization
@@ -110,36 +287,96 @@ func (org Organization) DRRuleDelete(name string, filters ...DRRuleFilter) error
 	return nil
 }
 
-func (d CoreDRRule) Equal(dr CoreDRRule) bool {
-	if !d.IsInSameNamespace(dr) {
-		return false
-	}
-	if *d.IsEnabled != *dr.IsEnabled {
-		return false
+// DRRuleVersion is a past version of a D&R rule.
+type DRRuleVersion struct {
+	Version   int64      `json:"version"`
+	Author    string     `json:"author"`
+	Timestamp int64      `json:"ts"`
+	Rule      CoreDRRule `json:"rule"`
+}
+
+// DRRuleHistory returns the versions of a D&R rule, oldest first.
+// Rules in namespaces without history return ErrorResourceNotFound.
+func (org Organization) DRRuleHistory(name string, filters ...DRRuleFilter) ([]DRRuleVersion, error) {
+	req := map[string]string{
+		"name": name,
 	}
-	j1, err := json.Marshal(d.Detect)
-	if err != nil {
-		return false
+	for _, f := range filters {
+		f(req)
 	}
-	j2, err := json.Marshal(dr.Detect)
-	if err != nil {
-		return false
+
+	resp := struct {
+		History []DRRuleVersion `json:"history"`
+	}{}
+	request := makeDefaultRequest(&resp).withQueryData(req)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("rules/%s/history", org.client.options.OID), request); err != nil {
+		return nil, err
 	}
-	if string(j1) != string(j2) {
-		return false
+	if len(resp.History) == 0 {
+		return nil, ErrorResourceNotFound
 	}
-	j1, err = json.Marshal(d.Response)
+	sort.Slice(resp.History, func(i, j int) bool {
+		return resp.History[i].Version < resp.History[j].Version
+	})
+	return resp.History, nil
+}
+
+// DRRuleRestoreVersion replaces a D&R rule with one of its past versions.
+func (org Organization) DRRuleRestoreVersion(name string, version int64, filters ...DRRuleFilter) error {
+	history, err := org.DRRuleHistory(name, filters...)
 	if err != nil {
-		return false
+		return err
 	}
-	j2, err = json.Marshal(dr.Response)
-	if err != nil {
-		return false
+	for _, v := range history {
+		if v.Version != version {
+			continue
+		}
+		isEnabled := true
+		if v.Rule.IsEnabled != nil {
+			isEnabled = *v.Rule.IsEnabled
+		}
+		return org.DRRuleAdd(name, v.Rule.Detect, v.Rule.Response, NewDRRuleOptions{
+			IsReplace:   true,
+			Namespace:   v.Rule.Namespace,
+			IsEnabled:   isEnabled,
+			Filters:     v.Rule.Filters,
+			Priority:    v.Rule.Priority,
+			Suppression: v.Rule.Suppression,
+			Description: v.Rule.Description,
+		})
+	}
+	return ErrorResourceNotFound
+}
+
+func (d CoreDRRule) Equal(dr CoreDRRule) bool {
+	return syncNormalizers.DRRule.Equal(d.comparable(), dr.comparable())
+}
+
+// comparable returns the content of the rule the way it is
+// compared, with its namespace and respond actions normalized.
+func (d CoreDRRule) comparable() Dict {
+	namespace := d.Namespace
+	if namespace == "" {
+		namespace = "general"
 	}
-	if string(j1) != string(j2) {
-		return false
+	isEnabled := d.IsEnabled == nil || *d.IsEnabled
+	return Dict{
+		"namespace":   namespace,
+		"is_enabled":  isEnabled,
+		"detect":      d.Detect,
+		"respond":     normalizeRespond(d.Response),
+		"priority":    d.Priority,
+		"description": d.Description,
+		"filters":     d.Filters,
+		"suppression": d.Suppression.normalized(),
+		// The expiry of a rule is relative to when it is pushed,
+		// so only whether it expires can be compared.
+		"expires": d.expires(),
 	}
-	return true
+}
+
+func (d CoreDRRule) expires() bool {
+	return d.TTL != 0 || d.ExpireOn != 0
 }
 
 func (d CoreDRRule) IsInSameNamespace(dr CoreDRRule) bool {
@@ -152,8 +389,32 @@ func (d CoreDRRule) IsInSameNamespace(dr CoreDRRule) bool {
 	return d.Namespace == dr.Namespace
 }
 
+// IsReadOnlyDRRuleNamespace returns true if the rules of the namespace
+// are owned by services or extensions, like "replicant" or the one of
+// an extension, rather than by the Org in "general" or "managed".
+func IsReadOnlyDRRuleNamespace(namespace string) bool {
+	return namespace != "" && namespace != "general" && namespace != "managed"
+}
+
 func WithNamespace(namespace string) func(map[string]string) {
 	return func(m map[string]string) {
 		m["namespace"] = namespace
 	}
 }
+
+// DRRuleNamespaces returns the sorted namespaces of the D&R rules of
+// the Org the credentials can list, like "general", "managed" or the
+// ones of services, from the "dr.list" and "dr.list.<namespace>"
+// permissions.
+func (org Organization) DRRuleNamespaces() ([]string, error) {
+	who, err := org.client.whoAmI()
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []string{}
+	for ns := range org.resolveAvailableNamespaces(who) {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
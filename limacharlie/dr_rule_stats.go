@@ -0,0 +1,240 @@
+package limacharlie
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DRRuleStats are the detections of a D&R rule over a time range
+// and its last modification, where the API reports them.
+type DRRuleStats struct {
+	Name      DRRuleName `json:"name"`
+	Namespace string     `json:"namespace"`
+	// Hits is the number of detections reported by the rule.
+	Hits int `json:"hits"`
+	// LastHit is the time of the last detection, zero without hits.
+	LastHit time.Time `json:"last_hit"`
+	// LastModified is zero when the API does not report it.
+	LastModified time.Time `json:"last_modified"`
+	LastAuthor   string    `json:"last_author,omitempty"`
+}
+
+// detectionRuleName returns the name and namespace of the rule
+// that reported a detection, whose source rule is "<namespace>.<name>".
+func detectionRuleName(d Detection) (DRRuleName, string) {
+	ns := d.Namespace
+	if ns != "" {
+		return strings.TrimPrefix(d.SourceRule, ns+"."), ns
+	}
+	for _, known := range []string{"general", "managed", "service"} {
+		if strings.HasPrefix(d.SourceRule, known+".") {
+			return strings.TrimPrefix(d.SourceRule, known+"."), known
+		}
+	}
+	return d.SourceRule, "general"
+}
+
+// DRRuleHits counts the detections reported by each D&R rule between
+// start and end, keyed by rule name. Rules without detections are absent.
+// All the detections of the range are listed.
+func (org Organization) DRRuleHits(start time.Time, end time.Time) (map[DRRuleName]DRRuleStats, error) {
+	stats := map[DRRuleName]DRRuleStats{}
+	var err error
+	org.DetectionsIter(context.Background(), start, end)(func(d Detection, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		if d.SourceRule == "" {
+			return true
+		}
+		name, ns := detectionRuleName(d)
+		s := stats[name]
+		s.Name = name
+		s.Namespace = ns
+		s.Hits++
+		// Detection timestamps are in milliseconds.
+		if ts := time.Unix(0, d.TimeStamp*int64(time.Millisecond)); ts.After(s.LastHit) {
+			s.LastHit = ts
+		}
+		stats[name] = s
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// DRRuleMetadata returns the last modification of the D&R rules
+// of a namespace, from the hive storing them, keyed by rule name.
+func (org Organization) DRRuleMetadata(namespace string) (map[DRRuleName]DRRuleStats, error) {
+	if namespace == "" {
+		namespace = "general"
+	}
+	records, err := NewHiveClient(&org).ListMtd(HiveArgs{
+		HiveName:     "dr-" + namespace,
+		PartitionKey: org.client.options.OID,
+	})
+	if err != nil {
+		if isRESTNotFound(err) {
+			return nil, ErrorResourceNotFound
+		}
+		return nil, err
+	}
+	stats := map[DRRuleName]DRRuleStats{}
+	for name, r := range records {
+		s := DRRuleStats{
+			Name:       name,
+			Namespace:  namespace,
+			LastAuthor: r.SysMtd.LastAuthor,
+		}
+		// The hive reports modification times in milliseconds.
+		if r.SysMtd.LastMod != 0 {
+			s.LastModified = time.Unix(0, r.SysMtd.LastMod*int64(time.Millisecond))
+		}
+		stats[name] = s
+	}
+	return stats, nil
+}
+
+// DRRuleStatsReport returns the stats of the D&R rules of the namespaces,
+// "general" if none, between start and end, sorted by name. Namespaces
+// whose metadata is unavailable only report the rules with hits.
+func (org Organization) DRRuleStatsReport(start time.Time, end time.Time, namespaces ...string) ([]DRRuleStats, error) {
+	if len(namespaces) == 0 {
+		namespaces = []string{"general"}
+	}
+	byName := map[DRRuleName]DRRuleStats{}
+	isWanted := map[string]bool{}
+	for _, ns := range namespaces {
+		isWanted[ns] = true
+		mtd, err := org.DRRuleMetadata(ns)
+		if err == ErrorResourceNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("DRRuleMetadata %s: %v", ns, err)
+		}
+		for name, s := range mtd {
+			byName[name] = s
+		}
+	}
+	hits, err := org.DRRuleHits(start, end)
+	if err != nil {
+		return nil, fmt.Errorf("DRRuleHits: %v", err)
+	}
+	for name, h := range hits {
+		if !isWanted[h.Namespace] {
+			continue
+		}
+		s, ok := byName[name]
+		if !ok {
+			s = DRRuleStats{Name: name, Namespace: h.Namespace}
+		}
+		s.Hits = h.Hits
+		s.LastHit = h.LastHit
+		byName[name] = s
+	}
+	stats := make([]DRRuleStats, 0, len(byName))
+	for _, s := range byName {
+		stats = append(stats, s)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats, nil
+}
+
+// SyncPlanStaleRule is a D&R rule of a plan which did not report
+// any detection for SyncOptions.StaleAfter.
+type SyncPlanStaleRule struct {
+	Name      DRRuleName    `json:"name"`
+	Namespace string        `json:"namespace"`
+	Period    time.Duration `json:"-"`
+	// LastModified is zero when the API does not report it.
+	LastModified time.Time `json:"last_modified"`
+}
+
+func (r SyncPlanStaleRule) String() string {
+	s := fmt.Sprintf("%s %s has not fired in %s", OrgSyncOperationElementType.DRRule, r.Name, formatStalePeriod(r.Period))
+	if !r.LastModified.IsZero() {
+		s += fmt.Sprintf(" (last modified %s)", r.LastModified.UTC().Format("2006-01-02"))
+	}
+	return s
+}
+
+// formatStalePeriod formats whole days as such, "30 days"
+// reading better than "720h0m0s".
+func formatStalePeriod(d time.Duration) string {
+	const day = 24 * time.Hour
+	if d%day != 0 {
+		return d.String()
+	}
+	if d == day {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", d/day)
+}
+
+// findStaleRules returns the D&R rules of the plan the Org already
+// has which did not fire in the period. Rules modified during the
+// period had no time to fire and are not reported.
+func (org *Organization) findStaleRules(conf OrgConfig, plan SyncPlanResult, period time.Duration) ([]SyncPlanStaleRule, error) {
+	existing := map[DRRuleName]string{}
+	namespaces := map[string]bool{}
+	for _, op := range plan.Operations {
+		if op.ElementType != OrgSyncOperationElementType.DRRule || op.Error != nil || op.IsRemoved {
+			continue
+		}
+		if op.IsAdded && !op.IsUpdated {
+			continue
+		}
+		ns := "general"
+		if r, ok := conf.DRRules[op.ElementName]; ok && r.Namespace != "" {
+			ns = r.Namespace
+		}
+		existing[op.ElementName] = ns
+		namespaces[ns] = true
+	}
+	if len(existing) == 0 {
+		return nil, nil
+	}
+	nsList := []string{}
+	for ns := range namespaces {
+		nsList = append(nsList, ns)
+	}
+	sort.Strings(nsList)
+
+	end := time.Now()
+	start := end.Add(-period)
+	stats, err := org.DRRuleStatsReport(start, end, nsList...)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[DRRuleName]DRRuleStats{}
+	for _, s := range stats {
+		byName[s.Name] = s
+	}
+
+	stale := []SyncPlanStaleRule{}
+	for name, ns := range existing {
+		s := byName[name]
+		if s.Hits != 0 || s.LastModified.After(start) {
+			continue
+		}
+		stale = append(stale, SyncPlanStaleRule{
+			Name:         name,
+			Namespace:    ns,
+			Period:       period,
+			LastModified: s.LastModified,
+		})
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		return stale[i].Name < stale[j].Name
+	})
+	return stale, nil
+}
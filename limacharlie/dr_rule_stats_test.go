@@ -0,0 +1,76 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDRRuleStatsAndStaleRules(t *testing.T) {
+	a := assert.New(t)
+
+	now := time.Now()
+	old := now.Add(-90*24*time.Hour).UnixNano() / int64(time.Millisecond)
+	recent := now.Add(-time.Hour).UnixNano() / int64(time.Millisecond)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/hive/dr-general/"):
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{
+				"firing": {"sys_mtd": {"last_mod": %d, "last_author": "alice"}},
+				"silent": {"sys_mtd": {"last_mod": %d, "last_author": "bob"}},
+				"new": {"sys_mtd": {"last_mod": %d}}
+			}`, old, old, recent)), nil
+		case strings.HasPrefix(r.URL.Path, "/v1/insight/"):
+			if r.URL.Query().Get("cursor") == "" {
+				return jsonResponse(http.StatusOK, fmt.Sprintf(`{
+					"detects": [{"source_rule": "general.firing", "ts": %d}],
+					"next_cursor": "c1"
+				}`, old)), nil
+			}
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{
+				"detects": [
+					{"source_rule": "general.firing", "ts": %d},
+					{"source_rule": "managed.other", "ts": %d}
+				]
+			}`, recent, recent)), nil
+		}
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	stats, err := org.DRRuleStatsReport(now.Add(-100*24*time.Hour), now)
+	a.NoError(err)
+	a.Len(stats, 3)
+	a.Equal("firing", stats[0].Name)
+	a.Equal(2, stats[0].Hits)
+	a.Equal(recent, stats[0].LastHit.UnixNano()/int64(time.Millisecond))
+	a.Equal("alice", stats[0].LastAuthor)
+	a.Equal("new", stats[1].Name)
+	a.Equal(0, stats[1].Hits)
+	a.True(stats[1].LastHit.IsZero())
+
+	conf := OrgConfig{DRRules: orgSyncDRRules{
+		"firing": {},
+		"silent": {},
+		"new":    {},
+		"added":  {},
+	}}
+	plan := NewSyncPlanResult([]OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "firing"},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "silent", IsAdded: true, IsUpdated: true},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "new"},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "added", IsAdded: true},
+	})
+	stale, err := org.findStaleRules(conf, plan, 30*24*time.Hour)
+	a.NoError(err)
+	a.Len(stale, 1)
+	a.Equal("silent", stale[0].Name)
+	a.Equal("general", stale[0].Namespace)
+	a.Equal(fmt.Sprintf("dr-rule silent has not fired in 30 days (last modified %s)", stale[0].LastModified.UTC().Format("2006-01-02")), stale[0].String())
+
+	plan.Stale = stale
+	a.Contains(plan.String(), "dr-rule silent has not fired in 30 days")
+}
@@ -1,7 +1,12 @@
 package limacharlie
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -53,3 +58,241 @@ func TestDRRuleAddDelete(t *testing.T) {
 		t.Errorf("new dr rule with key %s was not deleted ", testRuleName)
 	}
 }
+
+func TestDRRuleHistory(t *testing.T) {
+	a := assert.New(t)
+	var form url.Values
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			a.Equal("/v1/rules/"+vcrTestOID+"/history", r.URL.Path)
+			a.Equal("r1", r.URL.Query().Get("name"))
+			a.Equal("managed", r.URL.Query().Get("namespace"))
+			return jsonResponse(http.StatusOK, `{"history":[
+				{"version":2,"author":"bob","ts":200,"rule":{"namespace":"managed","detect":{"op":"is"},"respond":[],"is_enabled":false}},
+				{"version":1,"author":"alice","ts":100,"rule":{"namespace":"managed","detect":{"op":"exists"},"respond":[]}}
+			]}`), nil
+		}
+		a.NoError(r.ParseForm())
+		form = r.PostForm
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	history, err := org.DRRuleHistory("r1", WithNamespace("managed"))
+	a.NoError(err)
+	a.Len(history, 2)
+	a.Equal(int64(1), history[0].Version)
+	a.Equal("alice", history[0].Author)
+	a.Equal("bob", history[1].Author)
+
+	a.NoError(org.DRRuleRestoreVersion("r1", 1, WithNamespace("managed")))
+	a.Equal("r1", form.Get("name"))
+	a.Equal("managed", form.Get("namespace"))
+	a.Equal(`{"op":"exists"}`, form.Get("detection"))
+	a.Equal("true", form.Get("is_enabled"))
+
+	a.Equal(ErrorResourceNotFound, org.DRRuleRestoreVersion("r1", 3, WithNamespace("managed")))
+}
+
+func TestDRRuleAdvancedFields(t *testing.T) {
+	a := assert.New(t)
+	isEnabled := true
+	base := CoreDRRule{
+		Detect:    Dict{"op": "is"},
+		Response:  List{Dict{"action": "report"}},
+		IsEnabled: &isEnabled,
+		Filters:   &DRRuleTargets{Tags: []string{"b", "a"}, Platforms: []Platform{"windows"}},
+		Priority:  5,
+		Suppression: &DRRuleSuppression{
+			MaxCount: 1,
+			Period:   "1h",
+			Keys:     []string{"{{ .routing.sid }}"},
+		},
+		TTL: 3600,
+	}
+
+	fetched := base
+	fetched.Filters = &DRRuleTargets{Tags: []string{"a", "b"}, Platforms: []Platform{"windows"}}
+	fetched.TTL = 0
+	fetched.ExpireOn = 1700000000
+	a.True(base.Equal(fetched))
+
+	other := fetched
+	other.Priority = 1
+	a.False(base.Equal(other))
+	other = fetched
+	other.Filters = nil
+	a.False(base.Equal(other))
+	other = fetched
+	other.Suppression = &DRRuleSuppression{MaxCount: 2, Period: "1h"}
+	a.False(base.Equal(other))
+	other = fetched
+	other.ExpireOn = 0
+	a.False(base.Equal(other))
+
+	var form url.Values
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.NoError(r.ParseForm())
+		form = r.PostForm
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+	a.NoError(org.DRRuleAdd("r1", base.Detect, base.Response, NewDRRuleOptions{
+		IsEnabled:   true,
+		Filters:     base.Filters,
+		Priority:    base.Priority,
+		Suppression: base.Suppression,
+	}))
+	a.Equal(`{"tags":["b","a"],"platforms":["windows"]}`, form.Get("filters"))
+	a.Empty(form.Get("expire_on"))
+	a.Equal("5", form.Get("priority"))
+	a.Equal(`{"max_count":1,"period":"1h","keys":["{{ .routing.sid }}"]}`, form.Get("suppression"))
+
+	// The TTL is relative, the expiration sent is absolute.
+	before := time.Now().Unix()
+	a.NoError(org.DRRuleAdd("r1", base.Detect, base.Response, NewDRRuleOptions{
+		IsEnabled: true,
+		TTL:       base.TTL,
+	}))
+	after := time.Now().Unix()
+	expireOn, err := strconv.ParseInt(form.Get("expire_on"), 10, 64)
+	a.NoError(err)
+	a.GreaterOrEqual(expireOn, before+3600)
+	a.LessOrEqual(expireOn, after+3600)
+}
+
+func TestDRRuleReportSuppression(t *testing.T) {
+	a := assert.New(t)
+	isEnabled := true
+	respond, err := NewRespond(ActionReport{
+		Name: "noisy",
+		Suppression: &DRRuleSuppression{
+			MaxCount: 1,
+			Period:   "60m",
+			Keys:     []string{"{{ .event.FILE_PATH }}", "noisy"},
+		},
+	})
+	a.NoError(err)
+	local := CoreDRRule{
+		Detect:    Dict{"op": "is"},
+		Response:  respond,
+		IsEnabled: &isEnabled,
+	}
+
+	// As fetched from the API.
+	fetched := local
+	fetched.Response = List{map[string]interface{}{
+		"action": "report",
+		"name":   "noisy",
+		"suppression": map[string]interface{}{
+			"max_count": 1,
+			"period":    "1h",
+			"keys":      []interface{}{"noisy", "{{ .event.FILE_PATH }}"},
+		},
+	}}
+	a.True(local.Equal(fetched))
+
+	fetched.Response = List{map[string]interface{}{
+		"action": "report",
+		"name":   "noisy",
+		"suppression": map[string]interface{}{
+			"max_count": 5,
+			"period":    "1h",
+			"keys":      []interface{}{"noisy", "{{ .event.FILE_PATH }}"},
+		},
+	}}
+	a.False(local.Equal(fetched))
+
+	_, err = NewRespond(ActionReport{Name: "noisy", Suppression: &DRRuleSuppression{MaxCount: 1, Period: "soon"}})
+	a.Error(err)
+	_, err = NewRespond(ActionReport{Name: "noisy", Suppression: &DRRuleSuppression{Period: "1h"}})
+	a.EqualError(err, "action 0: report: suppression requires max_count or min_count")
+	a.Error(ValidateRespond(List{Dict{"action": "report", "name": "noisy", "suppression": Dict{"max_count": 1}}}))
+}
+
+func TestDRRuleGet(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/v1/rules/%s/my rule", vcrTestOID):
+			a.Equal("managed", r.URL.Query().Get("namespace"))
+			return jsonResponse(http.StatusOK, `{"namespace":"managed","is_enabled":true,"detect":{"op":"is"},"respond":[{"action":"report","name":"x"}],"priority":3}`), nil
+		case fmt.Sprintf("/v1/fp/%s/my-fp", vcrTestOID):
+			return jsonResponse(http.StatusOK, `{"data":{"op":"is"},"oid":"`+vcrTestOID+`"}`), nil
+		}
+		return jsonResponse(http.StatusNotFound, `{"error":"not found"}`), nil
+	}))
+
+	rule, err := org.DRRuleGet("my rule", WithNamespace("managed"))
+	a.NoError(err)
+	a.Equal("my rule", rule.Name)
+	a.Equal("managed", rule.Namespace)
+	a.True(*rule.IsEnabled)
+	a.Equal(3, rule.Priority)
+	a.Equal("is", rule.Detect["op"])
+
+	_, err = org.DRRuleGet("missing")
+	a.Equal(ErrorResourceNotFound, err)
+
+	fp, err := org.FPRuleGet("my-fp")
+	a.NoError(err)
+	a.Equal("my-fp", fp.Name)
+	a.Equal("is", fp.Detection["op"])
+
+	_, err = org.FPRuleGet("missing")
+	a.Equal(ErrorResourceNotFound, err)
+}
+
+func TestDRRuleNamespaces(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path != "/v1/who" {
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		}
+		return jsonResponse(http.StatusOK, fmt.Sprintf(`{"ident":"me","user_perms":{"%s":["dr.list","dr.list.managed","dr.list.ext-zeek","dr.set","output.list"]}}`, vcrTestOID)), nil
+	}))
+	namespaces, err := org.DRRuleNamespaces()
+	a.NoError(err)
+	a.Equal([]string{"ext-zeek", "general", "managed"}, namespaces)
+}
+
+func TestSyncDRRulesReadOnly(t *testing.T) {
+	a := assert.New(t)
+	changes := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["dr.list","dr.set","dr.del","dr.list.replicant"]}`), nil
+		}
+		if r.Method != http.MethodGet {
+			changes = append(changes, r.Method+" "+r.URL.Path)
+			return jsonResponse(http.StatusOK, `{}`), nil
+		}
+		if r.URL.Query().Get("namespace") == "replicant" {
+			return jsonResponse(http.StatusOK, `{"svc-rule":{"namespace":"replicant","detect":{"op":"is"},"respond":[{"action":"report"}],"is_enabled":true}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"mine":{"namespace":"general","detect":{"op":"is"},"respond":[{"action":"report"}],"is_enabled":true}}`), nil
+	}))
+
+	conf, err := org.SyncFetch(SyncOptions{SyncDRRules: true})
+	a.NoError(err)
+	a.True(conf.DRRules["svc-rule"].ReadOnly)
+	a.False(conf.DRRules["mine"].ReadOnly)
+
+	// Modifying a fetched read-only rule is not pushed.
+	rule := conf.DRRules["svc-rule"]
+	rule.Description = "changed"
+	conf.DRRules["svc-rule"] = rule
+	delete(conf.DRRules, "mine")
+	ops, err := org.SyncPush(conf, SyncOptions{SyncDRRules: true, IsForce: true})
+	a.NoError(err)
+	a.Equal([]string{"DELETE /v1/rules/" + vcrTestOID}, changes)
+	for _, op := range ops {
+		if op.ElementName == "svc-rule" {
+			a.True(op.IsSkipped)
+		}
+	}
+
+	// Nor are service rules missing from the config removed.
+	changes = []string{}
+	_, err = org.SyncPush(OrgConfig{}, SyncOptions{SyncDRRules: true, IsForce: true})
+	a.NoError(err)
+	a.Equal([]string{"DELETE /v1/rules/" + vcrTestOID}, changes)
+}
@@ -0,0 +1,185 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	defaultConsistencyTimeout  = 30 * time.Second
+	defaultConsistencyInterval = 1 * time.Second
+)
+
+// ErrorNotConsistent is returned by WaitForConsistency when
+// a change is still not visible once the timeout expires.
+var ErrorNotConsistent = errors.New("change not visible before timeout")
+
+// ElementID is a stable identifier of an element of the configuration
+// of an Org, like "<oid>/dr-rule/general/my-rule", suitable as the ID
+// of a resource managed by tools like Terraform.
+type ElementID struct {
+	OID  string
+	Type string
+	// Namespace of D&R rules, empty for other types.
+	Namespace string
+	Name      string
+}
+
+func (id ElementID) String() string {
+	return strings.Join([]string{id.OID, id.Type, id.Namespace, id.Name}, "/")
+}
+
+// ParseElementID parses an ElementID formatted by ElementID.String().
+func ParseElementID(s string) (ElementID, error) {
+	parts := strings.SplitN(s, "/", 4)
+	if len(parts) != 4 || parts[0] == "" || parts[1] == "" || parts[3] == "" {
+		return ElementID{}, fmt.Errorf("invalid element id: %s", s)
+	}
+	return ElementID{
+		OID:       parts[0],
+		Type:      parts[1],
+		Namespace: parts[2],
+		Name:      parts[3],
+	}, nil
+}
+
+// ElementID returns the ElementID of an element of this Org.
+func (org Organization) ElementID(elementType string, namespace string, name string) ElementID {
+	return ElementID{
+		OID:       org.client.options.OID,
+		Type:      elementType,
+		Namespace: namespace,
+		Name:      name,
+	}
+}
+
+// ConsistencyOptions configures WaitForConsistency.
+type ConsistencyOptions struct {
+	// Timeout defaults to 30 seconds.
+	Timeout time.Duration
+	// Interval between checks, defaults to 1 second.
+	Interval time.Duration
+}
+
+// WaitForConsistency calls check until it returns true, for reading
+// back a change made through an API which applies it asynchronously.
+// ErrorResourceNotFound returned by check is retried like false.
+func (org Organization) WaitForConsistency(check func() (bool, error), opts ...ConsistencyOptions) error {
+	opt := ConsistencyOptions{}
+	for _, o := range opts {
+		opt = o
+	}
+	if opt.Timeout <= 0 {
+		opt.Timeout = defaultConsistencyTimeout
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = defaultConsistencyInterval
+	}
+	deadline := time.Now().Add(opt.Timeout)
+	for {
+		isDone, err := check()
+		if err != nil && err != ErrorResourceNotFound {
+			return err
+		}
+		if isDone {
+			return nil
+		}
+		if time.Now().Add(opt.Interval).After(deadline) {
+			return ErrorNotConsistent
+		}
+		time.Sleep(opt.Interval)
+	}
+}
+
+// Output returns a single output.
+func (org Organization) Output(name OutputName) (OutputConfig, error) {
+	outputs, err := org.Outputs()
+	if err != nil {
+		return OutputConfig{}, err
+	}
+	output, ok := outputs[name]
+	if !ok {
+		return OutputConfig{}, ErrorResourceNotFound
+	}
+	return output, nil
+}
+
+// IntegrityRule returns a single integrity rule.
+func (org Organization) IntegrityRule(name IntegrityRuleName) (IntegrityRule, error) {
+	rules, err := org.IntegrityRules()
+	if err != nil {
+		return IntegrityRule{}, err
+	}
+	rule, ok := rules[name]
+	if !ok {
+		return IntegrityRule{}, ErrorResourceNotFound
+	}
+	return rule, nil
+}
+
+// ArtifactRule returns a single artifact collection rule.
+func (org Organization) ArtifactRule(name ArtifactRuleName) (ArtifactRule, error) {
+	rules, err := org.ArtifactsRules()
+	if err != nil {
+		return ArtifactRule{}, err
+	}
+	rule, ok := rules[name]
+	if !ok {
+		return ArtifactRule{}, ErrorResourceNotFound
+	}
+	return rule, nil
+}
+
+// ExfilRuleEvent returns a single exfil event rule.
+func (org Organization) ExfilRuleEvent(name ExfilRuleName) (ExfilRuleEvent, error) {
+	rules, err := org.ExfilRules()
+	if err != nil {
+		return ExfilRuleEvent{}, err
+	}
+	rule, ok := rules.Events[name]
+	if !ok {
+		return ExfilRuleEvent{}, ErrorResourceNotFound
+	}
+	return rule, nil
+}
+
+// ExfilRuleWatch returns a single exfil watch rule.
+func (org Organization) ExfilRuleWatch(name ExfilRuleName) (ExfilRuleWatch, error) {
+	rules, err := org.ExfilRules()
+	if err != nil {
+		return ExfilRuleWatch{}, err
+	}
+	rule, ok := rules.Watches[name]
+	if !ok {
+		return ExfilRuleWatch{}, ErrorResourceNotFound
+	}
+	return rule, nil
+}
+
+// YaraRule returns a single yara rule.
+func (org Organization) YaraRule(name YaraRuleName) (YaraRule, error) {
+	rules, err := org.YaraListRules()
+	if err != nil {
+		return YaraRule{}, err
+	}
+	rule, ok := rules[name]
+	if !ok {
+		return YaraRule{}, ErrorResourceNotFound
+	}
+	return rule, nil
+}
+
+// YaraSource returns a single yara source, without its content.
+func (org Organization) YaraSource(name YaraSourceName) (YaraSource, error) {
+	sources, err := org.YaraListSources()
+	if err != nil {
+		return YaraSource{}, err
+	}
+	source, ok := sources[name]
+	if !ok {
+		return YaraSource{}, ErrorResourceNotFound
+	}
+	return source, nil
+}
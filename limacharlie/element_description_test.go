@@ -0,0 +1,73 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestElementDescriptions(t *testing.T) {
+	a := assert.New(t)
+
+	conf := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(`
+rules:
+  r1:
+    description: flags encoded powershell
+    detect: {op: is, path: event/FILE_PATH, value: x}
+    respond: []
+fps:
+  f1:
+    description: backup agent
+    data: {op: is, path: cat, value: x}
+outputs:
+  o1:
+    description: forwards to the SIEM
+    module: syslog
+    type: detect
+    dest_host: syslog.example.com
+`), &conf))
+	a.Equal("flags encoded powershell", conf.DRRules["r1"].Description)
+	a.Equal("backup agent", conf.FPRules["f1"].Description)
+	a.Equal("forwards to the SIEM", conf.Outputs["o1"].Description)
+
+	data, err := yaml.Marshal(conf)
+	a.NoError(err)
+	roundTripped := OrgConfig{}
+	a.NoError(yaml.Unmarshal(data, &roundTripped))
+	a.Equal(conf.DRRules["r1"].Description, roundTripped.DRRules["r1"].Description)
+	a.Equal(conf.FPRules["f1"].Description, roundTripped.FPRules["f1"].Description)
+	a.Equal(conf.Outputs["o1"].Description, roundTripped.Outputs["o1"].Description)
+
+	enabled := true
+	rule := CoreDRRule{IsEnabled: &enabled, Description: "a"}
+	other := rule
+	a.True(rule.Equal(other))
+	other.Description = "b"
+	a.False(rule.Equal(other))
+
+	store := &fakeFPRuleStore{rules: map[FPRuleName]FPRule{
+		"f1": {Name: "f1", Detection: conf.FPRules["f1"].Detection},
+	}}
+	ops, err := syncFPRules(store, conf.FPRules, SyncOptions{})
+	a.NoError(err)
+	a.Equal([]OrgSyncOperation{{ElementType: OrgSyncOperationElementType.FPRule, ElementName: "f1", IsAdded: true, IsUpdated: true}}, ops)
+	a.Equal("backup agent", store.rules["f1"].Description)
+	ops, err = syncFPRules(store, conf.FPRules, SyncOptions{})
+	a.NoError(err)
+	a.Equal([]OrgSyncOperation{{ElementType: OrgSyncOperationElementType.FPRule, ElementName: "f1"}}, ops)
+}
+
+func TestDRRuleAddDescription(t *testing.T) {
+	a := assert.New(t)
+	description := ""
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.NoError(r.ParseForm())
+		description = r.PostForm.Get("description")
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+	a.NoError(org.DRRuleAdd("r1", Dict{}, List{}, NewDRRuleOptions{IsEnabled: true, Description: "documented"}))
+	a.Equal("documented", description)
+}
@@ -0,0 +1,70 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestElementID(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(nil)
+
+	id := org.ElementID(OrgSyncOperationElementType.DRRule, "managed", "my/rule")
+	a.Equal(vcrTestOID+"/dr-rule/managed/my/rule", id.String())
+	parsed, err := ParseElementID(id.String())
+	a.NoError(err)
+	a.Equal(id, parsed)
+
+	id = org.ElementID(OrgSyncOperationElementType.Output, "", "siem")
+	parsed, err = ParseElementID(id.String())
+	a.NoError(err)
+	a.Equal(id, parsed)
+
+	_, err = ParseElementID("oid/output/")
+	a.Error(err)
+}
+
+func TestWaitForConsistency(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(nil)
+	opts := ConsistencyOptions{Timeout: 50 * time.Millisecond, Interval: time.Millisecond}
+
+	n := 0
+	a.NoError(org.WaitForConsistency(func() (bool, error) {
+		n++
+		if n < 3 {
+			return false, ErrorResourceNotFound
+		}
+		return true, nil
+	}, opts))
+	a.Equal(3, n)
+
+	a.Equal(ErrorNotConsistent, org.WaitForConsistency(func() (bool, error) {
+		return false, nil
+	}, opts))
+
+	failure := errors.New("failure")
+	a.Equal(failure, org.WaitForConsistency(func() (bool, error) {
+		return false, failure
+	}, opts))
+}
+
+func TestOutputGet(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, fmt.Sprintf(`{"%s":{"siem":{"name":"siem","module":"syslog","for":"event"}}}`, vcrTestOID)), nil
+	}))
+
+	output, err := org.Output("siem")
+	a.NoError(err)
+	a.Equal("syslog", output.Module)
+	a.Equal(OutputType.Event, output.Type)
+
+	_, err = org.Output("missing")
+	a.Equal(ErrorResourceNotFound, err)
+}
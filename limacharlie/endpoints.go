@@ -0,0 +1,90 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Endpoints are the URLs of the services the client talks to, to
+// point it at another deployment, like an on-premise or air-gapped
+// one, or at a proxy. Empty fields use DefaultEndpoints.
+type Endpoints struct {
+	// APIRoot is the URL of the REST API, without its version.
+	APIRoot string `json:"api_root,omitempty" yaml:"api_root,omitempty"`
+	// JWT is the URL API keys are exchanged for JWTs at.
+	JWT string `json:"jwt,omitempty" yaml:"jwt,omitempty"`
+	// Sigma is the URL of the Sigma rule conversion service.
+	Sigma string `json:"sigma,omitempty" yaml:"sigma,omitempty"`
+	// Services override the hostnames the API reports for the
+	// services of an Org, like "ingestion" or "artifacts", see
+	// Organization.GetURLs.
+	Services map[string]string `json:"services,omitempty" yaml:"services,omitempty"`
+}
+
+// EndpointsForDomain returns the endpoints of a deployment
+// serving its services under the domain, like "limacharlie.io".
+func EndpointsForDomain(domain string) Endpoints {
+	domain = strings.Trim(domain, ".")
+	return Endpoints{
+		APIRoot: fmt.Sprintf("https://api.%s", domain),
+		JWT:     fmt.Sprintf("https://jwt.%s", domain),
+		Sigma:   fmt.Sprintf("https://sigma.%s/convert/rule", domain),
+	}
+}
+
+// DefaultEndpoints are the endpoints of the LimaCharlie cloud.
+var DefaultEndpoints = EndpointsForDomain("limacharlie.io")
+
+// EndpointPresets are the endpoints of known deployments by name,
+// which ClientOptions and config files can refer to. Deployments
+// can be added before creating clients.
+var EndpointPresets = map[string]Endpoints{
+	"default": DefaultEndpoints,
+}
+
+// EndpointsFromPreset returns the endpoints of a preset.
+func EndpointsFromPreset(name string) (Endpoints, error) {
+	e, ok := EndpointPresets[name]
+	if !ok {
+		names := []string{}
+		for n := range EndpointPresets {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Endpoints{}, NewInvalidClientOptionsError(fmt.Sprintf("unknown endpoint preset %s, expected one of: %s", name, strings.Join(names, ", ")))
+	}
+	return e, nil
+}
+
+func isZeroEndpoints(e Endpoints) bool {
+	return e.APIRoot == "" && e.JWT == "" && e.Sigma == "" && len(e.Services) == 0
+}
+
+// withDefaults returns the endpoints with the empty
+// ones set from DefaultEndpoints.
+func (e Endpoints) withDefaults() Endpoints {
+	if e.APIRoot == "" {
+		e.APIRoot = DefaultEndpoints.APIRoot
+	}
+	if e.JWT == "" {
+		e.JWT = DefaultEndpoints.JWT
+	}
+	if e.Sigma == "" {
+		e.Sigma = DefaultEndpoints.Sigma
+	}
+	e.APIRoot = strings.TrimSuffix(e.APIRoot, "/")
+	return e
+}
+
+// endpoints returns the endpoints of the client, from
+// ClientOptions.EndpointPreset if Endpoints are not set.
+func (c *Client) endpoints() Endpoints {
+	e := c.options.Endpoints
+	if isZeroEndpoints(e) && c.options.EndpointPreset != "" {
+		if preset, err := EndpointsFromPreset(c.options.EndpointPreset); err == nil {
+			e = preset
+		}
+	}
+	return e.withDefaults()
+}
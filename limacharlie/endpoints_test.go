@@ -0,0 +1,68 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndpoints(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("https://api.limacharlie.io", DefaultEndpoints.APIRoot)
+	a.Equal("https://jwt.limacharlie.io", DefaultEndpoints.JWT)
+	a.Equal(Endpoints{
+		APIRoot: "https://api.lc.example.com",
+		JWT:     "https://jwt.lc.example.com",
+		Sigma:   "https://sigma.lc.example.com/convert/rule",
+	}, EndpointsForDomain("lc.example.com."))
+
+	requested := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requested = append(requested, r.URL.String())
+		if r.URL.Host == "auth.lc.example.com" {
+			return jsonResponse(http.StatusOK, `{"jwt": "token"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"url": {"ingestion": "ingest.limacharlie.io", "artifacts": "artifacts.limacharlie.io"}}`), nil
+	}))
+	org.client.options.Endpoints = Endpoints{
+		APIRoot:  "https://proxy.lc.example.com/api/",
+		JWT:      "https://auth.lc.example.com/token",
+		Services: map[string]string{"ingestion": "ingest.lc.example.com"},
+	}
+	urls, err := org.GetURLs()
+	a.NoError(err)
+	a.Equal(map[string]string{"ingestion": "ingest.lc.example.com", "artifacts": "artifacts.limacharlie.io"}, urls)
+	_, err = org.client.RefreshJWT(0)
+	a.NoError(err)
+	a.Equal([]string{
+		"https://proxy.lc.example.com/api/v1/orgs/" + vcrTestOID + "/url",
+		"https://auth.lc.example.com/token",
+	}, requested)
+	a.Equal(DefaultEndpoints.Sigma, org.client.endpoints().Sigma)
+
+	EndpointPresets["test-region"] = EndpointsForDomain("region.example.com")
+	defer delete(EndpointPresets, "test-region")
+	org.client.options.Endpoints = Endpoints{}
+	org.client.options.EndpointPreset = "test-region"
+	a.Equal("https://api.region.example.com", org.client.endpoints().APIRoot)
+
+	o := ClientOptions{}
+	a.NoError(o.FromConfigString([]byte(`
+oid: 11111111-2222-3333-4444-555555555555
+env:
+  onprem:
+    oid: 41111111-2222-3333-4444-555555555555
+    endpoints:
+      api_root: https://api.lc.internal
+      jwt: https://jwt.lc.internal
+`), "onprem"))
+	a.Equal(Endpoints{APIRoot: "https://api.lc.internal", JWT: "https://jwt.lc.internal"}, o.Endpoints)
+	a.NoError(o.validate())
+	a.NoError(o.FromConfigString([]byte(`
+oid: 11111111-2222-3333-4444-555555555555
+endpoint_preset: unknown
+`), ""))
+	a.Error(o.validate())
+}
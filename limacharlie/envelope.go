@@ -0,0 +1,83 @@
+package limacharlie
+
+// EventEnvelope wraps an Event to give convenient access
+// to its routing information.
+type EventEnvelope struct {
+	Event
+}
+
+func NewEventEnvelope(e Event) EventEnvelope {
+	return EventEnvelope{Event: e}
+}
+
+func (e EventEnvelope) SID() string {
+	return e.Routing.SID
+}
+
+func (e EventEnvelope) OID() string {
+	return e.Routing.OID
+}
+
+func (e EventEnvelope) Hostname() string {
+	return e.Routing.Hostname
+}
+
+func (e EventEnvelope) EventType() EventType {
+	return e.Routing.EventType
+}
+
+func (e EventEnvelope) EventID() string {
+	return e.Routing.EventID
+}
+
+func (e EventEnvelope) Tags() []string {
+	return e.Routing.Tags
+}
+
+func (e EventEnvelope) InvestigationID() string {
+	return e.Routing.InvID
+}
+
+// HasTag returns true if the sensor had the tag when the event was generated.
+func (e EventEnvelope) HasTag(tag string) bool {
+	for _, t := range e.Routing.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Platform returns the name of the platform of the sensor, like "windows".
+func (e EventEnvelope) Platform() string {
+	return PlatformStrings[uint32(e.Routing.Plat)]
+}
+
+// Architecture returns the name of the architecture of the sensor, like "x64".
+func (e EventEnvelope) Architecture() string {
+	return ArchitectureStrings[uint32(e.Routing.Arch)]
+}
+
+// SelectorEnv returns the values sensor selectors are evaluated against.
+func (e EventEnvelope) SelectorEnv() SelectorEnv {
+	return SelectorEnv{
+		"sid":        e.Routing.SID,
+		"oid":        e.Routing.OID,
+		"iid":        e.Routing.IID,
+		"did":        e.Routing.DID,
+		"hostname":   e.Routing.Hostname,
+		"plat":       e.Platform(),
+		"arch":       e.Architecture(),
+		"int_ip":     e.Routing.IntIP,
+		"ext_ip":     e.Routing.ExtIP,
+		"tags":       e.Routing.Tags,
+		"tag":        e.Routing.Tags,
+		"event_type": e.Routing.EventType,
+	}
+}
+
+// Matches returns true if the event comes from a sensor matching
+// the sensor selector, like `plat == windows and tag in (vip)`.
+func (e EventEnvelope) Matches(selector string) (bool, error) {
+	return MatchesSelector(selector, e.SelectorEnv())
+}
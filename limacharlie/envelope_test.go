@@ -0,0 +1,43 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventEnvelopeMatches(t *testing.T) {
+	a := assert.New(t)
+	e := NewEventEnvelope(Event{
+		Routing: Routing{
+			SID:       "s1",
+			Hostname:  "dc-01.corp",
+			EventType: EventTypes.NewProcess,
+			Plat:      int(Platforms.Windows),
+			Tags:      []string{"vip", "servers"},
+			InvID:     "inv-1",
+		},
+	})
+	a.Equal("s1", e.SID())
+	a.Equal("windows", e.Platform())
+	a.Equal("inv-1", e.InvestigationID())
+	a.True(e.HasTag("vip"))
+
+	for selector, expected := range map[string]bool{
+		`plat == windows and tag in (vip)`:                true,
+		`plat == linux or tag in (workstations, laptops)`: false,
+		`"servers" in tags and hostname matches "^dc-"`:   true,
+		`not (plat != windows) and "vip" not in tags`:     false,
+		`hostname contains "corp" and iid is empty`:       true,
+		`event_type == NEW_PROCESS`:                       true,
+	} {
+		isMatch, err := e.Matches(selector)
+		a.NoError(err, selector)
+		a.Equal(expected, isMatch, selector)
+	}
+
+	_, err := e.Matches(`unknown_field == 1`)
+	a.Error(err)
+	_, err = e.Matches(`plat == windows and`)
+	a.Error(err)
+}
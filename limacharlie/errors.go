@@ -61,9 +61,25 @@ func (e RESTError) Error() string {
 // ErrorResourceNotFound is returned when querying for a resource that does not exist or that the client does not have the permission to see
 var ErrorResourceNotFound = errors.New("resource not found")
 
+// ErrorUSPBufferFull is returned by a USPClient dropping an element
+// because too many are buffered while the endpoint cannot be reached.
+var ErrorUSPBufferFull = errors.New("usp buffer full")
+
 // Returned for a feature that is not yet implemented to parity with the Python SDK.
 var ErrorNotImplemented = errors.New("not implemented")
 
 func IsServiceNotRegisteredError(err error) bool {
 	return strings.Contains(err.Error(), "org not registered to service")
 }
+
+// isRESTNotFound returns true if err is a RESTError of a 404 response.
+func isRESTNotFound(err error) bool {
+	restErr := RESTError{}
+	return errors.As(err, &restErr) && strings.HasPrefix(restErr.s, "404 ")
+}
+
+// isRESTBadRequest returns true if err is a RESTError of a 400 response.
+func isRESTBadRequest(err error) bool {
+	restErr := RESTError{}
+	return errors.As(err, &restErr) && strings.HasPrefix(restErr.s, "400 ")
+}
@@ -0,0 +1,192 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+type EventType = string
+
+// EventTypes is the common EDR event types with a typed representation.
+var EventTypes = struct {
+	NewProcess         EventType
+	ExistingProcess    EventType
+	TerminateProcess   EventType
+	DNSRequest         EventType
+	CodeIdentity       EventType
+	NetworkConnections EventType
+	NewDocument        EventType
+	UserObserved       EventType
+}{
+	NewProcess:         "NEW_PROCESS",
+	ExistingProcess:    "EXISTING_PROCESS",
+	TerminateProcess:   "TERMINATE_PROCESS",
+	DNSRequest:         "DNS_REQUEST",
+	CodeIdentity:       "CODE_IDENTITY",
+	NetworkConnections: "NETWORK_CONNECTIONS",
+	NewDocument:        "NEW_DOCUMENT",
+	UserObserved:       "USER_OBSERVED",
+}
+
+type ProcessEvent struct {
+	ProcessID       uint32        `json:"PROCESS_ID"`
+	ParentProcessID uint32        `json:"PARENT_PROCESS_ID"`
+	FilePath        string        `json:"FILE_PATH"`
+	CommandLine     string        `json:"COMMAND_LINE"`
+	UserName        string        `json:"USER_NAME"`
+	Hash            string        `json:"HASH"`
+	BaseAddress     uint64        `json:"BASE_ADDRESS"`
+	MemoryUsage     uint64        `json:"MEMORY_USAGE"`
+	ThreadsCount    uint32        `json:"THREADS"`
+	Parent          *ProcessEvent `json:"PARENT,omitempty"`
+}
+
+type TerminateProcessEvent struct {
+	ProcessID       uint32 `json:"PROCESS_ID"`
+	ParentProcessID uint32 `json:"PARENT_PROCESS_ID"`
+	FilePath        string `json:"FILE_PATH"`
+}
+
+type DNSRequestEvent struct {
+	DomainName string `json:"DOMAIN_NAME"`
+	IPAddress  string `json:"IP_ADDRESS"`
+	CName      string `json:"CNAME"`
+	DNSType    uint16 `json:"DNS_TYPE"`
+	MessageID  uint32 `json:"MESSAGE_ID"`
+	ProcessID  uint32 `json:"PROCESS_ID"`
+}
+
+type CodeIdentityEvent struct {
+	FilePath   string `json:"FILE_PATH"`
+	Hash       string `json:"HASH"`
+	HashMD5    string `json:"HASH_MD5"`
+	HashSHA1   string `json:"HASH_SHA1"`
+	FileSize   uint64 `json:"FILE_SIZE"`
+	ErrorCode  uint32 `json:"ERROR"`
+	Signature  Dict   `json:"SIGNATURE,omitempty"`
+	OriginalFN string `json:"ORIGINAL_FILE_NAME"`
+}
+
+type NetworkEndpoint struct {
+	IPAddress string `json:"IP_ADDRESS"`
+	Port      uint16 `json:"PORT"`
+}
+
+type NetworkConnection struct {
+	Source      NetworkEndpoint `json:"SOURCE"`
+	Destination NetworkEndpoint `json:"DESTINATION"`
+	Protocol    string          `json:"PROTOCOL"`
+	IsOutgoing  uint8           `json:"IS_OUTGOING"`
+	Timestamp   int64           `json:"TIMESTAMP"`
+}
+
+type NetworkConnectionsEvent struct {
+	ProcessID   uint32              `json:"PROCESS_ID"`
+	FilePath    string              `json:"FILE_PATH"`
+	CommandLine string              `json:"COMMAND_LINE"`
+	Connections []NetworkConnection `json:"NETWORK_ACTIVITY"`
+}
+
+type NewDocumentEvent struct {
+	FilePath  string `json:"FILE_PATH"`
+	Hash      string `json:"HASH"`
+	ProcessID uint32 `json:"PROCESS_ID"`
+}
+
+type UserObservedEvent struct {
+	UserName string `json:"USER_NAME"`
+}
+
+// EventFactory returns a pointer to a new typed event
+// that the generic event data gets decoded into.
+type EventFactory func() interface{}
+
+var eventRegistry = struct {
+	m sync.RWMutex
+	f map[EventType]EventFactory
+}{
+	f: map[EventType]EventFactory{
+		EventTypes.NewProcess:         func() interface{} { return &ProcessEvent{} },
+		EventTypes.ExistingProcess:    func() interface{} { return &ProcessEvent{} },
+		EventTypes.TerminateProcess:   func() interface{} { return &TerminateProcessEvent{} },
+		EventTypes.DNSRequest:         func() interface{} { return &DNSRequestEvent{} },
+		EventTypes.CodeIdentity:       func() interface{} { return &CodeIdentityEvent{} },
+		EventTypes.NetworkConnections: func() interface{} { return &NetworkConnectionsEvent{} },
+		EventTypes.NewDocument:        func() interface{} { return &NewDocumentEvent{} },
+		EventTypes.UserObserved:       func() interface{} { return &UserObservedEvent{} },
+	},
+}
+
+// RegisterEventType adds or replaces the typed representation of an event type.
+func RegisterEventType(eventType EventType, factory EventFactory) {
+	eventRegistry.m.Lock()
+	defer eventRegistry.m.Unlock()
+	eventRegistry.f[eventType] = factory
+}
+
+// DecodeEvent converts the generic data of an event to its
+// registered typed representation. Event types without a
+// registered representation are returned as a Dict.
+func DecodeEvent(eventType EventType, data Dict) (interface{}, error) {
+	eventRegistry.m.RLock()
+	factory, ok := eventRegistry.f[eventType]
+	eventRegistry.m.RUnlock()
+	if !ok {
+		return data, nil
+	}
+	out := factory()
+	if err := data.UnMarshalToStruct(out); err != nil {
+		return nil, fmt.Errorf("decoding %s: %v", eventType, err)
+	}
+	return out, nil
+}
+
+// Typed returns the typed representation of the event data.
+func (e Event) Typed() (interface{}, error) {
+	data := Dict{}
+	switch d := e.Event.(type) {
+	case Dict:
+		data = d
+	case map[string]interface{}:
+		data = d
+	case nil:
+	default:
+		return nil, fmt.Errorf("unexpected event data type: %T", e.Event)
+	}
+	return DecodeEvent(e.Routing.EventType, data)
+}
+
+// EventStreamDecoder decodes a stream of JSON events, like the
+// newline delimited events received by a Firehose or a webhook.
+type EventStreamDecoder struct {
+	d *json.Decoder
+}
+
+func NewEventStreamDecoder(r io.Reader) *EventStreamDecoder {
+	return &EventStreamDecoder{d: json.NewDecoder(r)}
+}
+
+// Next returns the next event in the stream and its typed
+// representation, io.EOF is returned at the end of the stream.
+func (sd *EventStreamDecoder) Next() (Event, interface{}, error) {
+	raw := struct {
+		Event   Dict    `json:"event"`
+		Routing Routing `json:"routing"`
+		TS      string  `json:"ts"`
+	}{}
+	if err := sd.d.Decode(&raw); err != nil {
+		return Event{}, nil, err
+	}
+	e := Event{
+		Event:     raw.Event,
+		Routing:   raw.Routing,
+		TimeStamp: raw.TS,
+	}
+	typed, err := DecodeEvent(e.Routing.EventType, raw.Event)
+	if err != nil {
+		return e, nil, err
+	}
+	return e, typed, nil
+}
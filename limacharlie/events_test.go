@@ -0,0 +1,73 @@
+package limacharlie
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventStreamDecoder(t *testing.T) {
+	a := assert.New(t)
+	stream := `{"routing":{"event_type":"NEW_PROCESS","sid":"s1"},"event":{"FILE_PATH":"c:\\a.exe","PROCESS_ID":12,"PARENT":{"PROCESS_ID":4}}}
+{"routing":{"event_type":"DNS_REQUEST"},"event":{"DOMAIN_NAME":"example.com","DNS_TYPE":1}}
+{"routing":{"event_type":"SOMETHING_ELSE"},"event":{"K":"v"}}
+`
+	d := NewEventStreamDecoder(strings.NewReader(stream))
+
+	e, typed, err := d.Next()
+	a.NoError(err)
+	a.Equal("s1", e.Routing.SID)
+	if p, ok := typed.(*ProcessEvent); a.True(ok) {
+		a.Equal(uint32(12), p.ProcessID)
+		a.Equal(`c:\a.exe`, p.FilePath)
+		a.Equal(uint32(4), p.Parent.ProcessID)
+	}
+
+	_, typed, err = d.Next()
+	a.NoError(err)
+	if dns, ok := typed.(*DNSRequestEvent); a.True(ok) {
+		a.Equal("example.com", dns.DomainName)
+		a.Equal(uint16(1), dns.DNSType)
+	}
+
+	_, typed, err = d.Next()
+	a.NoError(err)
+	a.Equal(Dict{"K": "v"}, typed)
+
+	_, _, err = d.Next()
+	a.Equal(io.EOF, err)
+}
+
+func TestDecodeNetworkConnections(t *testing.T) {
+	a := assert.New(t)
+	// As reported by a Windows sensor.
+	stream := `{"routing":{"event_type":"NETWORK_CONNECTIONS","sid":"s1","plat":268435456},"event":{
+		"COMMAND_LINE":"\"C:\\Program Files\\Mozilla Firefox\\firefox.exe\"",
+		"FILE_PATH":"C:\\Program Files\\Mozilla Firefox\\firefox.exe",
+		"HASH":"4f1a2c3b",
+		"NETWORK_ACTIVITY":[
+			{"DESTINATION":{"IP_ADDRESS":"142.250.72.110","PORT":443},"IS_OUTGOING":1,"PROTOCOL":"tcp4","SOURCE":{"IP_ADDRESS":"10.0.2.15","PORT":50123},"TIMESTAMP":1625097600123},
+			{"DESTINATION":{"IP_ADDRESS":"10.0.2.3","PORT":53},"IS_OUTGOING":1,"PROTOCOL":"udp4","SOURCE":{"IP_ADDRESS":"10.0.2.15","PORT":61000},"TIMESTAMP":1625097600130}
+		],
+		"PARENT_PROCESS_ID":4012,
+		"PROCESS_ID":7736
+	}}`
+	_, typed, err := NewEventStreamDecoder(strings.NewReader(stream)).Next()
+	a.NoError(err)
+	e, ok := typed.(*NetworkConnectionsEvent)
+	if !a.True(ok) {
+		return
+	}
+	a.Equal(uint32(7736), e.ProcessID)
+	a.Len(e.Connections, 2)
+	a.Equal(NetworkConnection{
+		Source:      NetworkEndpoint{IPAddress: "10.0.2.15", Port: 50123},
+		Destination: NetworkEndpoint{IPAddress: "142.250.72.110", Port: 443},
+		Protocol:    "tcp4",
+		IsOutgoing:  1,
+		Timestamp:   1625097600123,
+	}, e.Connections[0])
+	a.Equal(uint16(53), e.Connections[1].Destination.Port)
+}
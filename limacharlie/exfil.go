@@ -1,6 +1,10 @@
 package limacharlie
 
-import "encoding/json"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 type ExfilRuleName = string
 
@@ -11,8 +15,8 @@ type ExfilRulesType struct {
 }
 
 type ExfilEventFilters struct {
-	Tags      []string `json:"tags" yaml:"tags"`
-	Platforms []string `json:"platforms" yaml:"platforms"`
+	Tags      []string   `json:"tags" yaml:"tags"`
+	Platforms []Platform `json:"platforms" yaml:"platforms"`
 }
 
 type ExfilRuleEvent struct {
@@ -23,29 +27,8 @@ type ExfilRuleEvent struct {
 	Filters ExfilEventFilters `json:"filters" yaml:"filters"`
 }
 
-func (r ExfilRuleEvent) jsonMarhsalContent() ([]byte, error) {
-	if r.Filters.Platforms == nil {
-		r.Filters.Platforms = []string{}
-	}
-	if r.Filters.Tags == nil {
-		r.Filters.Tags = []string{}
-	}
-	return json.Marshal(Dict{
-		"events":  r.Events,
-		"filters": r.Filters,
-	})
-}
-
 func (r ExfilRuleEvent) EqualsContent(other ExfilRuleEvent) bool {
-	bytes, err := r.jsonMarhsalContent()
-	if err != nil {
-		return false
-	}
-	otherBytes, err := other.jsonMarhsalContent()
-	if err != nil {
-		return false
-	}
-	return string(bytes) == string(otherBytes)
+	return syncNormalizers.ExfilEvent.Equal(r, other)
 }
 
 func (org Organization) exfil(responseData interface{}, action string, req Dict) error {
@@ -69,7 +52,7 @@ func (org Organization) ExfilRuleEventAdd(name ExfilRuleName, event ExfilRuleEve
 	}
 	platforms := event.Filters.Platforms
 	if platforms == nil {
-		platforms = []string{}
+		platforms = []Platform{}
 	}
 	data := Dict{
 		"name":      name,
@@ -86,46 +69,61 @@ func (org Organization) ExfilRuleEventDelete(name ExfilRuleName) error {
 	return org.exfil(&resp, "remove_event_rule", Dict{"name": name})
 }
 
+// ExfilWatchOperator compares the value at the Path
+// of the events watched to the Value of a watch.
+type ExfilWatchOperator = string
+
+var ExfilWatchOperators = struct {
+	Is            ExfilWatchOperator
+	Contains      ExfilWatchOperator
+	StartsWith    ExfilWatchOperator
+	EndsWith      ExfilWatchOperator
+	Matches       ExfilWatchOperator
+	IsGreaterThan ExfilWatchOperator
+	IsLowerThan   ExfilWatchOperator
+}{
+	Is:            "is",
+	Contains:      "contains",
+	StartsWith:    "starts with",
+	EndsWith:      "ends with",
+	Matches:       "matches",
+	IsGreaterThan: "is greater than",
+	IsLowerThan:   "is lower than",
+}
+
 type ExfilRuleWatch struct {
 	LastUpdated uint64 `json:"updated,omitempty" yaml:"updated,omitempty"`
 	CreatedBy   string `json:"by,omitempty" yaml:"by,omitempty"`
 
-	Event    string            `json:"event" yaml:"event"`
-	Value    string            `json:"value" yaml:"value"`
-	Path     []string          `json:"path" yaml:"path"`
-	Operator string            `json:"operator" yaml:"operator"`
-	Filters  ExfilEventFilters `json:"filters" yaml:"filters"`
+	Event    string             `json:"event" yaml:"event"`
+	Value    string             `json:"value" yaml:"value"`
+	Path     []string           `json:"path" yaml:"path"`
+	Operator ExfilWatchOperator `json:"operator" yaml:"operator"`
+	Filters  ExfilEventFilters  `json:"filters" yaml:"filters"`
+	// Expiry is the time, in seconds since epoch, at which
+	// the service removes the watch, never if 0.
+	Expiry int64 `json:"expiry,omitempty" yaml:"expiry,omitempty"`
 }
 
-func (r ExfilRuleWatch) jsonMarhsalContent() ([]byte, error) {
-	if r.Path == nil {
-		r.Path = []string{}
-	}
-	if r.Filters.Platforms == nil {
-		r.Filters.Platforms = []string{}
-	}
-	if r.Filters.Tags == nil {
-		r.Filters.Tags = []string{}
-	}
-	return json.Marshal(Dict{
-		"event":    r.Event,
-		"value":    r.Value,
-		"path":     r.Path,
-		"operator": r.Operator,
-		"filters":  r.Filters,
-	})
+func (r ExfilRuleWatch) EqualsContent(other ExfilRuleWatch) bool {
+	return syncNormalizers.ExfilWatch.Equal(r, other)
 }
 
-func (r ExfilRuleWatch) EqualsContent(other ExfilRuleWatch) bool {
-	bytes, err := r.jsonMarhsalContent()
-	if err != nil {
-		return false
+// Validate checks the watch has an event, a path and a known operator.
+func (r ExfilRuleWatch) Validate() error {
+	if r.Event == "" {
+		return errors.New("watch has no event")
+	}
+	if len(r.Path) == 0 {
+		return errors.New("watch has no path")
 	}
-	otherBytes, err := other.jsonMarhsalContent()
-	if err != nil {
-		return false
+	switch strings.ToLower(r.Operator) {
+	case ExfilWatchOperators.Is, ExfilWatchOperators.Contains, ExfilWatchOperators.StartsWith,
+		ExfilWatchOperators.EndsWith, ExfilWatchOperators.Matches,
+		ExfilWatchOperators.IsGreaterThan, ExfilWatchOperators.IsLowerThan:
+		return nil
 	}
-	return string(bytes) == string(otherBytes)
+	return fmt.Errorf("unknown watch operator: %q", r.Operator)
 }
 
 func (org Organization) ExfilRuleWatchAdd(name ExfilRuleName, watch ExfilRuleWatch) error {
@@ -135,10 +133,9 @@ func (org Organization) ExfilRuleWatchAdd(name ExfilRuleName, watch ExfilRuleWat
 	}
 	platforms := watch.Filters.Platforms
 	if platforms == nil {
-		platforms = []string{}
+		platforms = []Platform{}
 	}
-	resp := Dict{}
-	return org.exfil(&resp, "add_watch", Dict{
+	data := Dict{
 		"name":      name,
 		"operator":  watch.Operator,
 		"event":     watch.Event,
@@ -146,10 +143,31 @@ func (org Organization) ExfilRuleWatchAdd(name ExfilRuleName, watch ExfilRuleWat
 		"path":      watch.Path,
 		"tags":      tags,
 		"platforms": platforms,
-	})
+	}
+	if watch.Expiry != 0 {
+		data["expiry"] = watch.Expiry
+	}
+	resp := Dict{}
+	return org.exfil(&resp, "add_watch", data)
 }
 
 func (org Organization) ExfilRuleWatchDelete(name ExfilRuleName) error {
 	resp := Dict{}
 	return org.exfil(&resp, "remove_watch", Dict{"name": name})
 }
+
+func lintExfilWatchInvalid(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	if conf.Exfil == nil {
+		return findings
+	}
+	for name, watch := range conf.Exfil.Watches {
+		if err := watch.Validate(); err != nil {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Error,
+				Message:  err.Error(),
+			}.at("exfil", "watch", name))
+		}
+	}
+	return findings
+}
@@ -40,7 +40,7 @@ func (s *ExfilTestSuite) TestEventAddDelete() {
 		Events: []string{"NEW_TCP4_CONNECTION", "NEW_TCP6_CONNECTION"},
 		Filters: ExfilEventFilters{
 			Tags:      []string{"vip"},
-			Platforms: []string{"windows", "linux"},
+			Platforms: []Platform{"windows", "linux"},
 		},
 	}
 	s.NoError(s.org.ExfilRuleEventAdd(ruleName, ruleEvent))
@@ -76,7 +76,7 @@ func (s *ExfilTestSuite) TestWatchAddDelete() {
 		Path:     []string{"FILE_PATH"},
 		Filters: ExfilEventFilters{
 			Tags:      []string{"server"},
-			Platforms: []string{"windows"},
+			Platforms: []Platform{"windows"},
 		},
 	}
 	s.NoError(s.org.ExfilRuleWatchAdd(ruleName, ruleWatch))
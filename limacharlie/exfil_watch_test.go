@@ -0,0 +1,59 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExfilWatchFilters(t *testing.T) {
+	a := assert.New(t)
+
+	watch := ExfilRuleWatch{
+		Event:    "MODULE_LOAD",
+		Operator: ExfilWatchOperators.EndsWith,
+		Value:    "wininet.dll",
+		Path:     []string{"FILE_PATH"},
+	}
+	a.NoError(watch.Validate())
+
+	tagged := watch
+	tagged.Filters = ExfilEventFilters{Tags: []string{"server"}, Platforms: []Platform{"windows"}}
+	a.False(tagged.EqualsContent(watch))
+	expiring := watch
+	expiring.Expiry = 1700000000
+	a.False(expiring.EqualsContent(watch))
+	upper := watch
+	upper.Operator = "Ends With"
+	a.True(upper.EqualsContent(watch))
+	a.NoError(upper.Validate())
+
+	invalid := watch
+	invalid.Operator = "resembles"
+	a.EqualError(invalid.Validate(), `unknown watch operator: "resembles"`)
+	findings := lintExfilWatchInvalid(OrgConfig{Exfil: &orgSyncExfilRules{
+		Watches: map[ExfilRuleName]ExfilRuleWatch{"w1": watch, "w2": invalid},
+	}})
+	a.Len(findings, 1)
+	a.Equal("exfil.watch.w2", findings[0].Location)
+
+	// Adding a tag filter to a watch of the Org updates it.
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, `{"watch":{"w1":{
+			"event":"MODULE_LOAD","operator":"ends with","value":"wininet.dll","path":["FILE_PATH"],
+			"filters":{"tags":[],"platforms":[]},"by":"someone","updated":1234
+		}}}`), nil
+	}))
+	ops, err := org.syncExfil(&orgSyncExfilRules{
+		Watches: map[ExfilRuleName]ExfilRuleWatch{"w1": tagged},
+	}, SyncOptions{IsDryRun: true})
+	a.NoError(err)
+	a.Equal([]OrgSyncOperation{{ElementType: OrgSyncOperationElementType.ExfilWatch, ElementName: "w1", IsAdded: true, IsUpdated: true}}, ops)
+
+	ops, err = org.syncExfil(&orgSyncExfilRules{
+		Watches: map[ExfilRuleName]ExfilRuleWatch{"w1": watch},
+	}, SyncOptions{IsDryRun: true})
+	a.NoError(err)
+	a.Equal([]OrgSyncOperation{{ElementType: OrgSyncOperationElementType.ExfilWatch, ElementName: "w1"}}, ops)
+}
@@ -0,0 +1,102 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+type ExtensionName = string
+
+const extensionConfigHive = "extension_config"
+
+// ExtensionDefinition describes an extension available in the marketplace.
+type ExtensionDefinition struct {
+	Name        ExtensionName `json:"name"`
+	Label       string        `json:"label,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Author      string        `json:"author,omitempty"`
+	IsPublic    bool          `json:"is_public,omitempty"`
+}
+
+type extensionDefinitionsList struct {
+	Extensions []ExtensionDefinition `json:"extensions"`
+}
+
+type extensionSubscriptionsList struct {
+	Extensions []ExtensionName `json:"extensions"`
+}
+
+// ExtensionsAvailable lists the extensions the organization can subscribe to.
+func (org Organization) ExtensionsAvailable() ([]ExtensionDefinition, error) {
+	resp := extensionDefinitionsList{}
+	if err := org.client.reliableRequest(http.MethodGet, "extension/definition", makeDefaultRequest(&resp)); err != nil {
+		return nil, err
+	}
+	return resp.Extensions, nil
+}
+
+// Extensions lists the extensions the organization is subscribed to.
+func (org Organization) Extensions() ([]ExtensionName, error) {
+	resp := extensionSubscriptionsList{}
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("orgs/%s/subscriptions", org.client.options.OID), makeDefaultRequest(&resp)); err != nil {
+		return nil, err
+	}
+	return resp.Extensions, nil
+}
+
+// SubscribeToExtension subscribes the organization to an extension.
+func (org Organization) SubscribeToExtension(name ExtensionName) error {
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withTimeout(restCreateOrgTimeout)
+	return org.client.reliableRequest(http.MethodPost, fmt.Sprintf("orgs/%s/subscription/extension/%s", org.client.options.OID, url.PathEscape(name)), request)
+}
+
+// UnsubscribeFromExtension removes the subscription of the organization to an extension.
+func (org Organization) UnsubscribeFromExtension(name ExtensionName) error {
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withTimeout(restCreateOrgTimeout)
+	return org.client.reliableRequest(http.MethodDelete, fmt.Sprintf("orgs/%s/subscription/extension/%s", org.client.options.OID, url.PathEscape(name)), request)
+}
+
+// ExtensionConfig gets the config record of an extension.
+// Extension configs are stored in the "extension_config" hive,
+// so they can also be synced through the "hives" section.
+func (org Organization) ExtensionConfig(name ExtensionName) (*HiveData, error) {
+	return NewHiveClient(&org).Get(HiveArgs{
+		HiveName:     extensionConfigHive,
+		PartitionKey: org.client.options.OID,
+		Key:          name,
+	})
+}
+
+// ExtensionConfigs gets the config records of all extensions.
+func (org Organization) ExtensionConfigs() (HiveConfigData, error) {
+	return NewHiveClient(&org).List(HiveArgs{
+		HiveName:     extensionConfigHive,
+		PartitionKey: org.client.options.OID,
+	})
+}
+
+// ExtensionConfigSet sets the config record of an extension.
+func (org Organization) ExtensionConfigSet(name ExtensionName, config Dict) error {
+	isEnabled := true
+	_, err := NewHiveClient(&org).Add(HiveArgs{
+		HiveName:     extensionConfigHive,
+		PartitionKey: org.client.options.OID,
+		Key:          name,
+		Data:         config,
+		Enabled:      &isEnabled,
+	})
+	return err
+}
+
+// ExtensionConfigDelete removes the config record of an extension.
+func (org Organization) ExtensionConfigDelete(name ExtensionName) error {
+	_, err := NewHiveClient(&org).Remove(HiveArgs{
+		HiveName:     extensionConfigHive,
+		PartitionKey: org.client.options.OID,
+		Key:          name,
+	})
+	return err
+}
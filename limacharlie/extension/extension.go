@@ -0,0 +1,260 @@
+// Package extension implements the webhook contract LimaCharlie uses
+// to talk to extensions, so that an extension can be written in Go by
+// only providing a schema and handlers.
+package extension
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	lc "github.com/refractionPOINT/go-limacharlie/limacharlie"
+)
+
+const (
+	// SignatureHeader is the header containing the hex HMAC-SHA256
+	// of the request body, signed with the extension's shared secret.
+	SignatureHeader = "lc-svc-sig"
+
+	maxMessageSize = 10 * 1024 * 1024
+)
+
+// ErrorInvalidSignature is returned when a message does not
+// carry a valid signature for the extension's secret.
+var ErrorInvalidSignature = errors.New("invalid signature")
+
+type ActionName = string
+type EventName = string
+
+// EventTypes is all the lifecycle events sent to extensions.
+var EventTypes = struct {
+	Subscribe   EventName
+	Unsubscribe EventName
+	Update      EventName
+}{
+	Subscribe:   "subscribe",
+	Unsubscribe: "unsubscribe",
+	Update:      "update",
+}
+
+type RequestHandler func(RequestMessage) Response
+type EventHandler func(EventMessage) Response
+
+// Extension is an http.Handler implementing the LimaCharlie extension protocol.
+type Extension struct {
+	// Name of the extension as registered in LimaCharlie.
+	Name string
+	// Shared secret used to verify messages come from LimaCharlie.
+	SecretKey string
+	// Schema returned to LimaCharlie when requested.
+	Schema Schema
+	// Handlers of requests, by action name.
+	RequestHandlers map[ActionName]RequestHandler
+	// Handlers of events, by event name.
+	EventHandlers map[EventName]EventHandler
+	// Called when LimaCharlie reports an error about this extension.
+	// Optional.
+	ErrorHandler func(ErrorReportMessage)
+
+	Logger lc.LCLogger
+}
+
+// Schema describes the config and requests supported by the extension.
+type Schema struct {
+	Config         SchemaObject                 `json:"config_schema"`
+	Requests       map[ActionName]RequestSchema `json:"request_schema"`
+	RequiredEvents []EventName                  `json:"required_events"`
+	Permissions    []string                     `json:"required_permissions,omitempty"`
+}
+
+type SchemaObject struct {
+	Fields       map[string]SchemaElement `json:"fields"`
+	Requirements [][]string               `json:"requirements,omitempty"`
+}
+
+type SchemaElement struct {
+	Label       string        `json:"label,omitempty"`
+	Description string        `json:"description"`
+	DataType    string        `json:"data_type"`
+	IsList      bool          `json:"is_list,omitempty"`
+	Default     interface{}   `json:"default_value,omitempty"`
+	Enum        []interface{} `json:"enum_values,omitempty"`
+	Object      *SchemaObject `json:"object,omitempty"`
+}
+
+type RequestSchema struct {
+	IsUserFacing bool         `json:"is_user_facing"`
+	Label        string       `json:"label,omitempty"`
+	Description  string       `json:"short_description"`
+	Parameters   SchemaObject `json:"parameter_definitions"`
+}
+
+// OrgAccessData is the organization credentials LimaCharlie
+// provides along with each message.
+type OrgAccessData struct {
+	OID   string `json:"oid"`
+	JWT   string `json:"jwt"`
+	Ident string `json:"ident"`
+}
+
+// Message is the envelope of everything sent by LimaCharlie.
+// Exactly one of the pointers is set.
+type Message struct {
+	Version        int    `json:"version"`
+	IdempotencyKey string `json:"idempotency_key"`
+
+	HeartBeat     *HeartBeatMessage     `json:"heartbeat,omitempty"`
+	ErrorReport   *ErrorReportMessage   `json:"error_report,omitempty"`
+	SchemaRequest *SchemaRequestMessage `json:"schema_request,omitempty"`
+	Request       *RequestMessage       `json:"request,omitempty"`
+	Event         *EventMessage         `json:"event,omitempty"`
+}
+
+type HeartBeatMessage struct{}
+
+type SchemaRequestMessage struct{}
+
+type ErrorReportMessage struct {
+	Error string `json:"error"`
+	OID   string `json:"oid"`
+}
+
+type RequestMessage struct {
+	Org    OrgAccessData `json:"org"`
+	Action ActionName    `json:"action"`
+	Data   lc.Dict       `json:"data"`
+	Config lc.Dict       `json:"config"`
+}
+
+type EventMessage struct {
+	Org       OrgAccessData `json:"org"`
+	EventName EventName     `json:"event_name"`
+	Data      lc.Dict       `json:"data"`
+	Config    lc.Dict       `json:"config"`
+}
+
+// Response is returned to LimaCharlie for requests and events.
+type Response struct {
+	Error string      `json:"error,omitempty"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// Organization builds an SDK organization from the credentials
+// sent by LimaCharlie so that handlers can call the API on behalf of the org.
+func (o OrgAccessData) Organization(logger lc.LCLogger) (*lc.Organization, error) {
+	if o.OID == "" || o.JWT == "" {
+		return nil, errors.New("missing org credentials")
+	}
+	if logger == nil {
+		logger = &lc.LCLoggerEmpty{}
+	}
+	c, err := lc.NewClientFromLoader(lc.ClientOptions{
+		OID: o.OID,
+		JWT: o.JWT,
+	}, logger)
+	if err != nil {
+		return nil, err
+	}
+	return lc.NewOrganization(c)
+}
+
+// ComputeSignature returns the expected signature of a message.
+func ComputeSignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks that the signature of a message was
+// produced with the shared secret.
+func VerifySignature(secret string, body []byte, signature string) error {
+	if secret == "" || signature == "" {
+		return ErrorInvalidSignature
+	}
+	expected := ComputeSignature(secret, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrorInvalidSignature
+	}
+	return nil
+}
+
+func (e *Extension) logger() lc.LCLogger {
+	if e.Logger == nil {
+		return &lc.LCLoggerEmpty{}
+	}
+	return e.Logger
+}
+
+// ServeHTTP receives messages from LimaCharlie.
+func (e *Extension) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := VerifySignature(e.SecretKey, body, r.Header.Get(SignatureHeader)); err != nil {
+		e.logger().Warn(fmt.Sprintf("extension %s: %v", e.Name, err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	msg := Message{}
+	if err := json.Unmarshal(body, &msg); err != nil {
+		e.logger().Error(fmt.Sprintf("extension %s: invalid message: %v", e.Name, err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	resp := e.HandleMessage(msg)
+
+	out, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+// HandleMessage routes an already verified message to the relevant handler.
+func (e *Extension) HandleMessage(msg Message) interface{} {
+	if msg.HeartBeat != nil {
+		return Response{}
+	}
+	if msg.SchemaRequest != nil {
+		return e.Schema
+	}
+	if msg.ErrorReport != nil {
+		e.logger().Error(fmt.Sprintf("extension %s: error reported for %s: %s", e.Name, msg.ErrorReport.OID, msg.ErrorReport.Error))
+		if e.ErrorHandler != nil {
+			e.ErrorHandler(*msg.ErrorReport)
+		}
+		return Response{}
+	}
+	if msg.Request != nil {
+		handler, ok := e.RequestHandlers[msg.Request.Action]
+		if !ok {
+			return Response{Error: fmt.Sprintf("unknown action: %s", msg.Request.Action)}
+		}
+		return handler(*msg.Request)
+	}
+	if msg.Event != nil {
+		handler, ok := e.EventHandlers[msg.Event.EventName]
+		if !ok {
+			// Events without handlers are simply acknowledged.
+			return Response{}
+		}
+		return handler(*msg.Event)
+	}
+	return Response{Error: "unknown message type"}
+}
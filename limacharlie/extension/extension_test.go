@@ -0,0 +1,71 @@
+package extension
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "test-secret"
+
+func testExtension() *Extension {
+	return &Extension{
+		Name:      "test-ext",
+		SecretKey: testSecret,
+		Schema: Schema{
+			RequiredEvents: []EventName{EventTypes.Subscribe},
+		},
+		RequestHandlers: map[ActionName]RequestHandler{
+			"ping": func(r RequestMessage) Response {
+				return Response{Data: map[string]interface{}{"pong": r.Data["v"]}}
+			},
+		},
+	}
+}
+
+func postMessage(e *Extension, body []byte, sig string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(SignatureHeader, sig)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	return w
+}
+
+func TestExtensionSignature(t *testing.T) {
+	a := assert.New(t)
+	e := testExtension()
+	body := []byte(`{"version":1,"heartbeat":{}}`)
+
+	a.Equal(http.StatusUnauthorized, postMessage(e, body, "").Code)
+	a.Equal(http.StatusUnauthorized, postMessage(e, body, ComputeSignature("other", body)).Code)
+	a.Equal(http.StatusOK, postMessage(e, body, ComputeSignature(testSecret, body)).Code)
+}
+
+func TestExtensionRouting(t *testing.T) {
+	a := assert.New(t)
+	e := testExtension()
+
+	body := []byte(`{"version":1,"request":{"org":{"oid":"x"},"action":"ping","data":{"v":"a"}}}`)
+	w := postMessage(e, body, ComputeSignature(testSecret, body))
+	a.Equal(http.StatusOK, w.Code)
+	resp := map[string]interface{}{}
+	a.NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	a.Equal(map[string]interface{}{"data": map[string]interface{}{"pong": "a"}}, resp)
+
+	body = []byte(`{"version":1,"request":{"action":"nope"}}`)
+	w = postMessage(e, body, ComputeSignature(testSecret, body))
+	a.Contains(w.Body.String(), "unknown action")
+
+	body = []byte(`{"version":1,"schema_request":{}}`)
+	w = postMessage(e, body, ComputeSignature(testSecret, body))
+	a.Contains(w.Body.String(), `"required_events":["subscribe"]`)
+}
+
+func TestOrgAccessDataMissingCreds(t *testing.T) {
+	_, err := OrgAccessData{OID: "x"}.Organization(nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,14 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtensionsList(t *testing.T) {
+	a := assert.New(t)
+	org := getTestOrgFromEnv(a)
+	_, err := org.Extensions()
+	a.NoError(err)
+}
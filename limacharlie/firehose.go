@@ -287,6 +287,9 @@ func NewFirehose(org *Organization, fhOpts FirehoseOptions, fhOutputOpts *Fireho
 	if err != nil {
 		return nil, fmt.Errorf("could not make tls config: %s", err)
 	}
+	if org != nil && org.client != nil {
+		tlsConfig.MinVersion = org.client.options.TLSMinVersion
+	}
 	fh := &Firehose{
 		Organization:     org,
 		opts:             fhOpts,
@@ -42,7 +42,7 @@ func TestFirehose(t *testing.T) {
 		defer wg.Done()
 		time.Sleep(2 * time.Second)
 
-		conn, err := getTestFeeder()
+		conn, err := getTestFeeder(3000)
 		a.NoError(err)
 		defer conn.Close()
 
@@ -58,7 +58,7 @@ func TestFirehose(t *testing.T) {
 		defer wg.Done()
 		time.Sleep(3 * time.Second)
 
-		conn, err := getTestFeeder()
+		conn, err := getTestFeeder(3000)
 		if err != nil {
 			t.Errorf("getTestFeeder: %v", err)
 			return
@@ -106,10 +106,10 @@ func TestFirehose(t *testing.T) {
 	a.True(ok)
 }
 
-func getTestFeeder() (net.Conn, error) {
+func getTestFeeder(port int) (net.Conn, error) {
 	return tls.DialWithDialer(&net.Dialer{
 		Timeout: 5 * time.Second,
-	}, "tcp", fmt.Sprintf("127.0.0.1:3000"), &tls.Config{
+	}, "tcp", fmt.Sprintf("127.0.0.1:%d", port), &tls.Config{
 		InsecureSkipVerify: true,
 	})
 }
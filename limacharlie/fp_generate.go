@@ -0,0 +1,90 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// GenerateFPRuleFromDetection builds an FP rule suppressing the
+// detections matching det on the fields, paths in the detection
+// like "cat" or "detect/event/FILE_PATH". The fields must hold a
+// string, number or boolean.
+func GenerateFPRuleFromDetection(det Detection, fields []string) (OrgSyncFPRule, error) {
+	if len(fields) == 0 {
+		return OrgSyncFPRule{}, fmt.Errorf("no fields to match")
+	}
+	raw, err := json.Marshal(det)
+	if err != nil {
+		return OrgSyncFPRule{}, err
+	}
+	d := Dict{}
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return OrgSyncFPRule{}, err
+	}
+
+	// Nested nodes have the types of rules loaded from YAML.
+	matches := []interface{}{}
+	for _, field := range fields {
+		path := strings.Trim(field, "/")
+		v, ok := d.FindByPath(path)
+		if !ok || v == nil {
+			return OrgSyncFPRule{}, fmt.Errorf("detection has no %s", path)
+		}
+		switch v.(type) {
+		case string, bool, int64, float64:
+		default:
+			return OrgSyncFPRule{}, fmt.Errorf("%s is not a string, number or boolean", path)
+		}
+		matches = append(matches, map[string]interface{}{
+			"op":    "is",
+			"path":  path,
+			"value": v,
+		})
+	}
+
+	rule := OrgSyncFPRule{}
+	if len(matches) == 1 {
+		rule.Detection = Dict(matches[0].(map[string]interface{}))
+	} else {
+		rule.Detection = Dict{
+			"op":    "and",
+			"rules": matches,
+		}
+	}
+	if det.DetectID != "" {
+		rule.Description = fmt.Sprintf("generated from detection %s", det.DetectID)
+	}
+	return rule, nil
+}
+
+var fpRuleNameInvalidChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// GenerateFPRulesFromDetections builds the FP rules of the detections
+// like GenerateFPRuleFromDetection, named "fp-<category>-<hash>" from
+// what they match so that detections matching the same values share
+// a rule and generating rules again yields the same names.
+func GenerateFPRulesFromDetections(dets []Detection, fields []string) (map[FPRuleName]OrgSyncFPRule, error) {
+	rules := map[FPRuleName]OrgSyncFPRule{}
+	for _, det := range dets {
+		rule, err := GenerateFPRuleFromDetection(det, fields)
+		if err != nil {
+			return nil, fmt.Errorf("detection %s: %v", det.DetectID, err)
+		}
+		h, err := ContentHash(rule.Detection)
+		if err != nil {
+			return nil, err
+		}
+		cat := strings.Trim(fpRuleNameInvalidChars.ReplaceAllString(strings.ToLower(det.Category), "-"), "-")
+		name := "fp-" + h[:12]
+		if cat != "" {
+			name = fmt.Sprintf("fp-%s-%s", cat, h[:12])
+		}
+		if _, ok := rules[name]; ok {
+			continue
+		}
+		rules[name] = rule
+	}
+	return rules, nil
+}
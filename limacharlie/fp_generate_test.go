@@ -0,0 +1,61 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateFPRuleFromDetection(t *testing.T) {
+	a := assert.New(t)
+	det := Detection{
+		DetectID: "d1",
+		Category: "Suspicious PowerShell",
+		Routing:  Routing{Hostname: "build-01"},
+		Detect: Dict{"event": map[string]interface{}{
+			"FILE_PATH":  `C:\tools\build.ps1`,
+			"PROCESS_ID": 1234,
+		}},
+	}
+
+	rule, err := GenerateFPRuleFromDetection(det, []string{"cat"})
+	a.NoError(err)
+	a.Equal(Dict{"op": "is", "path": "cat", "value": "Suspicious PowerShell"}, rule.Detection)
+	a.Equal("generated from detection d1", rule.Description)
+
+	rule, err = GenerateFPRuleFromDetection(det, []string{"cat", "routing/hostname", "detect/event/FILE_PATH"})
+	a.NoError(err)
+	a.Equal(Dict{
+		"op": "and",
+		"rules": []interface{}{
+			map[string]interface{}{"op": "is", "path": "cat", "value": "Suspicious PowerShell"},
+			map[string]interface{}{"op": "is", "path": "routing/hostname", "value": "build-01"},
+			map[string]interface{}{"op": "is", "path": "detect/event/FILE_PATH", "value": `C:\tools\build.ps1`},
+		},
+	}, rule.Detection)
+	a.Len(lintFPRuleTooBroad(OrgConfig{FPRules: orgSyncFPRules{"fp": OrgSyncFPRule{Detection: Dict{"op": "is", "path": "cat", "value": "x"}}}}), 1)
+	a.Empty(lintFPRuleTooBroad(OrgConfig{FPRules: orgSyncFPRules{"fp": rule}}))
+
+	_, err = GenerateFPRuleFromDetection(det, []string{"detect/event/COMMAND_LINE"})
+	a.EqualError(err, "detection has no detect/event/COMMAND_LINE")
+	_, err = GenerateFPRuleFromDetection(det, []string{"detect/event"})
+	a.Error(err)
+	_, err = GenerateFPRuleFromDetection(det, nil)
+	a.Error(err)
+
+	other := det
+	other.DetectID = "d2"
+	different := det
+	different.DetectID = "d3"
+	different.Routing.Hostname = "build-02"
+	rules, err := GenerateFPRulesFromDetections([]Detection{det, other, different}, []string{"cat", "routing/hostname"})
+	a.NoError(err)
+	a.Len(rules, 2)
+	for name, r := range rules {
+		a.Regexp(`^fp-suspicious-powershell-[0-9a-f]{12}$`, name)
+		a.NotEqual("generated from detection d2", r.Description)
+	}
+	again, err := GenerateFPRulesFromDetections([]Detection{different, det}, []string{"cat", "routing/hostname"})
+	a.NoError(err)
+	a.Equal(rules, again)
+}
@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 )
 
 type FPRuleOptions struct {
 	// Replace rule if it already exists with this name.
 	IsReplace bool
+	// Description documents the rule, it is stored with it.
+	Description string
 }
 
 type FPRuleName = string
@@ -16,6 +19,8 @@ type FPRule struct {
 	Detection Dict       `json:"data" yaml:"data"`
 	OID       string     `json:"oid" yaml:"oid"`
 	Name      FPRuleName `json:"name,omitempty" yaml:"name,omitempty"`
+
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 }
 
 // FPRules get all false positive rules from a LC organization.
@@ -28,10 +33,31 @@ func (org Organization) FPRules() (map[FPRuleName]FPRule, error) {
 	return resp, nil
 }
 
+// FPRuleGet returns a single false positive rule as stored.
+func (org Organization) FPRuleGet(name FPRuleName) (FPRule, error) {
+	resp := FPRule{}
+	request := makeDefaultRequest(&resp)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("fp/%s/%s", org.client.options.OID, url.PathEscape(name)), request); err != nil {
+		if isRESTNotFound(err) {
+			return FPRule{}, ErrorResourceNotFound
+		}
+		return FPRule{}, err
+	}
+	if resp.Detection == nil {
+		return FPRule{}, ErrorResourceNotFound
+	}
+	if resp.Name == "" {
+		resp.Name = name
+	}
+	return resp, nil
+}
+
 type fpAddRuleRequest struct {
 	IsReplace bool       `json:"is_replace,string"`
 	Name      FPRuleName `json:"name"`
 	Rule      string     `json:"rule"`
+
+	Description string `json:"description,omitempty"`
 }
 
 // FPRuleAdd add a false positive rule to a LC organization
@@ -53,6 +79,8 @@ func (org Organization) FPRuleAdd(name FPRuleName, detection interface{}, opts .
 		IsReplace: reqOpt.IsReplace,
 		Name:      name,
 		Rule:      string(ruleBytes),
+
+		Description: reqOpt.Description,
 	})
 	if err := org.client.reliableRequest(http.MethodPost, fmt.Sprintf("fp/%s", org.client.options.OID), request); err != nil {
 		return err
@@ -5,8 +5,10 @@ go 1.16
 require (
 	github.com/google/uuid v1.3.0
 	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/prometheus/client_golang v1.12.2
 	github.com/rs/zerolog v1.28.0
 	github.com/stretchr/testify v1.8.1
 	golang.org/x/sys v0.2.0 // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,89 @@
+package limacharlie
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ContentHash returns a hash of the canonical JSON representation of v.
+// Keys are sorted, numbers are normalized and null values as well as
+// empty lists and maps are ignored, so that two elements with the same
+// content have the same hash regardless of how they were loaded.
+func ContentHash(v interface{}) (string, error) {
+	b, err := canonicalJSON(v)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// DeepEqualConfig returns true if a and b, like rules or outputs as
+// structs or Dicts, have the same content the way sync compares them:
+// regardless of key order, of how numbers are typed, like 1 and 1.0,
+// and of null values as well as empty lists and maps, a nil and an
+// empty list being equal. Values that cannot be marshaled to JSON
+// are never equal. It is the zero Normalizer.
+func DeepEqualConfig(a interface{}, b interface{}) bool {
+	return Normalizer{}.Equal(a, b)
+}
+
+func canonicalJSON(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	var generic interface{}
+	if err := d.Decode(&generic); err != nil {
+		return nil, err
+	}
+	generic, _ = pruneCanonical(generic)
+	return json.Marshal(generic)
+}
+
+// pruneCanonical removes empty values and normalizes numbers.
+// It returns false if the value itself is empty.
+func pruneCanonical(v interface{}) (interface{}, bool) {
+	switch val := v.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		for k, e := range val {
+			n, ok := pruneCanonical(e)
+			if !ok {
+				delete(val, k)
+				continue
+			}
+			val[k] = n
+		}
+		return val, len(val) != 0
+	case []interface{}:
+		out := []interface{}{}
+		for _, e := range val {
+			if n, ok := pruneCanonical(e); ok {
+				out = append(out, n)
+			}
+		}
+		return out, len(out) != 0
+	case json.Number:
+		// Represent 1 and 1.0 the same way.
+		if i, err := val.Int64(); err == nil {
+			return json.Number(strconv.FormatInt(i, 10)), true
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return val, true
+		}
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return json.Number(strconv.FormatInt(int64(f), 10)), true
+		}
+		return json.Number(strconv.FormatFloat(f, 'g', -1, 64)), true
+	}
+	return v, true
+}
@@ -0,0 +1,66 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestContentHash(t *testing.T) {
+	a := assert.New(t)
+
+	fromYAML := CoreDRRule{}
+	a.NoError(yaml.Unmarshal([]byte(`
+detect:
+  op: is
+  path: event/PORT
+  value: 443.0
+  rules: []
+respond:
+  - action: report
+    name: test
+`), &fromYAML))
+	fromJSON := Dict{}
+	a.NoError(fromJSON.UnmarshalJSON([]byte(`{"value": 443, "path": "event/PORT", "op": "is", "extra": null}`)))
+
+	h1, err := ContentHash(fromYAML.Detect)
+	a.NoError(err)
+	h2, err := ContentHash(fromJSON)
+	a.NoError(err)
+	a.Equal(h1, h2)
+
+	h3, err := ContentHash(Dict{"op": "is", "path": "event/PORT", "value": 80})
+	a.NoError(err)
+	a.NotEqual(h1, h3)
+
+	isEnabled := true
+	fromYAML.IsEnabled = &isEnabled
+	other := CoreDRRule{Detect: fromJSON, Response: List{Dict{"name": "test", "action": "report"}}, IsEnabled: &isEnabled}
+	a.True(fromYAML.Equal(other))
+}
+
+func TestOrgSyncOperationState(t *testing.T) {
+	a := assert.New(t)
+
+	unchanged := OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "o"}
+	a.True(unchanged.IsUnchanged())
+	a.Equal("= output o", unchanged.String())
+
+	updated := OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "o", IsAdded: true, IsUpdated: true}
+	a.False(updated.IsUnchanged())
+	a.Equal("~ output o", updated.String())
+}
+
+func TestDeepEqualConfig(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(DeepEqualConfig(
+		Dict{"op": "is", "value": 443, "tags": []string{}, "extra": nil},
+		map[string]interface{}{"value": 443.0, "op": "is"},
+	))
+	a.True(DeepEqualConfig(CoreDRRule{Detect: Dict{"op": "is"}, Response: List{}}, CoreDRRule{Detect: Dict{"op": "is"}}))
+	a.False(DeepEqualConfig(Dict{"value": 443}, Dict{"value": 443.5}))
+	a.False(DeepEqualConfig(List{1, 2}, List{2, 1}))
+	a.False(DeepEqualConfig(Dict{"f": func() {}}, Dict{"f": func() {}}))
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"time"
 )
 
 type HiveClient struct {
@@ -104,6 +106,17 @@ func (h *HiveClient) Get(args HiveArgs) (*HiveData, error) {
 	return &hiveSet, nil
 }
 
+// GetToWriter streams the raw JSON of a record to a writer,
+// avoiding buffering large records like lookups in memory.
+func (h *HiveClient) GetToWriter(args HiveArgs, w io.Writer) error {
+	if args.Key == "" {
+		return errors.New("key is required")
+	}
+	request := makeDefaultRequest(nil).withTimeout(60 * time.Second).withResponseWriter(w)
+	return h.Organization.client.reliableRequest(http.MethodGet,
+		fmt.Sprintf("hive/%s/%s/%s/data", args.HiveName, args.PartitionKey, url.PathEscape(args.Key)), request)
+}
+
 func (h *HiveClient) GetMTD(args HiveArgs) (*HiveData, error) {
 	if args.Key == "" {
 		return nil, errors.New("key is required")
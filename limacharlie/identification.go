@@ -1,5 +1,11 @@
 package limacharlie
 
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
 var Platforms = struct {
 	Windows  uint32
 	Linux    uint32
@@ -121,3 +127,173 @@ var StringToArchitecture = map[string]uint32{
 	// USP Formats
 	"usp_adapter": Architectures.USPAdapter,
 }
+
+// Platform is the name of a sensor platform, like "windows",
+// as used in the filters of rules. Loading a config, from YAML,
+// with an unknown platform name fails while decoding one from
+// the JSON of the API keeps it as is, so that the platforms added
+// to LimaCharlie after this version do not break fetching. Such
+// platforms are reported by the "platform-unknown" lint rule.
+type Platform string
+
+// Architecture is the name of a sensor architecture, like "x64",
+// decoded like a Platform.
+type Architecture string
+
+// Mask of the platform in the numeric platform
+// and architecture values of a sensor.
+const platformMask = 0xF0000000
+
+// ParsePlatform returns the Platform with the name provided.
+func ParsePlatform(name string) (Platform, error) {
+	p := Platform(strings.ToLower(strings.TrimSpace(name)))
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// PlatformFromID returns the Platform of a numeric platform value,
+// which may also contain the architecture bits.
+func PlatformFromID(id uint32) (Platform, error) {
+	name, ok := PlatformStrings[id&platformMask]
+	if !ok {
+		return "", fmt.Errorf("unknown platform id: 0x%08x", id)
+	}
+	return Platform(name), nil
+}
+
+func (p Platform) Validate() error {
+	if _, ok := StringToPlatform[string(p)]; !ok {
+		return fmt.Errorf("unknown platform: %q", string(p))
+	}
+	return nil
+}
+
+// ID returns the numeric value of the platform, or 0 if it is unknown.
+func (p Platform) ID() uint32 {
+	return StringToPlatform[string(p)]
+}
+
+func (p *Platform) UnmarshalJSON(data []byte) error {
+	s := ""
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*p = Platform(strings.ToLower(strings.TrimSpace(s)))
+	return nil
+}
+
+func (p *Platform) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	s := ""
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := ParsePlatform(s)
+	if err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
+
+// PlatformNames converts platforms to their names.
+func PlatformNames(platforms []Platform) []string {
+	names := make([]string, 0, len(platforms))
+	for _, p := range platforms {
+		names = append(names, string(p))
+	}
+	return names
+}
+
+// ParseArchitecture returns the Architecture with the name provided.
+func ParseArchitecture(name string) (Architecture, error) {
+	a := Architecture(strings.ToLower(strings.TrimSpace(name)))
+	if err := a.Validate(); err != nil {
+		return "", err
+	}
+	return a, nil
+}
+
+// ArchitectureFromID returns the Architecture of a numeric architecture
+// value, which may also contain the platform bits.
+func ArchitectureFromID(id uint32) (Architecture, error) {
+	name, ok := ArchitectureStrings[id&^platformMask]
+	if !ok {
+		return "", fmt.Errorf("unknown architecture id: 0x%08x", id)
+	}
+	return Architecture(name), nil
+}
+
+func (a Architecture) Validate() error {
+	if _, ok := StringToArchitecture[string(a)]; !ok {
+		return fmt.Errorf("unknown architecture: %q", string(a))
+	}
+	return nil
+}
+
+// ID returns the numeric value of the architecture, or 0 if it is unknown.
+func (a Architecture) ID() uint32 {
+	return StringToArchitecture[string(a)]
+}
+
+func (a *Architecture) UnmarshalJSON(data []byte) error {
+	s := ""
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*a = Architecture(strings.ToLower(strings.TrimSpace(s)))
+	return nil
+}
+
+func (a *Architecture) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	s := ""
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := ParseArchitecture(s)
+	if err != nil {
+		return err
+	}
+	*a = parsed
+	return nil
+}
+
+func lintPlatformUnknown(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	check := func(path []string, platforms []Platform) {
+		for _, p := range platforms {
+			if err := p.Validate(); err != nil {
+				findings = append(findings, LintFinding{
+					Severity: LintSeverities.Error,
+					Message:  err.Error(),
+				}.at(path...))
+			}
+		}
+	}
+	for name, rule := range conf.DRRules {
+		if rule.Filters != nil {
+			check([]string{"rules", name}, rule.Filters.Platforms)
+		}
+	}
+	for name, rule := range conf.Integrity {
+		check([]string{"integrity", name}, rule.Platforms)
+	}
+	for name, rule := range conf.Artifacts {
+		check([]string{"artifact", name}, rule.Platforms)
+	}
+	if conf.Exfil != nil {
+		for name, rule := range conf.Exfil.Events {
+			check([]string{"exfil", "list", name}, rule.Filters.Platforms)
+		}
+		for name, rule := range conf.Exfil.Watches {
+			check([]string{"exfil", "watch", name}, rule.Filters.Platforms)
+		}
+	}
+	if conf.Yara != nil {
+		for name, rule := range conf.Yara.Rules {
+			check([]string{"yara", "rules", name}, rule.Filters.Platforms)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,57 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestPlatform(t *testing.T) {
+	a := assert.New(t)
+
+	p, err := ParsePlatform(" Windows ")
+	a.NoError(err)
+	a.Equal(Platform("windows"), p)
+	a.Equal(Platforms.Windows, p.ID())
+
+	_, err = ParsePlatform("windoze")
+	a.Error(err)
+
+	p, err = PlatformFromID(Platforms.Linux | Architectures.X64)
+	a.NoError(err)
+	a.Equal(Platform("linux"), p)
+	arch, err := ArchitectureFromID(Platforms.Linux | Architectures.X64)
+	a.NoError(err)
+	a.Equal(Architecture("x64"), arch)
+	a.Equal(Architectures.X64, arch.ID())
+
+	_, err = PlatformFromID(0)
+	a.Error(err)
+
+	f := IntegrityRuleFilter{}
+	a.NoError(json.Unmarshal([]byte(`{"tags":[],"platforms":["windows","chrome"]}`), &f))
+	a.Equal([]string{"windows", "chrome"}, PlatformNames(f.Platforms))
+
+	// Platforms decoded from the API are kept even if unknown,
+	// but are reported when linting the config.
+	a.NoError(json.Unmarshal([]byte(`{"platforms":["Linux", "new-platform"]}`), &f))
+	a.Equal([]string{"linux", "new-platform"}, PlatformNames(f.Platforms))
+	findings := OrgConfig{Integrity: orgSyncIntegrityRules{"test": {Platforms: f.Platforms}}}.Lint()
+	a.Len(findings, 1)
+	a.Equal("platform-unknown", findings[0].Rule)
+	a.Equal("integrity.test", findings[0].Location)
+
+	rule := ArtifactRule{}
+	a.NoError(json.Unmarshal([]byte(`{"patterns":["/var/log/*"],"filters":{"tags":[],"platforms":["new-platform"]}}`), &rule))
+	a.Equal([]Platform{"new-platform"}, rule.Filters.Platforms)
+
+	c := OrgConfig{}
+	a.Error(yaml.Unmarshal([]byte(`version: 3
+integrity:
+  test:
+    patterns: ["/etc/passwd"]
+    platforms: ["linx"]
+`), &c))
+}
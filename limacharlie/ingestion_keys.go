@@ -0,0 +1,101 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type IngestionKeyName = string
+
+type ingestionKeysList struct {
+	Keys map[IngestionKeyName]string `json:"keys"`
+}
+
+// ArtifactSource is what an external log shipper, like
+// Velociraptor, needs to ingest artifacts into an Org.
+type ArtifactSource struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name"`
+	Endpoint string `json:"endpoint"`
+	Key      string `json:"key"`
+}
+
+// IngestionKeys lists the ingestion keys of the Org by name.
+func (org Organization) IngestionKeys() (map[IngestionKeyName]string, error) {
+	resp := ingestionKeysList{}
+	request := makeDefaultRequest(&resp)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("insight/%s/ingestion_keys", org.client.options.OID), request); err != nil {
+		return nil, err
+	}
+	if resp.Keys == nil {
+		resp.Keys = map[IngestionKeyName]string{}
+	}
+	return resp.Keys, nil
+}
+
+// SetIngestionKey creates, or rotates if it exists, an ingestion key.
+func (org Organization) SetIngestionKey(name IngestionKeyName) (string, error) {
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withFormData(Dict{
+		"name": name,
+	})
+	if err := org.client.reliableRequest(http.MethodPost, fmt.Sprintf("insight/%s/ingestion_keys", org.client.options.OID), request); err != nil {
+		return "", err
+	}
+	key, _ := resp["key"].(string)
+	if key == "" {
+		return "", errors.New("no ingestion key returned")
+	}
+	return key, nil
+}
+
+// DelIngestionKey deletes an ingestion key.
+func (org Organization) DelIngestionKey(name IngestionKeyName) error {
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withFormData(Dict{
+		"name": name,
+	})
+	if err := org.client.reliableRequest(http.MethodDelete, fmt.Sprintf("insight/%s/ingestion_keys", org.client.options.OID), request); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ArtifactIngestEndpoint returns the URL artifacts are ingested at.
+func (org Organization) ArtifactIngestEndpoint() (string, error) {
+	urls, err := org.GetURLs()
+	if err != nil {
+		return "", err
+	}
+	host, ok := urls["artifacts"]
+	if !ok {
+		return "", errors.New("no artifacts url available for org")
+	}
+	return fmt.Sprintf("https://%s/ingest", host), nil
+}
+
+// ProvisionArtifactSource returns the endpoint and ingestion key for
+// an external artifact source, creating the key if it does not exist.
+func (org Organization) ProvisionArtifactSource(name IngestionKeyName) (ArtifactSource, error) {
+	source := ArtifactSource{
+		OID:  org.client.options.OID,
+		Name: name,
+	}
+	var err error
+	if source.Endpoint, err = org.ArtifactIngestEndpoint(); err != nil {
+		return source, err
+	}
+	keys, err := org.IngestionKeys()
+	if err != nil {
+		return source, err
+	}
+	if key, ok := keys[name]; ok {
+		source.Key = key
+		return source, nil
+	}
+	if source.Key, err = org.SetIngestionKey(name); err != nil {
+		return source, err
+	}
+	return source, nil
+}
@@ -0,0 +1,44 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvisionArtifactSource(t *testing.T) {
+	a := assert.New(t)
+	keys := map[string]string{"existing": "k1"}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case fmt.Sprintf("/v1/orgs/%s/url", vcrTestOID):
+			return jsonResponse(http.StatusOK, `{"url":{"artifacts":"artifacts.example.com","ingestion":"ingest.example.com"}}`), nil
+		case fmt.Sprintf("/v1/insight/%s/ingestion_keys", vcrTestOID):
+			if r.Method == http.MethodPost {
+				a.NoError(r.ParseForm())
+				name := r.PostForm.Get("name")
+				keys[name] = "new-" + name
+				return jsonResponse(http.StatusOK, fmt.Sprintf(`{"key":"%s"}`, keys[name])), nil
+			}
+			b, err := json.Marshal(Dict{"keys": keys})
+			a.NoError(err)
+			return jsonResponse(http.StatusOK, string(b)), nil
+		}
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	source, err := org.ProvisionArtifactSource("existing")
+	a.NoError(err)
+	a.Equal(ArtifactSource{OID: vcrTestOID, Name: "existing", Endpoint: "https://artifacts.example.com/ingest", Key: "k1"}, source)
+
+	source, err = org.ProvisionArtifactSource("velociraptor")
+	a.NoError(err)
+	a.Equal("new-velociraptor", source.Key)
+
+	all, err := org.IngestionKeys()
+	a.NoError(err)
+	a.Len(all, 2)
+}
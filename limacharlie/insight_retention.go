@@ -0,0 +1,41 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// RetentionConfig holds the data retention settings of an Org.
+// Zero values are left unchanged when set.
+type RetentionConfig struct {
+	// Number of days events are kept in Insight.
+	EventRetentionDays uint `json:"event_retention_days,omitempty" yaml:"event_retention_days,omitempty"`
+	// Default number of days artifacts are kept when
+	// an artifact rule does not specify a retention.
+	ArtifactRetentionDays uint `json:"artifact_retention_days,omitempty" yaml:"artifact_retention_days,omitempty"`
+}
+
+func (org Organization) RetentionConfig() (RetentionConfig, error) {
+	resp := RetentionConfig{}
+	request := makeDefaultRequest(&resp)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("orgs/%s/retention", org.client.options.OID), request); err != nil {
+		return RetentionConfig{}, err
+	}
+	return resp, nil
+}
+
+func (org Organization) RetentionConfigSet(conf RetentionConfig) error {
+	req := Dict{}
+	if conf.EventRetentionDays != 0 {
+		req["event_retention_days"] = conf.EventRetentionDays
+	}
+	if conf.ArtifactRetentionDays != 0 {
+		req["artifact_retention_days"] = conf.ArtifactRetentionDays
+	}
+	if len(req) == 0 {
+		return nil
+	}
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withFormData(req)
+	return org.client.reliableRequest(http.MethodPost, fmt.Sprintf("orgs/%s/retention", org.client.options.OID), request)
+}
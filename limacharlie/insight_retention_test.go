@@ -0,0 +1,31 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRetentionConfigMerge(t *testing.T) {
+	a := assert.New(t)
+
+	c1 := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(`version: 3
+retention:
+  event_retention_days: 90
+  artifact_retention_days: 30
+`), &c1))
+	c2 := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(`version: 3
+retention:
+  event_retention_days: 365
+`), &c2))
+
+	merged := c1.Merge(c2)
+	a.Equal(&RetentionConfig{
+		EventRetentionDays:    365,
+		ArtifactRetentionDays: 30,
+	}, merged.Retention)
+	a.Nil(OrgConfig{}.Merge(OrgConfig{}).Retention)
+}
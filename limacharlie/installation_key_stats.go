@@ -0,0 +1,93 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// InstallationKeyStats are the enrollments of the sensors
+// of the Org with an installation key.
+type InstallationKeyStats struct {
+	Key InstallationKey
+	// Enrollments of the sensors of the Org, deleted
+	// sensors not being counted.
+	Enrollments int
+	// LastEnrolledAt is the most recent enrollment,
+	// zero if no sensor was enrolled with the key.
+	LastEnrolledAt time.Time
+}
+
+// LastUsedAt returns when the key was last used to enroll
+// a sensor, or when it was created if it never was.
+func (s InstallationKeyStats) LastUsedAt() time.Time {
+	if !s.LastEnrolledAt.IsZero() {
+		return s.LastEnrolledAt
+	}
+	return time.Unix(s.Key.CreatedAt, 0)
+}
+
+// InstallationKeyStats returns the enrollment statistics of the
+// installation keys of the Org, computed from its sensors, by IID.
+func (org *Organization) InstallationKeyStats() (map[string]InstallationKeyStats, error) {
+	keys, err := org.InstallationKeys()
+	if err != nil {
+		return nil, err
+	}
+	sensors, err := org.ListSensors()
+	if err != nil {
+		return nil, err
+	}
+	stats := map[string]InstallationKeyStats{}
+	for _, k := range keys {
+		stats[k.ID] = InstallationKeyStats{Key: k}
+	}
+	for _, s := range sensors {
+		st, ok := stats[s.IID]
+		if !ok {
+			// Sensors of deleted keys.
+			continue
+		}
+		st.Enrollments++
+		if t, err := time.Parse(sensorTimestampFormat, s.EnrollTS); err == nil && t.After(st.LastEnrolledAt) {
+			st.LastEnrolledAt = t
+		}
+		stats[s.IID] = st
+	}
+	return stats, nil
+}
+
+// NewInstallationKeyUnusedLintRule returns a LintRule flagging the
+// installation keys of a config not used to enroll a sensor for more
+// than unusedDays, according to the stats of InstallationKeyStats.
+// Keys absent from the stats, not created yet, are not flagged.
+func NewInstallationKeyUnusedLintRule(stats map[string]InstallationKeyStats, unusedDays int) LintRule {
+	return NewLintRule("installation-key-unused", func(conf OrgConfig) []LintFinding {
+		byName := map[string]InstallationKeyStats{}
+		for _, s := range stats {
+			byName[s.Key.Description] = s
+		}
+		threshold := time.Now().Add(-time.Duration(unusedDays) * 24 * time.Hour)
+		names := []string{}
+		for name := range conf.InstallationKeys {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		findings := []LintFinding{}
+		for _, name := range names {
+			s, ok := byName[name]
+			if !ok || !s.LastUsedAt().Before(threshold) {
+				continue
+			}
+			message := fmt.Sprintf("installation key not used for more than %d days, last enrollment on %s", unusedDays, s.LastEnrolledAt.Format("2006-01-02"))
+			if s.LastEnrolledAt.IsZero() {
+				message = fmt.Sprintf("installation key never used in the %d days since its creation", int(time.Since(s.LastUsedAt())/(24*time.Hour)))
+			}
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Warning,
+				Message:  message,
+			}.at("installation_keys", name))
+		}
+		return findings
+	})
+}
@@ -0,0 +1,67 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstallationKeyStats(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now().UTC()
+	ts := func(d time.Duration) string {
+		return now.Add(-d).Format(sensorTimestampFormat)
+	}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/v1/installationkeys/" + vcrTestOID:
+			key := `{"iid": %q, "desc": %q, "key": "k", "json_key": "j", "tags": "", "created": %q}`
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{%q: {
+				"i1": `+key+`,
+				"i2": `+key+`,
+				"i3": `+key+`
+			}}`, vcrTestOID,
+				"i1", "servers", ts(400*24*time.Hour),
+				"i2", "vdi", ts(400*24*time.Hour),
+				"i3", "new", ts(24*time.Hour))), nil
+		case "/v1/sensors/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{"sensors": [
+				{"sid": "s1", "iid": "i1", "enroll": %q},
+				{"sid": "s2", "iid": "i1", "enroll": %q},
+				{"sid": "s3", "iid": "i2", "enroll": %q},
+				{"sid": "s4", "iid": "deleted", "enroll": %q}
+			]}`, ts(300*24*time.Hour), ts(200*24*time.Hour), ts(2*time.Hour), ts(time.Hour))), nil
+		}
+		t.Errorf("unexpected request: %s", r.URL.Path)
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	stats, err := org.InstallationKeyStats()
+	a.NoError(err)
+	a.Len(stats, 3)
+	a.Equal(2, stats["i1"].Enrollments)
+	a.Equal(ts(200*24*time.Hour), stats["i1"].LastEnrolledAt.Format(sensorTimestampFormat))
+	a.Equal(1, stats["i2"].Enrollments)
+	a.Equal(0, stats["i3"].Enrollments)
+	a.True(stats["i3"].LastEnrolledAt.IsZero())
+
+	conf := OrgConfig{InstallationKeys: orgSyncInstallationKeys{
+		"servers": {Description: "servers"},
+		"vdi":     {Description: "vdi"},
+		"new":     {Description: "new"},
+		"planned": {Description: "planned"},
+	}}
+	findings := conf.Lint(NewInstallationKeyUnusedLintRule(stats, 90))
+	a.Len(findings, 1)
+	a.Equal("installation_keys.servers", findings[0].Location)
+	a.Equal("installation-key-unused", findings[0].Rule)
+
+	stats["i3"] = InstallationKeyStats{Key: InstallationKey{Description: "new", CreatedAt: now.Add(-100 * 24 * time.Hour).Unix()}}
+	findings = conf.Lint(NewInstallationKeyUnusedLintRule(stats, 90))
+	a.Len(findings, 2)
+	a.Equal("installation_keys.new", findings[0].Location)
+	a.Contains(findings[0].Message, "never used")
+}
@@ -79,11 +79,11 @@ func (ik *InstallationKey) UnmarshalJSON(data []byte) error {
 }
 
 func (k InstallationKey) EqualsContent(k2 InstallationKey) bool {
-	if k.Description != k2.Description {
-		return false
-	}
 	// TODO: compare tags when we can update them.
-	return true
+	return syncNormalizers.InstallationKey.Equal(
+		Dict{"desc": k.Description},
+		Dict{"desc": k2.Description},
+	)
 }
 
 func (org Organization) InstallationKeys() ([]InstallationKey, error) {
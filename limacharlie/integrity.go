@@ -9,8 +9,8 @@ type IntegrityRule struct {
 }
 
 type IntegrityRuleFilter struct {
-	Tags      []string `json:"tags" yaml:"tags"`
-	Platforms []string `json:"platforms" yaml:"platforms"`
+	Tags      []string   `json:"tags" yaml:"tags"`
+	Platforms []Platform `json:"platforms" yaml:"platforms"`
 }
 
 func (ir IntegrityRule) WithPatterns(patterns []string) IntegrityRule {
@@ -23,7 +23,7 @@ func (ir IntegrityRule) WithTags(tags []string) IntegrityRule {
 	return ir
 }
 
-func (ir IntegrityRule) WithPlatforms(platforms []string) IntegrityRule {
+func (ir IntegrityRule) WithPlatforms(platforms []Platform) IntegrityRule {
 	ir.Filters.Platforms = append(ir.Filters.Platforms, platforms...)
 	return ir
 }
@@ -56,7 +56,7 @@ func (org Organization) IntegrityRuleAdd(ruleName IntegrityRuleName, rule Integr
 	}
 	platforms := rule.Filters.Platforms
 	if platforms == nil {
-		platforms = []string{}
+		platforms = []Platform{}
 	}
 
 	req := Dict{
@@ -0,0 +1,302 @@
+package limacharlie
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type IntegritySnapshotKind = string
+
+var IntegritySnapshotKinds = struct {
+	// Directory snapshots list the files under a directory.
+	Directory IntegritySnapshotKind
+	// Registry snapshots list the values of a registry key.
+	Registry IntegritySnapshotKind
+}{
+	Directory: "directory",
+	Registry:  "registry",
+}
+
+const (
+	defaultIntegritySnapshotTimeout = 2 * time.Minute
+	integritySnapshotArtifactHint   = "json"
+)
+
+// IntegritySnapshotEntry is a file or a registry value of a snapshot.
+type IntegritySnapshotEntry struct {
+	Path string `json:"path"`
+	// Size, Hash and ModifiedAt, in milliseconds, of files.
+	Size       uint64 `json:"size,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	ModifiedAt int64  `json:"modified_at,omitempty"`
+	// Type and Value of registry values.
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+// IntegritySnapshot is a baseline of a directory or registry key
+// of a sensor, to be compared with a later one by DiffIntegritySnapshots.
+type IntegritySnapshot struct {
+	SensorID string                `json:"sid"`
+	Kind     IntegritySnapshotKind `json:"kind"`
+	Root     string                `json:"root"`
+	// TakenAt is a unix timestamp in seconds.
+	TakenAt int64 `json:"taken_at"`
+	// Entries sorted by path.
+	Entries []IntegritySnapshotEntry `json:"entries"`
+}
+
+// IntegritySnapshotOptions describe the snapshot to take.
+type IntegritySnapshotOptions struct {
+	Kind IntegritySnapshotKind
+	// Root is the directory, or the registry key, of the snapshot.
+	Root string
+	// FilePattern of the files of directory snapshots, all by default.
+	FilePattern string
+	// Depth of the directories listed, the sensor's default if 0.
+	Depth int
+
+	// Responses are the events of the Org, like the Messages of a
+	// Firehose, read for the response of the sensor until Timeout,
+	// 2 minutes by default.
+	Responses <-chan FirehoseMessage
+	Timeout   time.Duration
+	// InvestigationID the task is sent with, a new one by default.
+	InvestigationID string
+}
+
+func (o IntegritySnapshotOptions) task() (string, string, error) {
+	if o.Root == "" {
+		return "", "", errors.New("the root of the snapshot is required")
+	}
+	root := strings.ReplaceAll(o.Root, `"`, `\"`)
+	switch o.Kind {
+	case IntegritySnapshotKinds.Directory:
+		pattern := o.FilePattern
+		if pattern == "" {
+			pattern = "*"
+		}
+		task := fmt.Sprintf(`dir_list "%s" "%s"`, root, strings.ReplaceAll(pattern, `"`, `\"`))
+		if o.Depth > 0 {
+			task = fmt.Sprintf("%s -d %d", task, o.Depth)
+		}
+		return task, "DIR_LIST_REP", nil
+	case IntegritySnapshotKinds.Registry:
+		return fmt.Sprintf(`reg_list "%s"`, root), "REG_LIST_REP", nil
+	}
+	return "", "", fmt.Errorf("unknown snapshot kind %q", o.Kind)
+}
+
+// TakeIntegritySnapshot tasks a sensor to list a directory or a
+// registry key and returns the snapshot of its response.
+func (org *Organization) TakeIntegritySnapshot(sid string, opts IntegritySnapshotOptions) (IntegritySnapshot, error) {
+	snapshot := IntegritySnapshot{SensorID: sid, Kind: opts.Kind, Root: opts.Root}
+	task, responseType, err := opts.task()
+	if err != nil {
+		return snapshot, err
+	}
+	if opts.Responses == nil {
+		return snapshot, errors.New("responses are required to receive the snapshot")
+	}
+	if opts.InvestigationID == "" {
+		opts.InvestigationID = uuid.New().String()
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultIntegritySnapshotTimeout
+	}
+	snapshot.TakenAt = time.Now().Unix()
+	if err := org.GetSensor(sid).Task(task, TaskingOptions{InvestigationID: opts.InvestigationID}); err != nil {
+		return snapshot, err
+	}
+
+	timeout := time.After(opts.Timeout)
+	for {
+		select {
+		case <-timeout:
+			return snapshot, fmt.Errorf("no %s from %s after %s", responseType, sid, opts.Timeout)
+		case msg, ok := <-opts.Responses:
+			if !ok {
+				return snapshot, fmt.Errorf("responses closed before the %s from %s", responseType, sid)
+			}
+			content := msg.Content
+			if content == nil {
+				if err := json.Unmarshal([]byte(msg.RawContent), &content); err != nil {
+					continue
+				}
+			}
+			d := Dict(content)
+			eventSID, _ := d.GetString("routing/sid")
+			eventType, _ := d.GetString("routing/event_type")
+			invID, _ := d.GetString("routing/investigation_id")
+			if eventSID != sid || eventType != responseType || !MatchesInvestigation(invID, opts.InvestigationID) {
+				continue
+			}
+			event, _ := d.GetDict("event")
+			snapshot.Entries = integritySnapshotEntries(opts.Kind, opts.Root, event)
+			return snapshot, nil
+		}
+	}
+}
+
+func integritySnapshotEntries(kind IntegritySnapshotKind, root string, event Dict) []IntegritySnapshotEntry {
+	entries := []IntegritySnapshotEntry{}
+	if kind == IntegritySnapshotKinds.Directory {
+		files, _ := event.GetList("DIRECTORY_LIST")
+		for _, f := range files {
+			m, ok := f.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			d := Dict(m)
+			entry := IntegritySnapshotEntry{}
+			entry.Path, _ = d.GetString("FILE_PATH")
+			if entry.Path == "" {
+				continue
+			}
+			if size, ok := d.GetInt("FILE_SIZE"); ok {
+				entry.Size = uint64(size)
+			}
+			entry.Hash, _ = d.GetString("HASH")
+			entry.ModifiedAt, _ = d.GetInt("MODIFICATION_TIME")
+			entries = append(entries, entry)
+		}
+	} else {
+		values, _ := event.GetList("REGISTRY_VALUE")
+		for _, v := range values {
+			m, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			d := Dict(m)
+			name, _ := d.GetString("NAME")
+			entry := IntegritySnapshotEntry{Path: strings.TrimSuffix(root, `\`) + `\` + name}
+			if t, ok := d.FindByPath("TYPE"); ok {
+				entry.Type = fmt.Sprintf("%v", t)
+			}
+			if value, ok := d.FindByPath("VALUE"); ok {
+				entry.Value = fmt.Sprintf("%v", value)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries
+}
+
+// IntegritySnapshotChange is an entry modified between two snapshots.
+type IntegritySnapshotChange struct {
+	Before IntegritySnapshotEntry `json:"before"`
+	After  IntegritySnapshotEntry `json:"after"`
+}
+
+// IntegritySnapshotDiff are the entries added, removed
+// or modified between two snapshots, sorted by path.
+type IntegritySnapshotDiff struct {
+	Added    []IntegritySnapshotEntry  `json:"added"`
+	Removed  []IntegritySnapshotEntry  `json:"removed"`
+	Modified []IntegritySnapshotChange `json:"modified"`
+}
+
+// IsEmpty returns true if the snapshots have the same entries.
+func (d IntegritySnapshotDiff) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Modified) == 0
+}
+
+// DiffIntegritySnapshots compares a baseline with a later snapshot.
+// Paths are compared case insensitively, like on Windows.
+func DiffIntegritySnapshots(before IntegritySnapshot, after IntegritySnapshot) IntegritySnapshotDiff {
+	diff := IntegritySnapshotDiff{
+		Added:    []IntegritySnapshotEntry{},
+		Removed:  []IntegritySnapshotEntry{},
+		Modified: []IntegritySnapshotChange{},
+	}
+	previous := map[string]IntegritySnapshotEntry{}
+	for _, e := range before.Entries {
+		previous[strings.ToLower(e.Path)] = e
+	}
+	seen := map[string]struct{}{}
+	for _, e := range after.Entries {
+		key := strings.ToLower(e.Path)
+		seen[key] = struct{}{}
+		old, ok := previous[key]
+		if !ok {
+			diff.Added = append(diff.Added, e)
+			continue
+		}
+		if old.Size != e.Size || !strings.EqualFold(old.Hash, e.Hash) || old.ModifiedAt != e.ModifiedAt || old.Type != e.Type || old.Value != e.Value {
+			diff.Modified = append(diff.Modified, IntegritySnapshotChange{Before: old, After: e})
+		}
+	}
+	for _, e := range before.Entries {
+		if _, ok := seen[strings.ToLower(e.Path)]; !ok {
+			diff.Removed = append(diff.Removed, e)
+		}
+	}
+	sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].Path < diff.Added[j].Path })
+	sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].Path < diff.Removed[j].Path })
+	sort.Slice(diff.Modified, func(i, j int) bool { return diff.Modified[i].After.Path < diff.Modified[j].After.Path })
+	return diff
+}
+
+// StoreIntegritySnapshot uploads a snapshot as a JSON artifact of the
+// sensor through an artifact source, see ProvisionArtifactSource, and
+// returns the ID of the artifact, for LoadIntegritySnapshot.
+func (org Organization) StoreIntegritySnapshot(source ArtifactSource, snapshot IntegritySnapshot) (string, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", err
+	}
+	id := uuid.New().String()
+	r, err := http.NewRequest(http.MethodPost, source.Endpoint, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	r.SetBasicAuth(source.OID, source.Key)
+	r.Header.Set("lc-source", snapshot.SensorID)
+	r.Header.Set("lc-hint", integritySnapshotArtifactHint)
+	r.Header.Set("lc-payload-id", id)
+	r.Header.Set("lc-path", base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%s-%d.json", snapshot.Kind, snapshot.SensorID, snapshot.TakenAt))))
+	resp, err := org.client.httpClient(defaultIntegritySnapshotTimeout).Do(r)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return "", fmt.Errorf("storing snapshot: %s: %s", resp.Status, string(body))
+	}
+	return id, nil
+}
+
+// LoadIntegritySnapshot downloads a snapshot stored as an artifact.
+func (org Organization) LoadIntegritySnapshot(artifactID string) (IntegritySnapshot, error) {
+	snapshot := IntegritySnapshot{}
+	u, err := org.ArtifactDownloadURL(artifactID)
+	if err != nil {
+		return snapshot, err
+	}
+	resp, err := org.client.httpClient(defaultIntegritySnapshotTimeout).Get(u)
+	if err != nil {
+		return snapshot, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return snapshot, fmt.Errorf("loading snapshot: %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&snapshot); err != nil {
+		return snapshot, fmt.Errorf("invalid snapshot %s: %v", artifactID, err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,142 @@
+package limacharlie
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeIntegritySnapshot(t *testing.T) {
+	a := assert.New(t)
+	responses := make(chan FirehoseMessage, 3)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal("/v1/s1", r.URL.Path)
+		if r.Method == http.MethodGet {
+			return jsonResponse(http.StatusOK, `{"info": {"sid": "s1"}}`), nil
+		}
+		r.ParseForm()
+		a.Equal(`dir_list "c:\windows\system32\drivers" "*.sys" -d 2`, r.Form.Get("tasks"))
+		invID := r.Form.Get("investigation_id")
+		a.NotEmpty(invID)
+		// Responses of other tasks and sensors are ignored.
+		responses <- FirehoseMessage{RawContent: `{"routing": {"sid": "s2", "event_type": "DIR_LIST_REP", "investigation_id": "` + invID + `"}, "event": {}}`}
+		responses <- FirehoseMessage{RawContent: `{"routing": {"sid": "s1", "event_type": "DIR_LIST_REP", "investigation_id": "other"}, "event": {}}`}
+		responses <- FirehoseMessage{RawContent: `{"routing": {"sid": "s1", "event_type": "DIR_LIST_REP", "investigation_id": "` + invID + `"}, "event": {"DIRECTORY_LIST": [
+			{"FILE_PATH": "c:\\windows\\system32\\drivers\\b.sys", "FILE_SIZE": 20, "MODIFICATION_TIME": 1700000000000},
+			{"FILE_PATH": "c:\\windows\\system32\\drivers\\a.sys", "FILE_SIZE": 10, "HASH": "aa"}
+		]}}`}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	snapshot, err := org.TakeIntegritySnapshot("s1", IntegritySnapshotOptions{
+		Kind:        IntegritySnapshotKinds.Directory,
+		Root:        `c:\windows\system32\drivers`,
+		FilePattern: "*.sys",
+		Depth:       2,
+		Responses:   responses,
+		Timeout:     time.Second,
+	})
+	a.NoError(err)
+	a.Equal("s1", snapshot.SensorID)
+	a.NotZero(snapshot.TakenAt)
+	a.Equal([]IntegritySnapshotEntry{
+		{Path: `c:\windows\system32\drivers\a.sys`, Size: 10, Hash: "aa"},
+		{Path: `c:\windows\system32\drivers\b.sys`, Size: 20, ModifiedAt: 1700000000000},
+	}, snapshot.Entries)
+
+	_, err = org.TakeIntegritySnapshot("s1", IntegritySnapshotOptions{Kind: "disk", Root: "/", Responses: responses})
+	a.Error(err)
+}
+
+func TestTakeIntegritySnapshotRegistryTimeout(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			return jsonResponse(http.StatusOK, `{"info": {"sid": "s1"}}`), nil
+		}
+		r.ParseForm()
+		a.Equal(`reg_list "hklm\software\microsoft\windows\currentversion\run"`, r.Form.Get("tasks"))
+		a.Equal("inv-1", r.Form.Get("investigation_id"))
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+	_, err := org.TakeIntegritySnapshot("s1", IntegritySnapshotOptions{
+		Kind:            IntegritySnapshotKinds.Registry,
+		Root:            `hklm\software\microsoft\windows\currentversion\run`,
+		Responses:       make(chan FirehoseMessage),
+		Timeout:         10 * time.Millisecond,
+		InvestigationID: "inv-1",
+	})
+	a.Error(err)
+
+	entries := integritySnapshotEntries(IntegritySnapshotKinds.Registry, `hklm\run\`, Dict{"REGISTRY_VALUE": []interface{}{
+		map[string]interface{}{"NAME": "updater", "TYPE": float64(1), "VALUE": "c:\\updater.exe"},
+	}})
+	a.Equal([]IntegritySnapshotEntry{{Path: `hklm\run\updater`, Type: "1", Value: `c:\updater.exe`}}, entries)
+}
+
+func TestDiffIntegritySnapshots(t *testing.T) {
+	a := assert.New(t)
+	before := IntegritySnapshot{Entries: []IntegritySnapshotEntry{
+		{Path: `C:\a.sys`, Size: 10, Hash: "AA"},
+		{Path: `c:\b.sys`, Size: 20},
+		{Path: `c:\c.sys`, Size: 30},
+	}}
+	after := IntegritySnapshot{Entries: []IntegritySnapshotEntry{
+		{Path: `c:\a.sys`, Size: 10, Hash: "aa"},
+		{Path: `c:\b.sys`, Size: 21},
+		{Path: `c:\d.sys`, Size: 40},
+	}}
+	diff := DiffIntegritySnapshots(before, after)
+	a.Equal([]IntegritySnapshotEntry{{Path: `c:\d.sys`, Size: 40}}, diff.Added)
+	a.Equal([]IntegritySnapshotEntry{{Path: `c:\c.sys`, Size: 30}}, diff.Removed)
+	a.Equal([]IntegritySnapshotChange{{Before: IntegritySnapshotEntry{Path: `c:\b.sys`, Size: 20}, After: IntegritySnapshotEntry{Path: `c:\b.sys`, Size: 21}}}, diff.Modified)
+	a.False(diff.IsEmpty())
+	a.True(DiffIntegritySnapshots(before, before).IsEmpty())
+}
+
+func TestStoreAndLoadIntegritySnapshot(t *testing.T) {
+	a := assert.New(t)
+	stored := ""
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case r.URL.Host == "artifacts.example.com":
+			user, key, ok := r.BasicAuth()
+			a.True(ok)
+			a.Equal(vcrTestOID, user)
+			a.Equal("ikey", key)
+			a.Equal("s1", r.Header.Get("lc-source"))
+			a.Equal("json", r.Header.Get("lc-hint"))
+			path, err := base64.StdEncoding.DecodeString(r.Header.Get("lc-path"))
+			a.NoError(err)
+			a.Equal("directory-s1-100.json", string(path))
+			body, _ := ioutil.ReadAll(r.Body)
+			stored = string(body)
+			return jsonResponse(http.StatusOK, `{}`), nil
+		case r.URL.Host == "storage.example.com":
+			return jsonResponse(http.StatusOK, stored), nil
+		case strings.HasPrefix(r.URL.Path, fmt.Sprintf("/v1/insight/%s/artifacts/originals/", vcrTestOID)):
+			return jsonResponse(http.StatusOK, `{"export": "https://storage.example.com/snapshot"}`), nil
+		}
+		t.Errorf("unexpected request: %s", r.URL.String())
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+	snapshot := IntegritySnapshot{
+		SensorID: "s1",
+		Kind:     IntegritySnapshotKinds.Directory,
+		Root:     "/etc",
+		TakenAt:  100,
+		Entries:  []IntegritySnapshotEntry{{Path: "/etc/passwd", Size: 1}},
+	}
+	id, err := org.StoreIntegritySnapshot(ArtifactSource{OID: vcrTestOID, Endpoint: "https://artifacts.example.com/ingest", Key: "ikey"}, snapshot)
+	a.NoError(err)
+	a.NotEmpty(id)
+	loaded, err := org.LoadIntegritySnapshot(id)
+	a.NoError(err)
+	a.Equal(snapshot, loaded)
+}
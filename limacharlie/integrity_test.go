@@ -16,10 +16,10 @@ func findUnsubscribeCallback(org *Organization, category string, name string) (u
 	cb := func() {
 		org.logger.Info(fmt.Sprintf("cleaning up resource: %s/%s", category, name))
 		org.ResourceUnsubscribe(name, category)
-		time.Sleep(6 * time.Second)
+		waitForOrg(org, 6*time.Second)
 	}
 	org.ResourceSubscribe(name, category)
-	time.Sleep(6 * time.Second)
+	waitForOrg(org, 6*time.Second)
 
 	resources, err := org.Resources()
 	if err != nil {
@@ -63,7 +63,7 @@ func TestIntegrityRuleAddDelete(t *testing.T) {
 	ruleName := "testintegrityrule"
 	rule := IntegrityRule{}.
 		WithPatterns([]string{"c:\\test.txt"}).
-		WithPlatforms([]string{"windows"})
+		WithPlatforms([]Platform{"windows"})
 	err = org.IntegrityRuleAdd(ruleName, rule)
 	a.NoError(err)
 
@@ -0,0 +1,51 @@
+package limacharlie
+
+// DRRuleStore manages the D&R rules of an Org.
+type DRRuleStore interface {
+	DRRules(filters ...DRRuleFilter) (map[string]Dict, error)
+	DRRuleAdd(name string, detection interface{}, response interface{}, opt ...NewDRRuleOptions) error
+	DRRuleDelete(name string, filters ...DRRuleFilter) error
+}
+
+// FPRuleStore manages the false positive rules of an Org.
+type FPRuleStore interface {
+	FPRules() (map[FPRuleName]FPRule, error)
+	FPRuleAdd(name FPRuleName, detection interface{}, opts ...FPRuleOptions) error
+	FPRuleDelete(name FPRuleName) error
+}
+
+// OutputStore manages the outputs of an Org.
+type OutputStore interface {
+	Outputs() (OutputsByName, error)
+	OutputAdd(output OutputConfig) (OutputConfig, error)
+	OutputDel(name string) (GenericJSON, error)
+}
+
+// ResourceManager manages the resources an Org is subscribed to.
+type ResourceManager interface {
+	Resources() (ResourcesByCategory, error)
+	ResourceSubscribe(name ResourceName, category ResourceCategory) error
+	ResourceUnsubscribe(name ResourceName, category ResourceCategory) error
+}
+
+// HiveStore manages the records of the hives of an Org.
+type HiveStore interface {
+	List(args HiveArgs) (HiveConfigData, error)
+	Get(args HiveArgs) (*HiveData, error)
+	Add(args HiveArgs) (*HiveResp, error)
+	Update(args HiveArgs) (interface{}, error)
+	Remove(args HiveArgs) (interface{}, error)
+}
+
+// Syncer fetches and applies the configuration of an Org.
+type Syncer interface {
+	SyncFetch(options SyncOptions) (OrgConfig, error)
+	SyncPush(conf OrgConfig, options SyncOptions) ([]OrgSyncOperation, error)
+}
+
+var _ DRRuleStore = Organization{}
+var _ FPRuleStore = Organization{}
+var _ OutputStore = Organization{}
+var _ ResourceManager = Organization{}
+var _ Syncer = Organization{}
+var _ HiveStore = &HiveClient{}
@@ -0,0 +1,44 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeOutputStore struct {
+	outputs OutputsByName
+}
+
+func (s *fakeOutputStore) Outputs() (OutputsByName, error) {
+	return s.outputs, nil
+}
+
+func (s *fakeOutputStore) OutputAdd(output OutputConfig) (OutputConfig, error) {
+	s.outputs[output.Name] = output
+	return output, nil
+}
+
+func (s *fakeOutputStore) OutputDel(name string) (GenericJSON, error) {
+	delete(s.outputs, name)
+	return GenericJSON{}, nil
+}
+
+func TestSyncOutputsWithStore(t *testing.T) {
+	a := assert.New(t)
+	store := &fakeOutputStore{outputs: OutputsByName{
+		"old": {Name: "old", Module: OutputTypes.Syslog, Type: OutputType.Event},
+	}}
+
+	fetched, err := syncFetchOutputs(store)
+	a.NoError(err)
+	a.Contains(fetched, "old")
+
+	ops, err := syncOutputs(store, orgSyncOutputs{
+		"new": {Name: "new", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+	}, SyncOptions{SyncOutputs: true, IsForce: true})
+	a.NoError(err)
+	a.Len(ops, 2)
+	a.Contains(store.outputs, "new")
+	a.NotContains(store.outputs, "old")
+}
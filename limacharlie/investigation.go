@@ -0,0 +1,143 @@
+package limacharlie
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NewInvestigationID allocates a unique investigation ID, starting
+// with the prefix if set, like "ransomware-<uuid>".
+func NewInvestigationID(prefix string) string {
+	id := uuid.New().String()
+	if prefix == "" {
+		return id
+	}
+	return prefix + "-" + id
+}
+
+// MatchesInvestigation returns true if invID, the investigation ID
+// of an event, is the investigation id or one of its contexts, like
+// "<id>/<context>".
+func MatchesInvestigation(invID string, id string) bool {
+	return invID == id || strings.HasPrefix(invID, id+"/")
+}
+
+// Investigation tracks the sensors tasked with an investigation ID,
+// so that all the events and detections carrying it can be queried
+// later, making multi-step automations traceable end-to-end.
+type Investigation struct {
+	org *Organization
+	ID  string
+
+	mutex   sync.Mutex
+	sensors map[string]struct{}
+}
+
+// NewInvestigation starts an investigation with a new ID,
+// see NewInvestigationID.
+func (org *Organization) NewInvestigation(prefix string) *Investigation {
+	return org.OpenInvestigation(NewInvestigationID(prefix))
+}
+
+// OpenInvestigation resumes an investigation, like one started by
+// another process, with the sensors already tasked.
+func (org *Organization) OpenInvestigation(id string, sensorIDs ...string) *Investigation {
+	inv := &Investigation{org: org, ID: id, sensors: map[string]struct{}{}}
+	for _, sid := range sensorIDs {
+		inv.sensors[sid] = struct{}{}
+	}
+	return inv
+}
+
+func (inv *Investigation) addSensor(sid string) {
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+	inv.sensors[sid] = struct{}{}
+}
+
+// Sensors returns the sorted SIDs of the sensors of the investigation.
+func (inv *Investigation) Sensors() []string {
+	inv.mutex.Lock()
+	defer inv.mutex.Unlock()
+	sids := []string{}
+	for sid := range inv.sensors {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+	return sids
+}
+
+// Task sends a task to a sensor with the investigation ID, along with
+// the context if set, like "step-1", to tell the responses apart.
+func (inv *Investigation) Task(sensorID string, task string, context string) error {
+	s := &Sensor{SID: sensorID, Organization: inv.org}
+	if err := s.Task(task, TaskingOptions{
+		InvestigationID:      inv.ID,
+		InvestigationContext: context,
+	}); err != nil {
+		return err
+	}
+	inv.addSensor(sensorID)
+	return nil
+}
+
+// TaskAll sends a task to the sensors matching the selector
+// with the investigation ID, see Organization.TaskAll.
+func (inv *Investigation) TaskAll(selector string, task string, opts TaskAllOptions) (TaskAllResults, error) {
+	opts.InvestigationID = inv.ID
+	results, err := inv.org.TaskAll(selector, task, opts)
+	for _, sid := range results.Succeeded() {
+		inv.addSensor(sid)
+	}
+	return results, err
+}
+
+// Timeline returns the events, the responses to the tasks, and
+// the detections of the sensors of the investigation carrying
+// its ID, between start and end, see Organization.Timeline.
+func (inv *Investigation) Timeline(ctx context.Context, start time.Time, end time.Time) ([]TimelineEntry, error) {
+	sids := inv.Sensors()
+	if len(sids) == 0 {
+		return nil, errors.New("no sensor tasked in the investigation")
+	}
+	entries := []TimelineEntry{}
+	for _, sid := range sids {
+		sensorEntries, err := inv.org.Timeline(ctx, TimelineQuery{
+			SensorID:        sid,
+			InvestigationID: inv.ID,
+			Start:           start,
+			End:             end,
+			Kinds:           []TimelineEntryKind{TimelineEntryKinds.Event, TimelineEntryKinds.Detection},
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, sensorEntries...)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
+
+// Events returns the events carrying the investigation ID, like the
+// responses to its tasks, between start and end, in chronological order.
+func (inv *Investigation) Events(ctx context.Context, start time.Time, end time.Time) ([]Event, error) {
+	entries, err := inv.Timeline(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{}
+	for _, e := range entries {
+		if e.Event != nil {
+			events = append(events, *e.Event)
+		}
+	}
+	return events, nil
+}
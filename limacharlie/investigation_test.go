@@ -0,0 +1,77 @@
+package limacharlie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInvestigationID(t *testing.T) {
+	a := assert.New(t)
+	id := NewInvestigationID("ir")
+	a.True(strings.HasPrefix(id, "ir-"))
+	a.NotEqual(id, NewInvestigationID("ir"))
+	a.NotContains(NewInvestigationID(""), "-ir")
+
+	a.True(MatchesInvestigation("inv", "inv"))
+	a.True(MatchesInvestigation("inv/step-1", "inv"))
+	a.False(MatchesInvestigation("inv2", "inv"))
+	a.False(MatchesInvestigation("", "inv"))
+}
+
+func TestInvestigation(t *testing.T) {
+	a := assert.New(t)
+	tasked := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/s1":
+			a.NoError(r.ParseForm())
+			tasked = append(tasked, r.PostForm.Get("investigation_id"))
+			return jsonResponse(http.StatusOK, `{}`), nil
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/s2":
+			return jsonResponse(http.StatusInternalServerError, `{"error": "offline"}`), nil
+		case r.URL.Path == fmt.Sprintf("/v1/insight/%s/s1", vcrTestOID):
+			return jsonResponse(http.StatusOK, `{"events": [
+				{"routing": {"sid": "s1", "event_type": "OS_VERSION_REP", "event_time": 150000, "investigation_id": "inv/step-2"}, "event": {}},
+				{"routing": {"sid": "s1", "event_type": "DIR_LIST_REP", "event_time": 120000, "investigation_id": "inv/step-1"}, "event": {}},
+				{"routing": {"sid": "s1", "event_type": "OS_VERSION_REP", "event_time": 110000, "investigation_id": "other"}, "event": {}}
+			]}`), nil
+		case r.URL.Path == fmt.Sprintf("/v1/insight/%s/detections", vcrTestOID):
+			return jsonResponse(http.StatusOK, `{"detects": [
+				{"cat": "evil", "ts": 130000, "routing": {"sid": "s1", "investigation_id": "inv"}}
+			]}`), nil
+		}
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	inv := org.OpenInvestigation("inv")
+	_, err := inv.Timeline(context.Background(), time.Unix(100, 0), time.Unix(200, 0))
+	a.Error(err)
+
+	a.NoError(inv.Task("s1", "dir_list / *", "step-1"))
+	a.NoError(inv.Task("s1", "os_version", "step-2"))
+	a.Error(inv.Task("s2", "os_version", ""))
+	a.Equal([]string{"inv/step-1", "inv/step-2"}, tasked)
+	a.Equal([]string{"s1"}, inv.Sensors())
+
+	entries, err := inv.Timeline(context.Background(), time.Unix(100, 0), time.Unix(200, 0))
+	a.NoError(err)
+	kinds := []string{}
+	for _, e := range entries {
+		kinds = append(kinds, fmt.Sprintf("%s@%d", e.Kind, e.Time.Unix()))
+	}
+	a.Equal([]string{"event@120", "detection@130", "event@150"}, kinds)
+
+	events, err := inv.Events(context.Background(), time.Unix(100, 0), time.Unix(200, 0))
+	a.NoError(err)
+	a.Len(events, 2)
+	a.Equal("DIR_LIST_REP", events[0].Routing.EventType)
+
+	a.Equal([]string{"s1", "s3"}, org.OpenInvestigation("inv", "s3", "s1").Sensors())
+}
@@ -0,0 +1,141 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type JobID = string
+
+// Job is a long running operation performed by a service,
+// like a responder sweep.
+type Job struct {
+	ID        JobID    `json:"job_id"`
+	Cause     string   `json:"cause"`
+	Owner     string   `json:"owner"`
+	Sensors   []string `json:"sensors,omitempty"`
+	StartTime int64    `json:"start"`
+	EndTime   int64    `json:"end,omitempty"`
+	// Entries logged by the job, only set when
+	// the job is fetched with its data.
+	Data []JobEntry `json:"data,omitempty"`
+}
+
+type JobEntry struct {
+	Timestamp   int64  `json:"ts"`
+	Message     string `json:"msg"`
+	Attachments []Dict `json:"attachments,omitempty"`
+}
+
+func (j Job) IsCompleted() bool {
+	return j.EndTime != 0
+}
+
+// SweepReport is the outcome of a responder sweep.
+type SweepReport struct {
+	JobID    JobID
+	SID      string
+	Messages []string
+	// Findings are the attachments of all the job entries,
+	// like the suspicious processes or autoruns found.
+	Findings []Dict
+}
+
+// Job returns a job along with its data.
+func (org Organization) Job(jobID JobID) (*Job, error) {
+	resp := Job{}
+	request := makeDefaultRequest(&resp).withQueryData(Dict{
+		"with_data": "true",
+	})
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("job/%s/%s", org.client.options.OID, jobID), request); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Jobs lists the jobs started in a time range, without their data.
+func (org Organization) Jobs(start time.Time, end time.Time) ([]Job, error) {
+	resp := struct {
+		Jobs map[JobID]Job `json:"jobs"`
+	}{}
+	request := makeDefaultRequest(&resp).withQueryData(Dict{
+		"start": start.Unix(),
+		"end":   end.Unix(),
+	})
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("job/%s", org.client.options.OID), request); err != nil {
+		return nil, err
+	}
+	jobs := []Job{}
+	for jobID, j := range resp.Jobs {
+		if j.ID == "" {
+			j.ID = jobID
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, nil
+}
+
+// WaitForJob polls a job until it completes or the timeout elapses.
+func (org Organization) WaitForJob(jobID JobID, pollInterval time.Duration, timeout time.Duration) (*Job, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := org.Job(jobID)
+		if err != nil {
+			return nil, err
+		}
+		if job.IsCompleted() {
+			return job, nil
+		}
+		if time.Now().Add(pollInterval).After(deadline) {
+			return job, fmt.Errorf("job %s not completed after %v", jobID, timeout)
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// ResponderSweep starts a sweep of a sensor by the responder
+// service and returns the id of the job tracking it.
+func (org Organization) ResponderSweep(sid string) (JobID, error) {
+	resp := struct {
+		JobID JobID `json:"job_id"`
+	}{}
+	if err := org.client.serviceRequest(&resp, "responder", Dict{
+		"action": "sweep",
+		"sid":    sid,
+	}, false); err != nil {
+		return "", err
+	}
+	if resp.JobID == "" {
+		return "", fmt.Errorf("responder did not return a job id")
+	}
+	return resp.JobID, nil
+}
+
+// SweepReport waits for a responder sweep to complete
+// and returns its report.
+func (org Organization) SweepReport(jobID JobID, timeout time.Duration) (*SweepReport, error) {
+	job, err := org.WaitForJob(jobID, 10*time.Second, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return job.toSweepReport(), nil
+}
+
+func (j Job) toSweepReport() *SweepReport {
+	report := &SweepReport{
+		JobID:    j.ID,
+		Messages: []string{},
+		Findings: []Dict{},
+	}
+	if len(j.Sensors) != 0 {
+		report.SID = j.Sensors[0]
+	}
+	for _, e := range j.Data {
+		if e.Message != "" {
+			report.Messages = append(report.Messages, e.Message)
+		}
+		report.Findings = append(report.Findings, e.Attachments...)
+	}
+	return report
+}
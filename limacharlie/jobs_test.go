@@ -0,0 +1,34 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobSweepReport(t *testing.T) {
+	a := assert.New(t)
+
+	job := Job{}
+	a.NoError(json.Unmarshal([]byte(`{
+  "job_id": "j1",
+  "cause": "sweep",
+  "owner": "responder",
+  "sensors": ["s1"],
+  "start": 1700000000,
+  "end": 1700000100,
+  "data": [
+    {"ts": 1700000010, "msg": "listing processes"},
+    {"ts": 1700000050, "msg": "suspicious process", "attachments": [{"pid": 42, "file_path": "c:\\temp\\evil.exe"}]}
+  ]
+}`), &job))
+	a.True(job.IsCompleted())
+
+	report := job.toSweepReport()
+	a.Equal("j1", report.JobID)
+	a.Equal("s1", report.SID)
+	a.Equal([]string{"listing processes", "suspicious process"}, report.Messages)
+	a.Len(report.Findings, 1)
+	a.Equal(`c:\temp\evil.exe`, report.Findings[0]["file_path"])
+}
@@ -0,0 +1,389 @@
+// Package lcmock implements an in-memory Organization backend
+// to unit test code depending on the limacharlie interfaces
+// without a live org.
+package lcmock
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	lc "github.com/refractionPOINT/go-limacharlie/limacharlie"
+)
+
+const defaultNamespace = "general"
+
+// Organization holds the state of an in-memory org.
+type Organization struct {
+	mutex     sync.Mutex
+	drRules   map[string]map[string]lc.Dict
+	fpRules   map[lc.FPRuleName]lc.FPRule
+	outputs   lc.OutputsByName
+	resources lc.ResourcesByCategory
+	hives     map[lc.HiveName]map[string]map[lc.HiveKey]lc.HiveData
+
+	// OID reported in the elements returned.
+	OID string
+}
+
+var _ lc.DRRuleStore = &Organization{}
+var _ lc.FPRuleStore = &Organization{}
+var _ lc.OutputStore = &Organization{}
+var _ lc.ResourceManager = &Organization{}
+var _ lc.Syncer = &Organization{}
+var _ lc.HiveStore = &HiveStore{}
+
+func NewOrganization(oid string) *Organization {
+	return &Organization{
+		drRules:   map[string]map[string]lc.Dict{},
+		fpRules:   map[lc.FPRuleName]lc.FPRule{},
+		outputs:   lc.OutputsByName{},
+		resources: lc.ResourcesByCategory{},
+		hives:     map[lc.HiveName]map[string]map[lc.HiveKey]lc.HiveData{},
+		OID:       oid,
+	}
+}
+
+func applyFilters(filters []lc.DRRuleFilter, m map[string]string) string {
+	for _, f := range filters {
+		f(m)
+	}
+	if ns := m["namespace"]; ns != "" {
+		return ns
+	}
+	return defaultNamespace
+}
+
+// toDict converts a value to a Dict the same way it
+// would be serialized when sent to the API.
+func toDict(v interface{}) (lc.Dict, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m, err := lc.UnmarshalCleanJSON(string(b))
+	if err != nil {
+		return nil, err
+	}
+	return lc.Dict(m), nil
+}
+
+func toList(v interface{}) (lc.List, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	l := lc.List{}
+	if err := json.Unmarshal(b, &l); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (o *Organization) DRRules(filters ...lc.DRRuleFilter) (map[string]lc.Dict, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ns := applyFilters(filters, map[string]string{})
+	rules := map[string]lc.Dict{}
+	for name, rule := range o.drRules[ns] {
+		rules[name] = rule
+	}
+	return rules, nil
+}
+
+func (o *Organization) DRRuleAdd(name string, detection interface{}, response interface{}, opt ...lc.NewDRRuleOptions) error {
+	reqOpt := lc.NewDRRuleOptions{
+		IsEnabled: true,
+	}
+	for _, op := range opt {
+		reqOpt = op
+	}
+	ns := reqOpt.Namespace
+	if ns == "" {
+		ns = defaultNamespace
+	}
+	detect, err := toDict(detection)
+	if err != nil {
+		return err
+	}
+	respond, err := toList(response)
+	if err != nil {
+		return err
+	}
+	rule := lc.Dict{
+		"name":       name,
+		"namespace":  ns,
+		"detect":     detect,
+		"respond":    respond,
+		"is_enabled": reqOpt.IsEnabled,
+		"oid":        o.OID,
+	}
+	if reqOpt.Filters != nil {
+		if rule["filters"], err = toDict(reqOpt.Filters); err != nil {
+			return err
+		}
+	}
+	if reqOpt.Priority != 0 {
+		rule["priority"] = reqOpt.Priority
+	}
+	if reqOpt.Suppression != nil {
+		if err := reqOpt.Suppression.Validate(); err != nil {
+			return err
+		}
+		if rule["suppression"], err = toDict(reqOpt.Suppression); err != nil {
+			return err
+		}
+	}
+	if reqOpt.Description != "" {
+		rule["description"] = reqOpt.Description
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if !reqOpt.IsReplace {
+		for _, rules := range o.drRules {
+			if _, ok := rules[name]; ok {
+				return lc.NewRESTError(fmt.Sprintf("rule %s already exists", name))
+			}
+		}
+	}
+	// A rule name is unique across namespaces.
+	for _, rules := range o.drRules {
+		delete(rules, name)
+	}
+	if _, ok := o.drRules[ns]; !ok {
+		o.drRules[ns] = map[string]lc.Dict{}
+	}
+	o.drRules[ns][name] = rule
+	return nil
+}
+
+func (o *Organization) DRRuleDelete(name string, filters ...lc.DRRuleFilter) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	ns := applyFilters(filters, map[string]string{"name": name})
+	if _, ok := o.drRules[ns][name]; !ok {
+		return lc.ErrorResourceNotFound
+	}
+	delete(o.drRules[ns], name)
+	return nil
+}
+
+func (o *Organization) FPRules() (map[lc.FPRuleName]lc.FPRule, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	rules := map[lc.FPRuleName]lc.FPRule{}
+	for name, rule := range o.fpRules {
+		rules[name] = rule
+	}
+	return rules, nil
+}
+
+func (o *Organization) FPRuleAdd(name lc.FPRuleName, detection interface{}, opts ...lc.FPRuleOptions) error {
+	reqOpt := lc.FPRuleOptions{}
+	for _, op := range opts {
+		reqOpt = op
+	}
+	detect, err := toDict(detection)
+	if err != nil {
+		return err
+	}
+
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.fpRules[name]; ok && !reqOpt.IsReplace {
+		return lc.NewRESTError(fmt.Sprintf("fp rule %s already exists", name))
+	}
+	o.fpRules[name] = lc.FPRule{
+		Name:      name,
+		OID:       o.OID,
+		Detection: detect,
+	}
+	return nil
+}
+
+func (o *Organization) FPRuleDelete(name lc.FPRuleName) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.fpRules[name]; !ok {
+		return lc.ErrorResourceNotFound
+	}
+	delete(o.fpRules, name)
+	return nil
+}
+
+func (o *Organization) Outputs() (lc.OutputsByName, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	outputs := lc.OutputsByName{}
+	for name, output := range o.outputs {
+		outputs[name] = output
+	}
+	return outputs, nil
+}
+
+func (o *Organization) OutputAdd(output lc.OutputConfig) (lc.OutputConfig, error) {
+	if output.Name == "" {
+		return lc.OutputConfig{}, lc.NewRESTError("output name is required")
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.outputs[output.Name] = output
+	return output, nil
+}
+
+func (o *Organization) OutputDel(name string) (lc.GenericJSON, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if _, ok := o.outputs[name]; !ok {
+		return nil, lc.ErrorResourceNotFound
+	}
+	delete(o.outputs, name)
+	return lc.GenericJSON{"success": true}, nil
+}
+
+func (o *Organization) Resources() (lc.ResourcesByCategory, error) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	resources := lc.ResourcesByCategory{}
+	for cat, names := range o.resources {
+		for name := range names {
+			resources.AddToCategory(cat, name)
+		}
+	}
+	return resources, nil
+}
+
+func (o *Organization) ResourceSubscribe(name lc.ResourceName, category lc.ResourceCategory) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.resources.AddToCategory(category, name)
+	return nil
+}
+
+func (o *Organization) ResourceUnsubscribe(name lc.ResourceName, category lc.ResourceCategory) error {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	o.resources.RemoveFromCategory(category, name)
+	return nil
+}
+
+// SyncFetch fetches the FP rules and the outputs of the org, the
+// mock failing with ErrorSyncCategoryNotSupported for the others.
+func (o *Organization) SyncFetch(options lc.SyncOptions) (lc.OrgConfig, error) {
+	return lc.SyncFetchStores(lc.SyncStores{FPRules: o, Outputs: o}, options)
+}
+
+// SyncPush applies the FP rules and the outputs of the config, the
+// mock failing with ErrorSyncCategoryNotSupported for the others.
+func (o *Organization) SyncPush(conf lc.OrgConfig, options lc.SyncOptions) ([]lc.OrgSyncOperation, error) {
+	return lc.SyncPushStores(lc.SyncStores{FPRules: o, Outputs: o}, conf, options)
+}
+
+// Hive returns a HiveStore backed by the org.
+func (o *Organization) Hive() *HiveStore {
+	return &HiveStore{org: o}
+}
+
+// HiveStore is the in-memory equivalent of a limacharlie.HiveClient.
+type HiveStore struct {
+	org *Organization
+}
+
+func (h *HiveStore) partition(args lc.HiveArgs, create bool) map[lc.HiveKey]lc.HiveData {
+	partitions, ok := h.org.hives[args.HiveName]
+	if !ok {
+		if !create {
+			return nil
+		}
+		partitions = map[string]map[lc.HiveKey]lc.HiveData{}
+		h.org.hives[args.HiveName] = partitions
+	}
+	records, ok := partitions[args.PartitionKey]
+	if !ok && create {
+		records = map[lc.HiveKey]lc.HiveData{}
+		partitions[args.PartitionKey] = records
+	}
+	return records
+}
+
+func (h *HiveStore) List(args lc.HiveArgs) (lc.HiveConfigData, error) {
+	h.org.mutex.Lock()
+	defer h.org.mutex.Unlock()
+	data := lc.HiveConfigData{}
+	for key, record := range h.partition(args, false) {
+		data[key] = record
+	}
+	return data, nil
+}
+
+func (h *HiveStore) Get(args lc.HiveArgs) (*lc.HiveData, error) {
+	h.org.mutex.Lock()
+	defer h.org.mutex.Unlock()
+	record, ok := h.partition(args, false)[args.Key]
+	if !ok {
+		return nil, lc.ErrorResourceNotFound
+	}
+	return &record, nil
+}
+
+func (h *HiveStore) Add(args lc.HiveArgs) (*lc.HiveResp, error) {
+	if args.Key == "" {
+		return nil, fmt.Errorf("key required")
+	}
+	h.org.mutex.Lock()
+	defer h.org.mutex.Unlock()
+	records := h.partition(args, true)
+	record := records[args.Key]
+	if args.ETag != nil && *args.ETag != record.SysMtd.Etag {
+		return nil, lc.NewRESTError("etag mismatch")
+	}
+	if len(args.Data) != 0 {
+		record.Data = map[string]interface{}(args.Data)
+	}
+	h.setUsrMtd(&record, args)
+	record.SysMtd.LastMod++
+	record.SysMtd.Etag = fmt.Sprintf("%d", record.SysMtd.LastMod)
+	records[args.Key] = record
+	return &lc.HiveResp{
+		Name: args.Key,
+		Hive: lc.HiveInfo{
+			Name:      args.HiveName,
+			Partition: args.PartitionKey,
+		},
+	}, nil
+}
+
+func (h *HiveStore) Update(args lc.HiveArgs) (interface{}, error) {
+	h.org.mutex.Lock()
+	_, ok := h.partition(args, false)[args.Key]
+	h.org.mutex.Unlock()
+	if !ok {
+		return nil, lc.ErrorResourceNotFound
+	}
+	args.ETag = nil
+	return h.Add(args)
+}
+
+func (h *HiveStore) Remove(args lc.HiveArgs) (interface{}, error) {
+	h.org.mutex.Lock()
+	defer h.org.mutex.Unlock()
+	records := h.partition(args, false)
+	if _, ok := records[args.Key]; !ok {
+		return nil, lc.ErrorResourceNotFound
+	}
+	delete(records, args.Key)
+	return lc.Dict{"success": true}, nil
+}
+
+func (h *HiveStore) setUsrMtd(record *lc.HiveData, args lc.HiveArgs) {
+	if args.Expiry != nil {
+		record.UsrMtd.Expiry = *args.Expiry
+	}
+	if args.Enabled != nil {
+		record.UsrMtd.Enabled = *args.Enabled
+	}
+	if args.Tags != nil {
+		record.UsrMtd.Tags = args.Tags
+	}
+}
@@ -0,0 +1,186 @@
+package lcmock
+
+import (
+	"errors"
+	"testing"
+
+	lc "github.com/refractionPOINT/go-limacharlie/limacharlie"
+	"github.com/stretchr/testify/assert"
+)
+
+// Functions written against the interfaces can be
+// tested with the mock instead of a live org.
+func addRule(store lc.DRRuleStore, name string) error {
+	return store.DRRuleAdd(name, lc.Dict{
+		"op":    "is",
+		"event": "NEW_PROCESS",
+		"path":  "event/FILE_PATH",
+		"value": "evil.exe",
+	}, lc.List{lc.Dict{"action": "report", "name": name}}, lc.NewDRRuleOptions{
+		Namespace: "managed",
+		IsEnabled: true,
+	})
+}
+
+func TestDRRules(t *testing.T) {
+	a := assert.New(t)
+	org := NewOrganization("oid")
+
+	a.NoError(addRule(org, "r1"))
+	a.Error(addRule(org, "r1"))
+
+	rules, err := org.DRRules()
+	a.NoError(err)
+	a.Empty(rules)
+
+	rules, err = org.DRRules(lc.WithNamespace("managed"))
+	a.NoError(err)
+	a.Len(rules, 1)
+	rule := lc.CoreDRRule{}
+	a.NoError(rules["r1"].UnMarshalToStruct(&rule))
+	a.Equal("evil.exe", rule.Detect["value"])
+	a.Equal("managed", rule.Namespace)
+
+	a.Error(org.DRRuleDelete("r1"))
+	a.NoError(org.DRRuleDelete("r1", lc.WithNamespace("managed")))
+
+	a.NoError(org.DRRuleAdd("r2", lc.Dict{"op": "exists", "path": "event"}, lc.List{}, lc.NewDRRuleOptions{
+		IsEnabled:   true,
+		Filters:     &lc.DRRuleTargets{Tags: []string{"vip"}},
+		Priority:    5,
+		Suppression: &lc.DRRuleSuppression{MaxCount: 1, Period: "1h"},
+		Description: "all events",
+	}))
+	rules, err = org.DRRules()
+	a.NoError(err)
+	rule = lc.CoreDRRule{}
+	a.NoError(rules["r2"].UnMarshalToStruct(&rule))
+	a.Equal(&lc.DRRuleTargets{Tags: []string{"vip"}}, rule.Filters)
+	a.Equal(5, rule.Priority)
+	a.Equal(&lc.DRRuleSuppression{MaxCount: 1, Period: "1h"}, rule.Suppression)
+	a.Equal("all events", rule.Description)
+
+	a.Error(org.DRRuleAdd("r3", lc.Dict{}, lc.List{}, lc.NewDRRuleOptions{
+		Suppression: &lc.DRRuleSuppression{Period: "1h"},
+	}))
+}
+
+func TestOutputsResourcesFPs(t *testing.T) {
+	a := assert.New(t)
+	org := NewOrganization("oid")
+
+	_, err := org.OutputAdd(lc.OutputConfig{Name: "o1", Module: lc.OutputTypes.Syslog, Type: lc.OutputType.Detect})
+	a.NoError(err)
+	outputs, err := org.Outputs()
+	a.NoError(err)
+	a.Contains(outputs, "o1")
+	_, err = org.OutputDel("o1")
+	a.NoError(err)
+	_, err = org.OutputDel("o1")
+	a.Error(err)
+
+	a.NoError(org.ResourceSubscribe("vt", lc.ResourceCategories.API))
+	resources, err := org.Resources()
+	a.NoError(err)
+	a.Contains(resources[lc.ResourceCategories.API], "vt")
+
+	a.NoError(org.FPRuleAdd("fp1", lc.Dict{"op": "is", "path": "cat", "value": "x"}))
+	a.Error(org.FPRuleAdd("fp1", lc.Dict{}))
+	a.NoError(org.FPRuleAdd("fp1", lc.Dict{}, lc.FPRuleOptions{IsReplace: true}))
+	fps, err := org.FPRules()
+	a.NoError(err)
+	a.Len(fps, 1)
+}
+
+func TestHive(t *testing.T) {
+	a := assert.New(t)
+	hive := NewOrganization("oid").Hive()
+
+	enabled := true
+	args := lc.HiveArgs{HiveName: "lookup", PartitionKey: "oid", Key: "k1", Data: lc.Dict{"lookup_data": lc.Dict{"a": lc.Dict{}}}, Enabled: &enabled}
+	_, err := hive.Add(args)
+	a.NoError(err)
+
+	record, err := hive.Get(args)
+	a.NoError(err)
+	a.True(record.UsrMtd.Enabled)
+	etag := record.SysMtd.Etag
+
+	stale := "stale"
+	args.ETag = &stale
+	_, err = hive.Add(args)
+	a.Error(err)
+	args.ETag = &etag
+	_, err = hive.Add(args)
+	a.NoError(err)
+
+	records, err := hive.List(lc.HiveArgs{HiveName: "lookup", PartitionKey: "oid"})
+	a.NoError(err)
+	a.Len(records, 1)
+
+	_, err = hive.Remove(args)
+	a.NoError(err)
+	_, err = hive.Get(args)
+	a.Error(err)
+}
+
+func TestSync(t *testing.T) {
+	a := assert.New(t)
+	org := NewOrganization("oid")
+	_, err := org.OutputAdd(lc.OutputConfig{Name: "old", Module: lc.OutputTypes.Syslog, Type: lc.OutputType.Event})
+	a.NoError(err)
+
+	conf, err := lc.LoadOrgConfig([]byte(`version: 3
+fps:
+  fp1:
+    data:
+      op: is
+      path: cat
+      value: noisy
+outputs:
+  o1:
+    module: syslog
+    type: detect
+    dest_host: syslog.example.com
+`))
+	a.NoError(err)
+	options := lc.SyncOptions{SyncFPRules: true, SyncOutputs: true, IsForce: true}
+
+	var syncer lc.Syncer = org
+	ops, err := syncer.SyncPush(conf, options)
+	a.NoError(err)
+	seen := map[string]bool{}
+	for _, op := range ops {
+		seen[op.String()] = true
+	}
+	a.Equal(map[string]bool{"+ fp-rule fp1": true, "+ output o1": true, "- output old": true}, seen)
+
+	fetched, err := syncer.SyncFetch(options)
+	a.NoError(err)
+	a.Contains(fetched.FPRules, "fp1")
+	a.Equal([]string{"o1"}, outputNames(fetched))
+
+	// Pushing again changes nothing.
+	ops, err = syncer.SyncPush(conf, options)
+	a.NoError(err)
+	for _, op := range ops {
+		a.False(op.IsAdded || op.IsRemoved, op.String())
+	}
+
+	// Categories the mock does not sync are not silently ignored.
+	options.SyncDRRules = true
+	options.SyncHives = map[string]bool{"lookup": true}
+	_, err = syncer.SyncPush(conf, options)
+	a.True(errors.Is(err, lc.ErrorSyncCategoryNotSupported))
+	a.EqualError(err, "sync category not supported: dr, hive:lookup")
+	_, err = syncer.SyncFetch(options)
+	a.True(errors.Is(err, lc.ErrorSyncCategoryNotSupported))
+}
+
+func outputNames(conf lc.OrgConfig) []string {
+	names := []string{}
+	for name := range conf.Outputs {
+		names = append(names, name)
+	}
+	return names
+}
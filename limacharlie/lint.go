@@ -0,0 +1,323 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type LintSeverity = string
+
+var LintSeverities = struct {
+	Info    LintSeverity
+	Warning LintSeverity
+	Error   LintSeverity
+}{
+	Info:    "info",
+	Warning: "warning",
+	Error:   "error",
+}
+
+// LintFinding is a single issue found in an OrgConfig.
+type LintFinding struct {
+	Rule     string       `json:"rule"`
+	Severity LintSeverity `json:"severity"`
+	// Location of the element in the config, like "rules.my-rule".
+	Location string `json:"location"`
+	// Path of the element in the config, like ["rules", "my-rule"],
+	// whose segments, unlike those of the Location, may contain dots.
+	Path []string `json:"path,omitempty"`
+	// Line of the element in the YAML config, only
+	// set when linting with LintYAML().
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// at sets the Path of the finding and its Location.
+func (f LintFinding) at(path ...string) LintFinding {
+	f.Path = path
+	f.Location = strings.Join(path, ".")
+	return f
+}
+
+func (f LintFinding) String() string {
+	loc := f.Location
+	if f.Line != 0 {
+		loc = fmt.Sprintf("%s (line %d)", loc, f.Line)
+	}
+	return fmt.Sprintf("[%s] %s: %s: %s", f.Severity, f.Rule, loc, f.Message)
+}
+
+// LintRule is a check performed on an OrgConfig.
+type LintRule interface {
+	Name() string
+	Check(conf OrgConfig) []LintFinding
+}
+
+type lintRuleFunc struct {
+	name  string
+	check func(conf OrgConfig) []LintFinding
+}
+
+func (r lintRuleFunc) Name() string {
+	return r.name
+}
+
+func (r lintRuleFunc) Check(conf OrgConfig) []LintFinding {
+	findings := r.check(conf)
+	for i := range findings {
+		if findings[i].Rule == "" {
+			findings[i].Rule = r.name
+		}
+	}
+	return findings
+}
+
+// NewLintRule creates a LintRule from a function. The Rule of the
+// findings returned is set to the name provided if left empty.
+func NewLintRule(name string, check func(conf OrgConfig) []LintFinding) LintRule {
+	return lintRuleFunc{name: name, check: check}
+}
+
+// DefaultLintRules are the built-in checks used when
+// no ruleset is provided to Lint().
+var DefaultLintRules = []LintRule{
+	NewLintRule("dr-rule-no-respond", lintDRRuleNoRespond),
+	NewLintRule("dr-rule-invalid-respond", lintDRRuleInvalidRespond),
+	NewLintRule("output-no-type", lintOutputNoType),
+	NewLintRule("output-invalid-module", lintOutputInvalidModule),
+	NewLintRule("artifact-invalid-pattern", lintArtifactInvalidPattern),
+	NewLintRule("exfil-watch-invalid", lintExfilWatchInvalid),
+	NewLintRule("selector-invalid", lintSelectorInvalid),
+	NewLintRule("platform-unknown", lintPlatformUnknown),
+	NewLintRule("fp-rule-too-broad", lintFPRuleTooBroad),
+	NewLintRule("yara-orphan-source", lintYaraOrphanSource),
+	NewLintRule("unused-lookup", lintUnusedLookup),
+}
+
+// Lint runs the ruleset against the config and returns the findings
+// sorted by location. The DefaultLintRules are used if the ruleset is empty.
+func (o OrgConfig) Lint(ruleset ...LintRule) []LintFinding {
+	if len(ruleset) == 0 {
+		ruleset = DefaultLintRules
+	}
+	findings := []LintFinding{}
+	for _, r := range ruleset {
+		findings = append(findings, r.Check(o)...)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Location != findings[j].Location {
+			return findings[i].Location < findings[j].Location
+		}
+		return findings[i].Rule < findings[j].Rule
+	})
+	return findings
+}
+
+// LintYAML parses a YAML config and lints it, setting the
+// line of each finding within the YAML document.
+func LintYAML(data []byte, ruleset ...LintRule) ([]LintFinding, error) {
+	conf := OrgConfig{}
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		return nil, err
+	}
+	root := yaml.Node{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	findings := conf.Lint(ruleset...)
+	for i, f := range findings {
+		path := f.Path
+		if len(path) == 0 {
+			path = strings.Split(f.Location, ".")
+		}
+		findings[i].Line = yamlPathLine(&root, path)
+	}
+	return findings, nil
+}
+
+func yamlPathLine(root *yaml.Node, path []string) int {
+	n := root
+	if n.Kind == yaml.DocumentNode && len(n.Content) != 0 {
+		n = n.Content[0]
+	}
+	line := 0
+	for _, component := range path {
+		if n.Kind == yaml.SequenceNode {
+			i, err := strconv.Atoi(component)
+			if err != nil || i < 0 || i >= len(n.Content) {
+				break
+			}
+			n = n.Content[i]
+			line = n.Line
+			continue
+		}
+		if n.Kind != yaml.MappingNode {
+			break
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if n.Content[i].Value == component {
+				line = n.Content[i].Line
+				next = n.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			break
+		}
+		n = next
+	}
+	return line
+}
+
+func lintDRRuleNoRespond(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	for name, rule := range conf.DRRules {
+		if len(rule.Response) == 0 {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Warning,
+				Message:  "rule has no respond actions",
+			}.at("rules", name))
+		}
+	}
+	for _, hiveName := range drRuleHives {
+		for name, data := range conf.Hives[hiveName] {
+			if respond, _ := data.Data["respond"].([]interface{}); len(respond) == 0 {
+				findings = append(findings, LintFinding{
+					Severity: LintSeverities.Warning,
+					Message:  "rule has no respond actions",
+				}.at("hives", hiveName, name))
+			}
+		}
+	}
+	return findings
+}
+
+func lintOutputNoType(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	for name, output := range conf.Outputs {
+		if output.Type == "" {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Error,
+				Message:  "output has no type",
+			}.at("outputs", name))
+		}
+		if output.Module == "" {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Error,
+				Message:  "output has no module",
+			}.at("outputs", name))
+		}
+	}
+	return findings
+}
+
+// Paths in FP rules which, when used alone, suppress entire
+// detection categories or event types.
+var lintBroadFPPaths = map[string]struct{}{
+	"cat":                       {},
+	"routing/event_type":        {},
+	"detect/routing/event_type": {},
+}
+
+func lintFPRuleTooBroad(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	for name, rule := range conf.FPRules {
+		paths := map[string]struct{}{}
+		lintCollectPaths(map[string]interface{}(rule.Detection), paths)
+		// Rules without any path, like those matching on
+		// a lookup, cannot be judged on their paths.
+		isBroad := len(paths) != 0
+		for p := range paths {
+			if _, ok := lintBroadFPPaths[p]; !ok {
+				isBroad = false
+				break
+			}
+		}
+		if isBroad {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Warning,
+				Message:  "fp rule only filters on the detection category or event type",
+			}.at("fps", name))
+		}
+	}
+	return findings
+}
+
+func lintCollectPaths(node map[string]interface{}, paths map[string]struct{}) {
+	if p, ok := node["path"].(string); ok {
+		paths[strings.Trim(p, "/")] = struct{}{}
+	}
+	rules, _ := node["rules"].([]interface{})
+	for _, r := range rules {
+		if sub, ok := r.(map[string]interface{}); ok {
+			lintCollectPaths(sub, paths)
+		}
+	}
+}
+
+func lintYaraOrphanSource(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	if conf.Yara == nil {
+		return findings
+	}
+	used := map[YaraSourceName]struct{}{}
+	for _, rule := range conf.Yara.Rules {
+		for _, s := range rule.Sources {
+			used[s] = struct{}{}
+		}
+	}
+	for name := range conf.Yara.Sources {
+		if _, ok := used[name]; !ok {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Info,
+				Message:  "yara source is not used by any yara rule",
+			}.at("yara", "sources", name))
+		}
+	}
+	return findings
+}
+
+func lintUnusedLookup(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	used := map[string]struct{}{}
+	for _, rule := range conf.DRRules {
+		lintCollectResources(map[string]interface{}(rule.Detect), used)
+	}
+	for _, hiveName := range drRuleHives {
+		for _, data := range conf.Hives[hiveName] {
+			if detect, ok := data.Data["detect"].(map[string]interface{}); ok {
+				lintCollectResources(detect, used)
+			}
+		}
+	}
+	// FP rules can also match on lookups.
+	for _, rule := range conf.FPRules {
+		lintCollectResources(map[string]interface{}(rule.Detection), used)
+	}
+	for name := range conf.Hives["lookup"] {
+		if _, ok := used[fmt.Sprintf("hive://lookup/%s", name)]; !ok {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Info,
+				Message:  "lookup is not used by any rule",
+			}.at("hives", "lookup", name))
+		}
+	}
+	return findings
+}
+
+func lintCollectResources(node map[string]interface{}, resources map[string]struct{}) {
+	if r, ok := node["resource"].(string); ok {
+		resources[r] = struct{}{}
+	}
+	rules, _ := node["rules"].([]interface{})
+	for _, r := range rules {
+		if sub, ok := r.(map[string]interface{}); ok {
+			lintCollectResources(sub, resources)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLint(t *testing.T) {
+	a := assert.New(t)
+
+	conf := `rules:
+  no-respond:
+    detect:
+      op: lookup
+      path: event/DOMAIN_NAME
+      resource: hive://lookup/used
+    respond: []
+fps:
+  everything:
+    data:
+      op: is
+      path: cat
+      value: some-detection
+  specific:
+    data:
+      op: and
+      rules:
+        - op: is
+          path: cat
+          value: some-detection
+        - op: is
+          path: routing/hostname
+          value: test-host
+  allowed-domains:
+    data:
+      op: lookup
+      path: detect/event/DOMAIN_NAME
+      resource: hive://lookup/allowed
+outputs:
+  no-type:
+    module: syslog
+    dest_host: syslog.example.com
+hives:
+  lookup:
+    used:
+      data:
+        lookup_data:
+          a: {}
+      usr_mtd:
+        enabled: true
+    unused:
+      data:
+        lookup_data:
+          b: {}
+      usr_mtd:
+        enabled: true
+    allowed:
+      data:
+        lookup_data:
+          c: {}
+      usr_mtd:
+        enabled: true
+  dr-managed:
+    managed-no-respond:
+      data:
+        detect:
+          op: exists
+          path: event/FILE_PATH
+        respond: []
+      usr_mtd:
+        enabled: true
+yara:
+  sources:
+    orphan:
+      source: https://example.com/rules.yara
+`
+	findings, err := LintYAML([]byte(conf))
+	a.NoError(err)
+
+	found := map[string]LintFinding{}
+	for _, f := range findings {
+		found[f.Rule+"|"+f.Location] = f
+	}
+	a.Len(found, 6, findings)
+	a.Equal(2, found["dr-rule-no-respond|rules.no-respond"].Line)
+	a.Contains(found, "dr-rule-no-respond|hives.dr-managed.managed-no-respond")
+	a.Equal(LintSeverities.Warning, found["fp-rule-too-broad|fps.everything"].Severity)
+	a.Equal(LintSeverities.Error, found["output-no-type|outputs.no-type"].Severity)
+	a.Contains(found, "unused-lookup|hives.lookup.unused")
+	a.Contains(found, "yara-orphan-source|yara.sources.orphan")
+
+	custom := NewLintRule("no-outputs", func(conf OrgConfig) []LintFinding {
+		if len(conf.Outputs) != 0 {
+			return nil
+		}
+		return []LintFinding{{Severity: LintSeverities.Info, Location: "outputs", Message: "no outputs"}}
+	})
+	findings = OrgConfig{}.Lint(custom)
+	a.Len(findings, 1)
+	a.Equal("no-outputs", findings[0].Rule)
+}
+
+func TestLintYAMLPaths(t *testing.T) {
+	a := assert.New(t)
+
+	conf := `rules:
+  win:
+    detect:
+      op: exists
+      path: event/FILE_PATH
+    respond:
+      - action: report
+        name: win
+  win.susp.proc:
+    detect:
+      op: exists
+      path: event/COMMAND_LINE
+    respond: []
+fps:
+  no-paths:
+    data:
+      op: lookup
+      resource: hive://lookup/allowed
+`
+	findings, err := LintYAML([]byte(conf))
+	a.NoError(err)
+	a.Len(findings, 1, findings)
+	a.Equal("dr-rule-no-respond", findings[0].Rule)
+	a.Equal("rules.win.susp.proc", findings[0].Location)
+	a.Equal([]string{"rules", "win.susp.proc"}, findings[0].Path)
+	a.Equal(9, findings[0].Line)
+}
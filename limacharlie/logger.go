@@ -2,7 +2,10 @@ package limacharlie
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -21,6 +24,117 @@ type LCLogger interface {
 	Trace(msg string)
 }
 
+// LogLevel is the severity of a log line.
+type LogLevel = int
+
+var LogLevels = struct {
+	Trace LogLevel
+	Debug LogLevel
+	Info  LogLevel
+	Warn  LogLevel
+	Error LogLevel
+	Fatal LogLevel
+}{
+	Trace: 0,
+	Debug: 1,
+	Info:  2,
+	Warn:  3,
+	Error: 4,
+	Fatal: 5,
+}
+
+// LCFieldLogger is implemented by loggers supporting structured
+// fields, which receive the context of the lines logged by the SDK,
+// like the path and status of API requests, as fields. Other
+// loggers receive the fields appended to the message as "key=value".
+type LCFieldLogger interface {
+	LCLogger
+	Log(level LogLevel, msg string, fields map[string]interface{})
+}
+
+// logWithFields logs to any LCLogger, which may be nil.
+func logWithFields(logger LCLogger, level LogLevel, msg string, fields map[string]interface{}) {
+	if logger == nil {
+		return
+	}
+	if fl, ok := logger.(LCFieldLogger); ok {
+		fl.Log(level, msg, fields)
+		return
+	}
+	logAtLevel(logger, level, formatLogFields(msg, fields))
+}
+
+func logAtLevel(logger LCLogger, level LogLevel, msg string) {
+	switch level {
+	case LogLevels.Trace:
+		logger.Trace(msg)
+	case LogLevels.Debug:
+		logger.Debug(msg)
+	case LogLevels.Info:
+		logger.Info(msg)
+	case LogLevels.Warn:
+		logger.Warn(msg)
+	case LogLevels.Error:
+		logger.Error(msg)
+	default:
+		logger.Fatal(msg)
+	}
+}
+
+func formatLogFields(msg string, fields map[string]interface{}) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{msg}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	return strings.Join(parts, " ")
+}
+
+// LCLoggerLevel only passes the lines at or above
+// Level to Logger, like to silence the debug lines.
+type LCLoggerLevel struct {
+	Logger LCLogger
+	Level  LogLevel
+}
+
+// NewLCLoggerLevel filters the lines of a logger by level.
+func NewLCLoggerLevel(logger LCLogger, level LogLevel) *LCLoggerLevel {
+	return &LCLoggerLevel{Logger: logger, Level: level}
+}
+
+// Log logs with fields if at or above the level.
+func (l *LCLoggerLevel) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	if level < l.Level {
+		return
+	}
+	logWithFields(l.Logger, level, msg, fields)
+}
+
+// Fatal logs if at or above the level.
+func (l *LCLoggerLevel) Fatal(msg string) { l.Log(LogLevels.Fatal, msg, nil) }
+
+// Error logs if at or above the level.
+func (l *LCLoggerLevel) Error(msg string) { l.Log(LogLevels.Error, msg, nil) }
+
+// Warn logs if at or above the level.
+func (l *LCLoggerLevel) Warn(msg string) { l.Log(LogLevels.Warn, msg, nil) }
+
+// Info logs if at or above the level.
+func (l *LCLoggerLevel) Info(msg string) { l.Log(LogLevels.Info, msg, nil) }
+
+// Debug logs if at or above the level.
+func (l *LCLoggerLevel) Debug(msg string) { l.Log(LogLevels.Debug, msg, nil) }
+
+// Trace logs if at or above the level.
+func (l *LCLoggerLevel) Trace(msg string) { l.Log(LogLevels.Trace, msg, nil) }
+
 // LCLoggerEmpty does not actually log anything
 type LCLoggerEmpty struct{}
 
@@ -47,6 +161,31 @@ type LCLoggerZerolog struct {
 	l zerolog.Logger
 }
 
+// NewLCLoggerZerolog logs to a zerolog logger.
+func NewLCLoggerZerolog(l zerolog.Logger) *LCLoggerZerolog {
+	return &LCLoggerZerolog{l: l}
+}
+
+// Log logs with the fields as zerolog fields.
+func (l *LCLoggerZerolog) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	var e *zerolog.Event
+	switch level {
+	case LogLevels.Trace:
+		e = l.l.Trace()
+	case LogLevels.Debug:
+		e = l.l.Debug()
+	case LogLevels.Info:
+		e = l.l.Info()
+	case LogLevels.Warn:
+		e = l.l.Warn()
+	case LogLevels.Error:
+		e = l.l.Error()
+	default:
+		e = l.l.Fatal()
+	}
+	e.Fields(fields).Msg(msg)
+}
+
 // Fatal see zerolog logger fatal function
 func (l *LCLoggerZerolog) Fatal(msg string) {
 	l.l.Fatal().Msg(msg)
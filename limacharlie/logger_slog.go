@@ -0,0 +1,62 @@
+//go:build go1.21
+// +build go1.21
+
+package limacharlie
+
+import (
+	"context"
+	"log/slog"
+	"os"
+)
+
+// LCLoggerSlog implements the logging interface with slog.
+type LCLoggerSlog struct {
+	l *slog.Logger
+}
+
+// NewLCLoggerSlog logs to a slog logger, the default one if nil.
+func NewLCLoggerSlog(l *slog.Logger) *LCLoggerSlog {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &LCLoggerSlog{l: l}
+}
+
+var slogLevels = map[LogLevel]slog.Level{
+	LogLevels.Trace: slog.LevelDebug - 4,
+	LogLevels.Debug: slog.LevelDebug,
+	LogLevels.Info:  slog.LevelInfo,
+	LogLevels.Warn:  slog.LevelWarn,
+	LogLevels.Error: slog.LevelError,
+	LogLevels.Fatal: slog.LevelError + 4,
+}
+
+// Log logs with the fields as slog attributes.
+func (l *LCLoggerSlog) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for k, v := range fields {
+		attrs = append(attrs, slog.Any(k, v))
+	}
+	l.l.LogAttrs(context.Background(), slogLevels[level], msg, attrs...)
+	if level == LogLevels.Fatal {
+		os.Exit(1)
+	}
+}
+
+// Fatal logs at a level above error and exits, like zerolog.
+func (l *LCLoggerSlog) Fatal(msg string) { l.Log(LogLevels.Fatal, msg, nil) }
+
+// Error see slog logger error function
+func (l *LCLoggerSlog) Error(msg string) { l.Log(LogLevels.Error, msg, nil) }
+
+// Warn see slog logger warn function
+func (l *LCLoggerSlog) Warn(msg string) { l.Log(LogLevels.Warn, msg, nil) }
+
+// Info see slog logger info function
+func (l *LCLoggerSlog) Info(msg string) { l.Log(LogLevels.Info, msg, nil) }
+
+// Debug see slog logger debug function
+func (l *LCLoggerSlog) Debug(msg string) { l.Log(LogLevels.Debug, msg, nil) }
+
+// Trace logs below the debug level.
+func (l *LCLoggerSlog) Trace(msg string) { l.Log(LogLevels.Trace, msg, nil) }
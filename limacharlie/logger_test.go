@@ -0,0 +1,104 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordedLogLine struct {
+	level  LogLevel
+	msg    string
+	fields map[string]interface{}
+}
+
+type recordingLogger struct {
+	LCLoggerEmpty
+	lines []recordedLogLine
+}
+
+func (l *recordingLogger) Log(level LogLevel, msg string, fields map[string]interface{}) {
+	l.lines = append(l.lines, recordedLogLine{level: level, msg: msg, fields: fields})
+}
+
+type plainLogger struct {
+	LCLoggerEmpty
+	warnings []string
+}
+
+func (l *plainLogger) Warn(msg string) {
+	l.warnings = append(l.warnings, msg)
+}
+
+func TestLoggerLevelFilter(t *testing.T) {
+	a := assert.New(t)
+
+	rec := &recordingLogger{}
+	l := NewLCLoggerLevel(rec, LogLevels.Info)
+	l.Debug("hidden")
+	l.Info("shown")
+	logWithFields(l, LogLevels.Warn, "with fields", map[string]interface{}{"k": 1})
+	a.Len(rec.lines, 2)
+	a.Equal("shown", rec.lines[0].msg)
+	a.Equal(LogLevels.Warn, rec.lines[1].level)
+	a.Equal(1, rec.lines[1].fields["k"])
+}
+
+func TestLoggerPlainFields(t *testing.T) {
+	a := assert.New(t)
+
+	plain := &plainLogger{}
+	logWithFields(plain, LogLevels.Warn, "rate limited", map[string]interface{}{
+		"wait": "10s",
+		"path": "sensors/oid",
+	})
+	a.Equal([]string{"rate limited path=sensors/oid wait=10s"}, plain.warnings)
+
+	// Nil loggers are ignored.
+	logWithFields(nil, LogLevels.Error, "dropped", nil)
+}
+
+func TestLoggerRequests(t *testing.T) {
+	a := assert.New(t)
+
+	nRequests := 0
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		nRequests++
+		if nRequests == 1 {
+			return jsonResponse(http.StatusInternalServerError, `{"error":"boom"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"keys":{}}`), nil
+	}))
+	rec := &recordingLogger{}
+	org.client.logger = rec
+
+	_, err := org.IngestionKeys()
+	a.NoError(err)
+
+	msgs := []string{}
+	for _, line := range rec.lines {
+		msgs = append(msgs, line.msg)
+	}
+	a.Equal([]string{"api request", "retrying api request", "api request"}, msgs)
+	a.Equal(http.StatusInternalServerError, rec.lines[0].fields["status"])
+	a.Equal(1, rec.lines[0].fields["attempt"])
+	a.Equal(LogLevels.Warn, rec.lines[1].level)
+	a.Equal(http.StatusOK, rec.lines[2].fields["status"])
+	a.Equal(2, rec.lines[2].fields["attempt"])
+	a.Equal("insight/"+vcrTestOID+"/ingestion_keys", rec.lines[2].fields["path"])
+}
+
+func TestLoggerSyncOperations(t *testing.T) {
+	a := assert.New(t)
+
+	rec := &recordingLogger{}
+	options := SyncOptions{Logger: rec}
+	options.appendOp(nil, OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "o1"})
+	options.appendOp(nil, OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "o2", IsAdded: true})
+	a.Len(rec.lines, 2)
+	a.Equal(LogLevels.Debug, rec.lines[0].level)
+	a.Equal("none", rec.lines[0].fields["action"])
+	a.Equal(LogLevels.Info, rec.lines[1].level)
+	a.Equal("add", rec.lines[1].fields["action"])
+}
@@ -0,0 +1,266 @@
+package limacharlie
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	lookupHiveName = "lookup"
+	lookupDataKey  = "lookup_data"
+
+	// Number of entries sent per request when
+	// adding or removing the entries of a lookup.
+	lookupEntriesChunkSize = 10000
+)
+
+// LookupName is the key of a lookup in the "lookup" hive.
+type LookupName = string
+
+// LookupEntries are the entries of a lookup, the metadata of each
+// indicator, like {"source": "feed-a"}, empty for those without.
+type LookupEntries map[string]Dict
+
+// LookupDelta is the difference between the entries of two lookups.
+type LookupDelta struct {
+	// Added are the entries which are new or whose metadata changed.
+	Added   LookupEntries
+	Removed []string
+}
+
+// IsEmpty returns true if the lookups have the same entries.
+func (d LookupDelta) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0
+}
+
+// DiffLookupEntries returns the changes turning the
+// current entries of a lookup into the wanted ones.
+func DiffLookupEntries(current LookupEntries, wanted LookupEntries) LookupDelta {
+	delta := LookupDelta{Added: LookupEntries{}, Removed: []string{}}
+	for indicator, mtd := range wanted {
+		if cur, ok := current[indicator]; ok && DeepEqualConfig(cur, mtd) {
+			continue
+		}
+		delta.Added[indicator] = mtd
+	}
+	for indicator := range current {
+		if _, ok := wanted[indicator]; !ok {
+			delta.Removed = append(delta.Removed, indicator)
+		}
+	}
+	sort.Strings(delta.Removed)
+	return delta
+}
+
+// lookupEntriesFromData returns the entries in the data of
+// a lookup record, false if it has no lookup_data.
+func lookupEntriesFromData(data map[string]interface{}) (LookupEntries, bool) {
+	raw, ok := data[lookupDataKey].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	entries := LookupEntries{}
+	for indicator, mtd := range raw {
+		m, _ := mtd.(map[string]interface{})
+		entries[indicator] = Dict(m)
+	}
+	return entries, true
+}
+
+// LookupData returns the data of a lookup record with the entries,
+// like for the SyncHiveData of a lookup loaded from a file.
+func LookupData(entries LookupEntries) map[string]interface{} {
+	raw := map[string]interface{}{}
+	for indicator, mtd := range entries {
+		if mtd == nil {
+			mtd = Dict{}
+		}
+		raw[indicator] = map[string]interface{}(mtd)
+	}
+	return map[string]interface{}{lookupDataKey: raw}
+}
+
+// LookupEntries returns the entries of a lookup.
+func (org Organization) LookupEntries(name LookupName) (LookupEntries, error) {
+	record, err := NewHiveClient(&org).Get(HiveArgs{
+		HiveName:     lookupHiveName,
+		PartitionKey: org.client.options.OID,
+		Key:          name,
+	})
+	if err != nil {
+		if isRESTNotFound(err) {
+			return nil, ErrorResourceNotFound
+		}
+		return nil, err
+	}
+	entries, ok := lookupEntriesFromData(record.Data)
+	if !ok {
+		return LookupEntries{}, nil
+	}
+	return entries, nil
+}
+
+// LookupAddEntries adds entries to a lookup, or updates their
+// metadata, without uploading the whole lookup, which matters
+// for lookups of hundreds of thousands of indicators. Entries
+// are sent in chunks, an error leaves the previous ones applied.
+func (org Organization) LookupAddEntries(name LookupName, entries LookupEntries) error {
+	indicators := make([]string, 0, len(entries))
+	for indicator := range entries {
+		indicators = append(indicators, indicator)
+	}
+	sort.Strings(indicators)
+	for start := 0; start < len(indicators); start += lookupEntriesChunkSize {
+		end := start + lookupEntriesChunkSize
+		if end > len(indicators) {
+			end = len(indicators)
+		}
+		chunk := map[string]interface{}{}
+		for _, indicator := range indicators[start:end] {
+			mtd := entries[indicator]
+			if mtd == nil {
+				mtd = Dict{}
+			}
+			chunk[indicator] = map[string]interface{}(mtd)
+		}
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return err
+		}
+		resp := Dict{}
+		request := makeDefaultRequest(&resp).withFormData(Dict{
+			"action":  "add",
+			"entries": string(data),
+		})
+		if err := org.client.reliableRequest(http.MethodPost, org.lookupEntriesPath(name), request); err != nil {
+			return fmt.Errorf("adding entries %d to %d of %d: %v", start, end, len(indicators), err)
+		}
+	}
+	return nil
+}
+
+// LookupRemoveEntries removes indicators from a
+// lookup, in chunks like LookupAddEntries.
+func (org Organization) LookupRemoveEntries(name LookupName, indicators []string) error {
+	for start := 0; start < len(indicators); start += lookupEntriesChunkSize {
+		end := start + lookupEntriesChunkSize
+		if end > len(indicators) {
+			end = len(indicators)
+		}
+		resp := Dict{}
+		request := makeDefaultRequest(&resp).withFormData(Dict{
+			"action":     "remove",
+			"indicators": indicators[start:end],
+		})
+		if err := org.client.reliableRequest(http.MethodPost, org.lookupEntriesPath(name), request); err != nil {
+			return fmt.Errorf("removing entries %d to %d of %d: %v", start, end, len(indicators), err)
+		}
+	}
+	return nil
+}
+
+func (org Organization) lookupEntriesPath(name LookupName) string {
+	return fmt.Sprintf("hive/%s/%s/%s/entries", lookupHiveName, org.client.options.OID, url.PathEscape(name))
+}
+
+// ApplyLookupDelta applies the changes of a LookupDelta to a lookup.
+func (org Organization) ApplyLookupDelta(name LookupName, delta LookupDelta) error {
+	if len(delta.Removed) != 0 {
+		if err := org.LookupRemoveEntries(name, delta.Removed); err != nil {
+			return err
+		}
+	}
+	if len(delta.Added) != 0 {
+		if err := org.LookupAddEntries(name, delta.Added); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupSyncDelta returns the delta to apply to a lookup record
+// to sync it instead of replacing it, false when the record differs
+// by more than the entries or the delta is larger than the lookup.
+func lookupSyncDelta(wanted SyncHiveData, current SyncHiveData) (LookupDelta, bool) {
+	wantedEntries, ok := lookupEntriesFromData(wanted.Data)
+	if !ok {
+		return LookupDelta{}, false
+	}
+	currentEntries, ok := lookupEntriesFromData(current.Data)
+	if !ok {
+		return LookupDelta{}, false
+	}
+	if !DeepEqualConfig(wanted.UsrMtd, current.UsrMtd) {
+		return LookupDelta{}, false
+	}
+	wantedOther := map[string]interface{}{}
+	for k, v := range wanted.Data {
+		if k != lookupDataKey {
+			wantedOther[k] = v
+		}
+	}
+	currentOther := map[string]interface{}{}
+	for k, v := range current.Data {
+		if k != lookupDataKey {
+			currentOther[k] = v
+		}
+	}
+	if !DeepEqualConfig(wantedOther, currentOther) {
+		return LookupDelta{}, false
+	}
+	delta := DiffLookupEntries(currentEntries, wantedEntries)
+	if len(delta.Added)+len(delta.Removed) >= len(wantedEntries) {
+		return LookupDelta{}, false
+	}
+	return delta, true
+}
+
+// LookupEntriesFromFile loads the entries of a lookup from a local
+// file: a JSON or YAML map of the indicators to their metadata, or
+// a text file of an indicator per line, "#" starting comments.
+func LookupEntriesFromFile(path string) (LookupEntries, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := LookupEntries{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("invalid lookup %s: %v", path, err)
+		}
+	case ".yaml", ".yml":
+		if err := yaml.NewDecoder(f).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("invalid lookup %s: %v", path, err)
+		}
+	default:
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			entries[line] = Dict{}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+	for indicator, mtd := range entries {
+		if mtd == nil {
+			entries[indicator] = Dict{}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,109 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffLookupEntries(t *testing.T) {
+	a := assert.New(t)
+	delta := DiffLookupEntries(LookupEntries{
+		"a.com": {},
+		"b.com": {"source": "feed-a"},
+		"c.com": {"source": "feed-a"},
+	}, LookupEntries{
+		"a.com": {},
+		"b.com": {"source": "feed-b"},
+		"d.com": {},
+	})
+	a.Equal(LookupEntries{"b.com": {"source": "feed-b"}, "d.com": {}}, delta.Added)
+	a.Equal([]string{"c.com"}, delta.Removed)
+	a.False(delta.IsEmpty())
+	a.True(DiffLookupEntries(LookupEntries{"a.com": {}}, LookupEntries{"a.com": {}}).IsEmpty())
+}
+
+func TestLookupEntriesFromFile(t *testing.T) {
+	a := assert.New(t)
+	dir := t.TempDir()
+	txt := filepath.Join(dir, "domains.txt")
+	a.NoError(ioutil.WriteFile(txt, []byte("# bad domains\nevil.com\n\n  bad.org \n"), 0600))
+	entries, err := LookupEntriesFromFile(txt)
+	a.NoError(err)
+	a.Equal(LookupEntries{"evil.com": {}, "bad.org": {}}, entries)
+
+	yml := filepath.Join(dir, "domains.yaml")
+	a.NoError(ioutil.WriteFile(yml, []byte("evil.com:\n  source: feed-a\nbad.org:\n"), 0600))
+	entries, err = LookupEntriesFromFile(yml)
+	a.NoError(err)
+	a.Equal(LookupEntries{"evil.com": {"source": "feed-a"}, "bad.org": {}}, entries)
+	a.Equal(map[string]interface{}{"lookup_data": map[string]interface{}{
+		"evil.com": map[string]interface{}{"source": "feed-a"},
+		"bad.org":  map[string]interface{}{},
+	}}, LookupData(entries))
+
+	_, err = LookupEntriesFromFile(filepath.Join(dir, "missing.json"))
+	a.Error(err)
+}
+
+func TestSyncHiveLookupDelta(t *testing.T) {
+	a := assert.New(t)
+	added := map[string]interface{}{}
+	removed := []string{}
+	isReplaced := false
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r.ParseForm()
+		switch r.URL.Path {
+		case "/v1/orgs/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{"oid": "`+vcrTestOID+`"}`), nil
+		case "/v1/hive/lookup/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{
+				"big": {"data": {"lookup_data": {"a.com": {}, "b.com": {}, "c.com": {}, "d.com": {}}}, "usr_mtd": {"enabled": true}},
+				"small": {"data": {"lookup_data": {"a.com": {}}}, "usr_mtd": {"enabled": true}}
+			}`), nil
+		case "/v1/hive/lookup/" + vcrTestOID + "/big/entries":
+			if r.Form.Get("action") == "remove" {
+				removed = append(removed, r.Form["indicators"]...)
+			} else {
+				a.NoError(json.Unmarshal([]byte(r.Form.Get("entries")), &added))
+			}
+			return jsonResponse(http.StatusOK, `{}`), nil
+		case "/v1/hive/lookup/" + vcrTestOID + "/small/data":
+			isReplaced = true
+			return jsonResponse(http.StatusOK, `{}`), nil
+		}
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	ops, err := org.syncHive(orgSyncHives{"lookup": {
+		"big": {
+			Data:   LookupData(LookupEntries{"a.com": {}, "b.com": {"source": "feed-a"}, "c.com": {}, "e.com": {}}),
+			UsrMtd: UsrMtd{Enabled: true},
+		},
+		// Replacing the single entry is smaller than a delta.
+		"small": {
+			Data:   LookupData(LookupEntries{"z.com": {}}),
+			UsrMtd: UsrMtd{Enabled: true},
+		},
+	}}, SyncOptions{})
+	a.NoError(err)
+	a.Len(ops, 2)
+	for _, op := range ops {
+		a.True(op.IsUpdated)
+		a.NoError(op.Error)
+	}
+	a.Equal([]string{"d.com"}, removed)
+	keys := []string{}
+	for k := range added {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	a.Equal([]string{"b.com", "e.com"}, keys)
+	a.True(isReplaced)
+}
@@ -0,0 +1,337 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MergeConflict is an element modified differently in
+// "ours" and "theirs" since the "base" of a three-way merge.
+// Values are nil when the element is absent.
+type MergeConflict struct {
+	// Path of the element, like "rules/my-rule".
+	Path   string      `json:"path"`
+	Base   interface{} `json:"base"`
+	Ours   interface{} `json:"ours"`
+	Theirs interface{} `json:"theirs"`
+}
+
+func (c MergeConflict) String() string {
+	return fmt.Sprintf("conflict on %s", c.Path)
+}
+
+// Number of levels of nesting before reaching the
+// individual elements of each section of an OrgConfig.
+var orgConfigSectionDepth = map[string]int{
+	"exfil": 2,
+	"hives": 2,
+	"yara":  2,
+}
+
+// Sections of an OrgConfig that are lists of unique values.
+var orgConfigSetSections = map[string]struct{}{
+	"extensions": {},
+}
+
+// MergeThreeWay merges the changes made in "ours" and "theirs" since
+// "base", element by element. Elements changed on a single side keep
+// that change. Elements changed differently on both sides are reported
+// as conflicts and keep the value from "ours".
+func MergeThreeWay(base OrgConfig, ours OrgConfig, theirs OrgConfig) (OrgConfig, []MergeConflict, error) {
+	conflicts := []MergeConflict{}
+	b, err := orgConfigToGeneric(base)
+	if err != nil {
+		return OrgConfig{}, nil, fmt.Errorf("base: %v", err)
+	}
+	o, err := orgConfigToGeneric(ours)
+	if err != nil {
+		return OrgConfig{}, nil, fmt.Errorf("ours: %v", err)
+	}
+	t, err := orgConfigToGeneric(theirs)
+	if err != nil {
+		return OrgConfig{}, nil, fmt.Errorf("theirs: %v", err)
+	}
+
+	merged := map[string]interface{}{}
+	for _, section := range genericKeys(b, o, t) {
+		if section == "version" {
+			continue
+		}
+		var v interface{}
+		var isPresent bool
+		if _, ok := orgConfigSetSections[section]; ok {
+			v, isPresent = mergeThreeWaySet(b[section], o[section], t[section])
+		} else {
+			depth := orgConfigSectionDepth[section]
+			if depth == 0 {
+				depth = 1
+			}
+			v, isPresent = mergeThreeWayMap(section, depth, b[section], o[section], t[section], &conflicts)
+		}
+		if isPresent {
+			merged[section] = v
+		}
+	}
+	merged["version"] = ours.Version
+
+	out := OrgConfig{}
+	serialized, err := json.Marshal(merged)
+	if err != nil {
+		return out, conflicts, err
+	}
+	if err := json.Unmarshal(serialized, &out); err != nil {
+		return out, conflicts, err
+	}
+	return out, conflicts, nil
+}
+
+func orgConfigToGeneric(c OrgConfig) (map[string]interface{}, error) {
+	serialized, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return UnmarshalCleanJSON(string(serialized))
+}
+
+func genericKeys(maps ...map[string]interface{}) []string {
+	keys := map[string]struct{}{}
+	for _, m := range maps {
+		for k := range m {
+			keys[k] = struct{}{}
+		}
+	}
+	out := []string{}
+	for k := range keys {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// mergeThreeWayMap merges maps of elements nested "depth" levels deep.
+// It returns false if the resulting value should be absent.
+func mergeThreeWayMap(path string, depth int, b interface{}, o interface{}, t interface{}, conflicts *[]MergeConflict) (interface{}, bool) {
+	if depth == 0 {
+		return mergeThreeWayValue(path, b, o, t, conflicts)
+	}
+	bm, bok := b.(map[string]interface{})
+	om, ook := o.(map[string]interface{})
+	tm, tok := t.(map[string]interface{})
+	if (b != nil && !bok) || (o != nil && !ook) || (t != nil && !tok) {
+		// Not a map, merge as a single value.
+		return mergeThreeWayValue(path, b, o, t, conflicts)
+	}
+	if o == nil && t == nil {
+		return nil, false
+	}
+	merged := map[string]interface{}{}
+	for _, k := range genericKeys(bm, om, tm) {
+		if v, ok := mergeThreeWayMap(strings.Join([]string{path, k}, "/"), depth-1, bm[k], om[k], tm[k], conflicts); ok {
+			merged[k] = v
+		}
+	}
+	return merged, true
+}
+
+func mergeThreeWayValue(path string, b interface{}, o interface{}, t interface{}, conflicts *[]MergeConflict) (interface{}, bool) {
+	switch {
+	case DeepEqualConfig(o, t) && (o == nil) == (t == nil):
+		return o, o != nil
+	case DeepEqualConfig(o, b) && (o == nil) == (b == nil):
+		return t, t != nil
+	case DeepEqualConfig(t, b) && (t == nil) == (b == nil):
+		return o, o != nil
+	}
+	*conflicts = append(*conflicts, MergeConflict{
+		Path:   path,
+		Base:   b,
+		Ours:   o,
+		Theirs: t,
+	})
+	return o, o != nil
+}
+
+func mergeThreeWaySet(b interface{}, o interface{}, t interface{}) (interface{}, bool) {
+	toSet := func(v interface{}) (map[string]struct{}, []string) {
+		s := map[string]struct{}{}
+		ordered := []string{}
+		l, _ := v.([]interface{})
+		for _, e := range l {
+			k := fmt.Sprintf("%v", e)
+			if _, ok := s[k]; ok {
+				continue
+			}
+			s[k] = struct{}{}
+			ordered = append(ordered, k)
+		}
+		return s, ordered
+	}
+	baseSet, _ := toSet(b)
+	ourSet, oOrdered := toSet(o)
+	theirSet, tOrdered := toSet(t)
+
+	merged := []interface{}{}
+	seen := map[string]struct{}{}
+	for _, e := range append(oOrdered, tOrdered...) {
+		if _, ok := seen[e]; ok {
+			continue
+		}
+		seen[e] = struct{}{}
+		_, inBase := baseSet[e]
+		_, inOurs := ourSet[e]
+		_, inTheirs := theirSet[e]
+		// Removed on one side since base.
+		if inBase && (!inOurs || !inTheirs) {
+			continue
+		}
+		merged = append(merged, e)
+	}
+	if o == nil && t == nil {
+		return nil, false
+	}
+	return merged, true
+}
+
+type MergeStrategy = string
+
+var MergeStrategies = struct {
+	// The element from the merged config replaces the existing one.
+	Replace MergeStrategy
+	// Fields set in the merged config are merged recursively
+	// into the existing element.
+	DeepMerge MergeStrategy
+	// Merging a different element with the same name is an error.
+	Error MergeStrategy
+}{
+	Replace:   "replace",
+	DeepMerge: "deep-merge",
+	Error:     "error",
+}
+
+// MergeOptions customizes how MergeWithOptions handles elements
+// with the same name in both configs.
+type MergeOptions struct {
+	// Strategy to use per section, keyed by the name of the section
+	// in the config, like "rules" or "outputs". Sections not listed
+	// use the same semantics as Merge().
+	Sections map[string]MergeStrategy `json:"sections,omitempty"`
+	// IsAppendLists appends lists in deep merges instead
+	// of replacing them.
+	IsAppendLists bool `json:"append_lists,omitempty"`
+}
+
+// MergeWithOptions is like Merge but applies the strategies from
+// the options to sections, allowing an include to override a single
+// field like "is_enabled" without restating the entire rule.
+func (o OrgConfig) MergeWithOptions(conf OrgConfig, opts MergeOptions) (OrgConfig, error) {
+	merged := o.Merge(conf)
+	if len(opts.Sections) == 0 {
+		return merged, nil
+	}
+
+	a, err := orgConfigToGeneric(o)
+	if err != nil {
+		return merged, err
+	}
+	b, err := orgConfigToGeneric(conf)
+	if err != nil {
+		return merged, err
+	}
+	m, err := orgConfigToGeneric(merged)
+	if err != nil {
+		return merged, err
+	}
+
+	for _, section := range genericKeys(a, b) {
+		strategy, ok := opts.Sections[section]
+		if !ok || strategy == MergeStrategies.Replace {
+			continue
+		}
+		if strategy != MergeStrategies.DeepMerge && strategy != MergeStrategies.Error {
+			return merged, fmt.Errorf("unknown merge strategy for %s: %s", section, strategy)
+		}
+		depth := orgConfigSectionDepth[section]
+		if depth == 0 {
+			depth = 1
+		}
+		v, err := mergeWithStrategy(section, depth, a[section], b[section], strategy, opts.IsAppendLists)
+		if err != nil {
+			return merged, err
+		}
+		m[section] = v
+	}
+
+	out := OrgConfig{}
+	serialized, err := json.Marshal(m)
+	if err != nil {
+		return merged, err
+	}
+	if err := json.Unmarshal(serialized, &out); err != nil {
+		return merged, err
+	}
+	out.Includes = merged.Includes
+	return out, nil
+}
+
+func mergeWithStrategy(path string, depth int, a interface{}, b interface{}, strategy MergeStrategy, isAppendLists bool) (interface{}, error) {
+	if b == nil {
+		return a, nil
+	}
+	if a == nil {
+		return b, nil
+	}
+	am, aok := a.(map[string]interface{})
+	bm, bok := b.(map[string]interface{})
+	if depth != 0 && aok && bok {
+		merged := map[string]interface{}{}
+		for _, k := range genericKeys(am, bm) {
+			v, err := mergeWithStrategy(strings.Join([]string{path, k}, "/"), depth-1, am[k], bm[k], strategy, isAppendLists)
+			if err != nil {
+				return nil, err
+			}
+			merged[k] = v
+		}
+		return merged, nil
+	}
+	if strategy == MergeStrategies.Error {
+		if !DeepEqualConfig(a, b) {
+			return nil, fmt.Errorf("merge conflict on %s", path)
+		}
+		return a, nil
+	}
+	return deepMerge(a, b, isAppendLists), nil
+}
+
+// deepMerge merges b into a, ignoring null values from b.
+func deepMerge(a interface{}, b interface{}, isAppendLists bool) interface{} {
+	if b == nil {
+		return a
+	}
+	switch bv := b.(type) {
+	case map[string]interface{}:
+		av, ok := a.(map[string]interface{})
+		if !ok {
+			return b
+		}
+		merged := map[string]interface{}{}
+		for k, v := range av {
+			merged[k] = v
+		}
+		for k, v := range bv {
+			if v == nil {
+				continue
+			}
+			merged[k] = deepMerge(av[k], v, isAppendLists)
+		}
+		return merged
+	case []interface{}:
+		av, ok := a.([]interface{})
+		if !ok || !isAppendLists {
+			return b
+		}
+		return append(append([]interface{}{}, av...), bv...)
+	}
+	return b
+}
@@ -0,0 +1,235 @@
+package limacharlie
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergeThreeWay(t *testing.T) {
+	a := assert.New(t)
+
+	load := func(s string) OrgConfig {
+		c := OrgConfig{}
+		a.NoError(yaml.Unmarshal([]byte(s), &c))
+		return c
+	}
+
+	base := load(`
+version: 3
+rules:
+  r1:
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: a
+    respond:
+      - action: report
+        name: r1
+  r2:
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: b
+    respond:
+      - action: report
+        name: r2
+outputs:
+  o1:
+    module: syslog
+    type: detect
+    dest_host: 1.1.1.1
+extensions:
+  - ext-a
+  - ext-b
+`)
+	// Locally r1 is modified, o1 is conflicting and ext-b is removed.
+	ours := load(`
+version: 3
+rules:
+  r1:
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: a-modified
+    respond:
+      - action: report
+        name: r1
+  r2:
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: b
+    respond:
+      - action: report
+        name: r2
+outputs:
+  o1:
+    module: syslog
+    type: detect
+    dest_host: 2.2.2.2
+extensions:
+  - ext-a
+`)
+	// Live r2 is removed, r3 is added and o1 is conflicting.
+	theirs := load(`
+version: 3
+rules:
+  r1:
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: a
+    respond:
+      - action: report
+        name: r1
+  r3:
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: c
+    respond:
+      - action: report
+        name: r3
+outputs:
+  o1:
+    module: syslog
+    type: detect
+    dest_host: 3.3.3.3
+extensions:
+  - ext-a
+  - ext-b
+  - ext-c
+`)
+
+	merged, conflicts, err := MergeThreeWay(base, ours, theirs)
+	a.NoError(err)
+	a.Equal(3, merged.Version)
+
+	a.Len(merged.DRRules, 2)
+	a.Equal("a-modified", merged.DRRules["r1"].Detect["value"])
+	a.Contains(merged.DRRules, "r3")
+
+	a.Len(conflicts, 1)
+	a.Equal("outputs/o1", conflicts[0].Path)
+	a.Equal("2.2.2.2", merged.Outputs["o1"].DestinationHost)
+
+	a.Equal(orgSyncExtensions{"ext-a", "ext-c"}, merged.Extensions)
+}
+
+func TestMergeWithOptions(t *testing.T) {
+	a := assert.New(t)
+
+	base := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(`
+rules:
+  r1:
+    is_enabled: true
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: a
+    respond:
+      - action: report
+        name: r1
+outputs:
+  o1:
+    module: syslog
+    type: detect
+`), &base))
+	override := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(`
+rules:
+  r1:
+    is_enabled: false
+    respond:
+      - action: add tag
+        tag: seen
+outputs:
+  o1:
+    module: syslog
+    type: event
+`), &override))
+
+	merged, err := base.MergeWithOptions(override, MergeOptions{
+		Sections:      map[string]MergeStrategy{"rules": MergeStrategies.DeepMerge},
+		IsAppendLists: true,
+	})
+	a.NoError(err)
+	a.False(*merged.DRRules["r1"].IsEnabled)
+	a.Equal("a", merged.DRRules["r1"].Detect["value"])
+	a.Len(merged.DRRules["r1"].Response, 2)
+	a.Equal(OutputDataType("event"), merged.Outputs["o1"].Type)
+
+	_, err = base.MergeWithOptions(override, MergeOptions{
+		Sections: map[string]MergeStrategy{"outputs": MergeStrategies.Error},
+	})
+	a.EqualError(err, "merge conflict on outputs/o1")
+
+	_, err = base.MergeWithOptions(base, MergeOptions{
+		Sections: map[string]MergeStrategy{"outputs": MergeStrategies.Error},
+	})
+	a.NoError(err)
+}
+
+func TestLoadEffectiveConfigMergeOptions(t *testing.T) {
+	a := assert.New(t)
+	files := map[string][]byte{
+		"root.yaml": []byte(`version: 3
+include:
+  - base.yaml
+  - override.yaml
+`),
+		"base.yaml": []byte(`version: 3
+rules:
+  r1:
+    is_enabled: true
+    detect:
+      op: is
+      path: event/FILE_PATH
+      value: a
+    respond:
+      - action: report
+        name: r1
+`),
+		"override.yaml": []byte(`version: 3
+rules:
+  r1:
+    is_enabled: false
+`),
+	}
+	ldr := func(parent string, configFile string) ([]byte, error) {
+		d, ok := files[filepath.Join(filepath.Dir(parent), configFile)]
+		if !ok {
+			return nil, fmt.Errorf("file not found: %s", configFile)
+		}
+		return d, nil
+	}
+
+	conf, err := loadEffectiveConfig("", "root.yaml", SyncOptions{
+		IncludeLoader: ldr,
+		MergeOptions: MergeOptions{
+			Sections: map[string]MergeStrategy{"rules": MergeStrategies.DeepMerge},
+		},
+	})
+	a.NoError(err)
+	a.False(*conf.DRRules["r1"].IsEnabled)
+	a.Equal("a", conf.DRRules["r1"].Detect["value"])
+	a.Len(conf.DRRules["r1"].Response, 1)
+
+	// By default the rule of the last include replaces the others.
+	conf, err = loadEffectiveConfig("", "root.yaml", SyncOptions{IncludeLoader: ldr})
+	a.NoError(err)
+	a.Nil(conf.DRRules["r1"].Detect)
+
+	_, err = loadEffectiveConfig("", "root.yaml", SyncOptions{
+		IncludeLoader: ldr,
+		MergeOptions: MergeOptions{
+			Sections: map[string]MergeStrategy{"rules": MergeStrategies.Error},
+		},
+	})
+	a.EqualError(err, "override.yaml: merge conflict on rules/r1")
+}
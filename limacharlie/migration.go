@@ -0,0 +1,92 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OrgConfigMigration upgrades the raw content of a config
+// from one version to the next one.
+type OrgConfigMigration = func(conf map[string]interface{}) (map[string]interface{}, error)
+
+var orgConfigMigrations = map[int]OrgConfigMigration{}
+var orgConfigMigrationsMutex sync.RWMutex
+
+// RegisterOrgConfigMigration registers the function upgrading
+// configs of version "from" to version "from + 1". Versions without
+// a migration are considered compatible with the next version.
+func RegisterOrgConfigMigration(from int, migration OrgConfigMigration) error {
+	if from <= 0 || from >= OrgConfigLatestVersion {
+		return fmt.Errorf("invalid migration version: %d", from)
+	}
+	orgConfigMigrationsMutex.Lock()
+	defer orgConfigMigrationsMutex.Unlock()
+	if _, ok := orgConfigMigrations[from]; ok {
+		return fmt.Errorf("migration already registered for version %d", from)
+	}
+	orgConfigMigrations[from] = migration
+	return nil
+}
+
+// LoadOrgConfig parses a YAML config, validating its version
+// and applying the migrations required to bring it to the
+// OrgConfigLatestVersion.
+func LoadOrgConfig(data []byte) (OrgConfig, error) {
+	conf := OrgConfig{}
+	if err := yaml.Unmarshal(data, &conf); err != nil {
+		return OrgConfig{}, err
+	}
+	if conf.Version <= 0 {
+		return OrgConfig{}, fmt.Errorf("invalid version: %v", conf.Version)
+	}
+	if conf.Version > OrgConfigLatestVersion {
+		return OrgConfig{}, fmt.Errorf("version %d is newer than the latest supported version %d, a newer SDK is required", conf.Version, OrgConfigLatestVersion)
+	}
+	if conf.Version == OrgConfigLatestVersion {
+		return conf, nil
+	}
+
+	orgConfigMigrationsMutex.RLock()
+	migrations := []OrgConfigMigration{}
+	for v := conf.Version; v < OrgConfigLatestVersion; v++ {
+		if m, ok := orgConfigMigrations[v]; ok {
+			migrations = append(migrations, m)
+		}
+	}
+	orgConfigMigrationsMutex.RUnlock()
+	if len(migrations) == 0 {
+		conf.Version = OrgConfigLatestVersion
+		return conf, nil
+	}
+
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return OrgConfig{}, err
+	}
+	from := conf.Version
+	for v := from; v < OrgConfigLatestVersion; v++ {
+		orgConfigMigrationsMutex.RLock()
+		m, ok := orgConfigMigrations[v]
+		orgConfigMigrationsMutex.RUnlock()
+		if !ok {
+			continue
+		}
+		var err error
+		if raw, err = m(raw); err != nil {
+			return OrgConfig{}, fmt.Errorf("migrating from version %d: %v", v, err)
+		}
+	}
+	raw["version"] = OrgConfigLatestVersion
+
+	migrated, err := yaml.Marshal(raw)
+	if err != nil {
+		return OrgConfig{}, err
+	}
+	conf = OrgConfig{}
+	if err := yaml.Unmarshal(migrated, &conf); err != nil {
+		return OrgConfig{}, err
+	}
+	return conf, nil
+}
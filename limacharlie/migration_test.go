@@ -0,0 +1,55 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadOrgConfigMigrations(t *testing.T) {
+	a := assert.New(t)
+
+	orgConfigMigrationsMutex.Lock()
+	saved := orgConfigMigrations
+	orgConfigMigrations = map[int]OrgConfigMigration{}
+	orgConfigMigrationsMutex.Unlock()
+	defer func() {
+		orgConfigMigrationsMutex.Lock()
+		orgConfigMigrations = saved
+		orgConfigMigrationsMutex.Unlock()
+	}()
+
+	// Pretend version 1 named the rules section "dr".
+	a.NoError(RegisterOrgConfigMigration(1, func(conf map[string]interface{}) (map[string]interface{}, error) {
+		conf["rules"] = conf["dr"]
+		delete(conf, "dr")
+		return conf, nil
+	}))
+	a.Error(RegisterOrgConfigMigration(1, nil))
+	a.Error(RegisterOrgConfigMigration(OrgConfigLatestVersion, nil))
+
+	conf, err := LoadOrgConfig([]byte(`
+version: 1
+dr:
+  r1:
+    detect:
+      op: exists
+      path: event
+    respond:
+      - action: report
+        name: r1
+`))
+	a.NoError(err)
+	a.Equal(OrgConfigLatestVersion, conf.Version)
+	a.Contains(conf.DRRules, "r1")
+
+	conf, err = LoadOrgConfig([]byte("version: 2\nextensions:\n  - ext-a\n"))
+	a.NoError(err)
+	a.Equal(OrgConfigLatestVersion, conf.Version)
+	a.Equal(orgSyncExtensions{"ext-a"}, conf.Extensions)
+
+	_, err = LoadOrgConfig([]byte("version: 99\n"))
+	a.Error(err)
+	_, err = LoadOrgConfig([]byte("extensions: []\n"))
+	a.Error(err)
+}
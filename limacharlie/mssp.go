@@ -0,0 +1,229 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// OrgAccessRequestID identifies a request for access to an Org.
+type OrgAccessRequestID = string
+
+type OrgAccessRequestStatus = string
+
+var OrgAccessRequestStatuses = struct {
+	Pending  OrgAccessRequestStatus
+	Accepted OrgAccessRequestStatus
+	Rejected OrgAccessRequestStatus
+}{
+	Pending:  "pending",
+	Accepted: "accepted",
+	Rejected: "rejected",
+}
+
+// OrgAccessRequest is a request from a group, like the one of an
+// MSSP managing Orgs on behalf of their owners, to be granted
+// access to an Org. Once accepted, the Org is part of the group.
+type OrgAccessRequest struct {
+	ID          OrgAccessRequestID     `json:"id"`
+	OID         string                 `json:"oid"`
+	GroupID     string                 `json:"gid"`
+	Requester   string                 `json:"requester,omitempty"`
+	Permissions []string               `json:"perms,omitempty"`
+	Status      OrgAccessRequestStatus `json:"status"`
+	// CreatedAt is a unix timestamp in seconds.
+	CreatedAt int64 `json:"created_at,omitempty"`
+}
+
+// RequestOrgAccess asks the owners of the Org oid to grant the
+// group access to it with the permissions, all of the group's
+// if none. The owners accept or reject it with
+// AcceptOrgAccessRequest and RejectOrgAccessRequest.
+func (c *Client) RequestOrgAccess(oid string, gid string, permissions []string) (OrgAccessRequest, error) {
+	resp := OrgAccessRequest{}
+	req := Dict{
+		"gid": gid,
+	}
+	if len(permissions) != 0 {
+		req["perms"] = strings.Join(permissions, ",")
+	}
+	request := makeDefaultRequest(&resp).withFormData(req)
+	if err := c.reliableRequest(http.MethodPost, fmt.Sprintf("orgs/%s/access_requests", oid), request); err != nil {
+		return OrgAccessRequest{}, err
+	}
+	return resp, nil
+}
+
+// OrgAccessRequests lists the requests for access to the Org,
+// sorted by ID.
+func (org *Organization) OrgAccessRequests() ([]OrgAccessRequest, error) {
+	resp := struct {
+		Requests []OrgAccessRequest `json:"requests"`
+	}{}
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("orgs/%s/access_requests", org.client.options.OID), makeDefaultRequest(&resp)); err != nil {
+		return nil, err
+	}
+	if resp.Requests == nil {
+		resp.Requests = []OrgAccessRequest{}
+	}
+	sort.Slice(resp.Requests, func(i, j int) bool {
+		return resp.Requests[i].ID < resp.Requests[j].ID
+	})
+	return resp.Requests, nil
+}
+
+// AcceptOrgAccessRequest grants the group of the request access to the Org.
+func (org *Organization) AcceptOrgAccessRequest(id OrgAccessRequestID) error {
+	return org.answerOrgAccessRequest(id, "accept")
+}
+
+// RejectOrgAccessRequest rejects a request for access to the Org.
+func (org *Organization) RejectOrgAccessRequest(id OrgAccessRequestID) error {
+	return org.answerOrgAccessRequest(id, "reject")
+}
+
+func (org *Organization) answerOrgAccessRequest(id OrgAccessRequestID, action string) error {
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withFormData(Dict{
+		"action": action,
+	})
+	if err := org.client.reliableRequest(http.MethodPost, fmt.Sprintf("orgs/%s/access_requests/%s", org.client.options.OID, id), request); err != nil {
+		if isRESTNotFound(err) {
+			return ErrorResourceNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// DelegatedOrg is an Org accessible to the credentials of a Client.
+type DelegatedOrg struct {
+	OID         string   `json:"oid"`
+	Permissions []string `json:"perms"`
+}
+
+// DelegatedOrgs lists the Orgs the credentials of the Client can
+// access, directly or through their groups, sorted by OID.
+func (c *Client) DelegatedOrgs() ([]DelegatedOrg, error) {
+	who, err := c.WhoAmI()
+	if err != nil {
+		return nil, err
+	}
+	oids := map[string]struct{}{}
+	for oid := range who.UserPermissions {
+		oids[oid] = struct{}{}
+	}
+	for _, oid := range who.Organizations {
+		oids[oid] = struct{}{}
+	}
+	orgs := []DelegatedOrg{}
+	for oid := range oids {
+		orgs = append(orgs, DelegatedOrg{
+			OID:         oid,
+			Permissions: who.EffectivePermissions(oid),
+		})
+	}
+	sort.Slice(orgs, func(i, j int) bool {
+		return orgs[i].OID < orgs[j].OID
+	})
+	return orgs, nil
+}
+
+// OrgSet is a set of Orgs accessed with the same credentials,
+// to operate on all of them, like an MSSP on the Orgs of its
+// customers.
+type OrgSet struct {
+	client *Client
+	OIDs   []string
+}
+
+// NewOrgSet returns the set of the Orgs accessed with the client.
+func NewOrgSet(c *Client, oids ...string) OrgSet {
+	return OrgSet{client: c, OIDs: oids}
+}
+
+// DelegatedOrgSet returns the set of the DelegatedOrgs
+// in which all the permissions are granted.
+func (c *Client) DelegatedOrgSet(permissions ...string) (OrgSet, error) {
+	orgs, err := c.DelegatedOrgs()
+	if err != nil {
+		return OrgSet{}, err
+	}
+	oids := []string{}
+	for _, o := range orgs {
+		isGranted := true
+		for _, p := range permissions {
+			if !arrayExistsInString(p, o.Permissions) {
+				isGranted = false
+				break
+			}
+		}
+		if isGranted {
+			oids = append(oids, o.OID)
+		}
+	}
+	return NewOrgSet(c, oids...), nil
+}
+
+// Org returns an Organization of the set.
+func (s OrgSet) Org(oid string) (*Organization, error) {
+	return NewOrganization(s.client.forOrg(oid))
+}
+
+// OrgSetResults are the errors of an operation on an
+// OrgSet by OID, nil for the Orgs where it succeeded.
+type OrgSetResults map[string]error
+
+// Succeeded returns the sorted OIDs where the operation succeeded.
+func (r OrgSetResults) Succeeded() []string {
+	return r.filter(func(err error) bool { return err == nil })
+}
+
+// Failed returns the sorted OIDs where the operation failed.
+func (r OrgSetResults) Failed() []string {
+	return r.filter(func(err error) bool { return err != nil })
+}
+
+func (r OrgSetResults) filter(cb func(error) bool) []string {
+	oids := []string{}
+	for oid, err := range r {
+		if cb(err) {
+			oids = append(oids, oid)
+		}
+	}
+	sort.Strings(oids)
+	return oids
+}
+
+// ForEach calls cb with every Org of the set, on up to concurrency
+// Orgs at the same time, 10 by default. A failure in an Org does
+// not stop the others.
+func (s OrgSet) ForEach(concurrency int, cb func(org *Organization) error) OrgSetResults {
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+	results := OrgSetResults{}
+	mutex := sync.Mutex{}
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, oid := range s.OIDs {
+		oid := oid
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			org, err := s.Org(oid)
+			if err == nil {
+				err = cb(org)
+			}
+			mutex.Lock()
+			defer mutex.Unlock()
+			results[oid] = err
+		}()
+	}
+	wg.Wait()
+	return results
+}
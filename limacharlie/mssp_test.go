@@ -0,0 +1,105 @@
+package limacharlie
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrgAccessRequests(t *testing.T) {
+	a := assert.New(t)
+	requests := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r.ParseForm()
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Form.Encode())
+		switch {
+		case r.Method == http.MethodGet:
+			return jsonResponse(http.StatusOK, `{"requests": [
+				{"id": "r2", "oid": "`+vcrTestOID+`", "gid": "g2", "status": "rejected"},
+				{"id": "r1", "oid": "`+vcrTestOID+`", "gid": "g1", "status": "pending", "perms": ["sensor.list"]}
+			]}`), nil
+		case strings.HasSuffix(r.URL.Path, "/missing"):
+			return jsonResponse(http.StatusNotFound, `{}`), nil
+		case strings.HasSuffix(r.URL.Path, "/access_requests"):
+			return jsonResponse(http.StatusOK, `{"id": "r3", "oid": "other", "gid": "g1", "status": "pending"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	req, err := org.client.RequestOrgAccess("other", "g1", []string{"sensor.list", "dr.list"})
+	a.NoError(err)
+	a.Equal(OrgAccessRequest{ID: "r3", OID: "other", GroupID: "g1", Status: OrgAccessRequestStatuses.Pending}, req)
+
+	pending, err := org.OrgAccessRequests()
+	a.NoError(err)
+	a.Len(pending, 2)
+	a.Equal("r1", pending[0].ID)
+	a.Equal([]string{"sensor.list"}, pending[0].Permissions)
+
+	a.NoError(org.AcceptOrgAccessRequest("r1"))
+	a.NoError(org.RejectOrgAccessRequest("r2"))
+	a.Equal(ErrorResourceNotFound, org.AcceptOrgAccessRequest("missing"))
+	a.Equal([]string{
+		"POST /v1/orgs/other/access_requests gid=g1&perms=sensor.list%2Cdr.list",
+		"GET /v1/orgs/" + vcrTestOID + "/access_requests ",
+		"POST /v1/orgs/" + vcrTestOID + "/access_requests/r1 action=accept",
+		"POST /v1/orgs/" + vcrTestOID + "/access_requests/r2 action=reject",
+	}, requests[:4])
+}
+
+func TestDelegatedOrgSet(t *testing.T) {
+	a := assert.New(t)
+	mutex := sync.Mutex{}
+	seen := map[string]int{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"ident": "mssp@example.com", "user_perms": {
+				"oid-b": ["sensor.list", "sensor.task"],
+				"oid-a": ["sensor.list"],
+				"oid-c": ["sensor.list", "sensor.task"]
+			}}`), nil
+		}
+		oid := strings.Split(r.URL.Path, "/")[3]
+		mutex.Lock()
+		seen[oid]++
+		mutex.Unlock()
+		if oid == "oid-c" {
+			return jsonResponse(http.StatusForbidden, `{"error": "forbidden"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"url": {}}`), nil
+	}))
+
+	orgs, err := org.client.DelegatedOrgs()
+	a.NoError(err)
+	a.Equal([]DelegatedOrg{
+		{OID: "oid-a", Permissions: []string{"sensor.list"}},
+		{OID: "oid-b", Permissions: []string{"sensor.list", "sensor.task"}},
+		{OID: "oid-c", Permissions: []string{"sensor.list", "sensor.task"}},
+	}, orgs)
+
+	set, err := org.client.DelegatedOrgSet("sensor.task")
+	a.NoError(err)
+	a.Equal([]string{"oid-b", "oid-c"}, set.OIDs)
+
+	results := set.ForEach(0, func(o *Organization) error {
+		_, err := o.GetURLs()
+		return err
+	})
+	a.Equal([]string{"oid-b"}, results.Succeeded())
+	a.Equal([]string{"oid-c"}, results.Failed())
+	a.Equal(1, seen["oid-b"])
+	a.Zero(seen["oid-a"])
+
+	results = NewOrgSet(org.client, "oid-a", "oid-b").ForEach(1, func(o *Organization) error {
+		if o.GetOID() == "oid-a" {
+			return errors.New("failed")
+		}
+		return nil
+	})
+	a.Equal([]string{"oid-a"}, results.Failed())
+	a.EqualError(results["oid-a"], "failed")
+}
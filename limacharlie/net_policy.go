@@ -0,0 +1,101 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+type NetPolicyName = string
+type NetPolicyType = string
+
+var NetPolicyTypes = struct {
+	Firewall NetPolicyType
+	DNS      NetPolicyType
+	Service  NetPolicyType
+	Capture  NetPolicyType
+}{
+	Firewall: "firewall",
+	DNS:      "dns",
+	Service:  "service",
+	Capture:  "capture",
+}
+
+// NetPolicy is a policy applied to the net sensors of an Org.
+type NetPolicy struct {
+	Name      NetPolicyName `json:"name,omitempty" yaml:"-"`
+	Type      NetPolicyType `json:"type" yaml:"type"`
+	Policy    Dict          `json:"policy" yaml:"policy"`
+	ExpiresOn int64         `json:"expires_on,omitempty" yaml:"expires_on,omitempty"`
+
+	OID       string `json:"oid,omitempty" yaml:"-"`
+	CreatedBy string `json:"created_by,omitempty" yaml:"-"`
+}
+
+type NetPoliciesByName = map[NetPolicyName]NetPolicy
+
+// EqualsContent compares the parts of the policies
+// that can be set in a config.
+func (p NetPolicy) EqualsContent(p2 NetPolicy) bool {
+	return syncNormalizers.NetPolicy.Equal(p, p2)
+}
+
+type netPoliciesResponse struct {
+	Policies NetPoliciesByName `json:"policies"`
+}
+
+func (org Organization) NetPolicies() (NetPoliciesByName, error) {
+	resp := netPoliciesResponse{}
+	request := makeDefaultRequest(&resp).withQueryData(Dict{
+		"oid": org.client.options.OID,
+	})
+	if err := org.client.reliableRequest(http.MethodGet, "net/policy", request); err != nil {
+		return nil, err
+	}
+	policies := NetPoliciesByName{}
+	for name, policy := range resp.Policies {
+		policy.Name = name
+		policies[name] = policy
+	}
+	return policies, nil
+}
+
+func (org Organization) NetPolicy(name NetPolicyName) (*NetPolicy, error) {
+	policies, err := org.NetPolicies()
+	if err != nil {
+		return nil, err
+	}
+	policy, ok := policies[name]
+	if !ok {
+		return nil, ErrorResourceNotFound
+	}
+	return &policy, nil
+}
+
+// NetPolicySet creates or replaces the policy with the same name.
+func (org Organization) NetPolicySet(policy NetPolicy) error {
+	serialized, err := json.Marshal(policy.Policy)
+	if err != nil {
+		return err
+	}
+	req := Dict{
+		"oid":    org.client.options.OID,
+		"name":   policy.Name,
+		"type":   policy.Type,
+		"policy": string(serialized),
+	}
+	if policy.ExpiresOn != 0 {
+		req["expires_on"] = policy.ExpiresOn
+	}
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withFormData(req)
+	return org.client.reliableRequest(http.MethodPost, "net/policy", request)
+}
+
+func (org Organization) NetPolicyDelete(name NetPolicyName) error {
+	resp := Dict{}
+	request := makeDefaultRequest(&resp).withFormData(Dict{
+		"oid":  org.client.options.OID,
+		"name": name,
+	})
+	return org.client.reliableRequest(http.MethodDelete, "net/policy", request)
+}
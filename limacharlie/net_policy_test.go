@@ -0,0 +1,50 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestNetPolicyConfig(t *testing.T) {
+	a := assert.New(t)
+
+	conf := `version: 3
+net-policy:
+  block-dns:
+    type: firewall
+    policy:
+      bpf_filter: port 53
+      is_allow: false
+      times:
+        - day_of_week_start: 2
+          day_of_week_end: 6
+          time_of_day_start: 800
+          time_of_day_end: 1900
+`
+	c := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(conf), &c))
+	a.Len(c.NetPolicies, 1)
+	policy := c.NetPolicies["block-dns"]
+	a.Equal(NetPolicyTypes.Firewall, policy.Type)
+
+	fromAPI := NetPolicy{}
+	a.NoError(yaml.Unmarshal([]byte(`type: firewall
+policy:
+  times:
+    - time_of_day_end: 1900.0
+      time_of_day_start: 800
+      day_of_week_end: 6
+      day_of_week_start: 2
+  is_allow: false
+  bpf_filter: port 53
+`), &fromAPI))
+	a.True(policy.EqualsContent(fromAPI))
+
+	fromAPI.Type = NetPolicyTypes.DNS
+	a.False(policy.EqualsContent(fromAPI))
+
+	merged := c.Merge(OrgConfig{NetPolicies: orgSyncNetPolicies{"other": fromAPI}})
+	a.Len(merged.NetPolicies, 2)
+}
@@ -0,0 +1,203 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+)
+
+// Normalizer puts elements in the canonical form used to compare
+// the configuration of an element with the one in an Org, so that
+// differences the API does not preserve do not cause updates.
+//
+// Fields are designated by their JSON path, like "filters/tags".
+// Like ContentHash, null values as well as empty lists and maps are
+// ignored and numbers are normalized.
+type Normalizer struct {
+	// Ignored fields are set by the API, like the author.
+	Ignored []string
+	// UnorderedLists are lists whose order is not meaningful.
+	UnorderedLists []string
+	// CaseInsensitive fields are strings, or lists of
+	// strings, which the API compares regardless of case.
+	CaseInsensitive []string
+	// Defaults are the values the API uses for missing fields,
+	// a field set to its default is the same as missing.
+	Defaults map[string]interface{}
+}
+
+// Normalize returns the canonical form of v as generic JSON values.
+func (n Normalizer) Normalize(v interface{}) (interface{}, error) {
+	b, err := canonicalJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	var generic interface{}
+	if err := d.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	for _, p := range n.Ignored {
+		walkNormalizerPath(generic, p, func(m map[string]interface{}, k string) {
+			delete(m, k)
+		})
+	}
+	for p, def := range n.Defaults {
+		canonicalDefault, err := canonicalJSON(def)
+		if err != nil {
+			return nil, err
+		}
+		walkNormalizerPath(generic, p, func(m map[string]interface{}, k string) {
+			if c, err := canonicalJSON(m[k]); err == nil && string(c) == string(canonicalDefault) {
+				delete(m, k)
+			}
+		})
+	}
+	for _, p := range n.CaseInsensitive {
+		walkNormalizerPath(generic, p, func(m map[string]interface{}, k string) {
+			m[k] = lowerCaseValue(m[k])
+		})
+	}
+	for _, p := range n.UnorderedLists {
+		walkNormalizerPath(generic, p, func(m map[string]interface{}, k string) {
+			if l, ok := m[k].([]interface{}); ok {
+				sortGenericList(l)
+			}
+		})
+	}
+
+	generic, _ = pruneCanonical(generic)
+	return generic, nil
+}
+
+// Equal returns true if a and b have the same canonical form.
+func (n Normalizer) Equal(a interface{}, b interface{}) bool {
+	na, err := n.Normalize(a)
+	if err != nil {
+		return false
+	}
+	nb, err := n.Normalize(b)
+	if err != nil {
+		return false
+	}
+	ba, err := json.Marshal(na)
+	if err != nil {
+		return false
+	}
+	bb, err := json.Marshal(nb)
+	if err != nil {
+		return false
+	}
+	return string(ba) == string(bb)
+}
+
+// walkNormalizerPath calls f with the map holding
+// the last key of the path, if the path exists.
+func walkNormalizerPath(v interface{}, path string, f func(m map[string]interface{}, k string)) {
+	keys := strings.Split(path, "/")
+	for i, k := range keys {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		if _, ok := m[k]; !ok {
+			return
+		}
+		if i == len(keys)-1 {
+			f(m, k)
+			return
+		}
+		v = m[k]
+	}
+}
+
+func lowerCaseValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case string:
+		return strings.ToLower(val)
+	case []interface{}:
+		for i, e := range val {
+			val[i] = lowerCaseValue(e)
+		}
+	}
+	return v
+}
+
+func sortGenericList(l []interface{}) {
+	keys := make([]string, len(l))
+	for i, e := range l {
+		b, _ := json.Marshal(e)
+		keys[i] = string(b)
+	}
+	sort.Sort(genericListSorter{l: l, keys: keys})
+}
+
+type genericListSorter struct {
+	l    []interface{}
+	keys []string
+}
+
+func (s genericListSorter) Len() int           { return len(s.l) }
+func (s genericListSorter) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s genericListSorter) Swap(i, j int) {
+	s.l[i], s.l[j] = s.l[j], s.l[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// syncNormalizers are the Normalizers of the elements synced.
+var syncNormalizers = struct {
+	DRRule          Normalizer
+	FPRule          Normalizer
+	Output          Normalizer
+	InstallationKey Normalizer
+	ExfilEvent      Normalizer
+	ExfilWatch      Normalizer
+	Integrity       Normalizer
+	Artifact        Normalizer
+	YaraRule        Normalizer
+	YaraSource      Normalizer
+	NetPolicy       Normalizer
+}{
+	DRRule: Normalizer{
+		UnorderedLists:  []string{"filters/tags", "filters/platforms"},
+		CaseInsensitive: []string{"filters/platforms"},
+	},
+	FPRule:          Normalizer{},
+	Output:          Normalizer{},
+	InstallationKey: Normalizer{},
+	ExfilEvent: Normalizer{
+		Ignored:         []string{"updated", "by"},
+		UnorderedLists:  []string{"events", "filters/tags", "filters/platforms"},
+		CaseInsensitive: []string{"filters/platforms"},
+	},
+	ExfilWatch: Normalizer{
+		Ignored:         []string{"updated", "by"},
+		UnorderedLists:  []string{"filters/tags", "filters/platforms"},
+		CaseInsensitive: []string{"operator", "filters/platforms"},
+	},
+	Integrity: Normalizer{
+		Ignored:         []string{"updated", "by"},
+		UnorderedLists:  []string{"patterns", "filters/tags", "filters/platforms"},
+		CaseInsensitive: []string{"filters/platforms"},
+	},
+	Artifact: Normalizer{
+		Ignored:         []string{"updated", "by"},
+		UnorderedLists:  []string{"patterns", "filters/tags", "filters/platforms", "filters/exclude_tags"},
+		CaseInsensitive: []string{"filters/platforms", "mode"},
+		Defaults:        map[string]interface{}{"mode": ArtifactRuleModes.Batch, "max_file_size": 0},
+	},
+	YaraRule: Normalizer{
+		Ignored:         []string{"updated", "by"},
+		UnorderedLists:  []string{"sources", "filters/tags", "filters/platforms"},
+		CaseInsensitive: []string{"filters/platforms"},
+	},
+	YaraSource: Normalizer{
+		Ignored: []string{"updated", "by"},
+	},
+	NetPolicy: Normalizer{
+		Ignored:  []string{"name", "oid", "created_by"},
+		Defaults: map[string]interface{}{"expires_on": 0},
+	},
+}
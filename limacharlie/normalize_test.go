@@ -0,0 +1,97 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizer(t *testing.T) {
+	a := assert.New(t)
+	n := Normalizer{
+		Ignored:         []string{"by"},
+		UnorderedLists:  []string{"filters/tags"},
+		CaseInsensitive: []string{"filters/platforms"},
+		Defaults:        map[string]interface{}{"ttl": 0},
+	}
+
+	a.True(n.Equal(Dict{
+		"by":      "alice",
+		"ttl":     0,
+		"filters": Dict{"tags": []string{"b", "a"}, "platforms": []string{"Windows"}},
+	}, Dict{
+		"filters": Dict{"tags": []string{"a", "b"}, "platforms": []string{"windows"}},
+	}))
+	a.False(n.Equal(Dict{"ttl": 1}, Dict{}))
+
+	// Only the lists designated are unordered.
+	a.False(n.Equal(Dict{"path": []string{"a", "b"}}, Dict{"path": []string{"b", "a"}}))
+
+	normalized, err := n.Normalize(Dict{"by": "alice", "filters": Dict{"tags": []string{}}})
+	a.NoError(err)
+	a.Equal(map[string]interface{}{}, normalized)
+}
+
+func TestSyncElementsEquality(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(ExfilRuleEvent{
+		Events:  []string{"NEW_PROCESS", "DNS_REQUEST"},
+		Filters: ExfilEventFilters{Tags: []string{"t2", "t1"}},
+	}.EqualsContent(ExfilRuleEvent{
+		LastUpdated: 1234,
+		CreatedBy:   "someone",
+		Events:      []string{"DNS_REQUEST", "NEW_PROCESS"},
+		Filters:     ExfilEventFilters{Tags: []string{"t1", "t2"}, Platforms: []Platform{}},
+	}))
+	a.False(ExfilRuleEvent{Events: []string{"NEW_PROCESS"}}.EqualsContent(ExfilRuleEvent{Events: []string{"DNS_REQUEST"}}))
+
+	a.True(ExfilRuleWatch{Event: "NEW_PROCESS", Path: []string{"event", "FILE_PATH"}}.EqualsContent(ExfilRuleWatch{
+		Event:     "NEW_PROCESS",
+		Path:      []string{"event", "FILE_PATH"},
+		CreatedBy: "someone",
+	}))
+	a.False(ExfilRuleWatch{Path: []string{"event", "FILE_PATH"}}.EqualsContent(ExfilRuleWatch{Path: []string{"FILE_PATH", "event"}}))
+
+	a.True(OrgSyncIntegrityRule{Patterns: []string{"/b", "/a"}}.EqualsContent(IntegrityRule{
+		Patterns:  []string{"/a", "/b"},
+		CreatedBy: "someone",
+	}))
+
+	a.True(OrgSyncArtifactRule{DaysRetentions: 30, Tags: []string{"x"}}.EqualsContent(ArtifactRule{
+		By:             "someone",
+		LastUpdated:    1,
+		DaysRetentions: 30,
+		Filters:        ArtifactRuleFilter{Tags: []string{"x"}, Platforms: []Platform{}},
+	}))
+
+	a.True(OrgSyncArtifactRule{Tags: []string{"x"}, ExcludeTags: []string{"b", "a"}, MaxFileSize: 1024}.EqualsContent(ArtifactRule{
+		MaxFileSize: 1024,
+		Mode:        ArtifactRuleModes.Batch,
+		Filters:     ArtifactRuleFilter{Tags: []string{"x"}, ExcludeTags: []string{"a", "b"}},
+	}))
+	a.False(OrgSyncArtifactRule{Mode: ArtifactRuleModes.Streaming}.EqualsContent(ArtifactRule{}))
+	a.False(OrgSyncArtifactRule{MaxFileSize: 1024}.EqualsContent(ArtifactRule{}))
+
+	a.True(YaraRule{Sources: []string{"s2", "s1"}}.EqualsContent(YaraRule{Sources: []string{"s1", "s2"}, Author: "someone"}))
+
+	a.True(NetPolicy{Name: "p", Type: NetPolicyTypes.DNS, OID: "oid"}.EqualsContent(NetPolicy{Type: NetPolicyTypes.DNS}))
+
+	isEnabled := true
+	a.True(CoreDRRule{
+		Detect:  Dict{"op": "exists", "path": "event/FILE_PATH", "times": 1},
+		Filters: &DRRuleTargets{Tags: []string{"b", "a"}},
+	}.Equal(CoreDRRule{
+		Namespace: "general",
+		IsEnabled: &isEnabled,
+		Detect:    Dict{"path": "event/FILE_PATH", "op": "exists", "times": 1.0},
+		Filters:   &DRRuleTargets{Tags: []string{"a", "b"}},
+	}))
+	a.False(CoreDRRule{Namespace: "managed"}.Equal(CoreDRRule{}))
+
+	a.True(OrgSyncFPRule{Detection: Dict{"op": "is", "value": 1}, Description: "d"}.Equals(FPRule{Detection: Dict{"value": 1.0, "op": "is"}, Description: "d"}))
+	a.False(OrgSyncFPRule{Detection: Dict{"op": "is"}, Description: "d"}.Equals(FPRule{Detection: Dict{"op": "is"}}))
+
+	a.True(InstallationKey{Description: "k", Tags: []string{"a"}}.EqualsContent(InstallationKey{Description: "k"}))
+	a.False(InstallationKey{Description: "k"}.EqualsContent(InstallationKey{Description: "k2"}))
+}
@@ -0,0 +1,252 @@
+package limacharlie
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OAuthTokenSource provides the identity token of a user, exchanged
+// for a JWT by clients without an API key, see ClientOptions.OAuth.
+type OAuthTokenSource interface {
+	IDToken() (string, error)
+}
+
+// StaticOAuthToken is an identity token obtained by other means,
+// like from the Python CLI, used until it expires.
+type StaticOAuthToken string
+
+func (t StaticOAuthToken) IDToken() (string, error) {
+	if t == "" {
+		return "", errors.New("empty identity token")
+	}
+	return string(t), nil
+}
+
+// oidcRefreshMargin is how long before its expiry a token is refreshed.
+const oidcRefreshMargin = time.Minute
+
+// OIDCProvider is the identity provider users log in with.
+type OIDCProvider struct {
+	AuthURL  string `json:"auth_url" yaml:"auth_url"`
+	TokenURL string `json:"token_url" yaml:"token_url"`
+	ClientID string `json:"client_id" yaml:"client_id"`
+	// ClientSecret, only for providers requiring one from native apps.
+	ClientSecret string `json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	// Scopes requested, "openid email" by default.
+	Scopes []string `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+
+	// HTTPClient of the requests to the TokenURL, a default one if nil.
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+}
+
+// OIDCToken are the tokens of a user logged in with OIDCLogin,
+// which can be stored to resume the session later.
+type OIDCToken struct {
+	IDToken      string    `json:"id_token" yaml:"id_token"`
+	RefreshToken string    `json:"refresh_token,omitempty" yaml:"refresh_token,omitempty"`
+	Expiry       time.Time `json:"expiry,omitempty" yaml:"expiry,omitempty"`
+}
+
+func (p OIDCProvider) httpClient() *http.Client {
+	if p.HTTPClient != nil {
+		return p.HTTPClient
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// token requests tokens from the TokenURL.
+func (p OIDCProvider) token(form url.Values) (OIDCToken, error) {
+	form.Set("client_id", p.ClientID)
+	if p.ClientSecret != "" {
+		form.Set("client_secret", p.ClientSecret)
+	}
+	resp, err := p.httpClient().PostForm(p.TokenURL, form)
+	if err != nil {
+		return OIDCToken{}, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return OIDCToken{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return OIDCToken{}, fmt.Errorf("token request: %s: %s", resp.Status, string(body))
+	}
+	tokens := struct {
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int64  `json:"expires_in"`
+	}{}
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return OIDCToken{}, err
+	}
+	if tokens.IDToken == "" {
+		return OIDCToken{}, errors.New("no id_token in the token response")
+	}
+	t := OIDCToken{IDToken: tokens.IDToken, RefreshToken: tokens.RefreshToken}
+	if tokens.ExpiresIn > 0 {
+		t.Expiry = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+func oidcRandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// OIDCLogin logs a user in through their browser with the authorization
+// code flow and PKCE. It calls open with the URL the user must visit,
+// like to launch their browser or print it, and waits for the provider
+// to redirect them to a local listener until the context is done.
+func OIDCLogin(ctx context.Context, provider OIDCProvider, open func(authURL string) error) (OIDCToken, error) {
+	verifier, err := oidcRandomString()
+	if err != nil {
+		return OIDCToken{}, err
+	}
+	state, err := oidcRandomString()
+	if err != nil {
+		return OIDCToken{}, err
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return OIDCToken{}, err
+	}
+	defer listener.Close()
+	redirectURL := fmt.Sprintf("http://%s/callback", listener.Addr().String())
+
+	scopes := provider.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{"openid", "email"}
+	}
+	authURL, err := url.Parse(provider.AuthURL)
+	if err != nil {
+		return OIDCToken{}, err
+	}
+	q := authURL.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", provider.ClientID)
+	q.Set("redirect_uri", redirectURL)
+	q.Set("scope", strings.Join(scopes, " "))
+	q.Set("state", state)
+	q.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	q.Set("code_challenge_method", "S256")
+	authURL.RawQuery = q.Encode()
+
+	type callback struct {
+		code string
+		err  error
+	}
+	callbacks := make(chan callback, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/callback" {
+			http.NotFound(w, r)
+			return
+		}
+		cb := callback{}
+		q := r.URL.Query()
+		switch {
+		case q.Get("state") != state:
+			cb.err = errors.New("invalid state in the login callback")
+		case q.Get("error") != "":
+			cb.err = fmt.Errorf("login failed: %s %s", q.Get("error"), q.Get("error_description"))
+		case q.Get("code") == "":
+			cb.err = errors.New("no code in the login callback")
+		default:
+			cb.code = q.Get("code")
+		}
+		if cb.err != nil {
+			http.Error(w, cb.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "Logged in, you can close this window.")
+		}
+		select {
+		case callbacks <- cb:
+		default:
+		}
+	})}
+	go server.Serve(listener)
+	defer server.Close()
+
+	if err := open(authURL.String()); err != nil {
+		return OIDCToken{}, err
+	}
+	select {
+	case <-ctx.Done():
+		return OIDCToken{}, ctx.Err()
+	case cb := <-callbacks:
+		if cb.err != nil {
+			return OIDCToken{}, cb.err
+		}
+		return provider.token(url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {cb.code},
+			"redirect_uri":  {redirectURL},
+			"code_verifier": {verifier},
+		})
+	}
+}
+
+// OIDCTokenSource is an OAuthTokenSource refreshing the
+// identity token of a user before it expires.
+type OIDCTokenSource struct {
+	provider OIDCProvider
+
+	mutex sync.Mutex
+	token OIDCToken
+}
+
+// NewOIDCTokenSource returns a source of the identity tokens
+// of a user, like logged in with OIDCLogin.
+func NewOIDCTokenSource(provider OIDCProvider, token OIDCToken) *OIDCTokenSource {
+	return &OIDCTokenSource{provider: provider, token: token}
+}
+
+// IDToken returns the identity token, refreshed if about to expire.
+func (s *OIDCTokenSource) IDToken() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.token.Expiry.IsZero() || time.Now().Add(oidcRefreshMargin).Before(s.token.Expiry) {
+		return s.token.IDToken, nil
+	}
+	if s.token.RefreshToken == "" {
+		return "", errors.New("identity token expired, log in again")
+	}
+	t, err := s.provider.token(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {s.token.RefreshToken},
+	})
+	if err != nil {
+		return "", err
+	}
+	if t.RefreshToken == "" {
+		// Providers may not rotate refresh tokens.
+		t.RefreshToken = s.token.RefreshToken
+	}
+	s.token = t
+	return s.token.IDToken, nil
+}
+
+// Token returns the current tokens, to store them.
+func (s *OIDCTokenSource) Token() OIDCToken {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.token
+}
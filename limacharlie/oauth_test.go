@@ -0,0 +1,108 @@
+package limacharlie
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshJWTWithOAuth(t *testing.T) {
+	a := assert.New(t)
+	form := url.Values{}
+	c := &Client{
+		options: ClientOptions{
+			UID:   "u1",
+			OAuth: StaticOAuthToken("id-token"),
+			Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				a.NoError(r.ParseForm())
+				form = r.PostForm
+				return jsonResponse(http.StatusOK, `{"jwt": "user-jwt"}`), nil
+			}),
+		},
+		logger: &LCLoggerEmpty{},
+	}
+	a.NoError(c.options.validateMinimumRequirements())
+	jwt, err := c.RefreshJWT(0)
+	a.NoError(err)
+	a.Equal("user-jwt", jwt)
+	a.Equal("id-token", form.Get("fb_auth"))
+	a.Equal("u1", form.Get("uid"))
+	a.Empty(form.Get("secret"))
+
+	// The identity is shared by the clients of the other Orgs.
+	jwt, err = c.forOrg("o2").RefreshJWT(0)
+	a.NoError(err)
+	a.Equal("user-jwt", jwt)
+	a.Equal("o2", form.Get("oid"))
+
+	c.options.OAuth = nil
+	_, err = c.RefreshJWT(0)
+	a.Equal(ErrorNoAPIKeyConfigured, err)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	a := assert.New(t)
+	challenge := ""
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.NoError(r.ParseForm())
+		a.Equal("cli", r.PostForm.Get("client_id"))
+		switch r.PostForm.Get("grant_type") {
+		case "authorization_code":
+			a.Equal("the-code", r.PostForm.Get("code"))
+			verifier := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+			a.Equal(challenge, base64.RawURLEncoding.EncodeToString(verifier[:]))
+			fmt.Fprint(w, `{"id_token": "id1", "refresh_token": "r1", "expires_in": 1}`)
+		case "refresh_token":
+			a.Equal("r1", r.PostForm.Get("refresh_token"))
+			fmt.Fprint(w, `{"id_token": "id2", "expires_in": 3600}`)
+		default:
+			http.Error(w, "bad grant", http.StatusBadRequest)
+		}
+	}))
+	defer tokenServer.Close()
+
+	provider := OIDCProvider{AuthURL: "https://idp/authorize", TokenURL: tokenServer.URL, ClientID: "cli"}
+	token, err := OIDCLogin(context.Background(), provider, func(authURL string) error {
+		u, err := url.Parse(authURL)
+		a.NoError(err)
+		q := u.Query()
+		a.Equal("cli", q.Get("client_id"))
+		a.Equal("S256", q.Get("code_challenge_method"))
+		a.Equal("openid email", q.Get("scope"))
+		challenge = q.Get("code_challenge")
+		// The browser is redirected by the provider.
+		go http.Get(q.Get("redirect_uri") + "?code=the-code&state=" + url.QueryEscape(q.Get("state")))
+		return nil
+	})
+	a.NoError(err)
+	a.Equal("id1", token.IDToken)
+	a.Equal("r1", token.RefreshToken)
+
+	source := NewOIDCTokenSource(provider, token)
+	id, err := source.IDToken()
+	a.NoError(err)
+	a.Equal("id2", id)
+	a.Equal("r1", source.Token().RefreshToken)
+	a.True(source.Token().Expiry.After(time.Now().Add(time.Hour - time.Minute)))
+
+	// A forged callback fails the login.
+	_, err = OIDCLogin(context.Background(), provider, func(authURL string) error {
+		u, _ := url.Parse(authURL)
+		go http.Get(u.Query().Get("redirect_uri") + "?code=the-code&state=forged")
+		return nil
+	})
+	a.Error(err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = OIDCLogin(ctx, provider, func(string) error { return nil })
+	a.Equal(context.DeadlineExceeded, err)
+}
@@ -0,0 +1,156 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CloneOrgConfigOptions configures how the config of an Org is cloned.
+type CloneOrgConfigOptions struct {
+	// Sync selects the sections cloned and how they are pushed
+	// to the destination, like IsForce or IsDryRun.
+	Sync SyncOptions
+
+	// Filter, if set, is called with the section of the config, like
+	// "rules" or "outputs", and the name of each of its elements, the
+	// element is not cloned if it returns false. Elements of the "exfil",
+	// "yara" and "hives" sections are named "<sub-section>/<name>", like
+	// "sources/my-source" or "lookup/my-lookup".
+	Filter func(section string, name string) bool
+
+	// Replacements are applied to every value of the config,
+	// like the bucket of an output specific to the source Org.
+	Replacements map[string]string
+
+	// Transform, if set, is called with the config
+	// after filtering and replacements, before it is pushed.
+	Transform func(conf *OrgConfig) error
+}
+
+// cloneNestedSections are the sections holding sub-sections of elements.
+var cloneNestedSections = map[string]bool{
+	"exfil": true,
+	"yara":  true,
+	"hives": true,
+}
+
+// forOrg returns a client with the same credentials for another Org.
+func (c *Client) forOrg(oid string) *Client {
+	opts := c.options
+	opts.OID = oid
+	// The JWT is specific to the Org.
+	opts.JWT = ""
+	return &Client{
+		options:   opts,
+		logger:    c.logger,
+		transport: c.transport,
+		limiter:   c.limiter,
+	}
+}
+
+// CloneOrgConfig fetches the config of the source Org and pushes it
+// to the destination Org, like when creating a tenant from a template.
+// The credentials must have access to both Orgs.
+func (c *Client) CloneOrgConfig(srcOID string, dstOID string, opts CloneOrgConfigOptions) ([]OrgSyncOperation, error) {
+	src, err := NewOrganization(c.forOrg(srcOID))
+	if err != nil {
+		return nil, err
+	}
+	dst, err := NewOrganization(c.forOrg(dstOID))
+	if err != nil {
+		return nil, err
+	}
+
+	conf, err := src.SyncFetch(opts.Sync)
+	if err != nil {
+		return nil, fmt.Errorf("fetch %s: %v", srcOID, err)
+	}
+	if conf, err = filterOrgConfig(conf, opts); err != nil {
+		return nil, err
+	}
+	if opts.Transform != nil {
+		if err := opts.Transform(&conf); err != nil {
+			return nil, err
+		}
+	}
+	return dst.SyncPush(conf, opts.Sync)
+}
+
+// filterOrgConfig applies the filter and replacements of the options.
+func filterOrgConfig(conf OrgConfig, opts CloneOrgConfigOptions) (OrgConfig, error) {
+	if opts.Filter == nil && len(opts.Replacements) == 0 {
+		return conf, nil
+	}
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return conf, err
+	}
+	d := json.NewDecoder(strings.NewReader(string(b)))
+	d.UseNumber()
+	generic := map[string]interface{}{}
+	if err := d.Decode(&generic); err != nil {
+		return conf, err
+	}
+
+	if opts.Filter != nil {
+		for section, elements := range generic {
+			m, ok := elements.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if !cloneNestedSections[section] {
+				filterCloneElements(m, section, "", opts.Filter)
+				continue
+			}
+			for sub, subElements := range m {
+				if sm, ok := subElements.(map[string]interface{}); ok {
+					filterCloneElements(sm, section, sub+"/", opts.Filter)
+				}
+			}
+		}
+	}
+
+	replaced := replaceCloneValues(generic, opts.Replacements)
+	if b, err = json.Marshal(replaced); err != nil {
+		return conf, err
+	}
+	filtered := OrgConfig{}
+	if err := json.Unmarshal(b, &filtered); err != nil {
+		return conf, err
+	}
+	filtered.Includes = conf.Includes
+	return filtered, nil
+}
+
+func filterCloneElements(elements map[string]interface{}, section string, prefix string, filter func(string, string) bool) {
+	for name := range elements {
+		if !filter(section, prefix+name) {
+			delete(elements, name)
+		}
+	}
+}
+
+func replaceCloneValues(v interface{}, replacements map[string]string) interface{} {
+	if len(replacements) == 0 {
+		return v
+	}
+	switch val := v.(type) {
+	case string:
+		for from, to := range replacements {
+			if from != "" {
+				val = strings.ReplaceAll(val, from, to)
+			}
+		}
+		return val
+	case map[string]interface{}:
+		for k, e := range val {
+			val[k] = replaceCloneValues(e, replacements)
+		}
+	case []interface{}:
+		for i, e := range val {
+			val[i] = replaceCloneValues(e, replacements)
+		}
+	}
+	return v
+}
@@ -0,0 +1,58 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterOrgConfig(t *testing.T) {
+	a := assert.New(t)
+	isEnabled := true
+	conf := OrgConfig{
+		Version: OrgConfigLatestVersion,
+		DRRules: orgSyncDRRules{
+			"keep":     {Detect: Dict{"op": "is"}, Response: List{}, IsEnabled: &isEnabled},
+			"internal": {Detect: Dict{"op": "is"}, Response: List{}, IsEnabled: &isEnabled},
+		},
+		Outputs: orgSyncOutputs{
+			"archive": {Name: "archive", Module: OutputTypes.S3, Type: OutputType.Event, Bucket: "golden-bucket", SecondsPerFile: 300, PrefixData: true},
+		},
+		Hives: orgSyncHives{
+			"lookup": {
+				"keep":     {Data: map[string]interface{}{"k": "golden-value"}},
+				"internal": {Data: map[string]interface{}{"k": "v"}},
+			},
+		},
+	}
+
+	filtered, err := filterOrgConfig(conf, CloneOrgConfigOptions{
+		Filter: func(section string, name string) bool {
+			return name != "internal" && name != "lookup/internal"
+		},
+		Replacements: map[string]string{"golden": "tenant1"},
+	})
+	a.NoError(err)
+	a.Contains(filtered.DRRules, "keep")
+	a.NotContains(filtered.DRRules, "internal")
+	a.Equal("tenant1-bucket", filtered.Outputs["archive"].Bucket)
+	a.Equal(300, filtered.Outputs["archive"].SecondsPerFile)
+	a.True(filtered.Outputs["archive"].PrefixData)
+	a.Contains(filtered.Hives["lookup"], "keep")
+	a.NotContains(filtered.Hives["lookup"], "internal")
+	a.Equal("tenant1-value", filtered.Hives["lookup"]["keep"].Data["k"])
+
+	// The source config is left untouched.
+	a.Equal("golden-bucket", conf.Outputs["archive"].Bucket)
+	a.Len(conf.DRRules, 2)
+}
+
+func TestClientForOrg(t *testing.T) {
+	a := assert.New(t)
+	c := &Client{options: ClientOptions{OID: "src", APIKey: "key", JWT: "jwt"}}
+	dst := c.forOrg("dst")
+	a.Equal("dst", dst.options.OID)
+	a.Equal("key", dst.options.APIKey)
+	a.Empty(dst.options.JWT)
+	a.Equal("src", c.options.OID)
+}
@@ -0,0 +1,144 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LazyOrgConfig is an OrgConfig in JSON decoded one section at a
+// time, like "rules" or "outputs", on first access. Tools going over
+// the configs of many Orgs, with thousands of rules each, only hold
+// the undecoded bytes of the sections they do not use.
+type LazyOrgConfig struct {
+	mutex  sync.Mutex
+	raw    map[string]json.RawMessage
+	config OrgConfig
+}
+
+// DecodeOrgConfig reads an OrgConfig in JSON, like one fetched with
+// SyncFetch and marshaled, from r as a stream, leaving its sections
+// undecoded until loaded.
+func DecodeOrgConfig(r io.Reader) (*LazyOrgConfig, error) {
+	decoder := json.NewDecoder(r)
+	if err := expectJSONDelim(decoder, '{'); err != nil {
+		return nil, err
+	}
+	c := &LazyOrgConfig{raw: map[string]json.RawMessage{}}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("invalid config key: %v", token)
+		}
+		raw := json.RawMessage{}
+		if err := decoder.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("%s: %v", key, err)
+		}
+		if _, ok := orgConfigSectionField(key); ok {
+			c.raw[key] = raw
+		}
+	}
+	if err := expectJSONDelim(decoder, '}'); err != nil {
+		return nil, err
+	}
+	if _, err := c.Load("version"); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func expectJSONDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("invalid config: expected %s, got %v", delim, token)
+	}
+	return nil
+}
+
+// orgConfigSectionField returns the index of the OrgConfig
+// field of a section by its JSON key.
+func orgConfigSectionField(key string) (int, bool) {
+	t := reflect.TypeOf(OrgConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		if name := strings.Split(tag, ",")[0]; name == key {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Sections returns the sorted keys of the sections of
+// the config not loaded yet.
+func (c *LazyOrgConfig) Sections() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	keys := []string{}
+	for key := range c.raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Load decodes the sections by key, all of them if none, and returns
+// the config with the sections loaded so far. Sections absent from
+// the config are left empty. The undecoded bytes of a section are
+// released once loaded.
+func (c *LazyOrgConfig) Load(sections ...string) (OrgConfig, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if len(sections) == 0 {
+		for key := range c.raw {
+			sections = append(sections, key)
+		}
+	}
+	v := reflect.ValueOf(&c.config).Elem()
+	for _, key := range sections {
+		i, ok := orgConfigSectionField(key)
+		if !ok {
+			return c.config, fmt.Errorf("unknown config section: %s", key)
+		}
+		raw, ok := c.raw[key]
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, v.Field(i).Addr().Interface()); err != nil {
+			return c.config, fmt.Errorf("%s: %v", key, err)
+		}
+		delete(c.raw, key)
+	}
+	return c.config, nil
+}
+
+// DRRules returns the D&R rules of the config, loading them first.
+func (c *LazyOrgConfig) DRRules() (orgSyncDRRules, error) {
+	conf, err := c.Load("rules")
+	return conf.DRRules, err
+}
+
+// Outputs returns the outputs of the config, loading them first.
+func (c *LazyOrgConfig) Outputs() (orgSyncOutputs, error) {
+	conf, err := c.Load("outputs")
+	return conf.Outputs, err
+}
+
+// Hives returns the hive records of the config, loading them first.
+func (c *LazyOrgConfig) Hives() (orgSyncHives, error) {
+	conf, err := c.Load("hives")
+	return conf.Hives, err
+}
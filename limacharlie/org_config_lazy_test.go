@@ -0,0 +1,57 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeOrgConfig(t *testing.T) {
+	a := assert.New(t)
+	conf := OrgConfig{
+		Version: OrgConfigLatestVersion,
+		DRRules: orgSyncDRRules{
+			"r1": CoreDRRule{Namespace: "general", Detect: Dict{"op": "is"}, Response: List{map[string]interface{}{"action": "report"}}},
+		},
+		Outputs: orgSyncOutputs{
+			"o1": OutputConfig{Module: OutputTypes.Syslog, Type: OutputType.Detect, DestinationHost: "h:1"},
+		},
+	}
+	b, err := json.Marshal(conf)
+	a.NoError(err)
+
+	lazy, err := DecodeOrgConfig(strings.NewReader(string(b)))
+	a.NoError(err)
+	a.Equal([]string{"outputs", "rules"}, lazy.Sections())
+
+	rules, err := lazy.DRRules()
+	a.NoError(err)
+	a.Equal(conf.DRRules, rules)
+	a.Equal([]string{"outputs"}, lazy.Sections())
+
+	// Loading again is a no-op.
+	partial, err := lazy.Load("rules", "exfil")
+	a.NoError(err)
+	a.Equal(OrgConfigLatestVersion, partial.Version)
+	a.Nil(partial.Outputs)
+
+	all, err := lazy.Load()
+	a.NoError(err)
+	a.Equal(conf, all)
+	a.Empty(lazy.Sections())
+
+	_, err = lazy.Load("nope")
+	a.Error(err)
+}
+
+func TestDecodeOrgConfigInvalid(t *testing.T) {
+	a := assert.New(t)
+	_, err := DecodeOrgConfig(strings.NewReader(`[]`))
+	a.Error(err)
+	lazy, err := DecodeOrgConfig(strings.NewReader(`{"version": 3, "rules": {"r1": 1}}`))
+	a.NoError(err)
+	_, err = lazy.DRRules()
+	a.Error(err)
+}
@@ -1,8 +1,11 @@
 package limacharlie
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 )
 
 type OrgValueInfo struct {
@@ -13,6 +16,80 @@ type OrgValueInfo struct {
 type OrgValueName = string
 type OrgValue = string
 
+// OrgValueNames are the well known Org Values.
+var OrgValueNames = struct {
+	VirusTotal OrgValueName
+	OTX        OrgValueName
+	Domain     OrgValueName
+	Shodan     OrgValueName
+	PagerDuty  OrgValueName
+	Twilio     OrgValueName
+}{
+	VirusTotal: "vt",
+	OTX:        "otx",
+	Domain:     "domain",
+	Shodan:     "shodan",
+	PagerDuty:  "pagerduty",
+	Twilio:     "twilio",
+}
+
+// OrgValueMetadata describes an Org Value supported by the API.
+type OrgValueMetadata struct {
+	Name        OrgValueName `json:"config" yaml:"config"`
+	Description string       `json:"description,omitempty" yaml:"description,omitempty"`
+	// IsSecret is set for values like API keys
+	// which are masked in sync results and logs.
+	IsSecret bool `json:"is_secret" yaml:"is_secret"`
+}
+
+// defaultOrgValueCatalog is used when the
+// catalog cannot be fetched from the API.
+var defaultOrgValueCatalog = []OrgValueMetadata{
+	{Name: OrgValueNames.VirusTotal, Description: "VirusTotal API key.", IsSecret: true},
+	{Name: OrgValueNames.OTX, Description: "AlienVault OTX API key.", IsSecret: true},
+	{Name: OrgValueNames.Domain, Description: "Domain of the Org."},
+	{Name: OrgValueNames.Shodan, Description: "Shodan API key.", IsSecret: true},
+	{Name: OrgValueNames.PagerDuty, Description: "PagerDuty routing key.", IsSecret: true},
+	{Name: OrgValueNames.Twilio, Description: "Twilio credentials.", IsSecret: true},
+}
+
+const orgValueMask = "********"
+
+// MaskOrgValue returns the value as it should be displayed,
+// masked if the Org Value is a secret.
+func (m OrgValueMetadata) MaskOrgValue(value OrgValue) string {
+	if !m.IsSecret || value == "" {
+		return value
+	}
+	return orgValueMask
+}
+
+// OrgValueCatalog lists the Org Values supported by the API.
+func (org Organization) OrgValueCatalog() ([]OrgValueMetadata, error) {
+	resp := struct {
+		Configs []OrgValueMetadata `json:"configs"`
+	}{}
+	request := makeDefaultRequest(&resp).withCache()
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("configs/%s", org.client.options.OID), request); err != nil {
+		return nil, err
+	}
+	return resp.Configs, nil
+}
+
+// orgValueCatalog is the catalog from the API or,
+// if it cannot be fetched, the well known Org Values.
+func (org Organization) orgValueCatalog() map[OrgValueName]OrgValueMetadata {
+	catalog, err := org.OrgValueCatalog()
+	if err != nil || len(catalog) == 0 {
+		catalog = defaultOrgValueCatalog
+	}
+	m := map[OrgValueName]OrgValueMetadata{}
+	for _, md := range catalog {
+		m[md.Name] = md
+	}
+	return m
+}
+
 // Get an Org Value from a specific org.
 func (org Organization) OrgValueGet(name string) (*OrgValueInfo, error) {
 	resp := OrgValueInfo{}
@@ -34,3 +111,62 @@ func (org Organization) OrgValueSet(name string, value string) error {
 	}
 	return nil
 }
+
+// OrgValueGetJSON gets an Org Value holding JSON, like
+// credentials, and unmarshals it into out.
+func (org Organization) OrgValueGetJSON(name string, out interface{}) error {
+	ov, err := org.OrgValueGet(name)
+	if err != nil {
+		return err
+	}
+	if ov.Value == "" {
+		return ErrorResourceNotFound
+	}
+	if err := json.Unmarshal([]byte(ov.Value), out); err != nil {
+		return fmt.Errorf("org value %s is not valid json: %v", name, err)
+	}
+	return nil
+}
+
+// OrgValueSetJSON sets an Org Value to the JSON of in.
+func (org Organization) OrgValueSetJSON(name string, in interface{}) error {
+	b, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return org.OrgValueSet(name, string(b))
+}
+
+// OrgValueGetString gets an Org Value, returning
+// ErrorResourceNotFound if it is not set.
+func (org Organization) OrgValueGetString(name string) (string, error) {
+	ov, err := org.OrgValueGet(name)
+	if err != nil {
+		return "", err
+	}
+	if ov.Value == "" {
+		return "", ErrorResourceNotFound
+	}
+	return ov.Value, nil
+}
+
+// orgValueMetadata returns the metadata of an Org Value,
+// assuming values missing from the catalog are secrets.
+func orgValueMetadata(catalog map[OrgValueName]OrgValueMetadata, name OrgValueName) OrgValueMetadata {
+	if md, ok := catalog[name]; ok {
+		return md
+	}
+	return OrgValueMetadata{Name: name, IsSecret: true}
+}
+
+// maskOrgValueError removes a secret value from an error
+// which may echo the request it originates from.
+func maskOrgValueError(err error, md OrgValueMetadata, value OrgValue) error {
+	if err == nil || !md.IsSecret || value == "" || !strings.Contains(err.Error(), value) {
+		return err
+	}
+	if restErr, ok := err.(RESTError); ok {
+		return NewRESTError(strings.ReplaceAll(restErr.s, value, md.MaskOrgValue(value)))
+	}
+	return errors.New(strings.ReplaceAll(err.Error(), value, md.MaskOrgValue(value)))
+}
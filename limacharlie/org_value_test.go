@@ -28,3 +28,23 @@ func TestOrgValue(t *testing.T) {
 	a.Equal(testConf, ov.Name)
 	a.Equal("", ov.Value)
 }
+
+func TestOrgValueMasking(t *testing.T) {
+	a := assert.New(t)
+	catalog := map[OrgValueName]OrgValueMetadata{}
+	for _, md := range defaultOrgValueCatalog {
+		catalog[md.Name] = md
+	}
+
+	a.Equal("example.com", orgValueMetadata(catalog, OrgValueNames.Domain).MaskOrgValue("example.com"))
+	a.Equal(orgValueMask, orgValueMetadata(catalog, OrgValueNames.VirusTotal).MaskOrgValue("abc123"))
+	a.Equal("", orgValueMetadata(catalog, OrgValueNames.VirusTotal).MaskOrgValue(""))
+	// Values missing from the catalog are assumed to be secrets.
+	a.True(orgValueMetadata(catalog, "unknown").IsSecret)
+
+	err := maskOrgValueError(NewRESTError("invalid value abc123"), catalog[OrgValueNames.OTX], "abc123")
+	a.IsType(RESTError{}, err)
+	a.EqualError(err, "api error: invalid value "+orgValueMask)
+	err = maskOrgValueError(NewRESTError("invalid value example.com"), catalog[OrgValueNames.Domain], "example.com")
+	a.EqualError(err, "api error: invalid value example.com")
+}
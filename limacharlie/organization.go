@@ -157,6 +157,12 @@ func (o *Organization) GetURLs() (map[string]string, error) {
 	if err := o.client.reliableRequest(http.MethodGet, fmt.Sprintf("orgs/%s/url", o.client.options.OID), makeDefaultRequest(&resp)); err != nil {
 		return nil, err
 	}
+	if resp.URLs == nil {
+		resp.URLs = map[string]string{}
+	}
+	for service, host := range o.client.options.Endpoints.Services {
+		resp.URLs[service] = host
+	}
 	return resp.URLs, nil
 }
 
@@ -245,6 +251,8 @@ func (o *Organization) SetQuota(quota int64) (bool, error) {
 	return false, nil
 }
 
+// ServiceRequest sends data to a service and unmarshals its response
+// into responseData, see ServiceAction for the actions of a service.
 func (o *Organization) ServiceRequest(responseData interface{}, serviceName string, serviceData Dict, isAsync bool) error {
 	return o.client.serviceRequest(responseData, serviceName, serviceData, isAsync)
 }
@@ -1,6 +1,7 @@
 package limacharlie
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -84,6 +85,8 @@ type OutputConfig struct {
 	Name   string           `json:"name,omitempty"`
 	Module OutputModuleType `json:"module"`
 	Type   OutputDataType   `json:"type"`
+	// Description documents the output, it is stored with it.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 
 	PrefixData        bool   `json:"is_prefix_data,omitempty,string" yaml:"is_prefix_data,omitempty"`
 	DeleteOnFailure   bool   `json:"is_delete_on_failure,omitempty,string" yaml:"is_delete_on_failure,omitempty"`
@@ -141,15 +144,7 @@ type OutputConfig struct {
 }
 
 func (o OutputConfig) Equals(other OutputConfig) bool {
-	otherBytes, err := json.Marshal(other)
-	if err != nil {
-		return false
-	}
-	bytes, err := json.Marshal(o)
-	if err != nil {
-		return false
-	}
-	return string(otherBytes) == string(bytes)
+	return syncNormalizers.Output.Equal(o, other)
 }
 
 func (o *OutputConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
@@ -280,25 +275,20 @@ type outputResponse struct {
 
 // Outputs returns all outputs by name
 func (org Organization) Outputs() (OutputsByName, error) {
-	outputsByOrg := map[string]map[OutputName]outputResponse{}
-	if err := org.OutputsGeneric(&outputsByOrg); err != nil {
+	outputs := OutputsByName{}
+	var err error
+	org.OutputsIter(context.Background())(func(output OutputConfig, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		outputs[output.Name] = output
+		return true
+	})
+	if err != nil {
 		return nil, err
 	}
-
-	outByName, ok := outputsByOrg[org.client.options.OID]
-	if !ok {
-		return nil, ErrorResourceNotFound
-	}
-
-	cleanOutByName := OutputsByName{}
-	for k, v := range outByName {
-		outputConfig := v.OutputConfig
-		outputConfig.Type = v.Type
-
-		cleanOutByName[k] = outputConfig
-	}
-
-	return cleanOutByName, nil
+	return outputs, nil
 }
 
 // OutputAdd add an output to the LC organization
@@ -324,6 +314,45 @@ func (org Organization) OutputDel(name string) (GenericJSON, error) {
 	return resp, nil
 }
 
+// OutputStats are the delivery counters of an output over a period.
+type OutputStats struct {
+	Name      OutputName `json:"name"`
+	Delivered uint64     `json:"delivered"`
+	Dropped   uint64     `json:"dropped"`
+	Errored   uint64     `json:"errors"`
+	// Last error reported by the output, if any.
+	LastError     string `json:"last_error,omitempty"`
+	LastErrorTime int64  `json:"last_error_ts,omitempty"`
+}
+
+// FailureRate returns the ratio of messages dropped or errored, between 0 and 1.
+func (s OutputStats) FailureRate() float64 {
+	failed := s.Dropped + s.Errored
+	total := s.Delivered + failed
+	if total == 0 {
+		return 0
+	}
+	return float64(failed) / float64(total)
+}
+
+// OutputStats returns the delivery counters of an output
+// for the period ending now.
+func (org Organization) OutputStats(name OutputName, period time.Duration) (OutputStats, error) {
+	resp := OutputStats{}
+	now := time.Now()
+	request := makeDefaultRequest(&resp).withTimeout(10 * time.Second).withQueryData(Dict{
+		"start": now.Add(-period).Unix(),
+		"end":   now.Unix(),
+	})
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("outputs/%s/%s/stats", org.client.options.OID, name), request); err != nil {
+		return OutputStats{}, err
+	}
+	if resp.Name == "" {
+		resp.Name = name
+	}
+	return resp, nil
+}
+
 func (org Organization) outputs(verb string, request restRequest) error {
 	return org.client.reliableRequest(verb, fmt.Sprintf("outputs/%s", org.client.options.OID), request)
 }
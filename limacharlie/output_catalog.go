@@ -0,0 +1,221 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// OutputModuleCatalogVersion is the version of the catalog of output
+// modules embedded in the SDK, bumped whenever it changes.
+const OutputModuleCatalogVersion = 1
+
+// OutputModuleField is a field of the OutputConfig of a module.
+type OutputModuleField struct {
+	// Name of the field in the config, like "dest_host".
+	Name string `json:"name"`
+	// Type is "string", "bool" or "int".
+	Type       string `json:"type"`
+	IsRequired bool   `json:"is_required"`
+}
+
+// OutputModuleSpec describes the fields supported by an output module.
+type OutputModuleSpec struct {
+	Module OutputModuleType    `json:"module"`
+	Fields []OutputModuleField `json:"fields"`
+}
+
+// OutputModuleCatalog is the set of supported output modules.
+type OutputModuleCatalog struct {
+	Version int                                   `json:"version"`
+	Modules map[OutputModuleType]OutputModuleSpec `json:"modules"`
+}
+
+// Fields supported by all the modules, to filter and shape the data.
+var outputCommonFields = []string{
+	"description",
+	"is_prefix_data",
+	"is_delete_on_failure",
+	"is_no_routing",
+	"is_no_sharding",
+	"is_payload_as_string",
+	"is_flat",
+	"inv_id",
+	"tag",
+	"cat",
+	"sid",
+	"event_white_list",
+	"event_black_list",
+	"cat_white_list",
+	"cat_black_list",
+	"sample_rate",
+	"custom_transform",
+}
+
+// Fields of each module, required ones first.
+var outputModuleFields = map[OutputModuleType]struct {
+	required []string
+	optional []string
+}{
+	OutputTypes.S3: {
+		required: []string{"bucket", "key_id", "secret_key"},
+		optional: []string{"dir", "region_name", "endpoint_url", "sec_per_file", "is_indexing", "is_compression"},
+	},
+	OutputTypes.GCS: {
+		required: []string{"bucket", "secret_key"},
+		optional: []string{"dir", "sec_per_file", "is_indexing", "is_compression"},
+	},
+	OutputTypes.Pubsub: {
+		required: []string{"project", "topic", "secret_key"},
+	},
+	OutputTypes.BigQuery: {
+		required: []string{"project", "dataset", "table", "secret_key"},
+		optional: []string{"sec_per_file"},
+	},
+	OutputTypes.SCP: {
+		required: []string{"dest_host", "username"},
+		optional: []string{"dir", "password", "secret_key"},
+	},
+	OutputTypes.SFTP: {
+		required: []string{"dest_host", "username"},
+		optional: []string{"dir", "password", "secret_key"},
+	},
+	OutputTypes.Slack: {
+		required: []string{"slack_api_token", "slack_channel"},
+		optional: []string{"attachment_text", "message", "color"},
+	},
+	OutputTypes.Syslog: {
+		required: []string{"dest_host"},
+		optional: []string{"is_tls", "is_strict_tls", "is_no_header", "structured_data"},
+	},
+	OutputTypes.Webhook: {
+		required: []string{"dest_host"},
+		optional: []string{"secret_key", "auth_header_name", "auth_header_value"},
+	},
+	OutputTypes.WebhookBulk: {
+		required: []string{"dest_host"},
+		optional: []string{"secret_key", "auth_header_name", "auth_header_value", "sec_per_file"},
+	},
+	OutputTypes.SMTP: {
+		required: []string{"dest_host", "dest_email", "from_email"},
+		optional: []string{"username", "password", "subject", "is_readable", "is_starttls", "is_authlogin"},
+	},
+	OutputTypes.Humio: {
+		required: []string{"humio_repo", "humio_api_token"},
+		optional: []string{"endpoint_url"},
+	},
+	OutputTypes.Kafka: {
+		required: []string{"dest_host"},
+		optional: []string{"routing_topic", "literal_topic", "username", "password", "is_tls"},
+	},
+	OutputTypes.AzureStorageBlob: {
+		required: []string{"key_id", "secret_key", "bucket"},
+		optional: []string{"dir", "sec_per_file", "is_indexing", "is_compression"},
+	},
+	OutputTypes.AzureEventHub: {
+		required: []string{"secret_key"},
+	},
+	OutputTypes.Tines: {
+		required: []string{"dest_host"},
+	},
+	OutputTypes.Torq: {
+		required: []string{"dest_host", "auth_header_value"},
+	},
+}
+
+// outputConfigFieldTypes maps the names of the fields
+// of OutputConfig to their type in the catalog.
+func outputConfigFieldTypes() map[string]string {
+	types := map[string]string{}
+	t := reflect.TypeOf(OutputConfig{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch f.Type.Kind() {
+		case reflect.Bool:
+			types[name] = "bool"
+		case reflect.Int:
+			types[name] = "int"
+		default:
+			types[name] = "string"
+		}
+	}
+	return types
+}
+
+func newOutputModuleCatalog() OutputModuleCatalog {
+	types := outputConfigFieldTypes()
+	catalog := OutputModuleCatalog{
+		Version: OutputModuleCatalogVersion,
+		Modules: map[OutputModuleType]OutputModuleSpec{},
+	}
+	for module, fields := range outputModuleFields {
+		spec := OutputModuleSpec{Module: module}
+		for _, f := range fields.required {
+			spec.Fields = append(spec.Fields, OutputModuleField{Name: f, Type: types[f], IsRequired: true})
+		}
+		optional := append(append([]string{}, fields.optional...), outputCommonFields...)
+		sort.Strings(optional)
+		for _, f := range optional {
+			spec.Fields = append(spec.Fields, OutputModuleField{Name: f, Type: types[f]})
+		}
+		catalog.Modules[module] = spec
+	}
+	return catalog
+}
+
+// OutputModules returns the catalog of the output modules
+// supported, as embedded in this version of the SDK.
+func (c *Client) OutputModules() OutputModuleCatalog {
+	return newOutputModuleCatalog()
+}
+
+// Validate checks that the module of the output is known and
+// that the fields it requires are set.
+func (c OutputModuleCatalog) Validate(output OutputConfig) error {
+	spec, ok := c.Modules[output.Module]
+	if !ok {
+		return fmt.Errorf("unknown output module: %q", output.Module)
+	}
+	raw, err := json.Marshal(output)
+	if err != nil {
+		return err
+	}
+	values := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+	missing := []string{}
+	for _, f := range spec.Fields {
+		if !f.IsRequired {
+			continue
+		}
+		if v, ok := values[f.Name]; !ok || v == "" {
+			missing = append(missing, f.Name)
+		}
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("missing required fields for %s: %s", output.Module, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func lintOutputInvalidModule(conf OrgConfig) []LintFinding {
+	catalog := newOutputModuleCatalog()
+	findings := []LintFinding{}
+	for name, output := range conf.Outputs {
+		if output.Module == "" {
+			// Reported by output-no-type.
+			continue
+		}
+		if err := catalog.Validate(output); err != nil {
+			findings = append(findings, LintFinding{
+				Severity: LintSeverities.Error,
+				Message:  err.Error(),
+			}.at("outputs", name))
+		}
+	}
+	return findings
+}
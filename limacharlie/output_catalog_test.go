@@ -0,0 +1,38 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputModuleCatalog(t *testing.T) {
+	a := assert.New(t)
+
+	catalog := (&Client{}).OutputModules()
+	a.Equal(OutputModuleCatalogVersion, catalog.Version)
+
+	syslog, ok := catalog.Modules[OutputTypes.Syslog]
+	a.True(ok)
+	a.Equal(OutputModuleField{Name: "dest_host", Type: "string", IsRequired: true}, syslog.Fields[0])
+	a.Contains(syslog.Fields, OutputModuleField{Name: "is_tls", Type: "bool"})
+	a.Contains(syslog.Fields, OutputModuleField{Name: "sample_rate", Type: "int"})
+
+	// Every field of the catalog exists in OutputConfig.
+	types := outputConfigFieldTypes()
+	for _, spec := range catalog.Modules {
+		for _, f := range spec.Fields {
+			a.Contains(types, f.Name, spec.Module)
+		}
+	}
+
+	a.NoError(catalog.Validate(OutputConfig{Module: OutputTypes.Syslog, DestinationHost: "siem:514"}))
+	a.EqualError(catalog.Validate(OutputConfig{Module: OutputTypes.S3, Bucket: "b"}), "missing required fields for s3: key_id, secret_key")
+	a.EqualError(catalog.Validate(OutputConfig{Module: "carrier-pigeon"}), `unknown output module: "carrier-pigeon"`)
+
+	findings := OrgConfig{Outputs: orgSyncOutputs{
+		"bad": {Module: OutputTypes.Slack, Type: OutputType.Detect},
+	}}.Lint(NewLintRule("output-invalid-module", lintOutputInvalidModule))
+	a.Len(findings, 1)
+	a.Equal("outputs.bad", findings[0].Location)
+}
@@ -0,0 +1,91 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// OutputSecretFields are the fields holding the credential of each
+// output module, by preference when a module supports several.
+var OutputSecretFields = map[OutputModuleType][]string{
+	OutputTypes.S3:               {"secret_key"},
+	OutputTypes.GCS:              {"secret_key"},
+	OutputTypes.Pubsub:           {"secret_key"},
+	OutputTypes.BigQuery:         {"secret_key"},
+	OutputTypes.SCP:              {"password", "secret_key"},
+	OutputTypes.SFTP:             {"password", "secret_key"},
+	OutputTypes.Slack:            {"slack_api_token"},
+	OutputTypes.Webhook:          {"secret_key", "auth_header_value"},
+	OutputTypes.WebhookBulk:      {"secret_key", "auth_header_value"},
+	OutputTypes.SMTP:             {"password"},
+	OutputTypes.Humio:            {"humio_api_token"},
+	OutputTypes.Kafka:            {"password"},
+	OutputTypes.AzureStorageBlob: {"secret_key"},
+	OutputTypes.AzureEventHub:    {"secret_key"},
+	OutputTypes.Torq:             {"auth_header_value"},
+}
+
+// outputStringField returns the string field of the
+// output with the json name, nil if there is none.
+func outputStringField(output *OutputConfig, name string) *string {
+	v := reflect.ValueOf(output).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("json"), ",")[0] != name {
+			continue
+		}
+		if p, ok := v.Field(i).Addr().Interface().(*string); ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// outputSecretField returns the name of the field holding the credential
+// of the output, the first of OutputSecretFields set, or the first one.
+func outputSecretField(output *OutputConfig) (string, error) {
+	fields, ok := OutputSecretFields[output.Module]
+	if !ok {
+		return "", fmt.Errorf("output module %q has no credential", output.Module)
+	}
+	for _, f := range fields {
+		if p := outputStringField(output, f); p != nil && *p != "" {
+			return f, nil
+		}
+	}
+	return fields[0], nil
+}
+
+// RotateOutputSecret replaces the credential of an existing output,
+// keeping the rest of its configuration. The field is the one of
+// OutputSecretFields already set, unless provided. The output is
+// updated in place under the same name, rather than deleted and
+// added, so no data is lost during the rotation.
+func (org Organization) RotateOutputSecret(name OutputName, newSecret string, field ...string) (OutputConfig, error) {
+	if newSecret == "" {
+		return OutputConfig{}, errors.New("the new secret is empty")
+	}
+	outputs, err := org.Outputs()
+	if err != nil {
+		return OutputConfig{}, err
+	}
+	output, ok := outputs[name]
+	if !ok {
+		return OutputConfig{}, ErrorResourceNotFound
+	}
+	f := ""
+	if len(field) != 0 && field[0] != "" {
+		f = field[0]
+	} else if f, err = outputSecretField(&output); err != nil {
+		return OutputConfig{}, err
+	}
+	p := outputStringField(&output, f)
+	if p == nil {
+		return OutputConfig{}, fmt.Errorf("unknown output field %q", f)
+	}
+	*p = newSecret
+	output.Name = name
+	return org.OutputAdd(output)
+}
@@ -0,0 +1,59 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateOutputSecret(t *testing.T) {
+	a := assert.New(t)
+	added := []*http.Request{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal("/v1/outputs/"+vcrTestOID, r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{%q: {
+				"s3-archive": {"module": "s3", "for": "event", "bucket": "b", "key_id": "k", "secret_key": "old"},
+				"hook": {"module": "webhook", "for": "detect", "dest_host": "https://h", "auth_header_name": "x-token", "auth_header_value": "old"},
+				"logs": {"module": "syslog", "for": "event", "dest_host": "h:514"}
+			}}`, vcrTestOID)), nil
+		case http.MethodPost:
+			a.NoError(r.ParseForm())
+			added = append(added, r)
+			return jsonResponse(http.StatusOK, `{}`), nil
+		}
+		t.Errorf("unexpected request: %s", r.Method)
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	_, err := org.RotateOutputSecret("s3-archive", "new")
+	a.NoError(err)
+	a.Len(added, 1)
+	a.Equal("new", added[0].PostForm.Get("secret_key"))
+	a.Equal("b", added[0].PostForm.Get("bucket"))
+	a.Equal("k", added[0].PostForm.Get("key_id"))
+	a.Equal("s3-archive", added[0].PostForm.Get("name"))
+
+	_, err = org.RotateOutputSecret("hook", "new")
+	a.NoError(err)
+	a.Len(added, 2)
+	a.Equal("new", added[1].PostForm.Get("auth_header_value"))
+	a.Equal("", added[1].PostForm.Get("secret_key"))
+	a.Equal("x-token", added[1].PostForm.Get("auth_header_name"))
+
+	_, err = org.RotateOutputSecret("hook", "signing", "secret_key")
+	a.NoError(err)
+	a.Equal("signing", added[2].PostForm.Get("secret_key"))
+	a.Equal("old", added[2].PostForm.Get("auth_header_value"))
+
+	_, err = org.RotateOutputSecret("logs", "new")
+	a.Error(err)
+	_, err = org.RotateOutputSecret("missing", "new")
+	a.Equal(ErrorResourceNotFound, err)
+	_, err = org.RotateOutputSecret("hook", "new", "nope")
+	a.Error(err)
+	a.Len(added, 3)
+}
@@ -126,3 +126,10 @@ type: event
 		t.Errorf("mismatch: %#v != %#v", y, expected)
 	}
 }
+
+func TestOutputStatsFailureRate(t *testing.T) {
+	a := assert.New(t)
+
+	a.Zero(OutputStats{}.FailureRate())
+	a.Equal(0.25, OutputStats{Delivered: 6, Dropped: 1, Errored: 1}.FailureRate())
+}
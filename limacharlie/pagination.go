@@ -0,0 +1,270 @@
+package limacharlie
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// continuationTokenKey is the key of the token of the next
+// page in listings, absent from the last page.
+const continuationTokenKey = "continuation_token"
+
+// outputsPage fetches a page of the outputs of the Org.
+func (org Organization) outputsPage(token string) (OutputsByName, string, error) {
+	resp := map[string]json.RawMessage{}
+	request := makeDefaultRequest(&resp).withTimeout(10 * time.Second)
+	if token != "" {
+		request = request.withQueryData(Dict{continuationTokenKey: token})
+	}
+	if err := org.outputs(http.MethodGet, request); err != nil {
+		return nil, "", err
+	}
+	next := popContinuationToken(resp)
+	raw, ok := resp[org.client.options.OID]
+	if !ok {
+		return nil, "", ErrorResourceNotFound
+	}
+	outByName := map[OutputName]outputResponse{}
+	if err := json.Unmarshal(raw, &outByName); err != nil {
+		return nil, "", err
+	}
+	outputs := OutputsByName{}
+	for name, v := range outByName {
+		outputConfig := v.OutputConfig
+		outputConfig.Type = v.Type
+		outputs[name] = outputConfig
+	}
+	return outputs, next, nil
+}
+
+// drRulesPage fetches a page of the D&R rules of the Org,
+// left undecoded for the callers to decode into what they need.
+func (org Organization) drRulesPage(req map[string]string, token string) (map[string]json.RawMessage, string, error) {
+	q := Dict{}
+	for k, v := range req {
+		q[k] = v
+	}
+	if token != "" {
+		q[continuationTokenKey] = token
+	}
+	resp := map[string]json.RawMessage{}
+	request := makeDefaultRequest(&resp).withQueryData(q)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("rules/%s", org.client.options.OID), request); err != nil {
+		return nil, "", err
+	}
+	next := popContinuationToken(resp)
+	return resp, next, nil
+}
+
+// popContinuationToken removes the token of the next page from
+// a listing keyed by name, in which it is a string and not an element.
+func popContinuationToken(resp map[string]json.RawMessage) string {
+	raw, ok := resp[continuationTokenKey]
+	if !ok {
+		return ""
+	}
+	token := ""
+	if err := json.Unmarshal(raw, &token); err != nil {
+		// An element with that name.
+		return ""
+	}
+	delete(resp, continuationTokenKey)
+	return token
+}
+
+// OutputsIter returns an iterator over the outputs of the Org, fetched
+// one page at a time. An error ends the iteration and is yielded with
+// a zero OutputConfig. The iteration also ends when ctx is done.
+func (org Organization) OutputsIter(ctx context.Context) func(yield func(OutputConfig, error) bool) {
+	return func(yield func(OutputConfig, error) bool) {
+		token := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(OutputConfig{}, err)
+				return
+			}
+			outputs, next, err := org.outputsPage(token)
+			if err != nil {
+				yield(OutputConfig{}, err)
+				return
+			}
+			for name, output := range outputs {
+				output.Name = name
+				if !yield(output, nil) {
+					return
+				}
+			}
+			if next == "" || next == token {
+				return
+			}
+			token = next
+		}
+	}
+}
+
+// DRRulesIter returns an iterator over the D&R rules of the Org, by
+// name, fetched one page at a time, like OutputsIter.
+func (org Organization) DRRulesIter(ctx context.Context, filters ...DRRuleFilter) func(yield func(string, Dict, error) bool) {
+	return func(yield func(string, Dict, error) bool) {
+		org.drRulesRawIter(ctx, filters...)(func(name string, raw json.RawMessage, err error) bool {
+			if err != nil {
+				yield("", nil, err)
+				return false
+			}
+			rule := Dict{}
+			if err := json.Unmarshal(raw, &rule); err != nil {
+				yield("", nil, fmt.Errorf("rule %s: %v", name, err))
+				return false
+			}
+			return yield(name, rule, nil)
+		})
+	}
+}
+
+// drRulesRawIter is DRRulesIter yielding the rules undecoded.
+func (org Organization) drRulesRawIter(ctx context.Context, filters ...DRRuleFilter) func(yield func(string, json.RawMessage, error) bool) {
+	req := map[string]string{}
+	for _, f := range filters {
+		f(req)
+	}
+	return func(yield func(string, json.RawMessage, error) bool) {
+		token := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield("", nil, err)
+				return
+			}
+			rules, next, err := org.drRulesPage(req, token)
+			if err != nil {
+				yield("", nil, err)
+				return
+			}
+			for name, rule := range rules {
+				if !yield(name, rule, nil) {
+					return
+				}
+			}
+			if next == "" || next == token {
+				return
+			}
+			token = next
+		}
+	}
+}
+
+// SensorsIter returns an iterator over the sensors of the Org matching
+// the selector, all of them if empty, fetched one page at a time,
+// like OutputsIter.
+func (org *Organization) SensorsIter(ctx context.Context, selector string) func(yield func(*Sensor, error) bool) {
+	return func(yield func(*Sensor, error) bool) {
+		token := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			page := sensorListPage{}
+			q := Dict{}
+			if token != "" {
+				q[continuationTokenKey] = token
+			}
+			if selector != "" {
+				q["selector"] = selector
+			}
+			request := makeDefaultRequest(&page).withCache()
+			if len(q) != 0 {
+				request = request.withQueryData(q)
+			}
+			if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("sensors/%s", org.client.options.OID), request); err != nil {
+				yield(nil, err)
+				return
+			}
+			for _, s := range page.Sensors {
+				s.Organization = org
+				s.InvestigationID = org.invID
+				if s.DID != "" {
+					s.Device = &Device{
+						DID:          s.DID,
+						Organization: org,
+					}
+				}
+				if !yield(s, nil) {
+					return
+				}
+			}
+			if page.ContinuationToken == "" || page.ContinuationToken == token {
+				return
+			}
+			token = page.ContinuationToken
+		}
+	}
+}
+
+// insightRecordsPage is a page of an insight listing, of detections,
+// events or audit entries.
+type insightRecordsPage struct {
+	Detects    []Dict `json:"detects"`
+	Events     []Dict `json:"events"`
+	Audit      []Dict `json:"audit"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// insightRecordsIter iterates over the records of an insight listing
+// between start and end, fetched one page at a time.
+func (org Organization) insightRecordsIter(ctx context.Context, path string, start time.Time, end time.Time) func(yield func(Dict, error) bool) {
+	return func(yield func(Dict, error) bool) {
+		cursor := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			page := insightRecordsPage{}
+			q := Dict{
+				"start": start.Unix(),
+				"end":   end.Unix(),
+			}
+			if cursor != "" {
+				q["cursor"] = cursor
+			}
+			request := makeDefaultRequest(&page).withQueryData(q)
+			if err := org.client.reliableRequest(http.MethodGet, path, request); err != nil {
+				yield(nil, err)
+				return
+			}
+			records := append(append(page.Detects, page.Events...), page.Audit...)
+			for _, r := range records {
+				if !yield(r, nil) {
+					return
+				}
+			}
+			if page.NextCursor == "" || page.NextCursor == cursor {
+				return
+			}
+			cursor = page.NextCursor
+		}
+	}
+}
+
+// DetectionsIter returns an iterator over the detections of the Org
+// reported between start and end, fetched one page at a time, like
+// OutputsIter.
+func (org Organization) DetectionsIter(ctx context.Context, start time.Time, end time.Time) func(yield func(Detection, error) bool) {
+	return func(yield func(Detection, error) bool) {
+		org.insightRecordsIter(ctx, fmt.Sprintf("insight/%s/detections", org.client.options.OID), start, end)(func(r Dict, err error) bool {
+			if err != nil {
+				yield(Detection{}, err)
+				return false
+			}
+			d := Detection{}
+			if err := r.UnMarshalToStruct(&d); err != nil {
+				yield(Detection{}, err)
+				return false
+			}
+			return yield(d, nil)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package limacharlie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPaginatedListings(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		token := r.URL.Query().Get("continuation_token")
+		switch r.URL.Path {
+		case fmt.Sprintf("/v1/outputs/%s", vcrTestOID):
+			if token == "" {
+				return jsonResponse(http.StatusOK, fmt.Sprintf(`{"%s":{"o1":{"module":"syslog","for":"event"}},"continuation_token":"p2"}`, vcrTestOID)), nil
+			}
+			a.Equal("p2", token)
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{"%s":{"o2":{"module":"s3","for":"detect"}}}`, vcrTestOID)), nil
+		case fmt.Sprintf("/v1/rules/%s", vcrTestOID):
+			a.Equal("managed", r.URL.Query().Get("namespace"))
+			if token == "" {
+				return jsonResponse(http.StatusOK, `{"r1":{"detect":{"op":"is"}},"continuation_token":"p2"}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{"r2":{"detect":{"op":"is"}}}`), nil
+		case fmt.Sprintf("/v1/sensors/%s", vcrTestOID):
+			a.Equal("plat == windows", r.URL.Query().Get("selector"))
+			if token == "" {
+				return jsonResponse(http.StatusOK, `{"sensors":[{"sid":"s1"}],"continuation_token":"p2"}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{"sensors":[{"sid":"s2"}]}`), nil
+		}
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	outputs, err := org.Outputs()
+	a.NoError(err)
+	a.Len(outputs, 2)
+	a.Equal(OutputType.Detect, outputs["o2"].Type)
+	a.Equal("o2", outputs["o2"].Name)
+
+	rules, err := org.DRRules(WithNamespace("managed"))
+	a.NoError(err)
+	a.Len(rules, 2)
+	a.Contains(rules, "r2")
+
+	sensors, err := org.ListSensorsFromSelector("plat == windows")
+	a.NoError(err)
+	a.Len(sensors, 2)
+
+	// Stopping early does not fetch the next pages.
+	n := 0
+	org.OutputsIter(context.Background())(func(output OutputConfig, err error) bool {
+		a.NoError(err)
+		n++
+		return false
+	})
+	a.Equal(1, n)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var iterErr error
+	org.DRRulesIter(ctx)(func(name string, rule Dict, err error) bool {
+		iterErr = err
+		return true
+	})
+	a.Equal(context.Canceled, iterErr)
+}
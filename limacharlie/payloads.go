@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
 )
 
@@ -38,21 +37,31 @@ func (org Organization) Payloads() (map[PayloadName]Payload, error) {
 
 // Download the content of a Payload in an LC organization.
 func (org Organization) Payload(name PayloadName) ([]byte, error) {
+	data := bytes.Buffer{}
+	if err := org.PayloadToWriter(name, &data); err != nil {
+		return nil, err
+	}
+	return data.Bytes(), nil
+}
+
+// Stream the content of a Payload in an LC organization
+// to a writer without buffering it in memory.
+func (org Organization) PayloadToWriter(name PayloadName, w io.Writer) error {
 	resp := payloadGetPointer{}
 	request := makeDefaultRequest(&resp)
 	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("payload/%s/%s", org.client.options.OID, name), request); err != nil {
-		return nil, err
+		return err
 	}
-	httpResp, err := http.Get(resp.URL)
+	httpResp, err := org.client.httpClient(0).Get(resp.URL)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer httpResp.Body.Close()
-	data, err := ioutil.ReadAll(httpResp.Body)
-	if err != nil {
-		return nil, err
+	if httpResp.StatusCode != 200 {
+		return fmt.Errorf("failed to GET payload, http status: %d", httpResp.StatusCode)
 	}
-	return data, nil
+	_, err = io.Copy(w, httpResp.Body)
+	return err
 }
 
 // Delete a Payload from within an LC organization.
@@ -76,7 +85,7 @@ func (org Organization) CreatePayloadFromReader(name PayloadName, data io.Reader
 	if err := org.client.reliableRequest(http.MethodPost, fmt.Sprintf("payload/%s/%s", org.client.options.OID, name), request); err != nil {
 		return err
 	}
-	c := &http.Client{}
+	c := org.client.httpClient(0)
 	req, err := http.NewRequest(http.MethodPut, resp.URL, data)
 	if err != nil {
 		return err
@@ -0,0 +1,185 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SensorPresence is the online status of a sensor.
+type SensorPresence struct {
+	Sensor   *Sensor
+	IsOnline bool
+	// Last time the sensor was seen by the cloud, zero if unknown.
+	LastSeen time.Time
+}
+
+// OfflineFor returns for how long the sensor has been offline
+// at the time provided, or zero if it is online or never seen.
+func (p SensorPresence) OfflineFor(now time.Time) time.Duration {
+	if p.IsOnline || p.LastSeen.IsZero() {
+		return 0
+	}
+	return now.Sub(p.LastSeen)
+}
+
+const sensorTimestampFormat = "2006-01-02 15:04:05"
+
+// Maximum number of sensors queried at once for their online status.
+const presenceBatchSize = 1000
+
+// SensorsWithPresence returns all the sensors of the Org
+// along with their online status, by SID.
+func (org *Organization) SensorsWithPresence() (map[string]SensorPresence, error) {
+	sensors, err := org.ListSensors()
+	if err != nil {
+		return nil, err
+	}
+	return org.presenceOf(sensors)
+}
+
+// presenceOf returns the online status of the sensors, by SID.
+func (org *Organization) presenceOf(sensors map[string]*Sensor) (map[string]SensorPresence, error) {
+	sids := []string{}
+	for sid := range sensors {
+		sids = append(sids, sid)
+	}
+	online := map[string]bool{}
+	for i := 0; i < len(sids); i += presenceBatchSize {
+		end := i + presenceBatchSize
+		if end > len(sids) {
+			end = len(sids)
+		}
+		batch, err := org.ActiveSensors(sids[i:end])
+		if err != nil {
+			return nil, err
+		}
+		for sid, isOnline := range batch {
+			online[sid] = isOnline
+		}
+	}
+
+	presence := map[string]SensorPresence{}
+	for sid, s := range sensors {
+		p := SensorPresence{
+			Sensor:   s,
+			IsOnline: online[sid],
+		}
+		if t, err := time.Parse(sensorTimestampFormat, s.AliveTS); err == nil {
+			p.LastSeen = t
+		}
+		presence[sid] = p
+	}
+	return presence, nil
+}
+
+type PresenceMonitorOptions struct {
+	// Time between polls of the sensors, defaults to 1 minute.
+	Interval time.Duration
+	// How long a sensor must be offline before OnOffline is called.
+	OfflineThreshold time.Duration
+
+	// Called when a sensor has been offline for longer than the threshold.
+	OnOffline func(p SensorPresence)
+	// Called when a sensor previously reported as offline is seen again.
+	OnOnline func(p SensorPresence)
+	// Called when polling the sensors fails.
+	OnError func(err error)
+}
+
+// PresenceMonitor polls the sensors of an Org and calls back
+// when they go offline or come back online. The first poll
+// only records the initial state of the sensors.
+type PresenceMonitor struct {
+	org  *Organization
+	opts PresenceMonitorOptions
+
+	isOffline   map[string]bool
+	isRunning   bool
+	stop        chan struct{}
+	wg          sync.WaitGroup
+	runningLock sync.Mutex
+}
+
+func NewPresenceMonitor(org *Organization, opts PresenceMonitorOptions) *PresenceMonitor {
+	if opts.Interval == 0 {
+		opts.Interval = 1 * time.Minute
+	}
+	return &PresenceMonitor{
+		org:  org,
+		opts: opts,
+	}
+}
+
+func (m *PresenceMonitor) Start() error {
+	m.runningLock.Lock()
+	defer m.runningLock.Unlock()
+	if m.isRunning {
+		return fmt.Errorf("presence monitor already running")
+	}
+	m.isRunning = true
+	m.isOffline = nil
+	m.stop = make(chan struct{})
+	m.wg.Add(1)
+	go m.run()
+	return nil
+}
+
+// Shutdown stops polling and waits for the current poll to finish.
+func (m *PresenceMonitor) Shutdown() {
+	m.runningLock.Lock()
+	if !m.isRunning {
+		m.runningLock.Unlock()
+		return
+	}
+	m.isRunning = false
+	close(m.stop)
+	m.runningLock.Unlock()
+	m.wg.Wait()
+}
+
+func (m *PresenceMonitor) IsRunning() bool {
+	m.runningLock.Lock()
+	defer m.runningLock.Unlock()
+	return m.isRunning
+}
+
+func (m *PresenceMonitor) run() {
+	defer m.wg.Done()
+	for {
+		presence, err := m.org.SensorsWithPresence()
+		if err != nil {
+			if m.opts.OnError != nil {
+				m.opts.OnError(err)
+			}
+		} else {
+			m.update(presence, time.Now())
+		}
+		select {
+		case <-m.stop:
+			return
+		case <-time.After(m.opts.Interval):
+		}
+	}
+}
+
+// update records the new presence of the sensors and
+// calls back for the ones that changed state.
+func (m *PresenceMonitor) update(presence map[string]SensorPresence, now time.Time) {
+	isFirst := m.isOffline == nil
+	isOffline := map[string]bool{}
+	for sid, p := range presence {
+		offline := !p.IsOnline && p.OfflineFor(now) >= m.opts.OfflineThreshold
+		isOffline[sid] = offline
+		if isFirst {
+			continue
+		}
+		wasOffline := m.isOffline[sid]
+		if offline && !wasOffline && m.opts.OnOffline != nil {
+			m.opts.OnOffline(p)
+		} else if !offline && wasOffline && m.opts.OnOnline != nil {
+			m.opts.OnOnline(p)
+		}
+	}
+	m.isOffline = isOffline
+}
@@ -0,0 +1,55 @@
+package limacharlie
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPresenceMonitorUpdate(t *testing.T) {
+	a := assert.New(t)
+
+	offline := []string{}
+	online := []string{}
+	m := NewPresenceMonitor(nil, PresenceMonitorOptions{
+		OfflineThreshold: 10 * time.Minute,
+		OnOffline: func(p SensorPresence) {
+			offline = append(offline, p.Sensor.SID)
+		},
+		OnOnline: func(p SensorPresence) {
+			online = append(online, p.Sensor.SID)
+		},
+	})
+
+	now := time.Now()
+	presence := func(sid string, isOnline bool, lastSeen time.Time) SensorPresence {
+		return SensorPresence{Sensor: &Sensor{SID: sid}, IsOnline: isOnline, LastSeen: lastSeen}
+	}
+
+	// Initial state, no callbacks.
+	m.update(map[string]SensorPresence{
+		"s1": presence("s1", true, now),
+		"s2": presence("s2", false, now.Add(-1*time.Hour)),
+	}, now)
+	a.Empty(offline)
+	a.Empty(online)
+
+	// s1 is offline but not beyond the threshold yet.
+	now = now.Add(5 * time.Minute)
+	m.update(map[string]SensorPresence{
+		"s1": presence("s1", false, now.Add(-4*time.Minute)),
+		"s2": presence("s2", false, now.Add(-1*time.Hour)),
+	}, now)
+	a.Empty(offline)
+
+	now = now.Add(10 * time.Minute)
+	m.update(map[string]SensorPresence{
+		"s1": presence("s1", false, now.Add(-14*time.Minute)),
+		"s2": presence("s2", true, now),
+	}, now)
+	a.Equal([]string{"s1"}, offline)
+	a.Equal([]string{"s2"}, online)
+
+	a.Equal(time.Duration(0), presence("s3", true, now.Add(-1*time.Hour)).OfflineFor(now))
+}
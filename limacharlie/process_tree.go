@@ -0,0 +1,274 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+const (
+	defaultProcessTreeMaxDepth = 10
+	defaultProcessTreeMaxNodes = 1000
+)
+
+// ProcessNode is a process of a ProcessTree, identified by the atom
+// of its NEW_PROCESS or EXISTING_PROCESS event.
+type ProcessNode struct {
+	Atom       string
+	ParentAtom string
+	Event      Event
+	Process    ProcessEvent
+	Parent     *ProcessNode
+	// Children sorted by the time they were started.
+	Children []*ProcessNode
+	// Events of the process which are not processes, like its
+	// DNS_REQUEST or NETWORK_CONNECTIONS, sorted by time.
+	Events []Event
+}
+
+// ProcessTree is the tree of the processes descending from a root.
+type ProcessTree struct {
+	Root *ProcessNode
+	// Nodes by atom.
+	Nodes map[string]*ProcessNode
+	// IsTruncated is true if the tree was cut at the limits
+	// of the ProcessTreeOptions.
+	IsTruncated bool
+}
+
+// ProcessTreeOptions limit the events fetched by ProcessTree.
+type ProcessTreeOptions struct {
+	// MaxDepth of the descendants of the root, 10 by default.
+	MaxDepth int
+	// MaxNodes of the tree, 1000 by default.
+	MaxNodes int
+	// Ancestors of the root to include, the tree then
+	// being rooted at the oldest one found.
+	Ancestors int
+}
+
+type eventChildren struct {
+	Events []Event `json:"events"`
+}
+
+func isProcessEvent(e Event) bool {
+	return e.Routing.EventType == EventTypes.NewProcess || e.Routing.EventType == EventTypes.ExistingProcess
+}
+
+// EventChildren returns the events whose parent is the event of the atom.
+func (org *Organization) EventChildren(sensorID string, atom string) ([]Event, error) {
+	resp := eventChildren{}
+	q := makeDefaultRequest(&resp)
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("insight/%s/%s/%s/children", org.client.options.OID, sensorID, atom), q); err != nil {
+		return nil, err
+	}
+	return resp.Events, nil
+}
+
+// ProcessTree fetches the events related to the process of the atom,
+// like the one of the routing of a detection, and reconstructs the tree
+// of its descendants, along with its ancestors if requested.
+func (org *Organization) ProcessTree(sensorID string, atom string, opts ...ProcessTreeOptions) (*ProcessTree, error) {
+	options := ProcessTreeOptions{}
+	if len(opts) != 0 {
+		options = opts[0]
+	}
+	if options.MaxDepth <= 0 {
+		options.MaxDepth = defaultProcessTreeMaxDepth
+	}
+	if options.MaxNodes <= 0 {
+		options.MaxNodes = defaultProcessTreeMaxNodes
+	}
+
+	root, err := org.EventByAtom(sensorID, atom)
+	if err != nil {
+		return nil, err
+	}
+	if !isProcessEvent(root.Event) {
+		return nil, fmt.Errorf("%s is a %s, not a process", atom, root.Event.Routing.EventType)
+	}
+	events := []Event{root.Event}
+	isTruncated := false
+
+	top := root.Event
+	for i := 0; i < options.Ancestors && top.Routing.Parent != ""; i++ {
+		parent, err := org.EventByAtom(sensorID, top.Routing.Parent)
+		if err != nil {
+			if isRESTNotFound(err) {
+				// Aged out of retention.
+				break
+			}
+			return nil, err
+		}
+		if !isProcessEvent(parent.Event) {
+			break
+		}
+		events = append(events, parent.Event)
+		top = parent.Event
+	}
+
+	// The ancestors only contribute themselves, descendants
+	// are fetched from the process of the atom.
+	level := []string{atom}
+	processes := 1
+	for depth := 0; depth < options.MaxDepth && len(level) != 0; depth++ {
+		next := []string{}
+		for _, a := range level {
+			children, err := org.EventChildren(sensorID, a)
+			if err != nil {
+				return nil, err
+			}
+			for _, c := range children {
+				if isProcessEvent(c) {
+					if processes == options.MaxNodes {
+						isTruncated = true
+						continue
+					}
+					processes++
+					next = append(next, c.Routing.This)
+				}
+				events = append(events, c)
+			}
+		}
+		level = next
+	}
+	if len(level) != 0 {
+		isTruncated = true
+	}
+
+	tree, err := NewProcessTree(events, top.Routing.This)
+	if err != nil {
+		return nil, err
+	}
+	tree.IsTruncated = isTruncated
+	return tree, nil
+}
+
+// NewProcessTree reconstructs the process tree rooted at the atom from
+// events, like those of ExportSensorEvents. Events of processes absent
+// from the tree are ignored.
+func NewProcessTree(events []Event, rootAtom string) (*ProcessTree, error) {
+	tree := &ProcessTree{Nodes: map[string]*ProcessNode{}}
+	others := []Event{}
+	for _, e := range events {
+		if !isProcessEvent(e) || e.Routing.This == "" {
+			others = append(others, e)
+			continue
+		}
+		if _, ok := tree.Nodes[e.Routing.This]; ok {
+			continue
+		}
+		node := &ProcessNode{
+			Atom:       e.Routing.This,
+			ParentAtom: e.Routing.Parent,
+			Event:      e,
+		}
+		if typed, err := e.Typed(); err == nil {
+			if p, ok := typed.(*ProcessEvent); ok {
+				node.Process = *p
+			}
+		}
+		tree.Nodes[node.Atom] = node
+	}
+	root, ok := tree.Nodes[rootAtom]
+	if !ok {
+		return nil, errors.New("no process event for the root atom")
+	}
+	tree.Root = root
+
+	for _, node := range tree.Nodes {
+		if node == root {
+			continue
+		}
+		if parent, ok := tree.Nodes[node.ParentAtom]; ok {
+			node.Parent = parent
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	for _, e := range others {
+		if node, ok := tree.Nodes[e.Routing.Parent]; ok {
+			node.Events = append(node.Events, e)
+		}
+	}
+	// Processes not connected to the root are dropped.
+	connected := map[string]*ProcessNode{}
+	tree.Walk(func(n *ProcessNode, depth int) bool {
+		connected[n.Atom] = n
+		return true
+	})
+	tree.Nodes = connected
+	for _, node := range tree.Nodes {
+		sortEventsByTime := func(i, j int) bool {
+			return node.Events[i].Routing.EventTime < node.Events[j].Routing.EventTime
+		}
+		sort.SliceStable(node.Events, sortEventsByTime)
+		sort.SliceStable(node.Children, func(i, j int) bool {
+			if node.Children[i].Event.Routing.EventTime != node.Children[j].Event.Routing.EventTime {
+				return node.Children[i].Event.Routing.EventTime < node.Children[j].Event.Routing.EventTime
+			}
+			return node.Children[i].Atom < node.Children[j].Atom
+		})
+	}
+	return tree, nil
+}
+
+// Walk calls cb with the nodes of the tree depth first, parents before
+// their children, the root being at depth 0. Returning false from cb
+// skips the descendants of the node.
+func (t *ProcessTree) Walk(cb func(node *ProcessNode, depth int) bool) {
+	if t.Root == nil {
+		return
+	}
+	var walk func(n *ProcessNode, depth int)
+	walk = func(n *ProcessNode, depth int) {
+		if !cb(n, depth) {
+			return
+		}
+		for _, c := range n.Children {
+			walk(c, depth+1)
+		}
+	}
+	walk(t.Root, 0)
+}
+
+// Find returns the nodes matching, in the order of Walk.
+func (t *ProcessTree) Find(match func(node *ProcessNode) bool) []*ProcessNode {
+	found := []*ProcessNode{}
+	t.Walk(func(n *ProcessNode, depth int) bool {
+		if match(n) {
+			found = append(found, n)
+		}
+		return true
+	})
+	return found
+}
+
+// Ancestors returns the parents of the node up to the root, closest first.
+func (n *ProcessNode) Ancestors() []*ProcessNode {
+	ancestors := []*ProcessNode{}
+	for p := n.Parent; p != nil; p = p.Parent {
+		ancestors = append(ancestors, p)
+	}
+	return ancestors
+}
+
+// Descendants returns the processes started by the
+// node, directly or not, in the order of Walk.
+func (n *ProcessNode) Descendants() []*ProcessNode {
+	descendants := []*ProcessNode{}
+	var walk func(p *ProcessNode)
+	walk = func(p *ProcessNode) {
+		for _, c := range p.Children {
+			descendants = append(descendants, c)
+			walk(c)
+		}
+	}
+	walk(n)
+	return descendants
+}
+
+// Depth returns the distance of the node to the root.
+func (n *ProcessNode) Depth() int {
+	return len(n.Ancestors())
+}
@@ -0,0 +1,83 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func processTreeTestEvent(eventType string, atom string, parent string, ts int64, path string) string {
+	return fmt.Sprintf(`{"routing": {"event_type": %q, "this": %q, "parent": %q, "event_time": %d}, "event": {"FILE_PATH": %q, "PROCESS_ID": %d}}`, eventType, atom, parent, ts, path, ts)
+}
+
+func TestProcessTree(t *testing.T) {
+	a := assert.New(t)
+	events := map[string]string{
+		"explorer": processTreeTestEvent("EXISTING_PROCESS", "explorer", "", 1, `c:\windows\explorer.exe`),
+		"cmd":      processTreeTestEvent("NEW_PROCESS", "cmd", "explorer", 10, `c:\windows\system32\cmd.exe`),
+	}
+	children := map[string][]string{
+		"cmd": {
+			processTreeTestEvent("NEW_PROCESS", "ps", "cmd", 30, `c:\windows\powershell.exe`),
+			processTreeTestEvent("NEW_PROCESS", "whoami", "cmd", 20, `c:\windows\system32\whoami.exe`),
+			processTreeTestEvent("DNS_REQUEST", "dns", "cmd", 25, ""),
+		},
+		"ps": {
+			processTreeTestEvent("NEW_PROCESS", "rundll", "ps", 40, `c:\windows\system32\rundll32.exe`),
+		},
+		"rundll": {
+			processTreeTestEvent("NEW_PROCESS", "deep", "rundll", 50, `c:\deep.exe`),
+		},
+	}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		prefix := fmt.Sprintf("/v1/insight/%s/s1/", vcrTestOID)
+		a.True(strings.HasPrefix(r.URL.Path, prefix), r.URL.Path)
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, prefix), "/")
+		if len(parts) == 2 && parts[1] == "children" {
+			return jsonResponse(http.StatusOK, `{"events": [`+strings.Join(children[parts[0]], ",")+`]}`), nil
+		}
+		e, ok := events[parts[0]]
+		if !ok {
+			return jsonResponse(http.StatusNotFound, `{"error": "not found"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"event": `+e+`}`), nil
+	}))
+
+	tree, err := org.ProcessTree("s1", "cmd", ProcessTreeOptions{MaxDepth: 2, Ancestors: 3})
+	a.NoError(err)
+	a.True(tree.IsTruncated)
+	a.Equal("explorer", tree.Root.Atom)
+	a.Len(tree.Nodes, 5)
+
+	walked := []string{}
+	tree.Walk(func(n *ProcessNode, depth int) bool {
+		walked = append(walked, fmt.Sprintf("%d:%s", depth, n.Atom))
+		return true
+	})
+	a.Equal([]string{"0:explorer", "1:cmd", "2:whoami", "2:ps", "3:rundll"}, walked)
+
+	cmd := tree.Nodes["cmd"]
+	a.Equal(`c:\windows\system32\cmd.exe`, cmd.Process.FilePath)
+	a.Len(cmd.Events, 1)
+	a.Equal("DNS_REQUEST", cmd.Events[0].Routing.EventType)
+	rundll := tree.Nodes["rundll"]
+	a.Equal(3, rundll.Depth())
+	a.Equal([]*ProcessNode{tree.Nodes["ps"], cmd, tree.Root}, rundll.Ancestors())
+	a.Equal([]*ProcessNode{tree.Nodes["whoami"], tree.Nodes["ps"], rundll}, cmd.Descendants())
+
+	found := tree.Find(func(n *ProcessNode) bool {
+		return strings.HasSuffix(n.Process.FilePath, "powershell.exe")
+	})
+	a.Equal([]*ProcessNode{tree.Nodes["ps"]}, found)
+
+	_, err = org.ProcessTree("s1", "missing")
+	a.Error(err)
+}
+
+func TestNewProcessTreeMissingRoot(t *testing.T) {
+	_, err := NewProcessTree([]Event{{Routing: Routing{EventType: "NEW_PROCESS", This: "a"}}}, "b")
+	assert.Error(t, err)
+}
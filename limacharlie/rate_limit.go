@@ -0,0 +1,60 @@
+package limacharlie
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// rateLimiter is a token bucket shared by the requests of a
+// client and of the clients derived from it, like for the
+// other Orgs of an OrgSet.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// newRateLimiter allows rate requests per second on average
+// and up to burst at once, which defaults to the rate.
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	b := float64(burst)
+	if burst <= 0 {
+		b = math.Max(1, math.Ceil(rate))
+	}
+	return &rateLimiter{
+		rate:   rate,
+		burst:  b,
+		tokens: b,
+		now:    time.Now,
+		sleep:  time.Sleep,
+	}
+}
+
+// wait blocks until a request is allowed. Requests reserve
+// their token when called, so they are served in order.
+func (l *rateLimiter) wait() {
+	if l == nil {
+		return
+	}
+	l.mutex.Lock()
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+	l.last = now
+	l.tokens--
+	delay := time.Duration(0)
+	if l.tokens < 0 {
+		delay = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mutex.Unlock()
+	if delay > 0 {
+		l.sleep(delay)
+	}
+}
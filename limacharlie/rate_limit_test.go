@@ -0,0 +1,71 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1000, 0)
+	slept := []time.Duration{}
+	l := newRateLimiter(2, 2)
+	l.now = func() time.Time { return now }
+	l.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+
+	// The burst goes through at once.
+	l.wait()
+	l.wait()
+	a.Empty(slept)
+
+	// Then one request every 1/2s.
+	l.wait()
+	l.wait()
+	a.Equal([]time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, slept)
+
+	// Idle time refills the bucket, up to the burst.
+	now = now.Add(time.Minute)
+	slept = slept[:0]
+	l.wait()
+	l.wait()
+	l.wait()
+	a.Equal([]time.Duration{500 * time.Millisecond}, slept)
+
+	// A nil limiter does not limit.
+	var none *rateLimiter
+	none.wait()
+}
+
+func TestRateLimiterDefaultBurst(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(float64(3), newRateLimiter(2.5, 0).burst)
+	a.Equal(float64(1), newRateLimiter(0.1, 0).burst)
+}
+
+func TestClientRateLimitShared(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+	org.client.limiter = newRateLimiter(1, 1)
+	now := time.Unix(1000, 0)
+	slept := time.Duration(0)
+	org.client.limiter.now = func() time.Time { return now }
+	org.client.limiter.sleep = func(d time.Duration) {
+		slept += d
+		now = now.Add(d)
+	}
+
+	other := org.client.forOrg("other-oid")
+	a.Same(org.client.limiter, other.limiter)
+	resp := Dict{}
+	a.NoError(org.client.reliableRequest(http.MethodGet, "orgs/x", makeDefaultRequest(&resp)))
+	a.NoError(other.reliableRequest(http.MethodGet, "orgs/y", makeDefaultRequest(&resp)))
+	a.Equal(time.Second, slept)
+}
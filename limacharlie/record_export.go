@@ -0,0 +1,344 @@
+package limacharlie
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportFormat is the file format of exported records.
+type ExportFormat = string
+
+var ExportFormats = struct {
+	NDJSON  ExportFormat
+	CSV     ExportFormat
+	Parquet ExportFormat
+}{
+	NDJSON:  "ndjson",
+	CSV:     "csv",
+	Parquet: "parquet",
+}
+
+// ExportColumnType is the type of the values of an ExportColumn.
+type ExportColumnType = string
+
+var ExportColumnTypes = struct {
+	String ExportColumnType
+	Int    ExportColumnType
+	Float  ExportColumnType
+	Bool   ExportColumnType
+	// JSON columns hold lists, serialized as JSON.
+	JSON ExportColumnType
+}{
+	String: "string",
+	Int:    "int",
+	Float:  "float",
+	Bool:   "bool",
+	JSON:   "json",
+}
+
+// ExportColumn is a leaf of the exported records, at a
+// path like the ones of Dict.FindByPath, "routing/sid".
+type ExportColumn struct {
+	Path string           `json:"path"`
+	Type ExportColumnType `json:"type"`
+}
+
+// ExportSchema is the schema inferred from all the exported
+// records, its columns sorted by path.
+type ExportSchema struct {
+	Columns []ExportColumn `json:"columns"`
+}
+
+// ExportRecordWriter writes records in a format.
+type ExportRecordWriter interface {
+	WriteRecord(record Dict) error
+	// Close flushes the records, it does not close the io.Writer.
+	Close() error
+}
+
+// ExportWriterFactory creates a writer of records of the schema to w.
+type ExportWriterFactory func(w io.Writer, schema ExportSchema) (ExportRecordWriter, error)
+
+// ErrorNoExportWriter is returned when exporting to a format
+// without a writer, like Parquet which needs one to be provided
+// in RecordExportOptions.Writers.
+var ErrorNoExportWriter = errors.New("no writer for export format")
+
+// RecordExportOptions configures ExportDetections and ExportSensorEvents.
+type RecordExportOptions struct {
+	Start time.Time
+	End   time.Time
+	// Format of the file, defaults to NDJSON.
+	Format ExportFormat
+	// Path of the file written.
+	Path string
+	// Writers of formats, in addition to or replacing the built-in
+	// NDJSON and CSV ones.
+	Writers map[ExportFormat]ExportWriterFactory
+}
+
+// RecordExportResult reports what an export did.
+type RecordExportResult struct {
+	Records int
+	Schema  ExportSchema
+}
+
+// ExportDetections writes the detections of the Org reported between
+// Start and End to a file, for offline analysis.
+func (org Organization) ExportDetections(opts RecordExportOptions) (RecordExportResult, error) {
+	return org.exportRecords(fmt.Sprintf("insight/%s/detections", org.client.options.OID), opts)
+}
+
+// ExportSensorEvents writes the events of a sensor between
+// Start and End to a file, like ExportDetections.
+func (org Organization) ExportSensorEvents(sensorID string, opts RecordExportOptions) (RecordExportResult, error) {
+	return org.exportRecords(fmt.Sprintf("insight/%s/%s", org.client.options.OID, sensorID), opts)
+}
+
+// exportRecords spools the records to a temporary NDJSON file while
+// inferring their schema, which formats like CSV need before writing
+// the first record, then writes them in the format. The file is only
+// created once complete.
+func (org Organization) exportRecords(path string, opts RecordExportOptions) (RecordExportResult, error) {
+	result := RecordExportResult{}
+	if opts.Path == "" {
+		return result, errors.New("export path is required")
+	}
+	format := opts.Format
+	if format == "" {
+		format = ExportFormats.NDJSON
+	}
+	factory, ok := opts.Writers[format]
+	if !ok {
+		factory, ok = builtinExportWriters[format]
+	}
+	if !ok {
+		return result, fmt.Errorf("%w: %s", ErrorNoExportWriter, format)
+	}
+
+	spool, err := ioutil.TempFile(filepath.Dir(opts.Path), ".export-*")
+	if err != nil {
+		return result, err
+	}
+	defer os.Remove(spool.Name())
+	defer spool.Close()
+
+	inferer := newExportSchemaInferer()
+	enc := json.NewEncoder(spool)
+	org.insightRecordsIter(context.Background(), path, opts.Start, opts.End)(func(r Dict, e error) bool {
+		if e != nil {
+			err = e
+			return false
+		}
+		inferer.add(r)
+		result.Records++
+		err = enc.Encode(r)
+		return err == nil
+	})
+	if err != nil {
+		return result, err
+	}
+	result.Schema = inferer.schema()
+	if _, err := spool.Seek(0, io.SeekStart); err != nil {
+		return result, err
+	}
+
+	partial := opts.Path + artifactPartialSuffix
+	if err := writeExportFile(partial, spool, factory, result.Schema); err != nil {
+		os.Remove(partial)
+		return result, err
+	}
+	return result, os.Rename(partial, opts.Path)
+}
+
+func writeExportFile(path string, spool io.Reader, factory ExportWriterFactory, schema ExportSchema) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	out := bufio.NewWriter(f)
+	w, err := factory(out, schema)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(spool)
+	for {
+		r := Dict{}
+		if err := dec.Decode(&r); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if err := w.WriteRecord(r); err != nil {
+			return err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// exportSchemaInferer widens the type of each column to fit all the
+// values seen: ints to floats, and conflicting types to strings.
+type exportSchemaInferer struct {
+	types map[string]ExportColumnType
+}
+
+func newExportSchemaInferer() *exportSchemaInferer {
+	return &exportSchemaInferer{types: map[string]ExportColumnType{}}
+}
+
+func (s *exportSchemaInferer) add(record Dict) {
+	flattenExportRecord("", map[string]interface{}(record), func(path string, v interface{}) {
+		t := exportValueType(v)
+		if t == "" {
+			return
+		}
+		prev, ok := s.types[path]
+		switch {
+		case !ok || prev == t:
+			s.types[path] = t
+		case (prev == ExportColumnTypes.Int && t == ExportColumnTypes.Float) || (prev == ExportColumnTypes.Float && t == ExportColumnTypes.Int):
+			s.types[path] = ExportColumnTypes.Float
+		default:
+			s.types[path] = ExportColumnTypes.String
+		}
+	})
+}
+
+func (s *exportSchemaInferer) schema() ExportSchema {
+	schema := ExportSchema{Columns: []ExportColumn{}}
+	for path, t := range s.types {
+		schema.Columns = append(schema.Columns, ExportColumn{Path: path, Type: t})
+	}
+	sort.Slice(schema.Columns, func(i, j int) bool {
+		return schema.Columns[i].Path < schema.Columns[j].Path
+	})
+	return schema
+}
+
+// flattenExportRecord calls cb with the path of every leaf of
+// the record, lists being leaves.
+func flattenExportRecord(prefix string, m map[string]interface{}, cb func(path string, v interface{})) {
+	for k, v := range m {
+		path := k
+		if prefix != "" {
+			path = prefix + "/" + k
+		}
+		switch c := v.(type) {
+		case map[string]interface{}:
+			flattenExportRecord(path, c, cb)
+		case Dict:
+			flattenExportRecord(path, c, cb)
+		default:
+			cb(path, v)
+		}
+	}
+}
+
+// exportValueType returns the type of a leaf, empty for nulls.
+func exportValueType(v interface{}) ExportColumnType {
+	switch v.(type) {
+	case nil:
+		return ""
+	case string:
+		return ExportColumnTypes.String
+	case bool:
+		return ExportColumnTypes.Bool
+	case int, int64, uint64:
+		return ExportColumnTypes.Int
+	case float64:
+		return ExportColumnTypes.Float
+	}
+	return ExportColumnTypes.JSON
+}
+
+var builtinExportWriters = map[ExportFormat]ExportWriterFactory{
+	ExportFormats.NDJSON: newNDJSONExportWriter,
+	ExportFormats.CSV:    newCSVExportWriter,
+}
+
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONExportWriter(w io.Writer, schema ExportSchema) (ExportRecordWriter, error) {
+	return &ndjsonExportWriter{enc: json.NewEncoder(w)}, nil
+}
+
+func (w *ndjsonExportWriter) WriteRecord(record Dict) error {
+	return w.enc.Encode(record)
+}
+
+func (w *ndjsonExportWriter) Close() error {
+	return nil
+}
+
+// csvExportWriter writes a column per path of the schema,
+// with a header row of the paths.
+type csvExportWriter struct {
+	w      *csv.Writer
+	schema ExportSchema
+}
+
+func newCSVExportWriter(w io.Writer, schema ExportSchema) (ExportRecordWriter, error) {
+	cw := &csvExportWriter{w: csv.NewWriter(w), schema: schema}
+	header := []string{}
+	for _, c := range schema.Columns {
+		header = append(header, c.Path)
+	}
+	return cw, cw.w.Write(header)
+}
+
+func (w *csvExportWriter) WriteRecord(record Dict) error {
+	row := make([]string, len(w.schema.Columns))
+	for i, c := range w.schema.Columns {
+		v, ok := record.FindByPath(c.Path)
+		if !ok || v == nil {
+			continue
+		}
+		s, err := formatExportValue(v)
+		if err != nil {
+			return fmt.Errorf("%s: %v", c.Path, err)
+		}
+		row[i] = s
+	}
+	return w.w.Write(row)
+}
+
+func (w *csvExportWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func formatExportValue(v interface{}) (string, error) {
+	switch c := v.(type) {
+	case string:
+		return c, nil
+	case bool:
+		return strconv.FormatBool(c), nil
+	case int64:
+		return strconv.FormatInt(c, 10), nil
+	case float64:
+		return strconv.FormatFloat(c, 'f', -1, 64), nil
+	}
+	data, err := json.Marshal(v)
+	return strings.TrimSpace(string(data)), err
+}
@@ -0,0 +1,84 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExportDetections(t *testing.T) {
+	a := assert.New(t)
+
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Query().Get("cursor") == "" {
+			return jsonResponse(http.StatusOK, `{
+				"detects": [{"cat": "first", "ts": 1, "routing": {"sid": "s1", "tags": ["a", "b"]}}],
+				"next_cursor": "c1"
+			}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{
+			"detects": [{"cat": "second", "ts": 2.5, "routing": {"sid": "s2"}, "detect": {"count": 3}}]
+		}`), nil
+	}))
+
+	dir := t.TempDir()
+	opts := RecordExportOptions{
+		Start:  time.Unix(0, 0),
+		End:    time.Unix(10, 0),
+		Format: ExportFormats.CSV,
+		Path:   filepath.Join(dir, "detections.csv"),
+	}
+	result, err := org.ExportDetections(opts)
+	a.NoError(err)
+	a.Equal(2, result.Records)
+	a.Equal(ExportSchema{Columns: []ExportColumn{
+		{Path: "cat", Type: ExportColumnTypes.String},
+		{Path: "detect/count", Type: ExportColumnTypes.Int},
+		{Path: "routing/sid", Type: ExportColumnTypes.String},
+		{Path: "routing/tags", Type: ExportColumnTypes.JSON},
+		{Path: "ts", Type: ExportColumnTypes.Float},
+	}}, result.Schema)
+	data, err := ioutil.ReadFile(opts.Path)
+	a.NoError(err)
+	a.Equal("cat,detect/count,routing/sid,routing/tags,ts\n"+
+		"first,,s1,\"[\"\"a\"\",\"\"b\"\"]\",1\n"+
+		"second,3,s2,,2.5\n", string(data))
+
+	opts.Format = ExportFormats.NDJSON
+	opts.Path = filepath.Join(dir, "detections.ndjson")
+	_, err = org.ExportDetections(opts)
+	a.NoError(err)
+	data, err = ioutil.ReadFile(opts.Path)
+	a.NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	a.Len(lines, 2)
+	first := Dict{}
+	a.NoError(json.Unmarshal([]byte(lines[0]), &first))
+	sid, _ := first.GetString("routing/sid")
+	a.Equal("s1", sid)
+
+	opts.Format = ExportFormats.Parquet
+	opts.Path = filepath.Join(dir, "detections.parquet")
+	_, err = org.ExportDetections(opts)
+	a.True(errors.Is(err, ErrorNoExportWriter))
+
+	columns := 0
+	opts.Writers = map[ExportFormat]ExportWriterFactory{
+		ExportFormats.Parquet: func(w io.Writer, schema ExportSchema) (ExportRecordWriter, error) {
+			columns = len(schema.Columns)
+			return newNDJSONExportWriter(w, schema)
+		},
+	}
+	result, err = org.ExportDetections(opts)
+	a.NoError(err)
+	a.Equal(5, columns)
+	a.FileExists(opts.Path)
+}
@@ -0,0 +1,80 @@
+package limacharlie
+
+import (
+	"fmt"
+	"time"
+)
+
+type ReliableTaskID = string
+
+// ReliableTask is a task queued by the reliable-tasking
+// service until the sensor comes online or the task expires.
+type ReliableTask struct {
+	TaskID    ReliableTaskID `json:"task_id"`
+	SID       string         `json:"sid"`
+	Task      string         `json:"task"`
+	Context   string         `json:"context,omitempty"`
+	CreatedAt int64          `json:"created"`
+	ExpiresAt int64          `json:"expiry"`
+}
+
+type ReliableTaskOptions struct {
+	// Context returned in the investigation id of the responses.
+	Context string
+}
+
+func (org Organization) reliableTasking(responseData interface{}, action string, req Dict) error {
+	reqData := req
+	reqData["action"] = action
+	return org.client.serviceRequest(responseData, "reliable-tasking", reqData, false)
+}
+
+// ReliableTask queues a task for all the sensors matching the
+// selector, sent as soon as each sensor is online within the ttl.
+func (org Organization) ReliableTask(selector string, task string, ttl time.Duration, options ...ReliableTaskOptions) error {
+	if err := ValidateSelector(selector); err != nil {
+		return fmt.Errorf("invalid selector: %v", err)
+	}
+	req := Dict{
+		"selector": selector,
+		"task":     task,
+		"ttl":      int64(ttl.Seconds()),
+	}
+	if len(options) != 0 && options[0].Context != "" {
+		req["context"] = options[0].Context
+	}
+	resp := Dict{}
+	return org.reliableTasking(&resp, "task", req)
+}
+
+// ReliableTasks lists the pending tasks of the sensors matching the selector.
+func (org Organization) ReliableTasks(selector string) ([]ReliableTask, error) {
+	resp := struct {
+		Tasks map[string][]ReliableTask `json:"tasks"`
+	}{}
+	if err := org.reliableTasking(&resp, "list", Dict{
+		"selector": selector,
+	}); err != nil {
+		return nil, err
+	}
+	tasks := []ReliableTask{}
+	for sid, sensorTasks := range resp.Tasks {
+		for _, t := range sensorTasks {
+			if t.SID == "" {
+				t.SID = sid
+			}
+			tasks = append(tasks, t)
+		}
+	}
+	return tasks, nil
+}
+
+// ReliableTaskCancel cancels a pending task for
+// all the sensors matching the selector.
+func (org Organization) ReliableTaskCancel(selector string, taskID ReliableTaskID) error {
+	resp := Dict{}
+	return org.reliableTasking(&resp, "untask", Dict{
+		"selector": selector,
+		"task_id":  taskID,
+	})
+}
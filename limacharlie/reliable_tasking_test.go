@@ -0,0 +1,33 @@
+package limacharlie
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReliableTaskAddCancel(t *testing.T) {
+	a := assert.New(t)
+	org := getTestOrgFromEnv(a)
+
+	unsubReplicantCB, err := findUnsubscribeReplicantCallback(org, "reliable-tasking")
+	a.NoError(err)
+	if unsubReplicantCB != nil {
+		defer unsubReplicantCB()
+	}
+
+	selector := fmt.Sprintf("hostname == `test-reliable-%d`", time.Now().Unix())
+	a.NoError(org.ReliableTask(selector, "os_version", 1*time.Hour, ReliableTaskOptions{Context: "test"}))
+
+	tasks, err := org.ReliableTasks(selector)
+	a.NoError(err)
+	for _, task := range tasks {
+		a.NoError(org.ReliableTaskCancel(selector, task.TaskID))
+	}
+
+	tasks, err = org.ReliableTasks(selector)
+	a.NoError(err)
+	a.Empty(tasks)
+}
@@ -3,6 +3,7 @@ package limacharlie
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -71,7 +72,7 @@ func (r *ResourcesByCategory) RemoveFromCategory(category ResourceCategory, name
 // Resources list available resources
 func (org Organization) Resources() (ResourcesByCategory, error) {
 	resp := resourceGetResponse{}
-	req := makeDefaultRequest(&resp).withTimeout(10 * time.Second)
+	req := makeDefaultRequest(&resp).withTimeout(10 * time.Second).withCache()
 	if err := org.resources(http.MethodGet, req); err != nil {
 		return ResourcesByCategory{}, err
 	}
@@ -117,3 +118,54 @@ func (org Organization) ResourceUnsubscribe(name ResourceName, category Resource
 	})
 	return org.resources(http.MethodDelete, req)
 }
+
+// LookupResource queries a subscribed API resource, like
+// "api/vt" or "api/ip-geo", for an indicator and returns
+// the resource's response.
+func (org Organization) LookupResource(resource string, indicator string) (Dict, error) {
+	name, err := parseAPIResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	resp := Dict{}
+	req := makeDefaultRequest(&resp).withTimeout(30 * time.Second).withQueryData(Dict{
+		"key": indicator,
+	})
+	if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("orgs/%s/resources/%s/%s", org.client.options.OID, ResourceCategories.API, name), req); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// LookupIPGeo returns the geolocation of an IP address
+// using the "ip-geo" API resource.
+func (org Organization) LookupIPGeo(ip string) (Dict, error) {
+	return org.LookupResource("api/ip-geo", ip)
+}
+
+// LookupVirusTotal returns the VirusTotal report of a hash
+// using the "vt" API resource.
+func (org Organization) LookupVirusTotal(hash string) (Dict, error) {
+	return org.LookupResource("api/vt", hash)
+}
+
+// parseAPIResource returns the name of an API resource
+// given as "api/<name>", "api:<name>" or "<name>".
+func parseAPIResource(resource string) (ResourceName, error) {
+	name := resource
+	for _, sep := range []string{"/", ":"} {
+		components := strings.SplitN(resource, sep, 2)
+		if len(components) != 2 {
+			continue
+		}
+		if components[0] != ResourceCategories.API {
+			return "", fmt.Errorf("resource %s is not an api resource", resource)
+		}
+		name = components[1]
+		break
+	}
+	if name == "" {
+		return "", fmt.Errorf("invalid resource: %s", resource)
+	}
+	return name, nil
+}
@@ -37,3 +37,17 @@ func TestResourceAddDelete(t *testing.T) {
 	a.NoError(err)
 	a.Equal(resourcesBase, resources)
 }
+
+func TestParseAPIResource(t *testing.T) {
+	a := assert.New(t)
+
+	for _, r := range []string{"api/vt", "api:vt", "vt"} {
+		name, err := parseAPIResource(r)
+		a.NoError(err)
+		a.Equal("vt", name)
+	}
+	_, err := parseAPIResource("replicant/yara")
+	a.Error(err)
+	_, err = parseAPIResource("api/")
+	a.Error(err)
+}
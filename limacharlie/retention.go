@@ -28,6 +28,7 @@ type Routing struct {
 	ExtIP     string   `json:"ext_ip"`
 	Hostname  string   `json:"hostname"`
 	IID       string   `json:"iid"`
+	InvID     string   `json:"investigation_id,omitempty"`
 	IntIP     string   `json:"int_ip"`
 	ModuleID  int      `json:"moduleid"`
 	OID       string   `json:"oid"`
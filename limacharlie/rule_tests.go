@@ -0,0 +1,111 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type RuleTestName = string
+
+// OrgSyncRuleTest declares a sample event and whether
+// a D&R rule from the same config is expected to match it.
+type OrgSyncRuleTest struct {
+	Rule    DRRuleName `json:"rule" yaml:"rule"`
+	Event   Dict       `json:"event" yaml:"event"`
+	IsMatch bool       `json:"match" yaml:"match"`
+	Lookups DRLookups  `json:"lookups,omitempty" yaml:"lookups,omitempty"`
+}
+
+type RuleTestResult struct {
+	Name            RuleTestName
+	Rule            DRRuleName
+	IsExpectedMatch bool
+	IsMatch         bool
+	Error           error
+}
+
+func (r RuleTestResult) IsSuccess() bool {
+	return r.Error == nil && r.IsMatch == r.IsExpectedMatch
+}
+
+func (r RuleTestResult) String() string {
+	if r.Error != nil {
+		return fmt.Sprintf("%s (%s): error: %v", r.Name, r.Rule, r.Error)
+	}
+	if !r.IsSuccess() {
+		return fmt.Sprintf("%s (%s): expected match=%t, got match=%t", r.Name, r.Rule, r.IsExpectedMatch, r.IsMatch)
+	}
+	return fmt.Sprintf("%s (%s): ok", r.Name, r.Rule)
+}
+
+// RunRuleTests evaluates every test from the "rules_tests" section
+// locally against the D&R rules of the config. An error is returned
+// if any of the tests fail, results for all tests are always returned.
+// Lookups found in the "lookup" hive of the config are available to
+// rules as "hive://lookup/<name>", in addition to the ones declared
+// by each test.
+func (o OrgConfig) RunRuleTests() ([]RuleTestResult, error) {
+	hiveLookups := o.ruleTestHiveLookups()
+
+	names := []RuleTestName{}
+	for name := range o.RuleTests {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := []RuleTestResult{}
+	failed := []string{}
+	for _, name := range names {
+		test := o.RuleTests[name]
+		result := RuleTestResult{
+			Name:            name,
+			Rule:            test.Rule,
+			IsExpectedMatch: test.IsMatch,
+		}
+		rule, ok := o.DRRules[test.Rule]
+		if !ok {
+			result.Error = fmt.Errorf("rule not found: %s", test.Rule)
+		} else {
+			lookups := DRLookups{}
+			for k, v := range hiveLookups {
+				lookups[k] = v
+			}
+			for k, v := range test.Lookups {
+				lookups[k] = v
+			}
+			event := test.Event
+			if _, ok := event["event"]; !ok {
+				if _, ok := event["routing"]; !ok {
+					event = Dict{"event": map[string]interface{}(test.Event)}
+				}
+			}
+			result.IsMatch, result.Error = EvaluateDetection(rule.Detect, event, lookups)
+		}
+		if !result.IsSuccess() {
+			failed = append(failed, name)
+		}
+		results = append(results, result)
+	}
+
+	if len(failed) != 0 {
+		return results, fmt.Errorf("%d rule tests failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+func (o OrgConfig) ruleTestHiveLookups() DRLookups {
+	lookups := DRLookups{}
+	for key, data := range o.Hives["lookup"] {
+		values, ok := data.Data["lookup_data"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		l := []string{}
+		for v := range values {
+			l = append(l, v)
+		}
+		lookups[fmt.Sprintf("hive://lookup/%s", key)] = l
+	}
+	return lookups
+}
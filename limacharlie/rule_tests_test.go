@@ -0,0 +1,156 @@
+package limacharlie
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestRunRuleTests(t *testing.T) {
+	a := assert.New(t)
+
+	conf := `
+rules:
+  suspicious-exec:
+    detect:
+      event: NEW_PROCESS
+      op: and
+      rules:
+        - op: ends with
+          path: event/FILE_PATH
+          value: \evil.exe
+          case sensitive: false
+        - op: is platform
+          name: windows
+    respond:
+      - action: report
+        name: suspicious-exec
+  bad-domain:
+    detect:
+      event: DNS_REQUEST
+      op: lookup
+      path: event/DOMAIN_NAME
+      resource: hive://lookup/bad-domains
+    respond:
+      - action: report
+        name: bad-domain
+hives:
+  lookup:
+    bad-domains:
+      data:
+        lookup_data:
+          evil.com: {}
+      usr_mtd:
+        enabled: true
+rules_tests:
+  evil-exec:
+    rule: suspicious-exec
+    match: true
+    event:
+      routing:
+        event_type: NEW_PROCESS
+        plat: 268435456
+      event:
+        FILE_PATH: C:\Temp\EVIL.exe
+  benign-exec:
+    rule: suspicious-exec
+    match: false
+    event:
+      routing:
+        event_type: NEW_PROCESS
+        plat: 268435456
+      event:
+        FILE_PATH: C:\Windows\notepad.exe
+  other-platform:
+    rule: suspicious-exec
+    match: false
+    event:
+      routing:
+        event_type: NEW_PROCESS
+        plat: 536870912
+      event:
+        FILE_PATH: /tmp/evil.exe
+  evil-domain:
+    rule: bad-domain
+    match: true
+    event:
+      routing:
+        event_type: DNS_REQUEST
+      event:
+        DOMAIN_NAME: evil.com
+  custom-lookup:
+    rule: bad-domain
+    match: true
+    lookups:
+      hive://lookup/bad-domains:
+        - other.com
+    event:
+      routing:
+        event_type: DNS_REQUEST
+      event:
+        DOMAIN_NAME: other.com
+`
+	orgConfig := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(conf), &orgConfig))
+
+	results, err := orgConfig.RunRuleTests()
+	a.NoError(err)
+	a.Len(results, 5)
+	for _, r := range results {
+		a.True(r.IsSuccess(), r.String())
+	}
+
+	orgConfig.RuleTests["wrong-expectation"] = OrgSyncRuleTest{
+		Rule:    "bad-domain",
+		IsMatch: true,
+		Event:   Dict{"routing": Dict{"event_type": "DNS_REQUEST"}, "event": Dict{"DOMAIN_NAME": "good.com"}},
+	}
+	orgConfig.RuleTests["missing-rule"] = OrgSyncRuleTest{Rule: "nope"}
+	results, err = orgConfig.RunRuleTests()
+	a.Error(err)
+	a.Len(results, 7)
+	failed := []string{}
+	for _, r := range results {
+		if !r.IsSuccess() {
+			failed = append(failed, r.Name)
+		}
+	}
+	a.Equal([]string{"missing-rule", "wrong-expectation"}, failed)
+}
+
+func TestEvaluateDetection(t *testing.T) {
+	a := assert.New(t)
+
+	event := Dict{
+		"routing": Dict{"event_type": "NETWORK_CONNECTIONS", "tags": []interface{}{"vip"}},
+		"event": Dict{
+			"NETWORK_ACTIVITY": []interface{}{
+				Dict{"DESTINATION": Dict{"PORT": 22}},
+				Dict{"DESTINATION": Dict{"PORT": 4444}},
+			},
+		},
+	}
+
+	isMatch, err := EvaluateDetection(Dict{"op": "is", "path": "event/NETWORK_ACTIVITY/*/DESTINATION/PORT", "value": 4444}, event, nil)
+	a.NoError(err)
+	a.True(isMatch)
+
+	isMatch, err = EvaluateDetection(Dict{"op": "is greater than", "path": "event/NETWORK_ACTIVITY/0/DESTINATION/PORT", "value": 1024}, event, nil)
+	a.NoError(err)
+	a.False(isMatch)
+
+	isMatch, err = EvaluateDetection(Dict{"op": "is tagged", "tag": "vip", "not": true}, event, nil)
+	a.NoError(err)
+	a.False(isMatch)
+
+	isMatch, err = EvaluateDetection(Dict{"event": "DNS_REQUEST", "op": "exists", "path": "event"}, event, nil)
+	a.NoError(err)
+	a.False(isMatch)
+
+	_, err = EvaluateDetection(Dict{"op": "lookup", "path": "event/X", "resource": "hive://lookup/missing"}, event, nil)
+	a.Error(err)
+
+	_, err = EvaluateDetection(Dict{"op": "is windows"}, event, nil)
+	a.Error(err)
+}
@@ -0,0 +1,646 @@
+package limacharlie
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// SelectorEnv holds the values a sensor selector is evaluated
+// against. Values are either a string or a []string.
+type SelectorEnv map[string]interface{}
+
+// Selector is a parsed sensor selector expression like:
+//
+//	plat == windows and ("vip" in tags or hostname matches "^dc-")
+type Selector struct {
+	raw    string
+	root   selectorNode
+	fields []string
+}
+
+type selectorNode interface {
+	eval(env SelectorEnv) (bool, error)
+}
+
+type selectorTokenType int
+
+const (
+	selectorTokenWord selectorTokenType = iota
+	selectorTokenString
+	selectorTokenOp
+	selectorTokenOpen
+	selectorTokenClose
+	selectorTokenComma
+)
+
+type selectorToken struct {
+	t   selectorTokenType
+	v   string
+	pos int
+}
+
+// ParseSelector parses a sensor selector expression.
+func ParseSelector(s string) (*Selector, error) {
+	tokens, err := tokenizeSelector(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty selector")
+	}
+	p := &selectorParser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isDone() {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().v, p.peek().pos)
+	}
+	return &Selector{raw: s, root: root, fields: p.fields}, nil
+}
+
+func (s *Selector) String() string {
+	return s.raw
+}
+
+// Fields returns the name of the fields referenced by the selector.
+func (s *Selector) Fields() []string {
+	return s.fields
+}
+
+// Evaluate returns true if the environment matches the selector.
+func (s *Selector) Evaluate(env SelectorEnv) (bool, error) {
+	return s.root.eval(env)
+}
+
+// MatchesSelector parses and evaluates a selector in one step.
+func MatchesSelector(selector string, env SelectorEnv) (bool, error) {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Evaluate(env)
+}
+
+func tokenizeSelector(s string) ([]selectorToken, error) {
+	tokens := []selectorToken{}
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		c := runes[i]
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, selectorToken{t: selectorTokenOpen, v: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, selectorToken{t: selectorTokenClose, v: ")", pos: i})
+			i++
+		case c == ',':
+			tokens = append(tokens, selectorToken{t: selectorTokenComma, v: ",", pos: i})
+			i++
+		case c == '=' || c == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return nil, fmt.Errorf("unexpected %q at position %d", string(c), i)
+			}
+			tokens = append(tokens, selectorToken{t: selectorTokenOp, v: string(runes[i : i+2]), pos: i})
+			i += 2
+		case c == '"' || c == '`' || c == '\'':
+			start := i
+			i++
+			val := strings.Builder{}
+			for i < len(runes) && runes[i] != c {
+				if runes[i] == '\\' && c == '"' && i+1 < len(runes) {
+					i++
+				}
+				val.WriteRune(runes[i])
+				i++
+			}
+			if i >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			i++
+			tokens = append(tokens, selectorToken{t: selectorTokenString, v: val.String(), pos: start})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("()!=,\"'`", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, selectorToken{t: selectorTokenWord, v: string(runes[start:i]), pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+type selectorParser struct {
+	tokens []selectorToken
+	i      int
+	fields []string
+}
+
+func (p *selectorParser) addField(field string) {
+	for _, f := range p.fields {
+		if f == field {
+			return
+		}
+	}
+	p.fields = append(p.fields, field)
+}
+
+func (p *selectorParser) isDone() bool {
+	return p.i >= len(p.tokens)
+}
+
+func (p *selectorParser) peek() selectorToken {
+	if p.isDone() {
+		return selectorToken{t: -1, pos: -1}
+	}
+	return p.tokens[p.i]
+}
+
+func (p *selectorParser) isKeyword(kw string) bool {
+	t := p.peek()
+	return t.t == selectorTokenWord && strings.EqualFold(t.v, kw)
+}
+
+func (p *selectorParser) parseOr() (selectorNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.i++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = selectorOr{left, right}
+	}
+	return left, nil
+}
+
+func (p *selectorParser) parseAnd() (selectorNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.i++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = selectorAnd{left, right}
+	}
+	return left, nil
+}
+
+func (p *selectorParser) parseUnary() (selectorNode, error) {
+	if p.isKeyword("not") {
+		p.i++
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return selectorNot{n}, nil
+	}
+	if p.peek().t == selectorTokenOpen {
+		p.i++
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().t != selectorTokenClose {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.i++
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *selectorParser) parseOperand() (selectorToken, error) {
+	t := p.peek()
+	if t.t != selectorTokenWord && t.t != selectorTokenString {
+		if p.isDone() {
+			return t, fmt.Errorf("unexpected end of selector")
+		}
+		return t, fmt.Errorf("unexpected %q at position %d", t.v, t.pos)
+	}
+	p.i++
+	return t, nil
+}
+
+func (p *selectorParser) parseList() ([]string, error) {
+	// Opening parenthesis already consumed.
+	out := []string{}
+	for {
+		t, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t.v)
+		next := p.peek()
+		p.i++
+		if next.t == selectorTokenClose {
+			return out, nil
+		}
+		if next.t != selectorTokenComma {
+			return nil, fmt.Errorf("expected ',' or ')' in list at position %d", next.pos)
+		}
+	}
+}
+
+func (p *selectorParser) parseComparison() (selectorNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	op := p.peek()
+	switch {
+	case op.t == selectorTokenOp:
+		p.i++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		p.addField(left.v)
+		return selectorCompare{field: left.v, value: right.v, isNot: op.v == "!="}, nil
+	case p.isKeyword("is"):
+		p.i++
+		isNot := false
+		if p.isKeyword("not") {
+			p.i++
+			isNot = true
+		}
+		if !p.isKeyword("empty") {
+			return nil, fmt.Errorf("expected 'empty' at position %d", p.peek().pos)
+		}
+		p.i++
+		p.addField(left.v)
+		return selectorEmpty{field: left.v, isNot: isNot}, nil
+	case p.isKeyword("matches"):
+		p.i++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		re, err := regexp.Compile(right.v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", right.v, err)
+		}
+		p.addField(left.v)
+		return selectorMatches{field: left.v, re: re}, nil
+	case p.isKeyword("contains"):
+		p.i++
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		p.addField(left.v)
+		return selectorContains{field: left.v, substring: right.v}, nil
+	case p.isKeyword("in") || p.isKeyword("not"):
+		isNot := false
+		if p.isKeyword("not") {
+			p.i++
+			isNot = true
+			if !p.isKeyword("in") {
+				return nil, fmt.Errorf("expected 'in' at position %d", p.peek().pos)
+			}
+		}
+		p.i++
+		if p.peek().t == selectorTokenOpen {
+			// field in (a, b, c)
+			p.i++
+			values, err := p.parseList()
+			if err != nil {
+				return nil, err
+			}
+			p.addField(left.v)
+			return selectorInList{field: left.v, values: values, isNot: isNot}, nil
+		}
+		// "value" in field
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		p.addField(right.v)
+		return selectorIn{field: right.v, value: left.v, isNot: isNot}, nil
+	}
+	if p.isDone() {
+		return nil, fmt.Errorf("missing operator after %q", left.v)
+	}
+	return nil, fmt.Errorf("unknown operator %q at position %d", op.v, op.pos)
+}
+
+type selectorOr struct{ l, r selectorNode }
+type selectorAnd struct{ l, r selectorNode }
+type selectorNot struct{ n selectorNode }
+
+func (n selectorOr) eval(env SelectorEnv) (bool, error) {
+	v, err := n.l.eval(env)
+	if err != nil || v {
+		return v, err
+	}
+	return n.r.eval(env)
+}
+
+func (n selectorAnd) eval(env SelectorEnv) (bool, error) {
+	v, err := n.l.eval(env)
+	if err != nil || !v {
+		return v, err
+	}
+	return n.r.eval(env)
+}
+
+func (n selectorNot) eval(env SelectorEnv) (bool, error) {
+	v, err := n.n.eval(env)
+	return !v, err
+}
+
+func (env SelectorEnv) values(field string) ([]string, error) {
+	v, ok := env[field]
+	if !ok {
+		return nil, fmt.Errorf("unknown selector field: %s", field)
+	}
+	switch val := v.(type) {
+	case string:
+		return []string{val}, nil
+	case []string:
+		return val, nil
+	case nil:
+		return nil, nil
+	}
+	return []string{fmt.Sprintf("%v", v)}, nil
+}
+
+type selectorCompare struct {
+	field string
+	value string
+	isNot bool
+}
+
+func (n selectorCompare) eval(env SelectorEnv) (bool, error) {
+	vals, err := env.values(n.field)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, v := range vals {
+		if v == n.value {
+			found = true
+			break
+		}
+	}
+	return found != n.isNot, nil
+}
+
+type selectorIn struct {
+	field string
+	value string
+	isNot bool
+}
+
+func (n selectorIn) eval(env SelectorEnv) (bool, error) {
+	vals, err := env.values(n.field)
+	if err != nil {
+		return false, err
+	}
+	found := false
+	for _, v := range vals {
+		if v == n.value {
+			found = true
+			break
+		}
+	}
+	return found != n.isNot, nil
+}
+
+// selectorContains is true if a value of the field contains the substring.
+type selectorContains struct {
+	field     string
+	substring string
+}
+
+func (n selectorContains) eval(env SelectorEnv) (bool, error) {
+	vals, err := env.values(n.field)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vals {
+		if strings.Contains(v, n.substring) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type selectorInList struct {
+	field  string
+	values []string
+	isNot  bool
+}
+
+func (n selectorInList) eval(env SelectorEnv) (bool, error) {
+	vals, err := env.values(n.field)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vals {
+		for _, candidate := range n.values {
+			if v == candidate {
+				return !n.isNot, nil
+			}
+		}
+	}
+	return n.isNot, nil
+}
+
+type selectorMatches struct {
+	field string
+	re    *regexp.Regexp
+}
+
+func (n selectorMatches) eval(env SelectorEnv) (bool, error) {
+	vals, err := env.values(n.field)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vals {
+		if n.re.MatchString(v) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type selectorEmpty struct {
+	field string
+	isNot bool
+}
+
+func (n selectorEmpty) eval(env SelectorEnv) (bool, error) {
+	vals, err := env.values(n.field)
+	if err != nil {
+		return false, err
+	}
+	isEmpty := true
+	for _, v := range vals {
+		if v != "" {
+			isEmpty = false
+			break
+		}
+	}
+	return isEmpty != n.isNot, nil
+}
+
+// SensorSelectorFields is all the fields sensor selectors can reference.
+var SensorSelectorFields = []string{
+	"sid",
+	"oid",
+	"iid",
+	"did",
+	"hostname",
+	"plat",
+	"ext_plat",
+	"arch",
+	"int_ip",
+	"ext_ip",
+	"tags",
+	"tag",
+	"isolated",
+	"kernel",
+	"enroll",
+	"alive",
+	"event_type",
+}
+
+// ValidateSelector checks that a selector parses and only references
+// known fields, so it can be validated before being pushed in a config.
+func ValidateSelector(selector string) error {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return err
+	}
+	for _, f := range s.Fields() {
+		isKnown := false
+		for _, k := range SensorSelectorFields {
+			if f == k {
+				isKnown = true
+				break
+			}
+		}
+		if !isKnown {
+			return fmt.Errorf("unknown selector field: %s", f)
+		}
+	}
+	return nil
+}
+
+// validateRequestSelector validates the sensor selector of a
+// service or extension request, like a reliable tasking request.
+func validateRequestSelector(req Dict) error {
+	selector, ok := req["selector"].(string)
+	if !ok {
+		return nil
+	}
+	if err := ValidateSelector(selector); err != nil {
+		return fmt.Errorf("invalid selector: %v", err)
+	}
+	return nil
+}
+
+// lintSelectorInvalid checks the sensor selectors of the extension
+// configs, like those of the exfil, artifact and reliable tasking
+// extensions. The selectors of the requests made by D&R rules are
+// checked along with their respond actions.
+func lintSelectorInvalid(conf OrgConfig) []LintFinding {
+	findings := []LintFinding{}
+	for name, record := range conf.Hives[extensionConfigHive] {
+		lintCollectSelectors(record.Data, []string{"hives", extensionConfigHive, name, "data"}, func(path []string, selector string) {
+			if err := ValidateSelector(selector); err != nil {
+				findings = append(findings, LintFinding{
+					Severity: LintSeverities.Error,
+					Message:  fmt.Sprintf("invalid selector: %v", err),
+				}.at(path...))
+			}
+		})
+	}
+	return findings
+}
+
+func lintCollectSelectors(node interface{}, path []string, found func(path []string, selector string)) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, v := range n {
+			p := append(append([]string{}, path...), k)
+			if selector, ok := v.(string); ok && k == "selector" {
+				found(p, selector)
+				continue
+			}
+			lintCollectSelectors(v, p, found)
+		}
+	case Dict:
+		lintCollectSelectors(map[string]interface{}(n), path, found)
+	case []interface{}:
+		for i, v := range n {
+			lintCollectSelectors(v, append(append([]string{}, path...), fmt.Sprintf("%d", i)), found)
+		}
+	}
+}
+
+// SelectorEnv returns the values sensor selectors are evaluated against.
+func (s *Sensor) SelectorEnv() SelectorEnv {
+	tags := s.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return SelectorEnv{
+		"sid":        s.SID,
+		"oid":        s.OID,
+		"iid":        s.IID,
+		"did":        s.DID,
+		"hostname":   s.Hostname,
+		"plat":       PlatformStrings[s.Platform],
+		"ext_plat":   PlatformStrings[s.Platform],
+		"arch":       ArchitectureStrings[s.Architecture],
+		"int_ip":     s.InternalIP,
+		"ext_ip":     s.ExternalIP,
+		"tags":       tags,
+		"tag":        tags,
+		"isolated":   fmt.Sprintf("%t", s.IsIsolated),
+		"kernel":     fmt.Sprintf("%t", s.IsKernelAvailable),
+		"enroll":     s.EnrollTS,
+		"alive":      s.AliveTS,
+		"event_type": "",
+	}
+}
+
+// Matches returns true if the sensor matches the selector.
+// Tags are only evaluated from the Tags field of the sensor.
+func (s *Sensor) Matches(selector string) (bool, error) {
+	return MatchesSelector(selector, s.SelectorEnv())
+}
+
+// FilterSensors returns the sensors matching the selector, evaluated
+// locally, like on the result of ListSensors().
+func FilterSensors(sensors map[string]*Sensor, selector string) (map[string]*Sensor, error) {
+	s, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]*Sensor{}
+	for sid, sensor := range sensors {
+		isMatch, err := s.Evaluate(sensor.SelectorEnv())
+		if err != nil {
+			return nil, err
+		}
+		if isMatch {
+			m[sid] = sensor
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,138 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSelector(t *testing.T) {
+	a := assert.New(t)
+
+	s, err := ParseSelector(`plat == windows and ("vip" in tags or hostname matches "^dc-")`)
+	a.NoError(err)
+	a.Equal([]string{"plat", "tags", "hostname"}, s.Fields())
+
+	s, err = ParseSelector(`hostname contains "corp" or tags contains "vi"`)
+	a.NoError(err)
+	a.Equal([]string{"hostname", "tags"}, s.Fields())
+	a.Equal(selectorContains{field: "hostname", substring: "corp"}, s.root.(selectorOr).l)
+	for env, expected := range map[string]bool{
+		"dc-corp-1": true,
+		"laptop":    false,
+	} {
+		v, err := s.Evaluate(SelectorEnv{"hostname": env, "tags": []string{"vip"}})
+		a.NoError(err)
+		a.True(v, env)
+		v, err = s.Evaluate(SelectorEnv{"hostname": env, "tags": []string{}})
+		a.NoError(err)
+		a.Equal(expected, v, env)
+	}
+
+	for _, invalid := range []string{
+		``,
+		`plat ==`,
+		`plat = windows`,
+		`(plat == windows`,
+		`plat == windows)`,
+		`hostname matches "("`,
+		`tag in (a b)`,
+		`hostname "unterminated`,
+	} {
+		_, err := ParseSelector(invalid)
+		a.Error(err, invalid)
+	}
+}
+
+func TestValidateSelector(t *testing.T) {
+	a := assert.New(t)
+	a.NoError(ValidateSelector(`plat == linux and isolated == false`))
+	a.Error(ValidateSelector(`platform == linux`))
+	a.Error(ValidateSelector(`plat ==`))
+}
+
+func TestFilterSensors(t *testing.T) {
+	a := assert.New(t)
+	sensors := map[string]*Sensor{
+		"s1": {SID: "s1", Platform: Platforms.Windows, Hostname: "dc-01", Tags: []string{"vip"}},
+		"s2": {SID: "s2", Platform: Platforms.Linux, Hostname: "web-01", IsIsolated: true},
+		"s3": {SID: "s3", Platform: Platforms.Windows, Hostname: "ws-01"},
+	}
+
+	m, err := FilterSensors(sensors, `plat == windows`)
+	a.NoError(err)
+	a.Len(m, 2)
+
+	m, err = FilterSensors(sensors, `"vip" in tags or isolated == true`)
+	a.NoError(err)
+	a.Len(m, 2)
+	a.Contains(m, "s1")
+	a.Contains(m, "s2")
+
+	m, err = FilterSensors(sensors, `plat == windows and not hostname matches "^dc-"`)
+	a.NoError(err)
+	a.Len(m, 1)
+	a.Contains(m, "s3")
+
+	_, err = FilterSensors(sensors, `plat in (`)
+	a.Error(err)
+}
+
+func TestLintSelectorInvalid(t *testing.T) {
+	a := assert.New(t)
+
+	conf := `rules:
+  task-vips:
+    detect:
+      op: exists
+      path: event/FILE_PATH
+    respond:
+      - action: extension request
+        extension name: ext-reliable-tasking
+        extension action: task
+        extension request:
+          task: os_version
+          selector: platform == windows
+hives:
+  extension_config:
+    ext-exfil:
+      data:
+        rules:
+          - selector: plat == windows and "vip" in tags
+          - selector: plat ==
+      usr_mtd:
+        enabled: true
+    ext-artifact:
+      data:
+        selector: tag == servers
+      usr_mtd:
+        enabled: true
+`
+	findings, err := LintYAML([]byte(conf))
+	a.NoError(err)
+
+	found := map[string]LintFinding{}
+	for _, f := range findings {
+		found[f.Rule+"|"+f.Location] = f
+	}
+	a.Len(found, 2, findings)
+	a.Contains(found, "selector-invalid|hives.extension_config.ext-exfil.data.rules.1.selector")
+	a.Contains(found["dr-rule-invalid-respond|rules.task-vips"].Message, "invalid selector")
+}
+
+func TestReliableTaskInvalidSelector(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	err := org.ReliableTask("platform == windows", "os_version", time.Hour)
+	a.Error(err)
+	a.Contains(err.Error(), "unknown selector field")
+
+	_, err = org.TaskAll("plat ==", "os_version", TaskAllOptions{})
+	a.Error(err)
+}
@@ -1,6 +1,7 @@
 package limacharlie
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -28,6 +29,9 @@ type Sensor struct {
 	ShouldIsolate     bool `json:"should_isolate"`
 	IsKernelAvailable bool `json:"kernel"`
 
+	// Tags of the sensor, only populated when known.
+	Tags []string `json:"tags,omitempty"`
+
 	Organization *Organization `json:"-"`
 
 	Device *Device `json:"-"`
@@ -55,6 +59,9 @@ type TagInfo struct {
 	Tag     string
 	By      string
 	AddedTS string
+	// Expiry is when a tag added with a TTL is removed,
+	// zero for permanent tags.
+	Expiry time.Time
 }
 
 type TaskingOptions struct {
@@ -83,6 +90,15 @@ func (t *TagInfo) UnmarshalJSON(b []byte) error {
 	if !ok {
 		return fmt.Errorf("added wrong datatype: %v (%T)", l[1], l[1])
 	}
+	if len(l) > 4 && l[4] != nil {
+		expiry, ok := l[4].(float64)
+		if !ok {
+			return fmt.Errorf("expiry wrong datatype: %v (%T)", l[4], l[4])
+		}
+		if expiry > 0 {
+			t.Expiry = time.Unix(int64(expiry), 0).UTC()
+		}
+	}
 	return nil
 }
 
@@ -226,77 +242,23 @@ func (org *Organization) GetSensors(SIDs []string) map[string]*Sensor {
 }
 
 func (org *Organization) ListSensors() (map[string]*Sensor, error) {
-	m := map[string]*Sensor{}
-	lastToken := ""
-
-	for {
-		page := sensorListPage{}
-		q := makeDefaultRequest(&page)
-		if lastToken != "" {
-			q = q.withQueryData(Dict{
-				"continuation_token": lastToken,
-			})
-		}
-		if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("sensors/%s", org.client.options.OID), q); err != nil {
-			return nil, err
-		}
-		for _, s := range page.Sensors {
-			s.Organization = org
-			s.InvestigationID = org.invID
-			if s.DID != "" {
-				s.Device = &Device{
-					DID:          s.DID,
-					Organization: org,
-				}
-			}
-			m[s.SID] = s
-		}
-		if page.ContinuationToken == "" {
-			break
-		}
-		lastToken = page.ContinuationToken
-	}
-
-	return m, nil
+	return org.ListSensorsFromSelector("")
 }
 
 func (org *Organization) ListSensorsFromSelector(selector string) (map[string]*Sensor, error) {
 	m := map[string]*Sensor{}
-	lastToken := ""
-
-	for {
-		page := sensorListPage{}
-		q := makeDefaultRequest(&page)
-		if lastToken != "" {
-			q = q.withQueryData(Dict{
-				"continuation_token": lastToken,
-				"selector":           selector,
-			})
-		} else {
-			q = q.withQueryData(Dict{
-				"selector": selector,
-			})
-		}
-		if err := org.client.reliableRequest(http.MethodGet, fmt.Sprintf("sensors/%s", org.client.options.OID), q); err != nil {
-			return nil, err
-		}
-		for _, s := range page.Sensors {
-			s.Organization = org
-			s.InvestigationID = org.invID
-			if s.DID != "" {
-				s.Device = &Device{
-					DID:          s.DID,
-					Organization: org,
-				}
-			}
-			m[s.SID] = s
-		}
-		if page.ContinuationToken == "" {
-			break
+	var err error
+	org.SensorsIter(context.Background(), selector)(func(s *Sensor, e error) bool {
+		if e != nil {
+			err = e
+			return false
 		}
-		lastToken = page.ContinuationToken
+		m[s.SID] = s
+		return true
+	})
+	if err != nil {
+		return nil, err
 	}
-
 	return m, nil
 }
 
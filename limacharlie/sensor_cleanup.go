@@ -0,0 +1,96 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// SensorCleanupPolicy selects the sensors deleted by CleanupSensors,
+// like for fleets with high churn such as VDI or ephemeral cloud
+// instances. Sensors must match all the criteria set.
+type SensorCleanupPolicy struct {
+	// Selector of the sensors, all by default.
+	Selector string
+	// OfflineFor is how long sensors must have been offline, sensors
+	// never seen being kept. Any offline duration if 0.
+	OfflineFor time.Duration
+	// IsDryRun only lists the sensors that would be deleted.
+	IsDryRun bool
+	// MaxDeletions, if set, aborts the cleanup before deleting
+	// anything when more sensors match, as a safeguard.
+	MaxDeletions int
+}
+
+// SensorCleanupResult lists the sensors matching a cleanup policy.
+type SensorCleanupResult struct {
+	// Sensors matching the policy, sorted by SID.
+	Sensors []SensorPresence
+	// Deleted SIDs, empty on dry runs.
+	Deleted []string
+	// Errors by SID of the sensors that could not be deleted.
+	Errors map[string]error
+}
+
+// Error aggregates the failures, nil if none.
+func (r SensorCleanupResult) Error() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	sids := []string{}
+	for sid := range r.Errors {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+	return fmt.Errorf("%d of %d sensors failed to be deleted, like %s: %v", len(r.Errors), len(r.Sensors), sids[0], r.Errors[sids[0]])
+}
+
+// CleanupSensors deletes the sensors matching the policy, offline beyond
+// a threshold and/or matching a selector. Online sensors are never deleted.
+// Failing sensors do not stop the others, they are reported in the result.
+func (org *Organization) CleanupSensors(policy SensorCleanupPolicy) (SensorCleanupResult, error) {
+	result := SensorCleanupResult{
+		Sensors: []SensorPresence{},
+		Deleted: []string{},
+		Errors:  map[string]error{},
+	}
+	if policy.Selector == "" && policy.OfflineFor <= 0 {
+		return result, errors.New("a selector or an offline duration is required")
+	}
+	sensors, err := org.ListSensorsFromSelector(policy.Selector)
+	if err != nil {
+		return result, err
+	}
+	presence, err := org.presenceOf(sensors)
+	if err != nil {
+		return result, err
+	}
+	now := time.Now()
+	for _, p := range presence {
+		if p.IsOnline {
+			continue
+		}
+		if policy.OfflineFor > 0 && (p.LastSeen.IsZero() || p.OfflineFor(now) < policy.OfflineFor) {
+			continue
+		}
+		result.Sensors = append(result.Sensors, p)
+	}
+	sort.Slice(result.Sensors, func(i, j int) bool {
+		return result.Sensors[i].Sensor.SID < result.Sensors[j].Sensor.SID
+	})
+	if policy.MaxDeletions > 0 && len(result.Sensors) > policy.MaxDeletions {
+		return result, fmt.Errorf("%d sensors match the cleanup policy, more than the maximum of %d", len(result.Sensors), policy.MaxDeletions)
+	}
+	if policy.IsDryRun {
+		return result, nil
+	}
+	for _, p := range result.Sensors {
+		if err := p.Sensor.Delete(); err != nil {
+			result.Errors[p.Sensor.SID] = err
+			continue
+		}
+		result.Deleted = append(result.Deleted, p.Sensor.SID)
+	}
+	return result, result.Error()
+}
@@ -0,0 +1,82 @@
+package limacharlie
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanupSensors(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now().UTC()
+	old := now.Add(-48 * time.Hour).Format(sensorTimestampFormat)
+	recent := now.Add(-1 * time.Hour).Format(sensorTimestampFormat)
+	deleted := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/sensors/"+vcrTestOID:
+			a.Equal("plat == windows", r.URL.Query().Get("selector"))
+			return jsonResponse(http.StatusOK, fmt.Sprintf(`{"sensors": [
+				{"sid": "s1", "alive": %q},
+				{"sid": "s2", "alive": %q},
+				{"sid": "s3", "alive": %q},
+				{"sid": "s4", "alive": %q},
+				{"sid": "s5"}
+			]}`, old, recent, old, old)), nil
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/online/"+vcrTestOID:
+			return jsonResponse(http.StatusOK, `{"s1": false, "s2": false, "s3": true, "s4": false, "s5": false}`), nil
+		case r.Method == http.MethodDelete:
+			deleted = append(deleted, r.URL.Path)
+			if r.URL.Path == "/v1/s4" {
+				return jsonResponse(http.StatusForbidden, `{"error": "denied"}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{}`), nil
+		}
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	sids := func(r SensorCleanupResult) []string {
+		s := []string{}
+		for _, p := range r.Sensors {
+			s = append(s, p.Sensor.SID)
+		}
+		return s
+	}
+
+	policy := SensorCleanupPolicy{
+		Selector:   "plat == windows",
+		OfflineFor: 24 * time.Hour,
+		IsDryRun:   true,
+	}
+	result, err := org.CleanupSensors(policy)
+	a.NoError(err)
+	a.Equal([]string{"s1", "s4"}, sids(result))
+	a.Empty(result.Deleted)
+	a.Empty(deleted)
+
+	policy.MaxDeletions = 1
+	policy.IsDryRun = false
+	_, err = org.CleanupSensors(policy)
+	a.Error(err)
+	a.Empty(deleted)
+
+	policy.MaxDeletions = 0
+	result, err = org.CleanupSensors(policy)
+	a.Error(err)
+	a.Equal([]string{"s1"}, result.Deleted)
+	a.Contains(result.Errors, "s4")
+	a.Contains(deleted, "/v1/s1")
+	a.Contains(deleted, "/v1/s4")
+
+	// Without a threshold, all offline sensors match, even never seen.
+	result, err = org.CleanupSensors(SensorCleanupPolicy{Selector: "plat == windows", IsDryRun: true})
+	a.NoError(err)
+	a.Equal([]string{"s1", "s2", "s4", "s5"}, sids(result))
+
+	_, err = org.CleanupSensors(SensorCleanupPolicy{})
+	a.Error(err)
+}
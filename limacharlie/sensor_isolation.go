@@ -0,0 +1,76 @@
+package limacharlie
+
+import (
+	"time"
+)
+
+const isolationPollInterval = 2 * time.Second
+
+// IsolationOutcome is the result of IsolateAndVerify or RejoinAndVerify.
+type IsolationOutcome struct {
+	SensorID string `json:"sid"`
+	// IsIsolating is true for an isolation, false for a rejoin.
+	IsIsolating bool `json:"is_isolating"`
+	// IsRequested is true once the API accepted the change.
+	IsRequested bool `json:"is_requested"`
+	// IsConfirmed is true once the sensor reports the change applied.
+	IsConfirmed bool `json:"is_confirmed"`
+	// IsOnline is only checked when the change is not confirmed
+	// in time, an offline sensor applies it when it comes back.
+	IsOnline bool          `json:"is_online"`
+	Duration time.Duration `json:"duration"`
+}
+
+// IsolateAndVerify isolates a sensor from the network and waits
+// until the sensor reports being isolated. ErrorNotConsistent is
+// returned, along with the outcome, if it is not within the timeout.
+func (org *Organization) IsolateAndVerify(sensorID string, timeout time.Duration) (IsolationOutcome, error) {
+	return org.setIsolationAndVerify(sensorID, true, timeout)
+}
+
+// RejoinAndVerify rejoins a sensor to the network and
+// waits for it to be confirmed, like IsolateAndVerify.
+func (org *Organization) RejoinAndVerify(sensorID string, timeout time.Duration) (IsolationOutcome, error) {
+	return org.setIsolationAndVerify(sensorID, false, timeout)
+}
+
+func (org *Organization) setIsolationAndVerify(sensorID string, isIsolating bool, timeout time.Duration) (IsolationOutcome, error) {
+	start := time.Now()
+	outcome := IsolationOutcome{
+		SensorID:    sensorID,
+		IsIsolating: isIsolating,
+	}
+	s := &Sensor{
+		OID:          org.client.options.OID,
+		SID:          sensorID,
+		Organization: org,
+	}
+	var err error
+	if isIsolating {
+		err = s.IsolateFromNetwork()
+	} else {
+		err = s.RejoinNetwork()
+	}
+	if err != nil {
+		outcome.Duration = time.Since(start)
+		return outcome, err
+	}
+	outcome.IsRequested = true
+
+	err = org.WaitForConsistency(func() (bool, error) {
+		s.LastError = nil
+		if s.Update(); s.LastError != nil {
+			return false, s.LastError
+		}
+		return s.IsIsolated == isIsolating, nil
+	}, ConsistencyOptions{
+		Timeout:  timeout,
+		Interval: isolationPollInterval,
+	})
+	outcome.IsConfirmed = err == nil
+	if err == ErrorNotConsistent {
+		outcome.IsOnline, _ = s.IsOnline()
+	}
+	outcome.Duration = time.Since(start)
+	return outcome, err
+}
@@ -0,0 +1,49 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsolateAndVerify(t *testing.T) {
+	a := assert.New(t)
+
+	requests := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		if r.URL.Path == "/v1/s1" {
+			return jsonResponse(http.StatusOK, `{"info":{"sid":"s1","isolated":true}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	outcome, err := org.IsolateAndVerify("s1", time.Minute)
+	a.NoError(err)
+	a.True(outcome.IsRequested)
+	a.True(outcome.IsConfirmed)
+	a.True(outcome.IsIsolating)
+	a.Equal([]string{"POST /v1/s1/isolation", "GET /v1/s1"}, requests)
+}
+
+func TestRejoinAndVerifyTimeout(t *testing.T) {
+	a := assert.New(t)
+
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/v1/s1":
+			return jsonResponse(http.StatusOK, `{"info":{"sid":"s1","isolated":true}}`), nil
+		case "/v1/online/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{"s1":false}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	outcome, err := org.RejoinAndVerify("s1", time.Millisecond)
+	a.Equal(ErrorNotConsistent, err)
+	a.True(outcome.IsRequested)
+	a.False(outcome.IsConfirmed)
+	a.False(outcome.IsOnline)
+}
@@ -0,0 +1,114 @@
+package limacharlie
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SensorQuery selects sensors by indicators. All the set
+// fields must match, an empty SensorQuery matches all sensors.
+type SensorQuery struct {
+	// Hostname matches exactly, or as a prefix if IsHostnamePrefix.
+	Hostname         string
+	IsHostnamePrefix bool
+	// IP matches either the internal or the external IP.
+	IP string
+	// Tags must all be set on the sensor.
+	Tags []string
+	// Platforms the sensor must be one of, like Platforms.Windows.
+	Platforms []uint32
+}
+
+// Selector returns the sensor selector equivalent to the query.
+func (q SensorQuery) Selector() (string, error) {
+	clauses := []string{}
+	if q.Hostname != "" {
+		if q.IsHostnamePrefix {
+			clauses = append(clauses, fmt.Sprintf("hostname matches %s", quoteSelectorString("^"+regexp.QuoteMeta(q.Hostname))))
+		} else {
+			clauses = append(clauses, fmt.Sprintf("hostname == %s", quoteSelectorString(q.Hostname)))
+		}
+	}
+	if q.IP != "" {
+		ip := quoteSelectorString(q.IP)
+		clauses = append(clauses, fmt.Sprintf("(int_ip == %s or ext_ip == %s)", ip, ip))
+	}
+	for _, tag := range q.Tags {
+		if tag == "" {
+			return "", fmt.Errorf("empty tag")
+		}
+		clauses = append(clauses, fmt.Sprintf("%s in tags", quoteSelectorString(tag)))
+	}
+	if len(q.Platforms) != 0 {
+		names := []string{}
+		for _, p := range q.Platforms {
+			name, ok := PlatformStrings[p]
+			if !ok {
+				return "", fmt.Errorf("unknown platform: %d", p)
+			}
+			names = append(names, quoteSelectorString(name))
+		}
+		clauses = append(clauses, fmt.Sprintf("plat in (%s)", strings.Join(names, ", ")))
+	}
+	return strings.Join(clauses, " and "), nil
+}
+
+func quoteSelectorString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// SensorSearch returns the sensors matching the query, sorted by
+// hostname. The query is evaluated server-side as a selector, and
+// locally on all the sensors if the API rejects the selector.
+func (org *Organization) SensorSearch(query SensorQuery) ([]*Sensor, error) {
+	selector, err := query.Selector()
+	if err != nil {
+		return nil, err
+	}
+	sensors, err := org.ListSensorsFromSelector(selector)
+	if err != nil && selector != "" && isRESTBadRequest(err) {
+		sensors, err = org.sensorSearchLocally(query, selector)
+	}
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*Sensor, 0, len(sensors))
+	for _, s := range sensors {
+		results = append(results, s)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Hostname != results[j].Hostname {
+			return results[i].Hostname < results[j].Hostname
+		}
+		return results[i].SID < results[j].SID
+	})
+	return results, nil
+}
+
+func (org *Organization) sensorSearchLocally(query SensorQuery, selector string) (map[string]*Sensor, error) {
+	sensors, err := org.ListSensors()
+	if err != nil {
+		return nil, err
+	}
+	// Tags are not part of the listing.
+	if len(query.Tags) != 0 {
+		tagsBySID := map[string][]string{}
+		for _, tag := range query.Tags {
+			tagged, err := org.GetSensorsWithTag(tag)
+			if err != nil {
+				return nil, err
+			}
+			for sid := range tagged {
+				tagsBySID[sid] = append(tagsBySID[sid], tag)
+			}
+		}
+		for sid, s := range sensors {
+			s.Tags = tagsBySID[sid]
+		}
+	}
+	return FilterSensors(sensors, selector)
+}
@@ -0,0 +1,88 @@
+package limacharlie
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSensorQuerySelector(t *testing.T) {
+	a := assert.New(t)
+
+	sel, err := SensorQuery{}.Selector()
+	a.NoError(err)
+	a.Equal("", sel)
+
+	sel, err = SensorQuery{
+		Hostname:         "web.corp",
+		IsHostnamePrefix: true,
+		IP:               "10.0.0.1",
+		Tags:             []string{"vip"},
+		Platforms:        []uint32{Platforms.Windows, Platforms.Linux},
+	}.Selector()
+	a.NoError(err)
+	a.Equal(`hostname matches "^web\\.corp" and (int_ip == "10.0.0.1" or ext_ip == "10.0.0.1") and "vip" in tags and plat in ("windows", "linux")`, sel)
+	a.NoError(ValidateSelector(sel))
+
+	isMatch, err := MatchesSelector(sel, (&Sensor{
+		Hostname:   "web.corp-01",
+		ExternalIP: "10.0.0.1",
+		Tags:       []string{"vip"},
+		Platform:   Platforms.Linux,
+	}).SelectorEnv())
+	a.NoError(err)
+	a.True(isMatch)
+
+	_, err = SensorQuery{Platforms: []uint32{0xff}}.Selector()
+	a.Error(err)
+}
+
+func TestSensorSearch(t *testing.T) {
+	a := assert.New(t)
+
+	selectors := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		selectors = append(selectors, r.URL.Query().Get("selector"))
+		return jsonResponse(http.StatusOK, `{"sensors":[
+			{"sid":"s2","hostname":"web-02"},
+			{"sid":"s1","hostname":"web-01"}
+		]}`), nil
+	}))
+
+	sensors, err := org.SensorSearch(SensorQuery{Hostname: "web-", IsHostnamePrefix: true})
+	a.NoError(err)
+	a.Equal([]string{`hostname matches "^web-"`}, selectors)
+	a.Len(sensors, 2)
+	a.Equal("s1", sensors[0].SID)
+	a.Equal("s2", sensors[1].SID)
+}
+
+func TestSensorSearchLocalFallback(t *testing.T) {
+	a := assert.New(t)
+
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/v1/tags/"):
+			return jsonResponse(http.StatusOK, `{"s1":["vip"],"s3":["vip"]}`), nil
+		case r.URL.Query().Get("selector") != "":
+			return jsonResponse(http.StatusBadRequest, `{"error":"unsupported selector"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"sensors":[
+			{"sid":"s1","hostname":"db-01","int_ip":"10.0.0.1","plat":268435456},
+			{"sid":"s2","hostname":"db-02","int_ip":"10.0.0.1","plat":268435456},
+			{"sid":"s3","hostname":"db-03","int_ip":"10.0.0.2","plat":268435456}
+		]}`), nil
+	}))
+
+	sensors, err := org.SensorSearch(SensorQuery{
+		IP:        "10.0.0.1",
+		Tags:      []string{"vip"},
+		Platforms: []uint32{Platforms.Windows},
+	})
+	a.NoError(err)
+	a.Len(sensors, 1)
+	a.Equal("s1", sensors[0].SID)
+	a.Equal([]string{"vip"}, sensors[0].Tags)
+}
@@ -0,0 +1,96 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TagBulkResult is the result of tagging the sensors matching a selector.
+type TagBulkResult struct {
+	// SIDs of the sensors successfully tagged, or untagged.
+	SIDs []string
+	// Errors by SID of the sensors that failed.
+	Errors map[string]error
+}
+
+// Error aggregates the failures, nil if none.
+func (r TagBulkResult) Error() error {
+	if len(r.Errors) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d sensors failed, like %s", len(r.Errors), len(r.Errors)+len(r.SIDs), r.firstError())
+}
+
+func (r TagBulkResult) firstError() string {
+	for sid, err := range r.Errors {
+		return fmt.Sprintf("%s: %v", sid, err)
+	}
+	return ""
+}
+
+// AddTagWithTTL adds a tag which the platform removes once the TTL
+// expires, which must be at least a second. Use AddTag with a zero
+// TTL for a permanent tag.
+func (s *Sensor) AddTagWithTTL(tag string, ttl time.Duration) error {
+	if ttl < time.Second {
+		return fmt.Errorf("invalid tag ttl: %v", ttl)
+	}
+	return s.AddTag(tag, ttl)
+}
+
+// TagExpiries returns when each tag of the sensor added
+// with a TTL expires. Permanent tags are not included.
+func (s *Sensor) TagExpiries() (map[string]time.Time, error) {
+	tags, err := s.GetTags()
+	if err != nil {
+		return nil, err
+	}
+	expiries := map[string]time.Time{}
+	for _, t := range tags {
+		if !t.Expiry.IsZero() {
+			expiries[t.Tag] = t.Expiry
+		}
+	}
+	return expiries, nil
+}
+
+// AddTagToSelector adds a tag, for the TTL if not zero, to all
+// the sensors matching the selector. Failing sensors do not stop
+// the others, they are reported in the result.
+func (org *Organization) AddTagToSelector(selector string, tag string, ttl time.Duration) (TagBulkResult, error) {
+	return org.applyToSelector(selector, func(s *Sensor) error {
+		return s.AddTag(tag, ttl)
+	})
+}
+
+// RemoveTagFromSelector removes a tag from all the
+// sensors matching the selector, like AddTagToSelector.
+func (org *Organization) RemoveTagFromSelector(selector string, tag string) (TagBulkResult, error) {
+	return org.applyToSelector(selector, func(s *Sensor) error {
+		return s.RemoveTag(tag)
+	})
+}
+
+func (org *Organization) applyToSelector(selector string, apply func(s *Sensor) error) (TagBulkResult, error) {
+	result := TagBulkResult{
+		SIDs:   []string{},
+		Errors: map[string]error{},
+	}
+	if selector == "" {
+		return result, fmt.Errorf("empty selector")
+	}
+	sensors, err := org.ListSensorsFromSelector(selector)
+	if err != nil {
+		return result, err
+	}
+	for sid, s := range sensors {
+		if err := apply(s); err != nil {
+			result.Errors[sid] = err
+			continue
+		}
+		result.SIDs = append(result.SIDs, sid)
+	}
+	sort.Strings(result.SIDs)
+	return result, result.Error()
+}
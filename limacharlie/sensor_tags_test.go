@@ -0,0 +1,57 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagInfoExpiry(t *testing.T) {
+	a := assert.New(t)
+
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, `{"tags":{"s1":{
+			"vip":["s1","vip","user","2024-01-01 00:00:00"],
+			"triage":["s1","triage","user","2024-01-01 00:00:00",1704070800]
+		}}}`), nil
+	}))
+	s := &Sensor{SID: "s1", Organization: org}
+
+	expiries, err := s.TagExpiries()
+	a.NoError(err)
+	a.Equal(map[string]time.Time{
+		"triage": time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}, expiries)
+
+	a.Error(s.AddTagWithTTL("triage", 0))
+}
+
+func TestAddTagToSelector(t *testing.T) {
+	a := assert.New(t)
+
+	forms := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.Method == http.MethodGet {
+			a.Equal(`"vip" in tags`, r.URL.Query().Get("selector"))
+			return jsonResponse(http.StatusOK, `{"sensors":[{"sid":"s2"},{"sid":"s1"}]}`), nil
+		}
+		a.NoError(r.ParseForm())
+		forms = append(forms, r.URL.Path+" "+r.PostForm.Get("tags")+" "+r.PostForm.Get("ttl"))
+		if r.URL.Path == "/v1/s2/tags" {
+			return jsonResponse(http.StatusForbidden, `{"error":"denied"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	result, err := org.AddTagToSelector(`"vip" in tags`, "triage", time.Hour)
+	a.Error(err)
+	a.Equal([]string{"s1"}, result.SIDs)
+	a.Len(result.Errors, 1)
+	a.Contains(result.Errors, "s2")
+	a.Contains(forms, "/v1/s1/tags triage 3600")
+
+	_, err = org.AddTagToSelector("", "triage", 0)
+	a.Error(err)
+}
@@ -0,0 +1,61 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+type ServiceName = string
+
+// ServiceResponse is the response of a service to an action.
+type ServiceResponse struct {
+	// Data is the response as returned by the service.
+	Data Dict
+	// JobID is set by services tracking the action as a
+	// job, like asynchronous sweeps, see WaitForJob.
+	JobID JobID
+
+	raw json.RawMessage
+}
+
+// Decode unmarshals the response into a type
+// specific to the service and action.
+func (r ServiceResponse) Decode(out interface{}) error {
+	if len(r.raw) == 0 {
+		return errors.New("empty service response")
+	}
+	return json.Unmarshal(r.raw, out)
+}
+
+// ServiceAction performs an action of a service, like a replicant or an
+// extension, which is the primitive behind the services that have no
+// dedicated methods. The data is sent along with the action, without
+// being modified. When isAsync is set the service acknowledges the
+// action without waiting for its result.
+func (org Organization) ServiceAction(serviceName ServiceName, action string, data Dict, isAsync bool) (ServiceResponse, error) {
+	if serviceName == "" {
+		return ServiceResponse{}, errors.New("empty service name")
+	}
+	reqData := Dict{}
+	for k, v := range data {
+		reqData[k] = v
+	}
+	if action != "" {
+		reqData["action"] = action
+	}
+	raw := json.RawMessage{}
+	if err := org.client.serviceRequest(&raw, serviceName, reqData, isAsync); err != nil {
+		return ServiceResponse{}, err
+	}
+	resp := ServiceResponse{
+		Data: Dict{},
+		raw:  raw,
+	}
+	if len(raw) != 0 && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &resp.Data); err != nil {
+			return resp, err
+		}
+	}
+	resp.JobID, _ = resp.Data["job_id"].(string)
+	return resp, nil
+}
@@ -0,0 +1,42 @@
+package limacharlie
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceAction(t *testing.T) {
+	a := assert.New(t)
+
+	sent := Dict{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal("/v1/service/"+vcrTestOID+"/dumper", r.URL.Path)
+		a.NoError(r.ParseForm())
+		a.Equal("true", r.PostForm.Get("is_async"))
+		b, err := base64.StdEncoding.DecodeString(r.PostForm.Get("request_data"))
+		a.NoError(err)
+		a.NoError(json.Unmarshal(b, &sent))
+		return jsonResponse(http.StatusOK, `{"job_id":"j1","size":42}`), nil
+	}))
+
+	data := Dict{"sid": "s1"}
+	resp, err := org.ServiceAction("dumper", "dump", data, true)
+	a.NoError(err)
+	a.Equal(Dict{"sid": "s1", "action": "dump"}, sent)
+	a.Equal(Dict{"sid": "s1"}, data)
+	a.Equal("j1", resp.JobID)
+	a.Equal(int64(42), resp.Data["size"])
+
+	typed := struct {
+		Size int `json:"size"`
+	}{}
+	a.NoError(resp.Decode(&typed))
+	a.Equal(42, typed.Size)
+
+	_, err = org.ServiceAction("", "dump", nil, false)
+	a.Error(err)
+}
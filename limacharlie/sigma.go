@@ -0,0 +1,143 @@
+package limacharlie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	sigmaConvertTarget  = "limacharlie"
+	sigmaConvertTimeout = 30 * time.Second
+)
+
+type sigmaConvertResponse struct {
+	Rule  string `json:"rule"`
+	Error string `json:"error"`
+}
+
+// SigmaConversionErrors are the Sigma rules which failed
+// to convert in a batch, by name of the rule.
+type SigmaConversionErrors map[DRRuleName]error
+
+func (e SigmaConversionErrors) Error() string {
+	names := []string{}
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := []string{}
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, e[name]))
+	}
+	return fmt.Sprintf("failed to convert %d sigma rules: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// ConvertSigma converts a Sigma rule, in YAML, into a D&R rule
+// using the conversion service of the platform.
+func (org Organization) ConvertSigma(sigmaYAML string) (CoreDRRule, error) {
+	rule := CoreDRRule{}
+	form := url.Values{}
+	form.Set("rule", sigmaYAML)
+	form.Set("target", sigmaConvertTarget)
+
+	r, err := http.NewRequest(http.MethodPost, org.client.endpoints().Sigma, strings.NewReader(form.Encode()))
+	if err != nil {
+		return rule, err
+	}
+	r.Header.Set("User-Agent", "limacharlie-sdk")
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := org.client.httpClient(sigmaConvertTimeout).Do(r)
+	if err != nil {
+		return rule, err
+	}
+	defer resp.Body.Close()
+
+	respData := bytes.Buffer{}
+	if _, err := io.Copy(&respData, resp.Body); err != nil {
+		return rule, err
+	}
+	converted := sigmaConvertResponse{}
+	if err := json.Unmarshal(respData.Bytes(), &converted); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return rule, NewRESTError(resp.Status)
+		}
+		return rule, err
+	}
+	if converted.Error != "" {
+		return rule, NewRESTError(converted.Error)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return rule, NewRESTError(resp.Status)
+	}
+
+	if err := yaml.Unmarshal([]byte(converted.Rule), &rule); err != nil {
+		return rule, fmt.Errorf("invalid converted rule: %v", err)
+	}
+	if len(rule.Detect) == 0 {
+		return rule, fmt.Errorf("invalid converted rule: missing detect")
+	}
+	return rule, nil
+}
+
+// ConvertSigmaRules converts Sigma rules, by name, into D&R rules
+// ready to be added to the DRRules of an OrgConfig. The rules which
+// converted are returned along with SigmaConversionErrors for the others.
+func (org Organization) ConvertSigmaRules(sigmaRules map[DRRuleName]string) (map[DRRuleName]CoreDRRule, error) {
+	rules := map[DRRuleName]CoreDRRule{}
+	failed := SigmaConversionErrors{}
+	for name, sigmaYAML := range sigmaRules {
+		rule, err := org.ConvertSigma(sigmaYAML)
+		if err != nil {
+			failed[name] = err
+			continue
+		}
+		rules[name] = rule
+	}
+	if len(failed) != 0 {
+		return rules, failed
+	}
+	return rules, nil
+}
+
+// ConvertSigmaDirectory converts the Sigma rules, files ending in ".yml" or
+// ".yaml", of a directory and its sub-directories like ConvertSigmaRules.
+// The rules are named after their file, without extension.
+func (org Organization) ConvertSigmaDirectory(dir string) (map[DRRuleName]CoreDRRule, error) {
+	sigmaRules := map[DRRuleName]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if info.IsDir() || (ext != ".yml" && ext != ".yaml") {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if _, ok := sigmaRules[name]; ok {
+			return fmt.Errorf("duplicate sigma rule name: %s", name)
+		}
+		sigmaRules[name] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return org.ConvertSigmaRules(sigmaRules)
+}
@@ -0,0 +1,46 @@
+package limacharlie
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertSigma(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal("sigma.limacharlie.io", r.URL.Host)
+		a.NoError(r.ParseForm())
+		a.Equal("limacharlie", r.PostForm.Get("target"))
+		if strings.Contains(r.PostForm.Get("rule"), "broken") {
+			return jsonResponse(http.StatusBadRequest, `{"error":"unsupported modifier"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"rule":"detect:\n  op: is\n  event: NEW_PROCESS\n  path: event/FILE_PATH\n  value: evil.exe\nrespond:\n  - action: report\n    name: evil\n"}`), nil
+	}))
+
+	rule, err := org.ConvertSigma("title: evil\n")
+	a.NoError(err)
+	a.Equal("NEW_PROCESS", rule.Detect["event"])
+	a.Equal(List{map[string]interface{}{"action": "report", "name": "evil"}}, rule.Response)
+
+	_, err = org.ConvertSigma("title: broken\n")
+	a.EqualError(err, "api error: unsupported modifier")
+
+	dir, err := ioutil.TempDir("", "sigma")
+	a.NoError(err)
+	defer os.RemoveAll(dir)
+	a.NoError(os.MkdirAll(filepath.Join(dir, "windows"), 0700))
+	a.NoError(ioutil.WriteFile(filepath.Join(dir, "windows", "evil.yml"), []byte("title: evil\n"), 0600))
+	a.NoError(ioutil.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("title: broken\n"), 0600))
+	a.NoError(ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("not a rule"), 0600))
+
+	rules, err := org.ConvertSigmaDirectory(dir)
+	a.EqualError(err, "failed to convert 1 sigma rules: broken: api error: unsupported modifier")
+	a.Len(rules, 1)
+	a.Contains(rules, "evil")
+}
@@ -0,0 +1,116 @@
+package limacharlie
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SplitOptions describes how WriteSplit lays out the files.
+type SplitOptions struct {
+	// IsPerElement writes each element of a section, like each
+	// D&R rule, to its own file in a directory named after the section.
+	IsPerElement bool
+	// IndexFileName is the name of the root file including all the
+	// others, defaults to "index.yaml".
+	IndexFileName string
+}
+
+var splitFileNameUnsafe = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// WriteSplit writes the config to dir as multiple YAML files, one per
+// section, and an index file including all of them. It is the inverse of
+// loading a config with includes, like SyncPushFromFiles() does.
+// The OID and the name of the Org the config is for, if set, are kept in
+// the index file since includes do not carry them. It returns the path of
+// the index file.
+func (o OrgConfig) WriteSplit(dir string, opts SplitOptions) (string, error) {
+	if opts.IndexFileName == "" {
+		opts.IndexFileName = "index.yaml"
+	}
+	if o.Version == 0 {
+		o.Version = OrgConfigLatestVersion
+	}
+
+	files := map[string]OrgConfig{}
+	v := reflect.ValueOf(o)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		section := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if section == "" || section == "-" || section == "version" || section == "oid" || section == "org-name" {
+			continue
+		}
+		field := v.Field(i)
+		if field.IsZero() || (field.Kind() == reflect.Map || field.Kind() == reflect.Slice) && field.Len() == 0 {
+			continue
+		}
+		if !opts.IsPerElement || field.Kind() != reflect.Map {
+			c := OrgConfig{Version: o.Version}
+			reflect.ValueOf(&c).Elem().Field(i).Set(field)
+			files[section+".yaml"] = c
+			continue
+		}
+		for _, key := range field.MapKeys() {
+			single := reflect.MakeMap(field.Type())
+			single.SetMapIndex(key, field.MapIndex(key))
+			c := OrgConfig{Version: o.Version}
+			reflect.ValueOf(&c).Elem().Field(i).Set(single)
+			name := splitFileNameUnsafe.ReplaceAllString(key.String(), "_")
+			fileName := filepath.ToSlash(filepath.Join(section, name+".yaml"))
+			if _, ok := files[fileName]; ok {
+				return "", fmt.Errorf("file name collision for %s/%s", section, key.String())
+			}
+			files[fileName] = c
+		}
+	}
+
+	includes := []string{}
+	for fileName := range files {
+		includes = append(includes, fileName)
+	}
+	sort.Strings(includes)
+
+	for _, fileName := range includes {
+		data, err := yaml.Marshal(files[fileName])
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", fileName, err)
+		}
+		path := filepath.Join(dir, filepath.FromSlash(fileName))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", err
+		}
+		if err := ioutil.WriteFile(path, data, 0644); err != nil {
+			return "", err
+		}
+	}
+
+	index, err := yaml.Marshal(struct {
+		Version  int      `yaml:"version"`
+		OID      string   `yaml:"oid,omitempty"`
+		OrgName  string   `yaml:"org-name,omitempty"`
+		Includes []string `yaml:"include,omitempty"`
+	}{
+		Version:  o.Version,
+		OID:      o.OID,
+		OrgName:  o.OrgName,
+		Includes: includes,
+	})
+	if err != nil {
+		return "", err
+	}
+	indexPath := filepath.Join(dir, opts.IndexFileName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(indexPath, index, 0644); err != nil {
+		return "", err
+	}
+	return indexPath, nil
+}
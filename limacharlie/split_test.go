@@ -0,0 +1,102 @@
+package limacharlie
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestWriteSplit(t *testing.T) {
+	a := assert.New(t)
+
+	conf := OrgConfig{}
+	a.NoError(yaml.Unmarshal([]byte(`
+version: 3
+rules:
+  r1:
+    detect:
+      op: exists
+      path: event/FILE_PATH
+    respond:
+      - action: report
+        name: r1
+  team/r2:
+    detect:
+      op: exists
+      path: event/COMMAND_LINE
+    respond:
+      - action: report
+        name: r2
+outputs:
+  o1:
+    module: syslog
+    type: detect
+    dest_host: 1.1.1.1
+extensions:
+  - ext-a
+`), &conf))
+
+	for _, isPerElement := range []bool{false, true} {
+		dir := t.TempDir()
+		index, err := conf.WriteSplit(dir, SplitOptions{IsPerElement: isPerElement})
+		a.NoError(err)
+		a.Equal(filepath.Join(dir, "index.yaml"), index)
+
+		if isPerElement {
+			_, err = os.Stat(filepath.Join(dir, "rules", "team_r2.yaml"))
+		} else {
+			_, err = os.Stat(filepath.Join(dir, "rules.yaml"))
+		}
+		a.NoError(err)
+
+		loaded, err := loadEffectiveConfig("", index, SyncOptions{IncludeLoader: localFileIncludeLoader})
+		a.NoError(err)
+		loaded.Includes = nil
+		a.True(DeepEqualConfig(conf, loaded), "per element: %v", isPerElement)
+		a.Len(loaded.DRRules, 2)
+		a.Equal(orgSyncExtensions{"ext-a"}, loaded.Extensions)
+	}
+}
+
+func TestWriteSplitKeepsOrgGuard(t *testing.T) {
+	a := assert.New(t)
+	requests := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r.URL.Path)
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	conf := OrgConfig{
+		OID:     "11111111-2222-3333-4444-555555555555",
+		OrgName: "customer-b",
+		Outputs: orgSyncOutputs{
+			"o1": {Name: "o1", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+		},
+	}
+	for _, isPerElement := range []bool{false, true} {
+		dir := t.TempDir()
+		index, err := conf.WriteSplit(dir, SplitOptions{IsPerElement: isPerElement})
+		a.NoError(err)
+
+		data, err := ioutil.ReadFile(index)
+		a.NoError(err)
+		a.Contains(string(data), "oid: "+conf.OID)
+		_, err = os.Stat(filepath.Join(dir, "oid.yaml"))
+		a.True(os.IsNotExist(err))
+
+		loaded, err := loadEffectiveConfig("", index, SyncOptions{IncludeLoader: localFileIncludeLoader})
+		a.NoError(err)
+		a.Equal(conf.OID, loaded.OID)
+		a.Equal(conf.OrgName, loaded.OrgName)
+
+		_, err = org.SyncPush(loaded, SyncOptions{IsDryRun: true, SyncOutputs: true})
+		a.True(errors.Is(err, ErrorOrgMismatch), "per element: %v", isPerElement)
+		a.Empty(requests)
+	}
+}
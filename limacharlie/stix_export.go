@@ -0,0 +1,262 @@
+package limacharlie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	stixSpecVersion   = "2.1"
+	taxiiContentType  = "application/taxii+json;version=2.1"
+	stixTimeFormat    = "2006-01-02T15:04:05.000Z"
+	taxiiPushTimeout  = 60 * time.Second
+	stixIndicatorType = "malicious-activity"
+)
+
+// stixNamespace is the namespace of the deterministic identifiers
+// of STIX Cyber-observable Objects, also used for the objects
+// exported so that exporting a detection twice yields the same ones.
+var stixNamespace = uuid.MustParse("00abedb4-aa42-466c-9c01-fed23315a9b7")
+
+// DefaultSTIXObservablePaths map the paths of the fields of the
+// events of detections to the STIX object paths of the patterns
+// of the indicators exported.
+var DefaultSTIXObservablePaths = map[string]string{
+	"event/DOMAIN_NAME":  "domain-name:value",
+	"event/IP_ADDRESS":   "ipv4-addr:value",
+	"event/HASH":         "file:hashes.'SHA-256'",
+	"event/COMMAND_LINE": "process:command_line",
+}
+
+// STIXObject is a STIX 2.1 object, like an indicator or a sighting.
+type STIXObject = map[string]interface{}
+
+// STIXBundle is a STIX 2.1 bundle of objects.
+type STIXBundle struct {
+	Type    string       `json:"type"`
+	ID      string       `json:"id"`
+	Objects []STIXObject `json:"objects"`
+}
+
+// STIXExportOptions customize DetectionsToSTIX.
+type STIXExportOptions struct {
+	// ObservablePaths are the fields of the detect of the detections
+	// the indicators are made of, DefaultSTIXObservablePaths if nil.
+	ObservablePaths map[string]string
+	// IdentityName is the name of the identity creating the
+	// objects, "LimaCharlie <oid>" by default.
+	IdentityName string
+}
+
+// DetectionsToSTIX converts detections into a STIX 2.1 bundle of an
+// indicator per detection, matching the observables of its event, and
+// of a sighting of the indicator on the sensor, for threat intelligence
+// platforms. Detections without any observable are not exported.
+// The ATT&CK techniques in the metadata of the detections become
+// external references of the indicators.
+func (org Organization) DetectionsToSTIX(detections []Detection, opts ...STIXExportOptions) STIXBundle {
+	options := STIXExportOptions{}
+	if len(opts) != 0 {
+		options = opts[0]
+	}
+	if options.ObservablePaths == nil {
+		options.ObservablePaths = DefaultSTIXObservablePaths
+	}
+	oid := org.client.options.OID
+	if options.IdentityName == "" {
+		options.IdentityName = fmt.Sprintf("LimaCharlie %s", oid)
+	}
+
+	identityID := stixID("identity", "org/"+oid)
+	objects := []STIXObject{{
+		"type":           "identity",
+		"spec_version":   stixSpecVersion,
+		"id":             identityID,
+		"name":           options.IdentityName,
+		"identity_class": "organization",
+	}}
+	sensors := map[string]struct{}{}
+	for _, d := range detections {
+		pattern := stixPattern(d, options.ObservablePaths)
+		if pattern == "" {
+			continue
+		}
+		ts := stixTime(d.TimeStamp)
+		indicatorID := stixID("indicator", "detection/"+d.DetectID)
+		indicator := STIXObject{
+			"type":            "indicator",
+			"spec_version":    stixSpecVersion,
+			"id":              indicatorID,
+			"created_by_ref":  identityID,
+			"created":         ts,
+			"modified":        ts,
+			"name":            d.Category,
+			"indicator_types": []string{stixIndicatorType},
+			"pattern":         pattern,
+			"pattern_type":    "stix",
+			"valid_from":      ts,
+		}
+		if d.SourceRule != "" {
+			indicator["description"] = fmt.Sprintf("detected by the D&R rule %s", d.SourceRule)
+		}
+		if refs := stixExternalReferences(d); len(refs) != 0 {
+			indicator["external_references"] = refs
+		}
+		objects = append(objects, indicator)
+
+		sighting := STIXObject{
+			"type":            "sighting",
+			"spec_version":    stixSpecVersion,
+			"id":              stixID("sighting", "detection/"+d.DetectID),
+			"created_by_ref":  identityID,
+			"created":         ts,
+			"modified":        ts,
+			"first_seen":      ts,
+			"last_seen":       ts,
+			"count":           1,
+			"sighting_of_ref": indicatorID,
+		}
+		if d.Routing.SID != "" {
+			sensorID := stixID("identity", "sensor/"+d.Routing.SID)
+			sighting["where_sighted_refs"] = []string{sensorID}
+			if _, ok := sensors[sensorID]; !ok {
+				sensors[sensorID] = struct{}{}
+				name := d.Routing.Hostname
+				if name == "" {
+					name = d.Routing.SID
+				}
+				objects = append(objects, STIXObject{
+					"type":           "identity",
+					"spec_version":   stixSpecVersion,
+					"id":             sensorID,
+					"name":           name,
+					"identity_class": "system",
+					"created_by_ref": identityID,
+				})
+			}
+		}
+		objects = append(objects, sighting)
+	}
+	return STIXBundle{
+		Type:    "bundle",
+		ID:      "bundle--" + uuid.New().String(),
+		Objects: objects,
+	}
+}
+
+func stixID(objectType string, name string) string {
+	return fmt.Sprintf("%s--%s", objectType, uuid.NewSHA1(stixNamespace, []byte(name)).String())
+}
+
+// stixTime formats a timestamp in milliseconds.
+func stixTime(ts int64) string {
+	return time.Unix(0, ts*int64(time.Millisecond)).UTC().Format(stixTimeFormat)
+}
+
+// stixPattern returns the pattern matching the observables of
+// the detection, any of them, empty if it has none.
+func stixPattern(d Detection, paths map[string]string) string {
+	fields := []string{}
+	for path := range paths {
+		fields = append(fields, path)
+	}
+	sort.Strings(fields)
+	comparisons := []string{}
+	for _, path := range fields {
+		v, ok := d.Detect.GetString(path)
+		if !ok || v == "" {
+			continue
+		}
+		v = strings.ReplaceAll(strings.ReplaceAll(v, `\`, `\\`), `'`, `\'`)
+		comparisons = append(comparisons, fmt.Sprintf("[%s = '%s']", paths[path], v))
+	}
+	return strings.Join(comparisons, " OR ")
+}
+
+func stixExternalReferences(d Detection) []STIXObject {
+	refs := []STIXObject{}
+	if d.Link != "" {
+		refs = append(refs, STIXObject{
+			"source_name": "limacharlie",
+			"url":         d.Link,
+		})
+	}
+	techniques := map[AttackTechniqueID]struct{}{}
+	findMetadataAttackTechniques(d.Metadata, techniques)
+	ids := []string{}
+	for t := range techniques {
+		ids = append(ids, t)
+	}
+	sort.Strings(ids)
+	for _, t := range ids {
+		refs = append(refs, STIXObject{
+			"source_name": "mitre-attack",
+			"external_id": t,
+		})
+	}
+	return refs
+}
+
+// TAXIIServer is a collection of a TAXII 2.1 server objects are pushed to.
+type TAXIIServer struct {
+	// CollectionURL is the URL of the collection, like
+	// "https://taxii.example.com/api/collections/<id>/".
+	CollectionURL string
+	// Headers sent with the requests, like an Authorization.
+	Headers map[string]string
+}
+
+// TAXIIStatus is the status of objects pushed to a TAXII server.
+type TAXIIStatus struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	TotalCount   int    `json:"total_count"`
+	SuccessCount int    `json:"success_count"`
+	FailureCount int    `json:"failure_count"`
+	PendingCount int    `json:"pending_count"`
+}
+
+// PushToTAXII adds the objects of the bundle to a TAXII 2.1 collection.
+func (org Organization) PushToTAXII(bundle STIXBundle, server TAXIIServer) (TAXIIStatus, error) {
+	status := TAXIIStatus{}
+	if server.CollectionURL == "" {
+		return status, fmt.Errorf("no collection url")
+	}
+	body, err := json.Marshal(map[string]interface{}{"objects": bundle.Objects})
+	if err != nil {
+		return status, err
+	}
+	r, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(server.CollectionURL, "/")+"/objects/", bytes.NewReader(body))
+	if err != nil {
+		return status, err
+	}
+	r.Header.Set("Content-Type", taxiiContentType)
+	r.Header.Set("Accept", taxiiContentType)
+	for k, v := range server.Headers {
+		r.Header.Set(k, v)
+	}
+	resp, err := org.client.httpClient(taxiiPushTimeout).Do(r)
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return status, err
+	}
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("pushing to taxii: %s: %s", resp.Status, string(data))
+	}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return status, fmt.Errorf("invalid taxii status: %v", err)
+	}
+	return status, nil
+}
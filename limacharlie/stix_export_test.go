@@ -0,0 +1,94 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectionsToSTIX(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(nil)
+	detections := []Detection{{
+		DetectID:   "d1",
+		Category:   "evil-dns",
+		SourceRule: "general.evil-dns",
+		Link:       "https://app.limacharlie.io/detections/d1",
+		Routing:    Routing{SID: "s1", Hostname: "host-1"},
+		Detect:     Dict{"event": map[string]interface{}{"DOMAIN_NAME": "evil.com", "IP_ADDRESS": "1.2.3.4"}},
+		Metadata:   Dict{"mitre": "attack.T1071.004"},
+		TimeStamp:  1700000000123,
+	}, {
+		DetectID:  "d2",
+		Category:  "no-observable",
+		Detect:    Dict{"event": map[string]interface{}{"FILE_PATH": "c:\\evil.exe"}},
+		TimeStamp: 1700000000123,
+	}, {
+		DetectID:  "d3",
+		Category:  "quoted",
+		Routing:   Routing{SID: "s1", Hostname: "host-1"},
+		Detect:    Dict{"event": map[string]interface{}{"COMMAND_LINE": "cmd /c 'whoami'"}},
+		TimeStamp: 1700000000123,
+	}}
+	bundle := org.DetectionsToSTIX(detections)
+	a.Equal("bundle", bundle.Type)
+
+	byType := map[string][]STIXObject{}
+	for _, o := range bundle.Objects {
+		byType[o["type"].(string)] = append(byType[o["type"].(string)], o)
+	}
+	// The org and the sensor s1, once.
+	a.Len(byType["identity"], 2)
+	a.Len(byType["indicator"], 2)
+	a.Len(byType["sighting"], 2)
+
+	indicator := byType["indicator"][0]
+	a.Equal("evil-dns", indicator["name"])
+	a.Equal("[domain-name:value = 'evil.com'] OR [ipv4-addr:value = '1.2.3.4']", indicator["pattern"])
+	a.Equal("2023-11-14T22:13:20.123Z", indicator["valid_from"])
+	a.Equal([]STIXObject{
+		{"source_name": "limacharlie", "url": "https://app.limacharlie.io/detections/d1"},
+		{"source_name": "mitre-attack", "external_id": "T1071.004"},
+	}, indicator["external_references"])
+	a.Equal(`[process:command_line = 'cmd /c \'whoami\'']`, byType["indicator"][1]["pattern"])
+
+	sighting := byType["sighting"][0]
+	a.Equal(indicator["id"], sighting["sighting_of_ref"])
+	a.Equal([]string{byType["identity"][1]["id"].(string)}, sighting["where_sighted_refs"])
+
+	// Identifiers are stable across exports.
+	again := org.DetectionsToSTIX(detections)
+	a.Equal(bundle.Objects, again.Objects)
+	a.NotEqual(bundle.ID, again.ID)
+}
+
+func TestPushToTAXII(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal(http.MethodPost, r.Method)
+		a.Equal("https://taxii.example.com/api/collections/c1/objects/", r.URL.String())
+		a.Equal("application/taxii+json;version=2.1", r.Header.Get("Content-Type"))
+		a.Equal("Bearer token", r.Header.Get("Authorization"))
+		body, err := ioutil.ReadAll(r.Body)
+		a.NoError(err)
+		envelope := map[string][]STIXObject{}
+		a.NoError(json.Unmarshal(body, &envelope))
+		a.Len(envelope["objects"], 1)
+		return jsonResponse(http.StatusAccepted, `{"id": "st1", "status": "complete", "total_count": 1, "success_count": 1}`), nil
+	}))
+	status, err := org.PushToTAXII(STIXBundle{Objects: []STIXObject{{"type": "identity"}}}, TAXIIServer{
+		CollectionURL: "https://taxii.example.com/api/collections/c1",
+		Headers:       map[string]string{"Authorization": "Bearer token"},
+	})
+	a.NoError(err)
+	a.Equal(TAXIIStatus{ID: "st1", Status: "complete", TotalCount: 1, SuccessCount: 1}, status)
+
+	org = newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusUnauthorized, `{}`), nil
+	}))
+	_, err = org.PushToTAXII(STIXBundle{}, TAXIIServer{CollectionURL: "https://taxii.example.com/api/collections/c1/"})
+	a.Error(err)
+}
@@ -1,14 +1,17 @@
 package limacharlie
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"gopkg.in/yaml.v3"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strings"
+	"time"
 )
 
 const (
@@ -31,6 +34,56 @@ type SyncOptions struct {
 	// Only simulate changes to the Org.
 	IsDryRun bool `json:"is_dry_run"`
 
+	// ContinueOnError keeps syncing the other elements when one
+	// of them, or a whole category, fails. Failed operations have
+	// their Error set and the error returned aggregates all the
+	// failures.
+	ContinueOnError bool `json:"continue_on_error"`
+
+	// ProtectedNamespaces lists D&R rule namespaces, like "managed",
+	// in which rules are never added, modified or removed, be it as
+	// D&R rules or as records of their "dr-<namespace>" hive.
+	// Operations against protected rules are reported as skipped.
+	ProtectedNamespaces []string `json:"protected_namespaces,omitempty"`
+
+	// ProtectedFPRules lists FP rules, which have no namespace, that
+	// are never added, modified or removed, reported as skipped.
+	ProtectedFPRules []FPRuleName `json:"protected_fp_rules,omitempty"`
+
+	// CheckPermissions checks, before pushing, that the credentials
+	// have the permissions needed by the categories synced, failing
+	// with a SyncPermissionError otherwise. Only the D&R rules, FP
+	// rules, outputs, integrity, artifacts, exfil, org values,
+	// installation keys, yara and net policies are checked, against
+	// permissions named like those of the API keys, "output.set".
+	CheckPermissions bool `json:"check_permissions"`
+
+	// AnnotateAuthor, if set, is recorded as the author of the D&R
+	// rules added or updated, like "jdoe@example.com (commit 1a2b3c4)",
+	// so their history shows where they came from.
+	AnnotateAuthor string `json:"annotate_author,omitempty"`
+
+	// AuditSink, if set, receives a record of the operations
+	// applied by SyncPush, even when it fails. Dry runs are
+	// not recorded.
+	AuditSink SyncAuditSink `json:"-"`
+
+	// TransactionLog, if set, receives a JSON line, a SyncTransaction,
+	// for every change SyncPush applies, as it is applied, for a local
+	// audit trail. The Org is fetched before pushing to hash the
+	// elements changed. Dry runs are not logged.
+	TransactionLog io.Writer `json:"-"`
+
+	// Schedule, if set, restricts when changes are applied
+	// and can defer removals to a later run.
+	Schedule *SyncSchedule `json:"schedule,omitempty"`
+
+	// MaxDestructiveOps, if set, aborts a forced sync before modifying
+	// the Org when it would remove more elements, as a guardrail against
+	// pushing to the wrong Org, see ErrorTooManyDestructiveOps. A maximum
+	// of 0 allows no removal at all.
+	MaxDestructiveOps *int `json:"max_destructive_ops,omitempty"`
+
 	SyncDRRules          bool            `json:"sync_dr"`
 	SyncOutputs          bool            `json:"sync_outputs"`
 	SyncResources        bool            `json:"sync_resources"`
@@ -42,97 +95,179 @@ type SyncOptions struct {
 	SyncHives            map[string]bool `json:"sync_hives"`
 	SyncInstallationKeys bool            `json:"sync_installation_keys"`
 	SyncYara             bool            `json:"sync_yara"`
+	SyncExtensions       bool            `json:"sync_extensions"`
+	SyncNetPolicies      bool            `json:"sync_net_policies"`
+	SyncRetention        bool            `json:"sync_retention"`
+	SyncDetectionRouting bool            `json:"sync_detection_routing"`
+	SyncThreatFeeds      bool            `json:"sync_threat_feeds"`
 
 	IncludeLoader IncludeLoaderCB `json:"-"`
+
+	// OnDuplicate, if set, is called for every element defined in
+	// more than one of the files of a config loaded from files.
+	OnDuplicate func(dup ConfigDuplicate) `json:"-"`
+	// Decrypter decrypts the "ENC[<scheme>,<ciphertext>]" values
+	// of a config loaded from files, see DecryptConfig.
+	Decrypter Decrypter `json:"-"`
+	// EstimateImpact makes SyncPlan count the sensors the collection
+	// rules added or updated apply to, which lists all the sensors.
+	EstimateImpact bool `json:"estimate_impact,omitempty"`
+	// StaleAfter, if set, makes SyncPlan report the D&R rules the Org
+	// already has which did not fire for this long, which lists all the
+	// detections of the period.
+	StaleAfter time.Duration `json:"stale_after,omitempty"`
+	// ErrorOnDuplicates fails loading a config from files
+	// with duplicate elements instead of only warning.
+	ErrorOnDuplicates bool `json:"error_on_duplicates,omitempty"`
+	// MergeOptions are used to merge the includes of a config loaded
+	// from files into the file including them, like to deep merge the
+	// rules so an include can only change their "is_enabled".
+	MergeOptions MergeOptions `json:"merge_options,omitempty"`
+
+	// OnOperation, if set, is called for every operation as it
+	// is performed. When a category fails to sync, it is called
+	// with the error and an operation with only the ElementType set.
+	OnOperation func(op OrgSyncOperation, err error) `json:"-"`
+
+	// Metrics, if set, observes the operations, duration and
+	// API errors of SyncPush, see NewPrometheusSyncMetrics.
+	Metrics SyncMetrics `json:"-"`
+
+	// Logger receives a line for every operation, at the debug
+	// level for unchanged elements. SyncPush defaults it to the
+	// logger of the Client.
+	Logger LCLogger `json:"-"`
 }
 
-type IncludeLoaderCB = func(parentFilePath string, filePathToInclude string) ([]byte, error)
+// SyncCategories are the names of the categories
+// accepted by NewSyncOptionsForCategories.
+var SyncCategories = []string{
+	"dr",
+	"outputs",
+	"resources",
+	"integrity",
+	"fp",
+	"exfil",
+	"artifacts",
+	"org_values",
+	"installation_keys",
+	"yara",
+	"extensions",
+	"net_policies",
+	"retention",
+	"detection_routing",
+	"feeds",
+}
 
-var supportedOrgValues []string = []string{
-	"vt",
-	"otx",
-	"domain",
-	"shodan",
-	"pagerduty",
-	"twilio",
+// NewSyncOptionsForCategories returns SyncOptions syncing the categories
+// named, see SyncCategories, along with hives named "hive:<name>". The
+// "all" category selects all of them along with the DefaultBackupHives.
+func NewSyncOptionsForCategories(categories ...string) (SyncOptions, error) {
+	options := SyncOptions{}
+	for _, c := range categories {
+		c = strings.TrimSpace(c)
+		if strings.HasPrefix(c, "hive:") {
+			if options.SyncHives == nil {
+				options.SyncHives = map[string]bool{}
+			}
+			options.SyncHives[strings.TrimPrefix(c, "hive:")] = true
+			continue
+		}
+		switch c {
+		case "all":
+			all := allSyncOptions()
+			for h := range options.SyncHives {
+				all.SyncHives[h] = true
+			}
+			options = all
+		case "dr":
+			options.SyncDRRules = true
+		case "outputs":
+			options.SyncOutputs = true
+		case "resources":
+			options.SyncResources = true
+		case "integrity":
+			options.SyncIntegrity = true
+		case "fp":
+			options.SyncFPRules = true
+		case "exfil":
+			options.SyncExfil = true
+		case "artifacts":
+			options.SyncArtifacts = true
+		case "org_values":
+			options.SyncOrgValues = true
+		case "installation_keys":
+			options.SyncInstallationKeys = true
+		case "yara":
+			options.SyncYara = true
+		case "extensions":
+			options.SyncExtensions = true
+		case "net_policies":
+			options.SyncNetPolicies = true
+		case "retention":
+			options.SyncRetention = true
+		case "detection_routing":
+			options.SyncDetectionRouting = true
+		case "feeds":
+			options.SyncThreatFeeds = true
+		default:
+			return options, fmt.Errorf("unknown sync category: %s", c)
+		}
+	}
+	return options, nil
 }
 
+type IncludeLoaderCB = func(parentFilePath string, filePathToInclude string) ([]byte, error)
+
 type DRRuleName = string
 
 type OrgSyncFPRule struct {
 	Detection Dict `json:"data" yaml:"data"`
+	// Description documents the rule, stored with it so that
+	// it survives a fetch.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
 }
 
 func (r OrgSyncFPRule) DetectionEquals(fpRule FPRule) bool {
-	orgRuleDetectionBytes, err := json.Marshal(r.Detection)
-	if err != nil {
-		return false
-	}
-	fpRuleDetectionBytes, err := json.Marshal(fpRule.Detection)
-	if err != nil {
-		return false
-	}
-	return string(orgRuleDetectionBytes) == string(fpRuleDetectionBytes)
+	return syncNormalizers.FPRule.Equal(r.Detection, fpRule.Detection)
+}
+
+// Equals compares the rule with one of the Org, including its description.
+func (r OrgSyncFPRule) Equals(fpRule FPRule) bool {
+	return syncNormalizers.FPRule.Equal(
+		Dict{"data": r.Detection, "description": r.Description},
+		Dict{"data": fpRule.Detection, "description": fpRule.Description},
+	)
 }
 
 type OrgSyncIntegrityRule struct {
-	Patterns  []string `json:"patterns" yaml:"patterns"`
-	Tags      []string `json:"tags" yaml:"tags"`
-	Platforms []string `json:"platforms" yaml:"platforms"`
+	Patterns  []string   `json:"patterns" yaml:"patterns"`
+	Tags      []string   `json:"tags" yaml:"tags"`
+	Platforms []Platform `json:"platforms" yaml:"platforms"`
 }
 
 func (oir OrgSyncIntegrityRule) EqualsContent(ir IntegrityRule) bool {
-	orgRulePatterns := oir.Patterns
-	if orgRulePatterns == nil {
-		orgRulePatterns = []string{}
-	}
-	orgRuleTags := oir.Tags
-	if orgRuleTags == nil {
-		orgRuleTags = []string{}
-	}
-	orgRulePlatforms := oir.Platforms
-	if orgRulePlatforms == nil {
-		orgRulePlatforms = []string{}
-	}
-	bytes, err := json.Marshal(Dict{
-		"patterns":  orgRulePatterns,
-		"tags":      orgRuleTags,
-		"platforms": orgRulePlatforms,
-	})
-	if err != nil {
-		return false
-	}
-
-	rulePattern := ir.Patterns
-	if rulePattern == nil {
-		rulePattern = []string{}
-	}
-	ruleTags := ir.Filters.Tags
-	if ruleTags == nil {
-		ruleTags = []string{}
-	}
-	rulePlatforms := ir.Filters.Platforms
-	if rulePlatforms == nil {
-		rulePlatforms = []string{}
-	}
-	otherBytes, err := json.Marshal(Dict{
-		"patterns":  rulePattern,
-		"tags":      ruleTags,
-		"platforms": rulePlatforms,
-	})
-	if err != nil {
-		return false
-	}
-	return string(bytes) == string(otherBytes)
+	return syncNormalizers.Integrity.Equal(IntegrityRule{
+		Patterns: oir.Patterns,
+		Filters: IntegrityRuleFilter{
+			Tags:      oir.Tags,
+			Platforms: oir.Platforms,
+		},
+	}, ir)
 }
 
 type OrgSyncArtifactRule struct {
-	IsIgnoreCert   bool     `json:"is_ignore_cert" yaml:"is_ignore_cert"`
-	IsDeleteAfter  bool     `json:"is_delete_after" yaml:"is_delete_after"`
-	DaysRetentions uint     `json:"days_retention" yaml:"days_retention"`
-	Patterns       []string `json:"patterns" yaml:"patterns"`
-	Tags           []string `json:"tags" yaml:"tags"`
-	Platforms      []string `json:"platforms" yaml:"platforms"`
+	IsIgnoreCert   bool       `json:"is_ignore_cert" yaml:"is_ignore_cert"`
+	IsDeleteAfter  bool       `json:"is_delete_after" yaml:"is_delete_after"`
+	DaysRetentions uint       `json:"days_retention" yaml:"days_retention"`
+	Patterns       []string   `json:"patterns" yaml:"patterns"`
+	Tags           []string   `json:"tags" yaml:"tags"`
+	Platforms      []Platform `json:"platforms" yaml:"platforms"`
+	// ExcludeTags, MaxFileSize and Mode are left out when
+	// unset, for configs of the older rules to round-trip.
+	ExcludeTags []string         `json:"exclude_tags,omitempty" yaml:"exclude_tags,omitempty"`
+	MaxFileSize uint64           `json:"max_file_size,omitempty" yaml:"max_file_size,omitempty"`
+	Mode        ArtifactRuleMode `json:"mode,omitempty" yaml:"mode,omitempty"`
 }
 
 func (oar OrgSyncArtifactRule) ToArtifactRule() ArtifactRule {
@@ -142,9 +277,12 @@ func (oar OrgSyncArtifactRule) ToArtifactRule() ArtifactRule {
 		DaysRetentions: oar.DaysRetentions,
 		Patterns:       oar.Patterns,
 		Filters: ArtifactRuleFilter{
-			Tags:      oar.Tags,
-			Platforms: oar.Platforms,
+			Tags:        oar.Tags,
+			Platforms:   oar.Platforms,
+			ExcludeTags: oar.ExcludeTags,
 		},
+		MaxFileSize: oar.MaxFileSize,
+		Mode:        oar.Mode,
 	}
 }
 
@@ -155,6 +293,9 @@ func (oar OrgSyncArtifactRule) FromArtifactRule(artifact ArtifactRule) OrgSyncAr
 	oar.Patterns = artifact.Patterns
 	oar.Tags = artifact.Filters.Tags
 	oar.Platforms = artifact.Filters.Platforms
+	oar.ExcludeTags = artifact.Filters.ExcludeTags
+	oar.MaxFileSize = artifact.MaxFileSize
+	oar.Mode = artifact.Mode
 	return oar
 }
 
@@ -166,21 +307,13 @@ func (oar OrgSyncArtifactRule) ToJson() ([]byte, error) {
 		oar.Tags = []string{}
 	}
 	if oar.Platforms == nil {
-		oar.Platforms = []string{}
+		oar.Platforms = []Platform{}
 	}
 	return json.Marshal(oar)
 }
 
 func (oar OrgSyncArtifactRule) EqualsContent(artifact ArtifactRule) bool {
-	bytes, err := oar.ToJson()
-	if err != nil {
-		return false
-	}
-	otherBytes, err := OrgSyncArtifactRule{}.FromArtifactRule(artifact).ToJson()
-	if err != nil {
-		return false
-	}
-	return string(bytes) == string(otherBytes)
+	return syncNormalizers.Artifact.Equal(oar.ToArtifactRule(), artifact)
 }
 
 type orgSyncResources = map[ResourceName][]string
@@ -193,14 +326,25 @@ type orgSyncArtifacts = map[ArtifactRuleName]OrgSyncArtifactRule
 type orgSyncOrgValues = map[OrgValueName]OrgValue
 type orgSyncHives = map[HiveName]map[HiveKey]SyncHiveData
 type orgSyncInstallationKeys = map[InstallationKeyName]InstallationKey
+type orgSyncExtensions = []ExtensionName
+type orgSyncRuleTests = map[RuleTestName]OrgSyncRuleTest
+type orgSyncNetPolicies = map[NetPolicyName]NetPolicy
 type orgSyncYara = struct {
 	Rules   map[YaraRuleName]YaraRule     `json:"rules,omitempty" yaml:"rules,omitempty"`
 	Sources map[YaraSourceName]YaraSource `json:"sources,omitempty" yaml:"sources,omitempty"`
 }
 
 type OrgConfig struct {
-	Version          int                     `json:"version" yaml:"version"`
-	Includes         []string                `json:"-" yaml:"-"`
+	Version  int      `json:"version" yaml:"version"`
+	Includes []string `json:"-" yaml:"-"`
+	// IncludeEntries are the Includes along with their options,
+	// only set when the config specifies options.
+	IncludeEntries []ConfigInclude `json:"-" yaml:"-"`
+	// OID and OrgName, if set, assert which Org the config is for,
+	// SyncPush refusing to apply it to another one. Only those of
+	// the root config are checked, not those of its includes.
+	OID              string                  `json:"oid,omitempty" yaml:"oid,omitempty"`
+	OrgName          string                  `json:"org-name,omitempty" yaml:"org-name,omitempty"`
 	Resources        orgSyncResources        `json:"resources,omitempty" yaml:"resources,omitempty"`
 	DRRules          orgSyncDRRules          `json:"rules,omitempty" yaml:"rules,omitempty"`
 	FPRules          orgSyncFPRules          `json:"fps,omitempty" yaml:"fps,omitempty"`
@@ -212,6 +356,12 @@ type OrgConfig struct {
 	Hives            orgSyncHives            `json:"hives,omitempty" yaml:"hives,omitempty"`
 	InstallationKeys orgSyncInstallationKeys `json:"installation_keys,omitempty" yaml:"installation_keys,omitempty"`
 	Yara             *orgSyncYara            `json:"yara,omitempty" yaml:"yara,omitempty"`
+	Extensions       orgSyncExtensions       `json:"extensions,omitempty" yaml:"extensions,omitempty"`
+	RuleTests        orgSyncRuleTests        `json:"rules_tests,omitempty" yaml:"rules_tests,omitempty"`
+	NetPolicies      orgSyncNetPolicies      `json:"net-policy,omitempty" yaml:"net-policy,omitempty"`
+	Retention        *RetentionConfig        `json:"retention,omitempty" yaml:"retention,omitempty"`
+	DetectionRouting orgSyncDetectionRouting `json:"detection-routing,omitempty" yaml:"detection-routing,omitempty"`
+	ThreatFeeds      orgSyncThreatFeeds      `json:"feeds,omitempty" yaml:"feeds,omitempty"`
 }
 
 type orgConfigRaw OrgConfig
@@ -240,12 +390,23 @@ func (o *OrgConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 	} else if err := unmarshal(&multiInclude); err == nil {
 		org.Includes = multiInclude.D
 	} else {
-		// Is there an unknown include statement.
+		// Includes with options, like a prefix.
 		d := map[string]interface{}{}
 		unmarshal(&d)
-		if inc, ok := d["include"]; ok {
-			return fmt.Errorf("unknown include format: %T", inc)
+		inc, ok := d["include"]
+		if !ok {
+			*o = OrgConfig(org)
+			return nil
+		}
+		entries, err := parseConfigIncludes(inc)
+		if err != nil {
+			return err
+		}
+		org.Includes = []string{}
+		for _, e := range entries {
+			org.Includes = append(org.Includes, e.Path)
 		}
+		org.IncludeEntries = entries
 	}
 
 	*o = OrgConfig(org)
@@ -264,6 +425,12 @@ func (o OrgConfig) Merge(conf OrgConfig) OrgConfig {
 	o.Hives = o.mergeHives(conf.Hives)
 	o.InstallationKeys = o.mergeInstallationKeys(conf.InstallationKeys)
 	o.Yara = o.mergeYara(conf.Yara)
+	o.Extensions = o.mergeExtensions(conf.Extensions)
+	o.RuleTests = o.mergeRuleTests(conf.RuleTests)
+	o.NetPolicies = o.mergeNetPolicies(conf.NetPolicies)
+	o.Retention = o.mergeRetention(conf.Retention)
+	o.DetectionRouting = o.mergeDetectionRouting(conf.DetectionRouting)
+	o.ThreatFeeds = o.mergeThreatFeeds(conf.ThreatFeeds)
 	return o
 }
 
@@ -460,6 +627,98 @@ func (a OrgConfig) mergeArtifacts(b orgSyncArtifacts) orgSyncArtifacts {
 	return n
 }
 
+func (a OrgConfig) mergeExtensions(b orgSyncExtensions) orgSyncExtensions {
+	if a.Extensions == nil && b == nil {
+		return nil
+	}
+	n := orgSyncExtensions{}
+	s := map[ExtensionName]struct{}{}
+	for _, e := range append(append([]ExtensionName{}, a.Extensions...), b...) {
+		if _, ok := s[e]; ok {
+			continue
+		}
+		s[e] = struct{}{}
+		n = append(n, e)
+	}
+	return n
+}
+
+func (a OrgConfig) mergeRuleTests(b orgSyncRuleTests) orgSyncRuleTests {
+	if a.RuleTests == nil && b == nil {
+		return nil
+	}
+	n := orgSyncRuleTests{}
+	for k, v := range a.RuleTests {
+		n[k] = v
+	}
+	for k, v := range b {
+		n[k] = v
+	}
+	return n
+}
+
+func (a OrgConfig) mergeNetPolicies(b orgSyncNetPolicies) orgSyncNetPolicies {
+	if a.NetPolicies == nil && b == nil {
+		return nil
+	}
+	n := orgSyncNetPolicies{}
+	for k, v := range a.NetPolicies {
+		n[k] = v
+	}
+	for k, v := range b {
+		n[k] = v
+	}
+	return n
+}
+
+func (a OrgConfig) mergeDetectionRouting(b orgSyncDetectionRouting) orgSyncDetectionRouting {
+	if a.DetectionRouting == nil && b == nil {
+		return nil
+	}
+	n := orgSyncDetectionRouting{}
+	for k, v := range a.DetectionRouting {
+		n[k] = v
+	}
+	for k, v := range b {
+		n[k] = v
+	}
+	return n
+}
+
+func (a OrgConfig) mergeThreatFeeds(b orgSyncThreatFeeds) orgSyncThreatFeeds {
+	if a.ThreatFeeds == nil && b == nil {
+		return nil
+	}
+	n := orgSyncThreatFeeds{}
+	for k, v := range a.ThreatFeeds {
+		n[k] = v
+	}
+	for k, v := range b {
+		n[k] = v
+	}
+	return n
+}
+
+func (a OrgConfig) mergeRetention(b *RetentionConfig) *RetentionConfig {
+	if a.Retention == nil && b == nil {
+		return nil
+	}
+	n := &RetentionConfig{}
+	if a.Retention != nil {
+		*n = *a.Retention
+	}
+	if b == nil {
+		return n
+	}
+	if b.EventRetentionDays != 0 {
+		n.EventRetentionDays = b.EventRetentionDays
+	}
+	if b.ArtifactRetentionDays != 0 {
+		n.ArtifactRetentionDays = b.ArtifactRetentionDays
+	}
+	return n
+}
+
 func (a OrgConfig) mergeOrgValues(b orgSyncOrgValues) orgSyncOrgValues {
 	if a.OrgValues == nil && b == nil {
 		return nil
@@ -489,6 +748,11 @@ var OrgSyncOperationElementType = struct {
 	InstallationKey string
 	YaraRule        string
 	YaraSource      string
+	Extension       string
+	Retention       string
+	Payload         string
+	DetectionRoute  string
+	ThreatFeed      string
 }{
 	DRRule:          "dr-rule",
 	FPRule:          "fp-rule",
@@ -498,11 +762,17 @@ var OrgSyncOperationElementType = struct {
 	ExfilEvent:      "exfil-list",
 	ExfilWatch:      "exfil-watch",
 	Artifact:        "artifact",
+	NetPolicy:       "net-policy",
 	OrgValue:        "org-value",
 	Hives:           "hives",
 	InstallationKey: "installation-key",
 	YaraRule:        "yara-rule",
 	YaraSource:      "yara-source",
+	Extension:       "extension",
+	Retention:       "retention",
+	Payload:         "payload",
+	DetectionRoute:  "detection-routing",
+	ThreatFeed:      "threat-feed",
 }
 
 type OrgSyncOperation struct {
@@ -510,18 +780,132 @@ type OrgSyncOperation struct {
 	ElementName string `json:"name"`
 	IsAdded     bool   `json:"is_added"`
 	IsRemoved   bool   `json:"is_removed"`
+	IsSkipped   bool   `json:"is_skipped,omitempty"`
+	// IsUpdated is set along with IsAdded when an existing
+	// element with different content is replaced.
+	IsUpdated bool `json:"is_updated,omitempty"`
+
+	// Error is set when the operation failed, which can only be
+	// seen in the results when SyncOptions.ContinueOnError is set
+	// or on the last operation of a failed sync.
+	Error error `json:"-"`
+}
+
+// IsUnchanged returns true if the element was already
+// up to date and no change was made.
+func (o OrgSyncOperation) IsUnchanged() bool {
+	return !o.IsAdded && !o.IsRemoved && !o.IsSkipped && o.Error == nil
 }
 
 func (o OrgSyncOperation) String() string {
 	op := "="
-	if o.IsAdded {
+	if o.IsSkipped {
+		op = "!"
+	} else if o.IsUpdated {
+		op = "~"
+	} else if o.IsAdded {
 		op = "+"
 	} else if o.IsRemoved {
 		op = "-"
 	}
+	if o.Error != nil {
+		return fmt.Sprintf("%s %s %s: %v", op, o.ElementType, o.ElementName, o.Error)
+	}
 	return fmt.Sprintf("%s %s %s", op, o.ElementType, o.ElementName)
 }
 
+func (options SyncOptions) appendOp(ops []OrgSyncOperation, op OrgSyncOperation) []OrgSyncOperation {
+	if options.OnOperation != nil {
+		options.OnOperation(op, op.Error)
+	}
+	// Unchanged elements are the bulk of a sync, only the changes
+	// are logged at the info level.
+	level := LogLevels.Info
+	action := syncPlanAction(op)
+	if op.Error != nil {
+		level = LogLevels.Error
+	} else if action == "none" {
+		level = LogLevels.Debug
+	}
+	fields := map[string]interface{}{
+		"type":   op.ElementType,
+		"name":   op.ElementName,
+		"action": action,
+	}
+	if op.Error != nil {
+		fields["error"] = op.Error.Error()
+	}
+	logWithFields(options.Logger, level, "sync operation", fields)
+	return append(ops, op)
+}
+
+// failOp records a failed operation. The error is
+// returned unless ContinueOnError is set.
+func (options SyncOptions) failOp(ops []OrgSyncOperation, op OrgSyncOperation, err error) ([]OrgSyncOperation, error) {
+	op.Error = err
+	ops = options.appendOp(ops, op)
+	if options.ContinueOnError {
+		return ops, nil
+	}
+	return ops, &syncOpError{err: err}
+}
+
+// syncOpError is the error of a failed operation,
+// already passed to OnOperation by failOp.
+type syncOpError struct {
+	err error
+}
+
+func (e *syncOpError) Error() string {
+	return e.err.Error()
+}
+
+func (e *syncOpError) Unwrap() error {
+	return e.err
+}
+
+// failCategory reports the failure of a whole category. The error
+// is returned unless ContinueOnError is set, in which case it is
+// added to errs and the sync moves on to the next category.
+func (options SyncOptions) failCategory(errs []error, elementType string, err error) ([]error, error) {
+	err = options.reportError(elementType, err)
+	if options.ContinueOnError {
+		return append(errs, err), nil
+	}
+	return errs, err
+}
+
+// syncOpsError aggregates the errors of the failed operations
+// and of the categories which failed as a whole.
+func syncOpsError(ops []OrgSyncOperation, categoryErrs ...error) error {
+	failures := []string{}
+	for _, op := range ops {
+		if op.Error != nil {
+			failures = append(failures, fmt.Sprintf("%s %s: %v", op.ElementType, op.ElementName, op.Error))
+		}
+	}
+	for _, err := range categoryErrs {
+		failures = append(failures, err.Error())
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d operations failed: %s", len(failures), strings.Join(failures, "; "))
+}
+
+func (options SyncOptions) reportError(elementType string, err error) error {
+	// The failure of an operation was already passed to OnOperation.
+	opErr := &syncOpError{}
+	if options.OnOperation != nil && !errors.As(err, &opErr) {
+		options.OnOperation(OrgSyncOperation{ElementType: elementType}, err)
+	}
+	logWithFields(options.Logger, LogLevels.Error, "sync failed", map[string]interface{}{
+		"type":  elementType,
+		"error": err.Error(),
+	})
+	return err
+}
+
 func (org Organization) SyncFetch(options SyncOptions) (orgConfig OrgConfig, err error) {
 	if options.SyncResources {
 		orgConfig.Resources, err = org.syncFetchResources()
@@ -529,6 +913,12 @@ func (org Organization) SyncFetch(options SyncOptions) (orgConfig OrgConfig, err
 			return orgConfig, fmt.Errorf("resources: %v", err)
 		}
 	}
+	if options.SyncExtensions {
+		orgConfig.Extensions, err = org.syncFetchExtensions()
+		if err != nil {
+			return orgConfig, fmt.Errorf("extensions: %v", err)
+		}
+	}
 	if options.SyncDRRules {
 		who, err := org.client.whoAmI()
 		if err != nil {
@@ -540,13 +930,13 @@ func (org Organization) SyncFetch(options SyncOptions) (orgConfig OrgConfig, err
 		}
 	}
 	if options.SyncFPRules {
-		orgConfig.FPRules, err = org.syncFetchFPRules()
+		orgConfig.FPRules, err = syncFetchFPRules(org)
 		if err != nil {
 			return orgConfig, fmt.Errorf("fp-rule: %v", err)
 		}
 	}
 	if options.SyncOutputs {
-		orgConfig.Outputs, err = org.syncFetchOutputs()
+		orgConfig.Outputs, err = syncFetchOutputs(org)
 		if err != nil {
 			return orgConfig, fmt.Errorf("outputs: %v", err)
 		}
@@ -593,18 +983,37 @@ func (org Organization) SyncFetch(options SyncOptions) (orgConfig OrgConfig, err
 			return orgConfig, fmt.Errorf("integrity: %v", err)
 		}
 	}
+	if options.SyncNetPolicies {
+		orgConfig.NetPolicies, err = org.syncFetchNetPolicies()
+		if err != nil {
+			return orgConfig, fmt.Errorf("net-policy: %v", err)
+		}
+	}
+	if options.SyncRetention {
+		retention, err := org.RetentionConfig()
+		if err != nil {
+			return orgConfig, fmt.Errorf("retention: %v", err)
+		}
+		orgConfig.Retention = &retention
+	}
+	if options.SyncDetectionRouting {
+		orgConfig.DetectionRouting, err = syncFetchDetectionRouting(org)
+		if err != nil {
+			return orgConfig, fmt.Errorf("detection-routing: %v", err)
+		}
+	}
 
 	orgConfig.Version = OrgConfigLatestVersion
-	return orgConfig, nil
+	return orgConfig.sortedLists(), nil
 }
 
 func (org Organization) syncFetchOrgValues() (orgSyncOrgValues, error) {
-	return org.getSupportedOrgValues()
+	return org.getSupportedOrgValues(org.orgValueCatalog())
 }
 
-func (org Organization) getSupportedOrgValues() (map[OrgValueName]OrgValue, error) {
+func (org Organization) getSupportedOrgValues(catalog map[OrgValueName]OrgValueMetadata) (map[OrgValueName]OrgValue, error) {
 	ov := map[OrgValueName]OrgValue{}
-	for _, ovn := range supportedOrgValues {
+	for ovn := range catalog {
 		ovi, err := org.OrgValueGet(ovn)
 		if err != nil {
 			// Likely the value was never set.
@@ -615,6 +1024,14 @@ func (org Organization) getSupportedOrgValues() (map[OrgValueName]OrgValue, erro
 	return ov, nil
 }
 
+func (org Organization) syncFetchExtensions() (orgSyncExtensions, error) {
+	extensions, err := org.Extensions()
+	if err != nil {
+		return nil, err
+	}
+	return orgSyncExtensions(extensions), nil
+}
+
 func (org Organization) syncFetchExfil() (*orgSyncExfilRules, error) {
 	exfils := &orgSyncExfilRules{}
 	orgExfil, err := org.ExfilRules()
@@ -657,14 +1074,7 @@ func (org Organization) syncFetchArtifacts() (orgSyncArtifacts, error) {
 	}
 	rules := orgSyncArtifacts{}
 	for name, artifactRule := range orgArtifacts {
-		rules[name] = OrgSyncArtifactRule{
-			IsIgnoreCert:   artifactRule.IsIgnoreCert,
-			IsDeleteAfter:  artifactRule.IsDeleteAfter,
-			DaysRetentions: artifactRule.DaysRetentions,
-			Patterns:       artifactRule.Patterns,
-			Tags:           artifactRule.Filters.Tags,
-			Platforms:      artifactRule.Filters.Platforms,
-		}
+		rules[name] = OrgSyncArtifactRule{}.FromArtifactRule(artifactRule)
 	}
 	return rules, nil
 }
@@ -685,8 +1095,8 @@ func (org Organization) syncFetchIntegrity() (orgSyncIntegrityRules, error) {
 	return rules, nil
 }
 
-func (org Organization) syncFetchOutputs() (orgSyncOutputs, error) {
-	orgOutputs, err := org.Outputs()
+func syncFetchOutputs(store OutputStore) (orgSyncOutputs, error) {
+	orgOutputs, err := store.Outputs()
 	if err != nil {
 		return nil, err
 	}
@@ -701,16 +1111,20 @@ func (org Organization) syncFetchOutputs() (orgSyncOutputs, error) {
 	return orgOutputs, nil
 }
 
-func (org Organization) syncFetchFPRules() (orgSyncFPRules, error) {
-	orgRules, err := org.FPRules()
+func syncFetchFPRules(store FPRuleStore) (orgSyncFPRules, error) {
+	orgRules, err := store.FPRules()
 	if err != nil {
 		return nil, err
 	}
 	rules := orgSyncFPRules{}
 	for ruleName, rule := range orgRules {
+		if isDetectionRoutingFPRule(ruleName) {
+			continue
+		}
 		rule.Name = ""
 		rules[ruleName] = OrgSyncFPRule{
-			Detection: rule.Detection,
+			Detection:   rule.Detection,
+			Description: rule.Description,
 		}
 	}
 	return rules, nil
@@ -746,6 +1160,7 @@ func (org Organization) syncFetchDRRules(who whoAmIJsonResponse) (orgSyncDRRules
 			continue
 		}
 		rule.Name = ""
+		rule.ReadOnly = IsReadOnlyDRRuleNamespace(rule.Namespace)
 		rules[ruleName] = rule
 	}
 	return rules, nil
@@ -767,6 +1182,22 @@ func (org Organization) syncFetchInstallationKeys() (orgSyncInstallationKeys, er
 	return keys, nil
 }
 
+func (org Organization) syncFetchNetPolicies() (orgSyncNetPolicies, error) {
+	orgPolicies, err := org.NetPolicies()
+	if err != nil {
+		return nil, err
+	}
+	policies := orgSyncNetPolicies{}
+	for name, policy := range orgPolicies {
+		policies[name] = NetPolicy{
+			Type:      policy.Type,
+			Policy:    policy.Policy,
+			ExpiresOn: policy.ExpiresOn,
+		}
+	}
+	return policies, nil
+}
+
 func (org Organization) syncFetchYara() (*orgSyncYara, error) {
 	rules, err := org.YaraListRules()
 	if err != nil {
@@ -806,19 +1237,45 @@ func (org Organization) SyncPushFromFiles(rootConfigFile string, options SyncOpt
 }
 
 func loadEffectiveConfig(parent string, configFile string, options SyncOptions) (OrgConfig, error) {
+	sources := configElementSources{}
+	conf, err := loadEffectiveConfigWithSources(parent, configFile, "", options, sources)
+	if err != nil {
+		return OrgConfig{}, err
+	}
+	if err := options.reportDuplicates(sources.duplicates()); err != nil {
+		return OrgConfig{}, err
+	}
+	return conf, nil
+}
+
+// loadEffectiveConfigWithSources loads a config and its includes, recording
+// the files defining each element, named with the prefix applied by the
+// includes of the file.
+func loadEffectiveConfigWithSources(parent string, configFile string, prefix string, options SyncOptions, sources configElementSources) (OrgConfig, error) {
 	thisConfig, err := loadConfWithOptions(parent, configFile, options)
 	if err != nil {
 		return OrgConfig{}, err
 	}
 
-	includePath := filepath.Join(filepath.Dir(parent), configFile)
+	includePath := configFile
+	if !filepath.IsAbs(configFile) {
+		includePath = filepath.Join(filepath.Dir(parent), configFile)
+	}
+	if err := sources.add(includePath, thisConfig.WithNamePrefix(prefix)); err != nil {
+		return OrgConfig{}, err
+	}
 
-	for _, toInclude := range thisConfig.Includes {
-		incConf, err := loadEffectiveConfig(includePath, toInclude, options)
+	for _, toInclude := range thisConfig.includeEntries() {
+		incConf, err := loadEffectiveConfigWithSources(includePath, toInclude.Path, prefix+toInclude.Prefix, options, sources)
 		if err != nil {
 			return OrgConfig{}, err
 		}
-		thisConfig = thisConfig.Merge(incConf)
+		if toInclude.Prefix != "" {
+			incConf = incConf.WithNamePrefix(toInclude.Prefix)
+		}
+		if thisConfig, err = thisConfig.MergeWithOptions(incConf, options.MergeOptions); err != nil {
+			return OrgConfig{}, fmt.Errorf("%s: %v", toInclude.Path, err)
+		}
 	}
 	return thisConfig, nil
 }
@@ -828,17 +1285,13 @@ func loadConfWithOptions(parent string, configFile string, options SyncOptions)
 	if err != nil {
 		return OrgConfig{}, err
 	}
-
-	thisConfig := OrgConfig{}
-	if err := yaml.Unmarshal(conf, &thisConfig); err != nil {
-		return OrgConfig{}, err
+	if conf, err = DecryptConfig(conf, options.Decrypter); err != nil {
+		return OrgConfig{}, fmt.Errorf("%s: %w", configFile, err)
 	}
 
-	if thisConfig.Version <= 0 {
-		return OrgConfig{}, fmt.Errorf("invalid version found (%s): %v", configFile, thisConfig.Version)
-	}
-	if thisConfig.Version > OrgConfigLatestVersion {
-		return OrgConfig{}, fmt.Errorf("version not supported (%s): %v", configFile, thisConfig.Version)
+	thisConfig, err := LoadOrgConfig(conf)
+	if err != nil {
+		return OrgConfig{}, fmt.Errorf("%s: %v", configFile, err)
 	}
 	return thisConfig, nil
 }
@@ -873,94 +1326,226 @@ func localFileIncludeLoader(parent string, toInclude string) ([]byte, error) {
 	return data, nil
 }
 
-func (org Organization) SyncPush(conf OrgConfig, options SyncOptions) ([]OrgSyncOperation, error) {
-	ops := []OrgSyncOperation{}
+func (org Organization) SyncPush(conf OrgConfig, options SyncOptions) (ops []OrgSyncOperation, err error) {
+	ops = []OrgSyncOperation{}
+	if options.Logger == nil {
+		options.Logger = org.logger
+	}
+
+	if err := org.checkConfigOrg(conf); err != nil {
+		return ops, err
+	}
+	if options.MaxDestructiveOps != nil && options.IsForce && (options.Schedule == nil || !options.Schedule.DeferRemovals) {
+		return org.syncPushGuarded(conf, options)
+	}
+	if options.Schedule != nil && !options.IsDryRun {
+		if err := options.Schedule.wait(); err != nil {
+			return ops, err
+		}
+	}
+	if options.Metrics != nil {
+		// Observed once for the whole sync, including
+		// the nested pushes deferring removals.
+		metrics := options.Metrics
+		options.Metrics = nil
+		org.client = org.client.withRequestErrorHook(metrics.ObserveAPIError)
+		start := time.Now()
+		defer func() {
+			observeSyncMetrics(metrics, ops, time.Since(start), err)
+		}()
+	}
+	if options.TransactionLog != nil && !options.IsDryRun {
+		// Logged once for the whole sync, like the metrics.
+		txLog, err := org.newSyncTransactionLog(conf, options)
+		if err != nil {
+			return ops, err
+		}
+		options.TransactionLog = nil
+		onOperation := options.OnOperation
+		options.OnOperation = func(op OrgSyncOperation, opErr error) {
+			txLog.record(op)
+			if onOperation != nil {
+				onOperation(op, opErr)
+			}
+		}
+		defer func() {
+			if txErr := txLog.err(); txErr != nil && err == nil {
+				err = txErr
+			}
+		}()
+	}
+	if options.Schedule != nil && !options.IsDryRun && options.Schedule.DeferRemovals && options.IsForce {
+		return org.syncPushDeferringRemovals(conf, options)
+	}
 
 	who, err := org.client.whoAmI()
 	if err != nil {
 		return ops, err
 	}
+	if options.AuditSink != nil && !options.IsDryRun {
+		defer func() {
+			if auditErr := org.sendSyncAudit(who, ops, err, options); auditErr != nil && err == nil {
+				err = auditErr
+			}
+		}()
+	}
+	if options.CheckPermissions {
+		if err := org.checkSyncPermissions(who, conf, options); err != nil {
+			return ops, err
+		}
+	}
 
 	// Order matters to minimize issues
 	// of dependance between components.
+	categoryErrs := []error{}
 	if options.SyncResources {
 		newOps, err := org.syncResources(conf.Resources, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("resources: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Resource, fmt.Errorf("resources: %w", err)); err != nil {
+				return ops, err
+			}
+		}
+	}
+	if options.SyncExtensions {
+		newOps, err := org.syncExtensions(conf.Extensions, options)
+		ops = append(ops, newOps...)
+		if err != nil {
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Extension, fmt.Errorf("extensions: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncOrgValues {
 		newOps, err := org.syncOrgValues(conf.OrgValues, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("org-value: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.OrgValue, fmt.Errorf("org-value: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncDRRules {
 		newOps, err := org.syncDRRules(who, conf.DRRules, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("dr-rules: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.DRRule, fmt.Errorf("dr-rules: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncFPRules {
-		newOps, err := org.syncFPRules(conf.FPRules, options)
+		newOps, err := syncFPRules(org, conf.FPRules, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("fp-rules: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.FPRule, fmt.Errorf("fp-rules: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncOutputs {
-		newOps, err := org.syncOutputs(conf.Outputs, options)
+		newOps, err := syncOutputs(org, conf.Outputs, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("outputs: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Output, fmt.Errorf("outputs: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncIntegrity {
 		newOps, err := org.syncIntegrity(conf.Integrity, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("integrity: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Integrity, fmt.Errorf("integrity: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncArtifacts {
 		newOps, err := org.syncArtifacts(conf.Artifacts, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("artifact: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Artifact, fmt.Errorf("artifact: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncExfil {
 		newOps, err := org.syncExfil(conf.Exfil, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("exfil: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.ExfilEvent, fmt.Errorf("exfil: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncHives != nil || len(options.SyncHives) != 0 {
 		newOps, err := org.syncHive(conf.Hives, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("sync_hives: %+v ", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Hives, fmt.Errorf("sync_hives: %w ", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncInstallationKeys {
 		newOps, err := org.syncInstallationKeys(conf.InstallationKeys, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("installation_keys: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.InstallationKey, fmt.Errorf("installation_keys: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 	if options.SyncYara {
 		newOps, err := org.syncYara(conf.Yara, options)
 		ops = append(ops, newOps...)
 		if err != nil {
-			return ops, fmt.Errorf("yara: %v", err)
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.YaraRule, fmt.Errorf("yara: %w", err)); err != nil {
+				return ops, err
+			}
+		}
+	}
+	if options.SyncNetPolicies {
+		newOps, err := org.syncNetPolicies(conf.NetPolicies, options)
+		ops = append(ops, newOps...)
+		if err != nil {
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.NetPolicy, fmt.Errorf("net-policy: %w", err)); err != nil {
+				return ops, err
+			}
+		}
+	}
+	if options.SyncRetention {
+		newOps, err := org.syncRetention(conf.Retention, options)
+		ops = append(ops, newOps...)
+		if err != nil {
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Retention, fmt.Errorf("retention: %w", err)); err != nil {
+				return ops, err
+			}
+		}
+	}
+	if options.SyncDetectionRouting {
+		newOps, err := syncDetectionRouting(org, conf.DetectionRouting, options)
+		ops = append(ops, newOps...)
+		if err != nil {
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.DetectionRoute, fmt.Errorf("detection-routing: %w", err)); err != nil {
+				return ops, err
+			}
+		}
+	}
+	if options.SyncThreatFeeds {
+		newOps, err := org.syncThreatFeeds(conf.ThreatFeeds, options)
+		ops = append(ops, newOps...)
+		if err != nil {
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.ThreatFeed, fmt.Errorf("feeds: %w", err)); err != nil {
+				return ops, err
+			}
 		}
 	}
 
+	if options.ContinueOnError {
+		return ops, syncOpsError(ops, categoryErrs...)
+	}
 	return ops, nil
 }
 
@@ -970,7 +1555,8 @@ func (org Organization) syncOrgValues(values orgSyncOrgValues, options SyncOptio
 	}
 
 	ops := []OrgSyncOperation{}
-	existingVals, err := org.getSupportedOrgValues()
+	catalog := org.orgValueCatalog()
+	existingVals, err := org.getSupportedOrgValues(catalog)
 	if err != nil {
 		return ops, err
 	}
@@ -979,31 +1565,41 @@ func (org Organization) syncOrgValues(values orgSyncOrgValues, options SyncOptio
 	}
 
 	for name, val := range values {
-		if v, ok := existingVals[name]; ok && v == val {
-			ops = append(ops, OrgSyncOperation{
+		v, found := existingVals[name]
+		if found && v == val {
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.OrgValue,
 				ElementName: name,
 			})
 			continue
 		}
+		// Empty values are the ones removed or never set.
+		isUpdated := found && v != ""
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.OrgValue,
 				ElementName: name,
 				IsAdded:     true,
+				IsUpdated:   isUpdated,
 			})
 			continue
 		}
 
-		if err := org.OrgValueSet(name, val); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.OrgValue,
 			ElementName: name,
 			IsAdded:     true,
-		})
+			IsUpdated:   isUpdated,
+		}
+		if err := org.OrgValueSet(name, val); err != nil {
+			err = maskOrgValueError(err, orgValueMetadata(catalog, name), val)
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1011,7 +1607,7 @@ func (org Organization) syncOrgValues(values orgSyncOrgValues, options SyncOptio
 	}
 
 	// remove non existing in config
-	existingVals, err = org.getSupportedOrgValues()
+	existingVals, err = org.getSupportedOrgValues(catalog)
 	if err != nil {
 		return ops, err
 	}
@@ -1023,7 +1619,7 @@ func (org Organization) syncOrgValues(values orgSyncOrgValues, options SyncOptio
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.OrgValue,
 				ElementName: name,
 				IsRemoved:   true,
@@ -1031,17 +1627,21 @@ func (org Organization) syncOrgValues(values orgSyncOrgValues, options SyncOptio
 			continue
 		}
 
-		if err := org.OrgValueSet(name, ""); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.OrgValue,
 			ElementName: name,
 			IsRemoved:   true,
-		})
-	}
-	return ops, nil
-}
+		}
+		if err := org.OrgValueSet(name, ""); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
+	return ops, nil
+}
 
 func (org Organization) syncArtifacts(artifacts orgSyncArtifacts, options SyncOptions) ([]OrgSyncOperation, error) {
 	if !options.IsForce && len(artifacts) == 0 {
@@ -1058,7 +1658,7 @@ func (org Organization) syncArtifacts(artifacts orgSyncArtifacts, options SyncOp
 		orgArtifact, found := orgArtifacts[ruleName]
 		if found {
 			if artifact.EqualsContent(orgArtifact) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Artifact,
 					ElementName: ruleName,
 				})
@@ -1066,22 +1666,28 @@ func (org Organization) syncArtifacts(artifacts orgSyncArtifacts, options SyncOp
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Artifact,
 				ElementName: ruleName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
-		if err := org.ArtifactRuleAdd(ruleName, artifact.ToArtifactRule()); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.Artifact,
 			ElementName: ruleName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if err := org.ArtifactRuleAdd(ruleName, artifact.ToArtifactRule()); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1101,7 +1707,7 @@ func (org Organization) syncArtifacts(artifacts orgSyncArtifacts, options SyncOp
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Artifact,
 				ElementName: ruleName,
 				IsRemoved:   true,
@@ -1109,14 +1715,18 @@ func (org Organization) syncArtifacts(artifacts orgSyncArtifacts, options SyncOp
 			continue
 		}
 
-		if err := org.ArtifactRuleDelete(ruleName); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.Artifact,
 			ElementName: ruleName,
 			IsRemoved:   true,
-		})
+		}
+		if err := org.ArtifactRuleDelete(ruleName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 	return ops, nil
 }
@@ -1140,7 +1750,7 @@ func (org Organization) syncExfil(exfil *orgSyncExfilRules, options SyncOptions)
 		orgWatch, found := orgRules.Watches[ruleName]
 		if found {
 			if watch.EqualsContent(orgWatch) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.ExfilWatch,
 					ElementName: ruleName,
 				})
@@ -1148,29 +1758,35 @@ func (org Organization) syncExfil(exfil *orgSyncExfilRules, options SyncOptions)
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.ExfilWatch,
 				ElementName: ruleName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
-		if err := org.ExfilRuleWatchAdd(ruleName, watch); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.ExfilWatch,
 			ElementName: ruleName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if err := org.ExfilRuleWatchAdd(ruleName, watch); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	for ruleName, event := range exfil.Events {
 		orgEvent, found := orgRules.Events[ruleName]
 		if found {
 			if event.EqualsContent(orgEvent) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.ExfilEvent,
 					ElementName: ruleName,
 				})
@@ -1178,22 +1794,28 @@ func (org Organization) syncExfil(exfil *orgSyncExfilRules, options SyncOptions)
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.ExfilEvent,
 				ElementName: ruleName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
-		if err := org.ExfilRuleEventAdd(ruleName, event); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.ExfilEvent,
 			ElementName: ruleName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if err := org.ExfilRuleEventAdd(ruleName, event); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1213,7 +1835,7 @@ func (org Organization) syncExfil(exfil *orgSyncExfilRules, options SyncOptions)
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.ExfilWatch,
 				ElementName: ruleName,
 				IsRemoved:   true,
@@ -1221,14 +1843,18 @@ func (org Organization) syncExfil(exfil *orgSyncExfilRules, options SyncOptions)
 			continue
 		}
 
-		if err := org.ExfilRuleWatchDelete(ruleName); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.ExfilWatch,
 			ElementName: ruleName,
 			IsRemoved:   true,
-		})
+		}
+		if err := org.ExfilRuleWatchDelete(ruleName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	for ruleName := range orgRules.Events {
@@ -1238,7 +1864,7 @@ func (org Organization) syncExfil(exfil *orgSyncExfilRules, options SyncOptions)
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.ExfilEvent,
 				ElementName: ruleName,
 				IsRemoved:   true,
@@ -1246,14 +1872,18 @@ func (org Organization) syncExfil(exfil *orgSyncExfilRules, options SyncOptions)
 			continue
 		}
 
-		if err := org.ExfilRuleEventDelete(ruleName); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.ExfilEvent,
 			ElementName: ruleName,
 			IsRemoved:   true,
-		})
+		}
+		if err := org.ExfilRuleEventDelete(ruleName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 	return ops, nil
 }
@@ -1273,7 +1903,7 @@ func (org Organization) syncIntegrity(integrity orgSyncIntegrityRules, options S
 		orgIntRules, found := orgIntRules[ruleName]
 		if found {
 			if rule.EqualsContent(orgIntRules) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Integrity,
 					ElementName: ruleName,
 				})
@@ -1281,14 +1911,21 @@ func (org Organization) syncIntegrity(integrity orgSyncIntegrityRules, options S
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Integrity,
 				ElementName: ruleName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.Integrity,
+			ElementName: ruleName,
+			IsAdded:     true,
+			IsUpdated:   found,
+		}
 		if err := org.IntegrityRuleAdd(ruleName, IntegrityRule{
 			Patterns: rule.Patterns,
 			Filters: IntegrityRuleFilter{
@@ -1296,13 +1933,12 @@ func (org Organization) syncIntegrity(integrity orgSyncIntegrityRules, options S
 				Platforms: rule.Platforms,
 			},
 		}); err != nil {
-			return ops, err
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
 		}
-		ops = append(ops, OrgSyncOperation{
-			ElementType: OrgSyncOperationElementType.Integrity,
-			ElementName: ruleName,
-			IsAdded:     true,
-		})
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1322,32 +1958,36 @@ func (org Organization) syncIntegrity(integrity orgSyncIntegrityRules, options S
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Integrity,
 				ElementName: ruleName,
 				IsRemoved:   true,
 			})
 			continue
 		}
-		if err := org.IntegrityRuleDelete(ruleName); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.Integrity,
 			ElementName: ruleName,
 			IsRemoved:   true,
-		})
+		}
+		if err := org.IntegrityRuleDelete(ruleName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 	return ops, nil
 }
 
-func (org Organization) syncOutputs(outputs orgSyncOutputs, options SyncOptions) ([]OrgSyncOperation, error) {
+func syncOutputs(store OutputStore, outputs orgSyncOutputs, options SyncOptions) ([]OrgSyncOperation, error) {
 	if !options.IsForce && len(outputs) == 0 {
 		return nil, nil
 	}
 
 	ops := []OrgSyncOperation{}
-	orgOutputs, err := org.Outputs()
+	orgOutputs, err := store.Outputs()
 	if err != nil {
 		return ops, err
 	}
@@ -1358,7 +1998,7 @@ func (org Organization) syncOutputs(outputs orgSyncOutputs, options SyncOptions)
 		orgOutput, found := orgOutputs[outputName]
 		if found {
 			if output.Equals(orgOutput) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Output,
 					ElementName: outputName,
 				})
@@ -1366,22 +2006,28 @@ func (org Organization) syncOutputs(outputs orgSyncOutputs, options SyncOptions)
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Output,
 				ElementName: outputName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 		output.Name = outputName
-		if _, err := org.OutputAdd(output); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.Output,
 			ElementName: outputName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if _, err := store.OutputAdd(output); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1389,7 +2035,7 @@ func (org Organization) syncOutputs(outputs orgSyncOutputs, options SyncOptions)
 	}
 
 	// refetch
-	orgOutputs, err = org.Outputs()
+	orgOutputs, err = store.Outputs()
 	if err != nil {
 		return ops, err
 	}
@@ -1401,42 +2047,180 @@ func (org Organization) syncOutputs(outputs orgSyncOutputs, options SyncOptions)
 			continue
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Output,
 				ElementName: outputName,
 				IsRemoved:   true,
 			})
 			continue
 		}
-		if _, err := org.OutputDel(outputName); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.Output,
 			ElementName: outputName,
 			IsRemoved:   true,
-		})
+		}
+		if _, err := store.OutputDel(outputName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
+	return ops, nil
+}
+
+func (org Organization) syncNetPolicies(policies orgSyncNetPolicies, options SyncOptions) ([]OrgSyncOperation, error) {
+	if !options.IsForce && len(policies) == 0 {
+		return nil, nil
+	}
+
+	ops := []OrgSyncOperation{}
+	orgPolicies, err := org.NetPolicies()
+	if err != nil {
+		return ops, err
+	}
+
+	for name, policy := range policies {
+		policy.Name = name
+		orgPolicy, found := orgPolicies[name]
+		if found && policy.EqualsContent(orgPolicy) {
+			ops = options.appendOp(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.NetPolicy,
+				ElementName: name,
+			})
+			continue
+		}
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.NetPolicy,
+			ElementName: name,
+			IsAdded:     true,
+			IsUpdated:   found,
+		}
+		if options.IsDryRun {
+			ops = options.appendOp(ops, op)
+			continue
+		}
+		if err := org.NetPolicySet(policy); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
+
+	if !options.IsForce {
+		return ops, nil
+	}
+
+	// Remove the policies not in the config.
+	for name := range orgPolicies {
+		if _, found := policies[name]; found {
+			continue
+		}
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.NetPolicy,
+			ElementName: name,
+			IsRemoved:   true,
+		}
+		if options.IsDryRun {
+			ops = options.appendOp(ops, op)
+			continue
+		}
+		if err := org.NetPolicyDelete(name); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
+	return ops, nil
+}
+
+// syncRetention only updates the settings present in the config
+// since retention settings can't be removed, even with IsForce.
+func (org Organization) syncRetention(retention *RetentionConfig, options SyncOptions) ([]OrgSyncOperation, error) {
+	if retention == nil {
+		return nil, nil
+	}
+
+	ops := []OrgSyncOperation{}
+	current, err := org.RetentionConfig()
+	if err != nil {
+		return ops, err
+	}
+
+	changes := []OrgSyncOperation{}
+	for _, setting := range []struct {
+		name    string
+		wanted  uint
+		current uint
+	}{
+		{"event_retention_days", retention.EventRetentionDays, current.EventRetentionDays},
+		{"artifact_retention_days", retention.ArtifactRetentionDays, current.ArtifactRetentionDays},
+	} {
+		if setting.wanted == 0 {
+			continue
+		}
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.Retention,
+			ElementName: setting.name,
+		}
+		if setting.wanted == setting.current {
+			ops = options.appendOp(ops, op)
+			continue
+		}
+		op.IsAdded = true
+		op.IsUpdated = true
+		changes = append(changes, op)
+	}
+	if len(changes) == 0 {
+		return ops, nil
+	}
+
+	if !options.IsDryRun {
+		if err := org.RetentionConfigSet(*retention); err != nil {
+			for _, op := range changes {
+				if ops, err = options.failOp(ops, op, err); err != nil {
+					return ops, err
+				}
+			}
+			return ops, nil
+		}
+	}
+	for _, op := range changes {
+		ops = options.appendOp(ops, op)
 	}
 	return ops, nil
 }
 
-func (org Organization) syncFPRules(rules orgSyncFPRules, options SyncOptions) ([]OrgSyncOperation, error) {
+func syncFPRules(store FPRuleStore, rules orgSyncFPRules, options SyncOptions) ([]OrgSyncOperation, error) {
 	if !options.IsForce && len(rules) == 0 {
 		return nil, nil
 	}
 
 	ops := []OrgSyncOperation{}
-	orgRules, err := org.FPRules()
+	orgRules, err := store.FPRules()
 	if err != nil {
 		return ops, err
 	}
 
 	// Add rules that should be replaced first
 	for ruleName, rule := range rules {
+		if options.isFPRuleProtected(ruleName) {
+			ops = options.appendOp(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.FPRule,
+				ElementName: ruleName,
+				IsSkipped:   true,
+			})
+			continue
+		}
 		orgRule, found := orgRules[ruleName]
 		if found {
-			if rule.DetectionEquals(orgRule) {
-				ops = append(ops, OrgSyncOperation{
+			if rule.Equals(orgRule) {
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.FPRule,
 					ElementName: ruleName,
 				})
@@ -1445,22 +2229,28 @@ func (org Organization) syncFPRules(rules orgSyncFPRules, options SyncOptions) (
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.FPRule,
 				ElementName: ruleName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
-		if err := org.FPRuleAdd(ruleName, rule.Detection, FPRuleOptions{IsReplace: true}); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.FPRule,
 			ElementName: ruleName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if err := store.FPRuleAdd(ruleName, rule.Detection, FPRuleOptions{IsReplace: true, Description: rule.Description}); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1468,7 +2258,7 @@ func (org Organization) syncFPRules(rules orgSyncFPRules, options SyncOptions) (
 	}
 
 	// refetch
-	orgRules, err = org.FPRules()
+	orgRules, err = store.FPRules()
 	if err != nil {
 		return ops, err
 	}
@@ -1476,25 +2266,38 @@ func (org Organization) syncFPRules(rules orgSyncFPRules, options SyncOptions) (
 	// Go through existing rules and removes the ones not in our list
 	for ruleName := range orgRules {
 		_, found := rules[ruleName]
-		if found {
+		if found || isDetectionRoutingFPRule(ruleName) {
 			continue
 		}
-		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+		if options.isFPRuleProtected(ruleName) {
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.FPRule,
 				ElementName: ruleName,
 				IsRemoved:   true,
+				IsSkipped:   true,
 			})
 			continue
 		}
-		if err := org.FPRuleDelete(ruleName); err != nil {
-			return ops, err
+		if options.IsDryRun {
+			ops = options.appendOp(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.FPRule,
+				ElementName: ruleName,
+				IsRemoved:   true,
+			})
+			continue
 		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.FPRule,
 			ElementName: ruleName,
 			IsRemoved:   true,
-		})
+		}
+		if err := store.FPRuleDelete(ruleName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 	return ops, nil
 }
@@ -1518,7 +2321,7 @@ func (org Organization) syncInstallationKeys(ikeys orgSyncInstallationKeys, opti
 		orgKey, found := orgKeyMap[keyName]
 		if found {
 			if key.EqualsContent(orgKey) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.InstallationKey,
 					ElementName: keyName,
 				})
@@ -1526,22 +2329,28 @@ func (org Organization) syncInstallationKeys(ikeys orgSyncInstallationKeys, opti
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.InstallationKey,
 				ElementName: keyName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
-		if _, err := org.AddInstallationKey(key); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.InstallationKey,
 			ElementName: keyName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if _, err := org.AddInstallationKey(key); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1561,21 +2370,25 @@ func (org Organization) syncInstallationKeys(ikeys orgSyncInstallationKeys, opti
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.InstallationKey,
 				ElementName: k.Description,
 				IsRemoved:   true,
 			})
 			continue
 		}
-		if err := org.DelInstallationKey(k.ID); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.InstallationKey,
 			ElementName: k.Description,
 			IsRemoved:   true,
-		})
+		}
+		if err := org.DelInstallationKey(k.ID); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 	return ops, nil
 }
@@ -1599,7 +2412,7 @@ func (org Organization) syncYara(yara *orgSyncYara, options SyncOptions) ([]OrgS
 		orgSource, found := orgSources[sourceName]
 		if found {
 			if source.EqualsContent(orgSource) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.YaraSource,
 					ElementName: sourceName,
 				})
@@ -1607,29 +2420,35 @@ func (org Organization) syncYara(yara *orgSyncYara, options SyncOptions) ([]OrgS
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.YaraSource,
 				ElementName: sourceName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
-		if err := org.YaraSourceAdd(sourceName, source); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.YaraSource,
 			ElementName: sourceName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if err := org.YaraSourceAdd(sourceName, source); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	for ruleName, rule := range yara.Rules {
 		orgRule, found := orgRules[ruleName]
 		if found {
 			if rule.EqualsContent(orgRule) {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.YaraRule,
 					ElementName: ruleName,
 				})
@@ -1637,22 +2456,28 @@ func (org Organization) syncYara(yara *orgSyncYara, options SyncOptions) ([]OrgS
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.YaraRule,
 				ElementName: ruleName,
 				IsAdded:     true,
+				IsUpdated:   found,
 			})
 			continue
 		}
 
-		if err := org.YaraRuleAdd(ruleName, rule); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.YaraRule,
 			ElementName: ruleName,
 			IsAdded:     true,
-		})
+			IsUpdated:   found,
+		}
+		if err := org.YaraRuleAdd(ruleName, rule); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	if !options.IsForce {
@@ -1676,21 +2501,25 @@ func (org Organization) syncYara(yara *orgSyncYara, options SyncOptions) ([]OrgS
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.YaraRule,
 				ElementName: ruleName,
 				IsRemoved:   true,
 			})
 			continue
 		}
-		if err := org.YaraRuleDelete(ruleName); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.YaraRule,
 			ElementName: ruleName,
 			IsRemoved:   true,
-		})
+		}
+		if err := org.YaraRuleDelete(ruleName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 
 	for sourceName := range orgSources {
@@ -1700,57 +2529,84 @@ func (org Organization) syncYara(yara *orgSyncYara, options SyncOptions) ([]OrgS
 		}
 
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{
+			ops = options.appendOp(ops, OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.YaraSource,
 				ElementName: sourceName,
 				IsRemoved:   true,
 			})
 			continue
 		}
-		if err := org.YaraSourceDelete(sourceName); err != nil {
-			return ops, err
-		}
-		ops = append(ops, OrgSyncOperation{
+		op := OrgSyncOperation{
 			ElementType: OrgSyncOperationElementType.YaraSource,
 			ElementName: sourceName,
 			IsRemoved:   true,
-		})
+		}
+		if err := org.YaraSourceDelete(sourceName); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
 	}
 	return ops, nil
 }
 
-func (org Organization) resolveAvailableNamespaces(who whoAmIJsonResponse) map[string]struct{} {
-	// Check which namespaces we have available.
-	availableNamespaces := map[string]struct{}{}
-	if who.hasPermissionForOrg(org.client.options.OID, "dr.list") {
-		availableNamespaces["general"] = struct{}{}
+func (options SyncOptions) isNamespaceProtected(namespace string) bool {
+	if namespace == "" {
+		namespace = "general"
 	}
-	if who.hasPermissionForOrg(org.client.options.OID, "dr.list.managed") {
-		availableNamespaces["managed"] = struct{}{}
+	for _, ns := range options.ProtectedNamespaces {
+		if ns == namespace {
+			return true
+		}
 	}
-	if who.hasPermissionForOrg(org.client.options.OID, "dr.list.replicant") {
-		availableNamespaces["replicant"] = struct{}{}
+	return false
+}
+
+func (options SyncOptions) isFPRuleProtected(ruleName FPRuleName) bool {
+	for _, name := range options.ProtectedFPRules {
+		if name == ruleName {
+			return true
+		}
 	}
-	if who.hasPermissionForOrg(org.client.options.OID, "dr.list.service") {
-		availableNamespaces["service"] = struct{}{}
+	return false
+}
+
+func (org Organization) resolveAvailableNamespaces(who whoAmIJsonResponse) map[string]struct{} {
+	// Check which namespaces we have available, any
+	// "dr.list.<namespace>" permission granting one.
+	availableNamespaces := map[string]struct{}{}
+	for _, perm := range who.toWhoAmI().EffectivePermissions(org.client.options.OID) {
+		if perm == "dr.list" {
+			availableNamespaces["general"] = struct{}{}
+		} else if strings.HasPrefix(perm, "dr.list.") {
+			availableNamespaces[strings.TrimPrefix(perm, "dr.list.")] = struct{}{}
+		}
 	}
 	return availableNamespaces
 }
 
 func (org Organization) drRulesFromNamespaces(namespaces map[string]struct{}) (existingRules orgSyncDRRules, err error) {
 	existingRules = orgSyncDRRules{}
-	// Get rules from all the namespaces we have access to.
+	// Get rules from all the namespaces we have access to, decoding
+	// them as they are fetched rather than listing them as Dicts first.
 	for ns := range namespaces {
-		tmpRules, err := org.DRRules(WithNamespace(ns))
-		if err != nil {
-			return existingRules, fmt.Errorf("DRRules %s: %v", ns, err)
-		}
-		for ruleName, rule := range tmpRules {
+		org.drRulesRawIter(context.Background(), WithNamespace(ns))(func(ruleName string, raw json.RawMessage, e error) bool {
+			if e != nil {
+				err = fmt.Errorf("DRRules %s: %v", ns, e)
+				return false
+			}
 			parsedRule := CoreDRRule{}
-			if err := rule.UnMarshalToStruct(&parsedRule); err != nil {
-				return existingRules, fmt.Errorf("UnMarshalToStruct %s: %v", ruleName, err)
+			if e := json.Unmarshal(raw, &parsedRule); e != nil {
+				err = fmt.Errorf("rule %s: %v", ruleName, e)
+				return false
 			}
 			existingRules[ruleName] = parsedRule
+			return true
+		})
+		if err != nil {
+			return existingRules, err
 		}
 	}
 	return existingRules, nil
@@ -1775,17 +2631,23 @@ func (org Organization) syncDRRules(who whoAmIJsonResponse, rules orgSyncDRRules
 			isTrue := true
 			rule.IsEnabled = &isTrue
 		}
-		if existingRule, ok := existingRules[ruleName]; ok {
-			// A rule with that name is already there.
-			// Is it the exact same rule?
+		existingRule, isExisting := existingRules[ruleName]
+		if rule.ReadOnly || options.isNamespaceProtected(rule.Namespace) || (isExisting && options.isNamespaceProtected(existingRule.Namespace)) {
+			ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsSkipped: true})
+			continue
+		}
+		if isExisting {
+			// A rule with that name is already there. Is it the exact
+			// same rule? The listing holds the rules as stored, with
+			// the defaults set by the API, so no need to get each one.
 			if existingRule.Equal(rule) {
-				ops = append(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName})
+				ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName})
 				// Nothing to do, move on.
 				continue
 			}
 			// If this is a DryRun, just report the op and move on.
 			if options.IsDryRun {
-				ops = append(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsAdded: true})
+				ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsAdded: true, IsUpdated: isExisting})
 				continue
 			}
 			// It must be replaced.
@@ -1797,22 +2659,36 @@ func (org Organization) syncDRRules(who whoAmIJsonResponse, rules orgSyncDRRules
 					existingNs = "general"
 				}
 				if err := org.DRRuleDelete(ruleName, WithNamespace(existingNs)); err != nil {
-					return ops, fmt.Errorf("DRDelRule %s: %v", ruleName, err)
+					op := OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsAdded: true, IsUpdated: isExisting}
+					if ops, err = options.failOp(ops, op, fmt.Errorf("DRDelRule %s: %v", ruleName, err)); err != nil {
+						return ops, err
+					}
+					continue
 				}
 			}
 		}
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsAdded: true})
+			ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsAdded: true, IsUpdated: isExisting})
 			continue
 		}
+		op := OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsAdded: true, IsUpdated: isExisting}
 		if err := org.DRRuleAdd(ruleName, rule.Detect, rule.Response, NewDRRuleOptions{
-			IsReplace: true,
-			Namespace: rule.Namespace,
-			IsEnabled: *rule.IsEnabled,
+			IsReplace:   true,
+			Namespace:   rule.Namespace,
+			IsEnabled:   *rule.IsEnabled,
+			Author:      options.AnnotateAuthor,
+			TTL:         rule.TTL,
+			Filters:     rule.Filters,
+			Priority:    rule.Priority,
+			Suppression: rule.Suppression,
+			Description: rule.Description,
 		}); err != nil {
-			return ops, fmt.Errorf("DRRuleAdd %s: %v", ruleName, err)
+			if ops, err = options.failOp(ops, op, fmt.Errorf("DRRuleAdd %s: %v", ruleName, err)); err != nil {
+				return ops, err
+			}
+			continue
 		}
-		ops = append(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsAdded: true})
+		ops = options.appendOp(ops, op)
 	}
 
 	// If we're not Forcing, then we're done.
@@ -1830,15 +2706,23 @@ func (org Organization) syncDRRules(who whoAmIJsonResponse, rules orgSyncDRRules
 			// Still there.
 			continue
 		}
+		if IsReadOnlyDRRuleNamespace(rule.Namespace) || options.isNamespaceProtected(rule.Namespace) {
+			ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsRemoved: true, IsSkipped: true})
+			continue
+		}
 		// If this is a DryRun, report the op and move on.
 		if options.IsDryRun {
-			ops = append(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsRemoved: true})
+			ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsRemoved: true})
 			continue
 		}
+		op := OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsRemoved: true}
 		if err := org.DRRuleDelete(ruleName, WithNamespace(rule.Namespace)); err != nil {
-			return ops, fmt.Errorf("DRDelRule %s: %v", ruleName, err)
+			if ops, err = options.failOp(ops, op, fmt.Errorf("DRDelRule %s: %v", ruleName, err)); err != nil {
+				return ops, err
+			}
+			continue
 		}
-		ops = append(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: ruleName, IsRemoved: true})
+		ops = options.appendOp(ops, op)
 	}
 
 	return ops, nil
@@ -1867,21 +2751,27 @@ func (org Organization) syncResources(resources orgSyncResources, options SyncOp
 			for _, resName := range resNames {
 				fullResName := fmt.Sprintf("%s/%s", resCat, resName)
 				if options.IsDryRun {
-					ops = append(ops, OrgSyncOperation{
+					ops = options.appendOp(ops, OrgSyncOperation{
 						ElementType: OrgSyncOperationElementType.Resource,
 						ElementName: fullResName,
 						IsAdded:     true,
+						IsUpdated:   found,
 					})
 					continue
 				}
-				if err := org.ResourceSubscribe(resName, resCat); err != nil {
-					return ops, err
-				}
-				ops = append(ops, OrgSyncOperation{
+				op := OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Resource,
 					ElementName: fullResName,
 					IsAdded:     true,
-				})
+					IsUpdated:   found,
+				}
+				if err := org.ResourceSubscribe(resName, resCat); err != nil {
+					if ops, err = options.failOp(ops, op, err); err != nil {
+						return ops, err
+					}
+					continue
+				}
+				ops = options.appendOp(ops, op)
 			}
 			continue
 		}
@@ -1890,28 +2780,34 @@ func (org Organization) syncResources(resources orgSyncResources, options SyncOp
 			_, found := orgResCat[resName]
 			fullResName := fmt.Sprintf("%s/%s", resCat, resName)
 			if found {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Resource,
 					ElementName: fullResName,
 				})
 				continue
 			}
 			if options.IsDryRun {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Resource,
 					ElementName: fullResName,
 					IsAdded:     true,
+					IsUpdated:   found,
 				})
 				continue
 			}
-			if err := org.ResourceSubscribe(resName, resCat); err != nil {
-				return ops, err
-			}
-			ops = append(ops, OrgSyncOperation{
+			op := OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Resource,
 				ElementName: fullResName,
 				IsAdded:     true,
-			})
+				IsUpdated:   found,
+			}
+			if err := org.ResourceSubscribe(resName, resCat); err != nil {
+				if ops, err = options.failOp(ops, op, err); err != nil {
+					return ops, err
+				}
+				continue
+			}
+			ops = options.appendOp(ops, op)
 		}
 	}
 
@@ -1953,24 +2849,113 @@ func (org Organization) syncResources(resources orgSyncResources, options SyncOp
 
 			fullResName := fmt.Sprintf("%s/%s", orgResCat, orgResName)
 			if options.IsDryRun {
-				ops = append(ops, OrgSyncOperation{
+				ops = options.appendOp(ops, OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Resource,
 					ElementName: fullResName,
 					IsRemoved:   true,
 				})
 				continue
 			}
-			if err := org.ResourceUnsubscribe(orgResName, orgResCat); err != nil {
-				return ops, err
-			}
-			ops = append(ops, OrgSyncOperation{
+			op := OrgSyncOperation{
 				ElementType: OrgSyncOperationElementType.Resource,
 				ElementName: fullResName,
 				IsRemoved:   true,
+			}
+			if err := org.ResourceUnsubscribe(orgResName, orgResCat); err != nil {
+				if ops, err = options.failOp(ops, op, err); err != nil {
+					return ops, err
+				}
+				continue
+			}
+			ops = options.appendOp(ops, op)
+		}
+	}
+
+	return ops, nil
+}
+
+func (org Organization) syncExtensions(extensions orgSyncExtensions, options SyncOptions) ([]OrgSyncOperation, error) {
+	if !options.IsForce && len(extensions) == 0 {
+		return nil, nil
+	}
+
+	ops := []OrgSyncOperation{}
+	orgExtensions, err := org.Extensions()
+	if err != nil {
+		return ops, err
+	}
+	subscribed := map[ExtensionName]struct{}{}
+	for _, e := range orgExtensions {
+		subscribed[e] = struct{}{}
+	}
+
+	wanted := map[ExtensionName]struct{}{}
+	for _, e := range extensions {
+		wanted[e] = struct{}{}
+		if _, found := subscribed[e]; found {
+			ops = options.appendOp(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.Extension,
+				ElementName: e,
 			})
+			continue
+		}
+		if options.IsDryRun {
+			ops = options.appendOp(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.Extension,
+				ElementName: e,
+				IsAdded:     true,
+			})
+			continue
 		}
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.Extension,
+			ElementName: e,
+			IsAdded:     true,
+		}
+		if err := org.SubscribeToExtension(e); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
+
+	if !options.IsForce {
+		return ops, nil
 	}
 
+	// Like resources, only remove extensions if the section
+	// is present in the config to avoid unexpected unsubscribes.
+	if len(extensions) == 0 {
+		return ops, nil
+	}
+
+	for _, e := range orgExtensions {
+		if _, found := wanted[e]; found {
+			continue
+		}
+		if options.IsDryRun {
+			ops = options.appendOp(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.Extension,
+				ElementName: e,
+				IsRemoved:   true,
+			})
+			continue
+		}
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.Extension,
+			ElementName: e,
+			IsRemoved:   true,
+		}
+		if err := org.UnsubscribeFromExtension(e); err != nil {
+			if ops, err = options.failOp(ops, op, err); err != nil {
+				return ops, err
+			}
+			continue
+		}
+		ops = options.appendOp(ops, op)
+	}
 	return ops, nil
 }
 
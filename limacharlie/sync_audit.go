@@ -0,0 +1,129 @@
+package limacharlie
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// SyncAuditRecord summarizes the changes applied by a SyncPush.
+type SyncAuditRecord struct {
+	OID string `json:"oid"`
+	// Identity of the credentials used for the sync.
+	Identity  string `json:"ident"`
+	Author    string `json:"author,omitempty"`
+	TimeStamp int64  `json:"ts"`
+	IsForce   bool   `json:"is_force"`
+
+	Summary SyncPlanSummary `json:"summary"`
+	// Operations which changed the Org or failed.
+	Operations []SyncOperationRecord `json:"operations"`
+	// Error is set when the sync failed.
+	Error string `json:"error,omitempty"`
+}
+
+// SyncAuditSink receives the audit record of each SyncPush.
+type SyncAuditSink interface {
+	SendSyncAudit(record SyncAuditRecord) error
+}
+
+// SyncAuditFunc adapts a function, like a hook recording
+// the changes in an extension, to a SyncAuditSink.
+type SyncAuditFunc func(record SyncAuditRecord) error
+
+func (f SyncAuditFunc) SendSyncAudit(record SyncAuditRecord) error {
+	return f(record)
+}
+
+// SyncAuditWebhook posts the audit records as JSON to a URL, like the
+// one of a webhook adapter, signing them the same way as the webhook
+// output module so they can be received with a WebhookReceiver.
+type SyncAuditWebhook struct {
+	URL string
+
+	// Secret used to sign the body in the WebhookSignatureHeader.
+	// Optional.
+	SecretKey string
+
+	// Header sent along with the record, like an adapter's secret.
+	// Optional.
+	AuthHeaderName  string
+	AuthHeaderValue string
+
+	// Client used to post the records, defaults to one
+	// with a 10 seconds timeout.
+	Client *http.Client
+}
+
+func (w SyncAuditWebhook) SendSyncAudit(record SyncAuditRecord) error {
+	body, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	r, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("User-Agent", "limacharlie-sdk")
+	if w.SecretKey != "" {
+		mac := hmac.New(sha256.New, []byte(w.SecretKey))
+		mac.Write(body)
+		r.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+	if w.AuthHeaderName != "" {
+		r.Header.Set(w.AuthHeaderName, w.AuthHeaderValue)
+	}
+
+	c := w.Client
+	if c == nil {
+		c = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := c.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		details, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("%s: %s", resp.Status, string(details))
+	}
+	return nil
+}
+
+func newSyncAuditRecord(oid string, who whoAmIJsonResponse, ops []OrgSyncOperation, syncErr error, options SyncOptions) SyncAuditRecord {
+	plan := NewSyncPlanResult(ops)
+	record := SyncAuditRecord{
+		OID:        oid,
+		Identity:   who.toWhoAmI().Identity,
+		Author:     options.AnnotateAuthor,
+		TimeStamp:  time.Now().Unix(),
+		IsForce:    options.IsForce,
+		Summary:    plan.Summary(),
+		Operations: []SyncOperationRecord{},
+	}
+	for _, op := range plan.Operations {
+		if op.IsUnchanged() {
+			continue
+		}
+		record.Operations = append(record.Operations, newSyncOperationRecord(op))
+	}
+	if syncErr != nil {
+		record.Error = syncErr.Error()
+	}
+	return record
+}
+
+func (org Organization) sendSyncAudit(who whoAmIJsonResponse, ops []OrgSyncOperation, syncErr error, options SyncOptions) error {
+	record := newSyncAuditRecord(org.client.options.OID, who, ops, syncErr, options)
+	if err := options.AuditSink.SendSyncAudit(record); err != nil {
+		return fmt.Errorf("sync audit: %v", err)
+	}
+	return nil
+}
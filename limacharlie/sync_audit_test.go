@@ -0,0 +1,77 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncAuditRecord(t *testing.T) {
+	a := assert.New(t)
+	ident := "ci@example.com"
+	ops := []OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "o1", IsAdded: true},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "r1"},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "r2", IsRemoved: true},
+	}
+	record := newSyncAuditRecord("oid", whoAmIJsonResponse{Identity: &ident}, ops, errors.New("failed"), SyncOptions{IsForce: true, AnnotateAuthor: "jdoe"})
+	a.Equal("oid", record.OID)
+	a.Equal(ident, record.Identity)
+	a.Equal("jdoe", record.Author)
+	a.True(record.IsForce)
+	a.Equal("failed", record.Error)
+	a.Equal(SyncPlanSummary{Added: 1, Removed: 1, Unchanged: 1, Additive: 1, Destructive: 1}, record.Summary)
+	a.Equal([]SyncOperationRecord{
+		{Type: OrgSyncOperationElementType.DRRule, Name: "r2", Action: "remove", Impact: SyncOperationImpacts.Destructive},
+		{Type: OrgSyncOperationElementType.Output, Name: "o1", Action: "add", Impact: SyncOperationImpacts.Additive},
+	}, record.Operations)
+}
+
+func TestSyncAuditWebhook(t *testing.T) {
+	a := assert.New(t)
+	received := SyncAuditRecord{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		a.NoError(ValidateWebhookSignature("s3cr3t", body, r.Header.Get(WebhookSignatureHeader)))
+		a.Equal("v", r.Header.Get("x-auth"))
+		a.NoError(json.Unmarshal(body, &received))
+	}))
+	defer server.Close()
+
+	sink := SyncAuditWebhook{URL: server.URL, SecretKey: "s3cr3t", AuthHeaderName: "x-auth", AuthHeaderValue: "v"}
+	a.NoError(sink.SendSyncAudit(SyncAuditRecord{OID: "oid", Summary: SyncPlanSummary{Added: 2}}))
+	a.Equal("oid", received.OID)
+	a.Equal(2, received.Summary.Added)
+}
+
+func TestSyncPushAudit(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return jsonResponse(http.StatusOK, `{"ident":"ci"}`), nil
+	}))
+
+	records := []SyncAuditRecord{}
+	sink := SyncAuditFunc(func(record SyncAuditRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	_, err := org.SyncPush(OrgConfig{}, SyncOptions{AuditSink: sink})
+	a.NoError(err)
+	a.Len(records, 1)
+	a.Equal("ci", records[0].Identity)
+
+	// Dry runs are not audited.
+	_, err = org.SyncPush(OrgConfig{}, SyncOptions{AuditSink: sink, IsDryRun: true})
+	a.NoError(err)
+	a.Len(records, 1)
+
+	_, err = org.SyncPush(OrgConfig{}, SyncOptions{AuditSink: SyncAuditFunc(func(SyncAuditRecord) error {
+		return errors.New("unreachable")
+	})})
+	a.EqualError(err, "sync audit: unreachable")
+}
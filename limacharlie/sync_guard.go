@@ -0,0 +1,60 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrorOrgMismatch is returned by SyncPush when the OID or the
+// OrgName asserted by the config are not those of the Org.
+var ErrorOrgMismatch = errors.New("config is for another org")
+
+// ErrorTooManyDestructiveOps is returned by SyncPush, before modifying
+// the Org, when a forced sync would remove more elements than
+// SyncOptions.MaxDestructiveOps.
+var ErrorTooManyDestructiveOps = errors.New("too many destructive operations")
+
+// syncPushGuarded plans a forced sync and only applies
+// it if it removes at most MaxDestructiveOps elements.
+func (org Organization) syncPushGuarded(conf OrgConfig, options SyncOptions) ([]OrgSyncOperation, error) {
+	planning := options
+	planning.MaxDestructiveOps = nil
+	planning.IsDryRun = true
+	planning.Schedule = nil
+	planning.AuditSink = nil
+	planning.TransactionLog = nil
+	planning.Metrics = nil
+	planning.OnOperation = nil
+	planning.Logger = nil
+	planned, err := org.SyncPush(conf, planning)
+	if err != nil {
+		return planned, err
+	}
+	if n := len(NewSyncPlanResult(planned).DestructiveOperations()); n > *options.MaxDestructiveOps {
+		return planned, fmt.Errorf("%w: %d elements would be removed, more than the maximum of %d", ErrorTooManyDestructiveOps, n, *options.MaxDestructiveOps)
+	}
+	if options.IsDryRun && options.OnOperation == nil && options.Metrics == nil {
+		return planned, nil
+	}
+	options.MaxDestructiveOps = nil
+	return org.SyncPush(conf, options)
+}
+
+// checkConfigOrg verifies the Org the config asserts it is for, if any.
+func (org Organization) checkConfigOrg(conf OrgConfig) error {
+	if conf.OID != "" && !strings.EqualFold(conf.OID, org.GetOID()) {
+		return fmt.Errorf("%w: config is for %s, not %s", ErrorOrgMismatch, conf.OID, org.GetOID())
+	}
+	if conf.OrgName == "" {
+		return nil
+	}
+	info, err := org.GetInfo()
+	if err != nil {
+		return fmt.Errorf("verifying the org name: %v", err)
+	}
+	if info.Name != conf.OrgName {
+		return fmt.Errorf("%w: config is for %q, not %q", ErrorOrgMismatch, conf.OrgName, info.Name)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package limacharlie
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncPushMaxDestructiveOps(t *testing.T) {
+	a := assert.New(t)
+	changes := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["output.list","output.set","output.del"]}`), nil
+		}
+		if !strings.HasPrefix(r.URL.Path, "/v1/outputs/") {
+			return jsonResponse(http.StatusOK, `{}`), nil
+		}
+		if r.Method == http.MethodGet {
+			return jsonResponse(http.StatusOK, `{"`+vcrTestOID+`":{
+				"old1":{"name":"old1","module":"syslog","for":"event"},
+				"old2":{"name":"old2","module":"syslog","for":"event"}
+			}}`), nil
+		}
+		changes = append(changes, r.Method)
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	conf := OrgConfig{Outputs: orgSyncOutputs{
+		"new": {Name: "new", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+	}}
+	maxOps := 1
+	options := SyncOptions{IsForce: true, SyncOutputs: true, MaxDestructiveOps: &maxOps}
+	ops, err := org.SyncPush(conf, options)
+	a.True(errors.Is(err, ErrorTooManyDestructiveOps))
+	a.Empty(changes)
+	a.Equal(2, NewSyncPlanResult(ops).Summary().Destructive)
+
+	plan, err := org.SyncPlan(conf, options)
+	a.True(errors.Is(err, ErrorTooManyDestructiveOps))
+	a.Equal(1, plan.Summary().Additive)
+
+	// Only forced syncs remove elements.
+	options.IsForce = false
+	_, err = org.SyncPush(conf, options)
+	a.NoError(err)
+	a.Equal([]string{http.MethodPost}, changes)
+
+	// A maximum of 0 allows no removal at all.
+	changes = []string{}
+	options.IsForce = true
+	maxOps = 0
+	_, err = org.SyncPush(conf, options)
+	a.True(errors.Is(err, ErrorTooManyDestructiveOps))
+	a.Empty(changes)
+
+	maxOps = 2
+	ops, err = org.SyncPush(conf, options)
+	a.NoError(err)
+	a.Equal([]string{http.MethodPost, http.MethodDelete, http.MethodDelete}, changes)
+	a.Equal(2, NewSyncPlanResult(ops).Summary().Destructive)
+}
+
+func TestSyncPushOrgMismatch(t *testing.T) {
+	a := assert.New(t)
+	requests := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		requests = append(requests, r.URL.Path)
+		if r.URL.Path == "/v1/orgs/"+vcrTestOID {
+			return jsonResponse(http.StatusOK, `{"oid":"`+vcrTestOID+`","name":"customer-a"}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	conf, err := LoadOrgConfig([]byte("version: 3\noid: 11111111-2222-3333-4444-555555555555\n"))
+	a.NoError(err)
+	a.Equal("11111111-2222-3333-4444-555555555555", conf.OID)
+	_, err = org.SyncPush(conf, SyncOptions{IsDryRun: true})
+	a.True(errors.Is(err, ErrorOrgMismatch))
+	a.Empty(requests)
+
+	conf, err = LoadOrgConfig([]byte("version: 3\noid: " + strings.ToUpper(vcrTestOID) + "\norg-name: customer-b\n"))
+	a.NoError(err)
+	_, err = org.SyncPush(conf, SyncOptions{})
+	a.True(errors.Is(err, ErrorOrgMismatch))
+	a.Contains(err.Error(), "customer-b")
+	a.Equal([]string{"/v1/orgs/" + vcrTestOID}, requests)
+
+	conf.OrgName = "customer-a"
+	_, err = org.SyncPush(conf, SyncOptions{})
+	a.NoError(err)
+}
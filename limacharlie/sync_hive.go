@@ -3,6 +3,7 @@ package limacharlie
 import (
 	"encoding/json"
 	"gopkg.in/yaml.v3"
+	"strings"
 	"sync"
 )
 
@@ -63,6 +64,11 @@ func (org Organization) syncHive(hiveConfigData orgSyncHives, opts SyncOptions)
 
 	var orgOps []OrgSyncOperation
 	for hiveName, newConfigData := range hiveConfigData {
+		// D&R rules in the hive of a protected namespace are left untouched.
+		isProtected := false
+		if ns, ok := drHiveNamespace(hiveName); ok {
+			isProtected = opts.isNamespaceProtected(ns)
+		}
 
 		// grab current config data as to determine if update or add needs to be processed
 		currentConfigData, err := org.fetchHiveConfigData(HiveArgs{
@@ -75,6 +81,14 @@ func (org Organization) syncHive(hiveConfigData orgSyncHives, opts SyncOptions)
 
 		// now check if we need to update or add new data for this particular hive
 		for hiveKey, ncd := range newConfigData {
+			if isProtected {
+				orgOps = opts.appendOp(orgOps, OrgSyncOperation{
+					ElementType: OrgSyncOperationElementType.Hives,
+					ElementName: hiveName + "/" + hiveKey,
+					IsSkipped:   true,
+				})
+				continue
+			}
 			// if key does not exist in current config data
 			// new data needs to be added
 			if _, ok := currentConfigData[hiveKey]; !ok {
@@ -85,7 +99,7 @@ func (org Organization) syncHive(hiveConfigData orgSyncHives, opts SyncOptions)
 					IsRemoved:   false,
 				}
 				if opts.IsDryRun {
-					orgOps = append(orgOps, op)
+					orgOps = opts.appendOp(orgOps, op)
 					continue
 				}
 				err = org.addHiveConfigData(HiveArgs{
@@ -94,9 +108,12 @@ func (org Organization) syncHive(hiveConfigData orgSyncHives, opts SyncOptions)
 					PartitionKey: orgInfo.OID,
 				}, newConfigData[hiveKey])
 				if err != nil {
-					return orgOps, err
+					if orgOps, err = opts.failOp(orgOps, op, err); err != nil {
+						return orgOps, err
+					}
+					continue
 				}
-				orgOps = append(orgOps, op)
+				orgOps = opts.appendOp(orgOps, op)
 			} else {
 				// if new config data exists in current config
 				// check to see if data is equal if not update
@@ -112,23 +129,37 @@ func (org Organization) syncHive(hiveConfigData orgSyncHives, opts SyncOptions)
 					IsRemoved:   false,
 				}
 				if equals {
-					orgOps = append(orgOps, op)
+					orgOps = opts.appendOp(orgOps, op)
 				} else { // not equal run hive update
+					op.IsUpdated = true
 					if opts.IsDryRun {
 						op.IsAdded = true
-						orgOps = append(orgOps, op)
+						orgOps = opts.appendOp(orgOps, op)
 						continue
 					}
-					err = org.updateHiveConfigData(HiveArgs{
-						Key:          hiveKey,
-						HiveName:     hiveName,
-						PartitionKey: orgInfo.OID},
-						ncd)
-					if err != nil {
-						return orgOps, err
+					// Large lookups are updated entry by entry
+					// rather than uploaded again.
+					delta, isDelta := LookupDelta{}, false
+					if hiveName == lookupHiveName {
+						delta, isDelta = lookupSyncDelta(ncd, curData)
+					}
+					if isDelta {
+						err = org.ApplyLookupDelta(hiveKey, delta)
+					} else {
+						err = org.updateHiveConfigData(HiveArgs{
+							Key:          hiveKey,
+							HiveName:     hiveName,
+							PartitionKey: orgInfo.OID},
+							ncd)
 					}
 					op.IsAdded = true
-					orgOps = append(orgOps, op)
+					if err != nil {
+						if orgOps, err = opts.failOp(orgOps, op, err); err != nil {
+							return orgOps, err
+						}
+						continue
+					}
+					orgOps = opts.appendOp(orgOps, op)
 				}
 			}
 		}
@@ -140,7 +171,11 @@ func (org Organization) syncHive(hiveConfigData orgSyncHives, opts SyncOptions)
 
 		// now that keys have been added or updated for this hive
 		// identify what keys should be removed
-		for k, _ := range currentConfigData {
+		for k, v := range currentConfigData {
+			// The lookups of threat feeds belong to the feeds section.
+			if hiveName == lookupHiveName && arrayExistsInString(threatFeedTag, v.UsrMtd.Tags) {
+				continue
+			}
 			if _, ok := newConfigData[k]; !ok {
 				op := OrgSyncOperation{
 					ElementType: OrgSyncOperationElementType.Hives,
@@ -148,22 +183,41 @@ func (org Organization) syncHive(hiveConfigData orgSyncHives, opts SyncOptions)
 					IsAdded:     false,
 					IsRemoved:   true,
 				}
+				if isProtected {
+					op.IsSkipped = true
+					orgOps = opts.appendOp(orgOps, op)
+					continue
+				}
 				if opts.IsDryRun {
-					orgOps = append(orgOps, op)
+					orgOps = opts.appendOp(orgOps, op)
 					continue
 				}
 
 				err := org.removeHiveConfigData(HiveArgs{Key: k, PartitionKey: orgInfo.OID, HiveName: hiveName})
 				if err != nil {
-					return orgOps, err
+					if orgOps, err = opts.failOp(orgOps, op, err); err != nil {
+						return orgOps, err
+					}
+					continue
 				}
-				orgOps = append(orgOps, op)
+				orgOps = opts.appendOp(orgOps, op)
 			}
 		}
 	}
 	return orgOps, nil
 }
 
+// drHiveNamespace returns the namespace of the D&R rules
+// stored in a hive, like "managed" for "dr-managed".
+func drHiveNamespace(hiveName HiveName) (string, bool) {
+	for _, h := range drRuleHives {
+		if h == hiveName {
+			return strings.TrimPrefix(hiveName, "dr-"), true
+		}
+	}
+	return "", false
+}
+
 func (org Organization) fetchHiveConfigData(args HiveArgs) (SyncHiveConfigData, error) {
 	hiveClient := NewHiveClient(&org)
 
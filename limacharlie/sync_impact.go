@@ -0,0 +1,149 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SyncPlanImpact estimates the sensors a collection rule
+// added or updated by a plan applies to.
+type SyncPlanImpact struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Sensors is the number of sensors of the Org
+	// currently matching the filters of the rule.
+	Sensors      int `json:"sensors"`
+	TotalSensors int `json:"total_sensors"`
+}
+
+// IsFleetWide returns true if the rule applies to all the sensors.
+func (i SyncPlanImpact) IsFleetWide() bool {
+	return i.TotalSensors != 0 && i.Sensors == i.TotalSensors
+}
+
+func (i SyncPlanImpact) String() string {
+	s := fmt.Sprintf("%s %s applies to %d of %d sensors", i.Type, i.Name, i.Sensors, i.TotalSensors)
+	if i.IsFleetWide() {
+		s += " (fleet-wide)"
+	}
+	return s
+}
+
+// collectionRuleFilters are the filters of a rule collecting data
+// from sensors, which applies to all sensors when empty.
+type collectionRuleFilters struct {
+	tags      []string
+	platforms []Platform
+}
+
+// planCollectionRuleFilters returns the filters of the collection
+// rules added or updated by the plan, keyed by type and name.
+func planCollectionRuleFilters(conf OrgConfig, plan SyncPlanResult) map[[2]string]collectionRuleFilters {
+	filters := map[[2]string]collectionRuleFilters{}
+	for _, op := range plan.Operations {
+		if op.IsSkipped || op.Error != nil || !op.IsAdded {
+			continue
+		}
+		key := [2]string{op.ElementType, op.ElementName}
+		switch op.ElementType {
+		case OrgSyncOperationElementType.ExfilEvent:
+			if conf.Exfil != nil {
+				if r, ok := conf.Exfil.Events[op.ElementName]; ok {
+					filters[key] = collectionRuleFilters{r.Filters.Tags, r.Filters.Platforms}
+				}
+			}
+		case OrgSyncOperationElementType.ExfilWatch:
+			if conf.Exfil != nil {
+				if r, ok := conf.Exfil.Watches[op.ElementName]; ok {
+					filters[key] = collectionRuleFilters{r.Filters.Tags, r.Filters.Platforms}
+				}
+			}
+		case OrgSyncOperationElementType.Artifact:
+			if r, ok := conf.Artifacts[op.ElementName]; ok {
+				filters[key] = collectionRuleFilters{r.Tags, r.Platforms}
+			}
+		case OrgSyncOperationElementType.Integrity:
+			if r, ok := conf.Integrity[op.ElementName]; ok {
+				filters[key] = collectionRuleFilters{r.Tags, r.Platforms}
+			}
+		}
+	}
+	return filters
+}
+
+// estimatePlanImpact counts the sensors matching the filters of the
+// collection rules added or updated by the plan. A rule applies to the
+// sensors with any of its tags and one of its platforms.
+func (org *Organization) estimatePlanImpact(conf OrgConfig, plan SyncPlanResult) ([]SyncPlanImpact, error) {
+	filters := planCollectionRuleFilters(conf, plan)
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	sensors, err := org.ListSensors()
+	if err != nil {
+		return nil, err
+	}
+
+	// Sensors by tag, for the tags used by the rules only.
+	tagged := map[string]map[string][]string{}
+	for _, f := range filters {
+		for _, tag := range f.tags {
+			if _, ok := tagged[tag]; ok {
+				continue
+			}
+			if tagged[tag], err = org.GetSensorsWithTag(tag); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	impacts := []SyncPlanImpact{}
+	for key, f := range filters {
+		impact := SyncPlanImpact{
+			Type:         key[0],
+			Name:         key[1],
+			TotalSensors: len(sensors),
+		}
+		for sid, s := range sensors {
+			if f.matches(sid, s, tagged) {
+				impact.Sensors++
+			}
+		}
+		impacts = append(impacts, impact)
+	}
+	sort.Slice(impacts, func(i, j int) bool {
+		if impacts[i].Type != impacts[j].Type {
+			return impacts[i].Type < impacts[j].Type
+		}
+		return impacts[i].Name < impacts[j].Name
+	})
+	return impacts, nil
+}
+
+func (f collectionRuleFilters) matches(sid string, s *Sensor, tagged map[string]map[string][]string) bool {
+	if len(f.platforms) != 0 {
+		p, err := PlatformFromID(s.Platform)
+		if err != nil {
+			return false
+		}
+		isMatch := false
+		for _, fp := range f.platforms {
+			if fp == p {
+				isMatch = true
+				break
+			}
+		}
+		if !isMatch {
+			return false
+		}
+	}
+	if len(f.tags) == 0 {
+		return true
+	}
+	for _, tag := range f.tags {
+		if _, ok := tagged[tag][sid]; ok {
+			return true
+		}
+	}
+	return false
+}
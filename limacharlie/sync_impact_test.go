@@ -0,0 +1,50 @@
+package limacharlie
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimatePlanImpact(t *testing.T) {
+	a := assert.New(t)
+
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if strings.HasPrefix(r.URL.Path, "/v1/tags/") {
+			return jsonResponse(http.StatusOK, `{"s2":["vip"],"s3":["vip"]}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{"sensors":[
+			{"sid":"s1","plat":268435456},
+			{"sid":"s2","plat":268435456},
+			{"sid":"s3","plat":536870912}
+		]}`), nil
+	}))
+
+	conf := OrgConfig{
+		Exfil: &orgSyncExfilRules{
+			Watches: map[ExfilRuleName]ExfilRuleWatch{
+				"all": {Event: "NEW_PROCESS"},
+			},
+		},
+		Artifacts: orgSyncArtifacts{
+			"vip-windows": {Tags: []string{"vip"}, Platforms: []Platform{"windows"}},
+			"unchanged":   {},
+		},
+	}
+	plan := NewSyncPlanResult([]OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.ExfilWatch, ElementName: "all", IsAdded: true},
+		{ElementType: OrgSyncOperationElementType.Artifact, ElementName: "vip-windows", IsAdded: true, IsUpdated: true},
+		{ElementType: OrgSyncOperationElementType.Artifact, ElementName: "unchanged"},
+	})
+
+	impacts, err := org.estimatePlanImpact(conf, plan)
+	a.NoError(err)
+	a.Equal([]SyncPlanImpact{
+		{Type: "artifact", Name: "vip-windows", Sensors: 1, TotalSensors: 3},
+		{Type: "exfil-watch", Name: "all", Sensors: 3, TotalSensors: 3},
+	}, impacts)
+	a.True(impacts[1].IsFleetWide())
+	a.Equal("exfil-watch all applies to 3 of 3 sensors (fleet-wide)", impacts[1].String())
+}
@@ -0,0 +1,25 @@
+package limacharlie
+
+import (
+	"time"
+)
+
+// SyncMetrics observes syncs, for scheduled sync jobs to report
+// their health. NewPrometheusSyncMetrics, built with the "prometheus"
+// build tag, exports them to a Prometheus registry.
+type SyncMetrics interface {
+	// ObserveOperation is called for every operation of a sync.
+	ObserveOperation(op OrgSyncOperation)
+	// ObserveAPIError is called for every failed attempt of an
+	// API request of a sync, isRetried if it is attempted again.
+	ObserveAPIError(statusCode int, isRetried bool)
+	// ObserveSync is called once a sync is done.
+	ObserveSync(duration time.Duration, err error)
+}
+
+func observeSyncMetrics(metrics SyncMetrics, ops []OrgSyncOperation, duration time.Duration, err error) {
+	for _, op := range ops {
+		metrics.ObserveOperation(op)
+	}
+	metrics.ObserveSync(duration, err)
+}
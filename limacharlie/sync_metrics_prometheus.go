@@ -0,0 +1,136 @@
+//go:build prometheus
+// +build prometheus
+
+package limacharlie
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const prometheusSyncNamespace = "limacharlie_sync"
+
+type prometheusSyncMetrics struct {
+	operations  *prometheus.CounterVec
+	runs        *prometheus.CounterVec
+	duration    prometheus.Gauge
+	lastSuccess prometheus.Gauge
+	apiErrors   *prometheus.CounterVec
+	apiRetries  prometheus.Counter
+}
+
+// NewPrometheusSyncMetrics registers the metrics of syncs to reg,
+// reusing the ones already registered so that every sync of a
+// process can share them:
+//
+//	limacharlie_sync_operations_total{type,action}
+//	limacharlie_sync_runs_total{result}
+//	limacharlie_sync_duration_seconds
+//	limacharlie_sync_last_success_timestamp_seconds
+//	limacharlie_sync_api_errors_total{status}
+//	limacharlie_sync_api_retries_total
+func NewPrometheusSyncMetrics(reg prometheus.Registerer) (SyncMetrics, error) {
+	m := &prometheusSyncMetrics{}
+	var err error
+	if m.operations, err = registerCounterVec(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusSyncNamespace,
+		Name:      "operations_total",
+		Help:      "Operations of syncs by element type and action: add, update, remove, skip, none or error.",
+	}, []string{"type", "action"})); err != nil {
+		return nil, err
+	}
+	if m.runs, err = registerCounterVec(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusSyncNamespace,
+		Name:      "runs_total",
+		Help:      "Syncs by result: success or error.",
+	}, []string{"result"})); err != nil {
+		return nil, err
+	}
+	if m.duration, err = registerGauge(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: prometheusSyncNamespace,
+		Name:      "duration_seconds",
+		Help:      "Duration of the last sync.",
+	})); err != nil {
+		return nil, err
+	}
+	if m.lastSuccess, err = registerGauge(reg, prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: prometheusSyncNamespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Time of the last successful sync.",
+	})); err != nil {
+		return nil, err
+	}
+	if m.apiErrors, err = registerCounterVec(reg, prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: prometheusSyncNamespace,
+		Name:      "api_errors_total",
+		Help:      "Failed API requests of syncs by HTTP status, 0 without a response.",
+	}, []string{"status"})); err != nil {
+		return nil, err
+	}
+	retries := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusSyncNamespace,
+		Name:      "api_retries_total",
+		Help:      "API requests of syncs attempted again.",
+	})
+	c, err := registerCollector(reg, retries)
+	if err != nil {
+		return nil, err
+	}
+	m.apiRetries = c.(prometheus.Counter)
+	return m, nil
+}
+
+// registerCollector registers c, returning the
+// collector already registered if there is one.
+func registerCollector(reg prometheus.Registerer, c prometheus.Collector) (prometheus.Collector, error) {
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			return are.ExistingCollector, nil
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+func registerCounterVec(reg prometheus.Registerer, c *prometheus.CounterVec) (*prometheus.CounterVec, error) {
+	registered, err := registerCollector(reg, c)
+	if err != nil {
+		return nil, err
+	}
+	return registered.(*prometheus.CounterVec), nil
+}
+
+func registerGauge(reg prometheus.Registerer, g prometheus.Gauge) (prometheus.Gauge, error) {
+	registered, err := registerCollector(reg, g)
+	if err != nil {
+		return nil, err
+	}
+	return registered.(prometheus.Gauge), nil
+}
+
+func (m *prometheusSyncMetrics) ObserveOperation(op OrgSyncOperation) {
+	action := syncPlanAction(op)
+	if op.Error != nil {
+		action = "error"
+	}
+	m.operations.WithLabelValues(op.ElementType, action).Inc()
+}
+
+func (m *prometheusSyncMetrics) ObserveAPIError(statusCode int, isRetried bool) {
+	m.apiErrors.WithLabelValues(strconv.Itoa(statusCode)).Inc()
+	if isRetried {
+		m.apiRetries.Inc()
+	}
+}
+
+func (m *prometheusSyncMetrics) ObserveSync(duration time.Duration, err error) {
+	m.duration.Set(duration.Seconds())
+	if err != nil {
+		m.runs.WithLabelValues("error").Inc()
+		return
+	}
+	m.runs.WithLabelValues("success").Inc()
+	m.lastSuccess.SetToCurrentTime()
+}
@@ -0,0 +1,58 @@
+//go:build prometheus
+// +build prometheus
+
+package limacharlie
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusSyncMetrics(t *testing.T) {
+	a := assert.New(t)
+	reg := prometheus.NewRegistry()
+	metrics, err := NewPrometheusSyncMetrics(reg)
+	a.NoError(err)
+
+	// Registering again shares the same metrics.
+	again, err := NewPrometheusSyncMetrics(reg)
+	a.NoError(err)
+
+	observeSyncMetrics(metrics, []OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "o1", IsAdded: true},
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "o2", IsRemoved: true, Error: errors.New("failed")},
+	}, 2*time.Second, nil)
+	again.ObserveOperation(OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "o3", IsAdded: true})
+	again.ObserveAPIError(503, true)
+	again.ObserveSync(time.Second, errors.New("failed"))
+
+	m := metrics.(*prometheusSyncMetrics)
+	a.Equal(float64(2), testutil.ToFloat64(m.operations.WithLabelValues(OrgSyncOperationElementType.Output, "add")))
+	a.Equal(float64(1), testutil.ToFloat64(m.operations.WithLabelValues(OrgSyncOperationElementType.Output, "error")))
+	a.Equal(float64(1), testutil.ToFloat64(m.runs.WithLabelValues("success")))
+	a.Equal(float64(1), testutil.ToFloat64(m.runs.WithLabelValues("error")))
+	a.Equal(float64(1), testutil.ToFloat64(m.duration))
+	a.NotZero(testutil.ToFloat64(m.lastSuccess))
+	a.Equal(float64(1), testutil.ToFloat64(m.apiErrors.WithLabelValues("503")))
+	a.Equal(float64(1), testutil.ToFloat64(m.apiRetries))
+
+	families, err := reg.Gather()
+	a.NoError(err)
+	names := []string{}
+	for _, f := range families {
+		names = append(names, f.GetName())
+	}
+	a.Equal([]string{
+		"limacharlie_sync_api_errors_total",
+		"limacharlie_sync_api_retries_total",
+		"limacharlie_sync_duration_seconds",
+		"limacharlie_sync_last_success_timestamp_seconds",
+		"limacharlie_sync_operations_total",
+		"limacharlie_sync_runs_total",
+	}, names)
+}
@@ -0,0 +1,76 @@
+package limacharlie
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingSyncMetrics struct {
+	ops       []string
+	apiErrors []int
+	retries   int
+	syncs     int
+	err       error
+}
+
+func (m *recordingSyncMetrics) ObserveOperation(op OrgSyncOperation) {
+	m.ops = append(m.ops, op.ElementType+" "+op.ElementName+" "+syncPlanAction(op))
+}
+
+func (m *recordingSyncMetrics) ObserveAPIError(statusCode int, isRetried bool) {
+	m.apiErrors = append(m.apiErrors, statusCode)
+	if isRetried {
+		m.retries++
+	}
+}
+
+func (m *recordingSyncMetrics) ObserveSync(duration time.Duration, err error) {
+	m.syncs++
+	m.err = err
+}
+
+func TestSyncPushMetrics(t *testing.T) {
+	a := assert.New(t)
+	isFailing := true
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["output.list","output.set","output.del"]}`), nil
+		}
+		if strings.HasPrefix(r.URL.Path, "/v1/outputs/") && r.Method == http.MethodGet {
+			if isFailing {
+				isFailing = false
+				return jsonResponse(http.StatusInternalServerError, `{}`), nil
+			}
+			return jsonResponse(http.StatusOK, `{"`+vcrTestOID+`":{"old":{"name":"old","module":"syslog","for":"event"}}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	metrics := &recordingSyncMetrics{}
+	conf := OrgConfig{Outputs: orgSyncOutputs{
+		"new": {Name: "new", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+	}}
+	_, err := org.SyncPush(conf, SyncOptions{
+		IsForce:     true,
+		SyncOutputs: true,
+		Schedule:    &SyncSchedule{DeferRemovals: true},
+		Metrics:     metrics,
+	})
+	a.NoError(err)
+	a.Equal([]string{"output new add", "output old skip"}, metrics.ops)
+	a.Equal([]int{http.StatusInternalServerError}, metrics.apiErrors)
+	a.Equal(1, metrics.retries)
+	// Observed once despite the nested pushes.
+	a.Equal(1, metrics.syncs)
+	a.NoError(metrics.err)
+
+	// Other requests of the Org are not observed.
+	isFailing = true
+	_, err = org.Outputs()
+	a.NoError(err)
+	a.Len(metrics.apiErrors, 1)
+}
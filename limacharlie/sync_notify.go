@@ -0,0 +1,183 @@
+package limacharlie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type SyncNotificationFormat = string
+
+var SyncNotificationFormats = struct {
+	Slack SyncNotificationFormat
+	Teams SyncNotificationFormat
+}{
+	Slack: "slack",
+	Teams: "teams",
+}
+
+const (
+	defaultSyncNotificationMaxOperations = 20
+	syncNotificationTimeout              = 30 * time.Second
+)
+
+// SyncNotification describes the notification of a sync plan or result
+// posted to the incoming webhook of a Slack or Microsoft Teams channel.
+type SyncNotification struct {
+	WebhookURL string
+	// Format of the payload, inferred from the host
+	// of the WebhookURL if empty, Slack by default.
+	Format SyncNotificationFormat
+	// Title of the message, like "drift of prod" or "pushed prod".
+	Title string
+	// MaxOperations listed, the others are only counted, 20 by default.
+	MaxOperations int
+	// IsOnlyChanges skips the notification of plans without changes
+	// or errors, like when checking for drift on a schedule.
+	IsOnlyChanges bool
+}
+
+func (n SyncNotification) format() SyncNotificationFormat {
+	if n.Format != "" {
+		return n.Format
+	}
+	u, err := url.Parse(n.WebhookURL)
+	if err == nil && (strings.HasSuffix(u.Host, ".office.com") || strings.HasSuffix(u.Host, ".logic.azure.com") || strings.HasSuffix(u.Host, ".powerplatform.com")) {
+		return SyncNotificationFormats.Teams
+	}
+	return SyncNotificationFormats.Slack
+}
+
+// lines renders the operations changing the Org or failing, "+"
+// for additions, "~" updates, "-" removals and "!" errors.
+func (n SyncNotification) lines(plan SyncPlanResult) []string {
+	max := n.MaxOperations
+	if max <= 0 {
+		max = defaultSyncNotificationMaxOperations
+	}
+	lines := []string{}
+	omitted := 0
+	for _, op := range plan.Operations {
+		prefix := ""
+		switch {
+		case op.Error != nil:
+			prefix = "!"
+		case op.IsSkipped || op.IsUnchanged():
+			continue
+		case op.IsUpdated:
+			prefix = "~"
+		case op.IsAdded:
+			prefix = "+"
+		case op.IsRemoved:
+			prefix = "-"
+		default:
+			continue
+		}
+		if len(lines) == max {
+			omitted++
+			continue
+		}
+		line := fmt.Sprintf("%s %s/%s", prefix, op.ElementType, op.ElementName)
+		if op.Error != nil {
+			line = fmt.Sprintf("%s: %v", line, op.Error)
+		}
+		lines = append(lines, line)
+	}
+	if omitted != 0 {
+		lines = append(lines, fmt.Sprintf("... and %d more", omitted))
+	}
+	return lines
+}
+
+func syncNotificationSummary(plan SyncPlanResult, err error) string {
+	s := plan.Summary()
+	summary := fmt.Sprintf("%d added, %d updated, %d removed, %d skipped", s.Added, s.Updated, s.Removed, s.Skipped)
+	if err != nil {
+		summary = fmt.Sprintf("%s\nfailed: %v", summary, err)
+	}
+	return summary
+}
+
+// Payload returns the JSON payload of the notification of the
+// plan, or of the result of a push along with its error.
+func (n SyncNotification) Payload(plan SyncPlanResult, err error) ([]byte, error) {
+	title := n.Title
+	if title == "" {
+		title = "LimaCharlie sync"
+	}
+	summary := syncNotificationSummary(plan, err)
+	lines := n.lines(plan)
+
+	switch n.format() {
+	case SyncNotificationFormats.Slack:
+		blocks := []Dict{
+			{"type": "header", "text": Dict{"type": "plain_text", "text": title}},
+			{"type": "section", "text": Dict{"type": "mrkdwn", "text": summary}},
+		}
+		if len(lines) != 0 {
+			blocks = append(blocks, Dict{"type": "section", "text": Dict{
+				"type": "mrkdwn",
+				"text": "```" + strings.Join(lines, "\n") + "```",
+			}})
+		}
+		return json.Marshal(Dict{
+			"text":   fmt.Sprintf("%s: %s", title, summary),
+			"blocks": blocks,
+		})
+	case SyncNotificationFormats.Teams:
+		body := []Dict{
+			{"type": "TextBlock", "text": title, "weight": "bolder", "size": "medium", "wrap": true},
+			{"type": "TextBlock", "text": summary, "wrap": true},
+		}
+		if len(lines) != 0 {
+			body = append(body, Dict{
+				"type":     "TextBlock",
+				"text":     strings.Join(lines, "\n\n"),
+				"fontType": "monospace",
+				"wrap":     true,
+			})
+		}
+		return json.Marshal(Dict{
+			"type": "message",
+			"attachments": []Dict{{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": Dict{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body":    body,
+				},
+			}},
+		})
+	}
+	return nil, fmt.Errorf("unknown notification format %q", n.Format)
+}
+
+// NotifySync posts the notification of the plan, or of the result
+// of a push along with its error, like:
+//
+//	ops, err := org.SyncPush(conf, options)
+//	org.NotifySync(n, NewSyncPlanResult(ops), err)
+func (org Organization) NotifySync(n SyncNotification, plan SyncPlanResult, syncErr error) error {
+	if n.IsOnlyChanges && syncErr == nil && !plan.HasChanges() && syncOpsError(plan.Operations) == nil {
+		return nil
+	}
+	payload, err := n.Payload(plan, syncErr)
+	if err != nil {
+		return err
+	}
+	resp, err := org.client.httpClient(syncNotificationTimeout).Post(n.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("notifying sync: %s: %s", resp.Status, string(body))
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSyncNotificationPlan() SyncPlanResult {
+	return NewSyncPlanResult([]OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "new", IsAdded: true},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "changed", IsAdded: true, IsUpdated: true},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "same"},
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "old", IsRemoved: true},
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "broken", IsAdded: true, Error: errors.New("denied")},
+	})
+}
+
+func TestSyncNotificationSlack(t *testing.T) {
+	a := assert.New(t)
+	n := SyncNotification{WebhookURL: "https://hooks.slack.com/services/x", Title: "drift of prod"}
+	data, err := n.Payload(testSyncNotificationPlan(), nil)
+	a.NoError(err)
+	payload := map[string]interface{}{}
+	a.NoError(json.Unmarshal(data, &payload))
+	a.Equal("drift of prod: 2 added, 1 updated, 1 removed, 0 skipped", payload["text"])
+	blocks := payload["blocks"].([]interface{})
+	a.Len(blocks, 3)
+	a.Equal("```~ dr-rule/changed\n+ dr-rule/new\n! output/broken: denied\n- output/old```",
+		blocks[2].(map[string]interface{})["text"].(map[string]interface{})["text"])
+
+	n.MaxOperations = 1
+	data, err = n.Payload(testSyncNotificationPlan(), errors.New("1 operations failed"))
+	a.NoError(err)
+	a.Contains(string(data), "... and 3 more")
+	a.Contains(string(data), "failed: 1 operations failed")
+}
+
+func TestSyncNotificationTeams(t *testing.T) {
+	a := assert.New(t)
+	n := SyncNotification{WebhookURL: "https://contoso.webhook.office.com/webhookb2/x"}
+	data, err := n.Payload(testSyncNotificationPlan(), nil)
+	a.NoError(err)
+	payload := Dict{}
+	a.NoError(json.Unmarshal(data, &payload))
+	a.Equal("message", payload["type"])
+	card, ok := payload.GetDict("attachments/0/content")
+	a.True(ok)
+	a.Equal("AdaptiveCard", card["type"])
+	title, _ := card.GetString("body/0/text")
+	a.Equal("LimaCharlie sync", title)
+
+	_, err = SyncNotification{Format: "irc"}.Payload(SyncPlanResult{}, nil)
+	a.Error(err)
+}
+
+func TestNotifySync(t *testing.T) {
+	a := assert.New(t)
+	posted := 0
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		posted++
+		a.Equal("https://hooks.slack.com/services/x", r.URL.String())
+		a.Equal("application/json", r.Header.Get("Content-Type"))
+		body, err := ioutil.ReadAll(r.Body)
+		a.NoError(err)
+		a.Contains(string(body), "dr-rule/new")
+		return jsonResponse(http.StatusOK, "ok"), nil
+	}))
+	n := SyncNotification{WebhookURL: "https://hooks.slack.com/services/x", IsOnlyChanges: true}
+	a.NoError(org.NotifySync(n, testSyncNotificationPlan(), nil))
+	a.NoError(org.NotifySync(n, NewSyncPlanResult([]OrgSyncOperation{{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "same"}}), nil))
+	a.Equal(1, posted)
+}
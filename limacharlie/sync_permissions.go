@@ -0,0 +1,149 @@
+package limacharlie
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type syncPermissions struct {
+	read  []string
+	write []string
+	// remove is only needed when the sync is forced.
+	remove []string
+}
+
+// syncCategoryPermissions are the permissions needed to sync
+// each category, named like the permissions of the API keys in
+// the web app. D&R rules depend on their namespaces and are
+// handled separately while categories absent are not checked.
+var syncCategoryPermissions = map[string]syncPermissions{
+	OrgSyncOperationElementType.FPRule: {
+		read:  []string{"fp.ctrl"},
+		write: []string{"fp.ctrl"},
+	},
+	OrgSyncOperationElementType.Output: {
+		read:   []string{"output.list"},
+		write:  []string{"output.set"},
+		remove: []string{"output.del"},
+	},
+	OrgSyncOperationElementType.Integrity: {
+		read:   []string{"fim.get"},
+		write:  []string{"fim.set"},
+		remove: []string{"fim.del"},
+	},
+	OrgSyncOperationElementType.Artifact: {
+		read:   []string{"logging.get"},
+		write:  []string{"logging.set"},
+		remove: []string{"logging.del"},
+	},
+	OrgSyncOperationElementType.ExfilEvent: {
+		read:   []string{"exfil.get"},
+		write:  []string{"exfil.set"},
+		remove: []string{"exfil.del"},
+	},
+	OrgSyncOperationElementType.OrgValue: {
+		read:  []string{"org.conf.get"},
+		write: []string{"org.conf.set"},
+	},
+	OrgSyncOperationElementType.InstallationKey: {
+		read:   []string{"ikey.list"},
+		write:  []string{"ikey.set"},
+		remove: []string{"ikey.del"},
+	},
+	OrgSyncOperationElementType.YaraRule: {
+		read:   []string{"yara.get"},
+		write:  []string{"yara.set"},
+		remove: []string{"yara.del"},
+	},
+	OrgSyncOperationElementType.NetPolicy: {
+		read:   []string{"net.policy.get"},
+		write:  []string{"net.policy.set"},
+		remove: []string{"net.policy.del"},
+	},
+}
+
+// SyncPermissionError is returned by SyncPush when the credentials
+// lack permissions needed by the categories being synced.
+type SyncPermissionError struct {
+	Missing []string
+}
+
+func (e *SyncPermissionError) Error() string {
+	return fmt.Sprintf("missing permissions: %s", strings.Join(e.Missing, ", "))
+}
+
+func (p syncPermissions) needed(options SyncOptions) []string {
+	perms := append([]string{}, p.read...)
+	if options.IsDryRun {
+		return perms
+	}
+	perms = append(perms, p.write...)
+	if options.IsForce {
+		perms = append(perms, p.remove...)
+	}
+	return perms
+}
+
+// drRuleNamespacePermission returns the name of a D&R rule
+// permission for a namespace, like "dr.set.managed".
+func drRuleNamespacePermission(action string, namespace string) string {
+	if namespace == "" || namespace == "general" {
+		return fmt.Sprintf("dr.%s", action)
+	}
+	return fmt.Sprintf("dr.%s.%s", action, namespace)
+}
+
+// syncRequiredPermissions returns the permissions needed
+// to push the configuration with the options.
+func syncRequiredPermissions(conf OrgConfig, options SyncOptions) []string {
+	perms := map[string]struct{}{}
+	add := func(names []string) {
+		for _, n := range names {
+			perms[n] = struct{}{}
+		}
+	}
+	categories := map[string]bool{
+		OrgSyncOperationElementType.FPRule:          options.SyncFPRules || options.SyncDetectionRouting,
+		OrgSyncOperationElementType.Output:          options.SyncOutputs,
+		OrgSyncOperationElementType.Integrity:       options.SyncIntegrity,
+		OrgSyncOperationElementType.Artifact:        options.SyncArtifacts,
+		OrgSyncOperationElementType.ExfilEvent:      options.SyncExfil,
+		OrgSyncOperationElementType.OrgValue:        options.SyncOrgValues,
+		OrgSyncOperationElementType.InstallationKey: options.SyncInstallationKeys,
+		OrgSyncOperationElementType.YaraRule:        options.SyncYara,
+		OrgSyncOperationElementType.NetPolicy:       options.SyncNetPolicies,
+	}
+	for category, isEnabled := range categories {
+		if isEnabled {
+			add(syncCategoryPermissions[category].needed(options))
+		}
+	}
+	if options.SyncDRRules && !options.IsDryRun {
+		// Listing is checked per namespace during the sync
+		// since only the namespaces accessible are synced.
+		for _, rule := range conf.DRRules {
+			if rule.ReadOnly {
+				continue
+			}
+			add([]string{drRuleNamespacePermission("set", rule.Namespace)})
+		}
+	}
+
+	names := []string{}
+	for n := range perms {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkSyncPermissions fails if any permission needed
+// to push the configuration is not granted.
+func (org Organization) checkSyncPermissions(who whoAmIJsonResponse, conf OrgConfig, options SyncOptions) error {
+	missing := who.toWhoAmI().MissingPermissions(org.client.options.OID, syncRequiredPermissions(conf, options))
+	if len(missing) != 0 {
+		return &SyncPermissionError{Missing: missing}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncRequiredPermissions(t *testing.T) {
+	a := assert.New(t)
+	conf := OrgConfig{
+		DRRules: orgSyncDRRules{
+			"r1": {},
+			"r2": {Namespace: "managed"},
+		},
+	}
+
+	a.Equal([]string{"dr.set", "dr.set.managed", "output.list", "output.set"}, syncRequiredPermissions(conf, SyncOptions{
+		SyncDRRules: true,
+		SyncOutputs: true,
+	}))
+	a.Equal([]string{"output.del", "output.list", "output.set"}, syncRequiredPermissions(conf, SyncOptions{
+		SyncOutputs: true,
+		IsForce:     true,
+	}))
+	a.Equal([]string{"output.list"}, syncRequiredPermissions(conf, SyncOptions{
+		SyncDRRules: true,
+		SyncOutputs: true,
+		IsDryRun:    true,
+	}))
+}
+
+func TestWhoAmIPermissions(t *testing.T) {
+	a := assert.New(t)
+	raw := whoAmIJsonResponse{}
+	a.NoError(json.Unmarshal([]byte(`{"ident":"me","user_perms":{"oid1":["dr.list","output.list"]}}`), &raw))
+	who := raw.toWhoAmI()
+	a.Equal("me", who.Identity)
+	a.True(who.HasAccessToOrg("oid1"))
+	a.False(who.HasAccessToOrg("oid2"))
+	a.True(who.HasPermission("oid1", "dr.list"))
+	a.Equal([]string{"output.set"}, who.MissingPermissions("oid1", []string{"output.list", "output.set"}))
+
+	raw = whoAmIJsonResponse{}
+	a.NoError(json.Unmarshal([]byte(`{"ident":"key","orgs":["oid1"],"perms":["fp.ctrl"]}`), &raw))
+	who = raw.toWhoAmI()
+	a.True(who.HasPermission("oid1", "fp.ctrl"))
+	a.False(who.HasPermission("oid2", "fp.ctrl"))
+	a.Equal([]string{}, who.EffectivePermissions("oid2"))
+}
+
+func TestSyncPushCheckPermissions(t *testing.T) {
+	a := assert.New(t)
+	added := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["output.list"]}`), nil
+		}
+		if r.Method == http.MethodPost {
+			added = append(added, r.URL.Path)
+		}
+		return jsonResponse(http.StatusOK, `{"`+vcrTestOID+`":{}}`), nil
+	}))
+	conf := OrgConfig{Outputs: orgSyncOutputs{"o1": {Module: OutputTypes.Syslog, Type: OutputType.Detect}}}
+
+	_, err := org.SyncPush(conf, SyncOptions{SyncOutputs: true, CheckPermissions: true})
+	permErr := &SyncPermissionError{}
+	a.True(errors.As(err, &permErr))
+	a.Equal([]string{"output.set"}, permErr.Missing)
+	a.Empty(added)
+
+	// The permissions are only checked when asked to.
+	_, err = org.SyncPush(conf, SyncOptions{SyncOutputs: true})
+	a.NoError(err)
+	a.Equal([]string{"/v1/outputs/" + vcrTestOID}, added)
+}
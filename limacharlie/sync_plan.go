@@ -0,0 +1,260 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SyncPlanResult is the set of operations a sync would perform.
+type SyncPlanResult struct {
+	// Operations sorted by element type and name.
+	Operations []OrgSyncOperation
+	// Impacts of the collection rules added or updated,
+	// only estimated with SyncOptions.EstimateImpact.
+	Impacts []SyncPlanImpact
+	// Stale D&R rules, only reported with SyncOptions.StaleAfter.
+	Stale []SyncPlanStaleRule
+}
+
+// SyncPlanSummary counts the operations of a plan by kind.
+type SyncPlanSummary struct {
+	Added     int `json:"added"`
+	Updated   int `json:"updated"`
+	Removed   int `json:"removed"`
+	Skipped   int `json:"skipped"`
+	Unchanged int `json:"unchanged"`
+
+	// Counts of the operations by impact, see SyncOperationImpacts.
+	Additive    int `json:"additive"`
+	Modifying   int `json:"modifying"`
+	Destructive int `json:"destructive"`
+}
+
+type SyncOperationImpact = string
+
+// SyncOperationImpacts classify the operations of a plan by how
+// much they can disrupt the Org, from none to destructive.
+var SyncOperationImpacts = struct {
+	// None for unchanged or skipped elements.
+	None SyncOperationImpact
+	// Additive operations add new elements.
+	Additive SyncOperationImpact
+	// Modifying operations replace existing elements.
+	Modifying SyncOperationImpact
+	// Destructive operations remove elements.
+	Destructive SyncOperationImpact
+}{
+	None:        "none",
+	Additive:    "additive",
+	Modifying:   "modifying",
+	Destructive: "destructive",
+}
+
+// Impact classifies the operation, see SyncOperationImpacts.
+func (o OrgSyncOperation) Impact() SyncOperationImpact {
+	switch syncPlanAction(o) {
+	case "add":
+		return SyncOperationImpacts.Additive
+	case "update":
+		return SyncOperationImpacts.Modifying
+	case "remove":
+		return SyncOperationImpacts.Destructive
+	}
+	return SyncOperationImpacts.None
+}
+
+// Exit codes of a plan, mirroring "terraform plan -detailed-exitcode".
+const (
+	SyncPlanExitNoChanges = 0
+	SyncPlanExitError     = 1
+	SyncPlanExitChanges   = 2
+)
+
+// SyncPlan computes the operations SyncPush would perform
+// without modifying the Org.
+func (org Organization) SyncPlan(conf OrgConfig, options SyncOptions) (SyncPlanResult, error) {
+	options.IsDryRun = true
+	ops, err := org.SyncPush(conf, options)
+	plan := NewSyncPlanResult(ops)
+	if err != nil {
+		return plan, err
+	}
+	if options.EstimateImpact {
+		if plan.Impacts, err = org.estimatePlanImpact(conf, plan); err != nil {
+			return plan, err
+		}
+	}
+	if options.StaleAfter > 0 {
+		if plan.Stale, err = org.findStaleRules(conf, plan, options.StaleAfter); err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+// SyncPlanFromFiles is SyncPlan for a config loaded like SyncPushFromFiles.
+func (org Organization) SyncPlanFromFiles(rootConfigFile string, options SyncOptions) (SyncPlanResult, error) {
+	if options.IncludeLoader == nil {
+		options.IncludeLoader = localFileIncludeLoader
+	}
+	conf, err := loadEffectiveConfig("", rootConfigFile, options)
+	if err != nil {
+		return NewSyncPlanResult(nil), err
+	}
+	return org.SyncPlan(conf, options)
+}
+
+// NewSyncPlanResult creates a plan from operations, sorting
+// them so plans of the same changes are identical.
+func NewSyncPlanResult(ops []OrgSyncOperation) SyncPlanResult {
+	sorted := make([]OrgSyncOperation, len(ops))
+	copy(sorted, ops)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ElementType != sorted[j].ElementType {
+			return sorted[i].ElementType < sorted[j].ElementType
+		}
+		if sorted[i].ElementName != sorted[j].ElementName {
+			return sorted[i].ElementName < sorted[j].ElementName
+		}
+		return sorted[i].String() < sorted[j].String()
+	})
+	return SyncPlanResult{Operations: sorted}
+}
+
+// HasChanges returns true if applying the plan would modify the Org.
+func (p SyncPlanResult) HasChanges() bool {
+	for _, op := range p.Operations {
+		if !op.IsSkipped && (op.IsAdded || op.IsRemoved) {
+			return true
+		}
+	}
+	return false
+}
+
+// ExitCode returns SyncPlanExitChanges if the plan has changes.
+func (p SyncPlanResult) ExitCode() int {
+	if p.HasChanges() {
+		return SyncPlanExitChanges
+	}
+	return SyncPlanExitNoChanges
+}
+
+func (p SyncPlanResult) Summary() SyncPlanSummary {
+	s := SyncPlanSummary{}
+	for _, op := range p.Operations {
+		switch op.Impact() {
+		case SyncOperationImpacts.Additive:
+			s.Additive++
+		case SyncOperationImpacts.Modifying:
+			s.Modifying++
+		case SyncOperationImpacts.Destructive:
+			s.Destructive++
+		}
+		switch syncPlanAction(op) {
+		case "add":
+			s.Added++
+		case "update":
+			s.Updated++
+		case "remove":
+			s.Removed++
+		case "skip":
+			s.Skipped++
+		default:
+			s.Unchanged++
+		}
+	}
+	return s
+}
+
+func syncPlanAction(op OrgSyncOperation) string {
+	switch {
+	case op.IsSkipped:
+		return "skip"
+	case op.IsUpdated:
+		return "update"
+	case op.IsAdded:
+		return "add"
+	case op.IsRemoved:
+		return "remove"
+	}
+	return "none"
+}
+
+// DestructiveOperations returns the operations removing elements.
+func (p SyncPlanResult) DestructiveOperations() []OrgSyncOperation {
+	ops := []OrgSyncOperation{}
+	for _, op := range p.Operations {
+		if op.Impact() == SyncOperationImpacts.Destructive {
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// SyncOperationRecord is the serializable form of an OrgSyncOperation.
+type SyncOperationRecord struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+	// Action is one of "add", "update", "remove", "skip" or "none".
+	Action string              `json:"action"`
+	Impact SyncOperationImpact `json:"impact"`
+	Error  string              `json:"error,omitempty"`
+}
+
+func newSyncOperationRecord(op OrgSyncOperation) SyncOperationRecord {
+	r := SyncOperationRecord{
+		Type:   op.ElementType,
+		Name:   op.ElementName,
+		Action: syncPlanAction(op),
+		Impact: op.Impact(),
+	}
+	if op.Error != nil {
+		r.Error = op.Error.Error()
+	}
+	return r
+}
+
+func (p SyncPlanResult) MarshalJSON() ([]byte, error) {
+	ops := []SyncOperationRecord{}
+	for _, op := range p.Operations {
+		ops = append(ops, newSyncOperationRecord(op))
+	}
+	return json.Marshal(struct {
+		HasChanges bool                  `json:"has_changes"`
+		Summary    SyncPlanSummary       `json:"summary"`
+		Operations []SyncOperationRecord `json:"operations"`
+		Impacts    []SyncPlanImpact      `json:"impacts,omitempty"`
+		Stale      []SyncPlanStaleRule   `json:"stale,omitempty"`
+	}{
+		HasChanges: p.HasChanges(),
+		Summary:    p.Summary(),
+		Operations: ops,
+		Impacts:    p.Impacts,
+		Stale:      p.Stale,
+	})
+}
+
+// String lists the operations changing the Org followed by a summary.
+func (p SyncPlanResult) String() string {
+	lines := []string{}
+	for _, op := range p.Operations {
+		if op.IsUnchanged() {
+			continue
+		}
+		lines = append(lines, op.String())
+	}
+	for _, i := range p.Impacts {
+		lines = append(lines, i.String())
+	}
+	for _, r := range p.Stale {
+		lines = append(lines, r.String())
+	}
+	s := p.Summary()
+	lines = append(lines, fmt.Sprintf("plan: %d to add, %d to update, %d to remove, %d skipped", s.Added, s.Updated, s.Removed, s.Skipped))
+	if s.Destructive != 0 {
+		lines = append(lines, fmt.Sprintf("warning: %d destructive operations", s.Destructive))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,51 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncPlanResult(t *testing.T) {
+	a := assert.New(t)
+
+	plan := NewSyncPlanResult([]OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "o1", IsRemoved: true},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "r2"},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "r1", IsAdded: true, IsUpdated: true},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "managed-r3", IsAdded: true, IsSkipped: true},
+	})
+	a.True(plan.HasChanges())
+	a.Equal(SyncPlanExitChanges, plan.ExitCode())
+	a.Equal([]string{"managed-r3", "r1", "r2", "o1"}, []string{
+		plan.Operations[0].ElementName,
+		plan.Operations[1].ElementName,
+		plan.Operations[2].ElementName,
+		plan.Operations[3].ElementName,
+	})
+	a.Equal(SyncPlanSummary{Updated: 1, Removed: 1, Skipped: 1, Unchanged: 1, Modifying: 1, Destructive: 1}, plan.Summary())
+	a.Equal(SyncOperationImpacts.None, plan.Operations[0].Impact())
+	a.Equal(SyncOperationImpacts.Modifying, plan.Operations[1].Impact())
+	a.Equal(SyncOperationImpacts.Destructive, plan.Operations[3].Impact())
+	a.Equal([]OrgSyncOperation{plan.Operations[3]}, plan.DestructiveOperations())
+
+	serialized, err := json.Marshal(plan)
+	a.NoError(err)
+	a.JSONEq(`{
+  "has_changes": true,
+  "summary": {"added": 0, "updated": 1, "removed": 1, "skipped": 1, "unchanged": 1, "additive": 0, "modifying": 1, "destructive": 1},
+  "operations": [
+    {"type": "dr-rule", "name": "managed-r3", "action": "skip", "impact": "none"},
+    {"type": "dr-rule", "name": "r1", "action": "update", "impact": "modifying"},
+    {"type": "dr-rule", "name": "r2", "action": "none", "impact": "none"},
+    {"type": "output", "name": "o1", "action": "remove", "impact": "destructive"}
+  ]
+}`, string(serialized))
+
+	noChanges := NewSyncPlanResult([]OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "r1"},
+	})
+	a.False(noChanges.HasChanges())
+	a.Equal(SyncPlanExitNoChanges, noChanges.ExitCode())
+}
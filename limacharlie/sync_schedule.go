@@ -0,0 +1,165 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrorOutsideSyncWindow is returned by SyncPush when
+// none of the windows of its schedule is open.
+var ErrorOutsideSyncWindow = errors.New("outside of sync maintenance windows")
+
+// SyncSchedule restricts when and how SyncPush modifies an Org.
+type SyncSchedule struct {
+	// Windows during which changes may be applied,
+	// changes may be applied anytime if empty.
+	Windows []SyncWindow `json:"windows,omitempty" yaml:"windows,omitempty"`
+
+	// Jitter is the maximum random delay before applying
+	// changes, to spread syncs of many Orgs over time.
+	Jitter time.Duration `json:"jitter,omitempty" yaml:"jitter,omitempty"`
+
+	// DeferRemovals only applies additions and updates of a
+	// forced sync. The removals are reported as skipped and
+	// applied by a later run without DeferRemovals.
+	DeferRemovals bool `json:"defer_removals,omitempty" yaml:"defer_removals,omitempty"`
+
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// SyncWindow is a daily time window, like "22:00" to "02:00".
+type SyncWindow struct {
+	// Days the window opens, every day if empty.
+	Weekdays []time.Weekday `json:"weekdays,omitempty" yaml:"weekdays,omitempty"`
+	// Start and End times formatted as "15:04".
+	Start string `json:"start" yaml:"start"`
+	End   string `json:"end" yaml:"end"`
+	// Location of the times, defaults to UTC.
+	Location *time.Location `json:"-" yaml:"-"`
+}
+
+func parseWindowTime(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid window time %q: %v", s, err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// IsOpen returns true if t falls within the window. A window ending
+// before it starts spans midnight and belongs to the day it starts.
+func (w SyncWindow) IsOpen(t time.Time) (bool, error) {
+	start, err := parseWindowTime(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseWindowTime(w.End)
+	if err != nil {
+		return false, err
+	}
+	loc := w.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	sinceMidnight := t.Sub(midnight)
+
+	if start <= end {
+		return w.isOnDay(t.Weekday()) && sinceMidnight >= start && sinceMidnight < end, nil
+	}
+	if sinceMidnight >= start {
+		return w.isOnDay(t.Weekday()), nil
+	}
+	if sinceMidnight < end {
+		return w.isOnDay(midnight.AddDate(0, 0, -1).Weekday()), nil
+	}
+	return false, nil
+}
+
+func (w SyncWindow) isOnDay(d time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, wd := range w.Weekdays {
+		if wd == d {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOpen returns true if changes may be applied at t.
+func (s SyncSchedule) IsOpen(t time.Time) (bool, error) {
+	if len(s.Windows) == 0 {
+		return true, nil
+	}
+	for _, w := range s.Windows {
+		isOpen, err := w.IsOpen(t)
+		if err != nil {
+			return false, err
+		}
+		if isOpen {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// wait checks the windows and waits for the jitter.
+func (s SyncSchedule) wait() error {
+	now := s.now
+	if now == nil {
+		now = time.Now
+	}
+	isOpen, err := s.IsOpen(now())
+	if err != nil {
+		return err
+	}
+	if !isOpen {
+		return ErrorOutsideSyncWindow
+	}
+	if s.Jitter <= 0 {
+		return nil
+	}
+	sleep := s.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+	sleep(time.Duration(rand.Int63n(int64(s.Jitter))))
+	return nil
+}
+
+// syncPushDeferringRemovals applies a forced sync without its
+// removals, which are reported as skipped operations.
+func (org Organization) syncPushDeferringRemovals(conf OrgConfig, options SyncOptions) ([]OrgSyncOperation, error) {
+	additive := options
+	additive.IsForce = false
+	additive.Schedule = nil
+	ops, err := org.SyncPush(conf, additive)
+	if err != nil {
+		return ops, err
+	}
+
+	pending := options
+	pending.IsDryRun = true
+	pending.Schedule = nil
+	pending.AuditSink = nil
+	pending.OnOperation = nil
+	pending.Logger = nil
+	planned, err := org.SyncPush(conf, pending)
+	if err != nil {
+		return ops, err
+	}
+	for _, op := range planned {
+		if !op.IsRemoved || op.IsSkipped {
+			continue
+		}
+		op.IsSkipped = true
+		ops = options.appendOp(ops, op)
+	}
+	return ops, nil
+}
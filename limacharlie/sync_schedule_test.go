@@ -0,0 +1,130 @@
+package limacharlie
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncWindow(t *testing.T) {
+	a := assert.New(t)
+	// 2023-01-06 is a Friday.
+	at := func(s string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04", s)
+		a.NoError(err)
+		return ts
+	}
+
+	w := SyncWindow{Start: "09:00", End: "17:00", Weekdays: []time.Weekday{time.Friday}}
+	isOpen, err := w.IsOpen(at("2023-01-06 09:00"))
+	a.NoError(err)
+	a.True(isOpen)
+	isOpen, _ = w.IsOpen(at("2023-01-06 17:00"))
+	a.False(isOpen)
+	isOpen, _ = w.IsOpen(at("2023-01-07 10:00"))
+	a.False(isOpen)
+
+	// Spanning midnight, the window belongs to the day it starts.
+	w = SyncWindow{Start: "22:00", End: "02:00", Weekdays: []time.Weekday{time.Friday}}
+	isOpen, _ = w.IsOpen(at("2023-01-06 23:00"))
+	a.True(isOpen)
+	isOpen, _ = w.IsOpen(at("2023-01-07 01:00"))
+	a.True(isOpen)
+	isOpen, _ = w.IsOpen(at("2023-01-06 01:00"))
+	a.False(isOpen)
+
+	_, err = SyncWindow{Start: "9am", End: "17:00"}.IsOpen(at("2023-01-06 10:00"))
+	a.Error(err)
+
+	s := SyncSchedule{}
+	isOpen, _ = s.IsOpen(at("2023-01-06 10:00"))
+	a.True(isOpen)
+}
+
+func TestSyncPushSchedule(t *testing.T) {
+	a := assert.New(t)
+	nRequests := 0
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		nRequests++
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	slept := time.Duration(-1)
+	schedule := &SyncSchedule{
+		Windows: []SyncWindow{{Start: "09:00", End: "17:00"}},
+		Jitter:  time.Minute,
+		now: func() time.Time {
+			return time.Date(2023, 1, 6, 20, 0, 0, 0, time.UTC)
+		},
+		sleep: func(d time.Duration) {
+			slept = d
+		},
+	}
+	_, err := org.SyncPush(OrgConfig{}, SyncOptions{Schedule: schedule})
+	a.Equal(ErrorOutsideSyncWindow, err)
+	a.Equal(0, nRequests)
+
+	// Dry runs ignore the schedule.
+	_, err = org.SyncPush(OrgConfig{}, SyncOptions{Schedule: schedule, IsDryRun: true})
+	a.NoError(err)
+	a.Equal(time.Duration(-1), slept)
+
+	schedule.Windows = nil
+	_, err = org.SyncPush(OrgConfig{}, SyncOptions{Schedule: schedule})
+	a.NoError(err)
+	a.True(slept >= 0 && slept < time.Minute)
+}
+
+func TestSyncPushDeferRemovals(t *testing.T) {
+	a := assert.New(t)
+	deleted := []string{}
+	added := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["output.list","output.set","output.del"]}`), nil
+		}
+		if !strings.HasPrefix(r.URL.Path, "/v1/outputs/") {
+			return jsonResponse(http.StatusOK, `{}`), nil
+		}
+		switch r.Method {
+		case http.MethodGet:
+			return jsonResponse(http.StatusOK, `{"`+vcrTestOID+`":{"old":{"name":"old","module":"syslog","for":"event"}}}`), nil
+		case http.MethodPost:
+			a.NoError(r.ParseForm())
+			added = append(added, r.PostForm.Get("name"))
+		case http.MethodDelete:
+			// Forms are only parsed for POST, PUT and PATCH.
+			body, _ := ioutil.ReadAll(r.Body)
+			form, err := url.ParseQuery(string(body))
+			a.NoError(err)
+			deleted = append(deleted, form.Get("name"))
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	conf := OrgConfig{Outputs: orgSyncOutputs{
+		"new": {Name: "new", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+	}}
+	ops, err := org.SyncPush(conf, SyncOptions{
+		IsForce:     true,
+		SyncOutputs: true,
+		Schedule:    &SyncSchedule{DeferRemovals: true},
+	})
+	a.NoError(err)
+	a.Equal([]string{"new"}, added)
+	a.Empty(deleted)
+	a.Equal([]OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "new", IsAdded: true},
+		{ElementType: OrgSyncOperationElementType.Output, ElementName: "old", IsRemoved: true, IsSkipped: true},
+	}, ops)
+
+	// The confirmation run applies the removals.
+	_, err = org.SyncPush(conf, SyncOptions{IsForce: true, SyncOutputs: true})
+	a.NoError(err)
+	a.Equal([]string{"old"}, deleted)
+}
@@ -0,0 +1,119 @@
+package limacharlie
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrorSyncCategoryNotSupported is wrapped by the errors of
+// SyncFetchStores and SyncPushStores when the options select
+// categories the stores cannot sync.
+var ErrorSyncCategoryNotSupported = errors.New("sync category not supported")
+
+// SyncStores are the stores SyncFetchStores and SyncPushStores sync
+// a config with, like the in-memory Organization of the lcmock package,
+// to test code managing a config without a live Org.
+type SyncStores struct {
+	FPRules FPRuleStore
+	Outputs OutputStore
+}
+
+// SyncFetchStores fetches the config held by the stores, like
+// SyncFetch. Only the FP rules and the outputs can be fetched,
+// selecting another category or one without a store is an error.
+func SyncFetchStores(stores SyncStores, options SyncOptions) (orgConfig OrgConfig, err error) {
+	if err := stores.checkCategories(options); err != nil {
+		return orgConfig, err
+	}
+	if options.SyncFPRules && stores.FPRules != nil {
+		orgConfig.FPRules, err = syncFetchFPRules(stores.FPRules)
+		if err != nil {
+			return orgConfig, fmt.Errorf("fp-rule: %v", err)
+		}
+	}
+	if options.SyncOutputs && stores.Outputs != nil {
+		orgConfig.Outputs, err = syncFetchOutputs(stores.Outputs)
+		if err != nil {
+			return orgConfig, fmt.Errorf("outputs: %v", err)
+		}
+	}
+	return orgConfig, nil
+}
+
+// SyncPushStores applies the config to the stores, like SyncPush.
+// Only the FP rules and the outputs can be pushed, selecting another
+// category or one without a store is an error.
+func SyncPushStores(stores SyncStores, conf OrgConfig, options SyncOptions) ([]OrgSyncOperation, error) {
+	ops := []OrgSyncOperation{}
+	if err := stores.checkCategories(options); err != nil {
+		return ops, err
+	}
+	categoryErrs := []error{}
+	if options.SyncFPRules && stores.FPRules != nil {
+		newOps, err := syncFPRules(stores.FPRules, conf.FPRules, options)
+		ops = append(ops, newOps...)
+		if err != nil {
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.FPRule, fmt.Errorf("fp-rules: %w", err)); err != nil {
+				return ops, err
+			}
+		}
+	}
+	if options.SyncOutputs && stores.Outputs != nil {
+		newOps, err := syncOutputs(stores.Outputs, conf.Outputs, options)
+		ops = append(ops, newOps...)
+		if err != nil {
+			if categoryErrs, err = options.failCategory(categoryErrs, OrgSyncOperationElementType.Output, fmt.Errorf("outputs: %w", err)); err != nil {
+				return ops, err
+			}
+		}
+	}
+	if options.ContinueOnError {
+		return ops, syncOpsError(ops, categoryErrs...)
+	}
+	return ops, nil
+}
+
+// checkCategories fails if the options select categories,
+// named like in SyncCategories, the stores cannot sync.
+func (stores SyncStores) checkCategories(options SyncOptions) error {
+	unsupported := []string{}
+	for _, c := range []struct {
+		name        string
+		isSelected  bool
+		isSupported bool
+	}{
+		{"dr", options.SyncDRRules, false},
+		{"outputs", options.SyncOutputs, stores.Outputs != nil},
+		{"resources", options.SyncResources, false},
+		{"integrity", options.SyncIntegrity, false},
+		{"fp", options.SyncFPRules, stores.FPRules != nil},
+		{"exfil", options.SyncExfil, false},
+		{"artifacts", options.SyncArtifacts, false},
+		{"org_values", options.SyncOrgValues, false},
+		{"installation_keys", options.SyncInstallationKeys, false},
+		{"yara", options.SyncYara, false},
+		{"extensions", options.SyncExtensions, false},
+		{"net_policies", options.SyncNetPolicies, false},
+		{"retention", options.SyncRetention, false},
+		{"detection_routing", options.SyncDetectionRouting, false},
+		{"feeds", options.SyncThreatFeeds, false},
+	} {
+		if c.isSelected && !c.isSupported {
+			unsupported = append(unsupported, c.name)
+		}
+	}
+	hives := []string{}
+	for hiveName, isSelected := range options.SyncHives {
+		if isSelected {
+			hives = append(hives, "hive:"+hiveName)
+		}
+	}
+	sort.Strings(hives)
+	unsupported = append(unsupported, hives...)
+	if len(unsupported) != 0 {
+		return fmt.Errorf("%w: %s", ErrorSyncCategoryNotSupported, strings.Join(unsupported, ", "))
+	}
+	return nil
+}
@@ -1,13 +1,14 @@
 package limacharlie
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net/http"
 	"path/filepath"
 	"sort"
 	"testing"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v3"
 )
@@ -23,7 +24,7 @@ func resetResource(org *Organization) {
 
 func TestSyncPushResources(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 
 	resetResource(org)
 	resourcesBase, err := org.Resources()
@@ -94,7 +95,7 @@ resources:
 
 func TestSyncPushDRRules(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	rules, err := org.DRRules()
 	a.NoError(err)
 	if len(rules) != 0 {
@@ -301,7 +302,7 @@ func sortSyncOps(ops []OrgSyncOperation) []OrgSyncOperation {
 
 func TestSyncPushFPRules(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	defer deleteAllFPRules(org)
 
 	rules, err := org.FPRules()
@@ -418,7 +419,7 @@ func deleteAllOutputs(org *Organization) {
 
 func TestSyncPushOutputs(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	defer deleteAllOutputs(org)
 
 	outputs, err := org.Outputs()
@@ -555,7 +556,7 @@ func deleteIntegrityRules(org *Organization) {
 
 func TestSyncPushIntegrity(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	defer deleteIntegrityRules(org)
 
 	unsubReplicantCB, err := findUnsubscribeReplicantCallback(org, "integrity")
@@ -678,7 +679,7 @@ func deleteExfil(org *Organization) {
 
 func TestSyncPushExfil(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	defer deleteExfil(org)
 
 	unsubReplicantCB, err := findUnsubscribeReplicantCallback(org, "exfil")
@@ -854,7 +855,7 @@ func deleteArtifacts(org *Organization) {
 
 func TestSyncPushArtifact(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	defer deleteArtifacts(org)
 
 	unsubCB, err := findUnsubscribeReplicantCallback(org, "logging")
@@ -1168,16 +1169,18 @@ rules:
 
 func TestSyncOrgValues(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 
 	// Start by zeroing out all values.
-	for _, v := range supportedOrgValues {
+	for _, md := range defaultOrgValueCatalog {
+		v := md.Name
 		err := org.OrgValueSet(v, "")
 		a.NoError(err)
 	}
 
-	ov1 := uuid.NewString()
-	ov2 := uuid.NewString()
+	// Fixed values, the responses being replayed from the fixture.
+	ov1 := "f7c3bc1d-808e-4e8b-9c5f-6b4a0b0a2d11"
+	ov2 := "2b8e1a54-3d4f-4c1e-a7a6-3f0e6c9d8b22"
 	yamlValues := fmt.Sprintf(`org-value:
   otx: %s
   twilio: %s
@@ -1319,7 +1322,7 @@ func deleteYaraRules(org *Organization) {
 
 func TestSyncPushYara(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	defer deleteYaraRules(org)
 
 	unsubReplicantCB, err := findUnsubscribeReplicantCallback(org, "yara")
@@ -1476,7 +1479,7 @@ yara:
 
 func TestSyncInstallationKeys(t *testing.T) {
 	a := assert.New(t)
-	org := getTestOrgFromEnv(a)
+	org := getTestOrgFromFixture(t, a)
 	deleteAllInstallationKeys(org)
 	defer deleteAllInstallationKeys(org)
 
@@ -1588,5 +1591,287 @@ func deleteAllInstallationKeys(org *Organization) {
 	for _, k := range keys {
 		org.DelInstallationKey(k.ID)
 	}
-	time.Sleep(1 * time.Second)
+	waitForOrg(org, 1*time.Second)
+}
+
+func TestMergeExtensions(t *testing.T) {
+	o1 := OrgConfig{
+		Version:    3,
+		Extensions: orgSyncExtensions{"ext-reliable-tasking", "ext-sensor-cull"},
+	}
+	o2 := OrgConfig{
+		Extensions: orgSyncExtensions{"ext-sensor-cull", "ext-exfil"},
+	}
+	expected := `version: 3
+extensions:
+    - ext-reliable-tasking
+    - ext-sensor-cull
+    - ext-exfil
+`
+
+	yOut, err := yaml.Marshal(o1.Merge(o2))
+	if err != nil {
+		t.Errorf("yaml: %v", err)
+	} else if string(yOut) != expected {
+		t.Errorf("unexpected config: %s\n!=\n\n%s", string(yOut), expected)
+	}
+}
+
+func TestSyncProtectedNamespaces(t *testing.T) {
+	a := assert.New(t)
+	options := SyncOptions{ProtectedNamespaces: []string{"managed", "soteria"}, ProtectedFPRules: []FPRuleName{"vendor-fp"}}
+
+	a.True(options.isNamespaceProtected("managed"))
+	a.False(options.isNamespaceProtected(""))
+	a.False(options.isNamespaceProtected("general"))
+	a.True(options.isFPRuleProtected("vendor-fp"))
+	a.False(options.isFPRuleProtected("soteria-noisy-host"))
+	a.False(options.isFPRuleProtected("noisy-host"))
+
+	a.True(SyncOptions{ProtectedNamespaces: []string{"general"}}.isNamespaceProtected(""))
+
+	op := OrgSyncOperation{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "r", IsRemoved: true, IsSkipped: true}
+	a.Equal("! dr-rule r", op.String())
+}
+
+func TestSyncHiveProtectedNamespaces(t *testing.T) {
+	a := assert.New(t)
+	modified := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/v1/orgs/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{"oid": "`+vcrTestOID+`"}`), nil
+		case "/v1/hive/dr-managed/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{"vendor-rule": {"data": {"detect": {"op": "exists"}}, "usr_mtd": {"enabled": true}}}`), nil
+		case "/v1/hive/dr-general/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{"old-rule": {"data": {"detect": {"op": "exists"}}, "usr_mtd": {"enabled": true}}}`), nil
+		}
+		modified = append(modified, r.Method+" "+r.URL.Path)
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	ops, err := org.syncHive(orgSyncHives{
+		"dr-managed": {"my-rule": {Data: map[string]interface{}{"detect": map[string]interface{}{"op": "is"}}, UsrMtd: UsrMtd{Enabled: true}}},
+		"dr-general": {},
+	}, SyncOptions{IsForce: true, ProtectedNamespaces: []string{"managed"}})
+	a.NoError(err)
+
+	seen := []string{}
+	for _, op := range ops {
+		seen = append(seen, op.String())
+	}
+	sort.Strings(seen)
+	a.Equal([]string{"! hives dr-managed/my-rule", "! hives dr-managed/vendor-rule", "- hives dr-general/old-rule"}, seen)
+	a.Equal([]string{"DELETE /v1/hive/dr-general/" + vcrTestOID + "/old-rule"}, modified)
+}
+
+func TestSyncOnOperation(t *testing.T) {
+	a := assert.New(t)
+
+	seen := []string{}
+	options := SyncOptions{
+		OnOperation: func(op OrgSyncOperation, err error) {
+			if err != nil {
+				seen = append(seen, fmt.Sprintf("%s: %v", op.ElementType, err))
+				return
+			}
+			seen = append(seen, op.String())
+		},
+		Logger: &LCLoggerEmpty{},
+	}
+
+	ops := []OrgSyncOperation{}
+	ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "o1", IsAdded: true})
+	ops = options.appendOp(ops, OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "o2"})
+	err := options.reportError(OrgSyncOperationElementType.Output, fmt.Errorf("outputs: failed"))
+
+	a.Len(ops, 2)
+	a.EqualError(err, "outputs: failed")
+	a.Equal([]string{"+ output o1", "= output o2", "output: outputs: failed"}, seen)
+}
+
+func TestSyncContinueOnError(t *testing.T) {
+	a := assert.New(t)
+
+	failed := OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "typo", IsAdded: true}
+
+	ops, err := SyncOptions{}.failOp(nil, failed, fmt.Errorf("invalid module"))
+	a.EqualError(err, "invalid module")
+	a.Len(ops, 1)
+
+	options := SyncOptions{ContinueOnError: true}
+	ops = options.appendOp(nil, OrgSyncOperation{ElementType: OrgSyncOperationElementType.Output, ElementName: "ok", IsAdded: true})
+	ops, err = options.failOp(ops, failed, fmt.Errorf("invalid module"))
+	a.NoError(err)
+	a.Len(ops, 2)
+	a.EqualError(ops[1].Error, "invalid module")
+	a.Equal("+ output typo: invalid module", ops[1].String())
+
+	a.EqualError(syncOpsError(ops), "1 operations failed: output typo: invalid module")
+	a.NoError(syncOpsError(ops[:1]))
+}
+
+type failingSyncStore struct {
+	outputs OutputsByName
+}
+
+func (s *failingSyncStore) FPRules() (map[FPRuleName]FPRule, error) {
+	return nil, fmt.Errorf("listing failed")
+}
+
+func (s *failingSyncStore) FPRuleAdd(name FPRuleName, detection interface{}, opts ...FPRuleOptions) error {
+	return nil
+}
+
+func (s *failingSyncStore) FPRuleDelete(name FPRuleName) error {
+	return nil
+}
+
+func (s *failingSyncStore) Outputs() (OutputsByName, error) {
+	return s.outputs, nil
+}
+
+func (s *failingSyncStore) OutputAdd(output OutputConfig) (OutputConfig, error) {
+	if output.Name == "typo" {
+		return output, fmt.Errorf("invalid module")
+	}
+	s.outputs[output.Name] = output
+	return output, nil
+}
+
+func (s *failingSyncStore) OutputDel(name string) (GenericJSON, error) {
+	delete(s.outputs, name)
+	return nil, nil
+}
+
+func TestSyncPushFailureReporting(t *testing.T) {
+	a := assert.New(t)
+
+	conf := OrgConfig{
+		FPRules: orgSyncFPRules{"fp1": {Detection: Dict{"op": "is"}}},
+		Outputs: orgSyncOutputs{
+			"good": {Name: "good", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+			"typo": {Name: "typo", Module: "sylog", Type: OutputType.Detect},
+		},
+	}
+	failures := []string{}
+	options := SyncOptions{
+		SyncOutputs: true,
+		OnOperation: func(op OrgSyncOperation, err error) {
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s %s: %v", op.ElementType, op.ElementName, err))
+			}
+		},
+	}
+
+	// A failed operation is reported once.
+	store := &failingSyncStore{outputs: OutputsByName{}}
+	_, err := SyncPushStores(SyncStores{FPRules: store, Outputs: store}, conf, options)
+	a.EqualError(err, "outputs: invalid module")
+	a.Equal([]string{"output typo: invalid module"}, failures)
+
+	// A failed category does not stop the others.
+	failures = []string{}
+	options.SyncFPRules = true
+	options.ContinueOnError = true
+	store = &failingSyncStore{outputs: OutputsByName{}}
+	_, err = SyncPushStores(SyncStores{FPRules: store, Outputs: store}, conf, options)
+	a.EqualError(err, "2 operations failed: output typo: invalid module; fp-rules: listing failed")
+	a.Equal([]string{"fp-rule : fp-rules: listing failed", "output typo: invalid module"}, failures)
+	a.Contains(store.outputs, "good")
+}
+
+func TestNewSyncOptionsForCategories(t *testing.T) {
+	a := assert.New(t)
+
+	options, err := NewSyncOptionsForCategories("dr", "outputs", "hive:lookup")
+	a.NoError(err)
+	a.True(options.SyncDRRules)
+	a.True(options.SyncOutputs)
+	a.False(options.SyncFPRules)
+	a.Equal(map[string]bool{"lookup": true}, options.SyncHives)
+
+	options, err = NewSyncOptionsForCategories("hive:secret", "all")
+	a.NoError(err)
+	a.True(options.SyncRetention)
+	a.True(options.SyncHives["secret"])
+	a.True(options.SyncHives["cloud_sensor"])
+
+	_, err = NewSyncOptionsForCategories("rules")
+	a.EqualError(err, "unknown sync category: rules")
+}
+
+func TestSyncArtifactsFetchPushRoundTrip(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["logging.get"]}`), nil
+		}
+		a.NoError(r.ParseForm())
+		data, err := base64.StdEncoding.DecodeString(r.PostForm.Get("request_data"))
+		a.NoError(err)
+		a.JSONEq(`{"action":"list_rules"}`, string(data))
+		return jsonResponse(http.StatusOK, `{"r1":{
+			"by": "someone",
+			"updated": 1,
+			"patterns": ["/var/log/*.log"],
+			"days_retention": 30,
+			"filters": {"tags": ["server"], "platforms": ["linux"], "exclude_tags": ["test"]},
+			"max_file_size": 1048576,
+			"mode": "streaming"
+		}}`), nil
+	}))
+
+	options := SyncOptions{SyncArtifacts: true}
+	conf, err := org.SyncFetch(options)
+	a.NoError(err)
+	a.Equal(OrgSyncArtifactRule{
+		DaysRetentions: 30,
+		Patterns:       []string{"/var/log/*.log"},
+		Tags:           []string{"server"},
+		Platforms:      []Platform{"linux"},
+		ExcludeTags:    []string{"test"},
+		MaxFileSize:    1048576,
+		Mode:           ArtifactRuleModes.Streaming,
+	}, conf.Artifacts["r1"])
+
+	options.IsForce = true
+	plan, err := org.SyncPlan(conf, options)
+	a.NoError(err)
+	a.False(plan.HasChanges(), "%v", plan.Operations)
+}
+
+func TestSyncDRRulesComparesListing(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch r.URL.Path {
+		case "/v1/who":
+			return jsonResponse(http.StatusOK, `{"orgs":["`+vcrTestOID+`"],"perms":["dr.list"]}`), nil
+		case "/v1/rules/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{
+				"same": {"name": "same", "namespace": "general", "is_enabled": true, "detect": {"op": "exists", "path": "event"}, "respond": [{"action": "report", "name": "same"}]},
+				"changed": {"name": "changed", "namespace": "general", "is_enabled": true, "detect": {"op": "exists", "path": "event"}, "respond": [{"action": "report", "name": "changed"}]}
+			}`), nil
+		}
+		// The rules are not fetched one by one.
+		a.Fail("unexpected request", r.URL.String())
+		return jsonResponse(http.StatusNotFound, `{"error":"not found"}`), nil
+	}))
+
+	conf := OrgConfig{DRRules: orgSyncDRRules{
+		"same": {
+			Detect:   Dict{"op": "exists", "path": "event"},
+			Response: List{Dict{"action": "report", "name": "same"}},
+		},
+		"changed": {
+			Detect:   Dict{"op": "exists", "path": "event/FILE_PATH"},
+			Response: List{Dict{"action": "report", "name": "changed"}},
+		},
+	}}
+	ops, err := org.SyncPush(conf, SyncOptions{IsDryRun: true, SyncDRRules: true})
+	a.NoError(err)
+	a.Equal(sortSyncOps([]OrgSyncOperation{
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "changed", IsAdded: true, IsUpdated: true},
+		{ElementType: OrgSyncOperationElementType.DRRule, ElementName: "same"},
+	}), sortSyncOps(ops))
 }
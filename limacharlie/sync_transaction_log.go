@@ -0,0 +1,203 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// SyncTransaction is a change applied by SyncPush, as written
+// to the SyncOptions.TransactionLog.
+type SyncTransaction struct {
+	TimeStamp time.Time `json:"ts"`
+	OID       string    `json:"oid"`
+	Type      string    `json:"type"`
+	Name      string    `json:"name"`
+	// Action is one of "add", "update" or "remove".
+	Action string `json:"action"`
+	// BeforeHash and AfterHash are the ContentHash of the element
+	// before and after the change, empty when it did not exist.
+	BeforeHash string `json:"before_hash,omitempty"`
+	AfterHash  string `json:"after_hash,omitempty"`
+	// Actor is the identity of the credentials used for the sync.
+	Actor  string `json:"actor"`
+	Author string `json:"author,omitempty"`
+}
+
+// syncElementPaths are the paths of the elements of each type in the
+// JSON of an OrgConfig, elements being keyed by name under the path.
+var syncElementPaths = map[string][]string{
+	OrgSyncOperationElementType.DRRule:          {"rules"},
+	OrgSyncOperationElementType.FPRule:          {"fps"},
+	OrgSyncOperationElementType.Output:          {"outputs"},
+	OrgSyncOperationElementType.Integrity:       {"integrity"},
+	OrgSyncOperationElementType.ExfilEvent:      {"exfil", "list"},
+	OrgSyncOperationElementType.ExfilWatch:      {"exfil", "watch"},
+	OrgSyncOperationElementType.Artifact:        {"artifact"},
+	OrgSyncOperationElementType.NetPolicy:       {"net-policy"},
+	OrgSyncOperationElementType.OrgValue:        {"org-value"},
+	OrgSyncOperationElementType.Hives:           {"hives"},
+	OrgSyncOperationElementType.InstallationKey: {"installation_keys"},
+	OrgSyncOperationElementType.YaraRule:        {"yara", "rules"},
+	OrgSyncOperationElementType.YaraSource:      {"yara", "sources"},
+	OrgSyncOperationElementType.Retention:       {"retention"},
+	OrgSyncOperationElementType.DetectionRoute:  {"detection-routing"},
+	OrgSyncOperationElementType.ThreatFeed:      {"feeds"},
+}
+
+// syncElementHashes hashes the elements of a config, keyed by
+// "<type>/<name>". Resources and extensions are only names, they
+// are hashed as such.
+func syncElementHashes(conf OrgConfig) (map[string]string, error) {
+	hashes := map[string]string{}
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return nil, err
+	}
+	root := map[string]interface{}{}
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	add := func(elementType string, name string, v interface{}) error {
+		h, err := ContentHash(v)
+		if err != nil {
+			return err
+		}
+		hashes[elementType+"/"+name] = h
+		return nil
+	}
+	for elementType, path := range syncElementPaths {
+		var node interface{} = root
+		for _, k := range path {
+			m, _ := node.(map[string]interface{})
+			node = m[k]
+		}
+		elements, _ := node.(map[string]interface{})
+		for name, v := range elements {
+			if elementType != OrgSyncOperationElementType.Hives {
+				if err := add(elementType, name, v); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			// Hive operations are named "<hive>/<key>".
+			records, _ := v.(map[string]interface{})
+			for key, r := range records {
+				if err := add(elementType, name+"/"+key, r); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	for cat, names := range conf.Resources {
+		for _, name := range names {
+			full := fmt.Sprintf("%s/%s", cat, name)
+			if err := add(OrgSyncOperationElementType.Resource, full, full); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, name := range conf.Extensions {
+		if err := add(OrgSyncOperationElementType.Extension, name, name); err != nil {
+			return nil, err
+		}
+	}
+	return hashes, nil
+}
+
+// syncTransactionLog writes the changes applied by a
+// SyncPush as JSON lines, see SyncOptions.TransactionLog.
+type syncTransactionLog struct {
+	mutex   sync.Mutex
+	enc     *json.Encoder
+	oid     string
+	actor   string
+	author  string
+	before  map[string]string
+	after   map[string]string
+	lastErr error
+}
+
+// newSyncTransactionLog hashes the elements of the Org before
+// the push, fetching the categories synced, and of the config.
+func (org Organization) newSyncTransactionLog(conf OrgConfig, options SyncOptions) (*syncTransactionLog, error) {
+	who, err := org.client.whoAmI()
+	if err != nil {
+		return nil, err
+	}
+	current, err := org.SyncFetch(options)
+	if err != nil {
+		return nil, fmt.Errorf("transaction log: %v", err)
+	}
+	before, err := syncElementHashes(current)
+	if err != nil {
+		return nil, fmt.Errorf("transaction log: %v", err)
+	}
+	after, err := syncElementHashes(conf)
+	if err != nil {
+		return nil, fmt.Errorf("transaction log: %v", err)
+	}
+	return &syncTransactionLog{
+		enc:    json.NewEncoder(options.TransactionLog),
+		oid:    org.client.options.OID,
+		actor:  who.toWhoAmI().Identity,
+		author: options.AnnotateAuthor,
+		before: before,
+		after:  after,
+	}, nil
+}
+
+// record writes the operation if it changed the Org.
+func (l *syncTransactionLog) record(op OrgSyncOperation) {
+	action := syncPlanAction(op)
+	if op.Error != nil || (action != "add" && action != "update" && action != "remove") {
+		return
+	}
+	key := op.ElementType + "/" + op.ElementName
+	t := SyncTransaction{
+		TimeStamp: time.Now().UTC(),
+		OID:       l.oid,
+		Type:      op.ElementType,
+		Name:      op.ElementName,
+		Action:    action,
+		Actor:     l.actor,
+		Author:    l.author,
+	}
+	if action != "add" {
+		t.BeforeHash = l.before[key]
+	}
+	if action != "remove" {
+		t.AfterHash = l.after[key]
+	}
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if err := l.enc.Encode(t); err != nil && l.lastErr == nil {
+		l.lastErr = err
+	}
+}
+
+func (l *syncTransactionLog) err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.lastErr != nil {
+		return fmt.Errorf("transaction log: %v", l.lastErr)
+	}
+	return nil
+}
+
+// ReadSyncTransactions reads a transaction log written by SyncPush.
+func ReadSyncTransactions(r io.Reader) ([]SyncTransaction, error) {
+	transactions := []SyncTransaction{}
+	dec := json.NewDecoder(r)
+	for {
+		t := SyncTransaction{}
+		if err := dec.Decode(&t); err == io.EOF {
+			return transactions, nil
+		} else if err != nil {
+			return transactions, fmt.Errorf("invalid transaction log: %v", err)
+		}
+		transactions = append(transactions, t)
+	}
+}
@@ -0,0 +1,79 @@
+package limacharlie
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncPushTransactionLog(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Path == "/v1/who" {
+			return jsonResponse(http.StatusOK, `{"ident":"ci@example.com","orgs":["`+vcrTestOID+`"],"perms":["output.list","output.set","output.del"]}`), nil
+		}
+		if strings.HasPrefix(r.URL.Path, "/v1/outputs/") && r.Method == http.MethodGet {
+			return jsonResponse(http.StatusOK, `{"`+vcrTestOID+`":{
+				"old": {"name": "old", "module": "syslog", "for": "event"},
+				"changed": {"name": "changed", "module": "syslog", "for": "event"},
+				"same": {"name": "same", "module": "syslog", "for": "event"}
+			}}`), nil
+		}
+		return jsonResponse(http.StatusOK, `{}`), nil
+	}))
+
+	conf := OrgConfig{Outputs: orgSyncOutputs{
+		"new":     {Name: "new", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+		"changed": {Name: "changed", Module: OutputTypes.Syslog, Type: OutputType.Detect},
+		"same":    {Name: "same", Module: OutputTypes.Syslog, Type: OutputType.Event},
+	}}
+	txLog := bytes.Buffer{}
+	options := SyncOptions{
+		IsForce:        true,
+		SyncOutputs:    true,
+		AnnotateAuthor: "jdoe",
+		TransactionLog: &txLog,
+	}
+	ops, err := org.SyncPush(conf, options)
+	a.NoError(err)
+	a.Len(ops, 4)
+
+	transactions, err := ReadSyncTransactions(&txLog)
+	a.NoError(err)
+	a.Len(transactions, 3)
+	byName := map[string]SyncTransaction{}
+	for _, tx := range transactions {
+		a.Equal(vcrTestOID, tx.OID)
+		a.Equal(OrgSyncOperationElementType.Output, tx.Type)
+		a.Equal("ci@example.com", tx.Actor)
+		a.Equal("jdoe", tx.Author)
+		a.False(tx.TimeStamp.IsZero())
+		byName[tx.Name] = tx
+	}
+	a.Equal("add", byName["new"].Action)
+	a.Empty(byName["new"].BeforeHash)
+	a.NotEmpty(byName["new"].AfterHash)
+	a.Equal("update", byName["changed"].Action)
+	a.NotEmpty(byName["changed"].BeforeHash)
+	a.NotEqual(byName["changed"].BeforeHash, byName["changed"].AfterHash)
+	a.Equal("remove", byName["old"].Action)
+	a.NotEmpty(byName["old"].BeforeHash)
+	a.Empty(byName["old"].AfterHash)
+
+	// Dry runs change nothing.
+	options.IsDryRun = true
+	_, err = org.SyncPush(conf, options)
+	a.NoError(err)
+	a.Zero(txLog.Len())
+
+	hashes, err := syncElementHashes(OrgConfig{
+		Resources: orgSyncResources{"replicant": {"yara"}},
+		Hives:     orgSyncHives{"lookup": {"tor": {Data: Dict{"a": "b"}}}},
+	})
+	a.NoError(err)
+	a.Contains(hashes, "resource/replicant/yara")
+	a.Contains(hashes, "hives/lookup/tor")
+}
@@ -0,0 +1,158 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultTaskResponseTimeout is how long TaskAll collects
+// responses for when no ResponseTimeout is set.
+const defaultTaskResponseTimeout = 30 * time.Second
+
+type TaskAllOptions struct {
+	TaskingOptions
+
+	// Maximum number of sensors tasked at the same time, defaults to 10.
+	Concurrency int
+
+	// Responses, if set, is read for the responses to the tasks until
+	// it is closed or ResponseTimeout, defaulting to 30 seconds, elapses
+	// after the last task is sent. This is usually the Messages of a
+	// Firehose receiving the events of the InvestigationID, which must
+	// be set to collect responses.
+	Responses       <-chan FirehoseMessage
+	ResponseTimeout time.Duration
+}
+
+// TaskResult is the outcome of tasking a single sensor.
+type TaskResult struct {
+	Sensor    *Sensor
+	Error     error
+	Responses []Dict
+}
+
+// TaskAllResults are the TaskResults of a mass tasking by SID.
+type TaskAllResults map[string]*TaskResult
+
+// Succeeded returns the sorted SIDs of the sensors tasked successfully.
+func (r TaskAllResults) Succeeded() []string {
+	return r.filter(func(res *TaskResult) bool { return res.Error == nil })
+}
+
+// Failed returns the sorted SIDs of the sensors that could not be tasked.
+func (r TaskAllResults) Failed() []string {
+	return r.filter(func(res *TaskResult) bool { return res.Error != nil })
+}
+
+func (r TaskAllResults) filter(cb func(*TaskResult) bool) []string {
+	sids := []string{}
+	for sid, res := range r {
+		if cb(res) {
+			sids = append(sids, sid)
+		}
+	}
+	sort.Strings(sids)
+	return sids
+}
+
+// TaskAll sends a task to all the sensors matching the selector.
+// Failures to task individual sensors are reported in the results,
+// only failing to list the sensors returns an error.
+func (org *Organization) TaskAll(selector string, task string, opts TaskAllOptions) (TaskAllResults, error) {
+	if opts.Responses != nil && opts.InvestigationID == "" {
+		return nil, fmt.Errorf("an investigation id is required to collect responses")
+	}
+	if err := ValidateSelector(selector); err != nil {
+		return nil, fmt.Errorf("invalid selector: %v", err)
+	}
+	sensors, err := org.ListSensorsFromSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 10
+	}
+
+	results := TaskAllResults{}
+	for sid, s := range sensors {
+		results[sid] = &TaskResult{Sensor: s}
+	}
+
+	// Start listening before tasking so fast responses are not missed.
+	collected := make(chan map[string][]Dict, 1)
+	stopCollecting := make(chan struct{})
+	if opts.Responses != nil {
+		go func() {
+			collected <- collectTaskResponses(opts.Responses, opts.InvestigationID, stopCollecting)
+		}()
+	}
+
+	sem := make(chan struct{}, concurrency)
+	wg := sync.WaitGroup{}
+	for _, res := range results {
+		res := res
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			res.Error = res.Sensor.Task(task, opts.TaskingOptions)
+		}()
+	}
+	wg.Wait()
+
+	if opts.Responses == nil {
+		return results, nil
+	}
+	timeout := opts.ResponseTimeout
+	if timeout <= 0 {
+		timeout = defaultTaskResponseTimeout
+	}
+	var responsesBySID map[string][]Dict
+	select {
+	case responsesBySID = <-collected:
+		// The responses were closed before the timeout.
+	case <-time.After(timeout):
+		close(stopCollecting)
+		responsesBySID = <-collected
+	}
+	for sid, responses := range responsesBySID {
+		if res, ok := results[sid]; ok {
+			res.Responses = responses
+		}
+	}
+	return results, nil
+}
+
+// collectTaskResponses reads messages until stopped and returns
+// the ones with the investigation id by SID.
+func collectTaskResponses(messages <-chan FirehoseMessage, investigationID string, stop <-chan struct{}) map[string][]Dict {
+	responses := map[string][]Dict{}
+	for {
+		select {
+		case <-stop:
+			return responses
+		case msg, ok := <-messages:
+			if !ok {
+				return responses
+			}
+			content := msg.Content
+			if content == nil {
+				if err := json.Unmarshal([]byte(msg.RawContent), &content); err != nil {
+					continue
+				}
+			}
+			routing, _ := content["routing"].(map[string]interface{})
+			sid, _ := routing["sid"].(string)
+			invID, _ := routing["investigation_id"].(string)
+			if sid == "" || !MatchesInvestigation(invID, investigationID) {
+				continue
+			}
+			responses[sid] = append(responses[sid], Dict(content))
+		}
+	}
+}
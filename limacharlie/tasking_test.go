@@ -0,0 +1,70 @@
+package limacharlie
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectTaskResponses(t *testing.T) {
+	a := assert.New(t)
+
+	messages := make(chan FirehoseMessage, 4)
+	messages <- FirehoseMessage{RawContent: `{"routing":{"sid":"s1","investigation_id":"inv/ctx"},"event":{"a":1}}`}
+	messages <- FirehoseMessage{Content: map[string]interface{}{
+		"routing": map[string]interface{}{"sid": "s2", "investigation_id": "inv"},
+	}}
+	messages <- FirehoseMessage{RawContent: `{"routing":{"sid":"s3","investigation_id":"other"}}`}
+	messages <- FirehoseMessage{RawContent: `not json`}
+	close(messages)
+
+	responses := collectTaskResponses(messages, "inv", make(chan struct{}))
+	a.Len(responses, 2)
+	a.Len(responses["s1"], 1)
+	a.Len(responses["s2"], 1)
+
+	results := TaskAllResults{
+		"s1": &TaskResult{},
+		"s2": &TaskResult{Error: assert.AnError},
+	}
+	a.Equal([]string{"s1"}, results.Succeeded())
+	a.Equal([]string{"s2"}, results.Failed())
+}
+
+func TestTaskAll(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/sensors/"+vcrTestOID:
+			return jsonResponse(http.StatusOK, `{"sensors": [{"sid": "s1"}, {"sid": "s2"}]}`), nil
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/s1":
+			return jsonResponse(http.StatusOK, `{}`), nil
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/s2":
+			return jsonResponse(http.StatusForbidden, `{"error": "denied"}`), nil
+		}
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	_, err := org.TaskAll("plat == windows", "os_version", TaskAllOptions{
+		Responses: make(chan FirehoseMessage),
+	})
+	a.Error(err)
+
+	// Without a timeout, responses are collected until closed.
+	messages := make(chan FirehoseMessage, 1)
+	messages <- FirehoseMessage{RawContent: `{"routing":{"sid":"s1","investigation_id":"inv"},"event":{"a":1}}`}
+	close(messages)
+	start := time.Now()
+	results, err := org.TaskAll("plat == windows", "os_version", TaskAllOptions{
+		TaskingOptions: TaskingOptions{InvestigationID: "inv"},
+		Responses:      messages,
+	})
+	a.NoError(err)
+	a.Less(int64(time.Since(start)), int64(defaultTaskResponseTimeout))
+	a.Equal([]string{"s1"}, results.Succeeded())
+	a.Equal([]string{"s2"}, results.Failed())
+	a.Len(results["s1"].Responses, 1)
+}
@@ -0,0 +1,549 @@
+package limacharlie
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ThreatFeedName is the name of a feed in the feeds section.
+type ThreatFeedName = string
+
+type ThreatFeedFormat = string
+
+var ThreatFeedFormats = struct {
+	// Text feeds have an indicator per line.
+	Text ThreatFeedFormat
+	CSV  ThreatFeedFormat
+	// MISP feeds are the JSON of the restSearch API of a MISP
+	// instance, of attributes or of events.
+	MISP ThreatFeedFormat
+	// STIX feeds are a STIX 2 bundle.
+	STIX ThreatFeedFormat
+	// TAXII feeds are the objects endpoint of a TAXII 2.1 collection.
+	TAXII ThreatFeedFormat
+}{
+	Text:  "text",
+	CSV:   "csv",
+	MISP:  "misp",
+	STIX:  "stix",
+	TAXII: "taxii",
+}
+
+const (
+	// threatFeedTag tags the lookups the feeds section
+	// owns, which the hives section leaves alone.
+	threatFeedTag = "threat-feed"
+
+	threatFeedTimeout = 60 * time.Second
+)
+
+// ThreatFeed is an external feed of indicators synced into a lookup,
+// which D&R rules can then match events against. Indicators absent
+// from the feed are aged out of the lookup after MaxAgeDays.
+type ThreatFeed struct {
+	Format ThreatFeedFormat `json:"format" yaml:"format"`
+	URL    string           `json:"url" yaml:"url"`
+	// Lookup the indicators are synced into, the name of the feed by
+	// default. Feeds syncing into the same lookup are de-duplicated.
+	Lookup LookupName `json:"lookup,omitempty" yaml:"lookup,omitempty"`
+	// Headers sent when fetching the feed, like the Authorization
+	// of a MISP instance, which can be encrypted, see DecryptConfig.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Column of the indicators of CSV feeds, the name of a column of
+	// the header or the index of a column, the first one by default.
+	Column string `json:"column,omitempty" yaml:"column,omitempty"`
+	// Types restricts the indicators to MISP attribute types, like
+	// "domain", or STIX types, like "domain-name". The indicators of
+	// text and CSV feeds have no type.
+	Types []string `json:"types,omitempty" yaml:"types,omitempty"`
+	// MaxAgeDays the indicators absent from the feed are kept for,
+	// removed on the next sync if 0.
+	MaxAgeDays int `json:"max_age_days,omitempty" yaml:"max_age_days,omitempty"`
+}
+
+type orgSyncThreatFeeds = map[ThreatFeedName]ThreatFeed
+
+func (f ThreatFeed) lookupName(name ThreatFeedName) LookupName {
+	if f.Lookup != "" {
+		return f.Lookup
+	}
+	return name
+}
+
+// Validate checks the feed can be fetched.
+func (f ThreatFeed) Validate() error {
+	switch f.Format {
+	case ThreatFeedFormats.Text, ThreatFeedFormats.CSV, ThreatFeedFormats.MISP, ThreatFeedFormats.STIX, ThreatFeedFormats.TAXII:
+	default:
+		return fmt.Errorf("unknown feed format %q", f.Format)
+	}
+	u, err := url.Parse(f.URL)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("invalid feed url %q", f.URL)
+	}
+	if f.MaxAgeDays < 0 {
+		return errors.New("max_age_days must not be negative")
+	}
+	if len(f.Types) != 0 && (f.Format == ThreatFeedFormats.Text || f.Format == ThreatFeedFormats.CSV) {
+		return fmt.Errorf("types are not supported by %s feeds", f.Format)
+	}
+	return nil
+}
+
+// FetchThreatFeed fetches the indicators of a feed, de-duplicated.
+func (org Organization) FetchThreatFeed(feed ThreatFeed) (map[string]struct{}, error) {
+	if err := feed.Validate(); err != nil {
+		return nil, err
+	}
+	indicators := map[string]struct{}{}
+	next := ""
+	for {
+		u, err := url.Parse(feed.URL)
+		if err != nil {
+			return nil, err
+		}
+		if next != "" {
+			q := u.Query()
+			q.Set("next", next)
+			u.RawQuery = q.Encode()
+		}
+		data, err := org.getThreatFeed(feed, u.String())
+		if err != nil {
+			return nil, err
+		}
+		next, err = parseThreatFeed(feed, data, indicators)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s feed: %v", feed.Format, err)
+		}
+		if next == "" {
+			return indicators, nil
+		}
+	}
+}
+
+func (org Organization) getThreatFeed(feed ThreatFeed, u string) ([]byte, error) {
+	r, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("User-Agent", "limacharlie-sdk")
+	switch feed.Format {
+	case ThreatFeedFormats.TAXII:
+		r.Header.Set("Accept", taxiiContentType)
+	case ThreatFeedFormats.MISP, ThreatFeedFormats.STIX:
+		r.Header.Set("Accept", "application/json")
+	}
+	for k, v := range feed.Headers {
+		r.Header.Set(k, v)
+	}
+	resp, err := org.client.httpClient(threatFeedTimeout).Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching feed: %s", resp.Status)
+	}
+	return data, nil
+}
+
+// parseThreatFeed adds the indicators of a page of a feed and
+// returns the cursor of the next page, for TAXII collections.
+func parseThreatFeed(feed ThreatFeed, data []byte, indicators map[string]struct{}) (string, error) {
+	types := map[string]bool{}
+	for _, t := range feed.Types {
+		types[strings.ToLower(t)] = true
+	}
+	add := func(t string, v string) {
+		v = strings.TrimSpace(v)
+		if v == "" || (len(types) != 0 && !types[strings.ToLower(t)]) {
+			return
+		}
+		indicators[v] = struct{}{}
+	}
+	switch feed.Format {
+	case ThreatFeedFormats.Text:
+		scanner := bufio.NewScanner(strings.NewReader(string(data)))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "#") {
+				continue
+			}
+			add("", line)
+		}
+		return "", scanner.Err()
+	case ThreatFeedFormats.CSV:
+		return "", parseCSVThreatFeed(data, feed.Column, add)
+	case ThreatFeedFormats.MISP:
+		return "", parseMISPThreatFeed(data, add)
+	}
+	envelope := struct {
+		Objects []map[string]interface{} `json:"objects"`
+		More    bool                     `json:"more"`
+		Next    string                   `json:"next"`
+	}{}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return "", err
+	}
+	for _, o := range envelope.Objects {
+		parseSTIXObject(o, add)
+	}
+	if feed.Format == ThreatFeedFormats.TAXII && envelope.More {
+		if envelope.Next == "" {
+			return "", errors.New("more objects without a next cursor")
+		}
+		return envelope.Next, nil
+	}
+	return "", nil
+}
+
+func parseCSVThreatFeed(data []byte, column string, add func(string, string)) error {
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.Comment = '#'
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	index, err := strconv.Atoi(column)
+	isNamed := column != "" && err != nil
+	if column == "" {
+		index = 0
+	}
+	for isFirst := true; ; isFirst = false {
+		row, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isFirst && isNamed {
+			index = -1
+			for i, name := range row {
+				if strings.EqualFold(strings.TrimSpace(name), column) {
+					index = i
+				}
+			}
+			if index == -1 {
+				return fmt.Errorf("no column %q in the header", column)
+			}
+			continue
+		}
+		if index < len(row) {
+			add("", row[index])
+		}
+	}
+}
+
+// parseMISPThreatFeed reads the attributes of the restSearch
+// API, {"response": {"Attribute": [...]}}, or its events,
+// {"response": [{"Event": {"Attribute": [...], "Object": [...]}}]}.
+func parseMISPThreatFeed(data []byte, add func(string, string)) error {
+	type mispAttribute struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}
+	type mispObject struct {
+		Attributes []mispAttribute `json:"Attribute"`
+	}
+	type mispEvent struct {
+		Attributes []mispAttribute `json:"Attribute"`
+		Objects    []mispObject    `json:"Object"`
+	}
+	resp := struct {
+		Response json.RawMessage `json:"response"`
+	}{}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return err
+	}
+	attributes := []mispAttribute{}
+	if strings.HasPrefix(strings.TrimSpace(string(resp.Response)), "[") {
+		events := []struct {
+			Event mispEvent `json:"Event"`
+		}{}
+		if err := json.Unmarshal(resp.Response, &events); err != nil {
+			return err
+		}
+		for _, e := range events {
+			attributes = append(attributes, e.Event.Attributes...)
+			for _, o := range e.Event.Objects {
+				attributes = append(attributes, o.Attributes...)
+			}
+		}
+	} else {
+		search := mispObject{}
+		if err := json.Unmarshal(resp.Response, &search); err != nil {
+			return err
+		}
+		attributes = search.Attributes
+	}
+	for _, a := range attributes {
+		add(a.Type, a.Value)
+	}
+	return nil
+}
+
+// stixPatternComparison matches the comparisons of a STIX pattern,
+// like "[domain-name:value = 'evil.com']", capturing the type of
+// the object and the value compared.
+var stixPatternComparison = regexp.MustCompile(`([a-z0-9-]+):[^=\[\]]*?=\s*'((?:[^'\\]|\\.)*)'`)
+
+// parseSTIXObject adds the values of an indicator's pattern, or
+// of an observable object like a domain-name or an ipv4-addr.
+func parseSTIXObject(o map[string]interface{}, add func(string, string)) {
+	t, _ := o["type"].(string)
+	if t == "indicator" {
+		pattern, _ := o["pattern"].(string)
+		for _, m := range stixPatternComparison.FindAllStringSubmatch(pattern, -1) {
+			add(m[1], strings.ReplaceAll(m[2], `\'`, `'`))
+		}
+		return
+	}
+	if v, ok := o["value"].(string); ok {
+		add(t, v)
+	}
+}
+
+// threatFeedEntries merges the indicators of the feeds of a lookup
+// into its current entries. Indicators keep the time they were first
+// seen and the feeds listing them, and are removed once missing from
+// all the feeds for maxAge.
+func threatFeedEntries(current LookupEntries, indicators map[string][]ThreatFeedName, maxAge time.Duration, now time.Time) LookupEntries {
+	entries := LookupEntries{}
+	for indicator, feeds := range indicators {
+		sort.Strings(feeds)
+		firstSeen := now.Unix()
+		if cur, ok := current[indicator]; ok {
+			if ts, ok := threatFeedTimestamp(cur["first_seen"]); ok {
+				firstSeen = ts
+			}
+		}
+		names := []interface{}{}
+		for _, f := range feeds {
+			names = append(names, f)
+		}
+		entries[indicator] = Dict{
+			"feeds":      names,
+			"first_seen": firstSeen,
+		}
+	}
+	for indicator, cur := range current {
+		if _, ok := entries[indicator]; ok {
+			continue
+		}
+		missingSince, ok := threatFeedTimestamp(cur["missing_since"])
+		if !ok {
+			missingSince = now.Unix()
+			mtd := Dict{}
+			for k, v := range cur {
+				mtd[k] = v
+			}
+			mtd["missing_since"] = missingSince
+			cur = mtd
+		}
+		if now.Sub(time.Unix(missingSince, 0)) >= maxAge {
+			continue
+		}
+		entries[indicator] = cur
+	}
+	return entries
+}
+
+func threatFeedTimestamp(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		return int64(n), true
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// syncThreatFeeds syncs the indicators of the feeds into their lookups,
+// one operation per feed. With IsForce, the lookups of the feeds which
+// are not in the config anymore are removed, reported as hive records.
+func (org Organization) syncThreatFeeds(feeds orgSyncThreatFeeds, options SyncOptions) ([]OrgSyncOperation, error) {
+	if !options.IsForce && len(feeds) == 0 {
+		return nil, nil
+	}
+	ops := []OrgSyncOperation{}
+	byLookup := map[LookupName][]ThreatFeedName{}
+	for name, feed := range feeds {
+		if err := feed.Validate(); err != nil {
+			return ops, fmt.Errorf("%s: %v", name, err)
+		}
+		lookup := feed.lookupName(name)
+		byLookup[lookup] = append(byLookup[lookup], name)
+	}
+	lookups := []LookupName{}
+	for lookup, names := range byLookup {
+		sort.Strings(names)
+		lookups = append(lookups, lookup)
+	}
+	sort.Strings(lookups)
+
+	for _, lookup := range lookups {
+		names := byLookup[lookup]
+		newOps, err := org.syncThreatFeedLookup(lookup, names, feeds, options)
+		for _, op := range newOps {
+			if op.Error != nil {
+				if ops, err = options.failOp(ops, op, op.Error); err != nil {
+					return ops, err
+				}
+				continue
+			}
+			ops = options.appendOp(ops, op)
+		}
+		if err != nil {
+			return ops, err
+		}
+	}
+
+	if !options.IsForce {
+		return ops, nil
+	}
+	records, err := NewHiveClient(&org).ListMtd(HiveArgs{
+		HiveName:     lookupHiveName,
+		PartitionKey: org.client.options.OID,
+	})
+	if err != nil {
+		return ops, err
+	}
+	stale := []LookupName{}
+	for key, record := range records {
+		if _, ok := byLookup[key]; !ok && arrayExistsInString(threatFeedTag, record.UsrMtd.Tags) {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(stale)
+	for _, key := range stale {
+		// The feeds of the lookup are gone from the config,
+		// so the lookup is what is reported as removed.
+		op := OrgSyncOperation{
+			ElementType: OrgSyncOperationElementType.Hives,
+			ElementName: lookupHiveName + "/" + key,
+			IsRemoved:   true,
+		}
+		if !options.IsDryRun {
+			if err := org.removeHiveConfigData(HiveArgs{HiveName: lookupHiveName, PartitionKey: org.client.options.OID, Key: key}); err != nil {
+				if ops, err = options.failOp(ops, op, err); err != nil {
+					return ops, err
+				}
+				continue
+			}
+		}
+		ops = options.appendOp(ops, op)
+	}
+	return ops, nil
+}
+
+// syncThreatFeedLookup syncs the feeds of a lookup. A feed failing
+// to be fetched fails its operations without changing the lookup,
+// since its indicators would otherwise start ageing out.
+func (org Organization) syncThreatFeedLookup(lookup LookupName, names []ThreatFeedName, feeds orgSyncThreatFeeds, options SyncOptions) ([]OrgSyncOperation, error) {
+	newOps := func(isAdded bool, isUpdated bool, err error) []OrgSyncOperation {
+		ops := []OrgSyncOperation{}
+		for _, name := range names {
+			ops = append(ops, OrgSyncOperation{
+				ElementType: OrgSyncOperationElementType.ThreatFeed,
+				ElementName: name,
+				IsAdded:     isAdded,
+				IsUpdated:   isUpdated,
+				Error:       err,
+			})
+		}
+		return ops
+	}
+
+	indicators := map[string][]ThreatFeedName{}
+	maxAge := time.Duration(0)
+	for _, name := range names {
+		feed := feeds[name]
+		fetched, err := org.FetchThreatFeed(feed)
+		if err != nil {
+			return newOps(false, false, fmt.Errorf("fetching %s: %v", name, err)), nil
+		}
+		for indicator := range fetched {
+			indicators[indicator] = append(indicators[indicator], name)
+		}
+		if age := time.Duration(feed.MaxAgeDays) * 24 * time.Hour; age > maxAge {
+			maxAge = age
+		}
+	}
+
+	current, err := org.LookupEntries(lookup)
+	isNew := err == ErrorResourceNotFound
+	if err != nil && !isNew {
+		return newOps(false, false, err), nil
+	}
+	wanted := threatFeedEntries(current, indicators, maxAge, time.Now())
+	if isNew {
+		if !options.IsDryRun {
+			err = org.addHiveConfigData(HiveArgs{HiveName: lookupHiveName, PartitionKey: org.client.options.OID, Key: lookup}, SyncHiveData{
+				Data:   LookupData(wanted),
+				UsrMtd: UsrMtd{Enabled: true, Tags: []string{threatFeedTag}},
+			})
+		}
+		return newOps(true, false, err), nil
+	}
+	delta := DiffLookupEntries(current, wanted)
+	if delta.IsEmpty() {
+		return newOps(false, false, nil), nil
+	}
+	if !options.IsDryRun {
+		if len(delta.Added)+len(delta.Removed) < len(wanted) {
+			err = org.ApplyLookupDelta(lookup, delta)
+		} else {
+			err = org.addHiveConfigData(HiveArgs{HiveName: lookupHiveName, PartitionKey: org.client.options.OID, Key: lookup}, SyncHiveData{
+				Data:   LookupData(wanted),
+				UsrMtd: UsrMtd{Enabled: true, Tags: []string{threatFeedTag}},
+			})
+		}
+	}
+	return newOps(true, true, err), nil
+}
+
+// RunThreatFeeds syncs the feeds every interval, the first time right
+// away, until the context is done. The results of every run are
+// reported to cb, if set.
+func (org Organization) RunThreatFeeds(ctx context.Context, feeds map[ThreatFeedName]ThreatFeed, interval time.Duration, options SyncOptions, cb func(ops []OrgSyncOperation, err error)) error {
+	if interval <= 0 {
+		return errors.New("the interval must be positive")
+	}
+	if options.Logger == nil {
+		options.Logger = org.logger
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ops, err := org.syncThreatFeeds(feeds, options)
+		if cb != nil {
+			cb(ops, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
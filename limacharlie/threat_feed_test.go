@@ -0,0 +1,185 @@
+package limacharlie
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestThreatFeed(t *testing.T, feed ThreatFeed, data string) map[string]struct{} {
+	indicators := map[string]struct{}{}
+	next, err := parseThreatFeed(feed, []byte(data), indicators)
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	return indicators
+}
+
+func TestParseThreatFeed(t *testing.T) {
+	a := assert.New(t)
+	a.Equal(map[string]struct{}{"evil.com": {}, "bad.org": {}}, parseTestThreatFeed(t, ThreatFeed{
+		Format: ThreatFeedFormats.Text,
+	}, "# bad domains\nevil.com\n\n bad.org \nevil.com\n"))
+
+	a.Equal(map[string]struct{}{"1.2.3.4": {}, "5.6.7.8": {}}, parseTestThreatFeed(t, ThreatFeed{
+		Format: ThreatFeedFormats.CSV,
+		Column: "ip",
+	}, "# generated daily\nfirst_seen,ip,port\n2024-01-01,1.2.3.4,443\n2024-01-02,5.6.7.8,80\n"))
+	a.Equal(map[string]struct{}{"443": {}, "80": {}}, parseTestThreatFeed(t, ThreatFeed{
+		Format: ThreatFeedFormats.CSV,
+		Column: "2",
+	}, "2024-01-01,1.2.3.4,443\n2024-01-02,5.6.7.8,80\n"))
+	_, err := parseThreatFeed(ThreatFeed{Format: ThreatFeedFormats.CSV, Column: "domain"}, []byte("ip\n1.2.3.4\n"), map[string]struct{}{})
+	a.Error(err)
+
+	a.Equal(map[string]struct{}{"evil.com": {}}, parseTestThreatFeed(t, ThreatFeed{
+		Format: ThreatFeedFormats.MISP,
+		Types:  []string{"domain"},
+	}, `{"response": {"Attribute": [{"type": "domain", "value": "evil.com"}, {"type": "ip-dst", "value": "1.2.3.4"}]}}`))
+	a.Equal(map[string]struct{}{"evil.com": {}, "1.2.3.4": {}}, parseTestThreatFeed(t, ThreatFeed{
+		Format: ThreatFeedFormats.MISP,
+	}, `{"response": [{"Event": {
+		"Attribute": [{"type": "domain", "value": "evil.com"}],
+		"Object": [{"Attribute": [{"type": "ip-dst", "value": "1.2.3.4"}]}]
+	}}]}`))
+
+	a.Equal(map[string]struct{}{"evil.com": {}, "1.2.3.4": {}, "bad.org": {}}, parseTestThreatFeed(t, ThreatFeed{
+		Format: ThreatFeedFormats.STIX,
+	}, `{"type": "bundle", "objects": [
+		{"type": "indicator", "pattern": "[domain-name:value = 'evil.com'] OR [ipv4-addr:value = '1.2.3.4']"},
+		{"type": "domain-name", "value": "bad.org"},
+		{"type": "malware", "name": "not an indicator"}
+	]}`))
+	a.Equal(map[string]struct{}{"1.2.3.4": {}}, parseTestThreatFeed(t, ThreatFeed{
+		Format: ThreatFeedFormats.STIX,
+		Types:  []string{"ipv4-addr"},
+	}, `{"objects": [{"type": "indicator", "pattern": "[domain-name:value = 'evil.com'] OR [ipv4-addr:value = '1.2.3.4']"}]}`))
+
+	// The indicators of text and CSV feeds have no type to filter on.
+	a.EqualError(ThreatFeed{
+		Format: ThreatFeedFormats.Text,
+		URL:    "https://feeds.example.com/domains.txt",
+		Types:  []string{"domain"},
+	}.Validate(), "types are not supported by text feeds")
+	a.NoError(ThreatFeed{
+		Format: ThreatFeedFormats.MISP,
+		URL:    "https://misp.example.com/attributes/restSearch",
+		Types:  []string{"domain"},
+	}.Validate())
+}
+
+func TestFetchThreatFeedTAXII(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal("application/taxii+json;version=2.1", r.Header.Get("Accept"))
+		a.Equal("Basic secret", r.Header.Get("Authorization"))
+		if r.URL.Query().Get("next") == "" {
+			return jsonResponse(http.StatusOK, `{"more": true, "next": "page-2", "objects": [{"type": "domain-name", "value": "evil.com"}]}`), nil
+		}
+		a.Equal("page-2", r.URL.Query().Get("next"))
+		return jsonResponse(http.StatusOK, `{"objects": [{"type": "domain-name", "value": "bad.org"}]}`), nil
+	}))
+	indicators, err := org.FetchThreatFeed(ThreatFeed{
+		Format:  ThreatFeedFormats.TAXII,
+		URL:     "https://taxii.example.com/api/collections/c1/objects/",
+		Headers: map[string]string{"Authorization": "Basic secret"},
+	})
+	a.NoError(err)
+	a.Equal(map[string]struct{}{"evil.com": {}, "bad.org": {}}, indicators)
+
+	_, err = org.FetchThreatFeed(ThreatFeed{Format: "xml", URL: "https://taxii.example.com/"})
+	a.Error(err)
+}
+
+func TestThreatFeedEntries(t *testing.T) {
+	a := assert.New(t)
+	now := time.Unix(1700000000, 0)
+	day := int64(24 * 60 * 60)
+	entries := threatFeedEntries(LookupEntries{
+		"kept.com":    {"feeds": []interface{}{"a"}, "first_seen": float64(now.Unix() - day)},
+		"missing.com": {"feeds": []interface{}{"a"}, "first_seen": float64(now.Unix() - 10*day)},
+		"aged.com":    {"feeds": []interface{}{"a"}, "first_seen": float64(now.Unix() - 10*day), "missing_since": float64(now.Unix() - 3*day)},
+		"back.com":    {"feeds": []interface{}{"a"}, "first_seen": float64(now.Unix() - 10*day), "missing_since": float64(now.Unix() - day)},
+	}, map[string][]ThreatFeedName{
+		"kept.com": {"b", "a"},
+		"back.com": {"a"},
+		"new.com":  {"b"},
+	}, 2*24*time.Hour, now)
+
+	a.Equal(LookupEntries{
+		"kept.com":    {"feeds": []interface{}{"a", "b"}, "first_seen": now.Unix() - day},
+		"back.com":    {"feeds": []interface{}{"a"}, "first_seen": now.Unix() - 10*day},
+		"new.com":     {"feeds": []interface{}{"b"}, "first_seen": now.Unix()},
+		"missing.com": {"feeds": []interface{}{"a"}, "first_seen": float64(now.Unix() - 10*day), "missing_since": now.Unix()},
+	}, entries)
+
+	// Without a max age, missing indicators are removed right away.
+	entries = threatFeedEntries(LookupEntries{"missing.com": {}}, nil, 0, now)
+	a.Empty(entries)
+}
+
+func TestSyncThreatFeeds(t *testing.T) {
+	a := assert.New(t)
+	created := map[string]interface{}{}
+	removed := []string{}
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		r.ParseForm()
+		switch r.URL.Host + r.URL.Path {
+		case "feeds.example.com/domains.txt":
+			return jsonResponse(http.StatusOK, "evil.com\nbad.org\n"), nil
+		case "feeds.example.com/more.txt":
+			return jsonResponse(http.StatusOK, "evil.com\n"), nil
+		case "feeds.example.com/down.txt":
+			return jsonResponse(http.StatusServiceUnavailable, ""), nil
+		}
+		switch r.URL.Path {
+		case "/v1/hive/lookup/" + vcrTestOID + "/domains/data":
+			if r.Method == http.MethodGet {
+				return jsonResponse(http.StatusNotFound, `{"error": "not found"}`), nil
+			}
+			a.NoError(json.Unmarshal([]byte(r.Form.Get("data")), &created))
+			mtd := UsrMtd{}
+			a.NoError(json.Unmarshal([]byte(r.Form.Get("usr_mtd")), &mtd))
+			a.Equal([]string{"threat-feed"}, mtd.Tags)
+			return jsonResponse(http.StatusOK, `{}`), nil
+		case "/v1/hive/lookup/" + vcrTestOID:
+			return jsonResponse(http.StatusOK, `{
+				"domains": {"usr_mtd": {"tags": ["threat-feed"]}},
+				"old-feed": {"usr_mtd": {"tags": ["threat-feed"]}},
+				"manual": {"usr_mtd": {"tags": []}}
+			}`), nil
+		case "/v1/hive/lookup/" + vcrTestOID + "/old-feed":
+			a.Equal(http.MethodDelete, r.Method)
+			removed = append(removed, "old-feed")
+			return jsonResponse(http.StatusOK, `{}`), nil
+		}
+		t.Errorf("unexpected request: %s %s", r.Method, r.URL.String())
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	ops, err := org.syncThreatFeeds(orgSyncThreatFeeds{
+		"domains": {Format: ThreatFeedFormats.Text, URL: "https://feeds.example.com/domains.txt"},
+		"more":    {Format: ThreatFeedFormats.Text, URL: "https://feeds.example.com/more.txt", Lookup: "domains"},
+		"down":    {Format: ThreatFeedFormats.Text, URL: "https://feeds.example.com/down.txt"},
+	}, SyncOptions{IsForce: true, ContinueOnError: true})
+	a.NoError(err)
+	a.Len(ops, 4)
+	names := map[string]OrgSyncOperation{}
+	for _, op := range ops {
+		names[op.ElementName] = op
+	}
+	a.Error(names["down"].Error)
+	a.True(names["domains"].IsAdded)
+	a.True(names["more"].IsAdded)
+	a.True(names["lookup/old-feed"].IsRemoved)
+	a.Equal(OrgSyncOperationElementType.Hives, names["lookup/old-feed"].ElementType)
+	a.Equal([]string{"old-feed"}, removed)
+
+	entries, ok := lookupEntriesFromData(created)
+	a.True(ok)
+	a.Len(entries, 2)
+	a.Equal([]interface{}{"domains", "more"}, entries["evil.com"]["feeds"])
+	a.Equal([]interface{}{"domains"}, entries["bad.org"]["feeds"])
+}
@@ -0,0 +1,188 @@
+package limacharlie
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+type TimelineEntryKind = string
+
+var TimelineEntryKinds = struct {
+	Event     TimelineEntryKind
+	Detection TimelineEntryKind
+	Audit     TimelineEntryKind
+}{
+	Event:     "event",
+	Detection: "detection",
+	Audit:     "audit",
+}
+
+// TimelineEntry is an event, a detection or an audit entry of a
+// Timeline, only the field of its Kind being set.
+type TimelineEntry struct {
+	Kind      TimelineEntryKind `json:"kind"`
+	Time      time.Time         `json:"time"`
+	Event     *Event            `json:"event,omitempty"`
+	Detection *Detection        `json:"detection,omitempty"`
+	Audit     *AuditEntry       `json:"audit,omitempty"`
+}
+
+// TimelineQuery selects the entries of a Timeline. At least one of
+// SensorID or InvestigationID is required, events are only included
+// with a SensorID.
+type TimelineQuery struct {
+	SensorID string
+	// InvestigationID of the events and detections, including
+	// those of its contexts, like "<id>/<context>".
+	InvestigationID string
+	Start           time.Time
+	End             time.Time
+
+	// Kinds of entries included, all by default.
+	Kinds []TimelineEntryKind
+	// EventTypes of the events included, all by default.
+	EventTypes []EventType
+	// DetectionCategories of the detections included, all by default.
+	DetectionCategories []string
+	// Filter, if set, is called with every entry, only those
+	// for which it returns true are included.
+	Filter func(entry TimelineEntry) bool
+}
+
+func (q TimelineQuery) includes(kind TimelineEntryKind) bool {
+	return len(q.Kinds) == 0 || arrayExistsInString(kind, q.Kinds)
+}
+
+func (q TimelineQuery) matchesInvestigation(invID string) bool {
+	return q.InvestigationID == "" || MatchesInvestigation(invID, q.InvestigationID)
+}
+
+func (q TimelineQuery) matchesSensor(sid string) bool {
+	return q.SensorID == "" || sid == q.SensorID
+}
+
+// auditMentions returns true if any value of the
+// metadata of an audit entry is the string s.
+func auditMentions(v interface{}, s string) bool {
+	switch val := v.(type) {
+	case string:
+		return val == s
+	case Dict:
+		return auditMentions(map[string]interface{}(val), s)
+	case map[string]interface{}:
+		for _, e := range val {
+			if auditMentions(e, s) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, e := range val {
+			if auditMentions(e, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func timelineTime(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// Timeline merges the events of a sensor, the detections and the audit
+// entries of the Org over a time window into a single chronologically
+// ordered list, like for incident reports. Audit entries are included
+// when their metadata references the sensor or the investigation.
+func (org Organization) Timeline(ctx context.Context, q TimelineQuery) ([]TimelineEntry, error) {
+	if q.SensorID == "" && q.InvestigationID == "" {
+		return nil, errors.New("a sensor or an investigation id is required")
+	}
+	if q.End.Before(q.Start) {
+		return nil, errors.New("the end of the timeline is before its start")
+	}
+	entries := []TimelineEntry{}
+	add := func(e TimelineEntry) {
+		if q.Filter == nil || q.Filter(e) {
+			entries = append(entries, e)
+		}
+	}
+	iterate := func(path string, cb func(r Dict) error) error {
+		var err error
+		org.insightRecordsIter(ctx, path, q.Start, q.End)(func(r Dict, e error) bool {
+			if e != nil {
+				err = e
+				return false
+			}
+			err = cb(r)
+			return err == nil
+		})
+		return err
+	}
+
+	if q.SensorID != "" && q.includes(TimelineEntryKinds.Event) {
+		if err := iterate(fmt.Sprintf("insight/%s/%s", org.client.options.OID, q.SensorID), func(r Dict) error {
+			e := Event{}
+			if err := r.UnMarshalToStruct(&e); err != nil {
+				return err
+			}
+			if !q.matchesInvestigation(e.Routing.InvID) {
+				return nil
+			}
+			if len(q.EventTypes) != 0 && !arrayExistsInString(e.Routing.EventType, q.EventTypes) {
+				return nil
+			}
+			add(TimelineEntry{Kind: TimelineEntryKinds.Event, Time: timelineTime(e.Routing.EventTime), Event: &e})
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("events: %v", err)
+		}
+	}
+
+	if q.includes(TimelineEntryKinds.Detection) {
+		if err := iterate(fmt.Sprintf("insight/%s/detections", org.client.options.OID), func(r Dict) error {
+			d := Detection{}
+			if err := r.UnMarshalToStruct(&d); err != nil {
+				return err
+			}
+			if !q.matchesSensor(d.Routing.SID) || !q.matchesInvestigation(d.Routing.InvID) {
+				return nil
+			}
+			if len(q.DetectionCategories) != 0 && !arrayExistsInString(d.Category, q.DetectionCategories) {
+				return nil
+			}
+			add(TimelineEntry{Kind: TimelineEntryKinds.Detection, Time: timelineTime(d.TimeStamp), Detection: &d})
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("detections: %v", err)
+		}
+	}
+
+	if q.includes(TimelineEntryKinds.Audit) {
+		if err := iterate(fmt.Sprintf("insight/%s/audit", org.client.options.OID), func(r Dict) error {
+			audit := AuditEntry{}
+			if err := r.UnMarshalToStruct(&audit); err != nil {
+				return err
+			}
+			mtd := map[string]interface{}(audit.Metadata)
+			if q.SensorID != "" && !auditMentions(mtd, q.SensorID) {
+				return nil
+			}
+			if q.InvestigationID != "" && !auditMentions(mtd, q.InvestigationID) {
+				return nil
+			}
+			add(TimelineEntry{Kind: TimelineEntryKinds.Audit, Time: timelineTime(audit.TimeStamp), Audit: &audit})
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("audit: %v", err)
+		}
+	}
+
+	// Entries of the same time keep the order of their kinds.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+	return entries, nil
+}
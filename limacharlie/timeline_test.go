@@ -0,0 +1,73 @@
+package limacharlie
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeline(t *testing.T) {
+	a := assert.New(t)
+	org := newTestOrgWithTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		a.Equal("100", r.URL.Query().Get("start"))
+		a.Equal("200", r.URL.Query().Get("end"))
+		switch r.URL.Path {
+		case fmt.Sprintf("/v1/insight/%s/s1", vcrTestOID):
+			return jsonResponse(http.StatusOK, `{"events": [
+				{"routing": {"sid": "s1", "event_type": "NEW_PROCESS", "event_time": 150000, "investigation_id": "inv/ctx"}, "event": {}},
+				{"routing": {"sid": "s1", "event_type": "DNS_REQUEST", "event_time": 120000, "investigation_id": "inv"}, "event": {}},
+				{"routing": {"sid": "s1", "event_type": "NEW_PROCESS", "event_time": 110000, "investigation_id": "other"}, "event": {}}
+			]}`), nil
+		case fmt.Sprintf("/v1/insight/%s/detections", vcrTestOID):
+			return jsonResponse(http.StatusOK, `{"detects": [
+				{"cat": "evil", "ts": 130000, "routing": {"sid": "s1", "investigation_id": "inv"}},
+				{"cat": "evil", "ts": 140000, "routing": {"sid": "s2", "investigation_id": "inv"}}
+			]}`), nil
+		case fmt.Sprintf("/v1/insight/%s/audit", vcrTestOID):
+			return jsonResponse(http.StatusOK, `{"audit": [
+				{"etype": "task", "msg": "tasked", "ts": 115000, "mtd": {"sid": "s1", "task": {"investigation_id": "inv"}}},
+				{"etype": "task", "msg": "tasked", "ts": 116000, "mtd": {"sid": "s2", "investigation_id": "inv"}}
+			]}`), nil
+		}
+		t.Errorf("unexpected request: %s", r.URL.Path)
+		return jsonResponse(http.StatusNotFound, `{}`), nil
+	}))
+
+	q := TimelineQuery{
+		SensorID:        "s1",
+		InvestigationID: "inv",
+		Start:           time.Unix(100, 0),
+		End:             time.Unix(200, 0),
+	}
+	entries, err := org.Timeline(context.Background(), q)
+	a.NoError(err)
+	kinds := []string{}
+	for _, e := range entries {
+		kinds = append(kinds, fmt.Sprintf("%s@%d", e.Kind, e.Time.Unix()))
+	}
+	a.Equal([]string{"audit@115", "event@120", "detection@130", "event@150"}, kinds)
+	a.Equal("DNS_REQUEST", entries[1].Event.Routing.EventType)
+	a.Equal("evil", entries[2].Detection.Category)
+	a.Equal("tasked", entries[0].Audit.Message)
+
+	q.Kinds = []TimelineEntryKind{TimelineEntryKinds.Event}
+	q.EventTypes = []EventType{EventTypes.NewProcess}
+	entries, err = org.Timeline(context.Background(), q)
+	a.NoError(err)
+	a.Len(entries, 1)
+	a.Equal(int64(150), entries[0].Time.Unix())
+
+	q.Kinds = nil
+	q.EventTypes = nil
+	q.Filter = func(e TimelineEntry) bool { return e.Kind != TimelineEntryKinds.Audit }
+	entries, err = org.Timeline(context.Background(), q)
+	a.NoError(err)
+	a.Len(entries, 3)
+
+	_, err = org.Timeline(context.Background(), TimelineQuery{})
+	a.Error(err)
+}
@@ -0,0 +1,294 @@
+package limacharlie
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	uspDefaultBatchSize     = 500
+	uspDefaultFlushInterval = 5 * time.Second
+	uspRetryBackoff         = time.Second
+	uspMaxRetryBackoff      = 30 * time.Second
+)
+
+type USPProtocol = string
+
+// USPProtocols is all the transports a USPClient can use.
+var USPProtocols = struct {
+	HTTPS USPProtocol
+}{
+	HTTPS: "https",
+}
+
+// USPClientOptions configures how custom telemetry is shipped.
+type USPClientOptions struct {
+	// Ingestion key used to authenticate, if not set the
+	// JWT of the Organization is used instead.
+	IngestionKey string
+
+	// Full URL to ship data to, defaults to the ingestion
+	// URL of the Organization.
+	IngestionURL string
+
+	// Transport to use, only HTTPS is supported.
+	Protocol USPProtocol
+
+	// Unique key identifying the cloud sensor the data is attributed to.
+	SensorSeedKey string
+	// Hostname reported for the cloud sensor.
+	Hostname string
+	// Format of the data, like "json" or "text".
+	Platform string
+	// Free form name of the source of the data.
+	Source string
+
+	// Number of elements buffered before being shipped.
+	BatchSize int
+	// Maximum time elements are buffered before being shipped.
+	FlushInterval time.Duration
+	// Maximum number of elements buffered, including those being
+	// shipped, while the endpoint cannot be reached. Elements shipped
+	// beyond it are dropped with ErrorUSPBufferFull. Defaults to 10
+	// batches.
+	MaxPending int
+	// Compress the batches with gzip.
+	IsCompressed bool
+}
+
+// USPClient ships custom telemetry into LimaCharlie as a cloud sensor.
+type USPClient struct {
+	org  *Organization
+	opts USPClientOptions
+
+	httpClient *http.Client
+	sleep      func(time.Duration)
+
+	m       sync.Mutex
+	pending [][]byte
+	// Number of elements of the batches being sent.
+	inFlight int
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewUSPClient creates a client shipping data for the organization
+// and starts its background flushing.
+func NewUSPClient(org *Organization, opts USPClientOptions) (*USPClient, error) {
+	if opts.Protocol == "" {
+		opts.Protocol = USPProtocols.HTTPS
+	}
+	if opts.Protocol != USPProtocols.HTTPS {
+		return nil, fmt.Errorf("unknown usp protocol: %s", opts.Protocol)
+	}
+	if opts.SensorSeedKey == "" {
+		return nil, errors.New("sensor seed key required")
+	}
+	if opts.Platform == "" {
+		opts.Platform = PlatformStrings[Platforms.JSON]
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = uspDefaultBatchSize
+	}
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = uspDefaultFlushInterval
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 10 * opts.BatchSize
+	}
+	if opts.IngestionURL == "" {
+		urls, err := org.GetURLs()
+		if err != nil {
+			return nil, err
+		}
+		host, ok := urls["ingestion"]
+		if !ok {
+			return nil, errors.New("no ingestion url available for org")
+		}
+		opts.IngestionURL = fmt.Sprintf("https://%s/ingest", host)
+	}
+
+	c := &USPClient{
+		org:        org,
+		opts:       opts,
+		httpClient: org.client.httpClient(30 * time.Second),
+		sleep:      time.Sleep,
+		stop:       make(chan struct{}),
+	}
+	c.wg.Add(1)
+	go c.flushLoop()
+	return c, nil
+}
+
+// ShipJSON queues a JSON element to be shipped.
+func (c *USPClient) ShipJSON(data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return c.ship(b)
+}
+
+// ShipText queues a line of text to be shipped.
+func (c *USPClient) ShipText(line string) error {
+	return c.ship([]byte(strings.TrimRight(line, "\r\n")))
+}
+
+func (c *USPClient) ship(data []byte) error {
+	c.m.Lock()
+	if len(c.pending)+c.inFlight >= c.opts.MaxPending {
+		c.m.Unlock()
+		return ErrorUSPBufferFull
+	}
+	c.pending = append(c.pending, data)
+	isFull := len(c.pending) >= c.opts.BatchSize
+	c.m.Unlock()
+	if isFull {
+		return c.Flush()
+	}
+	return nil
+}
+
+func (c *USPClient) flushLoop() {
+	defer c.wg.Done()
+	t := time.NewTicker(c.opts.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-c.stop:
+			return
+		case <-t.C:
+			if err := c.Flush(); err != nil {
+				c.org.logger.Error(fmt.Sprintf("usp flush: %v", err))
+			}
+		}
+	}
+}
+
+// Flush ships all the pending elements right away.
+func (c *USPClient) Flush() error {
+	c.m.Lock()
+	batch := c.pending
+	c.pending = nil
+	c.inFlight += len(batch)
+	c.m.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	err := c.send(bytes.Join(batch, []byte{'\n'}))
+	c.m.Lock()
+	c.inFlight -= len(batch)
+	if err != nil {
+		// Put the batch back so it is retried on the next flush.
+		c.pending = append(batch, c.pending...)
+	}
+	c.m.Unlock()
+	return err
+}
+
+// Close flushes pending elements and stops the client,
+// it is safe to call more than once.
+func (c *USPClient) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.stop)
+		c.wg.Wait()
+	})
+	return c.Flush()
+}
+
+func (c *USPClient) send(payload []byte) error {
+	body := payload
+	if c.opts.IsCompressed {
+		buf := bytes.Buffer{}
+		z := gzip.NewWriter(&buf)
+		if _, err := z.Write(payload); err != nil {
+			return err
+		}
+		if err := z.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	backoff := uspRetryBackoff
+	for i := 1; ; i++ {
+		statusCode, err := c.post(body)
+		if err == nil {
+			return nil
+		}
+		if statusCode == http.StatusUnauthorized && c.opts.IngestionKey == "" {
+			// The JWT may have expired, refresh and retry.
+			if _, rerr := c.org.client.RefreshJWT(c.org.client.options.JWTExpiryTime); rerr != nil {
+				return rerr
+			}
+		} else if !isUSPRetryable(statusCode) {
+			return err
+		}
+		if i >= restRetries {
+			return err
+		}
+		if statusCode != http.StatusUnauthorized {
+			c.sleep(backoff)
+			backoff *= 2
+			if backoff > uspMaxRetryBackoff {
+				backoff = uspMaxRetryBackoff
+			}
+		}
+	}
+}
+
+// isUSPRetryable returns true if a failed ingestion may succeed
+// when retried: network errors (no status), throttling and server errors.
+func isUSPRetryable(statusCode int) bool {
+	if statusCode == 0 || statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return statusCode >= 500
+}
+
+func (c *USPClient) post(body []byte) (int, error) {
+	r, err := http.NewRequest(http.MethodPost, c.opts.IngestionURL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	r.Header.Set("User-Agent", "limacharlie-sdk")
+	if c.opts.IngestionKey != "" {
+		creds := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", c.org.GetOID(), c.opts.IngestionKey)))
+		r.Header.Set("Authorization", fmt.Sprintf("Basic %s", creds))
+	} else {
+		r.Header.Set("Authorization", fmt.Sprintf("bearer %s", c.org.GetCurrentJWT()))
+	}
+	r.Header.Set("lc-sensor-seed-key", c.opts.SensorSeedKey)
+	r.Header.Set("lc-platform", c.opts.Platform)
+	if c.opts.Hostname != "" {
+		r.Header.Set("lc-hostname", c.opts.Hostname)
+	}
+	if c.opts.Source != "" {
+		r.Header.Set("lc-source", c.opts.Source)
+	}
+	if c.opts.IsCompressed {
+		r.Header.Set("Content-Encoding", "gzip")
+	}
+
+	resp, err := c.httpClient.Do(r)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		details, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, NewRESTError(fmt.Sprintf("%s: %s", resp.Status, string(details)))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,154 @@
+package limacharlie
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUSPClientBatching(t *testing.T) {
+	a := assert.New(t)
+
+	m := sync.Mutex{}
+	received := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Equal("seed", r.Header.Get("lc-sensor-seed-key"))
+		a.True(strings.HasPrefix(r.Header.Get("Authorization"), "Basic "))
+		z, err := gzip.NewReader(r.Body)
+		if !a.NoError(err) {
+			return
+		}
+		b, _ := ioutil.ReadAll(z)
+		m.Lock()
+		received = append(received, string(b))
+		m.Unlock()
+	}))
+	defer srv.Close()
+
+	c, err := NewClient(ClientOptions{OID: "11111111-2222-3333-4444-555555555555"}, nil)
+	a.NoError(err)
+	org, _ := NewOrganization(c)
+
+	usp, err := NewUSPClient(org, USPClientOptions{
+		IngestionKey:  "key",
+		IngestionURL:  srv.URL,
+		SensorSeedKey: "seed",
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		IsCompressed:  true,
+	})
+	a.NoError(err)
+
+	a.NoError(usp.ShipJSON(Dict{"a": 1}))
+	a.NoError(usp.ShipText("line\n"))
+	a.NoError(usp.ShipJSON(Dict{"b": 2}))
+	a.NoError(usp.Close())
+
+	a.Equal([]string{"{\"a\":1}\nline", "{\"b\":2}"}, received)
+}
+
+func newTestUSPClient(t *testing.T, url string) (*USPClient, *[]time.Duration) {
+	c, err := NewClient(ClientOptions{OID: "11111111-2222-3333-4444-555555555555"}, nil)
+	assert.NoError(t, err)
+	org, _ := NewOrganization(c)
+	usp, err := NewUSPClient(org, USPClientOptions{
+		IngestionKey:  "key",
+		IngestionURL:  url,
+		SensorSeedKey: "seed",
+		FlushInterval: time.Hour,
+	})
+	assert.NoError(t, err)
+	sleeps := []time.Duration{}
+	usp.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return usp, &sleeps
+}
+
+func TestUSPClientRetries(t *testing.T) {
+	a := assert.New(t)
+
+	for _, tc := range []struct {
+		name     string
+		statuses []int
+		isError  bool
+		calls    int
+		sleeps   []time.Duration
+	}{
+		{"success", []int{200}, false, 1, []time.Duration{}},
+		{"bad request is not retried", []int{400}, true, 1, []time.Duration{}},
+		{"forbidden is not retried", []int{403}, true, 1, []time.Duration{}},
+		{"server errors back off", []int{503, 500, 200}, false, 3, []time.Duration{time.Second, 2 * time.Second}},
+		{"throttling backs off", []int{429, 200}, false, 2, []time.Duration{time.Second}},
+		{"retries are bounded", []int{503, 503, 503, 200}, true, 3, []time.Duration{time.Second, 2 * time.Second}},
+	} {
+		calls := 0
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.statuses[calls])
+			calls++
+		}))
+		usp, sleeps := newTestUSPClient(t, srv.URL)
+
+		a.NoError(usp.ShipText("line"))
+		err := usp.Flush()
+		a.Equal(tc.isError, err != nil, tc.name)
+		a.Equal(tc.calls, calls, tc.name)
+		a.Equal(tc.sleeps, *sleeps, tc.name)
+
+		srv.Close()
+	}
+}
+
+func TestUSPClientRetriesNetworkErrors(t *testing.T) {
+	a := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := srv.URL
+	srv.Close()
+
+	usp, sleeps := newTestUSPClient(t, url)
+	a.NoError(usp.ShipText("line"))
+	a.Error(usp.Flush())
+	a.Equal([]time.Duration{time.Second, 2 * time.Second}, *sleeps)
+}
+
+func TestUSPClientCloseTwice(t *testing.T) {
+	a := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	usp, _ := newTestUSPClient(t, srv.URL)
+	a.NoError(usp.Close())
+	a.NotPanics(func() { a.NoError(usp.Close()) })
+}
+
+func TestUSPClientMaxPending(t *testing.T) {
+	a := assert.New(t)
+
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	usp, _ := newTestUSPClient(t, srv.URL)
+	usp.opts.MaxPending = 2
+
+	a.NoError(usp.ShipText("line 1"))
+	a.NoError(usp.ShipText("line 2"))
+	a.Equal(ErrorUSPBufferFull, usp.ShipText("line 3"))
+
+	// The batch failing to ship is kept, still filling the buffer.
+	a.Error(usp.Flush())
+	a.Equal(ErrorUSPBufferFull, usp.ShipText("line 3"))
+
+	status = http.StatusOK
+	a.NoError(usp.Flush())
+	a.NoError(usp.ShipText("line 3"))
+}
@@ -0,0 +1,195 @@
+package limacharlie
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type VCRModeType = string
+
+// VCRMode is whether a VCRTransport captures or replays interactions.
+var VCRMode = struct {
+	Record VCRModeType
+	Replay VCRModeType
+}{
+	Record: "record",
+	Replay: "replay",
+}
+
+const vcrRedacted = "redacted"
+
+// VCRInteraction is a request and its response as stored in a fixture.
+// Request bodies and headers are never stored since they may hold secrets.
+type VCRInteraction struct {
+	Method      string `json:"method"`
+	URL         string `json:"url"`
+	StatusCode  int    `json:"status_code"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body"`
+}
+
+// VCRTransport is an http.RoundTripper recording API interactions
+// to a fixture file or replaying them from it, for use as
+// ClientOptions.Transport in tests.
+//
+// Replayed requests are matched on their method and URL, each recorded
+// interaction is used once, in order, so tests replayed must issue
+// the same requests as when they were recorded.
+type VCRTransport struct {
+	Mode VCRModeType
+	Path string
+
+	// Transport performing the requests when recording,
+	// defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+
+	// Replacements are applied to the URLs and response bodies
+	// recorded, like the real OID with a placeholder one. When
+	// replaying, the client should be configured with the placeholders.
+	Replacements map[string]string
+
+	// JWTURL is the endpoint whose tokens are redacted from
+	// recordings, defaults to the one of DefaultEndpoints.
+	JWTURL string
+
+	mutex        sync.Mutex
+	interactions []VCRInteraction
+	used         []bool
+}
+
+// NewVCRTransport creates a transport for the fixture at path,
+// loading it when replaying.
+func NewVCRTransport(path string, mode VCRModeType) (*VCRTransport, error) {
+	t := &VCRTransport{
+		Mode:         mode,
+		Path:         path,
+		Replacements: map[string]string{},
+	}
+	switch mode {
+	case VCRMode.Record:
+	case VCRMode.Replay:
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(data, &t.interactions); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %v", path, err)
+		}
+		t.used = make([]bool, len(t.interactions))
+	default:
+		return nil, fmt.Errorf("unknown vcr mode: %s", mode)
+	}
+	return t, nil
+}
+
+func (t *VCRTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if t.Mode == VCRMode.Replay {
+		return t.replay(r)
+	}
+	return t.record(r)
+}
+
+func (t *VCRTransport) sanitize(s string) string {
+	for from, to := range t.Replacements {
+		if from == "" {
+			continue
+		}
+		s = strings.ReplaceAll(s, from, to)
+	}
+	return s
+}
+
+func (t *VCRTransport) record(r *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	resp, err := transport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	body := t.sanitize(string(data))
+	jwtURL := t.JWTURL
+	if jwtURL == "" {
+		jwtURL = DefaultEndpoints.JWT
+	}
+	if strings.HasPrefix(r.URL.String(), jwtURL) {
+		// Never store a usable token.
+		body = fmt.Sprintf(`{"jwt":"%s"}`, vcrRedacted)
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.interactions = append(t.interactions, VCRInteraction{
+		Method:      r.Method,
+		URL:         t.sanitize(r.URL.String()),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	})
+	return resp, nil
+}
+
+func (t *VCRTransport) replay(r *http.Request) (*http.Response, error) {
+	if r.Body != nil {
+		r.Body.Close()
+	}
+	url := t.sanitize(r.URL.String())
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for i, interaction := range t.interactions {
+		if t.used[i] || interaction.Method != r.Method || interaction.URL != url {
+			continue
+		}
+		t.used[i] = true
+		header := http.Header{}
+		if interaction.ContentType != "" {
+			header.Set("Content-Type", interaction.ContentType)
+		}
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", interaction.StatusCode, http.StatusText(interaction.StatusCode)),
+			StatusCode:    interaction.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        header,
+			Body:          ioutil.NopCloser(strings.NewReader(interaction.Body)),
+			ContentLength: int64(len(interaction.Body)),
+			Request:       r,
+		}, nil
+	}
+	return nil, fmt.Errorf("no recorded interaction for %s %s in %s", r.Method, url, t.Path)
+}
+
+// Interactions returns the interactions recorded or loaded.
+func (t *VCRTransport) Interactions() []VCRInteraction {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	interactions := make([]VCRInteraction, len(t.interactions))
+	copy(interactions, t.interactions)
+	return interactions
+}
+
+// Save writes the interactions recorded to the fixture file.
+func (t *VCRTransport) Save() error {
+	if t.Mode != VCRMode.Record {
+		return nil
+	}
+	data, err := json.MarshalIndent(t.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(t.Path, data, 0644)
+}
@@ -0,0 +1,167 @@
+package limacharlie
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	vcrTestOID = "00000000-0000-0000-0000-000000000001"
+	vcrTestKey = "00000000-0000-0000-0000-000000000002"
+)
+
+// getTestOrgFromFixture returns an Org for the test according to the
+// "_VCR_MODE" environment variable. When "record", the test runs against
+// the live Org and its interactions are saved to testdata/fixtures. When
+// "replay", they are served from the fixture and no credentials are
+// needed, the test is skipped if it has no fixture. Otherwise it behaves
+// like getTestOrgFromEnv, or replays when there are no credentials.
+func getTestOrgFromFixture(t *testing.T, a *assert.Assertions) *Organization {
+	mode := os.Getenv("_VCR_MODE")
+	if mode == "" {
+		if os.Getenv("_OID") != "" {
+			return getTestOrgFromEnv(a)
+		}
+		mode = VCRMode.Replay
+	}
+	path := filepath.Join("testdata", "fixtures", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+
+	var opts ClientOptions
+	var vcr *VCRTransport
+	var err error
+	switch mode {
+	case VCRMode.Record:
+		opts = getTestClientOpts(a)
+		vcr, err = NewVCRTransport(path, mode)
+		a.NoError(err)
+		vcr.Replacements[opts.OID] = vcrTestOID
+		vcr.Replacements[opts.APIKey] = vcrTestKey
+		t.Cleanup(func() {
+			a.NoError(os.MkdirAll(filepath.Dir(path), 0755))
+			a.NoError(vcr.Save())
+		})
+	case VCRMode.Replay:
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("no fixture recorded: %s", path)
+		}
+		opts = ClientOptions{OID: vcrTestOID, APIKey: vcrTestKey}
+		vcr, err = NewVCRTransport(path, mode)
+		a.NoError(err)
+	default:
+		a.FailNow("unknown _VCR_MODE: " + mode)
+	}
+	opts.Transport = vcr
+	c, err := NewClient(opts, nil)
+	a.NoError(err)
+	org, err := NewOrganization(c)
+	a.NoError(err)
+	return org
+}
+
+// waitForOrg sleeps for d, letting the Org apply asynchronous changes,
+// unless its interactions are replayed, the fixture having been
+// recorded after the changes applied.
+func waitForOrg(org *Organization, d time.Duration) {
+	if vcr, ok := org.client.options.Transport.(*VCRTransport); ok && vcr.Mode == VCRMode.Replay {
+		return
+	}
+	time.Sleep(d)
+}
+
+func TestVCRRecordReplay(t *testing.T) {
+	a := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"oid":"real-oid","path":"` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "fixture.json")
+	rec, err := NewVCRTransport(path, VCRMode.Record)
+	a.NoError(err)
+	rec.Replacements["real-oid"] = "test-oid"
+
+	hc := &http.Client{Transport: rec}
+	for _, p := range []string{"/first", "/second", "/first"} {
+		resp, err := hc.Get(server.URL + p)
+		a.NoError(err)
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		// The caller gets the real response.
+		a.Contains(string(data), "real-oid")
+	}
+	a.NoError(rec.Save())
+	a.Len(rec.Interactions(), 3)
+	a.Contains(rec.Interactions()[0].Body, "test-oid")
+	a.NotContains(rec.Interactions()[0].Body, "real-oid")
+
+	rep, err := NewVCRTransport(path, VCRMode.Replay)
+	a.NoError(err)
+	hc = &http.Client{Transport: rep}
+	for _, p := range []string{"/second", "/first", "/first"} {
+		resp, err := hc.Get(server.URL + p)
+		a.NoError(err)
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		a.Equal(http.StatusOK, resp.StatusCode)
+		a.Equal(`{"oid":"test-oid","path":"`+p+`"}`, string(data))
+	}
+	// Each interaction is only replayed once.
+	_, err = hc.Get(server.URL + "/first")
+	a.Error(err)
+}
+
+func TestVCRRedactsJWT(t *testing.T) {
+	a := assert.New(t)
+	rec, err := NewVCRTransport(filepath.Join(t.TempDir(), "fixture.json"), VCRMode.Record)
+	a.NoError(err)
+	rec.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"jwt":"secret-token"}`)),
+		}, nil
+	})
+	c := &Client{options: ClientOptions{APIKey: vcrTestKey, OID: vcrTestOID, Transport: rec}}
+	jwt, err := c.RefreshJWT(0)
+	a.NoError(err)
+	a.Equal("secret-token", jwt)
+	a.Len(rec.Interactions(), 1)
+	a.NotContains(rec.Interactions()[0].Body, "secret-token")
+}
+
+type roundTripFunc func(r *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+// newTestOrgWithTransport returns an Org whose requests
+// are all served by the transport.
+func newTestOrgWithTransport(rt http.RoundTripper) *Organization {
+	c := &Client{
+		options: ClientOptions{OID: vcrTestOID, APIKey: vcrTestKey, JWT: "jwt", Transport: rt},
+		logger:  &LCLoggerEmpty{},
+	}
+	org, _ := NewOrganization(c)
+	return org
+}
+
+// jsonResponse is an API response with the body.
+func jsonResponse(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
@@ -0,0 +1,226 @@
+package limacharlie
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// WebhookSignatureHeader is the header the webhook output
+	// module uses to ship the hex HMAC-SHA256 of the body
+	// signed with the output's secret_key.
+	WebhookSignatureHeader = "lc-signature"
+
+	maxWebhookBodySize = 32 * 1024 * 1024
+)
+
+// ErrorInvalidWebhookSignature is returned when a webhook is not
+// properly signed or authenticated.
+var ErrorInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// ErrorWebhookNotAuthenticated is returned for the webhooks received by a
+// WebhookReceiver with no way to authenticate them, see IsUnauthenticated.
+var ErrorWebhookNotAuthenticated = errors.New("webhook receiver has no secret key or auth header")
+
+// ErrorWebhookHandler is wrapped by the errors of Dispatch when
+// a callback fails, ServeHTTP then responds with a 500 so the
+// output delivers the webhook again, elements included.
+var ErrorWebhookHandler = errors.New("webhook handler failed")
+
+// AuditEntry is an audit log entry as sent by outputs.
+type AuditEntry struct {
+	OID        string `json:"oid"`
+	Identity   string `json:"ident"`
+	EntityType string `json:"etype"`
+	Message    string `json:"msg"`
+	Metadata   Dict   `json:"mtd,omitempty"`
+	TimeStamp  int64  `json:"ts"`
+}
+
+// WebhookReceiver is an http.Handler receiving the data sent
+// by a "webhook" or "webhook_bulk" output and dispatching it
+// to typed callbacks.
+type WebhookReceiver struct {
+	// Secret configured as "secret_key" in the output, used
+	// to validate the signature of each webhook.
+	// Optional.
+	SecretKey string
+
+	// Shared secret header configured with "auth_header_name"
+	// and "auth_header_value" in the output.
+	// Optional.
+	AuthHeaderName  string
+	AuthHeaderValue string
+
+	// IsUnauthenticated accepts webhooks when neither SecretKey
+	// nor AuthHeaderName are set, otherwise they are rejected.
+	IsUnauthenticated bool
+
+	// Type of data the output sends. If empty, the type
+	// is inferred from the content of each element.
+	DataType OutputDataType
+
+	OnEvent     func(Event) error
+	OnDetection func(Detection) error
+	OnAudit     func(AuditEntry) error
+
+	// Called with elements the receiver was not able to process,
+	// including those the callbacks failed to handle.
+	// Optional.
+	OnError func(raw []byte, err error)
+}
+
+// ValidateWebhookSignature checks a webhook body against its signature.
+func ValidateWebhookSignature(secretKey string, body []byte, signature string) error {
+	if signature == "" {
+		return ErrorInvalidWebhookSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secretKey))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrorInvalidWebhookSignature
+	}
+	return nil
+}
+
+func (wr *WebhookReceiver) authenticate(r *http.Request, body []byte) error {
+	if wr.SecretKey == "" && wr.AuthHeaderName == "" && !wr.IsUnauthenticated {
+		return ErrorWebhookNotAuthenticated
+	}
+	if wr.AuthHeaderName != "" && !hmac.Equal([]byte(r.Header.Get(wr.AuthHeaderName)), []byte(wr.AuthHeaderValue)) {
+		return ErrorInvalidWebhookSignature
+	}
+	if wr.SecretKey != "" {
+		return ValidateWebhookSignature(wr.SecretKey, body, r.Header.Get(WebhookSignatureHeader))
+	}
+	return nil
+}
+
+func (wr *WebhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxWebhookBodySize))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := wr.authenticate(r, body); err != nil {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if err := wr.Dispatch(body); err != nil {
+		if errors.Is(err, ErrorWebhookHandler) {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// Dispatch parses an authenticated webhook body, which may be a
+// single element or a list of elements for bulk webhooks,
+// and calls the relevant callback for each element. Elements which
+// cannot be parsed are only reported to OnError while the first error
+// of the callbacks is returned, wrapping ErrorWebhookHandler.
+func (wr *WebhookReceiver) Dispatch(body []byte) error {
+	body = bytes.TrimSpace(body)
+	elements := []json.RawMessage{}
+	if len(body) != 0 && body[0] == '[' {
+		if err := json.Unmarshal(body, &elements); err != nil {
+			return err
+		}
+	} else {
+		elements = append(elements, json.RawMessage(body))
+	}
+	var handlerErr error
+	for _, e := range elements {
+		err := wr.dispatchElement(e)
+		if err == nil {
+			continue
+		}
+		if wr.OnError != nil {
+			wr.OnError(e, err)
+		}
+		if handlerErr == nil && errors.Is(err, ErrorWebhookHandler) {
+			handlerErr = err
+		}
+	}
+	return handlerErr
+}
+
+func webhookHandlerError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %v", ErrorWebhookHandler, err)
+}
+
+func (wr *WebhookReceiver) dispatchElement(raw []byte) error {
+	dataType := wr.DataType
+	if dataType == "" {
+		var err error
+		if dataType, err = inferWebhookDataType(raw); err != nil {
+			return err
+		}
+	}
+	switch dataType {
+	case OutputType.Detect:
+		d := Detection{}
+		if err := json.Unmarshal(raw, &d); err != nil {
+			return err
+		}
+		if wr.OnDetection == nil {
+			return nil
+		}
+		return webhookHandlerError(wr.OnDetection(d))
+	case OutputType.Audit:
+		a := AuditEntry{}
+		if err := json.Unmarshal(raw, &a); err != nil {
+			return err
+		}
+		if wr.OnAudit == nil {
+			return nil
+		}
+		return webhookHandlerError(wr.OnAudit(a))
+	case OutputType.Event, OutputType.Tailored:
+		e := Event{}
+		if err := json.Unmarshal(raw, &e); err != nil {
+			return err
+		}
+		if wr.OnEvent == nil {
+			return nil
+		}
+		return webhookHandlerError(wr.OnEvent(e))
+	}
+	return fmt.Errorf("unsupported webhook data type: %s", dataType)
+}
+
+func inferWebhookDataType(raw []byte) (OutputDataType, error) {
+	keys := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return "", err
+	}
+	if _, ok := keys["detect"]; ok {
+		return OutputType.Detect, nil
+	}
+	if _, ok := keys["etype"]; ok {
+		return OutputType.Audit, nil
+	}
+	if _, ok := keys["routing"]; ok {
+		return OutputType.Event, nil
+	}
+	return "", errors.New("unable to determine webhook data type")
+}
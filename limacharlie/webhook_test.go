@@ -0,0 +1,109 @@
+package limacharlie
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebhookReceiver(t *testing.T) {
+	a := assert.New(t)
+
+	detections := []Detection{}
+	events := []Event{}
+	wr := &WebhookReceiver{
+		SecretKey: "s3cr3t",
+		OnDetection: func(d Detection) error {
+			detections = append(detections, d)
+			return nil
+		},
+		OnEvent: func(e Event) error {
+			events = append(events, e)
+			return nil
+		},
+	}
+
+	body := []byte(`[{"cat":"test-det","detect":{"event":{}},"routing":{"sid":"s1"}},{"event":{"A":1},"routing":{"sid":"s2","event_type":"NEW_PROCESS"}}]`)
+	mac := hmac.New(sha256.New, []byte("s3cr3t"))
+	mac.Write(body)
+
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	wr.ServeHTTP(w, req)
+	a.Equal(http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	req.Header.Set(WebhookSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	w = httptest.NewRecorder()
+	wr.ServeHTTP(w, req)
+	a.Equal(http.StatusOK, w.Code)
+
+	if a.Len(detections, 1) {
+		a.Equal("test-det", detections[0].Category)
+		a.Equal("s1", detections[0].Routing.SID)
+	}
+	if a.Len(events, 1) {
+		a.Equal("NEW_PROCESS", events[0].Routing.EventType)
+	}
+}
+
+func TestWebhookReceiverStatus(t *testing.T) {
+	a := assert.New(t)
+
+	failures := []string{}
+	newReceiver := func() *WebhookReceiver {
+		return &WebhookReceiver{
+			AuthHeaderName:  "x-auth",
+			AuthHeaderValue: "token",
+			OnDetection: func(d Detection) error {
+				if d.Category == "fail" {
+					return errors.New("boom")
+				}
+				return nil
+			},
+			OnError: func(raw []byte, err error) {
+				failures = append(failures, err.Error())
+			},
+		}
+	}
+	post := func(wr *WebhookReceiver, body string, isAuthenticated bool) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		if isAuthenticated {
+			req.Header.Set("x-auth", "token")
+		}
+		w := httptest.NewRecorder()
+		wr.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for _, tc := range []struct {
+		name            string
+		receiver        func() *WebhookReceiver
+		body            string
+		isAuthenticated bool
+		status          int
+	}{
+		{"handled", newReceiver, `{"cat":"ok","detect":{}}`, true, http.StatusOK},
+		{"not authenticated", newReceiver, `{"cat":"ok","detect":{}}`, false, http.StatusUnauthorized},
+		{"handler failure is retried", newReceiver, `[{"cat":"ok","detect":{}},{"cat":"fail","detect":{}}]`, true, http.StatusInternalServerError},
+		{"unknown element is not retried", newReceiver, `{"unknown":1}`, true, http.StatusOK},
+		{"invalid body", newReceiver, `[{`, true, http.StatusBadRequest},
+		{"no secret", func() *WebhookReceiver {
+			return &WebhookReceiver{}
+		}, `{"cat":"ok","detect":{}}`, false, http.StatusUnauthorized},
+		{"unauthenticated opt-in", func() *WebhookReceiver {
+			return &WebhookReceiver{IsUnauthenticated: true}
+		}, `{"cat":"ok","detect":{}}`, false, http.StatusOK},
+	} {
+		a.Equal(tc.status, post(tc.receiver(), tc.body, tc.isAuthenticated), tc.name)
+	}
+	a.Equal([]string{"webhook handler failed: boom", "unable to determine webhook data type"}, failures)
+}
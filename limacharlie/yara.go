@@ -1,7 +1,5 @@
 package limacharlie
 
-import "encoding/json"
-
 type YaraSource struct {
 	Author      string `json:"by,omitempty" yaml:"by,omitempty"`
 	Source      string `json:"source,omitempty" yaml:"source,omitempty"`
@@ -17,8 +15,8 @@ type YaraRule struct {
 }
 
 type YaraRuleFilter struct {
-	Tags      []string `json:"tags" yaml:"tags"`
-	Platforms []string `json:"platforms" yaml:"platforms"`
+	Tags      []string   `json:"tags" yaml:"tags"`
+	Platforms []Platform `json:"platforms" yaml:"platforms"`
 }
 
 type YaraRuleName = string
@@ -28,41 +26,11 @@ type YaraSources map[YaraSourceName]YaraSource
 type YaraRules map[YaraRuleName]YaraRule
 
 func (r YaraRule) EqualsContent(r2 YaraRule) bool {
-	r.Author = ""
-	r.LastUpdated = 0
-	if len(r.Sources) == 0 {
-		r.Sources = nil
-	}
-	if len(r.Filters.Platforms) == 0 {
-		r.Filters.Platforms = nil
-	}
-	if len(r.Filters.Tags) == 0 {
-		r.Filters.Tags = nil
-	}
-	r2.Author = ""
-	r2.LastUpdated = 0
-	if len(r2.Sources) == 0 {
-		r2.Sources = nil
-	}
-	if len(r2.Filters.Platforms) == 0 {
-		r2.Filters.Platforms = nil
-	}
-	if len(r2.Filters.Tags) == 0 {
-		r2.Filters.Tags = nil
-	}
-	d1, err := json.Marshal(r)
-	if err != nil {
-		return false
-	}
-	d2, err := json.Marshal(r2)
-	if err != nil {
-		return false
-	}
-	return string(d1) == string(d2)
+	return syncNormalizers.YaraRule.Equal(r, r2)
 }
 
 func (s YaraSource) EqualsContent(s2 YaraSource) bool {
-	return s.Source == s2.Source && s.Content == s2.Content
+	return syncNormalizers.YaraSource.Equal(s, s2)
 }
 
 func (org Organization) yara(responseData interface{}, action string, req Dict) error {
@@ -86,7 +54,7 @@ func (org Organization) YaraRuleAdd(ruleName string, rule YaraRule) error {
 	}
 	platforms := rule.Filters.Platforms
 	if platforms == nil {
-		platforms = []string{}
+		platforms = []Platform{}
 	}
 
 	req := Dict{
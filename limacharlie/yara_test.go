@@ -39,7 +39,7 @@ func TestYaraRuleAddDelete(t *testing.T) {
 		Sources: []string{"testsource"},
 		Filters: YaraRuleFilter{
 			Tags:      []string{"t1"},
-			Platforms: []string{"windows"},
+			Platforms: []Platform{"windows"},
 		},
 	}
 	err = org.YaraRuleAdd(ruleName, rule)